@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/a11y"
+)
+
+// checkA11y audits the generated pages for common accessibility issues and
+// warns about each of them, or fails the build with --strict-a11y.
+func checkA11y(gc *GenerationContext) error {
+	log.Debug().Msg("start checking accessibility")
+
+	var pages, total int
+	err := forEachPage(func(page string, data []byte) error {
+		issues, err := a11y.Check(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", page, err)
+		}
+		if len(issues) == 0 {
+			return nil
+		}
+		for _, issue := range issues {
+			log.Warn().Str("page", page).Str("rule", issue.Rule).Msgf("%s: %s", page, issue.Message)
+		}
+		pages++
+		total += len(issues)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Int("pages", pages).Int("issues", total).Msg("done checking accessibility")
+	if gc.StrictA11y && total > 0 {
+		return fmt.Errorf("found %d accessibility issues on %d pages", total, pages)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gosuda.org/website/internal/htmlcheck"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/types"
+)
+
+// acronymPattern returns the pattern matching the acronyms of ac as whole
+// words, in the plural too, longer acronyms first, or nil if there are none.
+func acronymPattern(ac *AcronymsConfig) *regexp.Regexp {
+	if len(ac.Terms) == 0 {
+		return nil
+	}
+	acronyms := make([]string, 0, len(ac.Terms))
+	for a := range ac.Terms {
+		acronyms = append(acronyms, regexp.QuoteMeta(a))
+	}
+	sort.Slice(acronyms, func(i, j int) bool {
+		if len(acronyms[i]) != len(acronyms[j]) {
+			return len(acronyms[i]) > len(acronyms[j])
+		}
+		return acronyms[i] < acronyms[j]
+	})
+	return regexp.MustCompile(`\b(?:` + strings.Join(acronyms, "|") + `)s?\b`)
+}
+
+// acronymSkipped are the elements whose text is not expanded: code, and the
+// abbreviations marked up in the source.
+var acronymSkipped = map[string]bool{
+	"code": true, "pre": true, "kbd": true, "samp": true, "var": true, "abbr": true,
+	"svg": true, "math": true, "script": true, "style": true,
+}
+
+// expandAcronyms wraps the acronyms of acronyms.terms in the text of posts in
+// <abbr> elements titled with their expansions.
+func expandAcronyms(gc *GenerationContext, post *types.Post, doc string) (string, error) {
+	re := gc.Acronyms
+	if !gc.Config.Acronyms.Enabled || re == nil {
+		return doc, nil
+	}
+
+	var skip int
+	out, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		switch t.Type {
+		case htmlrewrite.StartTagToken:
+			class, _ := t.Attr("class")
+			switch {
+			case htmlcheck.IsVoid(t.Data):
+			case skip > 0 || acronymSkipped[t.Data] || class == "math":
+				skip++
+			}
+			return nil
+		case htmlrewrite.EndTagToken:
+			if skip > 0 {
+				skip--
+			}
+			return nil
+		case htmlrewrite.TextToken:
+			if skip > 0 {
+				return nil
+			}
+		default:
+			return nil
+		}
+
+		locs := re.FindAllStringIndex(t.Data, -1)
+		if locs == nil {
+			return nil
+		}
+		var b bytes.Buffer
+		var last int
+		for _, loc := range locs {
+			acronym := t.Data[loc[0]:loc[1]]
+			expansion, ok := gc.Config.Acronyms.Terms[acronym]
+			if !ok {
+				expansion = gc.Config.Acronyms.Terms[strings.TrimSuffix(acronym, "s")]
+			}
+			fmt.Fprintf(&b, `%s<abbr title="%s">%s</abbr>`, html.EscapeString(t.Data[last:loc[0]]),
+				html.EscapeString(expansion), html.EscapeString(acronym))
+			last = loc[1]
+		}
+		b.WriteString(html.EscapeString(t.Data[last:]))
+		return b.Bytes()
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"html"
+
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/markdown"
+	"gosuda.org/website/internal/types"
+)
+
+// admonitions localizes the default titles of the admonitions of posts, such
+// as "Note" in a `> [!NOTE]` callout, and the default summary of their details
+// blocks. The titles written in the source are left as they are.
+type admonitions struct{}
+
+func (admonitions) Name() string { return "admonitions" }
+
+func (admonitions) TransformContent(gc *GenerationContext, post *types.Post, lang types.Lang, doc string) (string, error) {
+	l := gc.I18n.Localizer(lang)
+	defaults := map[string]bool{markdown.DetailsSummary: true}
+	for _, title := range markdown.AdmonitionTitles {
+		defaults[title] = true
+	}
+
+	var title bool
+	out, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		switch t.Type {
+		case htmlrewrite.StartTagToken:
+			class, _ := t.Attr("class")
+			title = t.Data == "p" && class == "admonition-title" || t.Data == "summary" && class == "details-summary"
+		case htmlrewrite.EndTagToken:
+			title = false
+		case htmlrewrite.TextToken:
+			if title && defaults[t.Data] {
+				return []byte(html.EscapeString(l.T(t.Data)))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"gosuda.org/website/internal/alttext"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/types"
+)
+
+// missingAlt is an image without alt text in a rendered document.
+type missingAlt struct {
+	Post string `yaml:"post"`
+	File string `yaml:"file"`
+	Lang string `yaml:"lang"`
+	Src  string `yaml:"src"`
+	// Suggestion is the generated alt text, for review before it is added to the source.
+	Suggestion string `yaml:"suggestion,omitempty"`
+
+	context string
+}
+
+// altContextSize is the number of characters of text around an image given to the vision model.
+const altContextSize = 400
+
+// findMissingAlt returns the images of doc with a missing or blank alt attribute.
+func findMissingAlt(post *types.Post, doc *types.Document) []*missingAlt {
+	var text strings.Builder
+	var found []*missingAlt
+	var offsets []int
+	htmlrewrite.Walk([]byte(doc.HTML), func(t *htmlrewrite.Token) {
+		switch {
+		case t.Type == htmlrewrite.TextToken:
+			text.WriteString(t.Data)
+		case t.IsTag("img"):
+			alt, _ := t.Attr("alt")
+			src, _ := t.Attr("src")
+			if strings.TrimSpace(alt) != "" || src == "" {
+				return
+			}
+			found = append(found, &missingAlt{Post: post.ID, File: post.FilePath, Lang: doc.Metadata.Language, Src: src})
+			offsets = append(offsets, text.Len())
+		default:
+			// keep the text of adjacent blocks apart
+			text.WriteByte(' ')
+		}
+	})
+
+	all := text.String()
+	for i, m := range found {
+		start := max(offsets[i]-altContextSize/2, 0)
+		end := min(offsets[i]+altContextSize/2, len(all))
+		m.context = strings.Join(strings.Fields(strings.ToValidUTF8(all[start:end], "")), " ")
+	}
+	return found
+}
+
+// readImage returns the contents and MIME type of an image referenced by a post.
+func readImage(src string) ([]byte, string, error) {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		resp, err := apiClient.Get(src)
+		if err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, "", fmt.Errorf("GET %s: %s", src, resp.Status)
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, http.DetectContentType(data), nil
+	}
+	data, err := os.ReadFile(publicPath(src))
+	if err != nil {
+		return nil, "", err
+	}
+	mimeType := imageType(src)
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return data, mimeType, nil
+}
+
+func report_alt_main(args []string) {
+	fs := flag.NewFlagSet("report alt", flag.ExitOnError)
+	suggest := fs.Bool("suggest", false, "generate alt text suggestions with the vision model")
+	review := fs.String("review", "alt-review.yaml", "the file suggestions are written to for review")
+	fail := fs.Bool("fail", false, "exit with a non-zero status if any image has no alt text")
+	fs.Parse(args)
+	if *suggest && llmModel == nil {
+		log.Fatal().Msg("-suggest needs the LLM client, which is disabled by LLM_INIT")
+	}
+
+	ds, err := initializeDatabaseContent(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+
+	var missing []*missingAlt
+	for _, post := range ds.Posts {
+		for _, doc := range post.Translated {
+			missing = append(missing, findMissingAlt(post, doc)...)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].File != missing[j].File {
+			return missing[i].File < missing[j].File
+		}
+		return missing[i].Lang < missing[j].Lang
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tLANG\tSRC")
+	for _, m := range missing {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", m.File, m.Lang, m.Src)
+	}
+	w.Flush()
+	log.Info().Int("images", len(missing)).Msgf("found %d images without alt text", len(missing))
+
+	if *suggest {
+		suggestAltText(*review, missing)
+	}
+	if *fail && len(missing) > 0 {
+		os.Exit(1)
+	}
+}
+
+// suggestAltText generates alt text for the missing images and writes them to
+// the review file. Suggestions already in the file are kept, not regenerated.
+func suggestAltText(review string, missing []*missingAlt) {
+	var reviewed []*missingAlt
+	if data, err := os.ReadFile(review); err == nil {
+		if err := yaml.Unmarshal(data, &reviewed); err != nil {
+			log.Fatal().Err(err).Msgf("failed to parse review file %s", review)
+		}
+	}
+	done := make(map[string]bool)
+	for _, r := range reviewed {
+		done[r.Post+"\x00"+r.Lang+"\x00"+r.Src] = true
+	}
+
+	var suggested int
+	for _, m := range missing {
+		if done[m.Post+"\x00"+m.Lang+"\x00"+m.Src] {
+			continue
+		}
+		image, mimeType, err := readImage(m.Src)
+		if err != nil {
+			log.Error().Err(err).Str("src", m.Src).Msgf("failed to read image %s", m.Src)
+			continue
+		}
+		log.Debug().Str("src", m.Src).Str("lang", m.Lang).Msgf("start generating alt text for %s", m.Src)
+		m.Suggestion, err = alttext.GenerateAltText(context.Background(), llmModel, image, mimeType, m.context, types.FullLangName(m.Lang))
+		if err != nil {
+			log.Error().Err(err).Str("src", m.Src).Msgf("failed to generate alt text for %s", m.Src)
+			continue
+		}
+		reviewed = append(reviewed, m)
+		suggested++
+	}
+
+	data, err := yaml.Marshal(reviewed)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to encode alt text suggestions")
+	}
+	err = os.WriteFile(review, data, 0644)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to write review file %s", review)
+	}
+	log.Info().Int("suggested", suggested).Msgf("wrote %d alt text suggestions to %s for review", suggested, review)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/view"
+)
+
+// analyticsProviders describes the scripts and endpoints of the supported analytics services.
+var analyticsProviders = map[string]struct {
+	// script returns the default script URL and its configuration attributes.
+	script func(id string) (string, map[string]string)
+	// connect are the origins the script reports to.
+	connect func(id string) []string
+}{
+	"plausible": {
+		script: func(id string) (string, map[string]string) {
+			return "https://plausible.io/js/script.js", map[string]string{"data-domain": id}
+		},
+		connect: func(id string) []string { return []string{"https://plausible.io"} },
+	},
+	"goatcounter": {
+		script: func(id string) (string, map[string]string) {
+			return "https://gc.zgo.at/count.js", map[string]string{"data-goatcounter": "https://" + id + ".goatcounter.com/count"}
+		},
+		connect: func(id string) []string { return []string{"https://" + id + ".goatcounter.com"} },
+	},
+	"ga4": {
+		script: func(id string) (string, map[string]string) {
+			return "https://www.googletagmanager.com/gtag/js?id=" + url.QueryEscape(id), map[string]string{"data-id": id}
+		},
+		connect: func(id string) []string {
+			return []string{"https://www.google-analytics.com", "https://*.google-analytics.com", "https://*.analytics.google.com"}
+		},
+	},
+	"cloudflare": {
+		script: func(id string) (string, map[string]string) {
+			return "https://static.cloudflareinsights.com/beacon.min.js", map[string]string{"data-cf-beacon": `{"token": "` + id + `"}`}
+		},
+		connect: func(id string) []string { return []string{"https://cloudflareinsights.com"} },
+	},
+}
+
+// analyticsEnabled reports whether tracking code is emitted in the current environment.
+func analyticsEnabled(cfg *SiteConfig) bool {
+	return slices.Contains(cfg.Analytics.Environments, cfg.Environment)
+}
+
+// viewAnalytics returns the analytics scripts to render, or nil outside the configured environments.
+func viewAnalytics(gc *GenerationContext) []view.Analytics {
+	if !analyticsEnabled(gc.Config) {
+		return nil
+	}
+
+	var scripts []view.Analytics
+	for _, p := range gc.Config.Analytics.Providers {
+		provider, ok := analyticsProviders[p.Name]
+		if !ok {
+			log.Warn().Str("provider", p.Name).Msgf("unknown analytics provider %s", p.Name)
+			continue
+		}
+		src, attrs := provider.script(p.ID)
+		if p.Script != "" {
+			src = p.Script
+		}
+		scripts = append(scripts, view.Analytics{
+			Provider: p.Name,
+			Src:      src,
+			Consent:  gc.Config.Analytics.Consent,
+			Attrs:    attrs,
+		})
+	}
+	return scripts
+}
+
+// analyticsPolicy returns the CSP sources the analytics scripts report to.
+func analyticsPolicy(cfg *SiteConfig) cspPolicy {
+	p := cspPolicy{}
+	if !analyticsEnabled(cfg) {
+		return p
+	}
+	for _, provider := range cfg.Analytics.Providers {
+		if ap, ok := analyticsProviders[provider.Name]; ok {
+			p.add("connect-src", ap.connect(provider.ID)...)
+		}
+		if provider.Script != "" {
+			p.addRef("connect-src", provider.Script)
+		}
+	}
+	return p
+}
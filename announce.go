@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Announcement tracks the social media announcement of a newly published post.
+type Announcement struct {
+	// Detected is when the build that first published the post ran.
+	Detected time.Time `json:"detected"`
+	// Posted maps platforms to the URL of the announcement.
+	Posted map[string]string `json:"posted,omitempty"`
+}
+
+// announceData is the data of announcement templates.
+type announceData struct {
+	Title       string
+	Description string
+	URL         string
+	Tags        []string
+}
+
+// Hashtags returns the tags as hashtags, reduced to letters and digits.
+func (d *announceData) Hashtags() string {
+	var tags []string
+	for _, t := range d.Tags {
+		var b strings.Builder
+		for _, word := range strings.FieldsFunc(t, func(r rune) bool { return !isTagRune(r) }) {
+			r, size := utf8.DecodeRuneInString(word)
+			b.WriteRune(unicode.ToUpper(r))
+			b.WriteString(word[size:])
+		}
+		if b.Len() > 0 {
+			tags = append(tags, "#"+b.String())
+		}
+	}
+	return strings.Join(tags, " ")
+}
+
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// visiblePostIDs returns the IDs of the posts listed on the site.
+func visiblePostIDs(gc *GenerationContext) map[string]bool {
+	ids := make(map[string]bool)
+	for _, post := range listedPosts(gc) {
+		if post.Main != nil && !post.Main.Metadata.Hidden {
+			ids[post.ID] = true
+		}
+	}
+	return ids
+}
+
+// detectNewPosts records the posts that became visible in this build, given the
+// visible posts before it, as pending announcements.
+func detectNewPosts(gc *GenerationContext, before map[string]bool) {
+	ds := gc.DataStore
+	if len(before) == 0 {
+		// a fresh DataStore has no history, so every post would look new
+		log.Info().Msg("skipping announcement detection for the first build")
+		return
+	}
+	if ds.Announcements == nil {
+		ds.Announcements = make(map[string]*Announcement)
+	}
+	now := time.Now().UTC()
+	for id := range visiblePostIDs(gc) {
+		if before[id] || ds.Announcements[id] != nil {
+			continue
+		}
+		ds.Announcements[id] = &Announcement{Detected: now}
+		log.Info().Str("post", id).Msgf("new post %q will be announced", ds.Posts[id].Main.Metadata.Title)
+	}
+}
+
+// announceText renders the template of a platform, shortening the description
+// until the text fits in limit characters.
+func announceText(tmpl string, d announceData, limit int) (string, error) {
+	t, err := template.New("announce").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	desc := []rune(d.Description)
+	for {
+		var b bytes.Buffer
+		if err := t.Execute(&b, &d); err != nil {
+			return "", err
+		}
+		text := strings.TrimSpace(b.String())
+		if utf8.RuneCountInString(text) <= limit || len(desc) == 0 {
+			return text, nil
+		}
+		over := utf8.RuneCountInString(text) - limit
+		desc = desc[:max(len(desc)-over-1, 0)]
+		d.Description = strings.TrimSpace(string(desc)) + "…"
+		if len(desc) == 0 {
+			d.Description = ""
+		}
+	}
+}
+
+// announcer posts text linking to url and returns the URL of the announcement.
+type announcer func(cfg *AnnounceConfig, text string, d *announceData) (string, error)
+
+func announceMastodon(cfg *AnnounceConfig, text string, d *announceData) (string, error) {
+	mc := &cfg.Mastodon
+	header := http.Header{"Authorization": {"Bearer " + mc.Token}}
+	// the idempotency key keeps retries of a failed build from posting twice
+	header.Set("Idempotency-Key", "announce-"+d.URL)
+	var resp struct {
+		URL string `json:"url"`
+	}
+	err := jsonRequest(http.MethodPost, strings.TrimSuffix(mc.Server, "/")+"/api/v1/statuses", header, map[string]any{
+		"status":     text,
+		"visibility": mc.Visibility,
+	}, &resp)
+	return resp.URL, err
+}
+
+func announceBluesky(cfg *AnnounceConfig, text string, d *announceData) (string, error) {
+	bc := &cfg.Bluesky
+	service := strings.TrimSuffix(bc.Service, "/")
+	var session struct {
+		AccessJwt string `json:"accessJwt"`
+		DID       string `json:"did"`
+		Handle    string `json:"handle"`
+	}
+	err := jsonRequest(http.MethodPost, service+"/xrpc/com.atproto.server.createSession", http.Header{}, map[string]any{
+		"identifier": bc.Handle,
+		"password":   bc.AppPassword,
+	}, &session)
+	if err != nil {
+		return "", err
+	}
+
+	record := map[string]any{
+		"$type":     "app.bsky.feed.post",
+		"text":      text,
+		"createdAt": time.Now().UTC().Format(time.RFC3339),
+		"embed": map[string]any{
+			"$type": "app.bsky.embed.external",
+			"external": map[string]any{
+				"uri":         d.URL,
+				"title":       d.Title,
+				"description": d.Description,
+			},
+		},
+	}
+	// links are only clickable when marked with a facet, addressed by UTF-8 byte offsets
+	if i := strings.Index(text, d.URL); i >= 0 {
+		record["facets"] = []any{map[string]any{
+			"index":    map[string]any{"byteStart": i, "byteEnd": i + len(d.URL)},
+			"features": []any{map[string]any{"$type": "app.bsky.richtext.facet#link", "uri": d.URL}},
+		}}
+	}
+
+	var resp struct {
+		URI string `json:"uri"`
+	}
+	err = jsonRequest(http.MethodPost, service+"/xrpc/com.atproto.repo.createRecord", http.Header{"Authorization": {"Bearer " + session.AccessJwt}}, map[string]any{
+		"repo":       session.DID,
+		"collection": "app.bsky.feed.post",
+		"record":     record,
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+	return "https://bsky.app/profile/" + session.Handle + "/post/" + path.Base(resp.URI), nil
+}
+
+// announcePending posts the pending announcements to every enabled platform.
+// Platforms that fail are retried on the next run.
+func announcePending(cfg *SiteConfig, ds *DataStore, dryRun bool) (failed int) {
+	ac := &cfg.Announce
+	platforms := []struct {
+		name     string
+		enabled  bool
+		template string
+		limit    int
+		post     announcer
+	}{
+		{"mastodon", ac.Mastodon.Enabled, ac.Mastodon.Template, 500, announceMastodon},
+		{"bluesky", ac.Bluesky.Enabled, ac.Bluesky.Template, 300, announceBluesky},
+	}
+
+	ids := make([]string, 0, len(ds.Announcements))
+	for id := range ds.Announcements {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		a := ds.Announcements[id]
+		post, ok := ds.Posts[id]
+		if !ok || post.Main == nil || post.Main.Metadata.Hidden {
+			continue
+		}
+		meta := &post.Main.Metadata
+		d := announceData{
+			Title:       meta.Title,
+			Description: meta.Description,
+			URL:         postURL(post, meta.Language),
+			Tags:        meta.Tags,
+		}
+
+		for _, p := range platforms {
+			if !p.enabled || a.Posted[p.name] != "" {
+				continue
+			}
+			tmpl := p.template
+			if tmpl == "" {
+				tmpl = ac.Template
+			}
+			text, err := announceText(tmpl, d, p.limit)
+			if err != nil {
+				log.Error().Err(err).Str("platform", p.name).Msg("failed to render announcement template")
+				failed++
+				continue
+			}
+			if dryRun {
+				log.Info().Str("platform", p.name).Str("post", id).Msgf("would announce:\n%s", text)
+				continue
+			}
+
+			url, err := p.post(ac, text, &d)
+			if err != nil {
+				log.Error().Err(err).Str("platform", p.name).Msgf("failed to announce %q", meta.Title)
+				failed++
+				continue
+			}
+			if a.Posted == nil {
+				a.Posted = make(map[string]string)
+			}
+			a.Posted[p.name] = url
+			log.Info().Str("platform", p.name).Str("url", url).Msgf("announced %q", meta.Title)
+		}
+	}
+	return failed
+}
+
+func announce_main() {
+	fs := flag.NewFlagSet("announce", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "print the announcements without posting them")
+	fs.Parse(os.Args[2:])
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+	ds, err := initializeDatabase(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+	if ds.Announcements == nil {
+		ds.Announcements = make(map[string]*Announcement)
+	}
+	// posts given as arguments are announced even if no build detected them
+	for _, id := range fs.Args() {
+		if _, ok := ds.Posts[id]; !ok {
+			log.Fatal().Msgf("unknown post %q", id)
+		}
+		if ds.Announcements[id] == nil {
+			ds.Announcements[id] = &Announcement{Detected: time.Now().UTC()}
+		}
+	}
+
+	failed := announcePending(cfg, ds, *dryRun)
+	if !*dryRun {
+		err = updateDatabase(dbFile, ds)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to update database file %s", dbFile)
+		}
+	}
+	if failed > 0 {
+		log.Fatal().Int("failed", failed).Msgf("failed to post %d announcements", failed)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+)
+
+// APIPostSummary is a post entry of the JSON API index.
+type APIPostSummary struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	API         string    `json:"api"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Author      string    `json:"author,omitempty"`
+	Language    string    `json:"language"`
+	Tags        []string  `json:"tags,omitempty"`
+	Series      string    `json:"series,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	Date        time.Time `json:"date"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Languages   []string  `json:"languages"`
+}
+
+// APIPost is the full representation of a post, with all translations.
+type APIPost struct {
+	APIPostSummary
+	Translations map[string]*APIDocument `json:"translations"`
+}
+
+// APIDocument is a post in a single language.
+type APIDocument struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	HTML        string `json:"html"`
+}
+
+// APIPostPage is a page of the post index.
+type APIPostPage struct {
+	Page       int               `json:"page"`
+	TotalPages int               `json:"total_pages"`
+	TotalPosts int               `json:"total_posts"`
+	Next       string            `json:"next,omitempty"`
+	Prev       string            `json:"prev,omitempty"`
+	Posts      []*APIPostSummary `json:"posts"`
+}
+
+// APITag lists the posts with a tag.
+type APITag struct {
+	Tag   string   `json:"tag"`
+	Count int      `json:"count"`
+	Posts []string `json:"posts"`
+}
+
+func apiPagePath(page int) string {
+	if page == 1 {
+		return "/api/posts.json"
+	}
+	return fmt.Sprintf("/api/posts/page/%d.json", page)
+}
+
+func writeJSON(sitePath string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	fp := filepath.Join(distDir, filepath.FromSlash(sitePath))
+	err = os.MkdirAll(filepath.Dir(fp), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fp, data, 0644)
+}
+
+func apiPostSummary(gc *GenerationContext, post *types.Post) *APIPostSummary {
+	pm := &post.Main.Metadata
+	s := &APIPostSummary{
+		ID:          post.ID,
+		URL:         postURL(post, pm.Language),
+		API:         baseURL + "/api/posts/" + post.ID + ".json",
+		Title:       pm.Title,
+		Description: pm.Description,
+		Author:      pm.Author,
+		Language:    pm.Language,
+		Tags:        pm.Tags,
+		Series:      pm.Series,
+		Date:        pm.Date,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+	}
+	if cover, ok := gc.Images[post.ID]; ok {
+		s.Image = baseURL + cover.URL
+	}
+	for _, lang := range types.SupportedLanguages {
+		if _, ok := post.Translated[lang]; ok {
+			s.Languages = append(s.Languages, lang)
+		}
+	}
+	return s
+}
+
+// generateAPI writes the static JSON API. Hidden posts and pages are left out, like on the index.
+func generateAPI(gc *GenerationContext) error {
+	if !gc.Config.API.Enabled {
+		return nil
+	}
+	log.Debug().Msg("start generating JSON API")
+
+	var posts []*types.Post
+	for _, post := range listedPosts(gc) {
+		if !post.Main.Metadata.Hidden {
+			posts = append(posts, post)
+		}
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].Main.Metadata.Date.After(posts[j].Main.Metadata.Date)
+	})
+
+	tags := make(map[string]*APITag)
+	summaries := make([]*APIPostSummary, 0, len(posts))
+	for _, post := range posts {
+		s := apiPostSummary(gc, post)
+		summaries = append(summaries, s)
+
+		full := &APIPost{APIPostSummary: *s, Translations: make(map[string]*APIDocument)}
+		for _, lang := range s.Languages {
+			doc, err := gc.DataStore.document(post, post.Translated[lang])
+			if err != nil {
+				return err
+			}
+			full.Translations[lang] = &APIDocument{
+				URL:         postURL(post, lang),
+				Title:       doc.Metadata.Title,
+				Description: doc.Metadata.Description,
+				HTML:        doc.HTML,
+			}
+		}
+		err := writeJSON("/api/posts/"+post.ID+".json", full)
+		if err != nil {
+			return err
+		}
+
+		for _, tag := range post.Main.Metadata.Tags {
+			t, ok := tags[tag]
+			if !ok {
+				t = &APITag{Tag: tag}
+				tags[tag] = t
+			}
+			t.Count++
+			t.Posts = append(t.Posts, post.ID)
+		}
+	}
+
+	size := gc.Config.API.PageSize
+	pages := (len(summaries) + size - 1) / size
+	if pages == 0 {
+		pages = 1
+	}
+	for page := 1; page <= pages; page++ {
+		start, end := (page-1)*size, page*size
+		if end > len(summaries) {
+			end = len(summaries)
+		}
+		p := &APIPostPage{
+			Page:       page,
+			TotalPages: pages,
+			TotalPosts: len(summaries),
+			Posts:      summaries[start:end],
+		}
+		if page < pages {
+			p.Next = baseURL + apiPagePath(page+1)
+		}
+		if page > 1 {
+			p.Prev = baseURL + apiPagePath(page-1)
+		}
+		err := writeJSON(apiPagePath(page), p)
+		if err != nil {
+			return err
+		}
+	}
+
+	tagList := make([]*APITag, 0, len(tags))
+	for _, t := range tags {
+		tagList = append(tagList, t)
+	}
+	sort.Slice(tagList, func(i, j int) bool { return tagList[i].Tag < tagList[j].Tag })
+	err := writeJSON("/api/tags.json", tagList)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Int("posts", len(summaries)).Int("pages", pages).Int("tags", len(tagList)).Msg("done generating JSON API")
+	return nil
+}
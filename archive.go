@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/types"
+)
+
+// ArchiveEntry tracks the archive.org snapshot of an outbound link of posts.
+type ArchiveEntry struct {
+	// URL is the snapshot of the link, "" until one was taken.
+	URL string `json:"url,omitempty"`
+	// Archived is when the snapshot was taken.
+	Archived time.Time `json:"archived"`
+	// Requested is when a snapshot was last requested. Failed requests are
+	// retried a day later.
+	Requested time.Time `json:"requested"`
+}
+
+// archiveRetry is how long a failed snapshot request waits to be retried.
+const archiveRetry = 24 * time.Hour
+
+// excludedHost reports whether links to host are left unarchived: those of
+// the site, of the Wayback Machine, and of archive.exclude with their
+// subdomains.
+func excludedHost(ac *ArchiveConfig, host string) bool {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	if u, err := url.Parse(baseURL); err == nil && strings.TrimPrefix(u.Host, "www.") == host {
+		return true
+	}
+	for _, h := range append([]string{"web.archive.org"}, ac.Exclude...) {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// outboundLinks returns the http and https links of doc to other sites, in
+// order of appearance, without duplicates.
+func outboundLinks(ac *ArchiveConfig, doc string) ([]string, error) {
+	var links []string
+	seen := make(map[string]bool)
+	_, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		if !t.IsTag("a") {
+			return nil
+		}
+		href, _ := t.Attr("href")
+		u, err := url.Parse(href)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || excludedHost(ac, u.Host) || seen[href] {
+			return nil
+		}
+		seen[href] = true
+		links = append(links, href)
+		return nil
+	})
+	return links, err
+}
+
+// postLinks returns the outbound links of the published posts in any
+// language, sorted.
+func postLinks(ac *ArchiveConfig, ds *DataStore) []string {
+	seen := make(map[string]bool)
+	for _, post := range ds.Posts {
+		if post.Main == nil || isUnpublished(post) {
+			continue
+		}
+		for lang, doc := range post.Translated {
+			doc, err := ds.document(post, doc)
+			if err != nil {
+				log.Warn().Err(err).Str("post", post.FilePath).Str("lang", lang).Msgf("failed to read the %s document of %s", lang, post.FilePath)
+				continue
+			}
+			links, err := outboundLinks(ac, doc.HTML)
+			if err != nil {
+				log.Warn().Err(err).Str("post", post.FilePath).Str("lang", lang).Msgf("failed to parse the links of %s", post.FilePath)
+				continue
+			}
+			for _, link := range links {
+				seen[link] = true
+			}
+		}
+	}
+	links := make([]string, 0, len(seen))
+	for link := range seen {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	return links
+}
+
+// savePageNow sends a form request to the Save Page Now API and decodes its
+// JSON response into out.
+func savePageNow(ac *ArchiveConfig, method, endpoint string, form url.Values, out any) error {
+	req, err := http.NewRequest(method, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if ac.AccessKey != "" {
+		req.Header.Set("Authorization", "LOW "+ac.AccessKey+":"+ac.Secret)
+	}
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: %s: %s", method, endpoint, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// archiveLink requests a snapshot of link from the Wayback Machine, reusing
+// one taken in the last month, and waits for it to be taken.
+func archiveLink(ac *ArchiveConfig, link string) (*ArchiveEntry, error) {
+	var job struct {
+		JobID   string `json:"job_id"`
+		Message string `json:"message"`
+	}
+	err := savePageNow(ac, http.MethodPost, "https://web.archive.org/save", url.Values{
+		"url":                    {link},
+		"if_not_archived_within": {"30d"},
+	}, &job)
+	if err != nil {
+		return nil, err
+	}
+	if job.JobID == "" {
+		return nil, fmt.Errorf("no snapshot: %s", job.Message)
+	}
+
+	for start := time.Now(); time.Since(start) < 3*time.Minute; time.Sleep(5 * time.Second) {
+		var status struct {
+			Status    string `json:"status"`
+			Timestamp string `json:"timestamp"`
+			Message   string `json:"message"`
+		}
+		err = savePageNow(ac, http.MethodGet, "https://web.archive.org/save/status/"+url.PathEscape(job.JobID), nil, &status)
+		if err != nil {
+			return nil, err
+		}
+		switch status.Status {
+		case "pending":
+			continue
+		case "success":
+			archived, err := time.Parse("20060102150405", status.Timestamp)
+			if err != nil {
+				return nil, fmt.Errorf("snapshot timestamp %q: %w", status.Timestamp, err)
+			}
+			return &ArchiveEntry{
+				URL:      "https://web.archive.org/web/" + status.Timestamp + "/" + link,
+				Archived: archived,
+			}, nil
+		default:
+			return nil, fmt.Errorf("snapshot %s: %s", status.Status, status.Message)
+		}
+	}
+	return nil, fmt.Errorf("snapshot job %s timed out", job.JobID)
+}
+
+// archivePending requests snapshots of up to archive.limit outbound links of
+// the published posts without one, and records them in the DataStore. The
+// pages link to the snapshots from the next build on.
+func archivePending(cfg *SiteConfig, ds *DataStore, dryRun bool) (archived, failed int) {
+	ac := &cfg.Archive
+	if ds.Archives == nil {
+		ds.Archives = make(map[string]*ArchiveEntry)
+	}
+
+	var pending []string
+	for _, link := range postLinks(ac, ds) {
+		entry := ds.Archives[link]
+		if entry == nil || entry.URL == "" && time.Since(entry.Requested) > archiveRetry {
+			pending = append(pending, link)
+		}
+	}
+	if len(pending) > ac.Limit {
+		log.Info().Int("pending", len(pending)).Msgf("archiving %d of %d links, the rest on the next runs", ac.Limit, len(pending))
+		pending = pending[:ac.Limit]
+	}
+
+	for _, link := range pending {
+		if dryRun {
+			log.Info().Str("link", link).Msgf("would archive %s", link)
+			continue
+		}
+		entry, err := archiveLink(ac, link)
+		if err != nil {
+			log.Warn().Err(err).Str("link", link).Msgf("failed to archive %s", link)
+			ds.Archives[link] = &ArchiveEntry{Requested: time.Now().UTC()}
+			failed++
+			continue
+		}
+		entry.Requested = time.Now().UTC()
+		ds.Archives[link] = entry
+		archived++
+		log.Info().Str("link", link).Str("snapshot", entry.URL).Msgf("archived %s", link)
+	}
+	return archived, failed
+}
+
+// archiveLinks is the content transformer adding a link to the snapshot after
+// each outbound link of posts that has one, for when the page is gone.
+type archiveLinks struct{}
+
+func (archiveLinks) Name() string { return "archive-links" }
+
+func (archiveLinks) TransformContent(gc *GenerationContext, post *types.Post, lang types.Lang, doc string) (string, error) {
+	if !gc.Config.Archive.Enabled || len(gc.DataStore.Archives) == 0 {
+		return doc, nil
+	}
+	l := gc.I18n.Localizer(lang)
+	var snapshot *ArchiveEntry
+	out, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		switch {
+		case t.IsTag("a"):
+			href, _ := t.Attr("href")
+			snapshot = gc.DataStore.Archives[href]
+		case t.Type == htmlrewrite.EndTagToken && t.Data == "a" && snapshot != nil && snapshot.URL != "":
+			title := l.T("Archived copy of %s", l.Date(snapshot.Archived))
+			link := fmt.Sprintf(` <small class="archived-link">(<a href="%s" rel="nofollow noopener" title="%s">%s</a>)</small>`,
+				html.EscapeString(snapshot.URL), html.EscapeString(title), html.EscapeString(l.T("archived")))
+			snapshot = nil
+			return append(t.Raw, link...)
+		}
+		return nil
+	})
+	return string(out), err
+}
+
+func archive_main() {
+	fs := flag.NewFlagSet("archive", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "list the links that would be archived without requesting snapshots")
+	fs.Parse(os.Args[2:])
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+	ds, err := initializeDatabaseContent(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+
+	archived, failed := archivePending(cfg, ds, *dryRun)
+	if !*dryRun {
+		err = updateDatabase(dbFile, ds)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to update database file %s", dbFile)
+		}
+	}
+	log.Info().Int("archived", archived).Int("failed", failed).Msgf("archived %d links", archived)
+}
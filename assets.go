@@ -0,0 +1,87 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+)
+
+// AssetChanges lists the site paths of the public/ files that differ from the previous build.
+type AssetChanges struct {
+	Added    []string `json:"added,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+}
+
+// Len returns the number of changed assets.
+func (c *AssetChanges) Len() int {
+	return len(c.Added) + len(c.Modified) + len(c.Removed)
+}
+
+// trackAssets hashes the files of public/, compares them with the hashes of the
+// previous build and stores the new hashes in the DataStore.
+func trackAssets(gc *GenerationContext) (*AssetChanges, error) {
+	log.Debug().Msg("start hashing static files")
+	list, err := generateFileList(publicDir)
+	if err != nil {
+		return nil, err
+	}
+
+	prev := gc.DataStore.Assets
+	hashes := make(map[string]string, len(list))
+	changes := &AssetChanges{}
+	for _, path := range list {
+		rel, err := filepath.Rel(publicDir, path)
+		if err != nil {
+			return nil, err
+		}
+		rel = "/" + filepath.ToSlash(rel)
+
+		hash, _, err := hashFile(path)
+		if err != nil {
+			return nil, err
+		}
+		hashes[rel] = hash
+
+		old, ok := prev[rel]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, rel)
+		case old != hash:
+			changes.Modified = append(changes.Modified, rel)
+		}
+	}
+	for rel := range prev {
+		if _, ok := hashes[rel]; !ok {
+			changes.Removed = append(changes.Removed, rel)
+		}
+	}
+	sort.Strings(changes.Removed)
+
+	gc.DataStore.Assets = hashes
+	log.Debug().Int("files", len(hashes)).Msg("done hashing static files")
+	return changes, nil
+}
+
+// logAssetChanges prints the build summary of the changed static assets.
+func logAssetChanges(c *AssetChanges) {
+	if c == nil || c.Len() == 0 {
+		log.Info().Msg("no static assets changed")
+		return
+	}
+	for _, path := range c.Added {
+		log.Debug().Str("path", path).Msgf("added static asset %s", path)
+	}
+	for _, path := range c.Modified {
+		log.Info().Str("path", path).Msgf("modified static asset %s", path)
+	}
+	for _, path := range c.Removed {
+		log.Info().Str("path", path).Msgf("removed static asset %s", path)
+	}
+	log.Info().
+		Int("added", len(c.Added)).
+		Int("modified", len(c.Modified)).
+		Int("removed", len(c.Removed)).
+		Msgf("%d static assets changed", c.Len())
+}
@@ -1,5 +1,6 @@
 bun install && \
+  bun run build && \
   go install golang.org/x/tools/cmd/stringer@latest && \
   go install github.com/a-h/templ/cmd/templ@latest && \
   go generate ./... && \
-  go run .
\ No newline at end of file
+  go run . --suggest-metadata
\ No newline at end of file
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/csspurge"
+	"gosuda.org/website/internal/htmlrewrite"
+)
+
+// bundleFallbacks maps generated entry points to the sources bundled in their
+// place until they are generated. Tailwind compiles main.css from
+// tailwind-main.css (bun run build); without it, pages lack the tailwind
+// utilities, but keep the plain styles of the site.
+var bundleFallbacks = map[string]string{
+	"/main.css": "/tailwind-main.css",
+}
+
+// bundleAssets bundles the configured entry points of public/ with esbuild into
+// minified, fingerprinted files in dist and removes the plain copies of the entry
+// points. It returns the bundled site path of each entry point. Missing entry
+// points are bundled from their entry in bundleFallbacks, or left out with a
+// warning.
+func bundleAssets(gc *GenerationContext) (map[string]string, error) {
+	bc := &gc.Config.Bundle
+	if len(bc.Entries) == 0 {
+		return nil, nil
+	}
+	log.Debug().Strs("entries", bc.Entries).Msg("start bundling assets")
+
+	var entries []string
+	var fallbacks []api.EntryPoint
+	// the entry points bundled from their fallback, keyed by the fallback
+	fallbackOf := make(map[string]string)
+	for _, entry := range bc.Entries {
+		path := publicPath(entry)
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			entries = append(entries, path)
+			continue
+		}
+		fallback, ok := bundleFallbacks[entry]
+		if ok {
+			_, err := os.Stat(publicPath(fallback))
+			ok = err == nil
+		}
+		if !ok {
+			log.Warn().Str("entry", entry).Msgf("bundle entry point %s does not exist, skipping it", path)
+			continue
+		}
+		log.Warn().Str("entry", entry).Msgf("bundle entry point %s does not exist, bundling %s in its place", path, publicPath(fallback))
+		rel := strings.TrimPrefix(entry, "/")
+		fallbacks = append(fallbacks, api.EntryPoint{
+			InputPath:  publicPath(fallback),
+			OutputPath: strings.TrimSuffix(rel, filepath.Ext(rel)),
+		})
+		fallbackOf[fallback] = entry
+	}
+	if len(entries)+len(fallbacks) == 0 {
+		return nil, nil
+	}
+
+	opts := api.BuildOptions{
+		EntryPoints:         entries,
+		EntryPointsAdvanced: fallbacks,
+		Outbase:             publicDir,
+		Outdir:              distDir,
+		EntryNames:          "[dir]/[name]-[hash]",
+		Bundle:              true,
+		MinifyWhitespace:    true,
+		MinifyIdentifiers:   true,
+		MinifySyntax:        true,
+		Target:              api.ES2020,
+		// absolute URLs, such as the fonts in url(/assets/...), are served as they are
+		External: []string{"/*"},
+		Metafile: true,
+		Write:    true,
+		LogLevel: api.LogLevelSilent,
+	}
+	if bc.SourceMaps {
+		opts.Sourcemap = api.SourceMapLinked
+	}
+	if bc.Purge {
+		used, err := usedClasses(gc)
+		if err != nil {
+			return nil, err
+		}
+		opts.Plugins = append(opts.Plugins, purgePlugin(used))
+	}
+
+	result := api.Build(opts)
+	if len(result.Errors) > 0 {
+		var errs []error
+		for _, m := range result.Errors {
+			if m.Location != nil {
+				errs = append(errs, fmt.Errorf("%s:%d:%d: %s", m.Location.File, m.Location.Line, m.Location.Column, m.Text))
+			} else {
+				errs = append(errs, errors.New(m.Text))
+			}
+		}
+		return nil, fmt.Errorf("failed to bundle assets: %w", errors.Join(errs...))
+	}
+	for _, m := range result.Warnings {
+		log.Warn().Msgf("esbuild: %s", m.Text)
+	}
+
+	var meta struct {
+		Outputs map[string]struct {
+			EntryPoint string `json:"entryPoint"`
+		} `json:"outputs"`
+	}
+	err := json.Unmarshal([]byte(result.Metafile), &meta)
+	if err != nil {
+		return nil, err
+	}
+
+	bundles := make(map[string]string, len(bc.Entries))
+	for out, o := range meta.Outputs {
+		if o.EntryPoint == "" {
+			continue
+		}
+		entry, err := filepath.Rel(publicDir, filepath.FromSlash(o.EntryPoint))
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(distDir, filepath.FromSlash(out))
+		if err != nil {
+			return nil, err
+		}
+		site := "/" + filepath.ToSlash(entry)
+		if e, ok := fallbackOf[site]; ok {
+			site = e
+		}
+		bundles[site] = "/" + filepath.ToSlash(rel)
+
+		err = os.Remove(filepath.Join(distDir, filepath.FromSlash(site)))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	for entry, bundle := range bundles {
+		log.Debug().Str("entry", entry).Str("bundle", bundle).Msgf("bundled %s into %s", entry, bundle)
+	}
+	log.Debug().Int("bundles", len(bundles)).Msg("done bundling assets")
+	return bundles, nil
+}
+
+// usedClasses collects the classes of the generated pages, and the class name
+// candidates of the templates and scripts for the classes not rendered by this
+// build, such as those of conditional markup or added by scripts.
+func usedClasses(gc *GenerationContext) (map[string]bool, error) {
+	used := make(map[string]bool)
+	for _, class := range gc.Config.Bundle.Safelist {
+		used[class] = true
+	}
+
+	list, err := generateFileList(distDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range list {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".html", ".htm", ".xsl":
+		default:
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		_, err = htmlrewrite.Rewrite(data, func(t *htmlrewrite.Token) []byte {
+			if class, ok := t.Attr("class"); ok {
+				for _, c := range strings.Fields(class) {
+					used[c] = true
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	sources, err := filepath.Glob(filepath.Join(viewDir, "*.templ"))
+	if err != nil {
+		return nil, err
+	}
+	public, err := generateFileList(publicDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range public {
+		if strings.ToLower(filepath.Ext(path)) == ".js" {
+			sources = append(sources, path)
+		}
+	}
+	for _, path := range sources {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range csspurge.Candidates(string(data)) {
+			used[c] = true
+		}
+	}
+	return used, nil
+}
+
+// purgePlugin removes the rules of unused classes from the stylesheets as esbuild loads them.
+func purgePlugin(used map[string]bool) api.Plugin {
+	return api.Plugin{
+		Name: "purge",
+		Setup: func(build api.PluginBuild) {
+			build.OnLoad(api.OnLoadOptions{Filter: `\.css$`}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				data, err := os.ReadFile(args.Path)
+				if err != nil {
+					return api.OnLoadResult{}, err
+				}
+				css := csspurge.Purge(string(data), func(class string) bool { return used[class] })
+				log.Debug().Str("path", args.Path).Int("size", len(data)).Int("purged", len(css)).Msgf("purged unused classes of %s", args.Path)
+				return api.OnLoadResult{Contents: &css, Loader: api.LoaderCSS}, nil
+			})
+		},
+	}
+}
+
+// applyBundles points the script and stylesheet references of the generated pages
+// and feed stylesheets at the bundled files.
+func applyBundles(gc *GenerationContext) error {
+	if len(gc.Bundles) == 0 {
+		return nil
+	}
+	log.Debug().Msg("start rewriting bundle references")
+
+	list, err := generateFileList(distDir)
+	if err != nil {
+		return err
+	}
+	for _, path := range list {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".html", ".htm", ".xsl":
+		default:
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var changed bool
+		data, err = htmlrewrite.Rewrite(data, func(t *htmlrewrite.Token) []byte {
+			var key string
+			switch {
+			case t.IsTag("script"):
+				key = "src"
+			case t.IsTag("link"):
+				key = "href"
+			default:
+				return nil
+			}
+			ref, _ := t.Attr(key)
+			bundle, ok := gc.Bundles[ref]
+			if !ok {
+				return nil
+			}
+			t.SetAttr(key, bundle)
+			changed = true
+			return t.Render()
+		})
+		if err != nil {
+			return fmt.Errorf("failed to rewrite %s: %w", path, err)
+		}
+		if !changed {
+			continue
+		}
+		err = os.WriteFile(path, data, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Debug().Msg("done rewriting bundle references")
+	return nil
+}
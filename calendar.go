@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+)
+
+// calendarEvent is a dated entry of the content calendar.
+type calendarEvent struct {
+	Date time.Time
+	// Kind is "scheduled", "published", "in review" or "review due".
+	Kind string
+	Post *types.Post
+	// Due is the date of the event before overdue reviews were moved to the
+	// start of the calendar, which keeps their UIDs stable.
+	Due time.Time
+}
+
+// contentCalendar returns the events of the posts between from and to: the
+// posts scheduled or published then, the posts waiting in a review state
+// since then, and the posts due for review then, months after they were last
+// reviewed or updated.
+func contentCalendar(cfg *SiteConfig, ds *DataStore, from, to time.Time, months int) []calendarEvent {
+	in := func(t time.Time) bool { return !t.Before(from) && t.Before(to) }
+
+	var events []calendarEvent
+	for _, post := range ds.Posts {
+		pm := &post.Main.Metadata
+		status := postStatus(post)
+		switch {
+		case slices.Contains(cfg.Workflow.Review, status):
+			// listed however long it has been waiting
+			events = append(events, calendarEvent{post.UpdatedAt, "in review", post, post.UpdatedAt})
+			continue
+		case status != types.StatusPublished:
+			continue
+		}
+
+		if in(pm.Date) {
+			kind := "published"
+			if pm.Date.After(time.Now()) {
+				kind = "scheduled"
+			}
+			events = append(events, calendarEvent{pm.Date, kind, post, pm.Date})
+		}
+		if pm.Hidden || !pm.IsPost() {
+			continue
+		}
+		if due := lastTouched(post).AddDate(0, months, 0); due.Before(to) {
+			// overdue reviews are listed at the start of the calendar
+			date := due
+			if date.Before(from) {
+				date = from
+			}
+			events = append(events, calendarEvent{date, "review due", post, due})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Date.Equal(events[j].Date) {
+			return events[i].Date.Before(events[j].Date)
+		}
+		return events[i].Post.FilePath < events[j].Post.FilePath
+	})
+	return events
+}
+
+// icalText escapes s as an iCalendar TEXT value.
+func icalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// icalCalendar renders the events as an iCalendar file of all-day events.
+func icalCalendar(events []calendarEvent) []byte {
+	var b bytes.Buffer
+	line := func(format string, args ...any) {
+		s := fmt.Sprintf(format, args...)
+		// content lines are folded at 75 octets, including the leading space
+		// of continuation lines
+		width := 75
+		for len(s) > width {
+			n := width
+			for n > 0 && s[n]&0xC0 == 0x80 {
+				n--
+			}
+			b.WriteString(s[:n] + "\r\n ")
+			s = s[n:]
+			width = 74
+		}
+		b.WriteString(s + "\r\n")
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	host := baseURL
+	if u, err := url.Parse(baseURL); err == nil {
+		host = u.Host
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//GoSuda//Content Calendar//EN")
+	line("X-WR-CALNAME:%s", icalText("GoSuda content calendar"))
+	for _, e := range events {
+		day := e.Date.UTC().Format("20060102")
+		line("BEGIN:VEVENT")
+		line("UID:%s-%s-%s@%s", e.Post.ID, strings.ReplaceAll(e.Kind, " ", "-"), e.Due.UTC().Format("20060102"), host)
+		line("DTSTAMP:%s", stamp)
+		line("DTSTART;VALUE=DATE:%s", day)
+		line("SUMMARY:%s", icalText(e.Kind+": "+e.Post.Main.Metadata.Title))
+		line("DESCRIPTION:%s", icalText(e.Post.FilePath+"\n"+e.Post.Main.Metadata.Author))
+		if e.Kind == "published" || e.Kind == "scheduled" {
+			line("URL:%s", baseURL+pageURL(e.Post.Path))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.Bytes()
+}
+
+func report_calendar_main(args []string) {
+	fs := flag.NewFlagSet("report calendar", flag.ExitOnError)
+	past := fs.Int("past", 30, "list the posts published in this many past days")
+	ahead := fs.Int("ahead", 90, "list the posts scheduled or due for review in this many days ahead")
+	months := fs.Int("months", 12, "posts are due for review this many months after they were last reviewed or updated")
+	ical := fs.String("ical", "", "also write the calendar to this iCalendar file")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+	ds, err := initializeDatabase(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	events := contentCalendar(cfg, ds, today.AddDate(0, 0, -*past), today.AddDate(0, 0, *ahead+1), *months)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DATE\tEVENT\tAUTHOR\tFILE\tTITLE")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.Date.Format(time.DateOnly), e.Kind, e.Post.Main.Metadata.Author, e.Post.FilePath, e.Post.Main.Metadata.Title)
+	}
+	w.Flush()
+
+	if *ical != "" {
+		err = os.WriteFile(*ical, icalCalendar(events), 0644)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to write %s", *ical)
+		}
+	}
+	log.Info().Int("events", len(events)).Msgf("%d events from %s to %s", len(events),
+		today.AddDate(0, 0, -*past).Format(time.DateOnly), today.AddDate(0, 0, *ahead).Format(time.DateOnly))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+
+	"gosuda.org/website/internal/bibliography"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/markdown"
+	"gosuda.org/website/internal/types"
+)
+
+// bibliographyFile resolves the bibliography of a post. Paths starting with "/"
+// are site paths in the public directory; other paths are relative to the post file.
+func bibliographyFile(post *types.Post) (string, error) {
+	file := post.Main.Metadata.Bibliography
+	if strings.HasPrefix(file, "/") {
+		return publicPath(file), nil
+	}
+
+	src := filepath.Join(filepath.Dir(post.FilePath), filepath.FromSlash(file))
+	rel, err := filepath.Rel(rootDir, src)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("bibliography %s is outside of %s", file, rootDir)
+	}
+	return src, nil
+}
+
+// loadBibliography reads the bibliography of a post, nil if it has none.
+func loadBibliography(gc *GenerationContext, post *types.Post) (bibliography.Bibliography, error) {
+	if post.Main == nil || post.Main.Metadata.Bibliography == "" {
+		return nil, nil
+	}
+	file, err := bibliographyFile(post)
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := gc.Bibliographies[file]; ok {
+		return b, nil
+	}
+	b, err := bibliography.Load(file)
+	if err != nil {
+		return nil, err
+	}
+	if gc.Bibliographies == nil {
+		gc.Bibliographies = make(map[string]bibliography.Bibliography)
+	}
+	gc.Bibliographies[file] = b
+	return b, nil
+}
+
+// citations is the content transformer numbering the `[@key]` citations of
+// posts in order of first citation, linking them to the references of the
+// page, which are appended to it.
+type citations struct{}
+
+func (citations) Name() string { return "citations" }
+
+func (citations) TransformContent(gc *GenerationContext, post *types.Post, lang types.Lang, doc string) (string, error) {
+	bib, err := loadBibliography(gc, post)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", post.FilePath, err)
+	}
+
+	var cited []string
+	numbers := make(map[string]int)
+	var citeErr error
+	out, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		if t.Type != htmlrewrite.CommentToken || citeErr != nil {
+			return nil
+		}
+		cites, ok := markdown.ParseCitationComment(t.Data)
+		if !ok {
+			return nil
+		}
+
+		var b strings.Builder
+		b.WriteString(`<span class="citation">[`)
+		for i, c := range cites {
+			if bib == nil {
+				citeErr = fmt.Errorf("%s: citation of %q without a bibliography", post.FilePath, c.Key)
+				return nil
+			}
+			if bib[c.Key] == nil {
+				citeErr = fmt.Errorf("%s: citation of %q, which is not in the bibliography", post.FilePath, c.Key)
+				return nil
+			}
+			if numbers[c.Key] == 0 {
+				cited = append(cited, c.Key)
+				numbers[c.Key] = len(cited)
+			}
+			if i > 0 {
+				b.WriteString("; ")
+			}
+			fmt.Fprintf(&b, `<a href="#ref-%s" role="doc-biblioref">%d</a>`, html.EscapeString(c.Key), numbers[c.Key])
+			if c.Locator != "" {
+				b.WriteString(", " + html.EscapeString(c.Locator))
+			}
+		}
+		b.WriteString("]</span>")
+		return []byte(b.String())
+	})
+	if err != nil {
+		return "", err
+	}
+	if citeErr != nil || len(cited) == 0 {
+		return string(out), citeErr
+	}
+
+	l := gc.I18n.Localizer(lang)
+	var b strings.Builder
+	b.Write(out)
+	fmt.Fprintf(&b, "<section class=\"references\" role=\"doc-bibliography\" aria-labelledby=\"references\">\n<h2 id=\"references\">%s</h2>\n<ol>\n", html.EscapeString(l.T("References")))
+	for _, key := range cited {
+		fmt.Fprintf(&b, "<li id=\"ref-%s\">%s</li>\n", html.EscapeString(key), bib[key].HTML())
+	}
+	b.WriteString("</ol>\n</section>\n")
+	return b.String(), nil
+}
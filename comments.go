@@ -0,0 +1,60 @@
+package main
+
+import (
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// commentOrigins maps a comment provider to the origin its script and iframe are served from.
+var commentOrigins = map[string]string{
+	"giscus":     "https://giscus.app",
+	"utterances": "https://utteranc.es",
+}
+
+// giscusLang maps site languages to giscus locales where they differ.
+var giscusLang = map[types.Lang]string{
+	types.LangChinese: "zh-CN",
+}
+
+// viewComments returns the comment widget settings for post, or nil if comments are disabled.
+func viewComments(gc *GenerationContext, post *types.Post, lang types.Lang) *view.Comments {
+	cc := &gc.Config.Comments
+	if _, ok := commentOrigins[cc.Provider]; !ok || cc.Repo == "" {
+		return nil
+	}
+	if enabled := post.Main.Metadata.Comments; enabled != nil && !*enabled {
+		return nil
+	}
+
+	c := &view.Comments{
+		Provider:   cc.Provider,
+		Repo:       cc.Repo,
+		RepoID:     cc.RepoID,
+		Category:   cc.Category,
+		CategoryID: cc.CategoryID,
+		Label:      cc.Label,
+		Term:       post.ID,
+		Theme:      cc.Theme,
+		Lang:       lang,
+	}
+	if l, ok := giscusLang[lang]; ok && cc.Provider == "giscus" {
+		c.Lang = l
+	}
+	if c.Theme == "" {
+		c.Theme = "light"
+		if cc.Provider == "utterances" {
+			c.Theme = "github-light"
+		}
+	}
+	return c
+}
+
+// commentsPolicy returns the CSP sources the comment widget needs at runtime.
+// The widget script is found in the pages, but its iframe is created by the script.
+func commentsPolicy(cc *CommentsConfig) cspPolicy {
+	p := cspPolicy{}
+	if origin, ok := commentOrigins[cc.Provider]; ok {
+		p.add("frame-src", origin)
+	}
+	return p
+}
@@ -0,0 +1,966 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-jsonnet"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/ignore"
+	"gosuda.org/website/internal/types"
+)
+
+// SiteConfig is the site-wide configuration evaluated from config.jsonnet.
+type SiteConfig struct {
+	// BaseURL is the absolute URL the site is served at. A path, as in
+	// "https://example.com/blog", is prefixed to every site path of the
+	// generated files. (default: "https://gosuda.org")
+	BaseURL string `json:"base_url"`
+	// URLStyle is the form of page URLs: "clean" (/post), "html" (/post.html)
+	// or "slash" (/post/). (default: "clean")
+	URLStyle string `json:"url_style"`
+	// Environment is the build environment. (e.g. "production", default: "development")
+	Environment string `json:"environment"`
+	// Deploy configures the `deploy` command.
+	Deploy DeployConfig `json:"deploy"`
+	// Plugins configures the extension points of the generator.
+	Plugins PluginsConfig `json:"plugins"`
+	// Hooks are the commands run before and after building and before deploying.
+	Hooks HooksConfig `json:"hooks"`
+	// Workflow configures the editorial states of posts.
+	Workflow WorkflowConfig `json:"workflow"`
+	// Preview configures the preview links of the posts not published yet.
+	Preview PreviewConfig `json:"preview"`
+	// Hosting configures the generated _headers and _redirects files.
+	Hosting HostingConfig `json:"hosting"`
+	// CSP configures the generated Content-Security-Policy.
+	CSP CSPConfig `json:"csp"`
+	// SRI configures Subresource Integrity attributes.
+	SRI SRIConfig `json:"sri"`
+	// Bundle configures bundling of the scripts and stylesheets of public/.
+	Bundle BundleConfig `json:"bundle"`
+	// Hints configures the resource hints added to the generated pages.
+	Hints HintsConfig `json:"hints"`
+	// Fonts configures the webfonts subset to the characters of the site.
+	Fonts FontsConfig `json:"fonts"`
+	// Features configures the scripts and stylesheets loaded by the pages whose content needs them.
+	Features FeaturesConfig `json:"features"`
+	// Extras restricts the extra scripts, stylesheets and head markup of posts.
+	Extras ExtrasConfig `json:"extras"`
+	// PWA configures the web manifest, icon set and service worker.
+	PWA PWAConfig `json:"pwa"`
+	// Robots configures the generated robots.txt.
+	Robots RobotsConfig `json:"robots"`
+	// Security configures the generated /.well-known/security.txt.
+	Security SecurityConfig `json:"security"`
+	// Humans configures the generated humans.txt.
+	Humans HumansConfig `json:"humans"`
+	// Expiry configures how posts past their expiry date are handled.
+	Expiry ExpiryConfig `json:"expiry"`
+	// Duplicates configures the check for near-duplicate posts.
+	Duplicates DuplicatesConfig `json:"duplicates"`
+	// Weight configures the page weight budget.
+	Weight WeightConfig `json:"weight"`
+	// Spelling configures the `check spelling` command.
+	Spelling SpellingConfig `json:"spelling"`
+	// Translation configures machine translation.
+	Translation TranslationConfig `json:"translation"`
+	// Repository describes the source repository of the site.
+	Repository RepositoryConfig `json:"repository"`
+	// Comments configures the comment section of post pages.
+	Comments CommentsConfig `json:"comments"`
+	// Analytics configures the analytics snippets.
+	Analytics AnalyticsConfig `json:"analytics"`
+	// Images configures image processing.
+	Images ImagesConfig `json:"images"`
+	// Podcast configures the podcast feed of posts with an audio version.
+	Podcast PodcastConfig `json:"podcast"`
+	// Home configures the sections of the home page.
+	Home HomeConfig `json:"home"`
+	// API configures the static JSON content API under /api/.
+	API APIConfig `json:"api"`
+	// Search configures the prebuilt search indexes under /search/.
+	Search SearchConfig `json:"search"`
+	// Docs configures the documentation tree under root/docs.
+	Docs DocsConfig `json:"docs"`
+	// Releases configures the /releases page of project release notes.
+	Releases ReleasesConfig `json:"releases"`
+	// Projects configures the /projects page of GitHub repositories.
+	Projects ProjectsConfig `json:"projects"`
+	// CV configures the /cv page rendered from structured data.
+	CV CVConfig `json:"cv"`
+	// Glossary configures the glossary page, which the first occurrence of each term in a post links to.
+	Glossary GlossaryConfig `json:"glossary"`
+	// Acronyms configures the expansions of the acronyms in posts.
+	Acronyms AcronymsConfig `json:"acronyms"`
+	// Text configures the text-only variants of posts.
+	Text TextConfig `json:"text"`
+	// Series describes the post series, keyed by the name used in post front matter.
+	Series map[string]SeriesConfig `json:"series"`
+	// Notion configures the `sync notion` command.
+	Notion NotionConfig `json:"notion"`
+	// CrossPost configures the `crosspost` command.
+	CrossPost CrossPostConfig `json:"crosspost"`
+	// Announce configures announcing new posts on social media.
+	Announce AnnounceConfig `json:"announce"`
+	// Archive configures archive.org snapshots of the outbound links of posts.
+	Archive ArchiveConfig `json:"archive"`
+}
+
+// DeployConfig describes where the generated dist directory is published.
+type DeployConfig struct {
+	// Target is the deploy target. (e.g. "github-pages")
+	Target string `json:"target"`
+	// Remote is the git remote name or repository URL to push to. (default: "origin")
+	Remote string `json:"remote"`
+	// Branch is the branch that receives the generated site. (default: "gh-pages")
+	Branch string `json:"branch"`
+	// CNAME is the custom domain written to dist/CNAME (optional).
+	CNAME string `json:"cname"`
+}
+
+// PluginsConfig configures the plugins of the generator, see plugins.go.
+type PluginsConfig struct {
+	// Disabled lists the names of the plugins that are not registered. (e.g. "podcast")
+	Disabled []string `json:"disabled"`
+	// Exec lists the out-of-process plugins, started in order after the
+	// built-in ones, see execplugins.go.
+	Exec []ExecPluginConfig `json:"exec"`
+}
+
+// ExecPluginConfig describes a plugin run as a separate program.
+type ExecPluginConfig struct {
+	// Name identifies the plugin, as the deploy target if it is one.
+	Name string `json:"name"`
+	// Command is the program and its arguments. (e.g. ["python3", "plugins/toc.py"])
+	Command []string `json:"command"`
+	// Options are passed to the plugin when it starts.
+	Options map[string]any `json:"options"`
+}
+
+// HooksConfig lists the shell commands run at each hook point, in order. They
+// get the hook name in SITE_HOOK, the absolute path of dist in SITE_DIST, the
+// base URL in SITE_BASE_URL and the changed source files, one per line, in
+// SITE_CHANGED_FILES. A failing command stops the build or the deploy.
+type HooksConfig struct {
+	// Prebuild runs before the website is generated, with no changed files yet.
+	Prebuild []string `json:"prebuild"`
+	// Postbuild runs after the website is generated and the database is saved.
+	Postbuild []string `json:"postbuild"`
+	// Predeploy runs before dist is deployed, with SITE_DEPLOY_TARGET set and
+	// the changed files of the build being deployed.
+	Predeploy []string `json:"predeploy"`
+}
+
+// WorkflowConfig configures the editorial states posts go through, set by the
+// status front matter field.
+type WorkflowConfig struct {
+	// States are the states of posts, in order. Only "published" posts reach
+	// dist, the others are served as previews. (default: ["draft", "review", "published"])
+	States []string `json:"states"`
+	// Review are the states `report review` lists as awaiting review. (default: ["review"])
+	Review []string `json:"review"`
+	// Labels maps states to the labels `sync labels` sets on the pull requests
+	// changing posts in them. (default: "status: <state>")
+	Labels map[string]string `json:"labels"`
+	// Repo is the GitHub repository ("owner/name") of the pull requests.
+	Repo string `json:"repo"`
+	// Token is a GitHub token allowed to label pull requests.
+	Token string `json:"token"`
+}
+
+// PreviewConfig configures the pages of the posts not published yet, drafts,
+// posts in review and future posts, which are rendered out of dist and served
+// by `serve` at signed /preview/<token> links.
+type PreviewConfig struct {
+	// Secret signs the preview links. Without it, unpublished posts are not
+	// rendered. Changing it revokes the links shared before.
+	Secret string `json:"secret"`
+}
+
+// HostingConfig declares static hosting behavior (Cloudflare Pages, Netlify).
+type HostingConfig struct {
+	// Provider selects the redirect file dialect. ("cloudflare" or "netlify")
+	Provider string `json:"provider"`
+	// Headers are added to every response.
+	Headers map[string]string `json:"headers"`
+	// Cache is a list of Cache-Control policies, matched by path pattern.
+	// Hosts merge the headers of every matching rule, so patterns should not overlap.
+	Cache []CacheRule `json:"cache"`
+	// Redirects is a list of static redirects in addition to post aliases.
+	Redirects []Redirect `json:"redirects"`
+	// LanguageRedirects redirects "/" to the visitor's language home page. (netlify only)
+	LanguageRedirects bool `json:"language_redirects"`
+}
+
+// CacheRule applies a Cache-Control header to paths matching Pattern. (e.g. "/assets/*")
+type CacheRule struct {
+	Pattern      string `json:"pattern"`
+	CacheControl string `json:"cache_control"`
+}
+
+// Redirect is a single redirect rule. Status defaults to 301.
+type Redirect struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Status int    `json:"status,omitempty"`
+}
+
+// CSPConfig controls Content-Security-Policy generation.
+// The policy is computed from the scripts and styles the build actually emits.
+type CSPConfig struct {
+	// Enabled turns on CSP generation.
+	Enabled bool `json:"enabled"`
+	// Meta injects a per-page <meta http-equiv="Content-Security-Policy"> tag.
+	Meta bool `json:"meta"`
+	// Header adds the site-wide policy to the generated _headers file.
+	Header bool `json:"header"`
+	// Extra lists additional sources per directive, for resources loaded at runtime.
+	Extra map[string][]string `json:"extra"`
+}
+
+// SRIConfig controls Subresource Integrity generation.
+type SRIConfig struct {
+	// Enabled adds integrity attributes to local scripts and stylesheets.
+	Enabled bool `json:"enabled"`
+	// Pinned is a list of URL prefixes of immutable (versioned) CDN assets that are hashed too.
+	Pinned []string `json:"pinned"`
+}
+
+// BundleConfig controls bundling with esbuild. Each entry point is bundled,
+// minified and written under a fingerprinted name, and references to it are
+// rewritten in the generated pages.
+type BundleConfig struct {
+	// Entries are the site paths of the entry points in public/. (default: "/main.js", "/main.css")
+	Entries []string `json:"entries"`
+	// SourceMaps writes a linked source map next to each bundle.
+	SourceMaps bool `json:"source_maps"`
+	// Purge removes the rules of classes used neither by the generated pages
+	// nor by the templates and scripts from the bundled stylesheets.
+	Purge bool `json:"purge"`
+	// Safelist are classes kept by the purge, such as ones built by scripts at runtime.
+	Safelist []string `json:"safelist"`
+}
+
+// HintsConfig controls the resource hints added to each page: preloads of its
+// web fonts, stylesheets and first image, and preconnects to the origins of its
+// external resources. Post pages also prefetch the previous and next post.
+type HintsConfig struct {
+	// Enabled turns on resource hints.
+	Enabled bool `json:"enabled"`
+	// Preconnect is the maximum number of origins preconnected by a page. (default: 4)
+	Preconnect int `json:"preconnect"`
+}
+
+// FontsConfig controls the webfonts. Each face is subset to the characters of
+// the generated pages and written as a fingerprinted WOFF2 file, and a stylesheet
+// of their @font-face rules is linked from every page.
+type FontsConfig struct {
+	// Faces are the font faces, in the order of their @font-face rules.
+	Faces []FontFaceConfig `json:"faces"`
+	// Dir is the site directory of the fonts and their stylesheet. (default: "/assets/fonts")
+	Dir string `json:"dir"`
+	// Characters are kept in addition to those of the pages and printable ASCII,
+	// such as the characters of text added by scripts.
+	Characters string `json:"characters"`
+}
+
+// FontFaceConfig is a face of a font family.
+type FontFaceConfig struct {
+	// Family is the font-family name of the face.
+	Family string `json:"family"`
+	// Source is the TrueType font of the face, relative to the repository root.
+	Source string `json:"source"`
+	// Weight is the font-weight of the face. (default: "400")
+	Weight string `json:"weight"`
+	// Style is the font-style of the face. (default: "normal")
+	Style string `json:"style"`
+}
+
+// FeaturesConfig lists the scripts and stylesheets of each kind of content,
+// loaded only by the pages of documents with such content.
+type FeaturesConfig struct {
+	// Code is loaded by pages with code blocks.
+	Code FeatureConfig `json:"code"`
+	// Math is loaded by pages with ```math blocks, rendered as <div class="math">.
+	Math FeatureConfig `json:"math"`
+	// Mermaid is loaded by pages with ```mermaid blocks, rendered as <pre class="mermaid">.
+	Mermaid FeatureConfig `json:"mermaid"`
+	// Gallery is loaded by pages with gallery shortcodes.
+	Gallery FeatureConfig `json:"gallery"`
+	// Tabs is loaded by pages with :::tabs groups.
+	Tabs FeatureConfig `json:"tabs"`
+}
+
+// FeatureConfig is the scripts and stylesheets of a kind of content.
+type FeatureConfig struct {
+	// Scripts are loaded deferred, in order, after main.js.
+	Scripts []string `json:"scripts"`
+	// Styles are linked after main.css.
+	Styles []string `json:"styles"`
+	// CSP are the sources the feature needs added to the Content-Security-Policy,
+	// such as the origin of the fonts of a stylesheet.
+	CSP map[string][]string `json:"csp"`
+}
+
+// ExtrasConfig restricts the extra_css, extra_js and head_html front matter of
+// posts. Site paths must exist in public/; URLs must be https and of an allowed origin.
+type ExtrasConfig struct {
+	// Origins are the origins extra scripts and stylesheets may be loaded from. (e.g. "https://cdn.jsdelivr.net")
+	Origins []string `json:"origins"`
+	// HeadElements are the elements allowed in head_html. (default: "link", "meta", "style")
+	HeadElements []string `json:"head_elements"`
+}
+
+// PWAConfig controls Progressive Web App support.
+type PWAConfig struct {
+	// Enabled turns on web manifest, icon and service worker generation.
+	Enabled bool `json:"enabled"`
+	// Name and ShortName are the application names shown when installed.
+	Name      string `json:"name"`
+	ShortName string `json:"short_name"`
+	// Description is the application description in the web manifest.
+	Description string `json:"description"`
+	// Icon is the source image the icon set is generated from. (square, >= 512px)
+	Icon string `json:"icon"`
+	// ThemeColor and BackgroundColor are the manifest colors.
+	ThemeColor      string `json:"theme_color"`
+	BackgroundColor string `json:"background_color"`
+	// PrecachePosts is the number of recent posts the service worker caches for offline reading.
+	PrecachePosts int `json:"precache_posts"`
+}
+
+// RobotsConfig describes the generated robots.txt.
+type RobotsConfig struct {
+	// Rules are the user-agent groups, written in order.
+	Rules []RobotsRule `json:"rules"`
+}
+
+// RobotsRule is a single robots.txt group.
+type RobotsRule struct {
+	// UserAgents are the crawlers the group applies to.
+	UserAgents []string `json:"user_agents"`
+	// Allow and Disallow are the path rules of the group.
+	Allow    []string `json:"allow"`
+	Disallow []string `json:"disallow"`
+}
+
+// SecurityConfig describes the generated security.txt. (RFC 9116)
+type SecurityConfig struct {
+	// Contact is a list of URIs (mailto:, https:) for reporting vulnerabilities.
+	Contact []string `json:"contact"`
+	// Encryption is the URL of the PGP key reporters should use.
+	Encryption string `json:"encryption"`
+	// Policy is the URL of the vulnerability disclosure policy.
+	Policy string `json:"policy"`
+	// PreferredLanguages is a list of languages reports are accepted in.
+	PreferredLanguages []string `json:"preferred_languages"`
+	// ExpiresInDays is how far from the build date the Expires field is set. (default: 365)
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+// HumansConfig describes the generated humans.txt.
+type HumansConfig struct {
+	// Team is the list of people behind the site. Post authors are listed automatically.
+	Team []HumansMember `json:"team"`
+	// Thanks is a list of people or projects to thank.
+	Thanks []string `json:"thanks"`
+	// Standards, Components and Software are listed in the SITE section.
+	Standards  []string `json:"standards"`
+	Components []string `json:"components"`
+	Software   []string `json:"software"`
+}
+
+// HumansMember is a team member listed in humans.txt.
+type HumansMember struct {
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	Contact  string `json:"contact"`
+	Location string `json:"location"`
+}
+
+// ExpiryConfig controls what happens to posts past their expiry date.
+type ExpiryConfig struct {
+	// Action is "banner" to keep expired posts with an outdated content notice,
+	// or "unpublish" to stop rendering them. (default: "banner")
+	Action string `json:"action"`
+}
+
+// DuplicatesConfig configures the near-duplicate check run by `generate`.
+type DuplicatesConfig struct {
+	// Threshold is the estimated similarity, from 0 to 1, at which two posts are near-duplicates. (default: 0.8)
+	Threshold float64 `json:"threshold"`
+	// Fail fails the build when near-duplicates are found, instead of only warning.
+	Fail bool `json:"fail"`
+}
+
+// WeightConfig configures the page weight check run by `generate`. The weight of a
+// page is the size of its HTML and of the local images, scripts and stylesheets it references.
+type WeightConfig struct {
+	// Budget is the maximum page weight in KiB. (default: 1024)
+	Budget int64 `json:"budget"`
+	// Fail fails the build when pages are over budget, instead of only warning.
+	Fail bool `json:"fail"`
+}
+
+// SpellingConfig configures spellchecking with hunspell dictionaries.
+type SpellingConfig struct {
+	// Dictionaries maps languages ("en", "ko") to hunspell dictionary paths
+	// without the .aff and .dic extensions. (default: "dictionaries/en_US", "dictionaries/ko_KR")
+	Dictionaries map[string]string `json:"dictionaries"`
+	// Allowlist is a file of correctly spelled words missing from the dictionaries, one per line. (default: "spelling.txt")
+	Allowlist string `json:"allowlist"`
+}
+
+// TranslationConfig configures machine translation of posts.
+type TranslationConfig struct {
+	// Glossary is a YAML file of protected terms and approved term translations,
+	// which translations are instructed with and checked against. (default: "translation-glossary.yaml")
+	Glossary string `json:"glossary"`
+}
+
+// RepositoryConfig points at the repository the site is built from.
+type RepositoryConfig struct {
+	// URL is the web URL of the repository. (e.g. "https://github.com/gosuda/website")
+	URL string `json:"url"`
+	// Branch is the branch edits are proposed against. (default: "main")
+	Branch string `json:"branch"`
+}
+
+// CommentsConfig configures a GitHub backed comment system. Threads are mapped by post ID.
+type CommentsConfig struct {
+	// Provider is "giscus", "utterances" or "" to disable comments.
+	Provider string `json:"provider"`
+	// Repo is the GitHub repository ("owner/name") that stores the discussions or issues.
+	Repo string `json:"repo"`
+	// RepoID, Category and CategoryID are the giscus discussion settings. (see https://giscus.app)
+	RepoID     string `json:"repo_id"`
+	Category   string `json:"category"`
+	CategoryID string `json:"category_id"`
+	// Label is the issue label used by utterances. (optional)
+	Label string `json:"label"`
+	// Theme is the widget theme. (default: "light" for giscus, "github-light" for utterances)
+	Theme string `json:"theme"`
+}
+
+// AnalyticsConfig configures analytics providers.
+type AnalyticsConfig struct {
+	// Environments lists the environments tracking code is emitted in.
+	Environments []string `json:"environments"`
+	// Consent is "none" to always load the trackers, or "dnt" to skip them for
+	// visitors sending Do Not Track or Global Privacy Control. (default: "none")
+	Consent string `json:"consent"`
+	// Providers are the analytics services to load.
+	Providers []AnalyticsProvider `json:"providers"`
+}
+
+// AnalyticsProvider is a single analytics service.
+type AnalyticsProvider struct {
+	// Name is "plausible", "goatcounter", "ga4" or "cloudflare".
+	Name string `json:"name"`
+	// ID is the site domain (plausible), site code (goatcounter), measurement ID (ga4) or token (cloudflare).
+	ID string `json:"id"`
+	// Script overrides the script URL, e.g. for a self-hosted instance. (optional)
+	Script string `json:"script"`
+}
+
+// ImagesConfig controls image processing.
+type ImagesConfig struct {
+	// StripMetadata removes EXIF (GPS, camera, ...), XMP and IPTC metadata from images in dist.
+	StripMetadata bool `json:"strip_metadata"`
+	// KeepEXIF lists EXIF tags that are preserved. (e.g. "Artist", "Copyright")
+	KeepEXIF []string `json:"keep_exif"`
+	// DarkVariants generates the dark mode variant of post images marked #light
+	// that have no #dark pair, and the reverse, by inverting their lightness.
+	DarkVariants bool `json:"dark_variants"`
+}
+
+// PodcastConfig describes the iTunes compatible podcast feed.
+type PodcastConfig struct {
+	// Enabled turns on podcast.rss generation.
+	Enabled bool `json:"enabled"`
+	// Title, Description and Author describe the show.
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Author      string `json:"author"`
+	// Email is the owner contact required by podcast directories.
+	Email string `json:"email"`
+	// Image is the site path of the show artwork. (square, 1400-3000px)
+	Image string `json:"image"`
+	// Category is an Apple Podcasts category. (e.g. "Technology")
+	Category string `json:"category"`
+	// Language is the language of the show. (default: "en")
+	Language string `json:"language"`
+	// Explicit marks the show as containing explicit content.
+	Explicit bool `json:"explicit"`
+}
+
+// HomeConfig describes the home page as a list of sections.
+type HomeConfig struct {
+	// Sections are rendered in order. (default: featured, latest)
+	Sections []HomeSectionConfig `json:"sections"`
+}
+
+// HomeSectionConfig is a section of the home page.
+type HomeSectionConfig struct {
+	// Type is the kind of section. ("hero", "featured", "latest" or "projects")
+	Type string `json:"type"`
+	// Title is the heading of the section (optional). It is translated with the UI messages.
+	Title string `json:"title"`
+	// Text is the text of a hero section. It is translated with the UI messages.
+	Text string `json:"text"`
+	// Count is the number of posts of a latest section. (default: 16)
+	Count int `json:"count"`
+	// Data is the YAML file the cards of a projects section are read from. (default: "data/projects.yaml")
+	Data string `json:"data"`
+}
+
+// APIConfig configures the static JSON content API.
+type APIConfig struct {
+	// Enabled turns on generation of dist/api/.
+	Enabled bool `json:"enabled"`
+	// PageSize is the number of posts per index page. (default: 20)
+	PageSize int `json:"page_size"`
+}
+
+// SearchConfig configures the prebuilt search indexes.
+type SearchConfig struct {
+	// Enabled turns on generation of dist/search/: a Lunr index of the pages
+	// of each language and the titles, descriptions and tags of its documents,
+	// and of the /search/ page of each language.
+	Enabled bool `json:"enabled"`
+	// Scripts are loaded by the search pages before search.js, starting with
+	// lunr.js.
+	Scripts []string `json:"scripts"`
+	// LanguageScripts are loaded after Scripts by the search pages of the
+	// languages stemmed by lunr-languages, with {lang} replaced by the language.
+	LanguageScripts []string `json:"language_scripts"`
+}
+
+// DocsConfig configures the documentation tree.
+type DocsConfig struct {
+	// Versions are the version directories of root/docs, oldest first, each
+	// published under /docs/<version>. (e.g. ["v1", "v2"], default: unversioned)
+	Versions []string `json:"versions"`
+	// Latest is the current version, aliased as /docs/latest. (default: the last of Versions)
+	Latest string `json:"latest"`
+}
+
+// ReleasesConfig configures the release notes page and feed.
+type ReleasesConfig struct {
+	// Enabled turns on generation of /releases and /releases/feed.rss.
+	Enabled bool `json:"enabled"`
+	// Repositories are the projects whose releases are published.
+	Repositories []ReleaseSource `json:"repositories"`
+	// Token is a GitHub token for the Releases API (optional, raises the rate limit).
+	Token string `json:"token"`
+	// Limit is the number of releases shown per repository. (default: 10)
+	Limit int `json:"limit"`
+}
+
+// ReleaseSource is a project whose releases are read from GitHub or from the
+// annotated tags of a local git repository.
+type ReleaseSource struct {
+	// Name is the display name of the project. (default: the repository)
+	Name string `json:"name"`
+	// GitHub is the "owner/name" of a GitHub repository whose releases are fetched.
+	GitHub string `json:"github"`
+	// Git is the path of a local git repository whose annotated tags are read. (e.g. ".")
+	Git string `json:"git"`
+	// URL links the project (optional).
+	URL string `json:"url"`
+}
+
+// ProjectsConfig configures the projects page.
+type ProjectsConfig struct {
+	// Enabled turns on generation of /projects.
+	Enabled bool `json:"enabled"`
+	// Repositories are the "owner/name" of the GitHub repositories listed, in order.
+	Repositories []string `json:"repositories"`
+	// Token is a GitHub token for the API (optional, raises the rate limit).
+	Token string `json:"token"`
+	// TTL is how long fetched repository metadata is reused. (default: "24h")
+	TTL string `json:"ttl"`
+}
+
+// CVConfig configures the CV page.
+type CVConfig struct {
+	// Enabled turns on generation of /cv.
+	Enabled bool `json:"enabled"`
+	// Data is the YAML file of the CV. (default: "data/cv.yaml")
+	Data string `json:"data"`
+	// PDF also renders the CV to /cv.pdf with headless chromium.
+	PDF bool `json:"pdf"`
+}
+
+// GlossaryConfig configures the glossary page.
+type GlossaryConfig struct {
+	// Enabled turns on generation of /glossary and the links of posts to it.
+	Enabled bool `json:"enabled"`
+	// Data is the YAML file of the terms and their definitions. (default: "data/glossary.yaml")
+	Data string `json:"data"`
+}
+
+// AcronymsConfig configures the expansions of acronyms, which the acronyms in
+// the text of posts are marked up with for screen readers and search engines.
+type AcronymsConfig struct {
+	// Enabled turns on wrapping the acronyms in <abbr> elements.
+	Enabled bool `json:"enabled"`
+	// Terms maps acronyms to their expansions, matched as whole words with their case. (e.g. {"GC": "garbage collector"})
+	Terms map[string]string `json:"terms"`
+}
+
+// TextConfig configures the text-only variants of posts under /txt, for slow
+// connections and reader-mode and LLM consumers.
+type TextConfig struct {
+	// Enabled turns on generation of the /txt pages.
+	Enabled bool `json:"enabled"`
+}
+
+// SeriesConfig describes a series of posts.
+type SeriesConfig struct {
+	// Title is the display title of the series. (default: the series name)
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Author      string `json:"author"`
+	// Image is the site path of the series cover image, relative to the public directory. (optional)
+	Image string `json:"image"`
+}
+
+// NotionConfig describes the Notion database synced into the content directory.
+type NotionConfig struct {
+	// Token is the secret of a Notion integration with access to the database.
+	Token string `json:"token"`
+	// DatabaseID is the ID of the database whose pages are synced as posts.
+	DatabaseID string `json:"database_id"`
+	// Dir is the directory under root/ that synced posts are written to. (default: "notion")
+	Dir string `json:"dir"`
+	// Properties names the database properties mapped to post metadata.
+	Properties NotionProperties `json:"properties"`
+}
+
+// NotionProperties are database property names. Properties missing from the database are ignored.
+type NotionProperties struct {
+	// Slug is a text property used as the file name. (default: "Slug", falls back to the title)
+	Slug string `json:"slug"`
+	// Description is a text property. (default: "Description")
+	Description string `json:"description"`
+	// Tags is a multi-select property. (default: "Tags")
+	Tags string `json:"tags"`
+	// Date is a date property. (default: "Date", falls back to the creation time of the page)
+	Date string `json:"date"`
+	// Author is a text or people property. (default: "Author")
+	Author string `json:"author"`
+	// Series is a select or text property. (default: "Series")
+	Series string `json:"series"`
+	// Published is a checkbox property; unchecked pages are synced as hidden posts. (default: "Published")
+	Published string `json:"published"`
+}
+
+// CrossPostConfig configures republishing posts on other platforms with a
+// canonical link back to the site.
+type CrossPostConfig struct {
+	// OnBuild publishes the new and changed posts at the end of `generate`,
+	// otherwise they are published by `crosspost`.
+	OnBuild bool `json:"on_build"`
+	// Since limits cross-posting to posts dated on or after this day. (e.g. "2025-01-01", optional)
+	Since string `json:"since"`
+	// DevTo configures publishing to dev.to.
+	DevTo DevToConfig `json:"devto"`
+	// Medium configures publishing to Medium.
+	Medium MediumConfig `json:"medium"`
+}
+
+type DevToConfig struct {
+	Enabled bool `json:"enabled"`
+	// APIKey is a dev.to API key. (Settings > Extensions)
+	APIKey string `json:"api_key"`
+	// Published publishes articles right away; otherwise they are created as drafts.
+	Published bool `json:"published"`
+}
+
+type MediumConfig struct {
+	Enabled bool `json:"enabled"`
+	// Token is a Medium integration token.
+	Token string `json:"token"`
+	// PublishStatus is the status of new posts. ("public", "unlisted" or "draft", default: "draft")
+	PublishStatus string `json:"publish_status"`
+}
+
+// AnnounceConfig configures announcing posts on Mastodon and Bluesky. Posts
+// that become visible in a build are queued and posted by `announce`, or right
+// after the build with OnBuild.
+type AnnounceConfig struct {
+	// Enabled queues the posts published by each build for announcement.
+	Enabled bool `json:"enabled"`
+	// OnBuild posts the queued announcements at the end of `generate`.
+	OnBuild bool `json:"on_build"`
+	// Template is the text/template of announcements, given .Title, .Description, .URL, .Tags and .Hashtags.
+	Template string `json:"template"`
+	// Mastodon configures posting to a Mastodon account.
+	Mastodon MastodonConfig `json:"mastodon"`
+	// Bluesky configures posting to a Bluesky account.
+	Bluesky BlueskyConfig `json:"bluesky"`
+}
+
+// ArchiveConfig configures archive.org snapshots of the outbound links of
+// posts, taken with the Save Page Now API.
+type ArchiveConfig struct {
+	// Enabled adds a link to the snapshot after the outbound links that have one.
+	Enabled bool `json:"enabled"`
+	// OnBuild requests the missing snapshots at the end of `generate`, otherwise
+	// they are requested by `archive`.
+	OnBuild bool `json:"on_build"`
+	// AccessKey and Secret are archive.org S3 keys (optional, raise the rate limit).
+	AccessKey string `json:"access_key"`
+	Secret    string `json:"secret"`
+	// Exclude are the hosts whose links are not archived, with their subdomains. (e.g. "github.com")
+	Exclude []string `json:"exclude"`
+	// Limit is the number of snapshots requested per run. (default: 20)
+	Limit int `json:"limit"`
+}
+
+type MastodonConfig struct {
+	Enabled bool `json:"enabled"`
+	// Server is the URL of the Mastodon instance. (e.g. "https://mastodon.social")
+	Server string `json:"server"`
+	// Token is an access token with the write:statuses scope.
+	Token string `json:"token"`
+	// Visibility of the statuses. ("public", "unlisted" or "private", default: "public")
+	Visibility string `json:"visibility"`
+	// Template overrides the announcement template. (optional)
+	Template string `json:"template"`
+}
+
+type BlueskyConfig struct {
+	Enabled bool `json:"enabled"`
+	// Service is the URL of the PDS of the account. (default: "https://bsky.social")
+	Service string `json:"service"`
+	// Handle is the handle of the account. (e.g. "gosuda.bsky.social")
+	Handle string `json:"handle"`
+	// AppPassword is an app password of the account. (Settings > App Passwords)
+	AppPassword string `json:"app_password"`
+	// Template overrides the announcement template. (optional)
+	Template string `json:"template"`
+}
+
+func loadConfig(path string) (*SiteConfig, error) {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+	envJSON, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtVar("env", string(envJSON))
+
+	log.Debug().Str("path", path).Msgf("evaluating config file %s", path)
+	out, err := vm.EvaluateFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg SiteConfig
+	err = json.Unmarshal([]byte(out), &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = baseURL
+	}
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("base_url: %q is not an absolute http(s) URL", cfg.BaseURL)
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	baseURL = cfg.BaseURL
+	pathPrefix = strings.TrimSuffix(u.Path, "/")
+
+	switch cfg.URLStyle {
+	case "":
+		cfg.URLStyle = urlStyleClean
+	case urlStyleClean, urlStyleHTML, urlStyleSlash:
+	default:
+		return nil, fmt.Errorf("url_style: unknown style %q", cfg.URLStyle)
+	}
+	urlStyle = cfg.URLStyle
+
+	siteIgnore, err = ignore.Load(siteIgnoreFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", siteIgnoreFile, err)
+	}
+
+	if cfg.Environment == "" {
+		cfg.Environment = "development"
+	}
+	if cfg.Analytics.Consent == "" {
+		cfg.Analytics.Consent = "none"
+	}
+	if cfg.Deploy.Target == "" {
+		cfg.Deploy.Target = "github-pages"
+	}
+	if cfg.Deploy.Remote == "" {
+		cfg.Deploy.Remote = "origin"
+	}
+	if cfg.Deploy.Branch == "" {
+		cfg.Deploy.Branch = "gh-pages"
+	}
+	if len(cfg.Workflow.States) == 0 {
+		cfg.Workflow.States = []string{"draft", "review", types.StatusPublished}
+	}
+	if !slices.Contains(cfg.Workflow.States, types.StatusPublished) {
+		return nil, fmt.Errorf("workflow.states: %q is missing", types.StatusPublished)
+	}
+	if cfg.Workflow.Review == nil {
+		cfg.Workflow.Review = []string{"review"}
+	}
+	for _, state := range cfg.Workflow.Review {
+		if !slices.Contains(cfg.Workflow.States, state) {
+			return nil, fmt.Errorf("workflow.review: unknown state %q", state)
+		}
+	}
+	if cfg.Workflow.Labels == nil {
+		cfg.Workflow.Labels = make(map[string]string)
+	}
+	for _, state := range cfg.Workflow.States {
+		if cfg.Workflow.Labels[state] == "" {
+			cfg.Workflow.Labels[state] = "status: " + state
+		}
+	}
+	if cfg.Hosting.Provider == "" {
+		cfg.Hosting.Provider = "cloudflare"
+	}
+	if cfg.Archive.Limit <= 0 {
+		cfg.Archive.Limit = 20
+	}
+	if cfg.API.PageSize <= 0 {
+		cfg.API.PageSize = 20
+	}
+	if cfg.CrossPost.Medium.PublishStatus == "" {
+		cfg.CrossPost.Medium.PublishStatus = "draft"
+	}
+	if cfg.Announce.Template == "" {
+		cfg.Announce.Template = "{{.Title}}\n\n{{.Description}}\n\n{{.URL}}"
+	}
+	if cfg.Announce.Mastodon.Visibility == "" {
+		cfg.Announce.Mastodon.Visibility = "public"
+	}
+	if cfg.Announce.Bluesky.Service == "" {
+		cfg.Announce.Bluesky.Service = "https://bsky.social"
+	}
+	if cfg.Notion.Dir == "" {
+		cfg.Notion.Dir = "notion"
+	}
+	props := &cfg.Notion.Properties
+	for _, p := range []struct {
+		field *string
+		name  string
+	}{
+		{&props.Slug, "Slug"}, {&props.Description, "Description"}, {&props.Tags, "Tags"}, {&props.Date, "Date"},
+		{&props.Author, "Author"}, {&props.Series, "Series"}, {&props.Published, "Published"},
+	} {
+		if *p.field == "" {
+			*p.field = p.name
+		}
+	}
+	if cfg.Podcast.Language == "" {
+		cfg.Podcast.Language = "en"
+	}
+	if len(cfg.Home.Sections) == 0 {
+		cfg.Home.Sections = []HomeSectionConfig{{Type: "featured"}, {Type: "latest"}}
+	}
+	for i := range cfg.Home.Sections {
+		s := &cfg.Home.Sections[i]
+		if s.Count <= 0 {
+			s.Count = 16
+		}
+		if s.Data == "" {
+			s.Data = "data/projects.yaml"
+		}
+	}
+	if cfg.Docs.Latest == "" && len(cfg.Docs.Versions) > 0 {
+		cfg.Docs.Latest = cfg.Docs.Versions[len(cfg.Docs.Versions)-1]
+	}
+	if cfg.Projects.TTL == "" {
+		cfg.Projects.TTL = "24h"
+	}
+	if _, err := time.ParseDuration(cfg.Projects.TTL); err != nil {
+		return nil, fmt.Errorf("projects.ttl: %w", err)
+	}
+	if cfg.CV.Data == "" {
+		cfg.CV.Data = "data/cv.yaml"
+	}
+	if cfg.Glossary.Data == "" {
+		cfg.Glossary.Data = "data/glossary.yaml"
+	}
+	if cfg.Releases.Limit <= 0 {
+		cfg.Releases.Limit = 10
+	}
+	for i := range cfg.Releases.Repositories {
+		r := &cfg.Releases.Repositories[i]
+		if r.Name == "" {
+			r.Name = cmp.Or(r.GitHub, filepath.Base(r.Git))
+		}
+		if r.URL == "" && r.GitHub != "" {
+			r.URL = "https://github.com/" + r.GitHub
+		}
+	}
+	if cfg.Repository.Branch == "" {
+		cfg.Repository.Branch = "main"
+	}
+	if cfg.Duplicates.Threshold <= 0 {
+		cfg.Duplicates.Threshold = 0.8
+	}
+	if cfg.Hints.Preconnect <= 0 {
+		cfg.Hints.Preconnect = 4
+	}
+	if cfg.Fonts.Dir == "" {
+		cfg.Fonts.Dir = "/assets/fonts"
+	}
+	for i := range cfg.Fonts.Faces {
+		if cfg.Fonts.Faces[i].Weight == "" {
+			cfg.Fonts.Faces[i].Weight = "400"
+		}
+		if cfg.Fonts.Faces[i].Style == "" {
+			cfg.Fonts.Faces[i].Style = "normal"
+		}
+	}
+	if cfg.Extras.HeadElements == nil {
+		cfg.Extras.HeadElements = []string{"link", "meta", "style"}
+	}
+	if cfg.Bundle.Entries == nil {
+		cfg.Bundle.Entries = []string{"/main.js", "/main.css"}
+	}
+	if cfg.Weight.Budget <= 0 {
+		cfg.Weight.Budget = 1024
+	}
+	if cfg.Spelling.Dictionaries == nil {
+		cfg.Spelling.Dictionaries = map[string]string{"en": "dictionaries/en_US", "ko": "dictionaries/ko_KR"}
+	}
+	if cfg.Spelling.Allowlist == "" {
+		cfg.Spelling.Allowlist = "spelling.txt"
+	}
+	if cfg.Translation.Glossary == "" {
+		cfg.Translation.Glossary = "translation-glossary.yaml"
+	}
+	if cfg.Expiry.Action == "" {
+		cfg.Expiry.Action = "banner"
+	}
+	if cfg.Security.ExpiresInDays == 0 {
+		cfg.Security.ExpiresInDays = 365
+	}
+
+	return &cfg, nil
+}
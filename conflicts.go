@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// pathConflict is a page file that several posts resolve to.
+type pathConflict struct {
+	file  string
+	paths []string
+	files []string
+}
+
+// findPathConflicts returns the page files that more than one published post
+// is written to, in any language. Each set of posts is reported once.
+func findPathConflicts(gc *GenerationContext) []pathConflict {
+	type owner struct{ path, file string }
+	owners := make(map[string][]owner)
+	for _, post := range publishedPosts(gc) {
+		seen := make(map[string]bool)
+		paths := []string{post.Path}
+		for lang := range post.Translated {
+			paths = append(paths, "/"+lang+post.Path)
+		}
+		for _, p := range paths {
+			fp := pageFile(p)
+			if seen[fp] {
+				continue
+			}
+			seen[fp] = true
+			owners[fp] = append(owners[fp], owner{path: p, file: post.FilePath})
+		}
+	}
+
+	var conflicts []pathConflict
+	for fp, list := range owners {
+		if len(list) < 2 {
+			continue
+		}
+		c := pathConflict{file: fp}
+		for _, o := range list {
+			c.paths = append(c.paths, o.path)
+			c.files = append(c.files, o.file)
+		}
+		sort.Strings(c.files)
+		conflicts = append(conflicts, c)
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].file < conflicts[j].file
+	})
+
+	// posts sharing a path conflict in every language, which is reported once
+	seen := make(map[string]bool)
+	unique := conflicts[:0]
+	for _, c := range conflicts {
+		key := strings.Join(c.files, "\x00")
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, c)
+		}
+	}
+	return unique
+}
+
+// checkPathConflicts fails the build if two posts resolve to the same path or
+// output file, which would otherwise make one silently replace the other.
+func checkPathConflicts(gc *GenerationContext) error {
+	conflicts := findPathConflicts(gc)
+	if len(conflicts) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("posts resolve to the same output files:")
+	for _, c := range conflicts {
+		log.Error().Strs("files", c.files).Strs("paths", c.paths).Str("output", c.file).
+			Msgf("%s resolve to the same output file %s", strings.Join(c.files, " and "), c.file)
+		fmt.Fprintf(&b, "\n  %s: %s", c.file, strings.Join(c.files, ", "))
+	}
+	return fmt.Errorf("%s", b.String())
+}
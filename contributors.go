@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/view"
+)
+
+// ContributorsEntry holds the commit authors of a source file.
+type ContributorsEntry struct {
+	// Commit is the last commit that touched the file when the entry was computed.
+	Commit string `json:"commit"`
+	// Contributors are the commit authors, most commits first.
+	Contributors []Contributor `json:"contributors"`
+}
+
+// Contributor is a commit author of a source file.
+type Contributor struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Commits int    `json:"commits"`
+}
+
+// gitContributors returns the commit authors of path, following renames.
+func gitContributors(path string) ([]Contributor, error) {
+	out, err := runGit(".", nil, "log", "--follow", "--format=%aN%x1f%aE", "--", path)
+	if err != nil {
+		return nil, err
+	}
+
+	byEmail := make(map[string]*Contributor)
+	var contributors []*Contributor
+	for _, line := range strings.Split(out, "\n") {
+		name, email, ok := strings.Cut(line, "\x1f")
+		if !ok {
+			continue
+		}
+		key := strings.ToLower(email)
+		c, ok := byEmail[key]
+		if !ok {
+			c = &Contributor{Name: name, Email: email}
+			byEmail[key] = c
+			contributors = append(contributors, c)
+		}
+		c.Commits++
+	}
+
+	// git log lists the newest commit first, so the stable sort keeps recent authors ahead on ties.
+	sort.SliceStable(contributors, func(i, j int) bool {
+		return contributors[i].Commits > contributors[j].Commits
+	})
+
+	result := make([]Contributor, 0, len(contributors))
+	for _, c := range contributors {
+		result = append(result, *c)
+	}
+	return result, nil
+}
+
+// updateContributors refreshes the cached contributors of every post whose source file has new commits.
+func updateContributors(gc *GenerationContext) error {
+	log.Debug().Msg("start updating contributors")
+	if gc.DataStore.Contributors == nil {
+		gc.DataStore.Contributors = make(map[string]*ContributorsEntry)
+	}
+
+	_, err := runGit(".", nil, "rev-parse", "--is-inside-work-tree")
+	if err != nil {
+		log.Warn().Err(err).Msg("not a git repository, skipping contributors")
+		return nil
+	}
+
+	used := make(map[string]struct{})
+	for _, post := range publishedPosts(gc) {
+		path := post.FilePath
+		used[path] = struct{}{}
+
+		commit, err := runGit(".", nil, "log", "-1", "--format=%H", "--", path)
+		if err != nil {
+			return err
+		}
+		if commit == "" {
+			continue // not committed yet
+		}
+		if entry, ok := gc.DataStore.Contributors[path]; ok && entry.Commit == commit {
+			continue
+		}
+
+		contributors, err := gitContributors(path)
+		if err != nil {
+			return err
+		}
+		gc.DataStore.Contributors[path] = &ContributorsEntry{Commit: commit, Contributors: contributors}
+		log.Debug().Str("path", path).Int("contributors", len(contributors)).Msgf("updated contributors of %s", path)
+	}
+
+	for path := range gc.DataStore.Contributors {
+		if _, ok := used[path]; !ok {
+			delete(gc.DataStore.Contributors, path)
+		}
+	}
+
+	log.Debug().Msg("done updating contributors")
+	return nil
+}
+
+// gravatarURL returns the Gravatar avatar URL for email, falling back to an identicon.
+func gravatarURL(email string, size int) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return "https://gravatar.com/avatar/" + hex.EncodeToString(sum[:]) + "?d=identicon&s=" + strconv.Itoa(size)
+}
+
+// viewContributors returns the cached contributors of a source file for rendering.
+func viewContributors(gc *GenerationContext, path string) []view.Contributor {
+	entry, ok := gc.DataStore.Contributors[path]
+	if !ok {
+		return nil
+	}
+
+	contributors := make([]view.Contributor, 0, len(entry.Contributors))
+	for _, c := range entry.Contributors {
+		contributors = append(contributors, view.Contributor{
+			Name:    c.Name,
+			Avatar:  gravatarURL(c.Email, 64),
+			Commits: c.Commits,
+		})
+	}
+	return contributors
+}
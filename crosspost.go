@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/frontmatter"
+	"gosuda.org/website/internal/types"
+)
+
+// RemotePost is a copy of a post on another platform.
+type RemotePost struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Hash is the hash of the article last sent, used to detect changes.
+	Hash      string    `json:"hash"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// crossPostArticle is the platform independent form of a post.
+type crossPostArticle struct {
+	Title        string
+	Description  string
+	Markdown     string
+	CanonicalURL string
+	Image        string
+	Tags         []string
+	Series       string
+	Hash         string
+}
+
+var (
+	relativeURL   = regexp.MustCompile(`(\]\(\s*<?|\]:\s*|\b(?:src|href)=")/([^/])`)
+	shortcodeLine = regexp.MustCompile(`(?m)^\{\{<.*>\}\}[ \t]*$`)
+)
+
+func newCrossPostArticle(cfg *SiteConfig, post *types.Post) (*crossPostArticle, error) {
+	meta := &post.Main.Metadata
+	_, body, err := frontmatter.Parse([]byte(post.Main.Markdown))
+	if err != nil {
+		return nil, err
+	}
+	canonical := postURL(post, meta.Language)
+	if meta.Canonical != "" {
+		canonical = meta.Canonical
+	}
+
+	// links are made absolute, and shortcodes, which only this site renders, link to the original
+	md := relativeURL.ReplaceAllString(string(body), "${1}"+baseURL+"/$2")
+	md = shortcodeLine.ReplaceAllString(md, "*[View this content in the original post]("+canonical+")*")
+	md = strings.TrimSpace(md) + "\n\n---\n\n*Originally published at [" + strings.TrimPrefix(baseURL, "https://") + "](" + canonical + ").*\n"
+
+	a := &crossPostArticle{
+		Title:        meta.Title,
+		Description:  meta.Description,
+		Markdown:     md,
+		CanonicalURL: canonical,
+		Tags:         meta.Tags,
+		Series:       meta.Series,
+	}
+	if meta.Image != "" {
+		a.Image = baseURL + meta.Image
+	}
+	if sc, ok := cfg.Series[meta.Series]; ok && sc.Title != "" {
+		a.Series = sc.Title
+	}
+
+	h := sha256.New()
+	json.NewEncoder(h).Encode(a)
+	a.Hash = hex.EncodeToString(h.Sum(nil))
+	return a, nil
+}
+
+// crossPostFunc creates the article on a platform, or updates remote if it has an ID.
+type crossPostFunc func(cfg *CrossPostConfig, a *crossPostArticle, remote *RemotePost) error
+
+var crossPosters = map[string]crossPostFunc{
+	"devto":  crossPostDevTo,
+	"medium": crossPostMedium,
+}
+
+var apiClient = &http.Client{Timeout: time.Minute}
+
+// jsonRequest sends a JSON request and decodes the JSON response into out.
+func jsonRequest(method, url string, header http.Header, body, out any) error {
+	_, err := jsonRequestHeader(method, url, header, body, out)
+	return err
+}
+
+// jsonRequestHeader is jsonRequest returning the headers of the response.
+func jsonRequestHeader(method, url string, header http.Header, body, out any) (http.Header, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return resp.Header, json.Unmarshal(respBody, out)
+}
+
+var tagInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+// platformTags returns at most n tags reduced to lowercase letters and digits.
+func platformTags(tags []string, n int) []string {
+	var out []string
+	for _, t := range tags {
+		t = tagInvalid.ReplaceAllString(strings.ToLower(t), "")
+		if t != "" && !slices.Contains(out, t) && len(out) < n {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// crossPostDevTo publishes to dev.to through the Forem API.
+func crossPostDevTo(cfg *CrossPostConfig, a *crossPostArticle, remote *RemotePost) error {
+	article := map[string]any{
+		"title":         a.Title,
+		"body_markdown": a.Markdown,
+		"published":     cfg.DevTo.Published,
+		"canonical_url": a.CanonicalURL,
+		"description":   a.Description,
+		"tags":          platformTags(a.Tags, 4),
+	}
+	if a.Image != "" {
+		article["main_image"] = a.Image
+	}
+	if a.Series != "" {
+		article["series"] = a.Series
+	}
+
+	header := http.Header{"Api-Key": {cfg.DevTo.APIKey}}
+	var resp struct {
+		ID  int    `json:"id"`
+		URL string `json:"url"`
+	}
+	method, url := http.MethodPost, "https://dev.to/api/articles"
+	if remote.ID != "" {
+		method, url = http.MethodPut, url+"/"+remote.ID
+	}
+	err := jsonRequest(method, url, header, map[string]any{"article": article}, &resp)
+	if err != nil {
+		return err
+	}
+	remote.ID = strconv.Itoa(resp.ID)
+	remote.URL = resp.URL
+	return nil
+}
+
+// crossPostMedium publishes to Medium. The Medium API cannot edit posts, so
+// changes to a post that was already published are only reported.
+func crossPostMedium(cfg *CrossPostConfig, a *crossPostArticle, remote *RemotePost) error {
+	if remote.ID != "" {
+		log.Warn().Str("url", remote.URL).Msgf("%q changed, but Medium posts cannot be updated through the API; edit %s by hand", a.Title, remote.URL)
+		return nil
+	}
+
+	header := http.Header{"Authorization": {"Bearer " + cfg.Medium.Token}}
+	var me struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	err := jsonRequest(http.MethodGet, "https://api.medium.com/v1/me", header, nil, &me)
+	if err != nil {
+		return err
+	}
+
+	content := "# " + a.Title + "\n\n" + a.Markdown
+	post := map[string]any{
+		"title":         a.Title,
+		"contentFormat": "markdown",
+		"content":       content,
+		"canonicalUrl":  a.CanonicalURL,
+		"tags":          platformTags(a.Tags, 5),
+		"publishStatus": cfg.Medium.PublishStatus,
+	}
+	var resp struct {
+		Data struct {
+			ID  string `json:"id"`
+			URL string `json:"url"`
+		} `json:"data"`
+	}
+	err = jsonRequest(http.MethodPost, "https://api.medium.com/v1/users/"+me.Data.ID+"/posts", header, post, &resp)
+	if err != nil {
+		return err
+	}
+	remote.ID = resp.Data.ID
+	remote.URL = resp.Data.URL
+	return nil
+}
+
+// crossPostPlatforms returns the names of the enabled platforms.
+func crossPostPlatforms(cc *CrossPostConfig) []string {
+	var platforms []string
+	if cc.DevTo.Enabled {
+		platforms = append(platforms, "devto")
+	}
+	if cc.Medium.Enabled {
+		platforms = append(platforms, "medium")
+	}
+	return platforms
+}
+
+// crossPostPending publishes the posts that are new or changed since they were
+// last sent to the enabled platforms, recording them in ds.CrossPosts.
+func crossPostPending(cfg *SiteConfig, ds *DataStore, dryRun bool) (published, failed int, err error) {
+	cc := &cfg.CrossPost
+	platforms := crossPostPlatforms(cc)
+	var since time.Time
+	if cc.Since != "" {
+		since, err = time.Parse(time.DateOnly, cc.Since)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid crosspost.since %q: %w", cc.Since, err)
+		}
+	}
+
+	if ds.CrossPosts == nil {
+		ds.CrossPosts = make(map[string]map[string]*RemotePost)
+	}
+	gc := &GenerationContext{Config: cfg, DataStore: ds}
+
+	posts := listedPosts(gc)
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].Main.Metadata.Date.Before(posts[j].Main.Metadata.Date)
+	})
+
+	for _, post := range posts {
+		if post.Main == nil || isNoIndex(post) || post.Main.Metadata.Date.Before(since) {
+			continue
+		}
+		a, err := newCrossPostArticle(cfg, post)
+		if err != nil {
+			log.Error().Err(err).Str("post", post.ID).Msgf("failed to prepare %s", post.FilePath)
+			failed++
+			continue
+		}
+
+		for _, platform := range platforms {
+			remote := ds.CrossPosts[post.ID][platform]
+			if remote != nil && remote.Hash == a.Hash {
+				continue
+			}
+			action := "update"
+			if remote == nil {
+				action = "create"
+				remote = &RemotePost{}
+			}
+			if dryRun {
+				fmt.Printf("%s\t%s\t%s\n", platform, action, a.CanonicalURL)
+				continue
+			}
+
+			log.Debug().Str("platform", platform).Str("post", post.ID).Msgf("start cross-posting %q", a.Title)
+			if err := crossPosters[platform](cc, a, remote); err != nil {
+				log.Error().Err(err).Str("platform", platform).Msgf("failed to %s %q", action, a.Title)
+				failed++
+				continue
+			}
+			remote.Hash = a.Hash
+			remote.UpdatedAt = time.Now().UTC()
+			if ds.CrossPosts[post.ID] == nil {
+				ds.CrossPosts[post.ID] = make(map[string]*RemotePost)
+			}
+			ds.CrossPosts[post.ID][platform] = remote
+			published++
+			log.Info().Str("platform", platform).Str("url", remote.URL).Msgf("cross-posted %q", a.Title)
+		}
+	}
+	return published, failed, nil
+}
+
+func crosspost_main() {
+	fs := flag.NewFlagSet("crosspost", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "list the posts that would be published without calling the APIs")
+	fs.Parse(os.Args[2:])
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+	if len(crossPostPlatforms(&cfg.CrossPost)) == 0 {
+		log.Fatal().Msg("no cross-posting platform is enabled (crosspost.devto, crosspost.medium)")
+	}
+
+	ds, err := initializeDatabaseContent(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+
+	published, failed, err := crossPostPending(cfg, ds, *dryRun)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to cross-post")
+	}
+
+	if !*dryRun {
+		err = updateDatabase(dbFile, ds)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to update database file %s", dbFile)
+		}
+	}
+	if failed > 0 {
+		log.Fatal().Int("published", published).Int("failed", failed).Msgf("failed to cross-post %d articles", failed)
+	}
+	log.Info().Int("published", published).Msgf("cross-posted %d articles", published)
+}
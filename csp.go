@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/htmlrewrite"
+)
+
+// cspPolicy maps a CSP directive to its set of sources.
+type cspPolicy map[string]map[string]struct{}
+
+func newCSPPolicy(extra map[string][]string) cspPolicy {
+	p := cspPolicy{}
+	p.add("default-src", "'self'")
+	p.add("base-uri", "'self'")
+	p.add("object-src", "'none'")
+	p.add("script-src", "'self'")
+	p.add("style-src", "'self'")
+	p.add("img-src", "'self'", "data:")
+	p.add("font-src", "'self'")
+	p.add("connect-src", "'self'")
+	p.add("manifest-src", "'self'")
+	p.add("form-action", "'self'")
+	for directive, sources := range extra {
+		p.add(directive, sources...)
+	}
+	return p
+}
+
+func (p cspPolicy) add(directive string, sources ...string) {
+	set, ok := p[directive]
+	if !ok {
+		set = make(map[string]struct{})
+		p[directive] = set
+	}
+	for _, src := range sources {
+		set[src] = struct{}{}
+	}
+}
+
+func (p cspPolicy) merge(o cspPolicy) {
+	for directive, sources := range o {
+		for src := range sources {
+			p.add(directive, src)
+		}
+	}
+}
+
+// String renders the policy. Hashes are dropped from directives that allow
+// 'unsafe-inline', since browsers ignore 'unsafe-inline' when a hash is present.
+func (p cspPolicy) String(meta bool) string {
+	directives := make([]string, 0, len(p))
+	for directive := range p {
+		if meta && (directive == "frame-ancestors" || directive == "report-uri" || directive == "sandbox") {
+			continue // not supported in <meta> policies
+		}
+		directives = append(directives, directive)
+	}
+	sort.Strings(directives)
+
+	var b strings.Builder
+	for _, directive := range directives {
+		_, unsafeInline := p[directive]["'unsafe-inline'"]
+		sources := make([]string, 0, len(p[directive]))
+		for src := range p[directive] {
+			if unsafeInline && strings.HasPrefix(src, "'sha256-") {
+				continue
+			}
+			sources = append(sources, src)
+		}
+		sort.Slice(sources, func(i, j int) bool {
+			qi, qj := strings.HasPrefix(sources[i], "'"), strings.HasPrefix(sources[j], "'")
+			if qi != qj {
+				return qi
+			}
+			return sources[i] < sources[j]
+		})
+
+		if b.Len() > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(directive)
+		for _, src := range sources {
+			b.WriteString(" " + src)
+		}
+	}
+	return b.String()
+}
+
+func cspHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}
+
+// cspSource returns the source expression needed to load ref, or "" for same-origin refs.
+func cspSource(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if strings.HasPrefix(ref, "data:") {
+		return "data:"
+	}
+	if strings.HasPrefix(ref, "blob:") {
+		return "blob:"
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	origin := u.Scheme + "://" + u.Host
+	if u.Scheme == "" {
+		origin = "https://" + u.Host
+	}
+	if origin == baseURL {
+		return ""
+	}
+	return origin
+}
+
+func (p cspPolicy) addRef(directive, ref string) {
+	if src := cspSource(ref); src != "" {
+		p.add(directive, src)
+	}
+}
+
+// isExecutableScript reports whether a script element with the given type is executed by the browser.
+func isExecutableScript(typ string) bool {
+	switch strings.ToLower(strings.TrimSpace(typ)) {
+	case "", "text/javascript", "application/javascript", "module":
+		return true
+	}
+	return false
+}
+
+// collectCSP scans a generated page and records every source it loads.
+func collectCSP(page []byte) (cspPolicy, error) {
+	p := cspPolicy{}
+	var inScript, inStyle bool
+
+	err := htmlrewrite.Walk(page, func(t *htmlrewrite.Token) {
+		switch t.Type {
+		case htmlrewrite.TextToken:
+			if inScript && len(bytes.TrimSpace(t.Raw)) > 0 {
+				p.add("script-src", cspHash(t.Raw))
+			}
+			if inStyle && len(bytes.TrimSpace(t.Raw)) > 0 {
+				p.add("style-src", cspHash(t.Raw))
+			}
+			return
+		case htmlrewrite.EndTagToken:
+			inScript, inStyle = false, false
+			return
+		case htmlrewrite.StartTagToken, htmlrewrite.SelfClosingTagToken:
+		default:
+			return
+		}
+
+		for _, a := range t.Token.Attr {
+			switch {
+			case a.Key == "style":
+				p.add("style-src", "'unsafe-inline'")
+			case strings.HasPrefix(a.Key, "on"):
+				p.add("script-src", "'unsafe-hashes'", cspHash([]byte(html.UnescapeString(a.Val))))
+			}
+		}
+
+		switch t.Data {
+		case "script":
+			typ, _ := t.Attr("type")
+			if src, ok := t.Attr("src"); ok {
+				p.addRef("script-src", src)
+			} else if t.Type == htmlrewrite.StartTagToken && isExecutableScript(typ) {
+				inScript = true
+			}
+		case "style":
+			inStyle = t.Type == htmlrewrite.StartTagToken
+		case "link":
+			rel, _ := t.Attr("rel")
+			href, _ := t.Attr("href")
+			switch rel {
+			case "stylesheet":
+				p.addRef("style-src", href)
+			case "icon", "shortcut icon", "apple-touch-icon", "mask-icon":
+				p.addRef("img-src", href)
+			case "manifest":
+				p.addRef("manifest-src", href)
+			case "preload", "modulepreload":
+				as, _ := t.Attr("as")
+				switch as {
+				case "font":
+					p.addRef("font-src", href)
+				case "style":
+					p.addRef("style-src", href)
+				case "image":
+					p.addRef("img-src", href)
+				default:
+					p.addRef("script-src", href)
+				}
+			}
+		case "img", "source":
+			if src, ok := t.Attr("src"); ok {
+				p.addRef("img-src", src)
+			}
+			if srcset, ok := t.Attr("srcset"); ok {
+				for _, candidate := range strings.Split(srcset, ",") {
+					fields := strings.Fields(candidate)
+					if len(fields) > 0 {
+						p.addRef("img-src", fields[0])
+					}
+				}
+			}
+		case "video", "audio":
+			if src, ok := t.Attr("src"); ok {
+				p.addRef("media-src", src)
+			}
+			if poster, ok := t.Attr("poster"); ok {
+				p.addRef("img-src", poster)
+			}
+		case "iframe":
+			if src, ok := t.Attr("src"); ok {
+				p.addRef("frame-src", src)
+			}
+		case "form":
+			if action, ok := t.Attr("action"); ok {
+				p.addRef("form-action", action)
+			}
+		}
+	})
+	return p, err
+}
+
+var attrEscaper = strings.NewReplacer(`&`, "&amp;", `"`, "&#34;", `<`, "&lt;", `>`, "&gt;")
+
+// injectCSPMeta inserts a CSP <meta> tag right after the charset declaration (or <head>).
+func injectCSPMeta(page []byte, policy string) ([]byte, error) {
+	tag := []byte(`<meta http-equiv="Content-Security-Policy" content="` + attrEscaper.Replace(policy) + `">`)
+	var injected bool
+	var pendingHead bool
+
+	out, err := htmlrewrite.Rewrite(page, func(t *htmlrewrite.Token) []byte {
+		if injected {
+			return nil
+		}
+		if t.IsTag("meta") {
+			if _, ok := t.Attr("charset"); ok {
+				injected = true
+				return append(t.Raw, tag...)
+			}
+		}
+		if t.IsTag("head") {
+			pendingHead = true
+			return nil
+		}
+		if pendingHead && (t.Type != htmlrewrite.TextToken || len(bytes.TrimSpace(t.Raw)) > 0) {
+			injected = true
+			return append(append([]byte(nil), tag...), t.Raw...)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// applyCSP computes Content-Security-Policies from the generated pages, injects them
+// as <meta> tags and/or adds the site-wide union to the hosting headers.
+func applyCSP(gc *GenerationContext) error {
+	cc := &gc.Config.CSP
+	if !cc.Enabled {
+		return nil
+	}
+	log.Debug().Msg("start computing content security policies")
+
+	list, err := generateFileList(distDir)
+	if err != nil {
+		return err
+	}
+
+	base := newCSPPolicy(cc.Extra)
+	base.merge(commentsPolicy(&gc.Config.Comments))
+	base.merge(analyticsPolicy(gc.Config))
+	base.merge(featuresPolicy(&gc.Config.Features))
+
+	site := cspPolicy{}
+	site.merge(base)
+	for _, path := range list {
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".html" && ext != ".htm" {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		page, err := collectCSP(data)
+		if err != nil {
+			return err
+		}
+		site.merge(page)
+
+		if cc.Meta {
+			policy := cspPolicy{}
+			policy.merge(base)
+			policy.merge(page)
+			data, err = injectCSPMeta(data, policy.String(true))
+			if err != nil {
+				return err
+			}
+			err = os.WriteFile(path, data, 0644)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if cc.Header {
+		if gc.Config.Hosting.Headers == nil {
+			gc.Config.Hosting.Headers = make(map[string]string)
+		}
+		gc.Config.Hosting.Headers["Content-Security-Policy"] = site.String(false)
+	}
+
+	log.Debug().Msg("done computing content security policies")
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// cvPath is the site path of the CV page. The PDF is at cvPath + ".pdf".
+const cvPath = "/cv"
+
+// loadCV reads the CV data file path.
+func loadCV(path string) (*view.CV, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cv view.CV
+	err = yaml.Unmarshal(data, &cv)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if cv.Name == "" {
+		return nil, fmt.Errorf("%s: name is required", path)
+	}
+	return &cv, nil
+}
+
+// generateCV writes the /cv page from the CV data file, and /cv.pdf if
+// cv.pdf is set. A PDF that cannot be rendered, such as when chromium is not
+// installed, is skipped with a warning rather than failing the build.
+func generateCV(gc *GenerationContext) error {
+	cc := &gc.Config.CV
+	if !cc.Enabled {
+		return nil
+	}
+	log.Debug().Str("path", cc.Data).Msg("start generating CV page")
+	cv, err := loadCV(cc.Data)
+	if err != nil {
+		return err
+	}
+
+	l := gc.I18n.Localizer(types.LangEnglish)
+	ctx := renderContext(gc, types.LangEnglish)
+	pdf := ""
+	if cc.PDF {
+		var b bytes.Buffer
+		err = view.CVPrint(types.LangEnglish, cv).Render(ctx, &b)
+		if err != nil {
+			return err
+		}
+		out := filepath.Join(distDir, filepath.FromSlash(cvPath)+".pdf")
+		if err := renderPDF(b.Bytes(), out); err != nil {
+			log.Warn().Err(err).Msg("failed to render the CV PDF, skipping it")
+		} else {
+			pdf = cvPath + ".pdf"
+		}
+	}
+
+	info, err := os.Stat(cc.Data)
+	if err != nil {
+		return err
+	}
+	url := baseURL + pageURL(cvPath)
+	meta := &view.Metadata{
+		Language:    types.LangEnglish,
+		Title:       cv.Name + " | " + l.T("CV"),
+		Description: cmp.Or(cv.Headline, cv.Summary),
+		Author:      cv.Name,
+		Image:       baseURL + "/assets/images/ogp_placeholder.png",
+		URL:         url,
+		Canonical:   url,
+		BaseURL:     baseURL,
+		UpdatedAt:   info.ModTime().UTC(),
+		Analytics:   viewAnalytics(gc),
+		Feeds:       viewFeeds(gc, types.LangEnglish),
+	}
+
+	var b bytes.Buffer
+	err = view.CVPage(meta, cv, pdf).Render(ctx, &b)
+	if err != nil {
+		return err
+	}
+	for _, p := range []string{cvPath, "/" + types.LangEnglish + cvPath} {
+		err = writePage(gc, p, cc.Data, b.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	gc.Sitemaps[types.LangEnglish] = append(gc.Sitemaps[types.LangEnglish], view.SitemapURL{Loc: url, LastMod: meta.UpdatedAt})
+	log.Debug().Bool("pdf", pdf != "").Msg("done generating CV page")
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/html"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/types"
+)
+
+// invertLightness inverts the lightness of every pixel of img, keeping its hue,
+// saturation and alpha, so that dark lines on a light background become light
+// lines on a dark one without changing their colors.
+func invertLightness(img image.Image) *image.NRGBA {
+	dst := image.NewNRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+	for i := 0; i < len(dst.Pix); i += 4 {
+		r, g, b := int(dst.Pix[i]), int(dst.Pix[i+1]), int(dst.Pix[i+2])
+		// in HSL, lightness is (max+min)/2; shifting each channel by
+		// 255-max-min maps it to 1-lightness
+		shift := 255 - max(r, g, b) - min(r, g, b)
+		dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2] = uint8(r+shift), uint8(g+shift), uint8(b+shift)
+	}
+	return dst
+}
+
+// darkVariant writes the image src of dist with its lightness inverted and
+// returns its site path. It returns "" for images that cannot be decoded, such
+// as vector images.
+func darkVariant(gc *GenerationContext, src string) (string, error) {
+	if gc.DarkImages == nil {
+		gc.DarkImages = make(map[string]string)
+	}
+	if variant, ok := gc.DarkImages[src]; ok {
+		return variant, nil
+	}
+
+	input := filepath.Join(distDir, filepath.FromSlash(src))
+	f, err := os.Open(input)
+	if err != nil {
+		return "", fmt.Errorf("image %s does not exist: %w", src, err)
+	}
+	img, format, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		log.Warn().Str("image", src).Msgf("cannot invert %s, add a #dark variant instead: %v", src, err)
+		gc.DarkImages[src] = ""
+		return "", nil
+	}
+
+	var b bytes.Buffer
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+		err = jpeg.Encode(&b, invertLightness(img), &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&b, invertLightness(img))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(src))
+	variant := "/assets/dark/" + hex.EncodeToString(sum[:8]) + ext
+	output := filepath.Join(distDir, filepath.FromSlash(variant))
+	err = os.MkdirAll(filepath.Dir(output), 0755)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(output, b.Bytes(), 0644)
+	if err != nil {
+		return "", err
+	}
+
+	gc.DarkImages[src] = variant
+	log.Debug().Str("image", src).Str("variant", variant).Msgf("generated dark variant of %s", src)
+	return variant, nil
+}
+
+// colorScheme returns the color scheme of an image reference marked with a
+// #light or #dark fragment, and the reference without it.
+func colorScheme(src string) (scheme, ref string) {
+	u, err := url.Parse(src)
+	if err != nil || (u.Fragment != "light" && u.Fragment != "dark") {
+		return "", src
+	}
+	scheme = u.Fragment
+	u.Fragment = ""
+	return scheme, u.String()
+}
+
+// schemeImage is an image marked with a color scheme, held until the next
+// token shows whether its variant for the other scheme follows.
+type schemeImage struct {
+	token  htmlrewrite.Token
+	scheme string
+	src    string
+	// between are the whitespace tokens after the image.
+	between []byte
+}
+
+// addDarkImages renders the images marked #light or #dark as pictures that
+// follow the color scheme of the reader. An image of one scheme directly
+// followed by one of the other is a pair; a single local raster image gets a
+// generated variant for the other scheme when images.dark_variants is set.
+func addDarkImages(gc *GenerationContext, post *types.Post, doc string) (string, error) {
+	var held *schemeImage
+	var darkErr error
+
+	// flush renders the held image, alone or with its pair.
+	flush := func(pair *schemeImage) []byte {
+		h := held
+		held = nil
+		light, dark := h, pair
+		if h.scheme == "dark" {
+			light, dark = pair, h
+		}
+
+		var lightSrc, darkSrc string
+		img := h.token
+		if light != nil {
+			lightSrc, img = light.src, light.token
+		}
+		if dark != nil {
+			darkSrc = dark.src
+		}
+
+		local := strings.HasPrefix(h.src, "/") && !strings.HasPrefix(h.src, "//")
+		if pair == nil && gc.Config.Images.DarkVariants && local {
+			variant, err := darkVariant(gc, h.src)
+			if err != nil {
+				darkErr = fmt.Errorf("%s: %w", post.FilePath, err)
+			}
+			if h.scheme == "light" {
+				darkSrc = variant
+			} else if variant != "" {
+				lightSrc = variant
+			}
+		}
+		if lightSrc == "" {
+			// a dark image without a light variant is shown as it is
+			lightSrc, darkSrc = darkSrc, ""
+		}
+
+		img.SetAttr("src", lightSrc)
+		if darkSrc == "" {
+			return append(img.Render(), h.between...)
+		}
+		var b bytes.Buffer
+		fmt.Fprintf(&b, `<picture><source srcset="%s" media="(prefers-color-scheme: dark)">`, html.EscapeString(darkSrc))
+		b.Write(img.Render())
+		b.WriteString("</picture>")
+		if pair == nil {
+			b.Write(h.between)
+		}
+		return b.Bytes()
+	}
+
+	out, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		var scheme, src string
+		if t.IsTag("img") {
+			s, _ := t.Attr("src")
+			scheme, src = colorScheme(s)
+		}
+
+		if held != nil {
+			switch {
+			case t.Type == html.TextToken && strings.TrimSpace(t.Data) == "":
+				held.between = append(held.between, t.Raw...)
+				return []byte{}
+			case scheme != "" && scheme != held.scheme:
+				return flush(&schemeImage{token: *t, scheme: scheme, src: src})
+			}
+		}
+
+		var prev []byte
+		if held != nil {
+			prev = flush(nil)
+		}
+		if scheme != "" {
+			held = &schemeImage{token: *t, scheme: scheme, src: src}
+			if prev == nil {
+				return []byte{}
+			}
+			return prev
+		}
+		if prev == nil {
+			return nil
+		}
+		return append(prev, t.Raw...)
+	})
+	if err != nil {
+		return "", err
+	}
+	if held != nil {
+		out = append(out, flush(nil)...)
+	}
+	return string(out), darkErr
+}
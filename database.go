@@ -2,75 +2,440 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"os"
+	"sort"
 
 	"github.com/klauspost/compress/zstd"
 	"github.com/rs/zerolog/log"
 	"gosuda.org/website/internal/types"
 )
 
-func initializeDatabase(dbFile string) (*DataStore, error) {
-	_, err := os.Stat(dbFile)
-	if err != nil && !os.IsNotExist(err) {
+// The database file is a zstd compressed stream of JSON values: the DataStore
+// without its posts, then two lines per post, the post without the content of
+// its documents and the content, so that the posts are read and written one
+// at a time and the content can be skipped. Database files of a single JSON
+// object, the DataStore with its posts, are read too, and rewritten as a
+// stream when they are saved.
+
+// storedPost is the line of a post in the database file, without the content
+// of its documents, which is on the next line.
+type storedPost struct {
+	*types.Post
+	// MainTranslated is the language of the translations that is the main
+	// document itself, stored once.
+	MainTranslated string `json:"main_translated,omitempty"`
+}
+
+// postContent is the content of the documents of a post, and its translation
+// memory.
+type postContent struct {
+	Main              *documentContent             `json:"main,omitempty"`
+	Translated        map[string]*documentContent  `json:"translated,omitempty"`
+	TranslationMemory map[string]map[string]string `json:"translation_memory,omitempty"`
+}
+
+type documentContent struct {
+	Markdown string `json:"markdown,omitempty"`
+	HTML     string `json:"html,omitempty"`
+}
+
+// splitDocument returns the copy of the document without its content, and the
+// content.
+func splitDocument(doc *types.Document) (*types.Document, *documentContent) {
+	d := *doc
+	d.Markdown, d.HTML = "", ""
+	return &d, &documentContent{Markdown: doc.Markdown, HTML: doc.HTML}
+}
+
+func (c *documentContent) empty() bool {
+	return c == nil || c.Markdown == "" && c.HTML == ""
+}
+
+// hasContent reports whether doc was read or made with its content.
+func hasContent(doc *types.Document) bool {
+	return doc.Markdown != "" || doc.HTML != ""
+}
+
+// fill sets the content of doc if it was read without it.
+func (c *documentContent) fill(doc *types.Document) {
+	if c != nil && doc != nil && !hasContent(doc) {
+		doc.Markdown, doc.HTML = c.Markdown, c.HTML
+	}
+}
+
+// storePost splits post into its line and its content line.
+func storePost(post *types.Post) (*storedPost, *postContent) {
+	p := *post
+	line := &storedPost{Post: &p}
+	content := &postContent{TranslationMemory: post.TranslationMemory}
+	p.TranslationMemory = nil
+	if post.Main != nil {
+		p.Main, content.Main = splitDocument(post.Main)
+	}
+	if post.Translated != nil {
+		p.Translated = make(map[string]*types.Document, len(post.Translated))
+		content.Translated = make(map[string]*documentContent, len(post.Translated))
+		for lang, doc := range post.Translated {
+			switch {
+			case doc == nil:
+			case doc == post.Main:
+				line.MainTranslated = lang
+			default:
+				p.Translated[lang], content.Translated[lang] = splitDocument(doc)
+			}
+		}
+	}
+	return line, content
+}
+
+// loadPost returns the post of a post line.
+func loadPost(line *storedPost) *types.Post {
+	post := line.Post
+	if line.MainTranslated != "" && post.Main != nil {
+		if post.Translated == nil {
+			post.Translated = make(map[string]*types.Document)
+		}
+		post.Translated[line.MainTranslated] = post.Main
+	}
+	return post
+}
+
+// fill sets the content of the documents of post read without it.
+func (c *postContent) fill(post *types.Post) {
+	c.Main.fill(post.Main)
+	for lang, doc := range post.Translated {
+		if doc != post.Main {
+			c.Translated[lang].fill(doc)
+		}
+	}
+	if post.TranslationMemory == nil {
+		post.TranslationMemory = c.TranslationMemory
+	}
+}
+
+// readDatabase reads the database file at path, and calls fn with each post
+// and, if content is set, its content, one at a time. The DataStore returned
+// has no posts, but for the database files of a single JSON object.
+func readDatabase(path string, content bool, fn func(post *types.Post, c *postContent) error) (*DataStore, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := zstd.NewReader(f)
+	if err != nil {
 		return nil, err
 	}
+	defer r.Close()
+
+	dec := json.NewDecoder(r)
+	var ds DataStore
+	err = dec.Decode(&ds)
+	if err != nil || ds.Posts != nil {
+		return &ds, err
+	}
 
-	var f *os.File
-	if err != nil && os.IsNotExist(err) {
-		log.Info().Err(err).Msgf("database file %s does not exist, Creating new database file", dbFile)
-		f, err = os.OpenFile(dbFile, os.O_CREATE|os.O_RDWR, 0644)
+	for {
+		var line storedPost
+		err = dec.Decode(&line)
+		if errors.Is(err, io.EOF) {
+			return &ds, nil
+		}
 		if err != nil {
 			return nil, err
 		}
-
-		w, err := zstd.NewWriter(f)
+		var c *postContent
+		if content {
+			c = &postContent{}
+			err = dec.Decode(c)
+		} else {
+			err = dec.Decode(&struct{}{})
+		}
 		if err != nil {
 			return nil, err
 		}
-
-		_, err = w.Write([]byte("{}"))
+		err = fn(loadPost(&line), c)
 		if err != nil {
 			return nil, err
 		}
+	}
+}
 
-		err = w.Close()
-		if err != nil {
-			return nil, err
+// loadDatabase reads the database file at path, keeping the content of the
+// documents in store if it is not nil.
+func loadDatabase(path string, store *contentStore) (*DataStore, error) {
+	posts := make(map[string]*types.Post)
+	ds, err := readDatabase(path, store != nil, func(post *types.Post, c *postContent) error {
+		posts[post.ID] = post
+		if store != nil {
+			return store.putPost(post, c)
 		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		log.Info().Err(err).Msgf("database file %s does not exist, creating a new database", path)
+		ds, err = &DataStore{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ds.path = path
+	if ds.Posts == nil {
+		ds.Posts = posts
+		ds.metadataOnly = true
+		ds.content = store
+	}
+	return ds, nil
+}
+
+// initializeDatabase reads the database file without the content of the
+// documents, which most commands do not need. The content is read from the
+// file again if it is needed after all, see document.
+func initializeDatabase(dbFile string) (*DataStore, error) {
+	return loadDatabase(dbFile, nil)
+}
 
-		_, err = f.Seek(0, 0)
+// initializeDatabaseLazy reads the database file like initializeDatabase, but
+// keeps the content of the documents in a temporary file as it reads it, from
+// which the content is read when a document is rendered, see document. The
+// build reads the file once this way without holding the content of every
+// post in memory.
+func initializeDatabaseLazy(dbFile string) (*DataStore, error) {
+	store, err := newContentStore()
+	if err != nil {
+		return nil, err
+	}
+	return loadDatabase(dbFile, store)
+}
+
+// initializeDatabaseContent reads the database file with the content of the
+// documents.
+func initializeDatabaseContent(dbFile string) (*DataStore, error) {
+	posts := make(map[string]*types.Post)
+	ds, err := readDatabase(dbFile, true, func(post *types.Post, c *postContent) error {
+		c.fill(post)
+		posts[post.ID] = post
+		return nil
+	})
+	if os.IsNotExist(err) {
+		log.Info().Err(err).Msgf("database file %s does not exist, creating a new database", dbFile)
+		ds, err = &DataStore{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ds.path = dbFile
+	if ds.Posts == nil {
+		ds.Posts = posts
+	}
+	return ds, nil
+}
+
+// contentStore keeps the content of the documents of a DataStore read without
+// it in a temporary file, from which it is read one document at a time.
+type contentStore struct {
+	f    *os.File
+	size int64
+	// entries are the offsets and lengths of the JSON encoded contents in f.
+	entries map[contentKey][2]int64
+}
+
+// contentKey is the ID of a post and the language of one of its documents, ""
+// for the main document, or memoryKey for the translation memory.
+type contentKey struct {
+	id   string
+	lang string
+}
+
+const memoryKey = "memory"
+
+func newContentStore() (*contentStore, error) {
+	f, err := os.CreateTemp("", "website-content-")
+	if err != nil {
+		return nil, err
+	}
+	// the open file stays readable and is freed when the process exits
+	os.Remove(f.Name())
+	return &contentStore{f: f, entries: make(map[contentKey][2]int64)}, nil
+}
+
+func (s *contentStore) put(key contentKey, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = s.f.WriteAt(data, s.size)
+	if err != nil {
+		return err
+	}
+	s.entries[key] = [2]int64{s.size, int64(len(data))}
+	s.size += int64(len(data))
+	return nil
+}
+
+// get decodes the content of key into v, which is left alone if the content is
+// not stored.
+func (s *contentStore) get(key contentKey, v any) error {
+	e, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	data := make([]byte, e[1])
+	_, err := s.f.ReadAt(data, e[0])
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// putPost stores the content c of post.
+func (s *contentStore) putPost(post *types.Post, c *postContent) error {
+	if c.Main != nil {
+		err := s.put(contentKey{post.ID, ""}, c.Main)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		for lang, doc := range post.Translated {
+			if doc == post.Main {
+				// the main document is the translation into its own language too
+				s.entries[contentKey{post.ID, lang}] = s.entries[contentKey{post.ID, ""}]
+			}
 		}
-	} else {
-		f, err = os.OpenFile(dbFile, os.O_RDWR, 0644)
+	}
+	for lang, dc := range c.Translated {
+		err := s.put(contentKey{post.ID, lang}, dc)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
-	defer f.Close()
+	if c.TranslationMemory != nil {
+		return s.put(contentKey{post.ID, memoryKey}, c.TranslationMemory)
+	}
+	return nil
+}
 
-	var ds DataStore
+// complete sets the content of c, the content of post, of the documents read
+// without it and the translation memory from s.
+func (s *contentStore) complete(post *types.Post, c *postContent) error {
+	if c.Main != nil && c.Main.empty() {
+		err := s.get(contentKey{post.ID, ""}, c.Main)
+		if err != nil {
+			return err
+		}
+	}
+	for lang, dc := range c.Translated {
+		if dc.empty() {
+			err := s.get(contentKey{post.ID, lang}, dc)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if c.TranslationMemory == nil {
+		return s.get(contentKey{post.ID, memoryKey}, &c.TranslationMemory)
+	}
+	return nil
+}
 
-	r, err := zstd.NewReader(f)
+// contentStore returns the store of the content of the documents read without
+// it, reading the database file again on the first call for the DataStores of
+// initializeDatabase, or nil if the content is in memory.
+func (ds *DataStore) contentStore() (*contentStore, error) {
+	if !ds.metadataOnly || ds.content != nil {
+		return ds.content, nil
+	}
+	store, err := newContentStore()
 	if err != nil {
 		return nil, err
 	}
-	defer r.Close()
+	_, err = readDatabase(ds.path, true, func(post *types.Post, c *postContent) error {
+		if _, ok := ds.Posts[post.ID]; ok {
+			return store.putPost(post, c)
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	ds.content = store
+	return store, nil
+}
+
+// documentKey returns the key of the content of doc, a document of post.
+func documentKey(post *types.Post, doc *types.Document) (contentKey, bool) {
+	if doc == post.Main {
+		return contentKey{post.ID, ""}, true
+	}
+	for lang, d := range post.Translated {
+		if d == doc {
+			return contentKey{post.ID, lang}, true
+		}
+	}
+	return contentKey{}, false
+}
 
-	err = json.NewDecoder(r).Decode(&ds)
+// document returns doc, a document of post, with its content. The content of
+// documents read without it is read from the content store into a copy of
+// doc, so that it is not held in memory longer than it is used.
+func (ds *DataStore) document(post *types.Post, doc *types.Document) (*types.Document, error) {
+	if doc == nil || hasContent(doc) {
+		return doc, nil
+	}
+	store, err := ds.contentStore()
+	if err != nil || store == nil {
+		return doc, err
+	}
+	key, ok := documentKey(post, doc)
+	if !ok {
+		return doc, nil
+	}
+	var c documentContent
+	err = store.get(key, &c)
 	if err != nil {
 		return nil, err
 	}
+	d := *doc
+	d.Markdown, d.HTML = c.Markdown, c.HTML
+	return &d, nil
+}
 
-	if ds.Posts == nil {
-		ds.Posts = make(map[string]*types.Post)
+// setDocument sets the content of doc, a document of post, to that of d. The
+// content of documents read without it is kept in the content store.
+func (ds *DataStore) setDocument(post *types.Post, doc, d *types.Document) error {
+	store, err := ds.contentStore()
+	if err != nil {
+		return err
 	}
+	key, ok := documentKey(post, doc)
+	if store == nil || !ok || hasContent(doc) {
+		doc.Markdown, doc.HTML = d.Markdown, d.HTML
+		return nil
+	}
+	return store.put(key, &documentContent{d.Markdown, d.HTML})
+}
 
-	return &ds, nil
+// loadTranslationMemory sets the translation memory of post if it was read
+// without it.
+func (ds *DataStore) loadTranslationMemory(post *types.Post) error {
+	if post.TranslationMemory != nil {
+		return nil
+	}
+	store, err := ds.contentStore()
+	if err != nil || store == nil {
+		return err
+	}
+	return store.get(contentKey{post.ID, memoryKey}, &post.TranslationMemory)
 }
 
 func updateDatabase(dbFile string, ds *DataStore) error {
+	// the content of the documents read without it is written back from the store
+	store, err := ds.contentStore()
+	if err != nil {
+		return err
+	}
+
 	f, err := os.OpenFile(dbFile+".tmp", os.O_CREATE|os.O_RDWR|os.O_TRUNC|os.O_EXCL, 0644)
 	if err != nil {
 		return err
@@ -83,7 +448,42 @@ func updateDatabase(dbFile string, ds *DataStore) error {
 	}
 	defer w.Close()
 
-	err = json.NewEncoder(w).Encode(ds)
+	enc := json.NewEncoder(w)
+	header := *ds
+	header.Posts = nil
+	err = enc.Encode(&header)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(ds.Posts))
+	for id := range ds.Posts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		line, content := storePost(ds.Posts[id])
+		if store != nil {
+			err = store.complete(ds.Posts[id], content)
+			if err != nil {
+				return err
+			}
+		}
+		err = enc.Encode(line)
+		if err != nil {
+			return err
+		}
+		err = enc.Encode(content)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	err = f.Close()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+func deploy_main() {
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+
+	var changed []string
+	if m, err := readManifest(distDir); err == nil {
+		changed = m.Changed
+	}
+
+	err = runHooks("predeploy", cfg.Hooks.Predeploy, changed, "SITE_DEPLOY_TARGET="+cfg.Deploy.Target)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("predeploy hook failed")
+	}
+
+	registry, err := newPluginRegistry(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to register plugins")
+	}
+	defer registry.Close()
+	if target, ok := registry.DeployTargets[cfg.Deploy.Target]; ok {
+		err = target.Deploy(cfg, distDir)
+	} else {
+		err = fmt.Errorf("unknown deploy target %q", cfg.Deploy.Target)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to deploy website")
+	}
+
+	log.Info().Msgf("website deployed")
+}
+
+// deployGitHubPages commits the contents of dir on top of the configured branch
+// and pushes it, without touching the current working tree or index.
+func deployGitHubPages(dc *DeployConfig, dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	if dc.CNAME != "" {
+		err = os.WriteFile(filepath.Join(dir, "CNAME"), []byte(dc.CNAME+"\n"), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	err = os.WriteFile(filepath.Join(dir, ".nojekyll"), nil, 0644)
+	if err != nil {
+		return err
+	}
+
+	gitDir, err := runGit(".", nil, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return err
+	}
+
+	index, err := os.CreateTemp("", "deploy-index-*")
+	if err != nil {
+		return err
+	}
+	index.Close()
+	os.Remove(index.Name()) // git refuses to read an empty index file
+	defer os.Remove(index.Name())
+
+	env := []string{"GIT_DIR=" + gitDir, "GIT_WORK_TREE=.", "GIT_INDEX_FILE=" + index.Name(), "GIT_LITERAL_PATHSPECS=1"}
+
+	var parent string
+	log.Debug().Str("remote", dc.Remote).Str("branch", dc.Branch).Msg("fetching deploy branch")
+	_, err = runGit(".", nil, "fetch", "--quiet", dc.Remote, dc.Branch)
+	if err == nil {
+		parent, err = runGit(".", nil, "rev-parse", "--verify", "FETCH_HEAD")
+		if err != nil {
+			return err
+		}
+	} else {
+		log.Info().Err(err).Str("branch", dc.Branch).Msgf("deploy branch %s not found, creating it", dc.Branch)
+	}
+
+	log.Debug().Str("dir", dir).Msg("staging deploy tree")
+	err = stageDeployTree(dir, env, parent)
+	if err != nil {
+		return err
+	}
+	tree, err := runGit(dir, env, "write-tree")
+	if err != nil {
+		return err
+	}
+
+	args := []string{"commit-tree", tree}
+	if parent != "" {
+		parentTree, err := runGit(".", nil, "rev-parse", "--verify", parent+"^{tree}")
+		if err != nil {
+			return err
+		}
+		if parentTree == tree {
+			log.Info().Str("branch", dc.Branch).Msgf("deploy branch %s is already up to date", dc.Branch)
+			return nil
+		}
+		args = append(args, "-p", parent)
+	}
+
+	message := "Deploy website"
+	if head, err := runGit(".", nil, "rev-parse", "--short", "HEAD"); err == nil {
+		message += " from " + head
+	}
+	args = append(args, "-m", message)
+
+	commit, err := runGit(".", nil, args...)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Str("commit", commit).Msgf("pushing %s to %s", dc.Branch, dc.Remote)
+	_, err = runGit(".", nil, "push", dc.Remote, commit+":refs/heads/"+strings.TrimPrefix(dc.Branch, "refs/heads/"))
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("commit", commit).Str("branch", dc.Branch).Msgf("pushed %s to %s", dc.Branch, dc.Remote)
+	return nil
+}
+
+// stageDeployTree stages the files of dir into the index of env. With a
+// previous deploy, its tree is staged first and only the files whose hash
+// differs from its manifest are added on top, so that unchanged assets are
+// neither rehashed nor uploaded; the files gone from dir are removed.
+func stageDeployTree(dir string, env []string, parent string) error {
+	var cur *Manifest
+	prev := deployedManifest(parent)
+	if prev != nil {
+		cur, _ = readManifest(dir)
+	}
+	if cur == nil {
+		// the first deploy, or one without manifests to compare
+		_, err := runGit(dir, env, "add", "--all", "--force", ".")
+		return err
+	}
+
+	_, err := runGit(dir, env, "read-tree", parent)
+	if err != nil {
+		return err
+	}
+
+	deployed := make(map[string]string, len(prev.Files))
+	for _, e := range prev.Files {
+		deployed[e.Path] = e.Hash
+	}
+	listed := make(map[string]bool, len(cur.Files))
+	var changed []string
+	for _, e := range cur.Files {
+		listed[e.Path] = true
+		if deployed[e.Path] != e.Hash {
+			changed = append(changed, strings.TrimPrefix(e.Path, "/"))
+		}
+	}
+	// files left out of the manifests, such as the manifest itself and CNAME
+	list, err := generateFileList(dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range list {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !listed["/"+rel] {
+			changed = append(changed, rel)
+		}
+	}
+
+	var removed []string
+	files, err := runGit(dir, env, "ls-files", "-z")
+	if err != nil {
+		return err
+	}
+	for _, rel := range strings.Split(files, "\x00") {
+		if rel == "" {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(rel))); errors.Is(err, fs.ErrNotExist) {
+			removed = append(removed, rel)
+		}
+	}
+
+	log.Info().Int("changed", len(changed)).Int("removed", len(removed)).Msgf("deploying %d changed and %d removed files", len(changed), len(removed))
+	err = gitPathspecs(dir, env, changed, "add", "--force")
+	if err != nil {
+		return err
+	}
+	return gitPathspecs(dir, env, removed, "rm", "--cached", "--quiet")
+}
+
+// deployedManifest returns the manifest of the deploy commit, or nil if it
+// has none.
+func deployedManifest(commit string) *Manifest {
+	if commit == "" {
+		return nil
+	}
+	data, err := runGit(".", nil, "show", commit+":"+manifestFile)
+	if err != nil {
+		return nil
+	}
+	var m Manifest
+	if json.Unmarshal([]byte(data), &m) != nil {
+		return nil
+	}
+	return &m
+}
+
+// gitPathspecs runs the git command args on paths, passed in a file rather
+// than as arguments, which could exceed the limits of the command line.
+func gitPathspecs(dir string, env, paths []string, args ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	f, err := os.CreateTemp("", "deploy-paths-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(strings.Join(paths, "\x00"))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	_, err = runGit(dir, env, append(args, "--pathspec-from-file="+f.Name(), "--pathspec-file-nul")...)
+	return err
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// docsDir is the directory of rootDir holding the documentation tree.
+const docsDir = "docs"
+
+// docsLatest is the version alias of the latest docs version.
+const docsLatest = "latest"
+
+// isDocsFile reports whether the source file path is in the documentation tree.
+func isDocsFile(path string) bool {
+	rel, err := filepath.Rel(filepath.Join(rootDir, docsDir), path)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
+
+// docsPath returns the URL path of the doc at the source file path, which
+// mirrors its place in the tree. Index files stand for their directory, so
+// root/docs/guide/index.md is /docs/guide.
+func docsPath(file string) string {
+	rel, _ := filepath.Rel(rootDir, file)
+	p := "/" + strings.TrimSuffix(filepath.ToSlash(rel), filepath.Ext(rel))
+	if path.Base(p) == "index" {
+		p = path.Dir(p)
+	}
+	return p
+}
+
+// docsVersion returns the version directory (docs.versions) of the doc at the
+// source file path, or "" for unversioned docs.
+func docsVersion(cfg *SiteConfig, file string) string {
+	rel, err := filepath.Rel(filepath.Join(rootDir, docsDir), file)
+	if err != nil {
+		return ""
+	}
+	first, _, ok := strings.Cut(filepath.ToSlash(rel), "/")
+	if ok && slices.Contains(cfg.Docs.Versions, first) {
+		return first
+	}
+	return ""
+}
+
+// docsRel returns the path of the doc at the source file path within the tree
+// of its version, without extension. (e.g. "guide/index")
+func docsRel(file, version string) string {
+	rel, _ := filepath.Rel(filepath.Join(rootDir, docsDir, version), file)
+	rel = filepath.ToSlash(rel)
+	return strings.TrimSuffix(rel, path.Ext(rel))
+}
+
+// docsSection returns the directory of the tree of version a doc is listed
+// in, with "." for the top level. Index files are listed as the section of
+// their directory.
+func docsSection(file, version string) (dir string, index bool) {
+	rel := docsRel(file, version)
+	return path.Dir(rel), path.Base(rel) == "index"
+}
+
+// docURL returns the URL of the doc in lang, or in its main language if it is
+// not translated into lang.
+func docURL(post *types.Post, lang types.Lang) string {
+	if _, ok := post.Translated[lang]; ok {
+		return postURL(post, lang)
+	}
+	return postURL(post, post.Main.Metadata.Language)
+}
+
+// collectDocs returns the published docs of every version, "" for unversioned
+// docs, keyed by docsRel. The doc pages are rendered with gc.Docs, collected
+// once per build.
+func collectDocs(gc *GenerationContext) map[string]map[string]*types.Post {
+	docs := make(map[string]map[string]*types.Post)
+	for _, post := range publishedPosts(gc) {
+		if post.Main.Metadata.Kind != types.KindDoc {
+			continue
+		}
+		version := docsVersion(gc.Config, post.FilePath)
+		if docs[version] == nil {
+			docs[version] = make(map[string]*types.Post)
+		}
+		docs[version][docsRel(post.FilePath, version)] = post
+	}
+	return docs
+}
+
+// docNode is a node of the docs tree while it is built.
+type docNode struct {
+	view.DocNode
+	weight   int
+	children []*docNode
+}
+
+// docsTree returns the docs sidebar of version in lang, with nested sections
+// for the directories of its tree ordered by weight and title, and the node of
+// its top index file if there is one. Docs not translated into lang are linked
+// in their main language.
+func docsTree(gc *GenerationContext, lang types.Lang, version string) (nodes []*view.DocNode, home *view.DocNode) {
+	sections := map[string]*docNode{".": {}}
+	var section func(dir string) *docNode
+	section = func(dir string) *docNode {
+		if n, ok := sections[dir]; ok {
+			return n
+		}
+		n := &docNode{DocNode: view.DocNode{Title: path.Base(dir)}}
+		sections[dir] = n
+		parent := section(path.Dir(dir))
+		parent.children = append(parent.children, n)
+		return n
+	}
+
+	for _, post := range gc.Docs[version] {
+		if post.Main.Metadata.Hidden {
+			continue
+		}
+		doc, ok := post.Translated[lang]
+		if !ok {
+			doc = post.Main
+		}
+
+		dir, index := docsSection(post.FilePath, version)
+		n := &docNode{}
+		if index && dir != "." {
+			n = section(dir)
+		} else {
+			parent := section(dir)
+			parent.children = append(parent.children, n)
+		}
+		n.Title = doc.Metadata.Title
+		n.URL = docURL(post, lang)
+		n.weight = post.Main.Metadata.Weight
+		if index && dir == "." {
+			home = &n.DocNode
+		}
+	}
+
+	var build func(n *docNode) []*view.DocNode
+	build = func(n *docNode) []*view.DocNode {
+		sort.Slice(n.children, func(i, j int) bool {
+			a, b := n.children[i], n.children[j]
+			if a.weight != b.weight {
+				return a.weight < b.weight
+			}
+			return a.Title < b.Title
+		})
+		nodes := make([]*view.DocNode, 0, len(n.children))
+		for _, c := range n.children {
+			c.Children = build(c)
+			nodes = append(nodes, &c.DocNode)
+		}
+		return nodes
+	}
+	return build(sections["."]), home
+}
+
+// docsVersions returns the version switcher of the doc post in lang. Each
+// version links to the same page in that version, or to its top page if the
+// page does not exist there.
+func docsVersions(gc *GenerationContext, post *types.Post, lang types.Lang) []view.DocsVersion {
+	version := docsVersion(gc.Config, post.FilePath)
+	rel := docsRel(post.FilePath, version)
+	var versions []view.DocsVersion
+	for _, v := range gc.Config.Docs.Versions {
+		docs := gc.Docs[v]
+		url := baseURL + pageURL(langPath("/"+docsDir+"/"+v, lang))
+		if p, ok := docs[rel]; ok {
+			url = docURL(p, lang)
+		} else if p, ok := docs["index"]; ok {
+			url = docURL(p, lang)
+		}
+		versions = append(versions, view.DocsVersion{Name: v, URL: url, Current: v == version, Latest: v == gc.Config.Docs.Latest})
+	}
+	return versions
+}
+
+// docsNav returns the sidebar, breadcrumbs and versions of the doc page of
+// post in lang.
+func docsNav(gc *GenerationContext, post *types.Post, lang types.Lang) *view.DocsNav {
+	url := postURL(post, lang)
+	version := docsVersion(gc.Config, post.FilePath)
+	nav := &view.DocsNav{Version: version}
+	var home *view.DocNode
+	nav.Sidebar, home = docsTree(gc, lang, version)
+
+	var mark func(nodes []*view.DocNode, trail []view.Breadcrumb) bool
+	mark = func(nodes []*view.DocNode, trail []view.Breadcrumb) bool {
+		for _, n := range nodes {
+			crumbs := append(trail[:len(trail):len(trail)], view.Breadcrumb{Title: n.Title, URL: n.URL})
+			if n.URL == url {
+				n.Active, n.Open = true, true
+				nav.Breadcrumbs = crumbs
+				return true
+			}
+			if mark(n.Children, crumbs) {
+				n.Open = true
+				return true
+			}
+		}
+		return false
+	}
+	var trail []view.Breadcrumb
+	if home != nil && home.URL != url {
+		trail = append(trail, view.Breadcrumb{Title: home.Title, URL: home.URL})
+	}
+	mark(nav.Sidebar, trail)
+
+	if version != "" {
+		nav.Versions = docsVersions(gc, post, lang)
+		for _, v := range nav.Versions {
+			if v.Latest && !v.Current {
+				nav.LatestURL = v.URL
+			}
+		}
+	}
+	return nav
+}
+
+// DocsVersions is the version list written to /docs/versions.json, for
+// version switchers outside the generated pages.
+type DocsVersions struct {
+	Latest   string             `json:"latest"`
+	Versions []DocsVersionEntry `json:"versions"`
+}
+
+type DocsVersionEntry struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// docsLatestRedirects returns the redirects of the /docs/latest alias in every
+// language, for hosts that read _redirects.
+func docsLatestRedirects(gc *GenerationContext) []Redirect {
+	latest := gc.Config.Docs.Latest
+	if latest == "" {
+		return nil
+	}
+	var redirects []Redirect
+	for _, lang := range types.SupportedLanguages {
+		from := langPath("/"+docsDir+"/"+docsLatest, lang)
+		to := langPath("/"+docsDir+"/"+latest, lang)
+		redirects = append(redirects,
+			Redirect{From: from, To: to, Status: 302},
+			Redirect{From: from + "/*", To: to + "/:splat", Status: 302},
+		)
+	}
+	return redirects
+}
+
+// generateDocsVersions writes /docs/versions.json and the redirect stubs of
+// the /docs/latest alias, which follows docs.latest.
+func generateDocsVersions(gc *GenerationContext) error {
+	dc := &gc.Config.Docs
+	if len(dc.Versions) == 0 {
+		return nil
+	}
+	log.Debug().Str("latest", dc.Latest).Msg("start generating docs versions")
+
+	list := DocsVersions{Latest: dc.Latest}
+	for _, v := range dc.Versions {
+		url := baseURL + pageURL("/"+docsDir+"/"+v)
+		if p, ok := gc.Docs[v]["index"]; ok {
+			url = docURL(p, types.LangEnglish)
+		}
+		list.Versions = append(list.Versions, DocsVersionEntry{Name: v, URL: url})
+	}
+	err := writeJSON("/"+docsDir+"/versions.json", &list)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	prefix := "/" + docsDir + "/" + dc.Latest
+	for _, post := range gc.Docs[dc.Latest] {
+		alias := docsPath(post.FilePath)
+		if !strings.HasPrefix(alias, prefix) {
+			continue
+		}
+		alias = "/" + docsDir + "/" + docsLatest + strings.TrimPrefix(alias, prefix)
+		for lang := range post.Translated {
+			b.Reset()
+			err = view.RedirectPage(postURL(post, lang)).Render(context.Background(), &b)
+			if err != nil {
+				return err
+			}
+			fp := pageFile(langPath(alias, lang))
+			err = os.MkdirAll(filepath.Dir(fp), 0755)
+			if err != nil {
+				return err
+			}
+			err = os.WriteFile(fp, b.Bytes(), 0644)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	log.Debug().Int("versions", len(dc.Versions)).Msg("done generating docs versions")
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/minhash"
+	"gosuda.org/website/internal/types"
+)
+
+// duplicate is a pair of documents of different posts with near-identical text.
+type duplicate struct {
+	a, b       *types.Post
+	lang       string
+	similarity float64
+}
+
+// findDuplicates returns the pairs of documents in the same language, from
+// different posts, whose text is at least threshold similar. Translations are
+// compared too, which catches posts imported twice or re-translated into the
+// language of another post.
+func findDuplicates(gc *GenerationContext, threshold float64) []duplicate {
+	type entry struct {
+		post *types.Post
+		lang string
+	}
+	var entries []entry
+	var sigs []minhash.Signature
+	for _, post := range publishedPosts(gc) {
+		if post.Main == nil {
+			continue
+		}
+		for lang, doc := range post.Translated {
+			doc, err := gc.DataStore.document(post, doc)
+			if err != nil {
+				log.Error().Err(err).Str("path", post.FilePath).Str("lang", lang).Msgf("failed to read the %s document of %s", lang, post.FilePath)
+				continue
+			}
+			prose, _ := documentProse(doc)
+			if len(minhash.Words(prose)) < minhash.ShingleSize {
+				continue
+			}
+			entries = append(entries, entry{post, lang})
+			sigs = append(sigs, minhash.New(prose))
+		}
+	}
+
+	var dups []duplicate
+	seen := make(map[[2]string]bool)
+	for _, p := range minhash.Similar(sigs, threshold) {
+		a, b := entries[p.I], entries[p.J]
+		if a.post == b.post || a.lang != b.lang {
+			continue
+		}
+		// report each pair of posts once, at the language it was found in first
+		key := [2]string{min(a.post.ID, b.post.ID), max(a.post.ID, b.post.ID)}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		dups = append(dups, duplicate{a: a.post, b: b.post, lang: a.lang, similarity: p.Similarity})
+	}
+	sort.Slice(dups, func(i, j int) bool {
+		return dups[i].similarity > dups[j].similarity
+	})
+	return dups
+}
+
+// checkDuplicates warns about near-duplicate posts, and fails the build if
+// duplicates.fail is set.
+func checkDuplicates(gc *GenerationContext) error {
+	dc := &gc.Config.Duplicates
+	log.Debug().Float64("threshold", dc.Threshold).Msg("start checking for duplicate posts")
+	dups := findDuplicates(gc, dc.Threshold)
+	for _, d := range dups {
+		log.Warn().Str("a", d.a.FilePath).Str("b", d.b.FilePath).Str("lang", d.lang).Float64("similarity", d.similarity).
+			Msgf("posts %q and %q are near-duplicates", d.a.Main.Metadata.Title, d.b.Main.Metadata.Title)
+	}
+	log.Debug().Int("duplicates", len(dups)).Msg("done checking for duplicate posts")
+	if dc.Fail && len(dups) > 0 {
+		return fmt.Errorf("found %d near-duplicate posts", len(dups))
+	}
+	return nil
+}
+
+func report_duplicates_main(args []string) {
+	fs := flag.NewFlagSet("report duplicates", flag.ExitOnError)
+	threshold := fs.Float64("threshold", 0, "report posts at least this similar, from 0 to 1 (default: duplicates.threshold)")
+	fail := fs.Bool("fail", false, "exit with a non-zero status if any posts are near-duplicates")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+	if *threshold <= 0 {
+		*threshold = cfg.Duplicates.Threshold
+	}
+	ds, err := initializeDatabaseContent(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+	gc := &GenerationContext{Config: cfg, DataStore: ds}
+
+	dups := findDuplicates(gc, *threshold)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SIMILARITY\tLANG\tFILE\tFILE")
+	for _, d := range dups {
+		fmt.Fprintf(w, "%.2f\t%s\t%s\t%s\n", d.similarity, d.lang, d.a.FilePath, d.b.FilePath)
+	}
+	w.Flush()
+
+	log.Info().Int("duplicates", len(dups)).Msgf("found %d near-duplicate posts", len(dups))
+	if *fail && len(dups) > 0 {
+		os.Exit(1)
+	}
+}
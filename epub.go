@@ -0,0 +1,488 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"gosuda.org/website/internal/types"
+)
+
+// seriesPosts returns the posts of a series in reading order.
+func seriesPosts(ds *DataStore, name string) []*types.Post {
+	var posts []*types.Post
+	for _, post := range ds.Posts {
+		if post.Main.Metadata.Series == name {
+			posts = append(posts, post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		a, b := &posts[i].Main.Metadata, &posts[j].Main.Metadata
+		if a.SeriesOrder != b.SeriesOrder {
+			return a.SeriesOrder < b.SeriesOrder
+		}
+		return a.Date.Before(b.Date)
+	})
+	return posts
+}
+
+type epubItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr,omitempty"`
+}
+
+type epubItemRef struct {
+	IDRef string `xml:"idref,attr"`
+}
+
+type epubMeta struct {
+	Property string `xml:"property,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type epubPackage struct {
+	XMLName          xml.Name `xml:"http://www.idpf.org/2007/opf package"`
+	Version          string   `xml:"version,attr"`
+	UniqueIdentifier string   `xml:"unique-identifier,attr"`
+	Lang             string   `xml:"xml:lang,attr"`
+	Metadata         struct {
+		DC          string     `xml:"xmlns:dc,attr"`
+		Identifier  epubID     `xml:"dc:identifier"`
+		Title       string     `xml:"dc:title"`
+		Language    string     `xml:"dc:language"`
+		Creator     []string   `xml:"dc:creator"`
+		Description string     `xml:"dc:description,omitempty"`
+		Publisher   string     `xml:"dc:publisher"`
+		Date        string     `xml:"dc:date"`
+		Meta        []epubMeta `xml:"meta"`
+	} `xml:"metadata"`
+	Manifest []epubItem    `xml:"manifest>item"`
+	Spine    []epubItemRef `xml:"spine>itemref"`
+}
+
+type epubID struct {
+	ID    string `xml:"id,attr"`
+	Value string `xml:",chardata"`
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+var epubPage = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="{{.Lang}}" lang="{{.Lang}}">
+<head>
+<meta charset="utf-8"/>
+<title>{{.Title}}</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+{{- if .Nav}}
+<nav epub:type="toc" id="toc">
+<h1>{{.Title}}</h1>
+<ol>
+{{- range .Nav}}
+<li><a href="{{.File}}">{{.Title}}</a></li>
+{{- end}}
+</ol>
+</nav>
+{{- else if .Cover}}
+<section epub:type="cover" class="cover"><img src="{{.Cover}}" alt="{{.Title}}"/></section>
+{{- else}}
+<section epub:type="chapter">
+<header>
+<h1>{{.Title}}</h1>
+<p class="meta">{{.Author}} · {{.Date.Format "January 2, 2006"}} · <a href="{{.URL}}">{{.URL}}</a></p>
+</header>
+{{.Body}}
+</section>
+{{- end}}
+</body>
+</html>
+`))
+
+const epubStyle = `body { font-family: serif; line-height: 1.5; }
+h1 { font-size: 1.6em; }
+.meta { color: #555; font-size: 0.85em; }
+pre { white-space: pre-wrap; word-wrap: break-word; font-size: 0.8em; }
+code { font-family: monospace; }
+img { max-width: 100%; }
+.cover { text-align: center; }
+.cover img { max-height: 95vh; }
+`
+
+type epubPageData struct {
+	Lang   string
+	Title  string
+	Author string
+	Date   time.Time
+	URL    string
+	Body   template.HTML
+	Cover  string
+	Nav    []epubNavEntry
+}
+
+type epubNavEntry struct {
+	File  string
+	Title string
+}
+
+// epubBuilder collects the files of an EPUB. Images referenced by chapters are
+// embedded, since reading systems do not load remote images.
+type epubBuilder struct {
+	lang     string
+	files    map[string][]byte
+	manifest []epubItem
+	spine    []epubItemRef
+	images   map[string]string // source URL -> file name
+	client   *http.Client
+}
+
+func (e *epubBuilder) add(id, file, mediaType, properties string, data []byte) {
+	e.files[file] = data
+	e.manifest = append(e.manifest, epubItem{ID: id, Href: file, MediaType: mediaType, Properties: properties})
+}
+
+// image embeds the image at src and returns its file name in the EPUB.
+func (e *epubBuilder) image(src string) (string, error) {
+	if file, ok := e.images[src]; ok {
+		return file, nil
+	}
+
+	var data []byte
+	var err error
+	typ := imageType(src)
+	switch {
+	case strings.HasPrefix(src, "https://") || strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "//"):
+		if strings.HasPrefix(src, "//") {
+			src = "https:" + src
+		}
+		resp, err := e.client.Get(src)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("fetching %s: %s", src, resp.Status)
+		}
+		data, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		if ct, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); strings.HasPrefix(ct, "image/") {
+			typ = ct
+		}
+	case strings.HasPrefix(src, "/"):
+		data, err = os.ReadFile(publicPath(src))
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported image reference %s", src)
+	}
+	if !strings.HasPrefix(typ, "image/") {
+		return "", fmt.Errorf("%s is not an image", src)
+	}
+
+	ext := path.Ext(strings.SplitN(path.Base(src), "?", 2)[0])
+	if ext == "" {
+		exts, _ := mime.ExtensionsByType(typ)
+		if len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	id := fmt.Sprintf("img%d", len(e.images)+1)
+	file := "images/" + id + strings.ToLower(ext)
+	e.add(id, file, typ, "", data)
+	e.images[src] = file
+	return file, nil
+}
+
+// epubDropped lists elements removed from chapters together with their content.
+var epubDropped = map[atom.Atom]bool{atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Form: true}
+
+// chapterXHTML converts rendered post HTML to XHTML, embedding its images.
+func (e *epubBuilder) chapterXHTML(doc, pageURL string) (string, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(doc), body)
+	if err != nil {
+		return "", err
+	}
+
+	var walk func(n *html.Node) error
+	walk = func(n *html.Node) error {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			switch {
+			case c.Type == html.CommentNode, c.Type == html.ElementNode && epubDropped[c.DataAtom]:
+				n.RemoveChild(c)
+			case c.Type == html.ElementNode:
+				attrs := c.Attr[:0]
+				for _, a := range c.Attr {
+					if strings.HasPrefix(a.Key, "on") || strings.HasPrefix(a.Key, "data-") || strings.Contains(a.Key, ":") {
+						continue
+					}
+					switch {
+					case a.Key == "href" && !strings.HasPrefix(a.Val, "#"):
+						a.Val = absURL(pageURL, a.Val)
+					case a.Key == "src" && c.DataAtom == atom.Img:
+						file, err := e.image(a.Val)
+						if err != nil {
+							log.Warn().Err(err).Str("src", a.Val).Msgf("failed to embed image %s", a.Val)
+							file = a.Val
+						}
+						a.Val = file
+					case a.Key == "srcset" || a.Key == "loading":
+						continue
+					}
+					attrs = append(attrs, a)
+				}
+				c.Attr = attrs
+				if err := walk(c); err != nil {
+					return err
+				}
+			}
+			c = next
+		}
+		return nil
+	}
+
+	var b bytes.Buffer
+	for _, n := range nodes {
+		if n.Type == html.CommentNode || n.Type == html.ElementNode && epubDropped[n.DataAtom] {
+			continue
+		}
+		body.AppendChild(n)
+	}
+	if err := walk(body); err != nil {
+		return "", err
+	}
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&b, c); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+func (e *epubBuilder) page(id, file, properties string, data *epubPageData, linear bool) error {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	data.Lang = e.lang
+	err := epubPage.Execute(&b, data)
+	if err != nil {
+		return err
+	}
+	e.add(id, file, "application/xhtml+xml", properties, b.Bytes())
+	if linear {
+		e.spine = append(e.spine, epubItemRef{IDRef: id})
+	}
+	return nil
+}
+
+// write writes the EPUB container. The mimetype file must come first and be stored uncompressed.
+func (e *epubBuilder) write(w io.Writer, pkg *epubPackage) error {
+	zw := zip.NewWriter(w)
+	mt, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	_, err = mt.Write([]byte("application/epub+zip"))
+	if err != nil {
+		return err
+	}
+
+	opf, err := xml.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		"META-INF/container.xml": []byte(epubContainer),
+		"OEBPS/content.opf":      append([]byte(xmlHeader), opf...),
+	}
+	for name, data := range e.files {
+		files["OEBPS/"+name] = data
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(files[name])
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// epubIdentifier derives a stable UUID for the series, so re-exports update the same book.
+func epubIdentifier(name string) string {
+	h := sha1.Sum([]byte(baseURL + "/series/" + name))
+	h[6] = h[6]&0x0f | 0x50
+	h[8] = h[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
+
+func export_epub_main(args []string) {
+	fs := flag.NewFlagSet("export epub", flag.ExitOnError)
+	series := fs.String("series", "", "name of the series to export (required)")
+	lang := fs.String("lang", "", "language of the book (default: the original language of the first post)")
+	output := fs.String("o", "", "output file (default: <series>.epub)")
+	fs.Parse(args)
+	if *series == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+	ds, err := initializeDatabaseContent(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+
+	posts := seriesPosts(ds, *series)
+	if len(posts) == 0 {
+		log.Fatal().Msgf("series %q has no posts", *series)
+	}
+	if *lang == "" {
+		*lang = posts[0].Main.Metadata.Language
+	}
+
+	sc := cfg.Series[*series]
+	if sc.Title == "" {
+		sc.Title = *series
+	}
+	if sc.Image == "" {
+		sc.Image = posts[0].Main.Metadata.Image
+	}
+
+	e := &epubBuilder{
+		lang:   *lang,
+		files:  make(map[string][]byte),
+		images: make(map[string]string),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	e.add("style", "style.css", "text/css", "", []byte(epubStyle))
+
+	pkg := &epubPackage{Version: "3.0", UniqueIdentifier: "bookid", Lang: *lang}
+	pkg.Metadata.DC = "http://purl.org/dc/elements/1.1/"
+	pkg.Metadata.Identifier = epubID{ID: "bookid", Value: epubIdentifier(*series)}
+	pkg.Metadata.Title = sc.Title
+	pkg.Metadata.Language = *lang
+	pkg.Metadata.Description = sc.Description
+	pkg.Metadata.Publisher = "GoSuda"
+
+	if sc.Image != "" {
+		file, err := e.image(sc.Image)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to embed cover image %s", sc.Image)
+		}
+		for i := range e.manifest {
+			if e.manifest[i].Href == file {
+				e.manifest[i].Properties = "cover-image"
+			}
+		}
+		err = e.page("cover", "cover.xhtml", "", &epubPageData{Title: sc.Title, Cover: file}, true)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to render cover page")
+		}
+	}
+
+	var nav []epubNavEntry
+	var authors []string
+	var modified time.Time
+	seen := make(map[string]bool)
+	for i, post := range posts {
+		doc, ok := post.Translated[*lang]
+		if !ok {
+			log.Fatal().Msgf("post %s has no %s translation", post.ID, *lang)
+		}
+		pageURL := postURL(post, *lang)
+		body, err := e.chapterXHTML(doc.HTML, pageURL)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to convert post %s", post.ID)
+		}
+
+		id := fmt.Sprintf("chapter%d", i+1)
+		file := id + ".xhtml"
+		err = e.page(id, file, "", &epubPageData{
+			Title:  doc.Metadata.Title,
+			Author: doc.Metadata.Author,
+			Date:   doc.Metadata.Date,
+			URL:    pageURL,
+			Body:   template.HTML(body),
+		}, true)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to render post %s", post.ID)
+		}
+		nav = append(nav, epubNavEntry{File: file, Title: doc.Metadata.Title})
+
+		if a := doc.Metadata.Author; a != "" && !seen[a] {
+			seen[a] = true
+			authors = append(authors, a)
+		}
+		if post.UpdatedAt.After(modified) {
+			modified = post.UpdatedAt
+		}
+	}
+
+	if sc.Author != "" {
+		authors = []string{sc.Author}
+	}
+	pkg.Metadata.Creator = authors
+	pkg.Metadata.Date = posts[0].Main.Metadata.Date.UTC().Format(time.RFC3339)
+	pkg.Metadata.Meta = []epubMeta{{Property: "dcterms:modified", Value: modified.UTC().Format("2006-01-02T15:04:05Z")}}
+
+	err = e.page("nav", "nav.xhtml", "nav", &epubPageData{Title: sc.Title, Nav: nav}, false)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to render table of contents")
+	}
+	pkg.Manifest = e.manifest
+	pkg.Spine = e.spine
+
+	out := *output
+	if out == "" {
+		out = *series + ".epub"
+	}
+	var b bytes.Buffer
+	err = e.write(&b, pkg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to build EPUB")
+	}
+	err = os.WriteFile(out, b.Bytes(), 0644)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to write %s", out)
+	}
+	log.Info().Int("chapters", len(nav)).Int("images", len(e.images)).Msgf("exported series %s to %s", *series, out)
+}
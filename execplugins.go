@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// Exec plugins are programs the generator starts once per build and calls with
+// JSON-RPC 1.0 over their standard input and output, one request at a time.
+// Their standard error is passed through. The params of every request are an
+// array of one object, as in {"method": "Plugin.Init", "params": [{...}],
+// "id": 0}, and the result of the response is an object:
+//
+//   - Plugin.Init {name, base_url, options} returns {points}, the extension
+//     points the plugin serves: "transform", "enrich", "output" or "deploy".
+//   - Plugin.TransformContent {post, lang, html} returns {html}.
+//   - Plugin.EnrichMetadata {post, lang, meta} returns {meta}; the fields
+//     left out of the returned meta keep their values.
+//   - Plugin.GenerateOutput {dist, base_url, posts} returns {}.
+//   - Plugin.Deploy {dist, deploy} returns {}.
+//
+// The plugin exits when its standard input is closed. plugins/banner is an
+// example written with net/rpc/jsonrpc.
+
+// pluginPost is a post as sent to exec plugins, with the metadata of one
+// language.
+type pluginPost struct {
+	ID       string         `json:"id"`
+	Path     string         `json:"path"`
+	FilePath string         `json:"file_path"`
+	Metadata types.Metadata `json:"metadata"`
+}
+
+// pluginMeta is the part of the page metadata exec plugins can change.
+type pluginMeta struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Keywords    []string `json:"keywords"`
+	Image       string   `json:"image"`
+	Robots      string   `json:"robots"`
+	Scripts     []string `json:"scripts"`
+	Styles      []string `json:"styles"`
+	Head        string   `json:"head"`
+}
+
+type execInit struct {
+	Name    string         `json:"name"`
+	BaseURL string         `json:"base_url"`
+	Options map[string]any `json:"options,omitempty"`
+}
+
+type execTransform struct {
+	Post pluginPost `json:"post"`
+	Lang types.Lang `json:"lang"`
+	HTML string     `json:"html"`
+}
+
+type execEnrich struct {
+	Post pluginPost `json:"post"`
+	Lang types.Lang `json:"lang"`
+	Meta pluginMeta `json:"meta"`
+}
+
+type execOutput struct {
+	Dist    string       `json:"dist"`
+	BaseURL string       `json:"base_url"`
+	Posts   []pluginPost `json:"posts"`
+}
+
+type execDeploy struct {
+	Dist   string       `json:"dist"`
+	Deploy DeployConfig `json:"deploy"`
+}
+
+// execPlugin is a running exec plugin.
+type execPlugin struct {
+	name   string
+	points []string
+	cmd    *exec.Cmd
+	client *rpc.Client
+}
+
+// stdio joins the pipes of a plugin process into a connection.
+type stdio struct {
+	io.ReadCloser
+	w io.WriteCloser
+}
+
+func (c stdio) Write(p []byte) (int, error) { return c.w.Write(p) }
+
+func (c stdio) Close() error {
+	err := c.w.Close()
+	c.ReadCloser.Close()
+	return err
+}
+
+// startExecPlugin starts the plugin of pc and asks it for its extension points.
+func startExecPlugin(pc *ExecPluginConfig) (*execPlugin, error) {
+	if pc.Name == "" || len(pc.Command) == 0 {
+		return nil, fmt.Errorf("plugins.exec: a plugin needs a name and a command")
+	}
+	cmd := exec.Command(pc.Command[0], pc.Command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", pc.Name, err)
+	}
+
+	p := &execPlugin{
+		name:   pc.Name,
+		cmd:    cmd,
+		client: jsonrpc.NewClient(stdio{stdout, stdin}),
+	}
+	var reply struct {
+		Points []string `json:"points"`
+	}
+	err = p.call("Init", execInit{Name: pc.Name, BaseURL: baseURL, Options: pc.Options}, &reply)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	for _, point := range reply.Points {
+		if !slices.Contains([]string{"transform", "enrich", "output", "deploy"}, point) {
+			p.Close()
+			return nil, fmt.Errorf("plugin %s: unknown extension point %q", pc.Name, point)
+		}
+	}
+	p.points = reply.Points
+	log.Debug().Str("plugin", pc.Name).Strs("points", p.points).Msgf("started plugin %s", pc.Name)
+	return p, nil
+}
+
+func (p *execPlugin) call(method string, args, reply any) error {
+	err := p.client.Call("Plugin."+method, args, reply)
+	if err != nil {
+		return fmt.Errorf("plugin %s: %s: %w", p.name, method, err)
+	}
+	return nil
+}
+
+// Close closes the standard input of the plugin and waits for it to exit.
+func (p *execPlugin) Close() error {
+	p.client.Close()
+	return p.cmd.Wait()
+}
+
+func (p *execPlugin) Name() string { return p.name }
+
+func (p *execPlugin) Implements(point string) bool {
+	return slices.Contains(p.points, point)
+}
+
+func newPluginPost(post *types.Post, lang types.Lang) pluginPost {
+	pp := pluginPost{ID: post.ID, Path: post.Path, FilePath: post.FilePath, Metadata: post.Main.Metadata}
+	if doc, ok := post.Translated[lang]; ok {
+		pp.Metadata = doc.Metadata
+	}
+	return pp
+}
+
+func (p *execPlugin) TransformContent(gc *GenerationContext, post *types.Post, lang types.Lang, doc string) (string, error) {
+	var reply struct {
+		HTML string `json:"html"`
+	}
+	err := p.call("TransformContent", execTransform{Post: newPluginPost(post, lang), Lang: lang, HTML: doc}, &reply)
+	return reply.HTML, err
+}
+
+func (p *execPlugin) EnrichMetadata(gc *GenerationContext, post *types.Post, lang types.Lang, meta *view.Metadata) error {
+	pm := pluginMeta{
+		Title:       meta.Title,
+		Description: meta.Description,
+		Keywords:    meta.Keywords,
+		Image:       meta.Image,
+		Robots:      meta.Robots,
+		Scripts:     meta.Scripts,
+		Styles:      meta.Styles,
+		Head:        meta.CustomHead,
+	}
+	reply := struct {
+		Meta *pluginMeta `json:"meta"`
+	}{&pm}
+	err := p.call("EnrichMetadata", execEnrich{Post: newPluginPost(post, lang), Lang: lang, Meta: pm}, &reply)
+	if err != nil {
+		return err
+	}
+	meta.Title, meta.Description, meta.Keywords, meta.Image = pm.Title, pm.Description, pm.Keywords, pm.Image
+	meta.Robots, meta.Scripts, meta.Styles, meta.CustomHead = pm.Robots, pm.Scripts, pm.Styles, pm.Head
+	return nil
+}
+
+func (p *execPlugin) GenerateOutput(gc *GenerationContext) error {
+	dist, err := filepath.Abs(distDir)
+	if err != nil {
+		return err
+	}
+	var posts []pluginPost
+	for _, post := range publishedPosts(gc) {
+		posts = append(posts, newPluginPost(post, post.Main.Metadata.Language))
+	}
+	return p.call("GenerateOutput", execOutput{Dist: dist, BaseURL: baseURL, Posts: posts}, &struct{}{})
+}
+
+func (p *execPlugin) Deploy(cfg *SiteConfig, dir string) error {
+	dist, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	return p.call("Deploy", execDeploy{Dist: dist, Deploy: cfg.Deploy}, &struct{}{})
+}
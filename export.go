@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+func export_main() {
+	if len(os.Args) < 3 {
+		log.Fatal().Msg("usage: export <email|pdf|epub> ...")
+	}
+
+	switch os.Args[2] {
+	case "email":
+		export_email_main(os.Args[3:]) // render a post as newsletter HTML.
+	case "pdf":
+		export_pdf_main(os.Args[3:]) // render posts to a printable PDF.
+	case "epub":
+		export_epub_main(os.Args[3:]) // bundle a post series into an EPUB.
+	default:
+		log.Fatal().Msgf("unknown export %q", os.Args[2])
+	}
+}
+
+// emailStyles are the inline styles applied to elements of the post body,
+// since most email clients ignore stylesheets.
+var emailStyles = map[string]string{
+	"h1":         "font-size:26px;line-height:1.25;margin:24px 0 12px 0;",
+	"h2":         "font-size:22px;line-height:1.3;margin:24px 0 12px 0;",
+	"h3":         "font-size:19px;line-height:1.3;margin:20px 0 8px 0;",
+	"h4":         "font-size:17px;margin:16px 0 8px 0;",
+	"p":          "margin:0 0 16px 0;",
+	"a":          "color:#1d4ed8;text-decoration:underline;",
+	"ul":         "margin:0 0 16px 0;padding-left:24px;",
+	"ol":         "margin:0 0 16px 0;padding-left:24px;",
+	"li":         "margin:0 0 4px 0;",
+	"blockquote": "margin:0 0 16px 0;padding:0 0 0 12px;border-left:4px solid #cccccc;color:#555555;",
+	"pre":        "margin:0 0 16px 0;padding:12px;background-color:#f5f5f5;border:1px solid #dddddd;font-family:Menlo,Consolas,monospace;font-size:13px;line-height:1.45;white-space:pre-wrap;word-break:break-all;",
+	"code":       "font-family:Menlo,Consolas,monospace;font-size:14px;background-color:#f5f5f5;",
+	"img":        "max-width:100%;height:auto;border:0;",
+	"table":      "border-collapse:collapse;margin:0 0 16px 0;",
+	"th":         "border:1px solid #cccccc;padding:6px 10px;text-align:left;background-color:#f5f5f5;",
+	"td":         "border:1px solid #cccccc;padding:6px 10px;",
+	"hr":         "border:0;border-top:1px solid #cccccc;margin:24px 0;",
+}
+
+// emailStripped lists elements that are removed together with their content.
+var emailStripped = map[string]bool{"script": true, "style": true, "noscript": true, "iframe": true, "object": true, "embed": true, "form": true, "template": true}
+
+// emailHTML converts rendered post HTML to email-safe markup: scripts, embeds and
+// comments (including shortcode placeholders) are removed, URLs are made absolute
+// and styles are inlined.
+func emailHTML(doc, pageURL string) (string, error) {
+	var skip string
+	depth := 0
+	inPre := false
+	out, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		if skip != "" {
+			switch {
+			case t.Type == htmlrewrite.StartTagToken && t.Data == skip:
+				depth++
+			case t.Type == htmlrewrite.EndTagToken && t.Data == skip:
+				depth--
+				if depth == 0 {
+					skip = ""
+				}
+			}
+			return []byte{}
+		}
+
+		switch t.Type {
+		case htmlrewrite.CommentToken:
+			return []byte{}
+		case htmlrewrite.EndTagToken:
+			if t.Data == "pre" {
+				inPre = false
+			}
+			if emailStripped[t.Data] {
+				return []byte{}
+			}
+			return nil
+		case htmlrewrite.StartTagToken, htmlrewrite.SelfClosingTagToken:
+		default:
+			return nil
+		}
+
+		if emailStripped[t.Data] {
+			if t.Type == htmlrewrite.StartTagToken {
+				skip, depth = t.Data, 1
+			}
+			return []byte{}
+		}
+
+		attrs := t.Token.Attr[:0]
+		for _, a := range t.Token.Attr {
+			if a.Key == "class" || strings.HasPrefix(a.Key, "on") || strings.HasPrefix(a.Key, "data-") {
+				continue
+			}
+			if a.Key == "href" || a.Key == "src" {
+				a.Val = absURL(pageURL, a.Val)
+			}
+			attrs = append(attrs, a)
+		}
+		t.Token.Attr = attrs
+
+		if t.Data == "pre" {
+			inPre = t.Type == htmlrewrite.StartTagToken
+		}
+		if style, ok := emailStyles[t.Data]; ok && !(inPre && t.Data == "code") {
+			if old, ok := t.Attr("style"); ok {
+				style += old
+			}
+			t.SetAttr("style", style)
+		}
+		return t.Render()
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func export_email_main(args []string) {
+	fs := flag.NewFlagSet("export email", flag.ExitOnError)
+	lang := fs.String("lang", "", "language of the exported post (default: the original language)")
+	output := fs.String("o", "", "write the email to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: export email [flags] <post-id>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ds, err := initializeDatabaseContent(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+
+	id := fs.Arg(0)
+	post, ok := ds.Posts[id]
+	if !ok {
+		log.Fatal().Msgf("post %s not found", id)
+	}
+	if *lang == "" {
+		*lang = post.Main.Metadata.Language
+	}
+	doc, ok := post.Translated[types.Lang(*lang)]
+	if !ok {
+		log.Fatal().Msgf("post %s has no %s translation", id, *lang)
+	}
+
+	pageURL := postURL(post, *lang)
+	body, err := emailHTML(doc.HTML, pageURL)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to convert post %s to email HTML", id)
+	}
+
+	var b bytes.Buffer
+	err = view.EmailPage(&view.Email{
+		Language:    *lang,
+		Title:       doc.Metadata.Title,
+		Description: doc.Metadata.Description,
+		Author:      doc.Metadata.Author,
+		Date:        doc.Metadata.Date,
+		URL:         pageURL,
+		Body:        body,
+	}).Render(localizedContext(*lang), &b)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to render email for post %s", id)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(b.Bytes())
+		return
+	}
+	err = os.WriteFile(*output, b.Bytes(), 0644)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to write %s", *output)
+	}
+	log.Info().Str("post_id", id).Str("lang", *lang).Msgf("exported email to %s", *output)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// checkExtraRef returns an error unless ref is the site path of a file in dist
+// or a URL of an allowed origin.
+func checkExtraRef(ec *ExtrasConfig, ref string) error {
+	if strings.HasPrefix(ref, "/") && !strings.HasPrefix(ref, "//") {
+		u, err := url.Parse(ref)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(filepath.Join(distDir, filepath.FromSlash(path.Clean(u.Path)))); err != nil {
+			return fmt.Errorf("%s does not exist in %s", ref, publicDir)
+		}
+		return nil
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%s is neither a site path nor an https URL", ref)
+	}
+	if !slices.Contains(ec.Origins, u.Scheme+"://"+u.Host) {
+		return fmt.Errorf("the origin of %s is not in extras.origins", ref)
+	}
+	return nil
+}
+
+// sanitizeHeadHTML checks that src has only allowed elements and references,
+// and returns it without event handler attributes.
+func sanitizeHeadHTML(ec *ExtrasConfig, src string) (string, error) {
+	var checkErr error
+	fail := func(format string, args ...any) []byte {
+		if checkErr == nil {
+			checkErr = fmt.Errorf(format, args...)
+		}
+		return []byte{}
+	}
+
+	var inRaw bool // in the text of a style or script element
+	out, err := htmlrewrite.Rewrite([]byte(src), func(t *htmlrewrite.Token) []byte {
+		switch t.Type {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if !slices.Contains(ec.HeadElements, t.Data) {
+				return fail("<%s> is not in extras.head_elements", t.Data)
+			}
+			inRaw = t.Data == "style" || t.Data == "script"
+			attrs := t.Token.Attr[:0]
+			for _, a := range t.Token.Attr {
+				if strings.HasPrefix(a.Key, "on") {
+					continue
+				}
+				switch a.Key {
+				case "href", "src":
+					if err := checkExtraRef(ec, a.Val); err != nil {
+						return fail("<%s %s>: %w", t.Data, a.Key, err)
+					}
+				case "http-equiv":
+					// refresh redirects and policies are up to the generator
+					return fail("<%s http-equiv> is not allowed", t.Data)
+				}
+				attrs = append(attrs, a)
+			}
+			t.Token.Attr = attrs
+			return t.Render()
+		case html.EndTagToken:
+			inRaw = false
+		case html.TextToken:
+			if !inRaw && len(bytes.TrimSpace(t.Raw)) > 0 {
+				return fail("text %q outside of an element", strings.TrimSpace(t.Data))
+			}
+		case html.CommentToken:
+			return []byte{}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(out), checkErr
+}
+
+// postExtras returns the extra scripts and stylesheets of a post and its
+// sanitized head markup, or an error if any is not allowed.
+func postExtras(gc *GenerationContext, post *types.Post) (scripts, styles []string, head string, err error) {
+	ec := &gc.Config.Extras
+	pm := &post.Main.Metadata
+	for _, ref := range pm.ExtraJS {
+		if err := checkExtraRef(ec, ref); err != nil {
+			return nil, nil, "", fmt.Errorf("%s: extra_js: %w", post.FilePath, err)
+		}
+		scripts = append(scripts, ref)
+	}
+	for _, ref := range pm.ExtraCSS {
+		if err := checkExtraRef(ec, ref); err != nil {
+			return nil, nil, "", fmt.Errorf("%s: extra_css: %w", post.FilePath, err)
+		}
+		styles = append(styles, ref)
+	}
+	if pm.HeadHTML != "" {
+		head, err = sanitizeHeadHTML(ec, pm.HeadHTML)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("%s: head_html: %w", post.FilePath, err)
+		}
+	}
+	return scripts, styles, head, nil
+}
+
+// enrichExtras adds the extra scripts, stylesheets and head markup of a post to
+// its page.
+func enrichExtras(gc *GenerationContext, post *types.Post, lang types.Lang, meta *view.Metadata) error {
+	scripts, styles, head, err := postExtras(gc, post)
+	if err != nil {
+		return err
+	}
+	meta.Scripts = append(meta.Scripts, scripts...)
+	meta.Styles = append(meta.Styles, styles...)
+	meta.CustomHead = head
+	return nil
+}
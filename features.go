@@ -0,0 +1,69 @@
+package main
+
+import (
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// featureConfigs returns the configurations of the features set in f.
+func featureConfigs(fc *FeaturesConfig, f types.Features) []*FeatureConfig {
+	var list []*FeatureConfig
+	for _, c := range []struct {
+		set bool
+		cfg *FeatureConfig
+	}{
+		{f.Code, &fc.Code},
+		{f.Math, &fc.Math},
+		{f.Mermaid, &fc.Mermaid},
+		{f.Gallery, &fc.Gallery},
+		{f.Tabs, &fc.Tabs},
+	} {
+		if c.set {
+			list = append(list, c.cfg)
+		}
+	}
+	return list
+}
+
+// featureAssets returns the scripts and stylesheets of the features set in f,
+// each once.
+func featureAssets(gc *GenerationContext, f types.Features) (scripts, styles []string) {
+	seen := make(map[string]bool)
+	for _, c := range featureConfigs(&gc.Config.Features, f) {
+		for _, src := range c.Scripts {
+			if !seen[src] {
+				seen[src] = true
+				scripts = append(scripts, src)
+			}
+		}
+		for _, href := range c.Styles {
+			if !seen[href] {
+				seen[href] = true
+				styles = append(styles, href)
+			}
+		}
+	}
+	return scripts, styles
+}
+
+// enrichFeatures adds the scripts and stylesheets of the features of a post to
+// its page.
+func enrichFeatures(gc *GenerationContext, post *types.Post, lang types.Lang, meta *view.Metadata) error {
+	// stored translations may predate feature tracking, the main document is rendered on every build
+	scripts, styles := featureAssets(gc, post.Main.Features)
+	meta.Scripts = append(meta.Scripts, scripts...)
+	meta.Styles = append(meta.Styles, styles...)
+	return nil
+}
+
+// featuresPolicy returns the CSP sources needed by the features.
+func featuresPolicy(fc *FeaturesConfig) cspPolicy {
+	p := cspPolicy{}
+	all := types.Features{Code: true, Math: true, Mermaid: true, Gallery: true, Tabs: true}
+	for _, c := range featureConfigs(fc, all) {
+		for directive, sources := range c.CSP {
+			p.add(directive, sources...)
+		}
+	}
+	return p
+}
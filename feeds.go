@@ -1,10 +1,11 @@
 package main
 
 import (
-	"bytes"
-	"context"
 	"encoding/hex"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/feeds"
@@ -14,23 +15,86 @@ import (
 	"gosuda.org/website/view"
 )
 
+// feedStylesheet returns the processing instruction that renders RSS feeds as
+// a page in browsers, see public/feed.xsl.
+func feedStylesheet() string {
+	return `<?xml-stylesheet type="text/xsl" href="` + sitePath("/feed.xsl") + `"?>` + "\n"
+}
+
+// styleFeed attaches the feed stylesheet to an RSS document, after its XML
+// declaration.
+func styleFeed(rss string) string {
+	if !strings.HasPrefix(rss, "<?xml") {
+		return feedStylesheet() + rss
+	}
+	decl, rest, _ := strings.Cut(rss, "?>")
+	return decl + "?>\n" + feedStylesheet() + strings.TrimLeft(rest, "\n")
+}
+
+// langFeedTitle returns the title of the feed of lang.
+func langFeedTitle(lang types.Lang) string {
+	if lang == types.LangEnglish {
+		return "GoSuda Blog"
+	}
+	return "GoSuda Blog" + " - " + types.FullLangName(lang)
+}
+
+// langFeedURL returns the URL of the feed of lang.
+func langFeedURL(lang types.Lang) string {
+	return baseURL + langPath("/feed.rss", lang)
+}
+
+// viewFeeds returns the feed autodiscovery links of pages in lang, starting
+// with the feed of lang.
+func viewFeeds(gc *GenerationContext, lang types.Lang) []view.FeedLink {
+	feeds := []view.FeedLink{{Title: langFeedTitle(lang), URL: langFeedURL(lang), Lang: lang}}
+	for _, l := range types.SupportedLanguages {
+		if l != lang {
+			feeds = append(feeds, view.FeedLink{Title: langFeedTitle(l), URL: langFeedURL(l), Lang: l})
+		}
+	}
+	if pc := &gc.Config.Podcast; pc.Enabled {
+		feeds = append(feeds, view.FeedLink{Title: pc.Title, URL: baseURL + "/podcast.rss"})
+	}
+	return feeds
+}
+
 func langFeedID(id string, lang types.Lang) string {
 	var buf [16]byte
 	blake3.DeriveKey("LANGUAGE FEED ID v0.1 LANG:"+lang, []byte(id), buf[:])
 	return hex.EncodeToString(buf[:])
 }
 
+// generateFeeds writes the feed of all posts and the feeds of each language.
+func generateFeeds(gc *GenerationContext) error {
+	err := generateGlobalFeed(gc)
+	if err != nil {
+		return err
+	}
+	for _, lang := range types.SupportedLanguages {
+		if lang == "en" {
+			continue
+		}
+		err = generateLocalFeed(gc, lang)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func generateGlobalFeed(gc *GenerationContext) error {
 	log.Debug().Msg("start generating global RSS feed")
 	globalFeed := &feeds.Feed{
 		Title:       "Gosuda Blog",
-		Link:        &feeds.Link{Href: "https://gosuda.org/"},
+		Link:        &feeds.Link{Href: baseURL + "/"},
 		Description: "Gosuda: A blog about software development, and other topics.",
 		Author:      &feeds.Author{Name: "Gosuda", Email: "webmaster@gosuda.org"},
 		Created:     time.Now().UTC(),
 	}
+	noindex := make(map[*feeds.Item]struct{})
 
-	for _, post := range gc.DataStore.Posts {
+	for _, post := range listedPosts(gc) {
 		doc := post.Main
 		if doc.Metadata.Language != "en" {
 			enDoc, ok := post.Translated["en"]
@@ -39,7 +103,7 @@ func generateGlobalFeed(gc *GenerationContext) error {
 			}
 			doc = enDoc
 		}
-		link := baseURL + post.Path
+		link := baseURL + pageURL(post.Path)
 
 		postFeed := &feeds.Item{
 			Id:          langFeedID(post.ID, doc.Metadata.Language),
@@ -50,7 +114,11 @@ func generateGlobalFeed(gc *GenerationContext) error {
 			Created:     post.CreatedAt,
 			Updated:     post.UpdatedAt,
 		}
+		postFeed.Enclosure = imageEnclosure(gc, post)
 		globalFeed.Items = append(globalFeed.Items, postFeed)
+		if isNoIndex(post) {
+			noindex[postFeed] = struct{}{}
+		}
 	}
 
 	globalFeed.Items = append(globalFeed.Items, &feeds.Item{
@@ -68,10 +136,9 @@ func generateGlobalFeed(gc *GenerationContext) error {
 		return err
 	}
 
-	sitemap, err := encodeSiteMapXML(globalFeed)
-	if err != nil {
-		return err
-	}
+	gc.Sitemaps[types.LangEnglish] = append(sitemapURLs(sitemapFeed(globalFeed, noindex)), pageSitemapURLs(gc, types.LangEnglish)...)
+
+	rss = styleFeed(rss)
 
 	err = os.WriteFile(distDir+"/feed.rss", []byte(rss), 0644)
 	if err != nil {
@@ -83,11 +150,6 @@ func generateGlobalFeed(gc *GenerationContext) error {
 		return err
 	}
 
-	err = os.WriteFile(distDir+"/sitemap.xml", []byte(sitemap), 0644)
-	if err != nil {
-		return err
-	}
-
 	log.Debug().Msg("done generating global RSS feed")
 	return nil
 }
@@ -96,19 +158,20 @@ func generateLocalFeed(gc *GenerationContext, lang types.Lang) error {
 	log.Debug().Str("lang", string(lang)).Msg("start generating local RSS feed")
 
 	feed := &feeds.Feed{
-		Title:       "GoSuda Blog" + " - " + types.FullLangName(lang),
+		Title:       langFeedTitle(lang),
 		Link:        &feeds.Link{Href: baseURL + "/" + lang + "/"},
 		Description: "Gosuda: A blog about software development, and other topics.",
 		Author:      &feeds.Author{Name: "Gosuda", Email: "webmaster@gosuda.org"},
 		Created:     time.Now().UTC(),
 	}
+	noindex := make(map[*feeds.Item]struct{})
 
-	for _, post := range gc.DataStore.Posts {
+	for _, post := range listedPosts(gc) {
 		doc, ok := post.Translated[lang]
 		if !ok {
 			continue
 		}
-		link := baseURL + "/" + lang + post.Path
+		link := baseURL + pageURL("/"+lang+post.Path)
 
 		postFeed := &feeds.Item{
 			Id:          langFeedID(post.ID, lang),
@@ -119,7 +182,11 @@ func generateLocalFeed(gc *GenerationContext, lang types.Lang) error {
 			Created:     post.CreatedAt.UTC(),
 			Updated:     post.UpdatedAt.UTC(),
 		}
+		postFeed.Enclosure = imageEnclosure(gc, post)
 		feed.Items = append(feed.Items, postFeed)
+		if isNoIndex(post) {
+			noindex[postFeed] = struct{}{}
+		}
 	}
 
 	feed.Items = append(feed.Items, &feeds.Item{
@@ -137,32 +204,128 @@ func generateLocalFeed(gc *GenerationContext, lang types.Lang) error {
 		return err
 	}
 
-	sitemap, err := encodeSiteMapXML(feed)
-	if err != nil {
-		return err
-	}
+	gc.Sitemaps[lang] = append(sitemapURLs(sitemapFeed(feed, noindex)), pageSitemapURLs(gc, lang)...)
 
-	err = os.WriteFile(distDir+"/"+lang+"/feed.rss", []byte(rss), 0644)
+	err = os.WriteFile(distDir+"/"+lang+"/feed.rss", []byte(styleFeed(rss)), 0644)
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(distDir+"/"+lang+"/sitemap.xml", sitemap, 0644)
-	if err != nil {
-		return err
+	log.Debug().Str("lang", string(lang)).Msg("done generating local RSS feed")
+	return nil
+}
+
+// tagPosts returns the published posts of each tag.
+func tagPosts(gc *GenerationContext) map[string][]*types.Post {
+	tags := make(map[string][]*types.Post)
+	for _, post := range listedPosts(gc) {
+		for _, tag := range post.Main.Metadata.Tags {
+			tags[tag] = append(tags[tag], post)
+		}
 	}
+	return tags
+}
 
-	log.Debug().Str("lang", string(lang)).Msg("done generating local RSS feed")
+// tagFeedPath returns the site path of the feed of tag.
+func tagFeedPath(tag string) string {
+	return "/tags/" + importSlug(tag) + "/feed.rss"
+}
+
+// generateTagFeeds writes a feed of the posts of each tag, in English where
+// translated and in their main language otherwise.
+func generateTagFeeds(gc *GenerationContext) error {
+	log.Debug().Msg("start generating tag RSS feeds")
+	tags := tagPosts(gc)
+	for tag, posts := range tags {
+		feed := &feeds.Feed{
+			Title:       "GoSuda Blog - " + tag,
+			Link:        &feeds.Link{Href: baseURL + "/"},
+			Description: "Posts tagged " + tag + " on the GoSuda blog.",
+			Author:      &feeds.Author{Name: "Gosuda", Email: "webmaster@gosuda.org"},
+			Created:     time.Now().UTC(),
+		}
+		for _, post := range posts {
+			lang := types.LangEnglish
+			doc, ok := post.Translated[lang]
+			if !ok {
+				doc, lang = post.Main, post.Main.Metadata.Language
+			}
+			feed.Items = append(feed.Items, &feeds.Item{
+				Id:          langFeedID(post.ID, lang),
+				Title:       doc.Metadata.Title,
+				Link:        &feeds.Link{Href: postURL(post, lang)},
+				Author:      &feeds.Author{Name: doc.Metadata.Author},
+				Description: doc.Metadata.Description,
+				Created:     post.CreatedAt.UTC(),
+				Updated:     post.UpdatedAt.UTC(),
+				Enclosure:   imageEnclosure(gc, post),
+			})
+		}
+
+		rss, err := feed.ToRss()
+		if err != nil {
+			return err
+		}
+		fp := filepath.Join(distDir, filepath.FromSlash(tagFeedPath(tag)))
+		err = os.MkdirAll(filepath.Dir(fp), 0755)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(fp, []byte(styleFeed(rss)), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	log.Debug().Int("tags", len(tags)).Msg("done generating tag RSS feeds")
 	return nil
 }
 
-const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+// imageEnclosure returns the cover image of post as a feed enclosure, or nil.
+func imageEnclosure(gc *GenerationContext, post *types.Post) *feeds.Enclosure {
+	img, ok := gc.Images[post.ID]
+	if !ok {
+		return nil
+	}
+	return &feeds.Enclosure{Url: baseURL + img.URL, Length: strconv.FormatInt(img.Size, 10), Type: img.Type}
+}
 
-func encodeSiteMapXML(feed *feeds.Feed) ([]byte, error) {
-	var b bytes.Buffer
-	err := view.Sitemap(feed).Render(context.Background(), &b)
-	if err != nil {
-		return nil, err
+// sitemapFeed returns a copy of feed without the excluded (noindex) items.
+func sitemapFeed(feed *feeds.Feed, exclude map[*feeds.Item]struct{}) *feeds.Feed {
+	f := *feed
+	f.Items = make([]*feeds.Item, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		if _, ok := exclude[item]; !ok {
+			f.Items = append(f.Items, item)
+		}
+	}
+	return &f
+}
+
+// pageSitemapURLs returns the sitemap entries of the standalone pages, docs,
+// talks and link posts in lang, which are not in the feeds the other entries
+// are collected from, and of the talks listing.
+func pageSitemapURLs(gc *GenerationContext, lang types.Lang) []view.SitemapURL {
+	var urls []view.SitemapURL
+	for _, post := range publishedPosts(gc) {
+		if post.Main.Metadata.IsPost() || isNoIndex(post) {
+			continue
+		}
+		if _, ok := post.Translated[lang]; !ok {
+			continue
+		}
+		urls = append(urls, view.SitemapURL{Loc: postURL(post, lang), LastMod: post.UpdatedAt.UTC()})
+	}
+	if talks := talkPosts(gc, lang); len(talks) > 0 {
+		urls = append(urls, view.SitemapURL{Loc: baseURL + pageURL(langPath(talksPath, lang)), LastMod: talks[0].UpdatedAt.UTC()})
+	}
+	return urls
+}
+
+// sitemapURLs returns the sitemap entries of the items of feed.
+func sitemapURLs(feed *feeds.Feed) []view.SitemapURL {
+	urls := make([]view.SitemapURL, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		urls = append(urls, view.SitemapURL{Loc: item.Link.Href, LastMod: item.Updated})
 	}
-	return append([]byte(xmlHeader), b.Bytes()...), nil
+	return urls
 }
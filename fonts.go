@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/html"
+	"gosuda.org/website/internal/fonts"
+	"gosuda.org/website/internal/htmlrewrite"
+)
+
+// pageCharacters returns the characters of the text and the text attributes of
+// the generated pages.
+func pageCharacters() (map[rune]bool, error) {
+	chars := make(map[rune]bool)
+	add := func(s string) {
+		for _, r := range s {
+			chars[r] = true
+		}
+	}
+
+	err := forEachPage(func(page string, data []byte) error {
+		var raw bool // inside a script or style element
+		_, err := htmlrewrite.Rewrite(data, func(t *htmlrewrite.Token) []byte {
+			switch t.Type {
+			case html.StartTagToken, html.SelfClosingTagToken:
+				raw = t.Data == "script" || t.Data == "style"
+				for _, key := range []string{"alt", "title", "placeholder", "aria-label", "value"} {
+					if v, ok := t.Attr(key); ok {
+						add(v)
+					}
+				}
+			case html.EndTagToken:
+				raw = false
+			case html.TextToken:
+				if !raw {
+					add(t.Data)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", page, err)
+		}
+		return nil
+	})
+	return chars, err
+}
+
+// generateFonts subsets the configured font faces to the characters of the
+// generated pages and writes them as fingerprinted WOFF2 files, along with the
+// stylesheet of their @font-face rules.
+func generateFonts(gc *GenerationContext) error {
+	fc := &gc.Config.Fonts
+	if len(fc.Faces) == 0 {
+		return nil
+	}
+	log.Debug().Int("faces", len(fc.Faces)).Msg("start generating fonts")
+
+	chars, err := pageCharacters()
+	if err != nil {
+		return err
+	}
+	for r := rune(0x20); r < 0x7F; r++ {
+		chars[r] = true
+	}
+	for _, r := range fc.Characters {
+		chars[r] = true
+	}
+	keep := func(r rune) bool { return chars[r] }
+
+	dir := filepath.Join(distDir, filepath.FromSlash(strings.TrimPrefix(fc.Dir, "/")))
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	var css strings.Builder
+	for _, face := range fc.Faces {
+		src, err := os.ReadFile(face.Source)
+		if err != nil {
+			return err
+		}
+		font, err := fonts.Subset(src, keep)
+		if err != nil {
+			return fmt.Errorf("failed to subset %s: %w", face.Source, err)
+		}
+		font, err = fonts.WOFF2(font)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", face.Source, err)
+		}
+
+		name := fingerprint(strings.TrimSuffix(filepath.Base(face.Source), filepath.Ext(face.Source)), ".woff2", font)
+		err = os.WriteFile(filepath.Join(dir, name), font, 0644)
+		if err != nil {
+			return err
+		}
+		log.Debug().Str("source", face.Source).Int("size", len(src)).Int("subset", len(font)).Msgf("subset %s to %s", face.Source, name)
+
+		fmt.Fprintf(&css, "@font-face {\n  font-family: %s;\n  font-style: %s;\n  font-weight: %s;\n  font-display: swap;\n  src: url(%s) format(\"woff2\");\n}\n",
+			cssString(face.Family), face.Style, face.Weight, cssString(path.Join(fc.Dir, name)))
+	}
+
+	name := fingerprint("fonts", ".css", []byte(css.String()))
+	err = os.WriteFile(filepath.Join(dir, name), []byte(css.String()), 0644)
+	if err != nil {
+		return err
+	}
+	gc.Fonts = path.Join(fc.Dir, name)
+
+	log.Debug().Int("characters", len(chars)).Str("stylesheet", gc.Fonts).Msg("done generating fonts")
+	return nil
+}
+
+// fingerprint returns the file name of stem and ext with a hash of data.
+func fingerprint(stem, ext string, data []byte) string {
+	sum := sha256.Sum256(data)
+	return stem + "-" + hex.EncodeToString(sum[:4]) + ext
+}
+
+// cssString quotes s as a CSS string.
+func cssString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\a `).Replace(s) + `"`
+}
+
+// applyFonts links the stylesheet of the webfonts from every page, before its
+// other stylesheets.
+func applyFonts(gc *GenerationContext) error {
+	if gc.Fonts == "" {
+		return nil
+	}
+	link := fmt.Sprintf(`<link rel="stylesheet" href="%s">`, html.EscapeString(gc.Fonts))
+
+	return forEachPage(func(page string, data []byte) error {
+		var done bool
+		data, err := htmlrewrite.Rewrite(data, func(t *htmlrewrite.Token) []byte {
+			if done {
+				return nil
+			}
+			if rel, _ := t.Attr("rel"); t.IsTag("link") && rel == "stylesheet" ||
+				t.Type == html.EndTagToken && t.Data == "head" {
+				done = true
+				return append([]byte(link), t.Raw...)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to link fonts from %s: %w", page, err)
+		}
+		if !done {
+			return nil
+		}
+		return os.WriteFile(filepath.Join(distDir, filepath.FromSlash(page)), data, 0644)
+	})
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/imagemeta"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+const galleryThumbnailWidth = 480
+
+// galleryDirs resolves the gallery directory argument of a post. Paths starting with "/" are
+// site paths in the public directory; other paths are relative to the post file and copied to dist.
+func galleryDirs(post *types.Post, dir string) (src string, site string, copied bool, err error) {
+	if strings.HasPrefix(dir, "/") {
+		return publicPath(dir), path.Clean(dir), false, nil
+	}
+
+	src = filepath.Join(filepath.Dir(post.FilePath), filepath.FromSlash(dir))
+	rel, err := filepath.Rel(rootDir, src)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", false, fmt.Errorf("gallery directory %s is outside of %s", dir, rootDir)
+	}
+	return src, "/assets/gallery/" + post.ID + "/" + path.Clean(filepath.ToSlash(dir)), true, nil
+}
+
+// galleryAlt derives alternative text from an image file name.
+func galleryAlt(name string) string {
+	name = strings.TrimSuffix(name, path.Ext(name))
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' }), " ")
+}
+
+// loadGallery copies the images of a gallery directory and generates their thumbnails.
+func loadGallery(gc *GenerationContext, post *types.Post, dir string) ([]view.GalleryImage, error) {
+	key := post.ID + "\x00" + dir
+	if images, ok := gc.Galleries[key]; ok {
+		return images, nil
+	}
+
+	srcDir, siteDir, copied, err := galleryDirs(post, dir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	distGallery := filepath.Join(distDir, filepath.FromSlash(siteDir))
+	err = os.MkdirAll(distGallery, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	var images []view.GalleryImage
+	for _, e := range entries {
+		name := e.Name()
+		switch strings.ToLower(path.Ext(name)) {
+		case ".jpg", ".jpeg", ".png", ".webp", ".gif":
+		default:
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(srcDir, name))
+		if err != nil {
+			return nil, err
+		}
+		src, format, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", name, err)
+		}
+
+		if copied {
+			if gc.Config.Images.StripMetadata {
+				stripped, err := imagemeta.Strip(data, gc.Config.Images.KeepEXIF)
+				if err != nil {
+					log.Warn().Err(err).Str("path", name).Msgf("failed to strip metadata of %s", name)
+				} else {
+					data = stripped
+				}
+			}
+			err = os.WriteFile(filepath.Join(distGallery, name), data, 0644)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		img := view.GalleryImage{
+			Src:    siteDir + "/" + name,
+			Thumb:  siteDir + "/" + name,
+			Alt:    galleryAlt(name),
+			Width:  src.Bounds().Dx(),
+			Height: src.Bounds().Dy(),
+		}
+
+		if format != "gif" && img.Width > galleryThumbnailWidth {
+			var b bytes.Buffer
+			thumb := resizeToWidth(src, galleryThumbnailWidth)
+			thumbName := strings.TrimSuffix(name, path.Ext(name))
+			if format == "jpeg" {
+				thumbName += ".jpg"
+				err = jpeg.Encode(&b, thumb, &jpeg.Options{Quality: 85})
+			} else {
+				thumbName += ".png"
+				err = png.Encode(&b, thumb)
+			}
+			if err != nil {
+				return nil, err
+			}
+			err = os.MkdirAll(filepath.Join(distGallery, "thumbs"), 0755)
+			if err != nil {
+				return nil, err
+			}
+			err = os.WriteFile(filepath.Join(distGallery, "thumbs", thumbName), b.Bytes(), 0644)
+			if err != nil {
+				return nil, err
+			}
+			img.Thumb = siteDir + "/thumbs/" + thumbName
+		}
+
+		images = append(images, img)
+	}
+
+	if len(images) == 0 {
+		return nil, fmt.Errorf("gallery directory %s has no images", dir)
+	}
+
+	if gc.Galleries == nil {
+		gc.Galleries = make(map[string][]view.GalleryImage)
+	}
+	gc.Galleries[key] = images
+	log.Debug().Str("dir", dir).Int("images", len(images)).Msgf("generated gallery %s", dir)
+	return images, nil
+}
+
+// galleryShortcode renders `{{< gallery dir >}}`.
+func galleryShortcode(gc *GenerationContext, post *types.Post, args string, index int) (string, error) {
+	dir := strings.TrimSpace(args)
+	if dir == "" {
+		return "", fmt.Errorf("missing gallery directory")
+	}
+
+	images, err := loadGallery(gc, post, dir)
+	if err != nil {
+		return "", err
+	}
+	items, err := json.Marshal(images)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	err = view.Gallery(fmt.Sprintf("gallery-%d", index), images, string(items)).Render(context.Background(), &b)
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
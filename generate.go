@@ -2,7 +2,6 @@ package main
 
 import (
 	"bytes"
-	"context"
 	"fmt"
 	"image/png"
 	"os"
@@ -12,6 +11,7 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/i18n"
 	"gosuda.org/website/internal/ogimage"
 	"gosuda.org/website/internal/types"
 	"gosuda.org/website/view"
@@ -37,16 +37,73 @@ func generate(gc *GenerationContext) error {
 	}
 	log.Debug().Msg("copied static files")
 
+	if gc.Plugins == nil {
+		gc.Plugins, err = newPluginRegistry(gc.Config)
+		if err != nil {
+			return err
+		}
+		defer gc.Plugins.Close()
+	}
+
+	gc.Assets, err = trackAssets(gc)
+	if err != nil {
+		return err
+	}
+
+	err = stripImageMetadata(gc)
+	if err != nil {
+		return err
+	}
+
 	log.Debug().Msg("creating root file index")
 	list, err := generateFileList(rootDir)
 	if err != nil {
 		return err
 	}
 
+	gc.Renderer, err = rendererHash()
+	if err != nil {
+		return fmt.Errorf("failed to hash the renderer: %w", err)
+	}
+	gc.RenderCache = newRenderCache(gc)
+
+	gc.I18n, err = i18n.Load(i18nDir)
+	if err != nil {
+		return fmt.Errorf("failed to load messages from %s: %w", i18nDir, err)
+	}
+
+	gc.Glossary, err = loadGlossary(gc.Config.Translation.Glossary)
+	if err != nil {
+		return fmt.Errorf("failed to load glossary %s: %w", gc.Config.Translation.Glossary, err)
+	}
+
+	if gc.Config.Glossary.Enabled {
+		gc.GlossaryTerms, err = loadGlossaryTerms(gc.Config.Glossary.Data)
+		if err != nil {
+			return fmt.Errorf("failed to load glossary terms %s: %w", gc.Config.Glossary.Data, err)
+		}
+	}
+	gc.Acronyms = acronymPattern(&gc.Config.Acronyms)
+
+	gc.Sections, err = loadSections(list)
+	if err != nil {
+		return fmt.Errorf("failed to load section defaults: %w", err)
+	}
+
+	findHumanTranslations(gc, list)
+
 	for _, path := range list {
 		log.Debug().Str("path", path).Msgf("processing file %s", path)
 		switch strings.ToLower(filepath.Ext(path)) {
 		case ".md", ".markdown":
+			if isSectionFile(path) {
+				log.Debug().Str("path", path).Msgf("skipping section defaults %s", path)
+				break
+			}
+			if isHumanTranslationFile(gc, path) {
+				log.Debug().Str("path", path).Msgf("skipping human translation %s", path)
+				break
+			}
 			_, err := processMarkdownFile(gc, path)
 			if err != nil {
 				log.Error().Err(err).Str("path", path).Msgf("failed to process markdown file %s", path)
@@ -57,6 +114,16 @@ func generate(gc *GenerationContext) error {
 		log.Debug().Str("path", path).Msgf("processed file %s", path)
 	}
 
+	err = processHumanTranslations(gc)
+	if err != nil {
+		return err
+	}
+
+	err = gc.RenderCache.prune()
+	if err != nil {
+		return fmt.Errorf("failed to prune render cache: %w", err)
+	}
+
 	// Remove unused posts
 	for id := range gc.DataStore.Posts {
 		if _, ok := gc.UsedPosts[id]; !ok {
@@ -65,6 +132,35 @@ func generate(gc *GenerationContext) error {
 		}
 	}
 
+	rerenderDocuments(gc)
+
+	err = checkStatuses(gc)
+	if err != nil {
+		return err
+	}
+
+	err = checkPathConflicts(gc)
+	if err != nil {
+		return err
+	}
+
+	err = checkDuplicates(gc)
+	if err != nil {
+		return err
+	}
+
+	err = updateContributors(gc)
+	if err != nil {
+		return err
+	}
+
+	err = processPostImages(gc)
+	if err != nil {
+		return err
+	}
+
+	gc.Docs = collectDocs(gc)
+
 	for _, lang := range types.SupportedLanguages {
 		err = generateIndex(gc, lang)
 		if err != nil {
@@ -74,21 +170,71 @@ func generate(gc *GenerationContext) error {
 		if err != nil {
 			return err
 		}
+		err = generateTalks(gc, lang)
+		if err != nil {
+			return err
+		}
+		err = generateSearchPage(gc, lang)
+		if err != nil {
+			return err
+		}
+		err = generateNotFoundPages(gc, lang)
+		if err != nil {
+			return err
+		}
 	}
+	reportMissingMessages(gc)
 
-	err = generateGlobalFeed(gc)
+	gc.Sitemaps = make(map[types.Lang][]view.SitemapURL)
+	for _, g := range gc.Plugins.Generators {
+		err = g.GenerateOutput(gc)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %w", g.Name(), err)
+		}
+	}
+
+	// subset to the characters of the written pages
+	err = generateFonts(gc)
 	if err != nil {
 		return err
 	}
 
-	for _, lang := range types.SupportedLanguages {
-		if lang == "en" {
-			continue
-		}
-		err = generateLocalFeed(gc, lang)
-		if err != nil {
-			return err
-		}
+	// bundled after the pages are written, whose classes the purge keeps,
+	// and before the service worker precaches the bundles
+	gc.Bundles, err = bundleAssets(gc)
+	if err != nil {
+		return err
+	}
+
+	err = generatePWA(gc)
+	if err != nil {
+		return err
+	}
+
+	err = applyBundles(gc)
+	if err != nil {
+		return err
+	}
+
+	err = applyFonts(gc)
+	if err != nil {
+		return err
+	}
+
+	err = applyResourceHints(gc)
+	if err != nil {
+		return err
+	}
+
+	err = applyURLStyle(gc)
+	if err != nil {
+		return err
+	}
+
+	// checked before minifying, so that the reported lines are meaningful
+	err = checkHTML()
+	if err != nil {
+		return err
 	}
 
 	err = minifyDir(distDir)
@@ -96,23 +242,75 @@ func generate(gc *GenerationContext) error {
 		return err
 	}
 
+	err = applyPathPrefix()
+	if err != nil {
+		return err
+	}
+
+	err = applySRI(gc)
+	if err != nil {
+		return err
+	}
+
+	err = applyCSP(gc)
+	if err != nil {
+		return err
+	}
+
+	err = checkPageWeights(gc)
+	if err != nil {
+		return err
+	}
+
+	err = checkA11y(gc)
+	if err != nil {
+		return err
+	}
+
+	err = extractPreviews(gc)
+	if err != nil {
+		return err
+	}
+
+	err = generateRobotsTxt(gc)
+	if err != nil {
+		return err
+	}
+
+	err = generateSecurityTxt(gc)
+	if err != nil {
+		return err
+	}
+
+	err = generateHumansTxt(gc)
+	if err != nil {
+		return err
+	}
+
+	err = generateHostingFiles(gc)
+	if err != nil {
+		return err
+	}
+
+	err = generateManifest(distDir, gc.Assets, gc.Bundles, changedFiles(gc))
+	if err != nil {
+		return err
+	}
+
 	log.Debug().Msg("done generating website")
 	return nil
 }
 
 func generatePostPages(gc *GenerationContext, lang types.Lang) error {
 	log.Debug().Msg("start generating post pages")
-	postList := make([]*types.Post, 0, len(gc.DataStore.Posts))
-	for _, post := range gc.DataStore.Posts {
-		postList = append(postList, post)
+	postList := publishedPosts(gc)
+	if gc.Config.Preview.Secret != "" {
+		// rendered like the others and moved out of dist, see extractPreviews
+		postList = append(postList, unpublishedPosts(gc.DataStore)...)
 	}
 
-	sort.Slice(postList, func(i, j int) bool {
-		return postList[i].ID < postList[j].ID
-	})
-
 	var b bytes.Buffer
-	ctx := context.Background()
+	ctx := renderContext(gc, lang)
 
 	for _, post := range postList {
 		pm := post.Main.Metadata
@@ -131,25 +329,24 @@ func generatePostPages(gc *GenerationContext, lang types.Lang) error {
 		sort.Strings(languages)
 
 		path := "/" + lang + post.Path
+		preview := isUnpublished(post)
+		if preview {
+			path = previewPrefix + previewToken(gc.Config.Preview.Secret, post.ID, lang)
+		}
 
 		log.Debug().Str("path", post.Path).Msgf("generating post page %s", path)
 
-		fp := filepath.Join(distDir, path)
-		err := os.MkdirAll(filepath.Dir(fp), 0755)
-		if err != nil {
-			return err
-		}
-
 		ogImagePath := filepath.Join(distDir, "assets", post.ID+"_"+lang+".png")
-		err = os.MkdirAll(filepath.Dir(ogImagePath), 0755)
+		err := os.MkdirAll(filepath.Dir(ogImagePath), 0755)
 		if err != nil {
 			return err
 		}
 
-		url := baseURL + "/" + lang + post.Path
+		url := baseURL + pageURL(path)
 
 		meta := &view.Metadata{
 			Language:    lang,
+			LangTag:     post.Translated[lang].Lang,
 			Title:       pm.Title,
 			Description: pm.Description,
 			Author:      pm.Author,
@@ -161,76 +358,115 @@ func generatePostPages(gc *GenerationContext, lang types.Lang) error {
 			UpdatedAt:   post.UpdatedAt,
 		}
 
-		alt := &view.Alternate{}
-		for _, lang := range languages {
-			if lang == types.LangEnglish {
-				alt.Versions = append(alt.Versions, view.KV{
-					Key:   lang,
-					Value: baseURL + post.Path,
-				})
-				continue
-			}
-			alt.Versions = append(alt.Versions, view.KV{
-				Key:   lang,
-				Value: baseURL + "/" + lang + post.Path,
-			})
-		}
-		meta.Alternate = alt
+		meta.Alternate = postAlternate(post, languages)
+		meta.Languages = pageLanguages(meta.Alternate)
 
 		if lang == types.LangEnglish {
-			meta.URL = baseURL + post.Path
+			meta.URL = baseURL + pageURL(post.Path)
 			meta.Canonical = meta.URL
 		}
 
 		if post.Main.Metadata.Canonical != "" {
-			meta.Canonical = post.Main.Metadata.Canonical
+			meta.Canonical = absURL(meta.URL, post.Main.Metadata.Canonical)
 		}
 
 		if post.Main.Metadata.LangCanonical != nil &&
 			post.Main.Metadata.LangCanonical[lang] != "" {
-			meta.Canonical = post.Main.Metadata.LangCanonical[lang]
+			meta.Canonical = absURL(meta.URL, post.Main.Metadata.LangCanonical[lang])
+		}
+
+		var robots []string
+		if isNoIndex(post) || preview {
+			robots = append(robots, "noindex")
+		}
+		if expiry := post.Main.Metadata.ExpiryDate; !expiry.IsZero() {
+			robots = append(robots, "unavailable_after: "+expiry.UTC().Format(time.RFC3339))
+		}
+		meta.Robots = strings.Join(robots, ", ")
+		meta.Outdated = isExpired(post)
+		meta.EditURL = editURL(gc, post, lang)
+		meta.Contributors = viewContributors(gc, post.FilePath)
+		meta.Comments = viewComments(gc, post, lang)
+		meta.Analytics = viewAnalytics(gc)
+		meta.Feeds = viewFeeds(gc, lang)
+		if gc.Config.Text.Enabled {
+			meta.TextURL = baseURL + pageURL(textPath(post, lang))
+		}
+		if gc.Config.Hints.Enabled {
+			prev, next := adjacentPosts(gc, post, lang)
+			for _, p := range []*types.Post{prev, next} {
+				if p != nil {
+					meta.Prefetch = append(meta.Prefetch, pageURL(langPath(p.Path, lang)))
+				}
+			}
+		}
+		switch post.Main.Metadata.Kind {
+		case types.KindDoc:
+			meta.Docs = docsNav(gc, post, lang)
+		case types.KindTalk:
+			meta.Talk = viewTalk(post)
+		}
+		if audio := post.Main.Metadata.AudioFile; audio != "" {
+			meta.AudioURL = audio
+			meta.AudioType = audioType(audio)
+		}
+
+		cover, hasCover := gc.Images[post.ID]
+		if hasCover {
+			meta.Image = baseURL + cover.URL
+			meta.ImageAlt = post.Main.Metadata.ImageAlt
 		}
 
 		if post.Main.Metadata.GoPackage != "" {
 			meta.GoImport = fmt.Sprintf("%s git %s", post.Main.Metadata.GoPackage, post.Main.Metadata.GoRepoURL)
 		}
 
-		b.Reset()
-		err = view.PostPage(meta, post.Translated[lang], post).Render(ctx, &b)
+		for _, e := range gc.Plugins.Enrichers {
+			err = e.EnrichMetadata(gc, post, lang, meta)
+			if err != nil {
+				return fmt.Errorf("plugin %s: %w", e.Name(), err)
+			}
+		}
+
+		stored, err := gc.DataStore.document(post, post.Translated[lang])
 		if err != nil {
 			return err
 		}
-
-		if strings.HasSuffix(fp, "/") {
-			err = os.WriteFile(fp+"index.html", b.Bytes(), 0644)
+		doc := *stored
+		for _, t := range gc.Plugins.Transformers {
+			doc.HTML, err = t.TransformContent(gc, post, lang, doc.HTML)
 			if err != nil {
-				return err
+				return fmt.Errorf("plugin %s: %w", t.Name(), err)
 			}
-		} else {
-			err = os.WriteFile(fp+".html", b.Bytes(), 0644)
+		}
+
+		b.Reset()
+		err = view.PostPage(meta, &doc, post).Render(ctx, &b)
+		if err != nil {
+			return err
+		}
+
+		err = writePage(gc, path, post.FilePath, b.Bytes())
+		if err != nil {
+			return err
+		}
+
+		if lang == types.LangEnglish && !preview {
+			err = writePage(gc, post.Path, post.FilePath, b.Bytes())
 			if err != nil {
 				return err
 			}
 		}
 
-		if lang == types.LangEnglish {
-			err := os.MkdirAll(filepath.Dir(filepath.Join(distDir, post.Path)), 0755)
+		if gc.Config.Text.Enabled && !preview {
+			err = writeTextPage(gc, ctx, post, lang, meta, doc)
 			if err != nil {
 				return err
 			}
+		}
 
-			fp = filepath.Join(distDir, post.Path)
-			if strings.HasSuffix(fp, "/") {
-				err = os.WriteFile(fp+"index.html", b.Bytes(), 0644)
-				if err != nil {
-					return err
-				}
-			} else {
-				err = os.WriteFile(fp+".html", b.Bytes(), 0644)
-				if err != nil {
-					return err
-				}
-			}
+		if hasCover || preview {
+			continue
 		}
 
 		img := ogimage.GenerateImage("GoSuda", pm.Title, pm.Date)
@@ -258,10 +494,123 @@ func generatePostPages(gc *GenerationContext, lang types.Lang) error {
 	return nil
 }
 
+// byWeight orders posts by ascending weight, then by date, newest first.
+func byWeight(posts []*types.Post) func(i, j int) bool {
+	return func(i, j int) bool {
+		mi, mj := posts[i].Main.Metadata, posts[j].Main.Metadata
+		if mi.Weight != mj.Weight {
+			return mi.Weight < mj.Weight
+		}
+		return mi.Date.After(mj.Date)
+	}
+}
+
+// postPreview returns the preview of post in lang, or nil if the post is not available in lang.
+func postPreview(gc *GenerationContext, post *types.Post, lang types.Lang) *view.BlogPostPreview {
+	pm := post.Main.Metadata
+	if lang != pm.Language {
+		if _, ok := post.Translated[lang]; ok {
+			pm = post.Translated[lang].Metadata
+		} else {
+			return nil
+		}
+	}
+
+	postPath := pageURL(langPath(post.Path, lang))
+
+	preview := &view.BlogPostPreview{
+		Title:       pm.Title,
+		Author:      pm.Author,
+		Description: pm.Description,
+		Date:        pm.Date,
+		URL:         postPath,
+		Pinned:      post.Main.Metadata.Pinned,
+	}
+	if post.Main.Metadata.Kind == types.KindLink {
+		preview.LinkURL = post.Main.Metadata.LinkURL
+		doc, err := gc.DataStore.document(post, post.Translated[lang])
+		if err != nil {
+			log.Error().Err(err).Str("path", post.FilePath).Str("lang", lang).Msgf("failed to read the %s document of %s", lang, post.FilePath)
+		} else {
+			preview.Body = doc.HTML
+		}
+	}
+	if img, ok := gc.Images[post.ID]; ok {
+		preview.Thumbnail = img.Thumbnail
+		preview.ImageAlt = post.Main.Metadata.ImageAlt
+	}
+	return preview
+}
+
+// recentPreviews returns previews of the most recent listed posts and link
+// posts available in lang.
+// Pinned posts come first, ordered by weight.
+func recentPreviews(gc *GenerationContext, lang types.Lang, limit int) []*view.BlogPostPreview {
+	var pinned, posts []*types.Post
+	for _, post := range publishedPosts(gc) {
+		if !post.Main.Metadata.IsPost() && post.Main.Metadata.Kind != types.KindLink || post.Main.Metadata.Hidden {
+			continue
+		}
+		if post.Main.Metadata.Pinned {
+			pinned = append(pinned, post)
+			continue
+		}
+		posts = append(posts, post)
+	}
+	sort.Slice(pinned, byWeight(pinned))
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].Main.Metadata.Date.After(posts[j].Main.Metadata.Date)
+	})
+	posts = append(pinned, posts...)
+
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+
+	var previews []*view.BlogPostPreview
+	for _, post := range posts {
+		if preview := postPreview(gc, post, lang); preview != nil {
+			previews = append(previews, preview)
+		}
+	}
+
+	return previews
+}
+
+// featuredPosts returns the featured posts available in lang, ordered by weight.
+func featuredPosts(gc *GenerationContext, lang types.Lang) []view.FeaturedPost {
+	var posts []*types.Post
+	for _, post := range listedPosts(gc) {
+		if post.Main.Metadata.Hidden || !post.Main.Metadata.Featured {
+			continue
+		}
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, byWeight(posts))
+
+	var featured []view.FeaturedPost
+	for _, post := range posts {
+		preview := postPreview(gc, post, lang)
+		if preview == nil {
+			continue
+		}
+		featured = append(featured, view.FeaturedPost{
+			Title:       preview.Title,
+			Link:        preview.URL,
+			Description: preview.Description,
+			Author:      preview.Author,
+			Date:        preview.Date,
+		})
+	}
+
+	return featured
+}
+
 func generateIndex(gc *GenerationContext, lang types.Lang) error {
 	log.Debug().Msg("start generating index")
 	var b bytes.Buffer
-	ctx := context.Background()
+	l := gc.I18n.Localizer(lang)
+	ctx := renderContext(gc, lang)
 
 	err := os.MkdirAll(filepath.Join(distDir, lang), 0755)
 	if err != nil {
@@ -270,8 +619,8 @@ func generateIndex(gc *GenerationContext, lang types.Lang) error {
 
 	meta := &view.Metadata{
 		Language:    lang,
-		Title:       "GoSuda | Home",
-		Description: "GoSuda is an industry-leading open source working group enabling developers to easily build, prototype, and deploy applications. Our comprehensive suite of tools and frameworks empowers developers to create robust, scalable solutions across various domains.",
+		Title:       l.T("GoSuda | Home"),
+		Description: l.T("GoSuda is an industry-leading open source working group enabling developers to easily build, prototype, and deploy applications. Our comprehensive suite of tools and frameworks empowers developers to create robust, scalable solutions across various domains."),
 		Author:      "GoSuda",
 		Image:       baseURL + "/assets/images/ogp_placeholder.png",
 		URL:         baseURL + "/",
@@ -279,6 +628,8 @@ func generateIndex(gc *GenerationContext, lang types.Lang) error {
 		BaseURL:     baseURL,
 		CreatedAt:   time.Date(2024, 10, 07, 0, 0, 0, 0, time.UTC),
 		UpdatedAt:   time.Now().UTC(),
+		Analytics:   viewAnalytics(gc),
+		Feeds:       viewFeeds(gc, lang),
 	}
 
 	if lang != "en" {
@@ -286,82 +637,72 @@ func generateIndex(gc *GenerationContext, lang types.Lang) error {
 		meta.Canonical = baseURL + "/" + lang + "/"
 	}
 
-	alt := &view.Alternate{}
-	for _, lang := range types.SupportedLanguages {
-		if lang == types.LangEnglish {
-			alt.Versions = append(alt.Versions, view.KV{
-				Key:   lang,
-				Value: baseURL + "/",
-			})
-			continue
-		}
-		alt.Versions = append(alt.Versions, view.KV{
-			Key:   lang,
-			Value: baseURL + "/" + lang + "/",
-		})
+	meta.Alternate = indexAlternate()
+	meta.Languages = pageLanguages(meta.Alternate)
+
+	sections, err := homeSections(gc, lang, l)
+	if err != nil {
+		return err
 	}
-	meta.Alternate = alt
 
-	var posts []*types.Post
-	for _, post := range gc.DataStore.Posts {
-		if post.Main.Metadata.Hidden {
-			continue
-		}
-		posts = append(posts, post)
+	err = view.IndexPage(meta, sections).Render(ctx, &b)
+	if err != nil {
+		return err
 	}
-	sort.Slice(posts, func(i, j int) bool {
-		return posts[i].Main.Metadata.Date.After(posts[j].Main.Metadata.Date)
-	})
 
-	if len(posts) > 16 {
-		posts = posts[:16]
+	err = os.WriteFile(filepath.Join(distDir, lang, "index.html"), b.Bytes(), 0644)
+	if err != nil {
+		return err
 	}
 
-	var previews []*view.BlogPostPreview
-	for _, post := range posts {
-		pm := post.Main.Metadata
-		if lang != pm.Language {
-			if _, ok := post.Translated[lang]; ok {
-				pm = post.Translated[lang].Metadata
-			} else {
-				continue
-			}
+	if lang == "en" {
+		err = os.WriteFile(filepath.Join(distDir, "index.html"), b.Bytes(), 0644)
+		if err != nil {
+			return err
 		}
+	}
 
-		postPath := post.Path
+	log.Debug().Msg("done generating index")
+	return nil
+}
 
-		if lang != "en" {
-			postPath = "/" + lang + post.Path
-		}
+func generateNotFoundPages(gc *GenerationContext, lang types.Lang) error {
+	log.Debug().Str("lang", lang).Msg("start generating 404 page")
+	var b bytes.Buffer
+	l := gc.I18n.Localizer(lang)
 
-		previews = append(previews, &view.BlogPostPreview{
-			Title:       pm.Title,
-			Author:      pm.Author,
-			Description: pm.Description,
-			Date:        pm.Date,
-			URL:         postPath,
-		})
+	meta := &view.Metadata{
+		Language:  lang,
+		Title:     l.T("GoSuda | Page Not Found"),
+		Author:    "GoSuda",
+		BaseURL:   baseURL,
+		Robots:    "noindex",
+		Analytics: viewAnalytics(gc),
+		Feeds:     viewFeeds(gc, lang),
 	}
 
-	var featuredPosts []view.FeaturedPost
+	err := view.NotFoundPage(meta, recentPreviews(gc, lang, 6)).Render(renderContext(gc, lang), &b)
+	if err != nil {
+		return err
+	}
 
-	err = view.IndexPage(meta, previews, featuredPosts).Render(ctx, &b)
+	err = os.MkdirAll(filepath.Join(distDir, lang), 0755)
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(filepath.Join(distDir, lang, "index.html"), b.Bytes(), 0644)
+	err = os.WriteFile(filepath.Join(distDir, lang, "404.html"), b.Bytes(), 0644)
 	if err != nil {
 		return err
 	}
 
-	if lang == "en" {
-		err = os.WriteFile(filepath.Join(distDir, "index.html"), b.Bytes(), 0644)
+	if lang == types.LangEnglish {
+		err = os.WriteFile(filepath.Join(distDir, "404.html"), b.Bytes(), 0644)
 		if err != nil {
 			return err
 		}
 	}
 
-	log.Debug().Msg("done generating index")
+	log.Debug().Str("lang", lang).Msg("done generating 404 page")
 	return nil
 }
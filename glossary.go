@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+	"gosuda.org/website/internal/translate"
+	"gosuda.org/website/internal/types"
+)
+
+// Glossary is the translation glossary of the site.
+//
+//	protected: [goroutine, gRPC]
+//	terms:
+//	  channel:
+//	    ko: 채널
+type Glossary struct {
+	// Protected are terms kept unchanged in every language, such as product
+	// names and code identifiers.
+	Protected []string `yaml:"protected"`
+	// Terms maps terms to their approved translation in each language.
+	// Languages without a translation keep the term unchanged.
+	Terms map[string]map[types.Lang]string `yaml:"terms"`
+}
+
+// loadGlossary reads the glossary file at path. A missing file is an empty
+// glossary.
+func loadGlossary(path string) (*Glossary, error) {
+	g := &Glossary{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return g, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(data, g)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// TermsFor returns the glossary terms for translations into lang.
+func (g *Glossary) TermsFor(lang types.Lang) []translate.Term {
+	if g == nil {
+		return nil
+	}
+	var terms []translate.Term
+	for _, p := range g.Protected {
+		terms = append(terms, translate.Term{Source: p})
+	}
+	for source, translations := range g.Terms {
+		terms = append(terms, translate.Term{Source: source, Translation: translations[lang]})
+	}
+	// longer terms first, so that the prompt lists "Go module" before "Go"
+	sort.SliceStable(terms, func(i, j int) bool {
+		if len(terms[i].Source) != len(terms[j].Source) {
+			return len(terms[i].Source) > len(terms[j].Source)
+		}
+		return terms[i].Source < terms[j].Source
+	})
+	return terms
+}
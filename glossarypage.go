@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"gosuda.org/website/internal/htmlcheck"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// glossaryPath is the site path of the glossary page.
+const glossaryPath = "/glossary"
+
+// glossaryTerm is a term of the glossary page with the patterns of its
+// occurrences, and those of its aliases, in posts.
+type glossaryTerm struct {
+	view.GlossaryTerm
+	patterns []*regexp.Regexp
+}
+
+// loadGlossaryTerms reads the glossary data file at path, sorted by term. A
+// missing file is an empty glossary.
+//
+//	terms:
+//	  - term: goroutine
+//	    aliases: [goroutines]
+//	    definition: A function running concurrently with others, managed by the Go runtime.
+func loadGlossaryTerms(path string) ([]*glossaryTerm, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Terms []view.GlossaryTerm `yaml:"terms"`
+	}
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	terms := make([]*glossaryTerm, 0, len(file.Terms))
+	ids := make(map[string]string)
+	for _, t := range file.Terms {
+		if t.Term == "" || t.Definition == "" {
+			return nil, fmt.Errorf("%s: term %q needs a term and a definition", path, t.Term)
+		}
+		id := view.GlossaryID(t.Term)
+		if other, ok := ids[id]; ok {
+			return nil, fmt.Errorf("%s: terms %q and %q have the same anchor %s", path, other, t.Term, id)
+		}
+		ids[id] = t.Term
+
+		term := &glossaryTerm{GlossaryTerm: t}
+		for _, s := range append([]string{t.Term}, t.Aliases...) {
+			term.patterns = append(term.patterns, regexp.MustCompile(`(?i)`+regexp.QuoteMeta(s)))
+		}
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return strings.ToLower(terms[i].Term) < strings.ToLower(terms[j].Term)
+	})
+	return terms, nil
+}
+
+// unspaced reports whether r is of a script written without spaces between
+// words, or with particles attached to them, such as Korean.
+func unspaced(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hangul, unicode.Hiragana, unicode.Katakana, unicode.Thai)
+}
+
+// wholeWord reports whether s[start:end] is not part of a longer word. The
+// edges of terms in unspaced scripts are not checked.
+func wholeWord(s string, start, end int) bool {
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_' }
+	if first, _ := utf8.DecodeRuneInString(s[start:]); start > 0 && !unspaced(first) {
+		if before, _ := utf8.DecodeLastRuneInString(s[:start]); isWord(before) {
+			return false
+		}
+	}
+	if last, _ := utf8.DecodeLastRuneInString(s[:end]); end < len(s) && !unspaced(last) {
+		if after, _ := utf8.DecodeRuneInString(s[end:]); isWord(after) {
+			return false
+		}
+	}
+	return true
+}
+
+// find returns the position of the first occurrence of the term in s as a
+// whole word, or nil.
+func (t *glossaryTerm) find(s string) []int {
+	var first []int
+	for _, re := range t.patterns {
+		for _, loc := range re.FindAllStringIndex(s, -1) {
+			if first != nil && loc[0] >= first[0] {
+				break
+			}
+			if wholeWord(s, loc[0], loc[1]) {
+				first = loc
+				break
+			}
+		}
+	}
+	return first
+}
+
+// glossarySkipped are the elements whose text is not linked to the glossary:
+// links, code, headings and the terms marked up otherwise.
+var glossarySkipped = map[string]bool{
+	"a": true, "code": true, "pre": true, "kbd": true, "samp": true, "var": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"abbr": true, "dfn": true, "sup": true, "button": true, "svg": true, "math": true,
+	"script": true, "style": true,
+}
+
+// linkGlossaryTerms links the first occurrence of each glossary term in a post
+// to its definition on the glossary page, with the definition as the tooltip
+// of the link. Posts with no_glossary set are left alone.
+func linkGlossaryTerms(gc *GenerationContext, post *types.Post, doc string) (string, error) {
+	if !gc.Config.Glossary.Enabled || len(gc.GlossaryTerms) == 0 || post.Main == nil || post.Main.Metadata.NoGlossary {
+		return doc, nil
+	}
+
+	linked := make(map[*glossaryTerm]bool)
+	var skip int
+	out, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		switch t.Type {
+		case htmlrewrite.StartTagToken:
+			class, _ := t.Attr("class")
+			switch {
+			case htmlcheck.IsVoid(t.Data):
+			case skip > 0 || glossarySkipped[t.Data] || class == "math" || class == "sidenote":
+				skip++
+			}
+			return nil
+		case htmlrewrite.EndTagToken:
+			if skip > 0 {
+				skip--
+			}
+			return nil
+		case htmlrewrite.TextToken:
+			if skip > 0 || len(linked) == len(gc.GlossaryTerms) {
+				return nil
+			}
+		default:
+			return nil
+		}
+
+		var b bytes.Buffer
+		rest := t.Data
+		for {
+			// the earliest occurrence of a term not linked yet, the longest
+			// of those at the same position
+			var term *glossaryTerm
+			var loc []int
+			for _, gt := range gc.GlossaryTerms {
+				if linked[gt] {
+					continue
+				}
+				if l := gt.find(rest); l != nil && (loc == nil || l[0] < loc[0] || l[0] == loc[0] && l[1] > loc[1]) {
+					term, loc = gt, l
+				}
+			}
+			if term == nil {
+				break
+			}
+			linked[term] = true
+			fmt.Fprintf(&b, `%s<a href="%s#%s" class="glossary-term" title="%s">%s</a>`,
+				html.EscapeString(rest[:loc[0]]), pageURL(glossaryPath), view.GlossaryID(term.Term),
+				html.EscapeString(term.Definition), html.EscapeString(rest[loc[0]:loc[1]]))
+			rest = rest[loc[1]:]
+		}
+		if b.Len() == 0 {
+			return nil
+		}
+		b.WriteString(html.EscapeString(rest))
+		return b.Bytes()
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// generateGlossaryPage writes the /glossary page of the terms of the glossary
+// data file, if it has any.
+func generateGlossaryPage(gc *GenerationContext) error {
+	if !gc.Config.Glossary.Enabled || len(gc.GlossaryTerms) == 0 {
+		return nil
+	}
+	log.Debug().Int("terms", len(gc.GlossaryTerms)).Msg("start generating glossary page")
+
+	terms := make([]view.GlossaryTerm, len(gc.GlossaryTerms))
+	for i, t := range gc.GlossaryTerms {
+		terms[i] = t.GlossaryTerm
+	}
+	info, err := os.Stat(gc.Config.Glossary.Data)
+	if err != nil {
+		return err
+	}
+
+	l := gc.I18n.Localizer(types.LangEnglish)
+	url := baseURL + pageURL(glossaryPath)
+	meta := &view.Metadata{
+		Language:    types.LangEnglish,
+		Title:       l.T("GoSuda | Glossary"),
+		Description: l.T("Definitions of the terms used in the posts of GoSuda."),
+		Author:      "GoSuda",
+		Image:       baseURL + "/assets/images/ogp_placeholder.png",
+		URL:         url,
+		Canonical:   url,
+		BaseURL:     baseURL,
+		UpdatedAt:   info.ModTime().UTC(),
+		Analytics:   viewAnalytics(gc),
+		Feeds:       viewFeeds(gc, types.LangEnglish),
+	}
+
+	var b bytes.Buffer
+	err = view.GlossaryPage(meta, terms).Render(renderContext(gc, types.LangEnglish), &b)
+	if err != nil {
+		return err
+	}
+	for _, p := range []string{glossaryPath, "/" + types.LangEnglish + glossaryPath} {
+		err = writePage(gc, p, gc.Config.Glossary.Data, b.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	gc.Sitemaps[types.LangEnglish] = append(gc.Sitemaps[types.LangEnglish], view.SitemapURL{Loc: url, LastMod: meta.UpdatedAt})
+	log.Debug().Msg("done generating glossary page")
+	return nil
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/graphql"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/types"
+)
+
+// contentGraph indexes the DataStore for GraphQL queries.
+type contentGraph struct {
+	ds        *DataStore
+	posts     []*types.Post // newest first
+	byID      map[string]*types.Post
+	byPath    map[string]*types.Post
+	tags      map[string][]*types.Post
+	links     map[string][]*types.Post // post ID -> linked posts
+	backlinks map[string][]*types.Post // post ID -> posts linking to it
+}
+
+func newContentGraph(ds *DataStore) *contentGraph {
+	g := &contentGraph{
+		ds:        ds,
+		byID:      make(map[string]*types.Post),
+		byPath:    make(map[string]*types.Post),
+		tags:      make(map[string][]*types.Post),
+		links:     make(map[string][]*types.Post),
+		backlinks: make(map[string][]*types.Post),
+	}
+	for _, post := range ds.Posts {
+		// drafts and future posts are only reachable through their preview links
+		if post.Main == nil || isUnpublished(post) {
+			continue
+		}
+		g.posts = append(g.posts, post)
+		g.byID[post.ID] = post
+		g.byPath[post.Path] = post
+	}
+	sort.Slice(g.posts, func(i, j int) bool {
+		return g.posts[i].Main.Metadata.Date.After(g.posts[j].Main.Metadata.Date)
+	})
+
+	for _, post := range g.posts {
+		for _, tag := range post.Main.Metadata.Tags {
+			g.tags[tag] = append(g.tags[tag], post)
+		}
+
+		seen := make(map[string]bool)
+		for _, target := range g.linkedPosts(post) {
+			if target == post || seen[target.ID] {
+				continue
+			}
+			seen[target.ID] = true
+			g.links[post.ID] = append(g.links[post.ID], target)
+			g.backlinks[target.ID] = append(g.backlinks[target.ID], post)
+		}
+	}
+	return g
+}
+
+// linkedPosts returns the posts linked from the original document of post.
+func (g *contentGraph) linkedPosts(post *types.Post) []*types.Post {
+	doc := post.Main
+	if d, ok := post.Translated[post.Main.Metadata.Language]; ok {
+		doc = d
+	}
+	doc, err := g.ds.document(post, doc)
+	if err != nil {
+		log.Warn().Err(err).Str("path", post.FilePath).Msgf("failed to read the links of %s", post.FilePath)
+		return nil
+	}
+	if doc == nil {
+		return nil
+	}
+
+	var targets []*types.Post
+	htmlrewrite.Walk([]byte(doc.HTML), func(t *htmlrewrite.Token) {
+		if !t.IsTag("a") {
+			return
+		}
+		href, _ := t.Attr("href")
+		u, err := url.Parse(href)
+		if err != nil || (u.Host != "" && "https://"+u.Host != baseURL) {
+			return
+		}
+		p := u.Path
+		if lang, rest, ok := strings.Cut(strings.TrimPrefix(p, "/"), "/"); ok && slices.Contains(types.SupportedLanguages, lang) {
+			p = "/" + rest
+		}
+		if target, ok := g.byPath[p]; ok {
+			targets = append(targets, target)
+		}
+	})
+	return targets
+}
+
+type resolvers = map[string]func(args map[string]any) (any, error)
+
+// constField resolves a field to a precomputed value.
+func constField(v any) func(map[string]any) (any, error) {
+	return func(map[string]any) (any, error) { return v, nil }
+}
+
+func (g *contentGraph) query() graphql.Object {
+	return &graphql.Fields{Name: "Query", Funcs: resolvers{
+		"posts": func(args map[string]any) (any, error) {
+			lang, err := graphql.String(args, "lang", "")
+			if err != nil {
+				return nil, err
+			}
+			tag, err := graphql.String(args, "tag", "")
+			if err != nil {
+				return nil, err
+			}
+			series, err := graphql.String(args, "series", "")
+			if err != nil {
+				return nil, err
+			}
+			author, err := graphql.String(args, "author", "")
+			if err != nil {
+				return nil, err
+			}
+			hidden, err := graphql.Bool(args, "hidden", true)
+			if err != nil {
+				return nil, err
+			}
+			offset, err := graphql.Int(args, "offset", 0)
+			if err != nil {
+				return nil, err
+			}
+			limit, err := graphql.Int(args, "limit", -1)
+			if err != nil {
+				return nil, err
+			}
+
+			var out []graphql.Object
+			for _, post := range g.posts {
+				pm := &post.Main.Metadata
+				switch {
+				case lang != "" && pm.Language != lang,
+					series != "" && pm.Series != series,
+					author != "" && pm.Author != author,
+					!hidden && pm.Hidden,
+					tag != "" && !slices.Contains(pm.Tags, tag):
+					continue
+				}
+				if offset > 0 {
+					offset--
+					continue
+				}
+				if limit == 0 {
+					break
+				}
+				limit--
+				out = append(out, g.post(post))
+			}
+			return out, nil
+		},
+		"post": func(args map[string]any) (any, error) {
+			id, err := graphql.String(args, "id", "")
+			if err != nil {
+				return nil, err
+			}
+			path, err := graphql.String(args, "path", "")
+			if err != nil {
+				return nil, err
+			}
+			if post, ok := g.byID[id]; ok {
+				return g.post(post), nil
+			}
+			if post, ok := g.byPath[path]; ok {
+				return g.post(post), nil
+			}
+			return nil, nil
+		},
+		"tags": func(args map[string]any) (any, error) {
+			names := make([]string, 0, len(g.tags))
+			for name := range g.tags {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			out := make([]graphql.Object, len(names))
+			for i, name := range names {
+				out[i] = g.tag(name)
+			}
+			return out, nil
+		},
+		"tag": func(args map[string]any) (any, error) {
+			name, err := graphql.String(args, "name", "")
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := g.tags[name]; !ok {
+				return nil, nil
+			}
+			return g.tag(name), nil
+		},
+	}}
+}
+
+func (g *contentGraph) postList(posts []*types.Post) []graphql.Object {
+	out := make([]graphql.Object, len(posts))
+	for i, post := range posts {
+		out[i] = g.post(post)
+	}
+	return out
+}
+
+func (g *contentGraph) post(post *types.Post) graphql.Object {
+	pm := &post.Main.Metadata
+	return &graphql.Fields{Name: "Post", Funcs: resolvers{
+		"id":          constField(post.ID),
+		"path":        constField(post.Path),
+		"url":         constField(postURL(post, pm.Language)),
+		"filePath":    constField(post.FilePath),
+		"title":       constField(pm.Title),
+		"description": constField(pm.Description),
+		"author":      constField(pm.Author),
+		"language":    constField(pm.Language),
+		"date":        constField(pm.Date),
+		"createdAt":   constField(post.CreatedAt),
+		"updatedAt":   constField(post.UpdatedAt),
+		"hidden":      constField(pm.Hidden),
+		"tags":        constField(pm.Tags),
+		"series":      constField(pm.Series),
+		"image":       constField(pm.Image),
+		"aliases":     constField(pm.Aliases),
+		"languages": func(map[string]any) (any, error) {
+			var langs []string
+			for _, lang := range types.SupportedLanguages {
+				if _, ok := post.Translated[lang]; ok {
+					langs = append(langs, lang)
+				}
+			}
+			return langs, nil
+		},
+		"translations": func(map[string]any) (any, error) {
+			var docs []graphql.Object
+			for _, lang := range types.SupportedLanguages {
+				if doc, ok := post.Translated[lang]; ok {
+					docs = append(docs, g.document(post, lang, doc))
+				}
+			}
+			return docs, nil
+		},
+		"translation": func(args map[string]any) (any, error) {
+			lang, err := graphql.String(args, "lang", pm.Language)
+			if err != nil {
+				return nil, err
+			}
+			doc, ok := post.Translated[lang]
+			if !ok {
+				return nil, nil
+			}
+			return g.document(post, lang, doc), nil
+		},
+		"links":     func(map[string]any) (any, error) { return g.postList(g.links[post.ID]), nil },
+		"backlinks": func(map[string]any) (any, error) { return g.postList(g.backlinks[post.ID]), nil },
+	}}
+}
+
+func (g *contentGraph) document(post *types.Post, lang types.Lang, doc *types.Document) graphql.Object {
+	return &graphql.Fields{Name: "Document", Funcs: resolvers{
+		"language":    constField(lang),
+		"url":         constField(postURL(post, lang)),
+		"title":       constField(doc.Metadata.Title),
+		"description": constField(doc.Metadata.Description),
+		"author":      constField(doc.Metadata.Author),
+		"markdown": func(map[string]any) (any, error) {
+			d, err := g.ds.document(post, doc)
+			if err != nil {
+				return nil, err
+			}
+			return d.Markdown, nil
+		},
+		"html": func(map[string]any) (any, error) {
+			d, err := g.ds.document(post, doc)
+			if err != nil {
+				return nil, err
+			}
+			return d.HTML, nil
+		},
+		"hash":     constField(doc.Hash),
+		"filePath": constField(doc.FilePath),
+	}}
+}
+
+func (g *contentGraph) tag(name string) graphql.Object {
+	posts := g.tags[name]
+	return &graphql.Fields{Name: "Tag", Funcs: resolvers{
+		"name":  func(map[string]any) (any, error) { return name, nil },
+		"count": func(map[string]any) (any, error) { return len(posts), nil },
+		"posts": func(map[string]any) (any, error) { return g.postList(posts), nil },
+	}}
+}
+
+// ServeHTTP handles GraphQL requests sent as JSON POST bodies or GET query parameters.
+func (g *contentGraph) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if vars := r.URL.Query().Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				http.Error(w, "invalid variables: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(graphql.Execute(g.query(), &req))
+}
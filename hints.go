@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/types"
+)
+
+// adjacentPosts returns the posts before and after post in lang: its neighbours
+// in its series, or by date among the listed posts. Either may be nil.
+func adjacentPosts(gc *GenerationContext, post *types.Post, lang types.Lang) (prev, next *types.Post) {
+	var posts []*types.Post
+	if series := post.Main.Metadata.Series; series != "" {
+		published := make(map[*types.Post]bool)
+		for _, p := range publishedPosts(gc) {
+			published[p] = true
+		}
+		for _, p := range seriesPosts(gc.DataStore, series) {
+			if published[p] {
+				posts = append(posts, p)
+			}
+		}
+	} else {
+		posts = listedPosts(gc)
+		sort.SliceStable(posts, func(i, j int) bool {
+			return posts[i].Main.Metadata.Date.Before(posts[j].Main.Metadata.Date)
+		})
+	}
+
+	visible := posts[:0]
+	for _, p := range posts {
+		if _, ok := p.Translated[lang]; ok && (!p.Main.Metadata.Hidden || p == post) {
+			visible = append(visible, p)
+		}
+	}
+	for i, p := range visible {
+		if p != post {
+			continue
+		}
+		if i > 0 {
+			prev = visible[i-1]
+		}
+		if i+1 < len(visible) {
+			next = visible[i+1]
+		}
+		break
+	}
+	return prev, next
+}
+
+// fontFaceRule and woff2URL find the woff2 sources of @font-face rules.
+var (
+	fontFaceRule = regexp.MustCompile(`@font-face\s*{[^}]*}`)
+	woff2URL     = regexp.MustCompile(`url\(\s*['"]?([^'")]+\.woff2)['"]?\s*\)`)
+)
+
+type pageHints struct {
+	styles     []string
+	fonts      []string
+	image      string
+	imageSet   string
+	imageSizes string
+	origins    []string
+	// existing are the hrefs of the page's own preload and preconnect links.
+	existing map[string]bool
+}
+
+// applyResourceHints adds preload and preconnect links for the critical
+// resources of each page after its charset declaration.
+func applyResourceHints(gc *GenerationContext) error {
+	hc := &gc.Config.Hints
+	if !hc.Enabled {
+		return nil
+	}
+	log.Debug().Msg("start adding resource hints")
+
+	fonts := make(map[string][]string)
+	styleFonts := func(href string) []string {
+		if f, ok := fonts[href]; ok {
+			return f
+		}
+		var list []string
+		data, err := os.ReadFile(filepath.Join(distDir, filepath.FromSlash(href)))
+		if err == nil {
+			for _, rule := range fontFaceRule.FindAll(data, -1) {
+				for _, m := range woff2URL.FindAllSubmatch(rule, -1) {
+					// font URLs are resolved against the stylesheet
+					ref, err := url.Parse(string(m[1]))
+					if err != nil {
+						continue
+					}
+					list = append(list, (&url.URL{Path: href}).ResolveReference(ref).String())
+				}
+			}
+		}
+		fonts[href] = list
+		return list
+	}
+
+	err := forEachPage(func(page string, data []byte) error {
+		h := &pageHints{existing: make(map[string]bool)}
+		seenOrigin := make(map[string]bool)
+		addOrigin := func(ref string) {
+			u, err := url.Parse(ref)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return
+			}
+			origin := u.Scheme + "://" + u.Host
+			if origin+pathPrefix == baseURL || seenOrigin[origin] || len(h.origins) >= hc.Preconnect {
+				return
+			}
+			seenOrigin[origin] = true
+			h.origins = append(h.origins, origin)
+		}
+		local := func(ref string) bool {
+			return strings.HasPrefix(ref, "/") && !strings.HasPrefix(ref, "//")
+		}
+
+		var inBody bool
+		_, err := htmlrewrite.Rewrite(data, func(t *htmlrewrite.Token) []byte {
+			switch {
+			case t.IsTag("body"):
+				inBody = true
+			case t.IsTag("link"):
+				rel, _ := t.Attr("rel")
+				href, _ := t.Attr("href")
+				switch rel {
+				case "stylesheet":
+					if local(href) {
+						h.styles = append(h.styles, href)
+						h.fonts = append(h.fonts, styleFonts(href)...)
+					} else {
+						addOrigin(href)
+					}
+				case "preload", "preconnect", "dns-prefetch":
+					h.existing[href] = true
+				}
+			case t.IsTag("script"):
+				if src, ok := t.Attr("src"); ok {
+					addOrigin(src)
+				}
+			case t.IsTag("iframe"):
+				if src, ok := t.Attr("src"); ok {
+					addOrigin(src)
+				}
+			case t.IsTag("img"):
+				src, _ := t.Attr("src")
+				addOrigin(src)
+				if loading, _ := t.Attr("loading"); !inBody || h.image != "" || loading == "lazy" {
+					return nil
+				}
+				h.image = src
+				h.imageSet, _ = t.Attr("srcset")
+				h.imageSizes, _ = t.Attr("sizes")
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", page, err)
+		}
+
+		hints := h.render()
+		if hints == "" {
+			return nil
+		}
+		var done bool
+		data, err = htmlrewrite.Rewrite(data, func(t *htmlrewrite.Token) []byte {
+			if done {
+				return nil
+			}
+			if t.IsTag("meta") {
+				if _, ok := t.Attr("charset"); ok {
+					done = true
+					return append(append([]byte{}, t.Raw...), hints...)
+				}
+			}
+			if t.Type == htmlrewrite.EndTagToken && t.Data == "head" {
+				done = true
+				return append([]byte(hints), t.Raw...)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add resource hints to %s: %w", page, err)
+		}
+		return os.WriteFile(filepath.Join(distDir, filepath.FromSlash(page)), data, 0644)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msg("done adding resource hints")
+	return nil
+}
+
+// render returns the link elements of the hints not already declared by the page.
+func (h *pageHints) render() string {
+	var b strings.Builder
+	seen := make(map[string]bool)
+	link := func(href string, attrs string) {
+		if href == "" || h.existing[href] || seen[href] {
+			return
+		}
+		seen[href] = true
+		fmt.Fprintf(&b, `<link %s href="%s">`, attrs, html.EscapeString(href))
+	}
+
+	for _, origin := range h.origins {
+		link(origin, `rel="preconnect"`)
+	}
+	for _, font := range h.fonts {
+		link(font, `rel="preload" as="font" type="font/woff2" crossorigin`)
+	}
+	for _, style := range h.styles {
+		link(style, `rel="preload" as="style"`)
+	}
+	if h.image != "" && !h.existing[h.image] {
+		attrs := `rel="preload" as="image"`
+		if h.imageSet != "" {
+			attrs += fmt.Sprintf(` imagesrcset="%s"`, html.EscapeString(h.imageSet))
+			if h.imageSizes != "" {
+				attrs += fmt.Sprintf(` imagesizes="%s"`, html.EscapeString(h.imageSizes))
+			}
+		}
+		link(h.image, attrs)
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"gosuda.org/website/internal/i18n"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// homeProject is a project card of the home page, as listed in the projects data file.
+type homeProject struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	URL         string   `yaml:"url"`
+	Image       string   `yaml:"image"`
+	Tags        []string `yaml:"tags"`
+}
+
+// loadProjects reads the project cards of path. A missing file has no cards.
+func loadProjects(path string) ([]homeProject, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		log.Warn().Str("path", path).Msgf("projects file %s not found, the projects section is empty", path)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var projects []homeProject
+	err = yaml.Unmarshal(data, &projects)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return projects, nil
+}
+
+// homeSections returns the sections of the home page in lang, as configured
+// by home.sections. Titles, texts and project descriptions are translated
+// with the UI messages.
+func homeSections(gc *GenerationContext, lang types.Lang, l *i18n.Localizer) ([]*view.HomeSection, error) {
+	featured := featuredPosts(gc, lang)
+
+	var sections []*view.HomeSection
+	for _, sc := range gc.Config.Home.Sections {
+		s := &view.HomeSection{Type: sc.Type}
+		if sc.Title != "" {
+			s.Title = l.T(sc.Title)
+		}
+		switch sc.Type {
+		case "hero":
+			s.Text = l.T(sc.Text)
+		case "featured":
+			s.Featured = featured
+		case "latest":
+			s.Posts = recentPreviews(gc, lang, sc.Count)
+			s.Featured = featured
+		case "projects":
+			projects, err := loadProjects(sc.Data)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range projects {
+				s.Projects = append(s.Projects, view.Project{
+					Name:        p.Name,
+					Description: l.T(p.Description),
+					URL:         p.URL,
+					Image:       p.Image,
+					Tags:        p.Tags,
+				})
+			}
+		default:
+			return nil, fmt.Errorf("unknown home section type %q", sc.Type)
+		}
+		sections = append(sections, s)
+	}
+	return sections, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// changedFiles returns the source files changed since the previous build: the
+// posts whose content changed and the added, modified or removed public/ files.
+func changedFiles(gc *GenerationContext) []string {
+	files := append([]string(nil), gc.ChangedPosts...)
+	if gc.Assets != nil {
+		for _, list := range [][]string{gc.Assets.Added, gc.Assets.Modified, gc.Assets.Removed} {
+			for _, rel := range list {
+				files = append(files, path.Join(publicDir, rel))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// runHooks runs the commands of a hook point in order with the shell, stopping
+// at the first that fails. The commands get the build context in SITE_HOOK,
+// SITE_DIST, SITE_BASE_URL and SITE_CHANGED_FILES, one file per line, along
+// with env.
+func runHooks(name string, commands []string, changed []string, env ...string) error {
+	if len(commands) == 0 {
+		return nil
+	}
+	dist, err := filepath.Abs(distDir)
+	if err != nil {
+		return err
+	}
+	env = append([]string{
+		"SITE_HOOK=" + name,
+		"SITE_DIST=" + dist,
+		"SITE_BASE_URL=" + baseURL,
+		"SITE_CHANGED_FILES=" + strings.Join(changed, "\n"),
+	}, env...)
+
+	for _, command := range commands {
+		log.Info().Str("hook", name).Msgf("running %s hook: %s", name, command)
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Env = append(os.Environ(), env...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q: %w", name, command, err)
+		}
+	}
+	return nil
+}
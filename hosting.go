@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+)
+
+// collectRedirects returns the redirect table: configured redirects followed by
+// post aliases, short URLs and the latest docs alias.
+func collectRedirects(gc *GenerationContext) []Redirect {
+	var redirects []Redirect
+	redirects = append(redirects, gc.Config.Hosting.Redirects...)
+
+	for _, post := range publishedPosts(gc) {
+		for _, alias := range post.Main.Metadata.Aliases {
+			redirects = append(redirects, Redirect{From: alias, To: pageURL(post.Path)})
+			for _, lang := range types.SupportedLanguages {
+				if lang == types.LangEnglish {
+					continue
+				}
+				if _, ok := post.Translated[lang]; !ok {
+					continue
+				}
+				redirects = append(redirects, Redirect{From: "/" + lang + alias, To: pageURL("/" + lang + post.Path)})
+			}
+		}
+	}
+	redirects = append(redirects, shortURLRedirects(gc)...)
+	redirects = append(redirects, docsLatestRedirects(gc)...)
+	redirects = append(redirects, pageRedirects(gc)...)
+
+	return redirects
+}
+
+func generateHostingFiles(gc *GenerationContext) error {
+	log.Debug().Msg("start generating hosting files")
+	hc := &gc.Config.Hosting
+
+	var b bytes.Buffer
+	if len(hc.Headers) > 0 {
+		keys := make([]string, 0, len(hc.Headers))
+		for k := range hc.Headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b.WriteString("/*\n")
+		for _, k := range keys {
+			fmt.Fprintf(&b, "  %s: %s\n", k, hc.Headers[k])
+		}
+	}
+	if gc.Config.API.Enabled {
+		// the JSON API is meant for apps and widgets on other origins.
+		b.WriteString("/api/*\n  Access-Control-Allow-Origin: *\n")
+	}
+	for _, rule := range hc.Cache {
+		fmt.Fprintf(&b, "%s\n  Cache-Control: %s\n", rule.Pattern, rule.CacheControl)
+	}
+
+	err := os.WriteFile(filepath.Join(distDir, "_headers"), b.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	b.Reset()
+	if hc.LanguageRedirects && hc.Provider == "netlify" {
+		for _, lang := range types.SupportedLanguages {
+			if lang == types.LangEnglish {
+				continue
+			}
+			fmt.Fprintf(&b, "/ /%s/ 302 Language=%s\n", lang, lang)
+		}
+	}
+	for _, r := range collectRedirects(gc) {
+		status := r.Status
+		if status == 0 {
+			status = 301
+		}
+		b.WriteString(r.From + " " + r.To + " " + strconv.Itoa(status) + "\n")
+	}
+	if hc.Provider == "netlify" {
+		// Cloudflare Pages serves the nearest 404.html on its own; Netlify needs explicit rules.
+		for _, lang := range types.SupportedLanguages {
+			fmt.Fprintf(&b, "/%s/* /%s/404.html 404\n", lang, lang)
+		}
+		b.WriteString("/* /404.html 404\n")
+	}
+
+	err = os.WriteFile(filepath.Join(distDir, "_redirects"), b.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msg("done generating hosting files")
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/htmlcheck"
+)
+
+// checkHTML reports structural errors of the generated pages, such as tags left
+// open by raw HTML in markdown and duplicate IDs.
+func checkHTML() error {
+	log.Debug().Msg("start checking html")
+
+	var pages, total int
+	err := forEachPage(func(page string, data []byte) error {
+		errs, err := htmlcheck.Check(data)
+		if err != nil {
+			return fmt.Errorf("failed to tokenize %s: %w", page, err)
+		}
+		if len(errs) == 0 {
+			return nil
+		}
+		for _, e := range errs {
+			log.Warn().Str("page", page).Int("line", e.Line).Msgf("%s:%d: %s", page, e.Line, e.Message)
+		}
+		pages++
+		total += len(errs)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Int("pages", pages).Int("errors", total).Msg("done checking html")
+	return nil
+}
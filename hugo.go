@@ -0,0 +1,509 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"gosuda.org/website/internal/frontmatter"
+	"gosuda.org/website/internal/htmltomd"
+	"gosuda.org/website/internal/types"
+)
+
+// hugoPage is a content file of a Hugo site.
+type hugoPage struct {
+	// file is the path of the content file relative to the content directory, slash separated.
+	file   string
+	front  map[string]any
+	body   string
+	bundle string // directory of a leaf bundle, relative to the content directory
+	slug   string
+	post   bool
+	meta   *types.Metadata
+	// resources maps the names of page bundle files to their site paths.
+	resources map[string]string
+}
+
+// hugoImport holds the state of a Hugo site import.
+type hugoImport struct {
+	site       string
+	contentDir string
+	permalinks map[string]string // section -> pattern
+	pages      []*hugoPage
+}
+
+// readHugoConfig reads the site configuration in any of the formats Hugo accepts.
+func readHugoConfig(site string) map[string]any {
+	for _, name := range []string{"hugo.toml", "hugo.yaml", "hugo.yml", "hugo.json", "config.toml", "config.yaml", "config.yml", "config.json"} {
+		data, err := os.ReadFile(filepath.Join(site, name))
+		if err != nil {
+			continue
+		}
+		config := make(map[string]any)
+		switch path.Ext(name) {
+		case ".toml":
+			config, err = frontmatter.ParseTOML(string(data))
+		case ".json":
+			err = json.Unmarshal(data, &config)
+		default:
+			err = yaml.Unmarshal(data, &config)
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to parse Hugo configuration %s", name)
+		}
+		log.Debug().Str("path", name).Msgf("read Hugo configuration %s", name)
+		return config
+	}
+	log.Warn().Str("site", site).Msg("no Hugo configuration found, using defaults")
+	return make(map[string]any)
+}
+
+// hugoPermalinks returns the permalink patterns of regular pages by section.
+func hugoPermalinks(config map[string]any) map[string]string {
+	permalinks := make(map[string]string)
+	m, _ := config["permalinks"].(map[string]any)
+	if page, ok := m["page"].(map[string]any); ok {
+		m = page
+	}
+	for section, v := range m {
+		if s, ok := v.(string); ok {
+			permalinks[section] = s
+		}
+	}
+	return permalinks
+}
+
+var hugoPlaceholder = regexp.MustCompile(`:(\w+)`)
+
+// originalURL returns the URL path the page had on the Hugo site.
+func (h *hugoImport) originalURL(p *hugoPage) string {
+	if u := frontString(p.front, "url"); u != "" {
+		return "/" + strings.TrimPrefix(u, "/")
+	}
+
+	dir := path.Dir(p.file)
+	if p.bundle != "" {
+		dir = path.Dir(p.bundle)
+	}
+	section, _, _ := strings.Cut(dir, "/")
+	if dir == "." {
+		section = ""
+	}
+	filename := strings.TrimSuffix(path.Base(p.file), path.Ext(p.file))
+	if p.bundle != "" {
+		filename = path.Base(p.bundle)
+	}
+
+	pattern, ok := h.permalinks[section]
+	if !ok {
+		u := "/" + path.Join(dir, filename) + "/"
+		if slug := frontString(p.front, "slug"); slug != "" {
+			u = "/" + path.Join(dir, slug) + "/"
+		}
+		return strings.TrimPrefix(u, "/.")
+	}
+
+	date := p.meta.Date
+	return hugoPlaceholder.ReplaceAllStringFunc(pattern, func(m string) string {
+		switch m[1:] {
+		case "year":
+			return date.Format("2006")
+		case "month":
+			return date.Format("01")
+		case "monthname":
+			return strings.ToLower(date.Format("January"))
+		case "day":
+			return date.Format("02")
+		case "section":
+			return section
+		case "sections":
+			return dir
+		case "title":
+			return importSlug(p.meta.Title)
+		case "slug":
+			if slug := frontString(p.front, "slug"); slug != "" {
+				return slug
+			}
+			return importSlug(p.meta.Title)
+		case "slugorfilename", "slugorcontentbasename":
+			if slug := frontString(p.front, "slug"); slug != "" {
+				return slug
+			}
+			return filename
+		case "filename", "contentbasename":
+			return filename
+		}
+		return m
+	})
+}
+
+var (
+	hugoShortcode = regexp.MustCompile(`(?s)\{\{([<%])(/\*)?\s*(/?)\s*([\w./-]+)(.*?)\s*(\*/)?[>%]\}\}`)
+	hugoHighlight = regexp.MustCompile(`(?s)\{\{[<%]\s*highlight\s+([\w+#-]*)[^}]*?[>%]\}\}\n?(.*?)\n?\{\{[<%]\s*/highlight\s*[>%]\}\}`)
+	hugoArg       = regexp.MustCompile("(?:([\\w-]+)\\s*=\\s*)?(?:\"((?:[^\"\\\\]|\\\\.)*)\"|`([^`]*)`|(\\S+))")
+	mdDestination = regexp.MustCompile(`(\]\(\s*<?|\b(?:src|href)=")([^)\s">]+)`)
+)
+
+// shortcodeArgs parses the named and positional arguments of a Hugo shortcode.
+func shortcodeArgs(s string) (named map[string]string, positional []string) {
+	named = make(map[string]string)
+	for _, m := range hugoArg.FindAllStringSubmatch(s, -1) {
+		v := m[2] + m[3] + m[4]
+		if m[2] != "" {
+			v = strings.ReplaceAll(m[2], `\"`, `"`)
+		}
+		if m[1] != "" {
+			named[m[1]] = v
+		} else {
+			positional = append(positional, v)
+		}
+	}
+	return named, positional
+}
+
+func argOr(named map[string]string, positional []string, name string, index int) string {
+	if v, ok := named[name]; ok {
+		return v
+	}
+	if index < len(positional) {
+		return positional[index]
+	}
+	return ""
+}
+
+// ref resolves the target of a ref or relref shortcode to the site path of an imported page.
+func (h *hugoImport) ref(from *hugoPage, target string) (string, bool) {
+	target, anchor, _ := strings.Cut(target, "#")
+	if anchor != "" {
+		anchor = "#" + anchor
+	}
+	if target == "" {
+		return anchor, true
+	}
+
+	candidates := []string{strings.TrimPrefix(path.Clean(target), "/")}
+	if !strings.HasPrefix(target, "/") {
+		candidates = append(candidates, path.Join(path.Dir(from.file), target))
+	}
+	for _, c := range candidates {
+		base := strings.TrimSuffix(c, path.Ext(c))
+		for _, p := range h.pages {
+			name := strings.TrimSuffix(p.file, path.Ext(p.file))
+			if c == p.file || base == name || c == p.bundle ||
+				!strings.Contains(c, "/") && (base == path.Base(name) || p.bundle != "" && base == path.Base(p.bundle)) {
+				return p.meta.Path + anchor, true
+			}
+		}
+	}
+	return "", false
+}
+
+// convert rewrites the Hugo specific markup of a page body to plain Markdown.
+func (h *hugoImport) convert(p *hugoPage) string {
+	file := filepath.Join(h.contentDir, filepath.FromSlash(p.file))
+	body := hugoHighlight.ReplaceAllStringFunc(p.body, func(m string) string {
+		sub := hugoHighlight.FindStringSubmatch(m)
+		fence := "```"
+		for strings.Contains(sub[2], fence) {
+			fence += "`"
+		}
+		return fence + sub[1] + "\n" + sub[2] + "\n" + fence
+	})
+
+	resource := func(src string) string {
+		if sitePath, ok := p.resources[strings.TrimPrefix(src, "./")]; ok {
+			return sitePath
+		}
+		return src
+	}
+
+	body = hugoShortcode.ReplaceAllStringFunc(body, func(m string) string {
+		sub := hugoShortcode.FindStringSubmatch(m)
+		if sub[2] != "" {
+			// escaped shortcode, shown literally in the source
+			closing := map[string]string{"<": ">", "%": "%"}[sub[1]]
+			return "{{" + sub[1] + " " + sub[3] + sub[4] + sub[5] + " " + closing + "}}"
+		}
+		name := sub[4]
+		named, positional := shortcodeArgs(sub[5])
+		if sub[3] != "" {
+			return unconverted(file, m)
+		}
+
+		switch name {
+		case "figure":
+			src := resource(argOr(named, positional, "src", 0))
+			img := "![" + argOr(named, nil, "alt", 0) + "](" + linkDestination(src)
+			if title := named["title"]; title != "" {
+				img += ` "` + strings.ReplaceAll(title, `"`, `\"`) + `"`
+			}
+			img += ")"
+			if link := named["link"]; link != "" {
+				img = "[" + img + "](" + linkDestination(link) + ")"
+			}
+			if caption := named["caption"]; caption != "" {
+				img += "\n\n*" + caption + "*"
+			}
+			return img
+		case "youtube":
+			return "<https://www.youtube.com/watch?v=" + argOr(named, positional, "id", 0) + ">"
+		case "vimeo":
+			return "<https://vimeo.com/" + argOr(named, positional, "id", 0) + ">"
+		case "gist":
+			return "<https://gist.github.com/" + argOr(named, positional, "user", 0) + "/" + argOr(named, positional, "id", 1) + ">"
+		case "tweet", "x", "twitter":
+			user, id := argOr(named, nil, "user", 0), argOr(named, nil, "id", 0)
+			if user == "" && len(positional) == 2 {
+				user, id = positional[0], positional[1]
+			} else if id == "" && len(positional) > 0 {
+				id = positional[0]
+			}
+			if user == "" {
+				return "<https://x.com/i/status/" + id + ">"
+			}
+			return "<https://x.com/" + user + "/status/" + id + ">"
+		case "instagram":
+			return "<https://www.instagram.com/p/" + argOr(named, positional, "id", 0) + "/>"
+		case "ref", "relref":
+			if target, ok := h.ref(p, argOr(named, positional, "path", 0)); ok {
+				return target
+			}
+			log.Warn().Str("path", file).Msgf("could not resolve %s in %s", strings.TrimSpace(m), file)
+			return argOr(named, positional, "path", 0)
+		case "param":
+			key := argOr(named, positional, "name", 0)
+			params, _ := p.front["params"].(map[string]any)
+			if v := frontString(p.front, key); v != "" {
+				return v
+			}
+			if v := frontString(params, key); v != "" {
+				return v
+			}
+		}
+		return unconverted(file, m)
+	})
+
+	// relative references to page bundle files
+	return mdDestination.ReplaceAllStringFunc(body, func(m string) string {
+		sub := mdDestination.FindStringSubmatch(m)
+		return sub[1] + resource(sub[2])
+	})
+}
+
+// linkDestination wraps a Markdown link destination in angle brackets if needed.
+func linkDestination(u string) string {
+	if strings.ContainsAny(u, " ()") {
+		return "<" + u + ">"
+	}
+	return u
+}
+
+func import_hugo_main(args []string) {
+	fs := flag.NewFlagSet("import hugo", flag.ExitOnError)
+	out := fs.String("out", rootDir, "directory to write posts (blog/) and pages (pages/) to")
+	sections := fs.String("sections", "posts,post,blog", "comma separated content sections imported as blog posts, other pages are imported to pages/")
+	drafts := fs.Bool("drafts", false, "import drafts as hidden posts")
+	static := fs.Bool("static", false, "copy the static directory of the site into "+publicDir)
+	overwrite := fs.Bool("overwrite", false, "overwrite previously imported files")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal().Msg("usage: import hugo [flags] <site directory>")
+	}
+
+	site := fs.Arg(0)
+	config := readHugoConfig(site)
+	h := &hugoImport{
+		site:       site,
+		contentDir: filepath.Join(site, "content"),
+		permalinks: hugoPermalinks(config),
+	}
+	if dir := frontString(config, "contentDir"); dir != "" {
+		h.contentDir = filepath.Join(site, dir)
+	}
+	defaultLang := frontString(config, "defaultContentLanguage")
+	if defaultLang == "" {
+		defaultLang = types.LangEnglish
+	}
+	postSections := strings.Split(*sections, ",")
+
+	var skipped int
+	err := filepath.WalkDir(h.contentDir, func(fp string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := filepath.Ext(fp)
+		if ext != ".md" && ext != ".markdown" && ext != ".html" {
+			return nil
+		}
+		rel, err := filepath.Rel(h.contentDir, fp)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		name := strings.TrimSuffix(path.Base(rel), ext)
+		if strings.HasPrefix(name, "_index") {
+			return nil // list pages are generated here
+		}
+		if base, lang, ok := strings.Cut(name, "."); ok {
+			if lang != defaultLang {
+				log.Info().Str("path", fp).Msgf("skipping %s, translations are generated by this site", fp)
+				skipped++
+				return nil
+			}
+			name = base
+		}
+
+		data, err := os.ReadFile(fp)
+		if err != nil {
+			return err
+		}
+		front, body, err := frontmatter.Parse(data)
+		if err != nil {
+			log.Error().Err(err).Str("path", fp).Msgf("skipping %s, failed to parse front matter", fp)
+			skipped++
+			return nil
+		}
+		if draft, _ := frontBool(front, "draft"); draft && !*drafts {
+			skipped++
+			return nil
+		}
+		if ext == ".html" {
+			md, err := htmltomd.Convert(string(body))
+			if err != nil {
+				return err
+			}
+			body = []byte(md)
+		}
+
+		p := &hugoPage{file: rel, front: front, body: string(body)}
+		if name == "index" {
+			p.bundle = path.Dir(rel)
+			name = path.Base(p.bundle)
+		}
+		section, _, _ := strings.Cut(rel, "/")
+		p.post = strings.Contains(rel, "/") && slices.Contains(postSections, section)
+		p.slug = importSlug(frontString(front, "slug"))
+		if p.slug == "" {
+			p.slug = importSlug(name)
+		}
+		h.pages = append(h.pages, p)
+		return nil
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to read Hugo content from %s", h.contentDir)
+	}
+
+	for _, p := range h.pages {
+		front := p.front
+		draft, _ := frontBool(front, "draft")
+		p.meta = &types.Metadata{
+			Title:       frontString(front, "title", "linkTitle"),
+			Description: frontString(front, "description", "summary"),
+			Date:        frontTime(front, "date", "publishDate", "pubdate", "published"),
+			ExpiryDate:  frontTime(front, "expiryDate", "unpublishdate"),
+			Hidden:      draft,
+		}
+		if authors := frontStrings(front, "authors"); len(authors) > 0 {
+			p.meta.Author = strings.Join(authors, ", ")
+		} else {
+			p.meta.Author = frontString(front, "author")
+		}
+		p.meta.Tags = appendUnique(frontStrings(front, "tags"), frontStrings(front, "categories")...)
+		if series := frontStrings(front, "series"); len(series) > 0 {
+			p.meta.Series = series[0]
+		}
+		if w, ok := front["weight"].(int64); ok {
+			p.meta.Weight = int(w)
+		} else if w, ok := front["weight"].(int); ok {
+			p.meta.Weight = w
+		}
+
+		original := h.originalURL(p)
+		if p.post {
+			p.meta.Path = "/blog/posts/" + p.slug
+		} else {
+			p.meta.Path = strings.TrimSuffix(original, "/")
+			if p.meta.Path == "" {
+				p.meta.Path = "/" + p.slug
+			}
+		}
+		if original != "" && !p.meta.Hidden && strings.TrimSuffix(original, "/") != p.meta.Path {
+			p.meta.Aliases = append(p.meta.Aliases, original)
+		}
+		for _, alias := range frontStrings(front, "aliases") {
+			if !strings.HasPrefix(alias, "/") {
+				alias = path.Join(path.Dir(strings.TrimSuffix(original, "/")), alias)
+			}
+			p.meta.Aliases = appendUnique(p.meta.Aliases, alias)
+		}
+
+		if p.bundle != "" {
+			p.resources = make(map[string]string)
+			dir := filepath.Join(h.contentDir, filepath.FromSlash(p.bundle))
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				log.Fatal().Err(err).Msgf("failed to read page bundle %s", dir)
+			}
+			for _, e := range entries {
+				if e.IsDir() || strings.HasPrefix(e.Name(), "index.") {
+					continue
+				}
+				sitePath := "/assets/images/hugo/" + p.slug + "/" + e.Name()
+				if _, err := copyTree(filepath.Join(dir, e.Name()), publicPath(sitePath)); err != nil {
+					log.Fatal().Err(err).Msgf("failed to copy page bundle resource %s", e.Name())
+				}
+				p.resources[e.Name()] = sitePath
+			}
+		}
+
+		image := frontString(front, "image", "featured_image", "featuredImage", "cover")
+		if images := frontStrings(front, "images"); image == "" && len(images) > 0 {
+			image = images[0]
+		}
+		if cover, ok := front["cover"].(map[string]any); ok && image == "" {
+			image = frontString(cover, "image")
+			p.meta.ImageAlt = frontString(cover, "alt")
+		}
+		if sitePath, ok := p.resources[strings.TrimPrefix(image, "./")]; ok {
+			image = sitePath
+		}
+		if strings.HasPrefix(image, "/") {
+			p.meta.Image = image
+		}
+	}
+
+	var imported int
+	for _, p := range h.pages {
+		dir := filepath.Join(*out, "blog")
+		if !p.post {
+			dir = filepath.Join(*out, "pages")
+		}
+		ok, err := writeImportedPost(dir, p.slug, p.meta, h.convert(p), *overwrite)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to write %q", p.meta.Title)
+		}
+		if ok {
+			imported++
+		} else {
+			skipped++
+		}
+	}
+
+	if *static {
+		copied, err := copyTree(filepath.Join(site, "static"), publicDir)
+		if err != nil && !os.IsNotExist(err) {
+			log.Fatal().Err(err).Msg("failed to copy the static directory")
+		}
+		log.Info().Int("files", copied).Msgf("copied %d static files", copied)
+	}
+
+	log.Info().Int("imported", imported).Int("skipped", skipped).Msgf("imported %d Hugo posts and pages", imported)
+}
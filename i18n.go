@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/i18n"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// localizedContext returns a context rendering templates in lang, for
+// commands rendering outside of generate.
+func localizedContext(lang types.Lang) context.Context {
+	b, err := i18n.Load(i18nDir)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load messages from %s", i18nDir)
+	}
+	return view.NewContext(context.Background(), &view.RenderContext{Localizer: b.Localizer(lang)})
+}
+
+// reportMissingMessages warns about the UI messages rendered without a
+// translation in languages with a message file. Languages without a message
+// file are rendered in English and only counted.
+func reportMissingMessages(gc *GenerationContext) {
+	missing := gc.I18n.Missing()
+	localized := gc.I18n.Languages()
+
+	langs := make([]string, 0, len(missing))
+	for lang := range missing {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	var untranslated []string
+	for _, lang := range langs {
+		if !slices.Contains(localized, lang) {
+			untranslated = append(untranslated, lang)
+			continue
+		}
+		keys := missing[lang]
+		log.Warn().Str("lang", lang).Strs("messages", keys).Msgf("%d UI messages are missing from %s/%s.yaml", len(keys), i18nDir, lang)
+	}
+	if len(untranslated) > 0 {
+		log.Info().Strs("langs", untranslated).Msgf("%d languages have no UI message file in %s and are rendered in English", len(untranslated), i18nDir)
+	}
+}
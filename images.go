@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"gosuda.org/website/internal/imagemeta"
+	"gosuda.org/website/internal/types"
+)
+
+const thumbnailWidth = 640
+
+// PostImage describes the cover image of a post.
+type PostImage struct {
+	// URL is the site path of the cover image.
+	URL string
+	// Thumbnail is the site path of the thumbnail shown on index cards.
+	Thumbnail string
+	// Type is the MIME type of the cover image.
+	Type string
+	// Size is the size of the cover image in bytes.
+	Size int64
+}
+
+// publicPath maps a site path to its source file in the public directory.
+func publicPath(sitePath string) string {
+	return filepath.Join(publicDir, filepath.FromSlash(path.Clean("/"+sitePath)))
+}
+
+func imageType(p string) string {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".png":
+		return "image/png"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".webp":
+		return "image/webp"
+	case ".gif":
+		return "image/gif"
+	case ".svg":
+		return "image/svg+xml"
+	}
+	return "application/octet-stream"
+}
+
+// resizeToWidth scales src down to width, keeping the aspect ratio.
+func resizeToWidth(src image.Image, width int) image.Image {
+	b := src.Bounds()
+	height := b.Dy() * width / b.Dx()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	return dst
+}
+
+// generateThumbnail writes a thumbnail of the cover image to dist and returns its site path.
+// Images narrower than the thumbnail width and vector images are used as they are.
+func generateThumbnail(post *types.Post, cover string) (string, error) {
+	if imageType(cover) == "image/svg+xml" {
+		return cover, nil
+	}
+
+	f, err := os.Open(publicPath(cover))
+	if err != nil {
+		return "", err
+	}
+	src, format, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		return "", fmt.Errorf("decoding %s: %w", cover, err)
+	}
+	if src.Bounds().Dx() <= thumbnailWidth {
+		return cover, nil
+	}
+
+	var b bytes.Buffer
+	thumb := resizeToWidth(src, thumbnailWidth)
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+		err = jpeg.Encode(&b, thumb, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&b, thumb)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	thumbPath := fmt.Sprintf("/assets/thumbnails/%s-%d%s", post.ID, thumbnailWidth, ext)
+	fp := filepath.Join(distDir, filepath.FromSlash(thumbPath))
+	err = os.MkdirAll(filepath.Dir(fp), 0755)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(fp, b.Bytes(), 0644)
+	if err != nil {
+		return "", err
+	}
+	return thumbPath, nil
+}
+
+// processPostImages validates the cover images of all posts and generates their thumbnails.
+func processPostImages(gc *GenerationContext) error {
+	log.Debug().Msg("start processing post images")
+	gc.Images = make(map[string]*PostImage)
+
+	for _, post := range publishedPosts(gc) {
+		pm := &post.Main.Metadata
+		if pm.Image == "" {
+			if pm.Thumbnail != "" {
+				return fmt.Errorf("%s: thumbnail is set without an image", post.FilePath)
+			}
+			continue
+		}
+
+		for _, p := range []string{pm.Image, pm.Thumbnail} {
+			if p == "" {
+				continue
+			}
+			if _, err := os.Stat(publicPath(p)); err != nil {
+				return fmt.Errorf("%s: image %s does not exist in %s: %w", post.FilePath, p, publicDir, err)
+			}
+		}
+		if pm.ImageAlt == "" {
+			log.Warn().Str("path", post.FilePath).Msgf("image %s of %s has no alt text", pm.Image, post.FilePath)
+		}
+
+		// the copy in dist may be smaller than the source after metadata stripping
+		info, err := os.Stat(filepath.Join(distDir, filepath.FromSlash(path.Clean("/"+pm.Image))))
+		if err != nil {
+			return err
+		}
+		img := &PostImage{
+			URL:       pm.Image,
+			Thumbnail: pm.Thumbnail,
+			Type:      imageType(pm.Image),
+			Size:      info.Size(),
+		}
+		if img.Thumbnail == "" {
+			img.Thumbnail, err = generateThumbnail(post, pm.Image)
+			if err != nil {
+				return err
+			}
+		}
+		gc.Images[post.ID] = img
+		log.Debug().Str("path", post.FilePath).Str("thumbnail", img.Thumbnail).Msgf("processed image of %s", post.FilePath)
+	}
+
+	log.Debug().Int("images", len(gc.Images)).Msg("done processing post images")
+	return nil
+}
+
+// stripImageMetadata removes metadata from every image in dist, keeping the allowed EXIF tags.
+func stripImageMetadata(gc *GenerationContext) error {
+	ic := &gc.Config.Images
+	if !ic.StripMetadata {
+		return nil
+	}
+	log.Debug().Msg("start stripping image metadata")
+
+	for _, name := range ic.KeepEXIF {
+		if _, ok := imagemeta.Tags[name]; !ok {
+			return fmt.Errorf("unknown EXIF tag %q in images.keep_exif", name)
+		}
+	}
+
+	list, err := generateFileList(distDir)
+	if err != nil {
+		return err
+	}
+
+	var stripped int
+	for _, fp := range list {
+		switch strings.ToLower(filepath.Ext(fp)) {
+		case ".jpg", ".jpeg", ".png", ".webp":
+		default:
+			continue
+		}
+
+		data, err := os.ReadFile(fp)
+		if err != nil {
+			return err
+		}
+		out, err := imagemeta.Strip(data, ic.KeepEXIF)
+		if err != nil {
+			log.Warn().Err(err).Str("path", fp).Msgf("failed to strip metadata of %s", fp)
+			continue
+		}
+		if bytes.Equal(out, data) {
+			continue
+		}
+
+		err = os.WriteFile(fp, out, 0644)
+		if err != nil {
+			return err
+		}
+		stripped++
+		log.Debug().Str("path", fp).Int("removed", len(data)-len(out)).Msgf("stripped metadata of %s", fp)
+	}
+
+	log.Debug().Int("images", stripped).Msg("done stripping image metadata")
+	return nil
+}
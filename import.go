@@ -0,0 +1,290 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"gosuda.org/website/internal/types"
+)
+
+func import_main() {
+	if len(os.Args) < 3 {
+		log.Fatal().Msg("usage: import <wordpress|hugo|jekyll> ...")
+	}
+
+	switch os.Args[2] {
+	case "wordpress":
+		import_wordpress_main(os.Args[3:]) // convert a WordPress WXR export to markdown posts.
+	case "hugo":
+		import_hugo_main(os.Args[3:]) // convert the content directory of a Hugo site.
+	case "jekyll":
+		import_jekyll_main(os.Args[3:]) // convert the posts and pages of a Jekyll site.
+	default:
+		log.Fatal().Msgf("unknown importer %q", os.Args[2])
+	}
+}
+
+var slugInvalid = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// importSlug returns a file name safe slug.
+func importSlug(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = slugInvalid.ReplaceAllString(s, "-")
+	return strings.Trim(s, "-")
+}
+
+// writeImportedPost writes a markdown file with front matter to dir/slug.md.
+// Existing files are kept unless overwrite is set, so imports can be re-run.
+func writeImportedPost(dir, slug string, meta *types.Metadata, body string, overwrite bool) (bool, error) {
+	fp := filepath.Join(dir, slug+".md")
+	if _, err := os.Stat(fp); err == nil && !overwrite {
+		log.Warn().Str("path", fp).Msgf("skipping %s, file already exists", fp)
+		return false, nil
+	}
+
+	if meta.ID == "" {
+		meta.ID = types.RandID()
+	}
+	front, err := yaml.Marshal(meta)
+	if err != nil {
+		return false, err
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return false, err
+	}
+	err = os.WriteFile(fp, []byte("---\n"+string(front)+"---\n\n"+strings.TrimSpace(body)+"\n"), 0644)
+	if err != nil {
+		return false, err
+	}
+	log.Debug().Str("path", fp).Msgf("imported %s", meta.Title)
+	return true, nil
+}
+
+// imageImporter downloads images referenced by imported posts into the public directory.
+type imageImporter struct {
+	// dir is the site directory images are stored in. (e.g. "/assets/images/wordpress")
+	dir    string
+	client *http.Client
+	done   map[string]string
+}
+
+func newImageImporter(dir string) *imageImporter {
+	return &imageImporter{
+		dir:    dir,
+		client: &http.Client{Timeout: time.Minute},
+		done:   make(map[string]string),
+	}
+}
+
+// fetch stores the image at src and returns its site path.
+func (ii *imageImporter) fetch(src string) (string, error) {
+	if p, ok := ii.done[src]; ok {
+		return p, nil
+	}
+
+	u, err := url.Parse(src)
+	if err != nil {
+		return "", err
+	}
+	// prefix a short hash of the URL, since exports reuse names across upload folders.
+	// The query is left out, as signed URLs (e.g. Notion files) change on every request.
+	sum := sha256.Sum256([]byte(u.Host + u.Path))
+	base := path.Base(u.Path)
+	name := hex.EncodeToString(sum[:4]) + "-" + importSlug(strings.TrimSuffix(base, path.Ext(base)))
+	if ext := path.Ext(base); ext != "" {
+		sitePath := ii.dir + "/" + name + strings.ToLower(ext)
+		if _, err := os.Stat(publicPath(sitePath)); err == nil {
+			ii.done[src] = sitePath
+			return sitePath, nil
+		}
+	}
+
+	resp, err := ii.client.Get(src)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching %s: %s", src, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	ext := path.Ext(base)
+	if ext == "" {
+		if exts, _ := mime.ExtensionsByType(resp.Header.Get("Content-Type")); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	name += strings.ToLower(ext)
+
+	sitePath := ii.dir + "/" + name
+	fp := publicPath(sitePath)
+	err = os.MkdirAll(filepath.Dir(fp), 0755)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(fp, data, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	ii.done[src] = sitePath
+	log.Debug().Str("src", src).Str("path", sitePath).Msgf("downloaded image %s", src)
+	return sitePath, nil
+}
+
+// localize returns the site path of a downloaded copy of src, or src itself if the download fails.
+func (ii *imageImporter) localize(src string) string {
+	if ii == nil || !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return src
+	}
+	p, err := ii.fetch(src)
+	if err != nil {
+		log.Warn().Err(err).Str("src", src).Msgf("failed to download image %s, keeping the remote URL", src)
+		return src
+	}
+	return p
+}
+
+// frontString returns the first non-empty string value of keys in front matter.
+func frontString(front map[string]any, keys ...string) string {
+	for _, k := range keys {
+		switch v := front[k].(type) {
+		case string:
+			if s := strings.TrimSpace(v); s != "" {
+				return s
+			}
+		case int, int64, float64:
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+// frontStrings returns a front matter value as a list; a single string is a list of one.
+func frontStrings(front map[string]any, key string) []string {
+	switch v := front[key].(type) {
+	case string:
+		if s := strings.TrimSpace(v); s != "" {
+			return []string{s}
+		}
+	case []any:
+		var list []string
+		for _, item := range v {
+			if s := strings.TrimSpace(fmt.Sprint(item)); item != nil && s != "" {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func frontBool(front map[string]any, key string) (value, ok bool) {
+	switch v := front[key].(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	}
+	return false, false
+}
+
+var frontTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.DateOnly,
+}
+
+// frontTime returns the first date of keys in front matter, in UTC.
+func frontTime(front map[string]any, keys ...string) time.Time {
+	for _, k := range keys {
+		switch v := front[k].(type) {
+		case time.Time:
+			return v.UTC()
+		case string:
+			for _, layout := range frontTimeLayouts {
+				if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+					return t.UTC()
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// appendUnique appends the items of list that are not in dst yet.
+func appendUnique(dst []string, list ...string) []string {
+	for _, s := range list {
+		if !slices.Contains(dst, s) {
+			dst = append(dst, s)
+		}
+	}
+	return dst
+}
+
+// unconverted marks markup that an importer could not convert, keeping it in the
+// source as a comment so it can be converted by hand.
+func unconverted(file, markup string) string {
+	log.Warn().Str("path", file).Msgf("could not convert %s in %s, left as a comment", markup, file)
+	return "<!-- TODO(import): " + strings.ReplaceAll(markup, "--", "- -") + " -->"
+}
+
+// copyTree copies the files under src to dst, keeping existing files.
+func copyTree(src, dst string) (int, error) {
+	var copied int
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if _, err := os.Stat(target); err == nil {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(filepath.Dir(target), 0755)
+		if err != nil {
+			return err
+		}
+		copied++
+		return os.WriteFile(target, data, 0644)
+	})
+	return copied, err
+}
@@ -0,0 +1,299 @@
+// Package a11y checks HTML documents for common accessibility issues. The checks
+// are static: styles from stylesheets and scripts are not taken into account.
+package a11y
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Rules reported by Check.
+const (
+	RuleLang        = "missing-lang"
+	RuleAlt         = "missing-alt"
+	RuleHeadingSkip = "heading-skip"
+	RuleEmptyLink   = "empty-link"
+	RuleEmptyButton = "empty-button"
+	RuleContrast    = "low-contrast"
+)
+
+// MinContrast is the minimum contrast ratio of normal text. (WCAG 2 AA)
+const MinContrast = 4.5
+
+// Issue is an accessibility problem found in a document.
+type Issue struct {
+	Rule    string
+	Message string
+}
+
+func (i Issue) String() string {
+	return i.Rule + ": " + i.Message
+}
+
+// Check parses doc and returns its accessibility issues in document order.
+func Check(doc []byte) ([]Issue, error) {
+	root, err := html.Parse(bytes.NewReader(doc))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &checker{}
+	c.walk(root)
+	return c.issues, nil
+}
+
+type checker struct {
+	issues  []Issue
+	heading int
+}
+
+func (c *checker) report(rule, format string, args ...any) {
+	c.issues = append(c.issues, Issue{Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) walk(n *html.Node) {
+	if n.Type == html.ElementNode {
+		c.element(n)
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.walk(child)
+	}
+}
+
+func (c *checker) element(n *html.Node) {
+	switch n.DataAtom {
+	case atom.Html:
+		if lang, _ := attr(n, "lang"); strings.TrimSpace(lang) == "" {
+			c.report(RuleLang, "<html> has no lang attribute")
+		}
+	case atom.Img:
+		if _, ok := attr(n, "alt"); !ok && !hidden(n) {
+			src, _ := attr(n, "src")
+			c.report(RuleAlt, "<img src=%q> has no alt attribute", src)
+		}
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		if c.heading > 0 && level > c.heading+1 {
+			c.report(RuleHeadingSkip, "<%s> %q follows <h%d>", n.Data, excerpt(textContent(n)), c.heading)
+		}
+		c.heading = level
+	case atom.A:
+		if _, ok := attr(n, "href"); ok && !hidden(n) && !hasName(n) {
+			href, _ := attr(n, "href")
+			c.report(RuleEmptyLink, "<a href=%q> has no text or label", href)
+		}
+	case atom.Button:
+		if !hidden(n) && !hasName(n) {
+			c.report(RuleEmptyButton, "<button> has no text or label")
+		}
+	}
+
+	if style, ok := attr(n, "style"); ok {
+		c.contrast(n, style)
+	}
+}
+
+// contrast reports inline styles that set both the text and background color
+// to colors with too little contrast.
+func (c *checker) contrast(n *html.Node, style string) {
+	var fg, bg string
+	for _, decl := range strings.Split(style, ";") {
+		prop, val, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		val = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(val), "!important"))
+		switch strings.ToLower(strings.TrimSpace(prop)) {
+		case "color":
+			fg = val
+		case "background-color", "background":
+			bg = val
+		}
+	}
+	if fg == "" || bg == "" {
+		return
+	}
+	fc, ok := parseColor(fg)
+	if !ok {
+		return
+	}
+	bc, ok := parseColor(bg)
+	if !ok {
+		return
+	}
+	if ratio := Contrast(fc, bc); ratio < MinContrast {
+		c.report(RuleContrast, "<%s> %q has a contrast ratio of %.2f (%s on %s)", n.Data, excerpt(textContent(n)), ratio, fg, bg)
+	}
+}
+
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// hidden reports whether n is removed from the accessibility tree.
+func hidden(n *html.Node) bool {
+	if v, _ := attr(n, "aria-hidden"); v == "true" {
+		return true
+	}
+	if _, ok := attr(n, "hidden"); ok {
+		return true
+	}
+	role, _ := attr(n, "role")
+	return role == "presentation" || role == "none"
+}
+
+// hasName reports whether n has an accessible name from its label attributes,
+// its text or the alternative text of its images.
+func hasName(n *html.Node) bool {
+	for _, key := range []string{"aria-label", "aria-labelledby", "title"} {
+		if v, _ := attr(n, key); strings.TrimSpace(v) != "" {
+			return true
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		switch child.Type {
+		case html.TextNode:
+			if strings.TrimSpace(child.Data) != "" {
+				return true
+			}
+		case html.ElementNode:
+			if v, _ := attr(child, "aria-hidden"); v == "true" {
+				continue
+			}
+			switch child.DataAtom {
+			case atom.Img:
+				if alt, _ := attr(child, "alt"); strings.TrimSpace(alt) != "" {
+					return true
+				}
+			case atom.Svg:
+				if v, _ := attr(child, "aria-label"); strings.TrimSpace(v) != "" {
+					return true
+				}
+				for t := child.FirstChild; t != nil; t = t.NextSibling {
+					if t.Type == html.ElementNode && t.Data == "title" && strings.TrimSpace(textContent(t)) != "" {
+						return true
+					}
+				}
+			default:
+				if hasName(child) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func excerpt(s string) string {
+	r := []rune(s)
+	if len(r) > 40 {
+		return string(r[:40]) + "…"
+	}
+	return s
+}
+
+// Color is an sRGB color with 8-bit channels.
+type Color struct {
+	R, G, B uint8
+}
+
+var namedColors = map[string]Color{
+	"black":  {0, 0, 0},
+	"white":  {255, 255, 255},
+	"gray":   {128, 128, 128},
+	"grey":   {128, 128, 128},
+	"silver": {192, 192, 192},
+	"red":    {255, 0, 0},
+	"green":  {0, 128, 0},
+	"blue":   {0, 0, 255},
+	"yellow": {255, 255, 0},
+	"orange": {255, 165, 0},
+}
+
+// parseColor parses hex, rgb() and a few named colors. Colors with an alpha
+// channel are not parsed, as their contrast depends on what is behind them.
+func parseColor(s string) (Color, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := namedColors[s]; ok {
+		return c, true
+	}
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) != 6 {
+			return Color{}, false
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return Color{}, false
+		}
+		return Color{uint8(v >> 16), uint8(v >> 8), uint8(v)}, true
+	}
+	if args, ok := strings.CutPrefix(s, "rgb("); ok {
+		args, ok = strings.CutSuffix(args, ")")
+		if !ok {
+			return Color{}, false
+		}
+		parts := strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' })
+		if len(parts) != 3 {
+			return Color{}, false
+		}
+		var ch [3]uint8
+		for i, p := range parts {
+			v, err := strconv.Atoi(p)
+			if err != nil || v < 0 || v > 255 {
+				return Color{}, false
+			}
+			ch[i] = uint8(v)
+		}
+		return Color{ch[0], ch[1], ch[2]}, true
+	}
+	return Color{}, false
+}
+
+func (c Color) luminance() float64 {
+	channel := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c.R) + 0.7152*channel(c.G) + 0.0722*channel(c.B)
+}
+
+// Contrast returns the WCAG contrast ratio of two colors, from 1 to 21.
+func Contrast(a, b Color) float64 {
+	la, lb := a.luminance(), b.luminance()
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
@@ -0,0 +1,80 @@
+package a11y
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func rules(issues []Issue) []string {
+	var r []string
+	for _, i := range issues {
+		r = append(r, i.Rule)
+	}
+	return r
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{
+			name: "clean",
+			doc: `<html lang="en"><body><h1>Title</h1><h2>Section</h2><h3>Sub</h3><h2>Next</h2>
+<img src="a.png" alt=""><a href="/">Home</a><a href="/x"><img src="x.png" alt="X"></a>
+<button aria-label="Menu"><svg aria-hidden="true"></svg></button>
+<a href="/y"><svg><title>Y</title></svg></a>
+<p style="color: #000; background: #fff">ok</p></body></html>`,
+		},
+		{
+			name: "missing lang",
+			doc:  `<html><body><p>Hi</p></body></html>`,
+			want: []string{RuleLang},
+		},
+		{
+			name: "missing alt",
+			doc:  `<html lang="en"><body><img src="a.png"><img src="b.png" aria-hidden="true"></body></html>`,
+			want: []string{RuleAlt},
+		},
+		{
+			name: "heading skip",
+			doc:  `<html lang="en"><body><h1>A</h1><h3>B</h3><h4>C</h4><h2>D</h2></body></html>`,
+			want: []string{RuleHeadingSkip},
+		},
+		{
+			name: "empty link and button",
+			doc:  `<html lang="en"><body><a href="/"> </a><a href="/i"><img src="i.png" alt=""></a><a name="anchor"></a><button><span></span></button></body></html>`,
+			want: []string{RuleEmptyLink, RuleEmptyLink, RuleEmptyButton},
+		},
+		{
+			name: "low contrast",
+			doc:  `<html lang="en"><body><p style="color:#777;background-color:#888">low</p><p style="color:#777">unknown</p><p style="color:rgba(0,0,0,.5);background:#fff">alpha</p></body></html>`,
+			want: []string{RuleContrast},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := Check([]byte(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rules(issues); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rules = %v, want %v (%v)", got, tt.want, issues)
+			}
+		})
+	}
+}
+
+func TestContrast(t *testing.T) {
+	black, _ := parseColor("#000")
+	white, _ := parseColor("white")
+	if got := Contrast(black, white); math.Abs(got-21) > 0.01 {
+		t.Errorf("Contrast(black, white) = %.2f, want 21", got)
+	}
+	gray, _ := parseColor("rgb(119, 119, 119)")
+	if got := Contrast(gray, white); math.Abs(got-4.48) > 0.01 {
+		t.Errorf("Contrast(#777, white) = %.2f, want 4.48", got)
+	}
+}
@@ -0,0 +1,73 @@
+package alttext
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/lemon-mint/coord/llm"
+	"github.com/lemon-mint/coord/llmtools"
+)
+
+const prompt = `You are tasked with writing alternative text for the attached image, which appears in a blog post. Alternative text is read by screen readers and shown when the image cannot be loaded.
+
+This is the text surrounding the image in the post:
+<context>
+<CONTEXT>
+</context>
+
+When writing the alternative text:
+- Describe what the image shows and why it matters in the post, not how it looks in general.
+- For diagrams and charts, state what they explain or the trend they show.
+- For screenshots of code or terminals, summarize what the code or output is.
+- Do not start with "Image of" or "Picture of".
+- Keep it within 125 characters.
+- Write it in <LANGUAGE>.
+
+Present the alternative text in the following format:
+[START_TOKEN]
+[Your alternative text here]
+[END_TOKEN]`
+
+var (
+	ErrFailedToGenerateAltText = errors.New("failed to generate alt text")
+)
+
+// GenerateAltText describes image, of the given MIME type, with a vision
+// model. surrounding is the text around the image in the post.
+func GenerateAltText(ctx context.Context, l llm.Model, image []byte, mimeType, surrounding, language string) (string, error) {
+	var b [8]byte
+	rand.Read(b[:])
+	startToken := "[" + hex.EncodeToString(b[:]) + "]"
+	rand.Read(b[:])
+	endToken := "[" + hex.EncodeToString(b[:]) + "]"
+
+	prompt := strings.Replace(prompt, "[START_TOKEN]", startToken, 1)
+	prompt = strings.Replace(prompt, "[END_TOKEN]", endToken, 1)
+	prompt = strings.Replace(prompt, "<LANGUAGE>", language, 1)
+	prompt = strings.Replace(prompt, "<CONTEXT>", surrounding, 1)
+
+	input := &llm.Content{
+		Role: llm.RoleUser,
+		Parts: []llm.Segment{
+			&llm.InlineData{MIMEType: mimeType, Data: image},
+			llm.Text(prompt),
+		},
+	}
+	resp := l.GenerateStream(ctx, &llm.ChatContext{}, input)
+	err := resp.Wait()
+	if err != nil {
+		return "", err
+	}
+
+	text := llmtools.TextFromContents(resp.Content)
+	sidx := strings.Index(text, startToken)
+	eidx := strings.Index(text, endToken)
+	if sidx != -1 && eidx > sidx {
+		return strings.TrimSpace(text[sidx+len(startToken) : eidx]), nil
+	}
+
+	return "", ErrFailedToGenerateAltText
+}
@@ -0,0 +1,272 @@
+// Package bibliography reads bibliography files, BibTeX or CSL-JSON, and
+// formats their entries as the references of a page.
+package bibliography
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Name is a person or, with Literal, an organization.
+type Name struct {
+	Family  string `json:"family,omitempty"`
+	Given   string `json:"given,omitempty"`
+	Suffix  string `json:"suffix,omitempty"`
+	Literal string `json:"literal,omitempty"`
+}
+
+// Entry is a work of a bibliography, with the CSL-JSON variables the
+// references are formatted from.
+type Entry struct {
+	ID             string `json:"id"`
+	Type           string `json:"type"`
+	Title          string `json:"title"`
+	Author         []Name `json:"author"`
+	Editor         []Name `json:"editor"`
+	Issued         Date   `json:"issued"`
+	ContainerTitle string `json:"container-title"`
+	Publisher      string `json:"publisher"`
+	Volume         Text   `json:"volume"`
+	Issue          Text   `json:"issue"`
+	Page           Text   `json:"page"`
+	DOI            string `json:"DOI"`
+	URL            string `json:"URL"`
+}
+
+// Date is a CSL-JSON date, of which only the year is used.
+type Date struct {
+	Year int
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var v struct {
+		DateParts [][]Text `json:"date-parts"`
+		Raw       string   `json:"raw"`
+		Literal   string   `json:"literal"`
+	}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	if len(v.DateParts) > 0 && len(v.DateParts[0]) > 0 {
+		d.Year, _ = strconv.Atoi(string(v.DateParts[0][0]))
+		return nil
+	}
+	d.Year = leadingYear(v.Raw + v.Literal)
+	return nil
+}
+
+// leadingYear returns the year s starts with, such as 2024 of "2024-05-01",
+// or 0.
+func leadingYear(s string) int {
+	s = strings.TrimSpace(s)
+	end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) })
+	if end < 0 {
+		end = len(s)
+	}
+	year, _ := strconv.Atoi(s[:end])
+	return year
+}
+
+// Text is a CSL-JSON variable given as a string or a number.
+type Text string
+
+func (t *Text) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*t = Text(s)
+		return nil
+	}
+	var n json.Number
+	err := json.Unmarshal(data, &n)
+	if err != nil {
+		return err
+	}
+	*t = Text(n)
+	return nil
+}
+
+// Bibliography maps citation keys to their entries.
+type Bibliography map[string]*Entry
+
+// Load reads the bibliography file at path, BibTeX if it ends with .bib and
+// CSL-JSON if it ends with .json.
+func Load(path string) (Bibliography, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".bib", ".bibtex":
+		entries, err = ParseBibTeX(data)
+	case ".json":
+		entries, err = ParseCSLJSON(data)
+	default:
+		return nil, fmt.Errorf("%s: unknown bibliography format %q", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	b := make(Bibliography, len(entries))
+	for _, e := range entries {
+		if _, ok := b[e.ID]; ok {
+			return nil, fmt.Errorf("%s: duplicate key %q", path, e.ID)
+		}
+		b[e.ID] = e
+	}
+	return b, nil
+}
+
+// ParseCSLJSON parses a CSL-JSON array of entries.
+func ParseCSLJSON(data []byte) ([]*Entry, error) {
+	var entries []*Entry
+	err := json.Unmarshal(data, &entries)
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range entries {
+		if e.ID == "" {
+			return nil, fmt.Errorf("entry %d has no id", i+1)
+		}
+	}
+	return entries, nil
+}
+
+// standalone are the types of self-contained works, whose titles are
+// italicized like those of containers.
+var standalone = map[string]bool{"book": true, "thesis": true, "report": true}
+
+// HTML formats the entry as a reference in the style of APA:
+//
+//	Family, G., & Other, A. (2024). Title. <i>Container</i>, <i>12</i>(3), 45–67. Publisher. https://doi.org/...
+func (e *Entry) HTML() string {
+	var b strings.Builder
+	names := e.Author
+	if len(names) == 0 {
+		names = e.Editor
+	}
+	if len(names) > 0 {
+		b.WriteString(html.EscapeString(formatNames(names)))
+		if len(e.Author) == 0 {
+			if len(names) == 1 {
+				b.WriteString(" (Ed.)")
+			} else {
+				b.WriteString(" (Eds.)")
+			}
+		}
+		b.WriteByte(' ')
+	}
+	title := html.EscapeString(strings.TrimSpace(e.Title))
+	if standalone[e.Type] {
+		title = "<i>" + title + "</i>"
+	}
+	title += period(e.Title)
+	// works without authors start with their titles
+	if len(names) == 0 {
+		b.WriteString(title + " ")
+	}
+	if e.Issued.Year > 0 {
+		fmt.Fprintf(&b, "(%d).", e.Issued.Year)
+	} else {
+		b.WriteString("(n.d.).")
+	}
+	if len(names) > 0 {
+		b.WriteString(" " + title)
+	}
+
+	if e.ContainerTitle != "" {
+		b.WriteString(" <i>" + html.EscapeString(e.ContainerTitle) + "</i>")
+		if e.Volume != "" {
+			b.WriteString(", <i>" + html.EscapeString(string(e.Volume)) + "</i>")
+			if e.Issue != "" {
+				b.WriteString("(" + html.EscapeString(string(e.Issue)) + ")")
+			}
+		}
+		if e.Page != "" {
+			b.WriteString(", " + html.EscapeString(pageRange(string(e.Page))))
+		}
+		b.WriteByte('.')
+	}
+	if e.Publisher != "" && e.Publisher != e.ContainerTitle {
+		b.WriteString(" " + html.EscapeString(sentence(e.Publisher)))
+	}
+
+	link := e.URL
+	if e.DOI != "" {
+		link = "https://doi.org/" + strings.TrimPrefix(e.DOI, "https://doi.org/")
+	}
+	if link != "" {
+		link = html.EscapeString(link)
+		b.WriteString(` <a href="` + link + `">` + link + `</a>`)
+	}
+	return b.String()
+}
+
+// sentence ends s with a period unless it ends with punctuation.
+func sentence(s string) string {
+	return strings.TrimSpace(s) + period(s)
+}
+
+// period returns the period ending s, "" if it ends with punctuation.
+func period(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ContainsAny(s[len(s)-1:], ".?!") {
+		return ""
+	}
+	return "."
+}
+
+// pageRange joins the pages of a range with an en dash.
+func pageRange(s string) string {
+	s = strings.ReplaceAll(s, "--", "–")
+	return strings.ReplaceAll(s, "-", "–")
+}
+
+// formatNames lists names as "Family, G., Other, A., & Last, B.".
+func formatNames(names []Name) string {
+	formatted := make([]string, len(names))
+	for i, n := range names {
+		formatted[i] = formatName(n)
+	}
+	switch len(formatted) {
+	case 1:
+		return formatted[0]
+	case 2:
+		return formatted[0] + ", & " + formatted[1]
+	}
+	return strings.Join(formatted[:len(formatted)-1], ", ") + ", & " + formatted[len(formatted)-1]
+}
+
+// formatName formats a name as "Family, G. G., Jr.", or as its literal.
+func formatName(n Name) string {
+	if n.Literal != "" || n.Family == "" {
+		return n.Literal
+	}
+	var initials []string
+	for _, given := range strings.Fields(n.Given) {
+		var parts []string
+		// hyphenated names keep the hyphen: "Jean-Paul" is "J.-P."
+		for _, part := range strings.Split(given, "-") {
+			if r := []rune(part); len(r) > 0 {
+				parts = append(parts, string(r[0])+".")
+			}
+		}
+		initials = append(initials, strings.Join(parts, "-"))
+	}
+	name := n.Family
+	if len(initials) > 0 {
+		name += ", " + strings.Join(initials, " ")
+	}
+	if n.Suffix != "" {
+		name += ", " + n.Suffix
+	}
+	return name
+}
@@ -0,0 +1,171 @@
+package bibliography
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testBib = `
+@comment{generated by hand}
+@string{acm = "ACM"}
+
+@article{pike2009,
+  author  = {Rob Pike and Griesemer, Robert and {The Go Team}},
+  title   = {The {Go} Programming Language --- an Overview},
+  journal = acm # " Queue",
+  year    = 2009,
+  volume  = {7},
+  number  = {4},
+  pages   = {10--20},
+  doi     = {10.1145/1234},
+}
+
+@book{knuth,
+  author    = "Donald E. Knuth",
+  title     = "The {\TeX}book",
+  publisher = {Addison-Wesley},
+  year      = {1984},
+}
+
+@misc(gödel, author = {Kurt G{\"o}del and Ludwig van Beethoven and others}, title = {Caf\'{e} \& Co}, date = {1931-05-01})
+`
+
+func TestParseBibTeX(t *testing.T) {
+	entries, err := ParseBibTeX([]byte(testBib))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Entry{
+		{
+			ID:             "pike2009",
+			Type:           "article-journal",
+			Title:          "The Go Programming Language — an Overview",
+			Author:         []Name{{Family: "Pike", Given: "Rob"}, {Family: "Griesemer", Given: "Robert"}, {Literal: "The Go Team"}},
+			Issued:         Date{Year: 2009},
+			ContainerTitle: "ACM Queue",
+			Volume:         "7",
+			Issue:          "4",
+			Page:           "10–20",
+			DOI:            "10.1145/1234",
+		},
+		{
+			ID:        "knuth",
+			Type:      "book",
+			Title:     "The TeXbook",
+			Author:    []Name{{Family: "Knuth", Given: "Donald E."}},
+			Issued:    Date{Year: 1984},
+			Publisher: "Addison-Wesley",
+		},
+		{
+			ID:     "gödel",
+			Type:   "document",
+			Title:  "Café & Co",
+			Author: []Name{{Family: "Gödel", Given: "Kurt"}, {Family: "van Beethoven", Given: "Ludwig"}},
+			Issued: Date{Year: 1931},
+		},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		for i := range entries {
+			t.Logf("entry %d: %+v", i, entries[i])
+		}
+		t.Errorf("entries differ from %+v", want)
+	}
+}
+
+func TestParseBibTeXErrors(t *testing.T) {
+	for _, src := range []string{
+		"@article{key, title = {unterminated}",
+		"@article{key, title {missing equals}}",
+		"@article{, title = {no key}}",
+	} {
+		if _, err := ParseBibTeX([]byte(src)); err == nil {
+			t.Errorf("ParseBibTeX(%q) succeeded", src)
+		}
+	}
+}
+
+func TestParseCSLJSON(t *testing.T) {
+	entries, err := ParseCSLJSON([]byte(`[{
+		"id": "doe",
+		"type": "article-journal",
+		"title": "A Study",
+		"author": [{"family": "Doe", "given": "Jane"}],
+		"issued": {"date-parts": [["2020", 5]]},
+		"container-title": "Journal",
+		"volume": 3,
+		"page": "1-9"
+	}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*Entry{{
+		ID:             "doe",
+		Type:           "article-journal",
+		Title:          "A Study",
+		Author:         []Name{{Family: "Doe", Given: "Jane"}},
+		Issued:         Date{Year: 2020},
+		ContainerTitle: "Journal",
+		Volume:         "3",
+		Page:           "1-9",
+	}}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries = %+v, want %+v", entries[0], want[0])
+	}
+
+	if _, err := ParseCSLJSON([]byte(`[{"title": "no id"}]`)); err == nil {
+		t.Error("entry without id accepted")
+	}
+}
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		entry *Entry
+		want  string
+	}{
+		{
+			entry: &Entry{
+				Type:           "article-journal",
+				Title:          "A Study",
+				Author:         []Name{{Family: "Doe", Given: "Jane-Marie Ann"}, {Family: "Roe", Given: "Richard", Suffix: "Jr."}, {Literal: "R&D Lab"}},
+				Issued:         Date{Year: 2020},
+				ContainerTitle: "Journal",
+				Volume:         "3",
+				Issue:          "2",
+				Page:           "1-9",
+				DOI:            "10.1/x",
+			},
+			want: `Doe, J.-M. A., Roe, R., Jr., &amp; R&amp;D Lab (2020). A Study. <i>Journal</i>, <i>3</i>(2), 1–9. <a href="https://doi.org/10.1/x">https://doi.org/10.1/x</a>`,
+		},
+		{
+			entry: &Entry{
+				Type:      "book",
+				Title:     "Is It a Book?",
+				Editor:    []Name{{Family: "Roe", Given: "Ann"}},
+				Publisher: "Press",
+			},
+			want: `Roe, A. (Ed.) (n.d.). <i>Is It a Book?</i> Press.`,
+		},
+		{
+			entry: &Entry{
+				Type:   "thesis",
+				Title:  "Go Generics",
+				Author: []Name{{Family: "Kim", Given: "Minji"}},
+				Issued: Date{Year: 2023},
+			},
+			want: `Kim, M. (2023). <i>Go Generics</i>.`,
+		},
+		{
+			entry: &Entry{
+				Type:  "webpage",
+				Title: "Go",
+				URL:   "https://go.dev/?a=1&b=2",
+			},
+			want: `Go. (n.d.). <a href="https://go.dev/?a=1&amp;b=2">https://go.dev/?a=1&amp;b=2</a>`,
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.entry.HTML(); got != tt.want {
+			t.Errorf("HTML() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package bibliography
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// bibTypes maps BibTeX entry types to CSL types. Other types are documents.
+var bibTypes = map[string]string{
+	"article":       "article-journal",
+	"book":          "book",
+	"booklet":       "book",
+	"inbook":        "chapter",
+	"incollection":  "chapter",
+	"inproceedings": "paper-conference",
+	"conference":    "paper-conference",
+	"phdthesis":     "thesis",
+	"mastersthesis": "thesis",
+	"thesis":        "thesis",
+	"techreport":    "report",
+	"report":        "report",
+	"online":        "webpage",
+	"electronic":    "webpage",
+	"www":           "webpage",
+}
+
+// months are the predefined month macros of BibTeX.
+var months = map[string]string{
+	"jan": "January", "feb": "February", "mar": "March", "apr": "April",
+	"may": "May", "jun": "June", "jul": "July", "aug": "August",
+	"sep": "September", "oct": "October", "nov": "November", "dec": "December",
+}
+
+// ParseBibTeX parses the entries of a BibTeX file, with @string macros.
+// @comment and @preamble entries are skipped.
+func ParseBibTeX(data []byte) ([]*Entry, error) {
+	p := &bibParser{src: string(data), macros: make(map[string]string)}
+	for k, v := range months {
+		p.macros[k] = v
+	}
+
+	var entries []*Entry
+	for {
+		at := strings.IndexByte(p.src[p.pos:], '@')
+		if at < 0 {
+			return entries, nil
+		}
+		p.pos += at + 1
+		kind := strings.ToLower(p.ident())
+		p.space()
+		if p.pos >= len(p.src) || p.src[p.pos] != '{' && p.src[p.pos] != '(' {
+			return nil, p.errorf("expected { after @%s", kind)
+		}
+		closing := byte('}')
+		if p.src[p.pos] == '(' {
+			closing = ')'
+		}
+		p.pos++
+
+		switch kind {
+		case "comment", "preamble":
+			_, err := p.group(closing)
+			if err != nil {
+				return nil, err
+			}
+		case "string":
+			fields, err := p.fields(closing)
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range fields {
+				p.macros[k] = v
+			}
+		default:
+			p.space()
+			start := p.pos
+			for p.pos < len(p.src) && p.src[p.pos] != ',' && p.src[p.pos] != closing {
+				p.pos++
+			}
+			key := strings.TrimSpace(p.src[start:p.pos])
+			if key == "" {
+				return nil, p.errorf("@%s without a key", kind)
+			}
+			if p.pos < len(p.src) && p.src[p.pos] == ',' {
+				p.pos++
+			}
+			fields, err := p.fields(closing)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			entries = append(entries, bibEntry(kind, key, fields))
+		}
+	}
+}
+
+// bibEntry converts the fields of a BibTeX entry, their values still in
+// LaTeX, to an entry.
+func bibEntry(kind, key string, fields map[string]string) *Entry {
+	e := &Entry{
+		ID:     key,
+		Type:   bibTypes[kind],
+		Author: bibNames(fields["author"]),
+		Editor: bibNames(fields["editor"]),
+		DOI:    strings.TrimSpace(fields["doi"]),
+		URL:    strings.TrimSpace(fields["url"]),
+	}
+	if e.Type == "" {
+		e.Type = "document"
+	}
+	text := func(names ...string) string {
+		for _, name := range names {
+			if v, ok := fields[name]; ok {
+				return decodeLaTeX(v)
+			}
+		}
+		return ""
+	}
+	e.Title = text("title")
+	e.ContainerTitle = text("journal", "journaltitle", "booktitle")
+	e.Publisher = text("publisher", "institution", "school", "organization")
+	e.Volume = Text(text("volume"))
+	e.Issue = Text(text("number", "issue"))
+	e.Page = Text(text("pages"))
+	e.Issued.Year = leadingYear(text("year", "date"))
+	return e
+}
+
+// bibNames parses a BibTeX name list: names separated by "and", each written
+// "First von Last", "von Last, First" or "von Last, Jr, First". Names in
+// braces are organizations, and "others" is left out.
+func bibNames(s string) []Name {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var names []Name
+	for _, raw := range splitTop(strings.Join(strings.Fields(s), " "), " and ") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" || raw == "others" {
+			continue
+		}
+		if strings.HasPrefix(raw, "{") && strings.HasSuffix(raw, "}") && len(splitTop(raw, " ")) == 1 {
+			names = append(names, Name{Literal: decodeLaTeX(raw)})
+			continue
+		}
+
+		var n Name
+		parts := splitTop(raw, ",")
+		switch len(parts) {
+		case 1:
+			words := splitTop(raw, " ")
+			// the family name starts at the first lowercase word, the von part
+			last := len(words) - 1
+			for i, w := range words[:last] {
+				if r := []rune(decodeLaTeX(w)); len(r) > 0 && unicode.IsLower(r[0]) {
+					last = i
+					break
+				}
+			}
+			n.Given = decodeLaTeX(strings.Join(words[:last], " "))
+			n.Family = decodeLaTeX(strings.Join(words[last:], " "))
+		case 2:
+			n.Family = decodeLaTeX(parts[0])
+			n.Given = decodeLaTeX(parts[1])
+		default:
+			n.Family = decodeLaTeX(parts[0])
+			n.Suffix = decodeLaTeX(parts[1])
+			n.Given = decodeLaTeX(strings.Join(parts[2:], ","))
+		}
+		names = append(names, n)
+	}
+	return names
+}
+
+// splitTop splits s at the occurrences of sep outside of braces, ignoring
+// the case of sep.
+func splitTop(s, sep string) []string {
+	var parts []string
+	lower := strings.ToLower(s)
+	var depth, start int
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '{':
+			depth++
+		case s[i] == '}':
+			depth--
+		case depth == 0 && strings.HasPrefix(lower[i:], sep):
+			parts = append(parts, s[start:i])
+			start = i + len(sep)
+			i = start - 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// bibParser reads the entries of a BibTeX file.
+type bibParser struct {
+	src    string
+	pos    int
+	macros map[string]string
+}
+
+func (p *bibParser) errorf(format string, args ...any) error {
+	line := strings.Count(p.src[:min(p.pos, len(p.src))], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *bibParser) space() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// ident reads an entry type, field name or macro name.
+func (p *bibParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n{}()=,#\"", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// group reads the content up to the unbalanced closing byte, which it skips.
+func (p *bibParser) group(closing byte) (string, error) {
+	start := p.pos
+	var depth int
+	for ; p.pos < len(p.src); p.pos++ {
+		switch c := p.src[p.pos]; {
+		case c == closing && depth == 0:
+			p.pos++
+			return p.src[start : p.pos-1], nil
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated group")
+}
+
+// fields reads the "name = value" fields of an entry up to its closing byte.
+// The names are lowercased.
+func (p *bibParser) fields(closing byte) (map[string]string, error) {
+	fields := make(map[string]string)
+	for {
+		p.space()
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated entry")
+		}
+		if p.src[p.pos] == closing {
+			p.pos++
+			return fields, nil
+		}
+		name := strings.ToLower(p.ident())
+		if name == "" {
+			return nil, p.errorf("expected a field name")
+		}
+		p.space()
+		if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+			return nil, p.errorf("expected = after %s", name)
+		}
+		p.pos++
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = value
+		p.space()
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+		}
+	}
+}
+
+// value reads a field value: braced or quoted strings, numbers and macros,
+// concatenated with #. The braces of quoted and braced strings are kept, as
+// they matter to names.
+func (p *bibParser) value() (string, error) {
+	var b strings.Builder
+	for {
+		p.space()
+		if p.pos >= len(p.src) {
+			return "", p.errorf("unterminated value")
+		}
+		switch p.src[p.pos] {
+		case '{':
+			p.pos++
+			s, err := p.group('}')
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		case '"':
+			start := p.pos + 1
+			var depth int
+			for p.pos++; p.pos < len(p.src) && (p.src[p.pos] != '"' || depth > 0); p.pos++ {
+				switch p.src[p.pos] {
+				case '{':
+					depth++
+				case '}':
+					depth--
+				}
+			}
+			if p.pos >= len(p.src) {
+				p.pos = start
+				return "", p.errorf("unterminated string")
+			}
+			b.WriteString(p.src[start:p.pos])
+			p.pos++
+		default:
+			name := p.ident()
+			if name == "" {
+				return "", p.errorf("expected a value")
+			}
+			if v, ok := p.macros[strings.ToLower(name)]; ok {
+				b.WriteString(v)
+			} else {
+				// numbers, and undefined macros as their names
+				b.WriteString(name)
+			}
+		}
+		p.space()
+		if p.pos >= len(p.src) || p.src[p.pos] != '#' {
+			return b.String(), nil
+		}
+		p.pos++
+	}
+}
+
+// accents maps the LaTeX accent commands to combining characters.
+var accents = map[string]rune{
+	"`": '̀', "'": '́', "^": '̂', "~": '̃', "=": '̄',
+	"u": '̆', ".": '̇', "\"": '̈', "r": '̊', "H": '̋',
+	"v": '̌', "c": '̧', "k": '̨',
+}
+
+// symbols maps LaTeX commands to the characters they stand for.
+var symbols = map[string]string{
+	"&": "&", "%": "%", "$": "$", "#": "#", "_": "_", "{": "{", "}": "}",
+	"ss": "ß", "o": "ø", "O": "Ø", "aa": "å", "AA": "Å", "ae": "æ", "AE": "Æ",
+	"oe": "œ", "OE": "Œ", "l": "ł", "L": "Ł", "i": "i", "j": "j",
+	"textendash": "–", "textemdash": "—", "LaTeX": "LaTeX", "TeX": "TeX",
+}
+
+// decodeLaTeX converts the LaTeX markup of a BibTeX value to text: braces are
+// removed, accents and special characters decoded, dashes and ties replaced,
+// and the arguments of other commands such as \emph kept.
+func decodeLaTeX(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '{', '}':
+		case '~':
+			b.WriteRune(' ')
+		case '-':
+			switch {
+			case strings.HasPrefix(s[i:], "---"):
+				b.WriteString("—")
+				i += 2
+			case strings.HasPrefix(s[i:], "--"):
+				b.WriteString("–")
+				i++
+			default:
+				b.WriteByte(c)
+			}
+		case '\\':
+			i++
+			if i >= len(s) {
+				break
+			}
+			start := i
+			if isLetter(s[i]) {
+				for i < len(s) && isLetter(s[i]) {
+					i++
+				}
+			} else {
+				i++
+			}
+			cmd := s[start:i]
+			if mark, ok := accents[cmd]; ok {
+				// the accented letter, maybe in braces or a command such as \i
+				for i < len(s) && (s[i] == '{' || s[i] == ' ') {
+					i++
+				}
+				letter := decodeLaTeX(s[i:min(i+1, len(s))])
+				if i < len(s) && s[i] == '\\' {
+					j := i + 1
+					for j < len(s) && isLetter(s[j]) {
+						j++
+					}
+					letter = symbols[s[i+1:j]]
+					i = j - 1
+				}
+				b.WriteString(letter)
+				b.WriteRune(mark)
+			} else {
+				b.WriteString(symbols[cmd])
+				if isLetter(cmd[0]) {
+					// the space ending a command name
+					for i < len(s) && s[i] == ' ' {
+						i++
+					}
+				}
+				i--
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return strings.Join(strings.Fields(norm.NFC.String(b.String())), " ")
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
@@ -0,0 +1,267 @@
+// Package csspurge removes the style rules of a stylesheet whose class selectors
+// match no class used by a site, as done for utility-first CSS frameworks.
+//
+// A selector is kept unless it requires a class that is not used. Classes inside
+// functional pseudo-classes such as :not() and :where() are not considered, and
+// at-rules other than @media, @supports, @container and @layer are kept as they are.
+package csspurge
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Purge returns css without the selectors that need a class for which used
+// returns false. Rules left without selectors are removed, as are the
+// grouping at-rules left empty.
+func Purge(css string, used func(class string) bool) string {
+	var b strings.Builder
+	b.Grow(len(css))
+	purgeBlock(&b, css, used)
+	return b.String()
+}
+
+// grouping at-rules contain rules that are purged recursively.
+var grouping = map[string]bool{"media": true, "supports": true, "container": true, "layer": true}
+
+func purgeBlock(b *strings.Builder, css string, used func(string) bool) {
+	for len(css) > 0 {
+		i := skipSpace(css)
+		b.WriteString(css[:i])
+		css = css[i:]
+		if css == "" {
+			return
+		}
+
+		if strings.HasPrefix(css, "/*") {
+			end := strings.Index(css[2:], "*/")
+			if end < 0 {
+				return
+			}
+			css = css[end+4:]
+			continue
+		}
+
+		// prelude up to the block or the end of a statement
+		end := scan(css, func(c byte) bool { return c == '{' || c == ';' || c == '}' })
+		prelude := css[:end]
+		if end == len(css) || css[end] != '{' {
+			// statement at-rule, or stray text
+			if end < len(css) {
+				end++
+			}
+			b.WriteString(css[:end])
+			css = css[end:]
+			continue
+		}
+
+		close := matchBrace(css, end)
+		body := css[end+1 : close]
+		next := close + 1
+		if close == len(css) {
+			next = close
+		}
+		css = css[next:]
+
+		if strings.HasPrefix(prelude, "@") {
+			name := prelude[1:]
+			if n := strings.IndexAny(name, " \t\n\r({"); n >= 0 {
+				name = name[:n]
+			}
+			if !grouping[strings.ToLower(name)] {
+				b.WriteString(prelude + "{" + body + "}")
+				continue
+			}
+			var inner strings.Builder
+			purgeBlock(&inner, body, used)
+			if strings.TrimSpace(inner.String()) != "" {
+				b.WriteString(prelude + "{" + inner.String() + "}")
+			}
+			continue
+		}
+
+		var kept []string
+		for _, sel := range splitSelectors(prelude) {
+			if selectorUsed(sel, used) {
+				kept = append(kept, sel)
+			}
+		}
+		if len(kept) > 0 {
+			b.WriteString(strings.Join(kept, ",") + "{" + body + "}")
+		}
+	}
+}
+
+func skipSpace(s string) int {
+	i := 0
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r' || s[i] == '\f') {
+		i++
+	}
+	return i
+}
+
+// scan returns the index of the first byte outside of strings, comments and
+// escapes for which stop is true, or len(s).
+func scan(s string, stop func(c byte) bool) int {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case c == '"' || c == '\'':
+			for i++; i < len(s) && s[i] != c; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return len(s)
+			}
+			i += end + 3
+		case stop(c):
+			return i
+		}
+	}
+	return len(s)
+}
+
+// matchBrace returns the index of the brace closing the one at open, or len(s).
+func matchBrace(s string, open int) int {
+	depth := 0
+	i := open
+	for i < len(s) {
+		n := scan(s[i:], func(c byte) bool { return c == '{' || c == '}' })
+		i += n
+		if i >= len(s) {
+			break
+		}
+		if s[i] == '{' {
+			depth++
+		} else {
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+		i++
+	}
+	return len(s)
+}
+
+// splitSelectors splits a selector list at the commas outside of parentheses.
+func splitSelectors(list string) []string {
+	var sels []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(list); i++ {
+		i += scan(list[i:], func(c byte) bool { return c == '(' || c == ')' || c == '[' || c == ']' || c == ',' })
+		if i >= len(list) {
+			break
+		}
+		switch list[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				sels = append(sels, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(sels, strings.TrimSpace(list[start:]))
+}
+
+// selectorUsed reports whether every class of sel outside of parentheses and
+// attribute selectors is used.
+func selectorUsed(sel string, used func(string) bool) bool {
+	depth := 0
+	for i := 0; i < len(sel); i++ {
+		i += scan(sel[i:], func(c byte) bool { return c == '(' || c == ')' || c == '[' || c == ']' || c == '.' })
+		if i >= len(sel) {
+			break
+		}
+		switch sel[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case '.':
+			class, n := ident(sel[i+1:])
+			if depth == 0 && class != "" && !used(class) {
+				return false
+			}
+			i += n
+		}
+	}
+	return true
+}
+
+// ident reads an identifier at the start of s, resolving escapes, and returns it
+// with the number of bytes read.
+func ident(s string) (string, int) {
+	var b strings.Builder
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			// hex escape, terminated by an optional space
+			j := i + 1
+			for j < len(s) && j < i+7 && isHex(s[j]) {
+				j++
+			}
+			if j > i+1 {
+				var r rune
+				for _, h := range s[i+1 : j] {
+					r = r*16 + rune(hexValue(byte(h)))
+				}
+				b.WriteRune(r)
+				if j < len(s) && s[j] == ' ' {
+					j++
+				}
+				i = j
+				continue
+			}
+			r, size := utf8.DecodeRuneInString(s[i+1:])
+			b.WriteRune(r)
+			i += 1 + size
+		case c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80:
+			b.WriteByte(c)
+			i++
+		default:
+			return b.String(), i
+		}
+	}
+	return b.String(), i
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func hexValue(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10
+	default:
+		return int(c-'A') + 10
+	}
+}
+
+// Candidates returns the strings of src that could be class names, split at
+// whitespace, quotes and markup characters, in the way utility-first frameworks
+// scan templates and scripts for the classes they use.
+func Candidates(src string) []string {
+	return strings.FieldsFunc(src, func(r rune) bool {
+		switch r {
+		case ' ', '\t', '\n', '\r', '"', '\'', '`', '<', '>', '=', '{', '}', ',', ';', '(', ')':
+			return true
+		}
+		return false
+	})
+}
@@ -0,0 +1,80 @@
+package csspurge
+
+import (
+	"reflect"
+	"testing"
+)
+
+func usedSet(classes ...string) func(string) bool {
+	m := make(map[string]bool)
+	for _, c := range classes {
+		m[c] = true
+	}
+	return func(c string) bool { return m[c] }
+}
+
+func TestPurge(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		used []string
+		want string
+	}{
+		{
+			name: "unused class",
+			css:  ".a{color:red}.b{color:blue}p{margin:0}",
+			used: []string{"a"},
+			want: ".a{color:red}p{margin:0}",
+		},
+		{
+			name: "selector list",
+			css:  ".a, .b > p, h1 {font-weight:bold}",
+			used: []string{"b"},
+			want: ".b > p,h1{font-weight:bold}",
+		},
+		{
+			name: "escaped classes",
+			css:  `.md\:flex{display:flex}.w-1\/2{width:50%}.hover\:underline:hover{text-decoration:underline}`,
+			used: []string{"md:flex", "hover:underline"},
+			want: `.md\:flex{display:flex}.hover\:underline:hover{text-decoration:underline}`,
+		},
+		{
+			name: "media",
+			css:  "@media (min-width:768px){.a{color:red}.b{color:blue}}@media print{.b{display:none}}",
+			used: []string{"a"},
+			want: "@media (min-width:768px){.a{color:red}}",
+		},
+		{
+			name: "other at-rules kept",
+			css:  "@charset \"utf-8\";@font-face{font-family:x;src:url(/a.woff2)}@keyframes spin{to{transform:rotate(1turn)}}.b{color:red}",
+			want: "@charset \"utf-8\";@font-face{font-family:x;src:url(/a.woff2)}@keyframes spin{to{transform:rotate(1turn)}}",
+		},
+		{
+			name: "pseudo-class arguments ignored",
+			css:  `.prose :where(p):not(:where([class~="not-prose"] *)){margin:0}.x:not(.y){color:red}`,
+			used: []string{"prose", "x"},
+			want: `.prose :where(p):not(:where([class~="not-prose"] *)){margin:0}.x:not(.y){color:red}`,
+		},
+		{
+			name: "strings and comments",
+			css:  `/* .b{} */.a::after{content:".b{"}[data-x=".b"]{color:red}`,
+			used: []string{"a"},
+			want: `.a::after{content:".b{"}[data-x=".b"]{color:red}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Purge(tt.css, usedSet(tt.used...)); got != tt.want {
+				t.Errorf("Purge() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	got := Candidates(`<div class={ "md:flex", templ.KV("hidden", x) }>el.classList.add('is-open')</div>`)
+	want := []string{"div", "class", "md:flex", "templ.KV", "hidden", "x", "el.classList.add", "is-open", "/div"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Candidates() = %q, want %q", got, want)
+	}
+}
@@ -26,25 +26,31 @@ To complete this task:
 3. Consider the document's structure, headings, and any emphasized text to determine the most important information.
 
 When writing the Open Graph Description:
-- Create a single, concise sentence that summarizes the core content of the document.
-- Keep the description within 150 characters.
+- Write one or two concise sentences that summarize the core content of the document.
+- Keep the description within 200 characters.
 - Write in a clear and engaging style that encourages users to click and read more.
 - Optimize for SEO by including relevant keywords naturally, but avoid keyword stuffing.
 - Ensure the description accurately represents the document's content.
-- Write the description in the same language as the input document.
+- Write the description in <LANGUAGE>.
 
 Present your Open Graph Description in the following format:
 [START_TOKEN]
 [Your description here]
 [END_TOKEN]
 
-Remember to check that your description is within the 150-character limit before submitting your answer.`
+Remember to check that your description is within the 200-character limit before submitting your answer.`
 
 var (
 	ErrFailedToGenerateDescription = errors.New("failed to generate description")
 )
 
-func GenerateDescription(ctx context.Context, l llm.Model, input string) (string, error) {
+// GenerateDescription summarizes the markdown document input in language, a
+// full language name. An empty language uses the language of the document.
+func GenerateDescription(ctx context.Context, l llm.Model, input, language string) (string, error) {
+	if language == "" {
+		language = "the same language as the input document"
+	}
+
 	var b [8]byte
 	rand.Read(b[:])
 	startToken := "[" + hex.EncodeToString(b[:]) + "]"
@@ -53,6 +59,7 @@ func GenerateDescription(ctx context.Context, l llm.Model, input string) (string
 
 	prompt := strings.Replace(prompt, "[START_TOKEN]", startToken, 1)
 	prompt = strings.Replace(prompt, "[END_TOKEN]", endToken, 1)
+	prompt = strings.Replace(prompt, "<LANGUAGE>", language, 1)
 	prompt = strings.Replace(prompt, "<INPUT_DOCUMENT>", input, 1)
 
 	resp := l.GenerateStream(ctx, &llm.ChatContext{}, llm.TextContent(llm.RoleUser, prompt))
@@ -0,0 +1,453 @@
+// Package fonts subsets TrueType fonts to a set of characters and encodes them
+// as WOFF2, for webfonts that carry only the glyphs a site uses.
+//
+// Subsetting keeps the glyph IDs of the font: the outlines and metrics of the
+// glyphs not needed are emptied, so that the layout tables referring to glyph
+// IDs stay valid without being rewritten. The character map is rebuilt for the
+// kept characters only.
+package fonts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrUnsupported is returned for fonts other than TrueType outline fonts, such
+// as CFF based OpenType fonts and font collections.
+var ErrUnsupported = errors.New("fonts: only TrueType outline fonts are supported")
+
+// errMalformed is raised by the readers below on out of range offsets.
+var errMalformed = errors.New("fonts: malformed font")
+
+// data reads big-endian values, panicking with errMalformed out of range.
+type data []byte
+
+func (d data) u16(off int) int {
+	if off < 0 || off+2 > len(d) {
+		panic(errMalformed)
+	}
+	return int(binary.BigEndian.Uint16(d[off:]))
+}
+
+func (d data) u32(off int) int {
+	if off < 0 || off+4 > len(d) {
+		panic(errMalformed)
+	}
+	return int(binary.BigEndian.Uint32(d[off:]))
+}
+
+func (d data) slice(off, n int) data {
+	if off < 0 || n < 0 || off+n > len(d) {
+		panic(errMalformed)
+	}
+	return d[off : off+n]
+}
+
+// table is a table of an sfnt font.
+type table struct {
+	tag  string
+	data []byte
+}
+
+// parse returns the sfnt version and the tables of font.
+func parse(font data) (uint32, map[string][]byte, error) {
+	if len(font) < 12 {
+		return 0, nil, errMalformed
+	}
+	version := binary.BigEndian.Uint32(font)
+	if version != 0x00010000 && version != 0x74727565 { // 'true'
+		return 0, nil, ErrUnsupported
+	}
+	n := font.u16(4)
+	tables := make(map[string][]byte, n)
+	for i := 0; i < n; i++ {
+		rec := font.slice(12+16*i, 16)
+		tables[string(rec[:4])] = font.slice(rec.u32(8), rec.u32(12))
+	}
+	return version, tables, nil
+}
+
+// Subset returns font with only the glyphs of the characters for which keep
+// returns true, the .notdef glyph, and the glyphs these are composed of or
+// substituted with.
+func Subset(font []byte, keep func(r rune) bool) (out []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r != errMalformed {
+				panic(r)
+			}
+			out, err = nil, errMalformed
+		}
+	}()
+
+	version, tables, err := parse(font)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range []string{"head", "maxp", "loca", "glyf", "cmap", "hhea", "hmtx"} {
+		if tables[tag] == nil {
+			if tag == "glyf" && tables["CFF "] != nil {
+				return nil, ErrUnsupported
+			}
+			return nil, fmt.Errorf("fonts: missing %s table", tag)
+		}
+	}
+
+	head := data(tables["head"])
+	numGlyphs := data(tables["maxp"]).u16(4)
+	longLoca := head.u16(50) == 1
+	loca := make([]int, numGlyphs+1)
+	for i := range loca {
+		if longLoca {
+			loca[i] = data(tables["loca"]).u32(4 * i)
+		} else {
+			loca[i] = 2 * data(tables["loca"]).u16(2*i)
+		}
+	}
+	glyf := data(tables["glyf"])
+	glyph := func(gid int) data {
+		if loca[gid+1] <= loca[gid] {
+			return nil
+		}
+		return glyf.slice(loca[gid], loca[gid+1]-loca[gid])
+	}
+
+	// characters and glyphs kept
+	cmap := make(map[rune]int)
+	for r, gid := range readCmap(tables["cmap"]) {
+		if gid < numGlyphs && keep(r) {
+			cmap[r] = gid
+		}
+	}
+	kept := make([]bool, numGlyphs)
+	kept[0] = true
+	for _, gid := range cmap {
+		kept[gid] = true
+	}
+	if gsub := tables["GSUB"]; gsub != nil {
+		closeGSUB(gsub, kept)
+	}
+	for gid := range kept {
+		if kept[gid] {
+			closeComposite(glyph, gid, kept)
+		}
+	}
+
+	// glyph outlines and locations
+	var newGlyf []byte
+	newLoca := make([]int, numGlyphs+1)
+	for gid := 0; gid < numGlyphs; gid++ {
+		newLoca[gid] = len(newGlyf)
+		if kept[gid] {
+			newGlyf = append(newGlyf, glyph(gid)...)
+			for len(newGlyf)%4 != 0 {
+				newGlyf = append(newGlyf, 0)
+			}
+		}
+	}
+	newLoca[numGlyphs] = len(newGlyf)
+	if !longLoca && len(newGlyf) > 2*0xFFFF {
+		longLoca = true
+	}
+	var locaData []byte
+	for _, off := range newLoca {
+		if longLoca {
+			locaData = binary.BigEndian.AppendUint32(locaData, uint32(off))
+		} else {
+			locaData = binary.BigEndian.AppendUint16(locaData, uint16(off/2))
+		}
+	}
+
+	// metrics of the removed glyphs are zeroed
+	hmtx := append([]byte{}, tables["hmtx"]...)
+	numMetrics := data(tables["hhea"]).u16(34)
+	for gid := 0; gid < numGlyphs; gid++ {
+		if kept[gid] {
+			continue
+		}
+		off, n := 4*gid, 4
+		if gid >= numMetrics {
+			off, n = 4*numMetrics+2*(gid-numMetrics), 2
+		}
+		if off+n <= len(hmtx) {
+			clear(hmtx[off : off+n])
+		}
+	}
+
+	newHead := append([]byte{}, head...)
+	binary.BigEndian.PutUint32(newHead[8:], 0) // checkSumAdjustment
+	if longLoca {
+		binary.BigEndian.PutUint16(newHead[50:], 1)
+	}
+
+	var list []table
+	for tag, t := range tables {
+		switch tag {
+		case "DSIG":
+			// the signature no longer matches
+			continue
+		case "head":
+			t = newHead
+		case "loca":
+			t = locaData
+		case "glyf":
+			t = newGlyf
+		case "hmtx":
+			t = hmtx
+		case "cmap":
+			t = writeCmap(cmap)
+		case "OS/2":
+			t = updateOS2(t, cmap)
+		}
+		list = append(list, table{tag, t})
+	}
+	out = writeSFNT(version, list)
+
+	// checkSumAdjustment makes the checksum of the whole font 0xB1B0AFBA
+	head = data(out).slice(tableOffset(out, "head"), len(newHead))
+	binary.BigEndian.PutUint32(head[8:], 0xB1B0AFBA-checksum(out))
+	return out, nil
+}
+
+// closeComposite marks the components of composite glyph gid, recursively.
+func closeComposite(glyph func(int) data, gid int, kept []bool) {
+	g := glyph(gid)
+	if len(g) < 10 || int16(g.u16(0)) >= 0 {
+		return
+	}
+	const (
+		argsAreWords  = 0x0001
+		haveScale     = 0x0008
+		moreComponent = 0x0020
+		haveXYScale   = 0x0040
+		haveTwoByTwo  = 0x0080
+	)
+	off := 10
+	for {
+		flags := g.u16(off)
+		component := g.u16(off + 2)
+		if component < len(kept) && !kept[component] {
+			kept[component] = true
+			closeComposite(glyph, component, kept)
+		}
+		off += 4
+		if flags&argsAreWords != 0 {
+			off += 4
+		} else {
+			off += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			off += 2
+		case flags&haveXYScale != 0:
+			off += 4
+		case flags&haveTwoByTwo != 0:
+			off += 8
+		}
+		if flags&moreComponent == 0 {
+			return
+		}
+	}
+}
+
+// readCmap returns the character map of the preferred Unicode subtable of cmap.
+func readCmap(cmap data) map[rune]int {
+	var best data
+	bestRank := 0
+	for i := 0; i < cmap.u16(2); i++ {
+		platform, encoding, off := cmap.u16(4+8*i), cmap.u16(6+8*i), cmap.u32(8+8*i)
+		format := cmap.u16(off)
+		rank := 0
+		switch {
+		case format == 12 && (platform == 3 && encoding == 10 || platform == 0):
+			rank = 2
+		case format == 4 && (platform == 3 && encoding == 1 || platform == 0):
+			rank = 1
+		}
+		if rank > bestRank {
+			best, bestRank = cmap.slice(off, len(cmap)-off), rank
+		}
+	}
+
+	m := make(map[rune]int)
+	switch bestRank {
+	case 2:
+		for i := 0; i < best.u32(12); i++ {
+			start, end, gid := best.u32(16+12*i), best.u32(20+12*i), best.u32(24+12*i)
+			for c := start; c <= end && c <= 0x10FFFF; c++ {
+				m[rune(c)] = gid + c - start
+			}
+		}
+	case 1:
+		segs := best.u16(6) / 2
+		ends, starts, deltas, ranges := 14, 16+2*segs, 16+4*segs, 16+6*segs
+		for i := 0; i < segs; i++ {
+			start, end := best.u16(starts+2*i), best.u16(ends+2*i)
+			delta, rangeOff := best.u16(deltas+2*i), best.u16(ranges+2*i)
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				gid := 0
+				if rangeOff == 0 {
+					gid = (c + delta) & 0xFFFF
+				} else if g := best.u16(ranges + 2*i + rangeOff + 2*(c-start)); g != 0 {
+					gid = (g + delta) & 0xFFFF
+				}
+				if gid != 0 {
+					m[rune(c)] = gid
+				}
+			}
+		}
+	}
+	return m
+}
+
+// writeCmap returns a cmap table with a format 4 subtable of the characters of
+// the Basic Multilingual Plane, and a format 12 subtable of all characters if
+// any is beyond it.
+func writeCmap(m map[rune]int) []byte {
+	codes := make([]rune, 0, len(m))
+	for r := range m {
+		codes = append(codes, r)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	// runs of consecutive characters with consecutive glyphs
+	type run struct{ start, end rune }
+	var runs, bmp []run
+	for _, c := range codes {
+		if n := len(runs); n > 0 && c == runs[n-1].end+1 && m[c] == m[runs[n-1].end]+1 {
+			runs[n-1].end = c
+		} else {
+			runs = append(runs, run{c, c})
+		}
+	}
+	for _, r := range runs {
+		if r.start <= 0xFFFE {
+			bmp = append(bmp, run{r.start, min(r.end, 0xFFFE)})
+		}
+	}
+	bmp = append(bmp, run{0xFFFF, 0xFFFF})
+
+	segs := len(bmp)
+	searchRange, entrySelector := 2, 0
+	for searchRange*2 <= 2*segs {
+		searchRange *= 2
+		entrySelector++
+	}
+	f4 := make([]byte, 16+8*segs)
+	be := binary.BigEndian
+	be.PutUint16(f4[0:], 4)
+	be.PutUint16(f4[2:], uint16(len(f4)))
+	be.PutUint16(f4[6:], uint16(2*segs))
+	be.PutUint16(f4[8:], uint16(searchRange))
+	be.PutUint16(f4[10:], uint16(entrySelector))
+	be.PutUint16(f4[12:], uint16(2*segs-searchRange))
+	for i, r := range bmp {
+		be.PutUint16(f4[14+2*i:], uint16(r.end))
+		be.PutUint16(f4[16+2*segs+2*i:], uint16(r.start))
+		delta := 1 // maps 0xFFFF to .notdef
+		if r.start != 0xFFFF {
+			delta = m[r.start] - int(r.start)
+		}
+		be.PutUint16(f4[16+4*segs+2*i:], uint16(delta))
+	}
+
+	var f12 []byte
+	if len(codes) > 0 && codes[len(codes)-1] > 0xFFFF {
+		f12 = make([]byte, 16+12*len(runs))
+		be.PutUint16(f12[0:], 12)
+		be.PutUint32(f12[4:], uint32(len(f12)))
+		be.PutUint32(f12[12:], uint32(len(runs)))
+		for i, r := range runs {
+			be.PutUint32(f12[16+12*i:], uint32(r.start))
+			be.PutUint32(f12[20+12*i:], uint32(r.end))
+			be.PutUint32(f12[24+12*i:], uint32(m[r.start]))
+		}
+	}
+
+	numTables := 1
+	if f12 != nil {
+		numTables = 2
+	}
+	out := make([]byte, 4+8*numTables)
+	be.PutUint16(out[2:], uint16(numTables))
+	be.PutUint16(out[4:], 3)
+	be.PutUint16(out[6:], 1)
+	be.PutUint32(out[8:], uint32(len(out)))
+	if f12 != nil {
+		be.PutUint16(out[12:], 3)
+		be.PutUint16(out[14:], 10)
+		be.PutUint32(out[16:], uint32(len(out)+len(f4)))
+	}
+	out = append(out, f4...)
+	return append(out, f12...)
+}
+
+// updateOS2 sets the first and last character indexes of the OS/2 table to
+// those of the kept characters.
+func updateOS2(os2 []byte, m map[rune]int) []byte {
+	if len(os2) < 68 || len(m) == 0 {
+		return os2
+	}
+	first, last := rune(0xFFFF), rune(0)
+	for r := range m {
+		first, last = min(first, r), max(last, r)
+	}
+	os2 = append([]byte{}, os2...)
+	binary.BigEndian.PutUint16(os2[64:], uint16(min(first, 0xFFFF)))
+	binary.BigEndian.PutUint16(os2[66:], uint16(min(last, 0xFFFF)))
+	return os2
+}
+
+// writeSFNT returns a font of the tables, in tag order and 4-byte aligned.
+func writeSFNT(version uint32, tables []table) []byte {
+	sort.Slice(tables, func(i, j int) bool { return tables[i].tag < tables[j].tag })
+	n := len(tables)
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= n {
+		searchRange *= 2
+		entrySelector++
+	}
+	be := binary.BigEndian
+	out := make([]byte, 12+16*n)
+	be.PutUint32(out[0:], version)
+	be.PutUint16(out[4:], uint16(n))
+	be.PutUint16(out[6:], uint16(16*searchRange))
+	be.PutUint16(out[8:], uint16(entrySelector))
+	be.PutUint16(out[10:], uint16(16*(n-searchRange)))
+	for i, t := range tables {
+		rec := out[12+16*i:]
+		copy(rec, t.tag)
+		be.PutUint32(rec[4:], checksum(t.data))
+		be.PutUint32(rec[8:], uint32(len(out)))
+		be.PutUint32(rec[12:], uint32(len(t.data)))
+		out = append(out, t.data...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+// tableOffset returns the offset of the table tag in font, or -1.
+func tableOffset(font []byte, tag string) int {
+	d := data(font)
+	for i := 0; i < d.u16(4); i++ {
+		rec := d.slice(12+16*i, 16)
+		if string(rec[:4]) == tag {
+			return rec.u32(8)
+		}
+	}
+	return -1
+}
+
+func checksum(b []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(b); i += 4 {
+		var word [4]byte
+		copy(word[:], b[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
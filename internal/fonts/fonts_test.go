@@ -0,0 +1,159 @@
+package fonts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+func glyphIndex(t *testing.T, f *sfnt.Font, r rune) sfnt.GlyphIndex {
+	t.Helper()
+	gid, err := f.GlyphIndex(nil, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gid
+}
+
+func TestSubset(t *testing.T) {
+	orig, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Subset(goregular.TTF, func(r rune) bool { return r == 'a' || r == 'é' })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) >= len(goregular.TTF)/4 {
+		t.Errorf("subset is %d bytes of %d", len(out), len(goregular.TTF))
+	}
+	if sum := checksum(out); sum != 0xB1B0AFBA {
+		t.Errorf("font checksum = %#x, want 0xB1B0AFBA", sum)
+	}
+
+	f, err := sfnt.Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []rune{'a', 'é'} {
+		gid := glyphIndex(t, f, r)
+		if want := glyphIndex(t, orig, r); gid != want {
+			t.Errorf("glyph of %q = %d, want %d", r, gid, want)
+		}
+		// é is a composite glyph, which needs the glyph of e
+		segs, err := f.LoadGlyph(nil, gid, fixed.I(16), nil)
+		if err != nil || len(segs) == 0 {
+			t.Errorf("glyph of %q has %d segments, %v", r, len(segs), err)
+		}
+	}
+	if gid := glyphIndex(t, f, 'b'); gid != 0 {
+		t.Errorf("glyph of 'b' = %d, want 0", gid)
+	}
+	segs, err := f.LoadGlyph(nil, glyphIndex(t, orig, 'b'), fixed.I(16), nil)
+	if err != nil || len(segs) != 0 {
+		t.Errorf("removed glyph has %d segments, %v", len(segs), err)
+	}
+}
+
+func TestSubsetUnsupported(t *testing.T) {
+	_, err := Subset([]byte("OTTO\x00\x00\x00\x00\x00\x00\x00\x00"), func(rune) bool { return true })
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Subset() error = %v, want ErrUnsupported", err)
+	}
+	_, err = Subset(goregular.TTF[:100], func(rune) bool { return true })
+	if err == nil {
+		t.Error("Subset() of a truncated font succeeded")
+	}
+}
+
+func TestCmap(t *testing.T) {
+	m := map[rune]int{'A': 10, 'B': 11, 'C': 12, 'a': 5, '가': 300, 0x1F600: 301, 0x1F601: 302}
+	if got := readCmap(writeCmap(m)); !reflect.DeepEqual(got, m) {
+		t.Errorf("readCmap(writeCmap(m)) = %v, want %v", got, m)
+	}
+}
+
+func TestWOFF2(t *testing.T) {
+	font, err := Subset(goregular.TTF, func(r rune) bool { return r < 0x80 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := WOFF2(font)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	be := binary.BigEndian
+	if string(out[:4]) != "wOF2" || int(be.Uint32(out[8:])) != len(out) || len(out)%4 != 0 {
+		t.Fatalf("bad header %q, length %d of %d", out[:4], be.Uint32(out[8:]), len(out))
+	}
+	_, tables, _ := parse(font)
+	n := int(be.Uint16(out[12:]))
+	if n != len(tables) {
+		t.Errorf("%d tables, want %d", n, len(tables))
+	}
+
+	// decode the table directory and the stream
+	r := bytes.NewReader(out[48:])
+	var order []string
+	var lengths []int
+	for i := 0; i < n; i++ {
+		flags, _ := r.ReadByte()
+		tag := ""
+		if flags&63 == 63 {
+			b := make([]byte, 4)
+			r.Read(b)
+			tag = string(b)
+		} else {
+			tag = knownTags[flags&63]
+		}
+		var length int
+		for {
+			b, _ := r.ReadByte()
+			length = length<<7 | int(b&0x7F)
+			if b&0x80 == 0 {
+				break
+			}
+		}
+		order = append(order, tag)
+		lengths = append(lengths, length)
+	}
+	compressed := make([]byte, be.Uint32(out[20:]))
+	r.Read(compressed)
+	stream, err := io.ReadAll(brotli.NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tag := range order {
+		if !bytes.Equal(stream[:lengths[i]], tables[tag]) {
+			t.Errorf("table %q differs", tag)
+		}
+		stream = stream[lengths[i]:]
+	}
+	if len(stream) != 0 {
+		t.Errorf("%d bytes left in the stream", len(stream))
+	}
+}
+
+func TestBase128(t *testing.T) {
+	tests := map[uint32][]byte{
+		0:     {0},
+		127:   {127},
+		128:   {0x81, 0},
+		16383: {0xFF, 0x7F},
+		16384: {0x81, 0x80, 0},
+	}
+	for n, want := range tests {
+		if got := base128(n); !bytes.Equal(got, want) {
+			t.Errorf("base128(%d) = %x, want %x", n, got, want)
+		}
+	}
+}
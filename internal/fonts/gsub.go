@@ -0,0 +1,124 @@
+package fonts
+
+// GSUB lookup types whose output glyphs are kept.
+const (
+	lookupSingle       = 1
+	lookupMultiple     = 2
+	lookupAlternate    = 3
+	lookupLigature     = 4
+	lookupExtension    = 7
+	lookupReverseChain = 8
+)
+
+// closeGSUB marks the glyphs the substitutions of gsub produce from kept
+// glyphs, until no more are added. Contextual lookups are not evaluated: the
+// lookups they apply are in the lookup list as well, so their substitutions
+// are kept whatever the context.
+func closeGSUB(gsub data, kept []bool) {
+	lookups := gsub.slice(gsub.u16(8), len(gsub)-gsub.u16(8))
+	for changed := true; changed; {
+		changed = false
+		mark := func(gid int) {
+			if gid < len(kept) && !kept[gid] {
+				kept[gid] = true
+				changed = true
+			}
+		}
+		for i := 0; i < lookups.u16(0); i++ {
+			lookup := lookups.slice(lookups.u16(2+2*i), len(lookups)-lookups.u16(2+2*i))
+			typ := lookup.u16(0)
+			for j := 0; j < lookup.u16(4); j++ {
+				off := lookup.u16(6 + 2*j)
+				sub := lookup.slice(off, len(lookup)-off)
+				t := typ
+				if t == lookupExtension {
+					t = sub.u16(2)
+					sub = sub.slice(sub.u32(4), len(sub)-sub.u32(4))
+				}
+				closeSubtable(t, sub, kept, mark)
+			}
+		}
+	}
+}
+
+func closeSubtable(typ int, sub data, kept []bool, mark func(int)) {
+	at := func(off int) data { return sub.slice(off, len(sub)-off) }
+	var cov []int
+	switch typ {
+	case lookupSingle, lookupMultiple, lookupAlternate, lookupLigature, lookupReverseChain:
+		cov = coverage(at(sub.u16(2)))
+	}
+	isKept := func(gid int) bool { return gid < len(kept) && kept[gid] }
+
+	switch typ {
+	case lookupSingle:
+		for i, gid := range cov {
+			if !isKept(gid) {
+				continue
+			}
+			if sub.u16(0) == 1 {
+				mark((gid + sub.u16(4)) & 0xFFFF)
+			} else {
+				mark(sub.u16(6 + 2*i))
+			}
+		}
+
+	case lookupMultiple, lookupAlternate:
+		for i, gid := range cov {
+			if !isKept(gid) {
+				continue
+			}
+			seq := at(sub.u16(6 + 2*i))
+			for k := 0; k < seq.u16(0); k++ {
+				mark(seq.u16(2 + 2*k))
+			}
+		}
+
+	case lookupLigature:
+		for i, gid := range cov {
+			if !isKept(gid) {
+				continue
+			}
+			set := at(sub.u16(6 + 2*i))
+		ligatures:
+			for k := 0; k < set.u16(0); k++ {
+				lig := set.slice(set.u16(2+2*k), len(set)-set.u16(2+2*k))
+				for c := 1; c < lig.u16(2); c++ {
+					if !isKept(lig.u16(2 + 2*c)) {
+						continue ligatures
+					}
+				}
+				mark(lig.u16(0))
+			}
+		}
+
+	case lookupReverseChain:
+		backtrack := sub.u16(4)
+		lookahead := sub.u16(6 + 2*backtrack)
+		substitutes := 10 + 2*backtrack + 2*lookahead
+		for i, gid := range cov {
+			if isKept(gid) {
+				mark(sub.u16(substitutes + 2*i))
+			}
+		}
+	}
+}
+
+// coverage returns the glyphs of a coverage table in coverage index order.
+func coverage(c data) []int {
+	var glyphs []int
+	switch c.u16(0) {
+	case 1:
+		for i := 0; i < c.u16(2); i++ {
+			glyphs = append(glyphs, c.u16(4+2*i))
+		}
+	case 2:
+		for i := 0; i < c.u16(2); i++ {
+			start, end := c.u16(4+6*i), c.u16(6+6*i)
+			for g := start; g <= end; g++ {
+				glyphs = append(glyphs, g)
+			}
+		}
+	}
+	return glyphs
+}
@@ -0,0 +1,107 @@
+package fonts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	"github.com/andybalholm/brotli"
+)
+
+// knownTags are the tags WOFF2 encodes by their index in a single byte.
+var knownTags = []string{
+	"cmap", "head", "hhea", "hmtx", "maxp", "name", "OS/2", "post", "cvt ", "fpgm", "glyf", "loca",
+	"prep", "CFF ", "VORG", "EBDT", "EBLC", "gasp", "hdmx", "kern", "LTSH", "PCLT", "VDMX", "vhea",
+	"vmtx", "BASE", "GDEF", "GPOS", "GSUB", "EBSC", "JSTF", "MATH", "CBDT", "CBLC", "COLR", "CPAL",
+	"SVG ", "sbix", "acnt", "avar", "bdat", "bloc", "bsln", "cvar", "fdsc", "feat", "fmtx", "fvar",
+	"gvar", "hsty", "just", "lcar", "mort", "morx", "opbd", "prop", "trak", "Zapf", "Silf", "Glat",
+	"Gloc", "Feat", "Sill",
+}
+
+// WOFF2 encodes a TrueType font as WOFF2. The tables are compressed as they
+// are, with the null transform of the glyf and loca tables.
+func WOFF2(font []byte) (out []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if r != errMalformed {
+				panic(r)
+			}
+			out, err = nil, errMalformed
+		}
+	}()
+
+	version, tables, err := parse(font)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	var dir, stream bytes.Buffer
+	sfntSize := 12 + 16*len(tags)
+	for _, tag := range tags {
+		t := tables[tag]
+		flags := byte(63)
+		for i, known := range knownTags {
+			if known == tag {
+				flags = byte(i)
+				break
+			}
+		}
+		if tag == "glyf" || tag == "loca" {
+			flags |= 3 << 6 // null transform
+		}
+		dir.WriteByte(flags)
+		if flags&63 == 63 {
+			dir.WriteString(tag)
+		}
+		dir.Write(base128(uint32(len(t))))
+		stream.Write(t)
+		sfntSize += (len(t) + 3) &^ 3
+	}
+
+	var compressed bytes.Buffer
+	w := brotli.NewWriterOptions(&compressed, brotli.WriterOptions{Quality: brotli.BestCompression, LGWin: 24})
+	_, err = w.Write(stream.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	const headerSize = 48
+	length := (headerSize + dir.Len() + compressed.Len() + 3) &^ 3
+	out = make([]byte, headerSize, length)
+	be := binary.BigEndian
+	be.PutUint32(out[0:], 0x774F4632) // 'wOF2'
+	be.PutUint32(out[4:], version)
+	be.PutUint32(out[8:], uint32(length))
+	be.PutUint16(out[12:], uint16(len(tags)))
+	be.PutUint32(out[16:], uint32(sfntSize))
+	be.PutUint32(out[20:], uint32(compressed.Len()))
+	be.PutUint16(out[24:], 1) // WOFF major version
+	out = append(out, dir.Bytes()...)
+	out = append(out, compressed.Bytes()...)
+	return out[:length], nil
+}
+
+// base128 encodes n as a UIntBase128 of WOFF2.
+func base128(n uint32) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(n & 0x7F)}, b...)
+		n >>= 7
+		if n == 0 {
+			break
+		}
+	}
+	for i := range b[:len(b)-1] {
+		b[i] |= 0x80
+	}
+	return b
+}
@@ -0,0 +1,72 @@
+// Package frontmatter parses the front matter of content files written for other
+// static site generators: YAML between "---" lines, TOML between "+++" lines, or a
+// JSON object at the start of the file.
+package frontmatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrUnterminated = errors.New("unterminated front matter")
+
+// Parse splits src into its front matter and body. A file without front matter
+// yields an empty map and the whole file as body.
+func Parse(src []byte) (map[string]any, []byte, error) {
+	src = bytes.TrimPrefix(src, []byte("\xef\xbb\xbf"))
+	src = bytes.ReplaceAll(src, []byte("\r\n"), []byte("\n"))
+	front := make(map[string]any)
+
+	switch {
+	case bytes.HasPrefix(src, []byte("---\n")):
+		data, body, err := cut(src, "---")
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := yaml.Unmarshal(data, &front); err != nil {
+			return nil, nil, fmt.Errorf("yaml front matter: %w", err)
+		}
+		if front == nil {
+			front = make(map[string]any)
+		}
+		return front, body, nil
+	case bytes.HasPrefix(src, []byte("+++\n")):
+		data, body, err := cut(src, "+++")
+		if err != nil {
+			return nil, nil, err
+		}
+		front, err := ParseTOML(string(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf("toml front matter: %w", err)
+		}
+		return front, body, nil
+	case bytes.HasPrefix(src, []byte("{")):
+		dec := json.NewDecoder(bytes.NewReader(src))
+		if err := dec.Decode(&front); err != nil {
+			return nil, nil, fmt.Errorf("json front matter: %w", err)
+		}
+		return front, bytes.TrimLeft(src[dec.InputOffset():], "\n"), nil
+	}
+	return front, src, nil
+}
+
+// cut returns the lines between the opening delimiter and the next line consisting
+// of delim, and the content after it.
+func cut(src []byte, delim string) (front, body []byte, err error) {
+	rest := src[len(delim)+1:]
+	if bytes.HasPrefix(rest, []byte(delim+"\n")) {
+		return nil, rest[len(delim)+1:], nil
+	}
+	i := bytes.Index(rest, []byte("\n"+delim+"\n"))
+	if i < 0 {
+		if !bytes.HasSuffix(rest, []byte("\n"+delim)) {
+			return nil, nil, ErrUnterminated
+		}
+		return rest[:len(rest)-len(delim)-1], nil, nil
+	}
+	return rest[:i+1], rest[i+len(delim)+2:], nil
+}
@@ -0,0 +1,132 @@
+package frontmatter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		front map[string]any
+		body  string
+	}{
+		{
+			name:  "yaml",
+			src:   "---\ntitle: Hello\ntags: [a, b]\n---\n\nBody\n",
+			front: map[string]any{"title": "Hello", "tags": []any{"a", "b"}},
+			body:  "\nBody\n",
+		},
+		{
+			name:  "toml",
+			src:   "+++\ntitle = \"Hello\"\ndraft = true\n+++\nBody\n",
+			front: map[string]any{"title": "Hello", "draft": true},
+			body:  "Body\n",
+		},
+		{
+			name:  "json",
+			src:   "{\n  \"title\": \"Hello\"\n}\n\nBody\n",
+			front: map[string]any{"title": "Hello"},
+			body:  "Body\n",
+		},
+		{
+			name:  "none",
+			src:   "Just text\n",
+			front: map[string]any{},
+			body:  "Just text\n",
+		},
+		{
+			name:  "empty",
+			src:   "---\n---\nBody\n",
+			front: map[string]any{},
+			body:  "Body\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			front, body, err := Parse([]byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(front, tt.front) {
+				t.Errorf("front = %#v, want %#v", front, tt.front)
+			}
+			if string(body) != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+
+	if _, _, err := Parse([]byte("---\ntitle: x\n")); err != ErrUnterminated {
+		t.Errorf("unterminated front matter: err = %v", err)
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	src := `# comment
+title = "Say \"hi\" \u00e9"
+path = 'C:\raw'
+count = 1_000
+ratio = 0.5
+date = 2024-03-01T10:00:00+09:00
+day = 2024-03-01
+tags = [
+  "go",   # trailing comment
+  "web",
+]
+author.name = "Kim"
+note = """
+line one \
+  continued"""
+menu = { main = { weight = 2 } }
+
+[params]
+toc = true
+
+[[links]]
+url = "a"
+[[links]]
+url = "b"
+`
+	got, err := ParseTOML(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"title":  "Say \"hi\" é",
+		"path":   `C:\raw`,
+		"count":  int64(1000),
+		"ratio":  0.5,
+		"date":   time.Date(2024, 3, 1, 10, 0, 0, 0, time.FixedZone("", 9*60*60)),
+		"day":    time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"tags":   []any{"go", "web"},
+		"author": map[string]any{"name": "Kim"},
+		"note":   "line one continued",
+		"menu":   map[string]any{"main": map[string]any{"weight": int64(2)}},
+		"params": map[string]any{"toc": true},
+		"links":  []any{map[string]any{"url": "a"}, map[string]any{"url": "b"}},
+	}
+	for k, w := range want {
+		g := got[k]
+		if gt, ok := g.(time.Time); ok {
+			if !gt.Equal(w.(time.Time)) {
+				t.Errorf("%s = %v, want %v", k, g, w)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(g, w) {
+			t.Errorf("%s = %#v, want %#v", k, g, w)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d keys, want %d", len(got), len(want))
+	}
+
+	for _, bad := range []string{"a = ", "a = 1\na = 2", "a = \"open", "[t\n", "a = [1 2]"} {
+		if _, err := ParseTOML(bad); err == nil {
+			t.Errorf("ParseTOML(%q) succeeded, want error", bad)
+		}
+	}
+}
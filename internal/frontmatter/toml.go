@@ -0,0 +1,409 @@
+package frontmatter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTOML parses a TOML document. Tables are returned as map[string]any, arrays
+// as []any, integers as int64, floats as float64 and date-times as time.Time.
+// Local date-times and dates are interpreted as UTC; local times are not supported.
+func ParseTOML(src string) (map[string]any, error) {
+	p := &tomlParser{src: src}
+	root := make(map[string]any)
+	cur := root
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.src) {
+			return root, nil
+		}
+
+		if p.src[p.pos] == '[' {
+			array := strings.HasPrefix(p.src[p.pos:], "[[")
+			closing := "]"
+			if array {
+				closing = "]]"
+			}
+			p.pos += len(closing)
+			p.skipSpace()
+			keys, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if !strings.HasPrefix(p.src[p.pos:], closing) {
+				return nil, p.errorf("expected %q", closing)
+			}
+			p.pos += len(closing)
+			if cur, err = p.table(root, keys, array); err != nil {
+				return nil, err
+			}
+		} else {
+			keys, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if !p.consume('=') {
+				return nil, p.errorf("expected \"=\" after key %q", strings.Join(keys, "."))
+			}
+			p.skipSpace()
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.set(cur, keys, v); err != nil {
+				return nil, err
+			}
+		}
+
+		p.skipSpace()
+		p.skipComment()
+		if p.pos < len(p.src) && !p.consume('\n') {
+			return nil, p.errorf("expected end of line")
+		}
+	}
+}
+
+type tomlParser struct {
+	src string
+	pos int
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	line := strings.Count(p.src[:p.pos], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) consume(c byte) bool {
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *tomlParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	if p.pos < len(p.src) && p.src[p.pos] == '#' {
+		for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+			p.pos++
+		}
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments.
+func (p *tomlParser) skipBlank() {
+	for {
+		p.skipSpace()
+		p.skipComment()
+		if !p.consume('\n') {
+			return
+		}
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c == '_' || c == '-' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
+// key parses a possibly dotted key.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("expected key")
+		}
+		switch c := p.src[p.pos]; {
+		case c == '"' || c == '\'':
+			s, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, s)
+		case isBareKeyChar(c):
+			start := p.pos
+			for p.pos < len(p.src) && isBareKeyChar(p.src[p.pos]) {
+				p.pos++
+			}
+			keys = append(keys, p.src[start:p.pos])
+		default:
+			return nil, p.errorf("unexpected character %q in key", c)
+		}
+		p.skipSpace()
+		if !p.consume('.') {
+			return keys, nil
+		}
+	}
+}
+
+// table returns the table named by keys, creating it if needed. For array tables
+// a new table is appended to the array.
+func (p *tomlParser) table(root map[string]any, keys []string, array bool) (map[string]any, error) {
+	t := root
+	for i, k := range keys {
+		last := i == len(keys)-1
+		switch v := t[k].(type) {
+		case nil:
+			if last && array {
+				next := make(map[string]any)
+				t[k] = []any{next}
+				return next, nil
+			}
+			next := make(map[string]any)
+			t[k] = next
+			t = next
+		case map[string]any:
+			if last && array {
+				return nil, p.errorf("key %q is already a table", strings.Join(keys, "."))
+			}
+			t = v
+		case []any:
+			if last && array {
+				next := make(map[string]any)
+				t[k] = append(v, next)
+				return next, nil
+			}
+			var m map[string]any
+			if len(v) > 0 {
+				m, _ = v[len(v)-1].(map[string]any)
+			}
+			if m == nil {
+				return nil, p.errorf("key %q is not a table", strings.Join(keys[:i+1], "."))
+			}
+			t = m
+		default:
+			return nil, p.errorf("key %q is already defined", strings.Join(keys[:i+1], "."))
+		}
+	}
+	return t, nil
+}
+
+func (p *tomlParser) set(t map[string]any, keys []string, v any) error {
+	for i, k := range keys[:len(keys)-1] {
+		switch next := t[k].(type) {
+		case nil:
+			m := make(map[string]any)
+			t[k] = m
+			t = m
+		case map[string]any:
+			t = next
+		default:
+			return p.errorf("key %q is already defined", strings.Join(keys[:i+1], "."))
+		}
+	}
+	k := keys[len(keys)-1]
+	if _, ok := t[k]; ok {
+		return p.errorf("key %q is already defined", strings.Join(keys, "."))
+	}
+	t[k] = v
+	return nil
+}
+
+var tomlDateTime = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(?:[Tt ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:[Zz]|[+-]\d{2}:\d{2})?)?`)
+
+func (p *tomlParser) value() (any, error) {
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("expected value")
+	}
+	rest := p.src[p.pos:]
+	switch c := rest[0]; {
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '[':
+		p.pos++
+		list := []any{}
+		for {
+			p.skipBlank()
+			if p.consume(']') {
+				return list, nil
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			p.skipBlank()
+			if !p.consume(',') {
+				p.skipBlank()
+				if !p.consume(']') {
+					return nil, p.errorf("expected \",\" or \"]\" in array")
+				}
+				return list, nil
+			}
+		}
+	case c == '{':
+		p.pos++
+		t := make(map[string]any)
+		p.skipSpace()
+		if p.consume('}') {
+			return t, nil
+		}
+		for {
+			keys, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if !p.consume('=') {
+				return nil, p.errorf("expected \"=\" in inline table")
+			}
+			p.skipSpace()
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.set(t, keys, v); err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if p.consume('}') {
+				return t, nil
+			}
+			if !p.consume(',') {
+				return nil, p.errorf("expected \",\" or \"}\" in inline table")
+			}
+			p.skipSpace()
+		}
+	case strings.HasPrefix(rest, "true"):
+		p.pos += 4
+		return true, nil
+	case strings.HasPrefix(rest, "false"):
+		p.pos += 5
+		return false, nil
+	}
+
+	if m := tomlDateTime.FindString(rest); m != "" {
+		p.pos += len(m)
+		s := strings.NewReplacer("t", "T", " ", "T", "z", "Z").Replace(m)
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", time.DateOnly} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return nil, p.errorf("invalid date-time %q", m)
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && (isBareKeyChar(p.src[p.pos]) || p.src[p.pos] == '+' || p.src[p.pos] == '.') {
+		p.pos++
+	}
+	s := p.src[start:p.pos]
+	if s == "" {
+		return nil, p.errorf("expected value")
+	}
+	if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return n, nil
+	}
+	if !strings.HasPrefix(s, "0x") {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+	}
+	return nil, p.errorf("invalid value %q", s)
+}
+
+// str parses a basic, literal or multi-line string.
+func (p *tomlParser) str() (string, error) {
+	rest := p.src[p.pos:]
+	quote := rest[:1]
+	multi := strings.HasPrefix(rest, strings.Repeat(quote, 3))
+	if multi {
+		quote = strings.Repeat(quote, 3)
+	}
+	start := p.pos + len(quote)
+
+	end := start
+	for {
+		if end >= len(p.src) {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.src[end]
+		if c == '\n' && !multi {
+			return "", p.errorf("unterminated string")
+		}
+		if c == '\\' && quote[0] == '"' {
+			end += 2
+			continue
+		}
+		if strings.HasPrefix(p.src[end:], quote) {
+			// up to two quotes may directly precede the closing delimiter
+			for multi && strings.HasPrefix(p.src[end+1:], quote) {
+				end++
+			}
+			break
+		}
+		end++
+	}
+	s := p.src[start:end]
+	p.pos = end + len(quote)
+
+	if multi {
+		s = strings.TrimPrefix(strings.TrimPrefix(s, "\r"), "\n")
+	}
+	if quote[0] == '\'' {
+		return s, nil
+	}
+	if multi {
+		s = lineEndingBackslash.ReplaceAllString(s, "")
+	}
+	return unescape(s)
+}
+
+var lineEndingBackslash = regexp.MustCompile(`\\[ \t]*\r?\n[ \t\r\n]*`)
+
+func unescape(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 >= len(s) {
+			return "", fmt.Errorf("invalid escape at end of string")
+		}
+		i++
+		switch s[i] {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case 'e':
+			b.WriteByte(0x1b)
+		case '"', '\\':
+			b.WriteByte(s[i])
+		case 'u', 'U':
+			n := 4
+			if s[i] == 'U' {
+				n = 8
+			}
+			if i+n >= len(s) {
+				return "", fmt.Errorf("invalid unicode escape %q", s[i-1:])
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape %q", s[i-1:i+1+n])
+			}
+			b.WriteRune(rune(r))
+			i += n
+		default:
+			return "", fmt.Errorf("invalid escape %q", s[i-1:i+1])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,341 @@
+// Package graphql implements a small GraphQL query executor over resolver functions.
+// It supports queries with aliases, arguments, variables and fragments; mutations,
+// subscriptions, directives and introspection are not supported.
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Object is a value with selectable fields.
+type Object interface {
+	// TypeName is returned for the __typename meta field.
+	TypeName() string
+	// Resolve returns the value of the named field. The result is a scalar,
+	// an Object, a slice of either, or nil.
+	Resolve(field string, args map[string]any) (any, error)
+}
+
+// Fields is an Object defined by a resolver function per field.
+type Fields struct {
+	Name  string
+	Funcs map[string]func(args map[string]any) (any, error)
+}
+
+func (f *Fields) TypeName() string {
+	return f.Name
+}
+
+func (f *Fields) Resolve(field string, args map[string]any) (any, error) {
+	fn, ok := f.Funcs[field]
+	if !ok {
+		return nil, fmt.Errorf("cannot query field %q on type %s", field, f.Name)
+	}
+	return fn(args)
+}
+
+// Request is a GraphQL request as sent over HTTP.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Error is a GraphQL error entry.
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// Response is the result of executing a request.
+type Response struct {
+	Data   *OrderedMap `json:"data,omitempty"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// OrderedMap is a JSON object that keeps the order of the selected fields.
+type OrderedMap struct {
+	Keys   []string
+	Values map[string]any
+}
+
+func (m *OrderedMap) set(key string, v any) {
+	if m.Values == nil {
+		m.Values = make(map[string]any)
+	}
+	if _, ok := m.Values[key]; !ok {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Values[key] = v
+}
+
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range m.Keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		val, err := json.Marshal(m.Values[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(val)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Execute runs the query of req against root.
+func Execute(root Object, req *Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	var op *operation
+	for _, o := range doc.operations {
+		if req.OperationName == "" || o.name == req.OperationName {
+			if op != nil {
+				return &Response{Errors: []Error{{Message: "operationName is required for documents with several operations"}}}
+			}
+			op = o
+		}
+	}
+	if op == nil {
+		return &Response{Errors: []Error{{Message: fmt.Sprintf("unknown operation %q", req.OperationName)}}}
+	}
+	if op.kind != "query" {
+		return &Response{Errors: []Error{{Message: fmt.Sprintf("%s operations are not supported", op.kind)}}}
+	}
+
+	vars := make(map[string]any)
+	for name, def := range op.variables {
+		if v, ok := req.Variables[name]; ok {
+			vars[name] = v
+		} else if def != nil {
+			vars[name] = def
+		}
+	}
+
+	e := &executor{fragments: doc.fragments, vars: vars}
+	data := e.selectObject(root, op.selections, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+type executor struct {
+	fragments map[string]*fragment
+	vars      map[string]any
+	errors    []Error
+}
+
+func (e *executor) fail(path []any, err error) {
+	e.errors = append(e.errors, Error{Message: err.Error(), Path: append([]any(nil), path...)})
+}
+
+// collect flattens fragment spreads into the list of fields to resolve on obj.
+func (e *executor) collect(obj Object, sels []*selection, out []*selection, seen map[string]bool) ([]*selection, error) {
+	for _, s := range sels {
+		switch {
+		case s.fragment != "":
+			if seen[s.fragment] {
+				continue
+			}
+			f, ok := e.fragments[s.fragment]
+			if !ok {
+				return nil, fmt.Errorf("unknown fragment %q", s.fragment)
+			}
+			seen[s.fragment] = true
+			if f.on != "" && f.on != obj.TypeName() {
+				continue
+			}
+			var err error
+			out, err = e.collect(obj, f.selections, out, seen)
+			if err != nil {
+				return nil, err
+			}
+		case s.inline:
+			if s.on != "" && s.on != obj.TypeName() {
+				continue
+			}
+			var err error
+			out, err = e.collect(obj, s.selections, out, seen)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (e *executor) selectObject(obj Object, sels []*selection, path []any) *OrderedMap {
+	fields, err := e.collect(obj, sels, nil, make(map[string]bool))
+	if err != nil {
+		e.fail(path, err)
+		return nil
+	}
+
+	m := &OrderedMap{}
+	for _, f := range fields {
+		key := f.alias
+		if key == "" {
+			key = f.name
+		}
+		fpath := append(path, key)
+
+		if f.name == "__typename" {
+			m.set(key, obj.TypeName())
+			continue
+		}
+
+		args, err := e.arguments(f.args)
+		if err != nil {
+			e.fail(fpath, err)
+			m.set(key, nil)
+			continue
+		}
+		v, err := obj.Resolve(f.name, args)
+		if err != nil {
+			e.fail(fpath, err)
+			m.set(key, nil)
+			continue
+		}
+		m.set(key, e.complete(v, f.selections, fpath))
+	}
+	return m
+}
+
+// complete converts a resolved value to its JSON form.
+func (e *executor) complete(v any, sels []*selection, path []any) any {
+	if v == nil {
+		return nil
+	}
+	if obj, ok := v.(Object); ok {
+		if reflect.ValueOf(obj).Kind() == reflect.Pointer && reflect.ValueOf(obj).IsNil() {
+			return nil
+		}
+		if len(sels) == 0 {
+			e.fail(path, fmt.Errorf("field of type %s must have a selection of subfields", obj.TypeName()))
+			return nil
+		}
+		return e.selectObject(obj, sels, path)
+	}
+	if t, ok := v.(time.Time); ok {
+		if t.IsZero() {
+			return nil
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice {
+		list := make([]any, rv.Len())
+		for i := range list {
+			list[i] = e.complete(rv.Index(i).Interface(), sels, append(path, i))
+		}
+		return list
+	}
+	if len(sels) > 0 {
+		e.fail(path, errors.New("scalar fields cannot have a selection of subfields"))
+		return nil
+	}
+	return v
+}
+
+func (e *executor) arguments(args map[string]*value) (map[string]any, error) {
+	out := make(map[string]any, len(args))
+	for name, v := range args {
+		val, err := e.resolveValue(v)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = val
+	}
+	return out, nil
+}
+
+func (e *executor) resolveValue(v *value) (any, error) {
+	switch {
+	case v.variable != "":
+		val, ok := e.vars[v.variable]
+		if !ok {
+			return nil, nil
+		}
+		return val, nil
+	case v.list != nil:
+		list := make([]any, len(v.list))
+		for i, item := range v.list {
+			val, err := e.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = val
+		}
+		return list, nil
+	case v.object != nil:
+		return e.arguments(v.object)
+	}
+	return v.literal, nil
+}
+
+// String returns a string argument, or def if it is missing.
+func String(args map[string]any, name, def string) (string, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		return def, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("argument %q must be a string", name)
+	}
+	return s, nil
+}
+
+// Int returns an integer argument, or def if it is missing.
+func Int(args map[string]any, name string, def int) (int, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		return def, nil
+	}
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case float64: // from JSON variables
+		if n == float64(int(n)) {
+			return int(n), nil
+		}
+	case json.Number:
+		i, err := strconv.Atoi(string(n))
+		if err == nil {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q must be an integer", name)
+}
+
+// Bool returns a boolean argument, or def if it is missing.
+func Bool(args map[string]any, name string, def bool) (bool, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		return def, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("argument %q must be a boolean", name)
+	}
+	return b, nil
+}
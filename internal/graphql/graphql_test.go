@@ -0,0 +1,116 @@
+package graphql
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type testPost struct {
+	id    string
+	title string
+	tags  []string
+}
+
+func postObject(p *testPost) Object {
+	return &Fields{Name: "Post", Funcs: map[string]func(map[string]any) (any, error){
+		"id":    func(map[string]any) (any, error) { return p.id, nil },
+		"title": func(map[string]any) (any, error) { return p.title, nil },
+		"tags":  func(map[string]any) (any, error) { return p.tags, nil },
+	}}
+}
+
+func testRoot() Object {
+	posts := []*testPost{
+		{id: "a", title: "First", tags: []string{"go"}},
+		{id: "b", title: "Second"},
+	}
+	return &Fields{Name: "Query", Funcs: map[string]func(map[string]any) (any, error){
+		"posts": func(args map[string]any) (any, error) {
+			limit, err := Int(args, "limit", len(posts))
+			if err != nil {
+				return nil, err
+			}
+			var out []Object
+			for _, p := range posts[:limit] {
+				out = append(out, postObject(p))
+			}
+			return out, nil
+		},
+		"post": func(args map[string]any) (any, error) {
+			id, err := String(args, "id", "")
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range posts {
+				if p.id == id {
+					return postObject(p), nil
+				}
+			}
+			return nil, nil
+		},
+	}}
+}
+
+func run(t *testing.T, req *Request) string {
+	t.Helper()
+	b, err := json.Marshal(Execute(testRoot(), req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{
+			name: "fields and arguments",
+			req:  Request{Query: `{ posts(limit: 1) { id title tags } }`},
+			want: `{"data":{"posts":[{"id":"a","title":"First","tags":["go"]}]}}`,
+		},
+		{
+			name: "aliases and variables",
+			req: Request{
+				Query:     `query Get($id: String!, $other: String = "b") { first: post(id: $id) { title } second: post(id: $other) { title __typename } }`,
+				Variables: map[string]any{"id": "a"},
+			},
+			want: `{"data":{"first":{"title":"First"},"second":{"title":"Second","__typename":"Post"}}}`,
+		},
+		{
+			name: "fragments",
+			req:  Request{Query: `{ post(id: "b") { ...f ... on Post { id } } } fragment f on Post { title }`},
+			want: `{"data":{"post":{"title":"Second","id":"b"}}}`,
+		},
+		{
+			name: "null object",
+			req:  Request{Query: `{ post(id: "x") { id } }`},
+			want: `{"data":{"post":null}}`,
+		},
+		{
+			name: "unknown field",
+			req:  Request{Query: `{ post(id: "a") { body } }`},
+			want: `{"data":{"post":{"body":null}},"errors":[{"message":"cannot query field \"body\" on type Post","path":["post","body"]}]}`,
+		},
+		{
+			name: "syntax error",
+			req:  Request{Query: `{ posts { id }`},
+			want: `{"errors":[{"message":"syntax error: expected name, found end of document"}]}`,
+		},
+		{
+			name: "mutation",
+			req:  Request{Query: `mutation { posts { id } }`},
+			want: `{"errors":[{"message":"mutation operations are not supported"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := run(t, &tt.req); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
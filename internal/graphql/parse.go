@@ -0,0 +1,491 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string // "query", "mutation" or "subscription"
+	name       string
+	variables  map[string]any // default values, nil if there is none
+	selections []*selection
+}
+
+type fragment struct {
+	on         string
+	selections []*selection
+}
+
+// selection is a field, a fragment spread (fragment is set) or an inline fragment (inline is set).
+type selection struct {
+	alias      string
+	name       string
+	args       map[string]*value
+	selections []*selection
+
+	fragment string
+	inline   bool
+	on       string
+}
+
+// value is an argument value: a variable reference, a list, an object or a literal.
+type value struct {
+	variable string
+	list     []*value
+	object   map[string]*value
+	literal  any
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			goto scan
+		}
+	}
+	return token{kind: tokEOF, pos: l.pos}, nil
+
+scan:
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokPunct, text: "...", pos: start}, nil
+	case strings.ContainsRune("!$():=@[]{}|", rune(c)):
+		l.pos++
+		return token{kind: tokPunct, text: string(c), pos: start}, nil
+	case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+		for l.pos < len(l.src) && isNameChar(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokName, text: l.src[start:l.pos], pos: start}, nil
+	case c == '-' || c >= '0' && c <= '9':
+		l.pos++
+		kind := tokInt
+		for l.pos < len(l.src) {
+			d := l.src[l.pos]
+			if d == '.' || d == 'e' || d == 'E' || (d == '-' || d == '+') && kind == tokFloat {
+				kind = tokFloat
+			} else if d < '0' || d > '9' {
+				break
+			}
+			l.pos++
+		}
+		return token{kind: kind, text: l.src[start:l.pos], pos: start}, nil
+	case c == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			end := strings.Index(l.src[l.pos+3:], `"""`)
+			if end < 0 {
+				return token{}, fmt.Errorf("unterminated block string at %d", start)
+			}
+			l.pos += 3 + end + 3
+			return token{kind: tokString, text: l.src[start+3 : l.pos-3], pos: start}, nil
+		}
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				return token{}, fmt.Errorf("unterminated string at %d", start)
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return token{}, fmt.Errorf("unterminated string at %d", start)
+		}
+		l.pos++
+		s, err := strconv.Unquote(l.src[start:l.pos])
+		if err != nil {
+			return token{}, fmt.Errorf("invalid string at %d: %w", start, err)
+		}
+		return token{kind: tokString, text: s, pos: start}, nil
+	}
+	return token{}, fmt.Errorf("unexpected character %q at %d", c, start)
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+func (p *parser) advance() error {
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = t
+	return nil
+}
+
+func (p *parser) is(text string) bool {
+	return (p.tok.kind == tokPunct || p.tok.kind == tokName) && p.tok.text == text
+}
+
+func (p *parser) expect(text string) error {
+	if !p.is(text) {
+		return p.unexpected("\"" + text + "\"")
+	}
+	return p.advance()
+}
+
+func (p *parser) unexpected(want string) error {
+	if p.tok.kind == tokEOF {
+		return fmt.Errorf("syntax error: expected %s, found end of document", want)
+	}
+	return fmt.Errorf("syntax error: expected %s, found %q at %d", want, p.tok.text, p.tok.pos)
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.unexpected("name")
+	}
+	n := p.tok.text
+	return n, p.advance()
+}
+
+func parse(src string) (*document, error) {
+	p := &parser{lex: &lexer{src: src}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.is("{"):
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: sels})
+		case p.is("query") || p.is("mutation") || p.is("subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.is("fragment"):
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("on"); err != nil {
+				return nil, err
+			}
+			on, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = &fragment{on: on, selections: sels}
+		default:
+			return nil, p.unexpected("operation or fragment")
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document has no operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: p.tok.text, variables: make(map[string]any)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokName {
+		op.name = p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.is("(") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for !p.is(")") {
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if err := p.skipType(); err != nil {
+				return nil, err
+			}
+			var def any
+			if p.is("=") {
+				if err := p.advance(); err != nil {
+					return nil, err
+				}
+				v, err := p.value(true)
+				if err != nil {
+					return nil, err
+				}
+				def = v.literal
+			}
+			op.variables[name] = def
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.is("@") {
+		return nil, fmt.Errorf("directives are not supported")
+	}
+	sels, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = sels
+	return op, nil
+}
+
+// skipType skips a variable type such as [String!]!; types are not checked.
+func (p *parser) skipType() error {
+	if p.is("[") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.is("!") {
+		return p.advance()
+	}
+	return nil
+}
+
+func (p *parser) selectionSet() ([]*selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []*selection
+	for !p.is("}") {
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, s)
+	}
+	return sels, p.advance()
+}
+
+func (p *parser) selection() (*selection, error) {
+	if p.is("...") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		s := &selection{}
+		if p.tok.kind == tokName && p.tok.text != "on" {
+			s.fragment = p.tok.text
+			return s, p.advance()
+		}
+		s.inline = true
+		if p.is("on") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			on, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			s.on = on
+		}
+		sels, err := p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+		s.selections = sels
+		return s, nil
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	s := &selection{name: name}
+	if p.is(":") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		s.alias = name
+		if s.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.is("(") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		s.args = make(map[string]*value)
+		for !p.is(")") {
+			arg, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			v, err := p.value(false)
+			if err != nil {
+				return nil, err
+			}
+			s.args[arg] = v
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.is("@") {
+		return nil, fmt.Errorf("directives are not supported")
+	}
+	if p.is("{") {
+		if s.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) value(constant bool) (*value, error) {
+	t := p.tok
+	switch {
+	case p.is("$") && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		return &value{variable: name}, nil
+	case p.is("["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v := &value{list: []*value{}}
+		var lits []any
+		for !p.is("]") {
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			v.list = append(v.list, item)
+			lits = append(lits, item.literal)
+		}
+		v.literal = lits
+		return v, p.advance()
+	case p.is("{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v := &value{object: make(map[string]*value)}
+		lits := make(map[string]any)
+		for !p.is("}") {
+			key, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			v.object[key] = item
+			lits[key] = item.literal
+		}
+		v.literal = lits
+		return v, p.advance()
+	case t.kind == tokInt:
+		n, err := strconv.Atoi(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q at %d", t.text, t.pos)
+		}
+		return &value{literal: n}, p.advance()
+	case t.kind == tokFloat:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		return &value{literal: f}, p.advance()
+	case t.kind == tokString:
+		return &value{literal: t.text}, p.advance()
+	case t.kind == tokName:
+		switch t.text {
+		case "true":
+			return &value{literal: true}, p.advance()
+		case "false":
+			return &value{literal: false}, p.advance()
+		case "null":
+			return &value{}, p.advance()
+		}
+		// enum values are passed as strings
+		return &value{literal: t.text}, p.advance()
+	}
+	return nil, p.unexpected("value")
+}
@@ -0,0 +1,140 @@
+// Package htmlcheck reports structural errors of HTML documents that browsers
+// silently recover from: unclosed and stray tags, and duplicate IDs.
+package htmlcheck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// Error is a structural error found in a document.
+type Error struct {
+	// Line is the 1-based line of the token the error was found at.
+	Line    int
+	Message string
+}
+
+func (e Error) String() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// void elements have no end tag.
+var void = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// IsVoid reports whether tag is a void element, which has no end tag.
+func IsVoid(tag string) bool {
+	return void[tag]
+}
+
+// optionalEnd elements may omit their end tag, so they are closed implicitly.
+var optionalEnd = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true, "dt": true, "dd": true,
+	"option": true, "optgroup": true, "rt": true, "rp": true, "colgroup": true, "caption": true,
+	"thead": true, "tbody": true, "tfoot": true, "tr": true, "td": true, "th": true,
+}
+
+type open struct {
+	name string
+	line int
+}
+
+// Check tokenizes doc and returns its structural errors in document order.
+func Check(doc []byte) ([]Error, error) {
+	z := html.NewTokenizer(bytes.NewReader(doc))
+	var errs []Error
+	var stack []open
+	ids := make(map[string]int)
+	line := 1
+	foreign := 0 // depth of svg and math elements, where any element may self-close
+
+	report := func(line int, format string, args ...any) {
+		errs = append(errs, Error{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if !errors.Is(z.Err(), io.EOF) {
+				return nil, z.Err()
+			}
+			break
+		}
+		start := line
+		line += bytes.Count(z.Raw(), []byte("\n"))
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				if string(key) != "id" {
+					continue
+				}
+				id := string(val)
+				if first, ok := ids[id]; ok {
+					report(start, "duplicate id %q, first used on line %d", id, first)
+				} else {
+					ids[id] = start
+				}
+			}
+
+			if void[tag] {
+				continue
+			}
+			if tt == html.SelfClosingTagToken {
+				if foreign > 0 || tag == "svg" || tag == "math" {
+					continue
+				}
+				report(start, "<%s/> is not a void element, the slash is ignored and the element is left open", tag)
+			}
+			if tag == "svg" || tag == "math" || foreign > 0 {
+				foreign++
+			}
+			stack = append(stack, open{tag, start})
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if void[tag] {
+				report(start, "end tag </%s> of a void element", tag)
+				continue
+			}
+			i := len(stack) - 1
+			for i >= 0 && stack[i].name != tag {
+				i--
+			}
+			if i < 0 {
+				report(start, "stray end tag </%s>", tag)
+				continue
+			}
+			for _, o := range stack[i+1:] {
+				if !optionalEnd[o.name] {
+					report(o.line, "<%s> is not closed before </%s> on line %d", o.name, tag, start)
+				}
+			}
+			if foreign > 0 {
+				foreign -= len(stack) - i
+				if foreign < 0 {
+					foreign = 0
+				}
+			}
+			stack = stack[:i]
+		}
+	}
+
+	for _, o := range stack {
+		if !optionalEnd[o.name] {
+			report(o.line, "<%s> is never closed", o.name)
+		}
+	}
+	return errs, nil
+}
@@ -0,0 +1,68 @@
+package htmlcheck
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{
+			name: "valid",
+			doc: `<!doctype html><html lang=en><head><meta charset=utf-8><title>T</title>
+<body><ul><li>a<li>b</ul><p>x<p>y<br><img src=a.png>
+<svg><path d="M0 0"/><g><circle r=1 /></g></svg>
+<table><tr><td>1<td>2</table><script>if (a < b) { document.write("</div>") }</script>`,
+		},
+		{
+			name: "unclosed",
+			doc:  "<div>\n<section>\n<span>x</div>",
+			want: []string{
+				"line 2: <section> is not closed before </div> on line 3",
+				"line 3: <span> is not closed before </div> on line 3",
+			},
+		},
+		{
+			name: "never closed",
+			doc:  "<body>\n<div><em>x</em>",
+			want: []string{"line 2: <div> is never closed"},
+		},
+		{
+			name: "stray",
+			doc:  "<div>x</div>\n</div></br>",
+			want: []string{
+				"line 2: stray end tag </div>",
+				"line 2: end tag </br> of a void element",
+			},
+		},
+		{
+			name: "self-closing",
+			doc:  "<div/><p>x</p></div>",
+			want: []string{"line 1: <div/> is not a void element, the slash is ignored and the element is left open"},
+		},
+		{
+			name: "duplicate id",
+			doc:  "<h2 id=intro>A</h2>\n<h2 id=intro>B</h2>",
+			want: []string{`line 2: duplicate id "intro", first used on line 1`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := Check([]byte(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range errs {
+				got = append(got, e.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Package htmlrewrite provides token level rewriting of generated HTML documents.
+// Tokens that are not replaced are copied verbatim, so inline scripts and
+// already minified markup are preserved byte for byte.
+package htmlrewrite
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// Token is a single HTML token passed to a rewrite function.
+type Token struct {
+	html.Token
+	// Raw is the raw source bytes of the token.
+	Raw []byte
+}
+
+// Attr returns the value of the named attribute and whether it is present.
+func (t *Token) Attr(key string) (string, bool) {
+	for _, a := range t.Token.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// SetAttr sets the named attribute, appending it if it is not present.
+func (t *Token) SetAttr(key, val string) {
+	for i := range t.Token.Attr {
+		if t.Token.Attr[i].Key == key {
+			t.Token.Attr[i].Val = val
+			return
+		}
+	}
+	t.Token.Attr = append(t.Token.Attr, html.Attribute{Key: key, Val: val})
+}
+
+// IsTag reports whether the token is a start or self-closing tag named tag.
+func (t *Token) IsTag(tag string) bool {
+	return (t.Type == html.StartTagToken || t.Type == html.SelfClosingTagToken) && t.Data == tag
+}
+
+// Render serializes the token from its parsed form.
+// Use it after modifying attributes of a start tag.
+func (t *Token) Render() []byte {
+	return []byte(t.Token.String())
+}
+
+// Func inspects a token and returns the bytes to emit in its place.
+// Returning nil keeps the raw token.
+type Func func(t *Token) []byte
+
+// Rewrite tokenizes src and calls fn for every token.
+func Rewrite(src []byte, fn Func) ([]byte, error) {
+	var out bytes.Buffer
+	out.Grow(len(src))
+
+	z := html.NewTokenizer(bytes.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if errors.Is(z.Err(), io.EOF) {
+				return out.Bytes(), nil
+			}
+			return nil, z.Err()
+		}
+
+		raw := append([]byte(nil), z.Raw()...)
+		t := &Token{Token: z.Token(), Raw: raw}
+		if repl := fn(t); repl != nil {
+			out.Write(repl)
+		} else {
+			out.Write(raw)
+		}
+	}
+}
+
+// Walk calls fn for every token of src without modifying it.
+func Walk(src []byte, fn func(t *Token)) error {
+	_, err := Rewrite(src, func(t *Token) []byte {
+		fn(t)
+		return nil
+	})
+	return err
+}
+
+// Token types, re-exported so callers do not need to import golang.org/x/net/html.
+const (
+	TextToken           = html.TextToken
+	StartTagToken       = html.StartTagToken
+	EndTagToken         = html.EndTagToken
+	SelfClosingTagToken = html.SelfClosingTagToken
+	CommentToken        = html.CommentToken
+	DoctypeToken        = html.DoctypeToken
+)
@@ -0,0 +1,34 @@
+package htmlrewrite
+
+import (
+	"testing"
+)
+
+func TestRewritePreservesRaw(t *testing.T) {
+	src := `<!DOCTYPE html><html><head><script>if (a < b && c) {}</script></head><body><p class=x>a &amp; b</p></body></html>`
+	out, err := Rewrite([]byte(src), func(t *Token) []byte { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != src {
+		t.Errorf("expected %q, got %q", src, out)
+	}
+}
+
+func TestRewriteSetAttr(t *testing.T) {
+	src := `<head><script src="/main.js" defer></script></head>`
+	out, err := Rewrite([]byte(src), func(t *Token) []byte {
+		if t.IsTag("script") {
+			t.SetAttr("integrity", "sha384-x")
+			return t.Render()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<head><script src="/main.js" defer="" integrity="sha384-x"></script></head>`
+	if string(out) != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
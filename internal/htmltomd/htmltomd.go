@@ -0,0 +1,367 @@
+// Package htmltomd converts HTML content, as found in blog exports, to CommonMark
+// with GitHub flavored tables and strikethrough. Elements without a Markdown
+// equivalent are reduced to their text, except for scripts and styles, which are dropped.
+package htmltomd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Convert converts an HTML fragment to Markdown.
+func Convert(src string) (string, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(src), body)
+	if err != nil {
+		return "", err
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+
+	out := blocks(body)
+	out = blankLines.ReplaceAllString(out, "\n\n")
+	return strings.TrimSpace(out) + "\n", nil
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+var dropped = map[atom.Atom]bool{atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true, atom.Head: true}
+
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Aside: true, atom.Header: true,
+	atom.Footer: true, atom.Main: true, atom.Nav: true, atom.Figure: true, atom.Figcaption: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Blockquote: true, atom.Pre: true, atom.Hr: true,
+	atom.Table: true, atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Details: true, atom.Summary: true,
+	atom.Iframe: true, atom.Video: true, atom.Audio: true,
+}
+
+func isBlock(n *html.Node) bool {
+	return n.Type == html.ElementNode && blockElements[n.DataAtom]
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// blocks renders the children of n as a sequence of blocks separated by blank lines.
+// Runs of inline content between blocks become paragraphs.
+func blocks(n *html.Node) string {
+	var b strings.Builder
+	var inline []*html.Node
+	flush := func() {
+		text := strings.TrimSpace(inlines(inline))
+		inline = inline[:0]
+		if text != "" {
+			b.WriteString(text + "\n\n")
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.CommentNode || c.Type == html.ElementNode && dropped[c.DataAtom] {
+			continue
+		}
+		if !isBlock(c) {
+			inline = append(inline, c)
+			continue
+		}
+		flush()
+		if s := block(c); strings.TrimSpace(s) != "" {
+			b.WriteString(s + "\n\n")
+		}
+	}
+	flush()
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		return strings.Repeat("#", level) + " " + strings.TrimSpace(oneLine(inlines(children(n))))
+	case atom.P, atom.Dt, atom.Summary:
+		return strings.TrimSpace(inlines(children(n)))
+	case atom.Figcaption:
+		if text := strings.TrimSpace(inlines(children(n))); text != "" {
+			return "*" + text + "*"
+		}
+		return ""
+	case atom.Hr:
+		return "---"
+	case atom.Blockquote:
+		return prefixLines(blocks(n), "> ", "> ")
+	case atom.Pre:
+		return codeBlock(n)
+	case atom.Ul, atom.Ol:
+		return list(n)
+	case atom.Table:
+		if t := table(n); t != "" {
+			return t
+		}
+		return blocks(n)
+	case atom.Iframe, atom.Video, atom.Audio:
+		src := attr(n, "src")
+		if src == "" {
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.DataAtom == atom.Source {
+					src = attr(c, "src")
+					break
+				}
+			}
+		}
+		if src == "" {
+			return ""
+		}
+		return "<" + src + ">"
+	}
+	return blocks(n)
+}
+
+func children(n *html.Node) []*html.Node {
+	var list []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		list = append(list, c)
+	}
+	return list
+}
+
+func prefixLines(s, first, rest string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		p := rest
+		if i == 0 {
+			p = first
+		}
+		if line == "" {
+			lines[i] = strings.TrimRight(p, " ")
+		} else {
+			lines[i] = p + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func list(n *html.Node) string {
+	var items []string
+	num := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		num = start
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(num) + ". "
+			num++
+		}
+		content := blocks(c)
+		items = append(items, prefixLines(content, marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+func codeBlock(n *html.Node) string {
+	lang := ""
+	src := n
+	if c := n.FirstChild; c != nil && c.DataAtom == atom.Code && c.NextSibling == nil {
+		src = c
+		for _, class := range strings.Fields(attr(c, "class")) {
+			if l, ok := strings.CutPrefix(class, "language-"); ok {
+				lang = l
+			}
+		}
+	}
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if l, ok := strings.CutPrefix(class, "language-"); ok && lang == "" {
+			lang = l
+		} else if l, ok := strings.CutPrefix(class, "lang:"); ok && lang == "" {
+			lang = l // SyntaxHighlighter plugin
+		}
+	}
+
+	code := strings.TrimRight(text(src), "\n")
+	code = strings.TrimPrefix(code, "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+// text returns the raw text content of n.
+func text(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	if n.DataAtom == atom.Br {
+		return "\n"
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(text(c))
+	}
+	return b.String()
+}
+
+func table(n *html.Node) string {
+	var rows [][]string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.DataAtom {
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(c)
+			case atom.Tr:
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+						s := strings.TrimSpace(oneLine(inlines(children(cell))))
+						row = append(row, strings.ReplaceAll(s, "|", `\|`))
+					}
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	var b strings.Builder
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+var spaces = regexp.MustCompile(`[ \t\r\n]+`)
+
+func oneLine(s string) string {
+	return strings.ReplaceAll(s, "\\\n", " ")
+}
+
+// escaper escapes characters that would otherwise start Markdown syntax inside text.
+var escaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`)
+
+// EscapeText escapes the characters of s that would otherwise start Markdown
+// syntax, for use as Markdown text.
+func EscapeText(s string) string {
+	return escaper.Replace(s)
+}
+
+func inlines(nodes []*html.Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		b.WriteString(inline(n))
+	}
+	// collapse whitespace, but keep hard line breaks
+	parts := strings.Split(b.String(), "\\\n")
+	for i, p := range parts {
+		parts[i] = spaces.ReplaceAllString(p, " ")
+	}
+	s := strings.Join(parts, "\\\n")
+	return strings.ReplaceAll(s, "\\\n ", "\\\n")
+}
+
+func wrap(s, marker string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	lead := s[:len(s)-len(strings.TrimLeft(s, " \t\n"))]
+	trail := s[len(strings.TrimRight(s, " \t\n")):]
+	return lead + marker + trimmed + marker + trail
+}
+
+func inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return escaper.Replace(n.Data)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if dropped[n.DataAtom] {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\\\n"
+	case atom.Strong, atom.B:
+		return wrap(inlines(children(n)), "**")
+	case atom.Em, atom.I, atom.Cite:
+		return wrap(inlines(children(n)), "*")
+	case atom.Del, atom.S, atom.Strike:
+		return wrap(inlines(children(n)), "~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		code := text(n)
+		fence := "`"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+			code = " " + code + " "
+		}
+		return fence + code + fence
+	case atom.A:
+		content := strings.TrimSpace(inlines(children(n)))
+		href := attr(n, "href")
+		if href == "" {
+			return content
+		}
+		if content == "" {
+			content = escaper.Replace(href)
+		}
+		return "[" + content + "](" + linkDest(href) + linkTitle(attr(n, "title")) + ")"
+	case atom.Img:
+		src := attr(n, "src")
+		if src == "" {
+			return ""
+		}
+		return "![" + escaper.Replace(attr(n, "alt")) + "](" + linkDest(src) + linkTitle(attr(n, "title")) + ")"
+	}
+
+	if isBlock(n) {
+		// block content inside inline context, e.g. a <div> in a <span>
+		return " " + oneLine(blocks(n)) + " "
+	}
+	return inlines(children(n))
+}
+
+func linkDest(u string) string {
+	if strings.ContainsAny(u, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(u) + ">"
+	}
+	return u
+}
+
+func linkTitle(t string) string {
+	if t == "" {
+		return ""
+	}
+	return ` "` + strings.ReplaceAll(t, `"`, `\"`) + `"`
+}
@@ -0,0 +1,62 @@
+package htmltomd
+
+import "testing"
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "inline",
+			html: `<p>Hello <strong>bold</strong> and <em>it</em>, <code>x := 1</code> and <a href="https://go.dev" title="Go">a link</a>.</p>`,
+			want: "Hello **bold** and *it*, `x := 1` and [a link](https://go.dev \"Go\").\n",
+		},
+		{
+			name: "headings and escaping",
+			html: `<h2>Title  with
+			space</h2><p>a*b [c]</p>`,
+			want: "## Title with space\n\na\\*b \\[c\\]\n",
+		},
+		{
+			name: "lists",
+			html: `<ul><li>one</li><li>two<ol start="3"><li>three</li></ol></li></ul>`,
+			want: "- one\n- two\n\n  3. three\n",
+		},
+		{
+			name: "code block",
+			html: `<pre><code class="language-go">func main() {
+	fmt.Println("a &lt; b")
+}</code></pre>`,
+			want: "```go\nfunc main() {\n\tfmt.Println(\"a < b\")\n}\n```\n",
+		},
+		{
+			name: "blockquote and image",
+			html: `<blockquote><p>quoted</p><p>twice</p></blockquote><figure><img src="/a.png" alt="A"><figcaption>cap</figcaption></figure>`,
+			want: "> quoted\n>\n> twice\n\n![A](/a.png)\n\n*cap*\n",
+		},
+		{
+			name: "table",
+			html: `<table><thead><tr><th>a</th><th>b</th></tr></thead><tbody><tr><td>1</td><td>x|y</td></tr></tbody></table>`,
+			want: "| a | b |\n| --- | --- |\n| 1 | x\\|y |\n",
+		},
+		{
+			name: "loose text and breaks",
+			html: "first line<br>second<script>alert(1)</script><!-- c --><p>para</p>",
+			want: "first line\\\nsecond\n\npara\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert(tt.html)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package i18n
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateFormat is the CLDR long date format of a language.
+type dateFormat struct {
+	// pattern is a CLDR date pattern using y, M, MMMM, d and 'quoted' literals.
+	pattern string
+	// months are the wide month names in the format context, which are
+	// inflected in some languages (e.g. Russian "июня", Polish "czerwca").
+	months [12]string
+}
+
+var numericMonths = [12]string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12"}
+
+// dateFormats holds the gregorian long date formats of CLDR for the supported languages.
+var dateFormats = map[string]dateFormat{
+	"en": {"MMMM d, y", [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}},
+	"es": {"d 'de' MMMM 'de' y", [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"}},
+	"zh": {"y年M月d日", numericMonths},
+	"ko": {"y년 M월 d일", numericMonths},
+	"ja": {"y年M月d日", numericMonths},
+	"de": {"d. MMMM y", [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"}},
+	"ru": {"d MMMM y 'г'.", [12]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"}},
+	"fr": {"d MMMM y", [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"}},
+	"nl": {"d MMMM y", [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"}},
+	"it": {"d MMMM y", [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"}},
+	"id": {"d MMMM y", [12]string{"Januari", "Februari", "Maret", "April", "Mei", "Juni", "Juli", "Agustus", "September", "Oktober", "November", "Desember"}},
+	"pt": {"d 'de' MMMM 'de' y", [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"}},
+	"sv": {"d MMMM y", [12]string{"januari", "februari", "mars", "april", "maj", "juni", "juli", "augusti", "september", "oktober", "november", "december"}},
+	"cs": {"d. MMMM y", [12]string{"ledna", "února", "března", "dubna", "května", "června", "července", "srpna", "září", "října", "listopadu", "prosince"}},
+	"sk": {"d. MMMM y", [12]string{"januára", "februára", "marca", "apríla", "mája", "júna", "júla", "augusta", "septembra", "októbra", "novembra", "decembra"}},
+	"pl": {"d MMMM y", [12]string{"stycznia", "lutego", "marca", "kwietnia", "maja", "czerwca", "lipca", "sierpnia", "września", "października", "listopada", "grudnia"}},
+	"ro": {"d MMMM y", [12]string{"ianuarie", "februarie", "martie", "aprilie", "mai", "iunie", "iulie", "august", "septembrie", "octombrie", "noiembrie", "decembrie"}},
+	"hu": {"y. MMMM d.", [12]string{"január", "február", "március", "április", "május", "június", "július", "augusztus", "szeptember", "október", "november", "december"}},
+	"fi": {"d. MMMM y", [12]string{"tammikuuta", "helmikuuta", "maaliskuuta", "huhtikuuta", "toukokuuta", "kesäkuuta", "heinäkuuta", "elokuuta", "syyskuuta", "lokakuuta", "marraskuuta", "joulukuuta"}},
+	"tr": {"d MMMM y", [12]string{"Ocak", "Şubat", "Mart", "Nisan", "Mayıs", "Haziran", "Temmuz", "Ağustos", "Eylül", "Ekim", "Kasım", "Aralık"}},
+}
+
+// FormatDate formats the date of t in the long date format of lang, falling
+// back to English for unknown languages.
+func FormatDate(lang string, t time.Time) string {
+	f, ok := dateFormats[lang]
+	if !ok {
+		base, _, _ := strings.Cut(lang, "-")
+		if f, ok = dateFormats[base]; !ok {
+			f = dateFormats["en"]
+		}
+	}
+
+	var b strings.Builder
+	p := f.pattern
+	for len(p) > 0 {
+		c := p[0]
+		n := 1
+		for n < len(p) && p[n] == c {
+			n++
+		}
+		switch c {
+		case 'y':
+			b.WriteString(strconv.Itoa(t.Year()))
+		case 'M':
+			if n >= 4 {
+				b.WriteString(f.months[t.Month()-1])
+			} else {
+				b.WriteString(strconv.Itoa(int(t.Month())))
+			}
+		case 'd':
+			b.WriteString(strconv.Itoa(t.Day()))
+		case '\'':
+			end := strings.IndexByte(p[1:], '\'')
+			if end < 0 {
+				end = len(p) - 1
+			}
+			b.WriteString(p[1 : end+1])
+			n = min(end+2, len(p))
+		default:
+			b.WriteString(p[:n])
+		}
+		p = p[n:]
+	}
+	return b.String()
+}
@@ -0,0 +1,154 @@
+// Package i18n localizes the UI strings of templates from per-language
+// message files.
+//
+// Messages are keyed by their English text, so that templates stay readable
+// and English needs no message file. A message file i18n/<lang>.yaml maps
+// English messages to their translation:
+//
+//	"Featured Posts": "추천 글"
+//	"By %s": "%s 작성"
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"gopkg.in/yaml.v3"
+)
+
+// Bundle holds the messages of every language.
+type Bundle struct {
+	messages map[string]map[string]string
+
+	mu      sync.Mutex
+	missing map[string]map[string]struct{}
+}
+
+// Load reads the message files <lang>.yaml of dir. A missing directory is an
+// empty bundle.
+func Load(dir string) (*Bundle, error) {
+	b := &Bundle{
+		messages: make(map[string]map[string]string),
+		missing:  make(map[string]map[string]struct{}),
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	for _, fp := range files {
+		data, err := os.ReadFile(fp)
+		if err != nil {
+			return nil, err
+		}
+		messages := make(map[string]string)
+		err = yaml.Unmarshal(data, &messages)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fp, err)
+		}
+		b.messages[strings.TrimSuffix(filepath.Base(fp), ".yaml")] = messages
+	}
+	return b, nil
+}
+
+// Languages returns the languages with a message file.
+func (b *Bundle) Languages() []string {
+	langs := make([]string, 0, len(b.messages))
+	for lang := range b.messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Localizer returns the localizer of lang.
+func (b *Bundle) Localizer(lang string) *Localizer {
+	return &Localizer{bundle: b, lang: lang}
+}
+
+// Missing returns the messages looked up in each language without a
+// translation, sorted. English is never missing.
+func (b *Bundle) Missing() map[string][]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	missing := make(map[string][]string, len(b.missing))
+	for lang, keys := range b.missing {
+		for key := range keys {
+			missing[lang] = append(missing[lang], key)
+		}
+		sort.Strings(missing[lang])
+	}
+	return missing
+}
+
+func (b *Bundle) lookup(lang, key string) (string, bool) {
+	if msg, ok := b.messages[lang][key]; ok && msg != "" {
+		return msg, true
+	}
+	if lang == "en" {
+		return key, true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.missing == nil {
+		b.missing = make(map[string]map[string]struct{})
+	}
+	if b.missing[lang] == nil {
+		b.missing[lang] = make(map[string]struct{})
+	}
+	b.missing[lang][key] = struct{}{}
+	return key, false
+}
+
+// Localizer translates messages into one language.
+type Localizer struct {
+	bundle *Bundle
+	lang   string
+}
+
+// Lang returns the language of l.
+func (l *Localizer) Lang() string {
+	if l == nil {
+		return "en"
+	}
+	return l.lang
+}
+
+// T returns the translation of the English message key, formatted with args
+// if any, with numbers in the conventions of the language. Untranslated
+// messages are returned in English. A nil Localizer returns messages in English.
+func (l *Localizer) T(key string, args ...any) string {
+	msg := key
+	if l != nil && l.bundle != nil {
+		msg, _ = l.bundle.lookup(l.lang, key)
+	}
+	if len(args) > 0 {
+		return message.NewPrinter(language.Make(l.Lang())).Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Date formats the date of t in the long date format of the language.
+func (l *Localizer) Date(t time.Time) string {
+	return FormatDate(l.Lang(), t)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l.
+func NewContext(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the Localizer of ctx, or nil.
+func FromContext(ctx context.Context) *Localizer {
+	l, _ := ctx.Value(contextKey{}).(*Localizer)
+	return l
+}
@@ -0,0 +1,76 @@
+package i18n
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLocalizer(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "ko.yaml"), []byte("\"Featured Posts\": \"추천 글\"\n\"By %s\": \"%s 작성\"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ko := b.Localizer("ko")
+	if got := ko.T("Featured Posts"); got != "추천 글" {
+		t.Errorf("T() = %q, want %q", got, "추천 글")
+	}
+	if got := ko.T("By %s", "gopher"); got != "gopher 작성" {
+		t.Errorf("T() = %q, want %q", got, "gopher 작성")
+	}
+	if got := ko.T("Search"); got != "Search" {
+		t.Errorf("T() of a missing message = %q, want the English message", got)
+	}
+	if got := b.Localizer("en").T("Contributors"); got != "Contributors" {
+		t.Errorf("T() in English = %q, want %q", got, "Contributors")
+	}
+
+	want := map[string][]string{"ko": {"Search"}}
+	if got := b.Missing(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Missing() = %v, want %v", got, want)
+	}
+
+	ctx := NewContext(context.Background(), ko)
+	if got := FromContext(ctx).T("Featured Posts"); got != "추천 글" {
+		t.Errorf("FromContext().T() = %q, want %q", got, "추천 글")
+	}
+	if got := FromContext(context.Background()).T("By %s", "gopher"); got != "By gopher" {
+		t.Errorf("nil Localizer T() = %q, want %q", got, "By gopher")
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2024, time.June, 3, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		lang string
+		want string
+	}{
+		{"en", "June 3, 2024"},
+		{"ko", "2024년 6월 3일"},
+		{"ja", "2024年6月3日"},
+		{"es", "3 de junio de 2024"},
+		{"de", "3. Juni 2024"},
+		{"ru", "3 июня 2024 г."},
+		{"hu", "2024. június 3."},
+		{"pt-BR", "3 de junho de 2024"},
+		{"xx", "June 3, 2024"},
+	}
+	for _, tc := range testCases {
+		if got := FormatDate(tc.lang, date); got != tc.want {
+			t.Errorf("FormatDate(%q) = %q, want %q", tc.lang, got, tc.want)
+		}
+	}
+
+	if got := (&Bundle{}).Localizer("de").T("%d commits", 1234); got != "1.234 commits" {
+		t.Errorf("T() = %q, want localized number %q", got, "1.234 commits")
+	}
+}
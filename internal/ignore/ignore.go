@@ -0,0 +1,159 @@
+// Package ignore matches slash separated paths against patterns written in the
+// gitignore syntax.
+package ignore
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+)
+
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher holds the rules of an ignore file. The zero value and nil match nothing.
+type Matcher struct {
+	rules []rule
+}
+
+// Load reads the ignore file at path. A missing file yields an empty Matcher.
+func Load(path string) (*Matcher, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Matcher{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(data)), nil
+}
+
+// Parse parses the lines of an ignore file. Blank lines and comments are skipped.
+func Parse(src string) *Matcher {
+	m := &Matcher{}
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var r rule
+		if strings.HasPrefix(line, "!") {
+			r.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// a pattern without a slash matches at any depth, otherwise it is
+		// relative to the location of the ignore file
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+
+		re, err := regexp.Compile("^" + translate(line) + "$")
+		if err != nil {
+			continue
+		}
+		r.re = re
+		m.rules = append(m.rules, r)
+	}
+	return m
+}
+
+// translate converts a glob pattern into a regular expression.
+func translate(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**") {
+				switch {
+				case strings.HasPrefix(pattern[i:], "**/"):
+					b.WriteString("(?:.*/)?")
+					i += 2
+				case i+2 == len(pattern):
+					b.WriteString(".*")
+					i++
+				default:
+					b.WriteString("[^/]*")
+					i++
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+func (m *Matcher) match(path string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(path) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// Match reports whether path, relative to the location of the ignore file, is
+// ignored. As in git, the contents of an ignored directory cannot be re-included.
+func (m *Matcher) Match(path string, isDir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+	path = strings.Trim(path, "/")
+	if path == "" || path == "." {
+		return false
+	}
+
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && m.match(path[:i], true) {
+			return true
+		}
+	}
+	return m.match(path, isDir)
+}
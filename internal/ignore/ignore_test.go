@@ -0,0 +1,71 @@
+package ignore
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	m := Parse(`# editor files
+*.swp
+*~
+.DS_Store
+
+_drafts/
+/public/raw/
+design/**/*.psd
+!design/keep/*.psd
+root/**/notes.md
+\#hash
+file?.txt
+[abc].log
+`)
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"root/blog/post.md.swp", false, true},
+		{"public/main.js~", false, true},
+		{"public/assets/.DS_Store", false, true},
+		{"root/blog/post.md", false, false},
+
+		{"root/_drafts", true, true},
+		{"root/_drafts/post.md", false, true},
+		{"root/_drafts", false, false},
+
+		{"public/raw", true, true},
+		{"public/raw/logo.svg", false, true},
+		{"public/assets/raw/logo.svg", false, false},
+
+		{"design/logo.psd", false, true},
+		{"design/a/b/logo.psd", false, true},
+		{"design/keep/logo.psd", false, false},
+
+		{"root/notes.md", false, true},
+		{"root/blog/2024/notes.md", false, true},
+
+		{"#hash", false, true},
+		{"file1.txt", false, true},
+		{"file10.txt", false, false},
+		{"b.log", false, true},
+		{"d.log", false, false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestNilMatcher(t *testing.T) {
+	var m *Matcher
+	if m.Match("anything", false) {
+		t.Error("nil Matcher matched a path")
+	}
+}
+
+func TestNoReinclude(t *testing.T) {
+	m := Parse("build/\n!build/keep.txt\n")
+	if !m.Match("build/keep.txt", false) {
+		t.Error("file in an ignored directory was re-included")
+	}
+}
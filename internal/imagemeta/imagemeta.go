@@ -0,0 +1,299 @@
+// Package imagemeta removes privacy sensitive metadata (EXIF, XMP, IPTC, text chunks)
+// from JPEG, PNG and WebP files without re-encoding the image data.
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+var ErrMalformed = errors.New("imagemeta: malformed image")
+
+// Tags maps the EXIF tag names accepted by Strip to their IFD0 tag IDs.
+var Tags = map[string]uint16{
+	"ImageDescription": 0x010E,
+	"Make":             0x010F,
+	"Model":            0x0110,
+	"Software":         0x0131,
+	"DateTime":         0x0132,
+	"Artist":           0x013B,
+	"Copyright":        0x8298,
+}
+
+// pngKeywords maps EXIF tag names to the equivalent PNG text keywords.
+var pngKeywords = map[string]string{
+	"ImageDescription": "Description",
+	"Software":         "Software",
+	"Artist":           "Author",
+	"Copyright":        "Copyright",
+}
+
+const tagOrientation = 0x0112
+
+// Strip returns data without metadata, except for the named EXIF tags in keep
+// (see Tags) and the orientation, which affects how the image is displayed.
+// Data in other formats is returned unchanged.
+func Strip(data []byte, keep []string) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return stripJPEG(data, keep)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return stripPNG(data, keep)
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return stripWebP(data, keep)
+	}
+	return data, nil
+}
+
+var exifHeader = []byte("Exif\x00\x00")
+
+func stripJPEG(data []byte, keep []string) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+
+	i := 2
+	for {
+		if i+4 > len(data) || data[i] != 0xFF {
+			return nil, ErrMalformed
+		}
+		marker := data[i+1]
+		if marker == 0xFF { // fill byte
+			i++
+			continue
+		}
+		if marker == 0xDA { // start of scan: the rest is image data
+			return append(out, data[i:]...), nil
+		}
+		if marker == 0xD9 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 {
+			out = append(out, data[i:i+2]...)
+			i += 2
+			continue
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, ErrMalformed
+		}
+		payload := data[i+4 : end]
+
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(payload, exifHeader):
+			exif, err := filterEXIF(payload[len(exifHeader):], keep)
+			if err != nil {
+				return nil, err
+			}
+			if exif != nil {
+				seg := append(append([]byte(nil), exifHeader...), exif...)
+				out = append(out, 0xFF, 0xE1, byte((len(seg)+2)>>8), byte(len(seg)+2))
+				out = append(out, seg...)
+			}
+		case marker == 0xE1 || marker == 0xED || marker == 0xFE:
+			// XMP, Photoshop/IPTC and comments
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+}
+
+// pngDropped lists chunks that carry metadata. Text chunks are kept when their keyword is allowed.
+var pngDropped = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+func stripPNG(data []byte, keep []string) ([]byte, error) {
+	keywords := make(map[string]bool)
+	for _, name := range keep {
+		if kw, ok := pngKeywords[name]; ok {
+			keywords[kw] = true
+		}
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:8]...)
+	for i := 8; i < len(data); {
+		if i+12 > len(data) {
+			return nil, ErrMalformed
+		}
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, ErrMalformed
+		}
+		typ := string(data[i+4 : i+8])
+		body := data[i+8 : i+8+length]
+
+		switch {
+		case typ == "eXIf":
+			exif, err := filterEXIF(body, keep)
+			if err != nil {
+				return nil, err
+			}
+			if exif != nil {
+				out = appendPNGChunk(out, typ, exif)
+			}
+		case pngDropped[typ]:
+			kw, _, _ := bytes.Cut(body, []byte{0})
+			if typ != "tIME" && keywords[string(kw)] {
+				out = append(out, data[i:end]...)
+			}
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
+
+func appendPNGChunk(out []byte, typ string, body []byte) []byte {
+	out = binary.BigEndian.AppendUint32(out, uint32(len(body)))
+	start := len(out)
+	out = append(out, typ...)
+	out = append(out, body...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(out[start:]))
+}
+
+const (
+	webpFlagXMP  = 0x04
+	webpFlagEXIF = 0x08
+)
+
+func stripWebP(data []byte, keep []string) ([]byte, error) {
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+
+	var flags byte
+	vp8x := -1
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, ErrMalformed
+		}
+		typ := string(data[i : i+4])
+		length := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + length + length&1
+		if length < 0 || i+8+length > len(data) {
+			return nil, ErrMalformed
+		}
+		if end > len(data) {
+			end = len(data)
+		}
+		body := data[i+8 : i+8+length]
+
+		switch typ {
+		case "EXIF":
+			exif, err := filterEXIF(bytes.TrimPrefix(body, exifHeader), keep)
+			if err != nil {
+				return nil, err
+			}
+			if exif != nil {
+				flags |= webpFlagEXIF
+				out = append(out, "EXIF"...)
+				out = binary.LittleEndian.AppendUint32(out, uint32(len(exif)))
+				out = append(out, exif...)
+				if len(exif)&1 == 1 {
+					out = append(out, 0)
+				}
+			}
+		case "XMP ":
+		case "VP8X":
+			vp8x = len(out)
+			out = append(out, data[i:end]...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+
+	if vp8x >= 0 && vp8x+8 < len(out) {
+		out[vp8x+8] = out[vp8x+8]&^(webpFlagXMP|webpFlagEXIF) | flags
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
+
+// typeSizes are the byte sizes of the TIFF field types.
+var typeSizes = [...]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+// filterEXIF rebuilds a TIFF structure holding only the allowed IFD0 tags.
+// Sub-IFDs (Exif, GPS, interoperability) and the thumbnail IFD are dropped.
+// It returns nil if no tag is left.
+func filterEXIF(tiff []byte, keep []string) ([]byte, error) {
+	if len(tiff) < 8 {
+		return nil, ErrMalformed
+	}
+	var bo interface {
+		binary.ByteOrder
+		binary.AppendByteOrder
+	}
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return nil, ErrMalformed
+	}
+
+	allowed := map[uint16]bool{tagOrientation: true}
+	for _, name := range keep {
+		if tag, ok := Tags[name]; ok {
+			allowed[tag] = true
+		}
+	}
+
+	ifd := int(bo.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return nil, ErrMalformed
+	}
+	n := int(bo.Uint16(tiff[ifd:]))
+	if ifd+2+12*n > len(tiff) {
+		return nil, ErrMalformed
+	}
+
+	type field struct {
+		entry []byte
+		value []byte // out-of-line value, nil if it fits in the entry
+	}
+	var fields []field
+	for k := 0; k < n; k++ {
+		entry := tiff[ifd+2+12*k : ifd+14+12*k]
+		tag, typ, count := bo.Uint16(entry), bo.Uint16(entry[2:]), int(bo.Uint32(entry[4:]))
+		if !allowed[tag] || int(typ) >= len(typeSizes) || typeSizes[typ] == 0 {
+			continue
+		}
+		size := typeSizes[typ] * count
+		f := field{entry: entry}
+		if size > 4 {
+			off := int(bo.Uint32(entry[8:]))
+			if count < 0 || off < 0 || off+size > len(tiff) {
+				return nil, ErrMalformed
+			}
+			f.value = tiff[off : off+size]
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	out := make([]byte, 8, 64)
+	copy(out, tiff[:4])
+	bo.PutUint32(out[4:], 8)
+	out = bo.AppendUint16(out, uint16(len(fields)))
+	dataOff := len(out) + 12*len(fields) + 4
+	var values []byte
+	for _, f := range fields {
+		entry := append([]byte(nil), f.entry...)
+		if f.value != nil {
+			bo.PutUint32(entry[8:], uint32(dataOff+len(values)))
+			values = append(values, f.value...)
+			if len(values)&1 == 1 {
+				values = append(values, 0) // values start on word boundaries
+			}
+		}
+		out = append(out, entry...)
+	}
+	out = bo.AppendUint32(out, 0) // no next IFD
+	return append(out, values...), nil
+}
@@ -0,0 +1,121 @@
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testEXIF builds a little-endian TIFF structure with Artist, Copyright and a GPS IFD pointer.
+func testEXIF() []byte {
+	bo := binary.LittleEndian
+	artist := []byte("John Roe\x00")
+	copyright := []byte("(c) Jane Doe\x00")
+
+	b := []byte("II\x2a\x00")
+	b = bo.AppendUint32(b, 8)
+	b = bo.AppendUint16(b, 3)
+	dataOff := 8 + 2 + 12*3 + 4
+
+	entry := func(tag, typ uint16, count, value uint32) {
+		b = bo.AppendUint16(b, tag)
+		b = bo.AppendUint16(b, typ)
+		b = bo.AppendUint32(b, count)
+		b = bo.AppendUint32(b, value)
+	}
+	entry(0x013B, 2, uint32(len(artist)), uint32(dataOff))
+	entry(0x8298, 2, uint32(len(copyright)), uint32(dataOff+len(artist)))
+	entry(0x8825, 4, 1, 0) // GPS IFD pointer
+	b = bo.AppendUint32(b, 0)
+	b = append(b, artist...)
+	return append(b, copyright...)
+}
+
+func testImage() image.Image {
+	return image.NewGray(image.Rect(0, 0, 4, 4))
+}
+
+func TestStripJPEG(t *testing.T) {
+	var enc bytes.Buffer
+	if err := jpeg.Encode(&enc, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	seg := append([]byte("Exif\x00\x00"), testEXIF()...)
+	xmp := []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")
+	var data []byte
+	data = append(data, enc.Bytes()[:2]...)
+	data = append(data, 0xFF, 0xE1, byte((len(seg)+2)>>8), byte(len(seg)+2))
+	data = append(data, seg...)
+	data = append(data, 0xFF, 0xE1, byte((len(xmp)+2)>>8), byte(len(xmp)+2))
+	data = append(data, xmp...)
+	data = append(data, enc.Bytes()[2:]...)
+
+	out, err := Strip(data, []string{"Copyright"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("John Roe")) {
+		t.Error("Artist was not removed")
+	}
+	if !bytes.Contains(out, []byte("(c) Jane Doe")) {
+		t.Error("Copyright was removed")
+	}
+	if bytes.Contains(out, []byte("xmpmeta")) {
+		t.Error("XMP was not removed")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("stripped JPEG does not decode: %v", err)
+	}
+
+	out, err = Strip(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("Exif")) {
+		t.Error("EXIF segment was not removed")
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	var enc bytes.Buffer
+	if err := png.Encode(&enc, testImage()); err != nil {
+		t.Fatal(err)
+	}
+
+	ihdrEnd := 8 + 12 + 13
+	var data []byte
+	data = append(data, enc.Bytes()[:ihdrEnd]...)
+	data = appendPNGChunk(data, "tEXt", []byte("Copyright\x00(c) Jane Doe"))
+	data = appendPNGChunk(data, "tEXt", []byte("Comment\x00taken at home"))
+	data = appendPNGChunk(data, "eXIf", testEXIF())
+	data = append(data, enc.Bytes()[ihdrEnd:]...)
+
+	out, err := Strip(data, []string{"Copyright"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("taken at home")) {
+		t.Error("Comment was not removed")
+	}
+	if bytes.Count(out, []byte("(c) Jane Doe")) != 2 {
+		t.Error("Copyright text and EXIF tag should both be kept")
+	}
+	if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("stripped PNG does not decode: %v", err)
+	}
+}
+
+func TestStripUnknown(t *testing.T) {
+	data := []byte("<svg></svg>")
+	out, err := Strip(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Error("unknown format was modified")
+	}
+}
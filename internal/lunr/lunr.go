@@ -0,0 +1,297 @@
+// Package lunr builds prebuilt Lunr search indexes, which lunr.js loads with
+// lunr.Index.load without indexing the documents in the browser.
+//
+// Terms are produced like the default lunr.Builder pipeline of the language:
+// lowercased, split on whitespace and hyphens, trimmed, stop words removed for
+// English, and stemmed with the Snowball stemmer lunr.js (English) or
+// lunr-languages (other languages) stems queries with. Languages without a
+// stemmer are indexed unstemmed, with an empty search pipeline, and Chinese
+// and Japanese text is only split at whitespace, like lunr.tokenizer does.
+package lunr
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/blevesearch/snowballstem"
+	"github.com/blevesearch/snowballstem/dutch"
+	"github.com/blevesearch/snowballstem/finnish"
+	"github.com/blevesearch/snowballstem/french"
+	"github.com/blevesearch/snowballstem/german"
+	"github.com/blevesearch/snowballstem/hungarian"
+	"github.com/blevesearch/snowballstem/italian"
+	"github.com/blevesearch/snowballstem/porter"
+	"github.com/blevesearch/snowballstem/portuguese"
+	"github.com/blevesearch/snowballstem/romanian"
+	"github.com/blevesearch/snowballstem/russian"
+	"github.com/blevesearch/snowballstem/spanish"
+	"github.com/blevesearch/snowballstem/swedish"
+	"github.com/blevesearch/snowballstem/turkish"
+)
+
+// Version is the lunr.js version of the serialized index format.
+const Version = "2.3.9"
+
+// BM25 parameters of lunr.Builder.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// stemmers are the Snowball stemmers by language. lunr.js stems English with
+// the original Porter algorithm, the other languages are those of
+// lunr-languages.
+var stemmers = map[string]func(*snowballstem.Env) bool{
+	"en": porter.Stem,
+	"de": german.Stem,
+	"es": spanish.Stem,
+	"fi": finnish.Stem,
+	"fr": french.Stem,
+	"hu": hungarian.Stem,
+	"it": italian.Stem,
+	"nl": dutch.Stem,
+	"pt": portuguese.Stem,
+	"ro": romanian.Stem,
+	"ru": russian.Stem,
+	"sv": swedish.Stem,
+	"tr": turkish.Stem,
+}
+
+// stopWords are the words lunr.stopWordFilter leaves out of English indexes.
+var stopWords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.Fields(`a able about across after all almost also am among an and any are as at
+		be because been but by can cannot could dear did do does either else ever every for from get got
+		had has have he her hers him his how however i if in into is it its just least let like likely
+		may me might most must my neither no nor not of off often on only or other our own rather said
+		say says she should since so some than that the their them then there these they this tis to too
+		twas us wants was we were what when where which while who whom why will with would yet you your`) {
+		words[w] = true
+	}
+	return words
+}()
+
+// Pipeline returns the search pipeline of the indexes of lang, the names of
+// the registered lunr.Pipeline functions queries are run through.
+func Pipeline(lang string) []string {
+	switch {
+	case lang == "en":
+		return []string{"stemmer"}
+	case stemmers[lang] != nil:
+		// registered by lunr-languages
+		return []string{"stemmer-" + lang}
+	}
+	return []string{}
+}
+
+// Stemmed reports whether the indexes of lang are stemmed.
+func Stemmed(lang string) bool {
+	return stemmers[lang] != nil
+}
+
+// Field is a field of the indexed documents.
+type Field struct {
+	Name string
+	// Boost multiplies the scores of matches in the field, 1 if zero.
+	Boost float64
+}
+
+// Builder indexes documents of one language.
+type Builder struct {
+	lang   string
+	fields []Field
+	stem   func(*snowballstem.Env) bool
+	env    *snowballstem.Env
+
+	// terms in the order they were first seen, which gives their vector index
+	terms []string
+	// postings maps a term to its index and, by field, the documents it is in
+	postings map[string]*posting
+	// refs are the field references of the documents in the order they
+	// were added, with the frequencies of their terms and their lengths
+	refs        []string
+	frequencies map[string]map[string]int
+	lengths     map[string]int
+	docs        int
+}
+
+type posting struct {
+	index  int
+	fields map[string]map[string]bool
+}
+
+// NewBuilder returns a builder of an index of documents in lang with fields.
+func NewBuilder(lang string, fields ...Field) *Builder {
+	return &Builder{
+		lang:        lang,
+		fields:      fields,
+		stem:        stemmers[lang],
+		env:         snowballstem.NewEnv(""),
+		postings:    make(map[string]*posting),
+		frequencies: make(map[string]map[string]int),
+		lengths:     make(map[string]int),
+	}
+}
+
+// Terms returns the indexed terms of text.
+func (b *Builder) Terms(text string) []string {
+	var terms []string
+	for _, token := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-'
+	}) {
+		// lunr-languages trim to the word characters of the language
+		token = strings.TrimFunc(token, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r)
+		})
+		if token == "" || b.lang == "en" && stopWords[token] {
+			continue
+		}
+		// lunr.stemmer leaves words shorter than 3 characters alone
+		if b.stem != nil && (b.lang != "en" || len(utf16.Encode([]rune(token))) >= 3) {
+			b.env.SetCurrent(token)
+			b.stem(b.env)
+			token = b.env.Current()
+		}
+		if token != "" {
+			terms = append(terms, token)
+		}
+	}
+	return terms
+}
+
+// Add indexes the document of ref, with the values of its fields in the order
+// they were given to NewBuilder.
+func (b *Builder) Add(ref string, values ...string) {
+	b.docs++
+	for i, f := range b.fields {
+		var terms []string
+		if i < len(values) {
+			terms = b.Terms(values[i])
+		}
+		fieldRef := f.Name + "/" + ref
+		freq := make(map[string]int)
+		b.refs = append(b.refs, fieldRef)
+		b.frequencies[fieldRef] = freq
+		b.lengths[fieldRef] = len(terms)
+
+		for _, term := range terms {
+			freq[term]++
+			p, ok := b.postings[term]
+			if !ok {
+				p = &posting{index: len(b.terms), fields: make(map[string]map[string]bool)}
+				for _, g := range b.fields {
+					p.fields[g.Name] = make(map[string]bool)
+				}
+				b.postings[term] = p
+				b.terms = append(b.terms, term)
+			}
+			p.fields[f.Name][ref] = true
+		}
+	}
+}
+
+// Index is a serialized lunr.Index.
+type Index struct {
+	Version       string   `json:"version"`
+	Fields        []string `json:"fields"`
+	FieldVectors  []any    `json:"fieldVectors"`
+	InvertedIndex []any    `json:"invertedIndex"`
+	Pipeline      []string `json:"pipeline"`
+}
+
+// Build returns the index of the added documents.
+func (b *Builder) Build() *Index {
+	idx := &Index{
+		Version:       Version,
+		FieldVectors:  make([]any, 0, len(b.refs)),
+		InvertedIndex: make([]any, 0, len(b.terms)),
+		Pipeline:      Pipeline(b.lang),
+	}
+
+	boosts := make(map[string]float64)
+	average := make(map[string]float64)
+	for _, f := range b.fields {
+		idx.Fields = append(idx.Fields, f.Name)
+		boosts[f.Name] = f.Boost
+		if f.Boost == 0 {
+			boosts[f.Name] = 1
+		}
+		var total int
+		for _, ref := range b.refs {
+			if name, _, _ := strings.Cut(ref, "/"); name == f.Name {
+				total += b.lengths[ref]
+			}
+		}
+		if b.docs > 0 {
+			average[f.Name] = float64(total) / float64(b.docs)
+		}
+	}
+
+	idf := make(map[string]float64)
+	for _, fieldRef := range b.refs {
+		name, _, _ := strings.Cut(fieldRef, "/")
+		length := float64(b.lengths[fieldRef])
+		var vector []float64
+		terms := make([]string, 0, len(b.frequencies[fieldRef]))
+		for term := range b.frequencies[fieldRef] {
+			terms = append(terms, term)
+		}
+		// vectors are sorted by term index
+		sort.Slice(terms, func(i, j int) bool {
+			return b.postings[terms[i]].index < b.postings[terms[j]].index
+		})
+		for _, term := range terms {
+			p := b.postings[term]
+			w, ok := idf[term]
+			if !ok {
+				var with int
+				for _, docs := range p.fields {
+					with += len(docs)
+				}
+				w = math.Log(1 + math.Abs((float64(b.docs)-float64(with)+0.5)/(float64(with)+0.5)))
+				idf[term] = w
+			}
+			tf := float64(b.frequencies[fieldRef][term])
+			score := w * ((bm25K1 + 1) * tf) / (bm25K1*(1-bm25B+bm25B*(length/average[name])) + tf)
+			score *= boosts[name]
+			vector = append(vector, float64(p.index), math.Floor(score*1000+0.5)/1000)
+		}
+		if vector == nil {
+			vector = []float64{}
+		}
+		idx.FieldVectors = append(idx.FieldVectors, []any{fieldRef, vector})
+	}
+
+	// lunr.TokenSet.fromArray needs the terms in JavaScript string order
+	terms := append([]string(nil), b.terms...)
+	sort.Slice(terms, func(i, j int) bool {
+		return jsLess(terms[i], terms[j])
+	})
+	for _, term := range terms {
+		p := b.postings[term]
+		entry := map[string]any{"_index": p.index}
+		for name, docs := range p.fields {
+			refs := make(map[string]struct{}, len(docs))
+			for ref := range docs {
+				refs[ref] = struct{}{}
+			}
+			entry[name] = refs
+		}
+		idx.InvertedIndex = append(idx.InvertedIndex, []any{term, entry})
+	}
+	return idx
+}
+
+// jsLess compares strings by UTF-16 code units, like Array.prototype.sort.
+func jsLess(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
@@ -0,0 +1,68 @@
+package lunr
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		lang string
+		text string
+		want []string
+	}{
+		{"en", "The goroutines are running, and it is fine-grained.", []string{"goroutin", "run", "fine", "grain"}},
+		{"en", "Go is an open-source language", []string{"go", "open", "sourc", "languag"}},
+		{"de", "Die Häuser werden gebaut.", []string{"die", "haus", "werd", "gebaut"}},
+		{"ko", "고루틴은 가볍다 (Go)", []string{"고루틴은", "가볍다", "go"}},
+	}
+	for _, tt := range tests {
+		if got := NewBuilder(tt.lang).Terms(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("Terms(%q, %q) = %q, want %q", tt.lang, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	b := NewBuilder("en", Field{Name: "title", Boost: 10}, Field{Name: "body"})
+	b.Add("/a", "Goroutines", "Goroutines are cheap threads.")
+	b.Add("/b", "Channels", "Channels connect goroutines.")
+	idx := b.Build()
+
+	if !slices.Equal(idx.Fields, []string{"title", "body"}) || !slices.Equal(idx.Pipeline, []string{"stemmer"}) {
+		t.Errorf("fields %q and pipeline %q, want [title body] and [stemmer]", idx.Fields, idx.Pipeline)
+	}
+	if len(idx.FieldVectors) != 4 {
+		t.Fatalf("%d field vectors, want 4", len(idx.FieldVectors))
+	}
+
+	var terms []string
+	for _, entry := range idx.InvertedIndex {
+		terms = append(terms, entry.([]any)[0].(string))
+	}
+	if want := []string{"channel", "cheap", "connect", "goroutin", "thread"}; !slices.Equal(terms, want) {
+		t.Errorf("terms = %q, want %q", terms, want)
+	}
+
+	data, err := json.Marshal(idx.InvertedIndex[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `["goroutin",{"_index":0,"body":{"/a":{},"/b":{}},"title":{"/a":{}}}]`; string(data) != want {
+		t.Errorf("posting = %s, want %s", data, want)
+	}
+
+	// the boosted title match scores above the body matches
+	vector := func(i int) []float64 { return idx.FieldVectors[i].([]any)[1].([]float64) }
+	if title, body := vector(0), vector(1); title[0] != 0 || body[0] != 0 || title[1] <= body[1] {
+		t.Errorf("title vector %v and body vector %v of /a, want a higher score of term 0 in the title", title, body)
+	}
+}
+
+func TestJSLess(t *testing.T) {
+	// U+1F600 sorts after U+FF21 in UTF-8, before it in UTF-16
+	if !jsLess("\U0001f600", "Ａ") || jsLess("b", "a") || !jsLess("a", "ab") {
+		t.Error("jsLess does not compare by UTF-16 code units")
+	}
+}
@@ -0,0 +1,179 @@
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// AdmonitionTitles are the default titles of the admonition variants, which
+// pages localize when they are generated.
+var AdmonitionTitles = map[string]string{
+	"note":      "Note",
+	"tip":       "Tip",
+	"important": "Important",
+	"warning":   "Warning",
+	"caution":   "Caution",
+}
+
+// Admonition is a callout of a variant of AdmonitionTitles, written as a GitHub
+// alert, a blockquote starting with a `[!NOTE]` line, or as a `:::note` container
+// closed by a `:::` line, which can have a title: `:::warning Breaking change`.
+type Admonition struct {
+	ast.BaseBlock
+	Variant string
+	Title   string
+}
+
+var KindAdmonition = ast.NewNodeKind("Admonition")
+
+func (n *Admonition) Kind() ast.NodeKind {
+	return KindAdmonition
+}
+
+func (n *Admonition) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Variant": n.Variant, "Title": n.Title}, nil)
+}
+
+var (
+	alertRe      = regexp.MustCompile(`^\[!([A-Za-z]+)\]\s*$`)
+	admonitionRe = regexp.MustCompile(`^:::\s*([A-Za-z]+)(?:\s+(.*?))?\s*$`)
+)
+
+type admonitionParser struct{}
+
+func (p *admonitionParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+func (p *admonitionParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	m := admonitionRe.FindSubmatch(bytes.TrimRight(line, "\r\n"))
+	if m == nil {
+		return nil, parser.NoChildren
+	}
+	typ := strings.ToLower(string(m[1]))
+	if _, ok := AdmonitionTitles[typ]; !ok {
+		return nil, parser.NoChildren
+	}
+	reader.Advance(segment.Len() - newline(line))
+	return &Admonition{Variant: typ, Title: string(m[2])}, parser.HasChildren
+}
+
+func (p *admonitionParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	if closesContainer(node, line, pc) {
+		// leaving the line ending, the paragraphs of the admonition see a
+		// blank line, and do not take the next line as a lazy continuation
+		reader.Advance(segment.Len() - newline(line))
+		return parser.Close
+	}
+	return parser.Continue | parser.HasChildren
+}
+
+// newline returns the length of the line ending of line.
+func newline(line []byte) int {
+	if bytes.HasSuffix(line, []byte("\n")) {
+		return 1
+	}
+	return 0
+}
+
+func (p *admonitionParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *admonitionParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *admonitionParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// alertTransformer replaces the blockquotes starting with a `[!NOTE]` line by
+// admonitions.
+type alertTransformer struct{}
+
+func (t *alertTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var quotes []*ast.Blockquote
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if q, ok := n.(*ast.Blockquote); ok && entering {
+			quotes = append(quotes, q)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, q := range quotes {
+		para, ok := q.FirstChild().(*ast.Paragraph)
+		if !ok || para.Lines().Len() == 0 {
+			continue
+		}
+		first := para.Lines().At(0)
+		m := alertRe.FindSubmatch(first.Value(source))
+		if m == nil {
+			continue
+		}
+		typ := strings.ToLower(string(m[1]))
+		if _, ok := AdmonitionTitles[typ]; !ok {
+			continue
+		}
+
+		// the inlines of the marker line
+		for c := para.FirstChild(); c != nil; {
+			next := c.NextSibling()
+			if t, ok := c.(*ast.Text); !ok || t.Segment.Start >= first.Stop {
+				break
+			}
+			para.RemoveChild(para, c)
+			c = next
+		}
+		if !para.HasChildren() {
+			q.RemoveChild(q, para)
+		}
+
+		a := &Admonition{Variant: typ}
+		for c := q.FirstChild(); c != nil; {
+			next := c.NextSibling()
+			a.AppendChild(a, c)
+			c = next
+		}
+		q.Parent().ReplaceChild(q.Parent(), q, a)
+	}
+}
+
+type admonitionRenderer struct{}
+
+func (r *admonitionRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindAdmonition, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		n := node.(*Admonition)
+		if !entering {
+			w.WriteString("</aside>\n")
+			return ast.WalkContinue, nil
+		}
+		title := n.Title
+		if title == "" {
+			title = AdmonitionTitles[n.Variant]
+		}
+		w.WriteString(`<aside class="admonition admonition-` + n.Variant + `" role="note">` + "\n")
+		w.WriteString(`<p class="admonition-title">` + html.EscapeString(title) + "</p>\n")
+		return ast.WalkContinue, nil
+	})
+}
+
+type admonitionExtension struct{}
+
+func (e *admonitionExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&admonitionParser{}, 50)),
+		parser.WithASTTransformers(util.Prioritized(&alertTransformer{}, 100)),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&admonitionRenderer{}, 50)))
+}
@@ -0,0 +1,81 @@
+package markdown
+
+import "testing"
+
+func TestAdmonition(t *testing.T) {
+	testMarkdown(t, []struct{ name, md, want string }{
+		{
+			name: "container",
+			md:   ":::note\nhello\n:::\nafter\n",
+			want: `<aside class="admonition admonition-note" role="note">
+<p class="admonition-title">Note</p>
+<p>hello</p>
+</aside>
+<p>after</p>
+`,
+		},
+		{
+			name: "title",
+			md:   ":::WARNING Breaking <change>\n- a\n- b\n:::\n",
+			want: `<aside class="admonition admonition-warning" role="note">
+<p class="admonition-title">Breaking &lt;change&gt;</p>
+<ul>
+<li>a</li>
+<li>b</li>
+</ul>
+</aside>
+`,
+		},
+		{
+			name: "alert",
+			md:   "> [!TIP]\n> hi **there**\n",
+			want: `<aside class="admonition admonition-tip" role="note">
+<p class="admonition-title">Tip</p>
+<p>hi <strong>there</strong></p>
+</aside>
+`,
+		},
+		{
+			name: "unknown variant",
+			md:   ":::bogus\nx\n:::\n\n> [!BOGUS]\n> y\n",
+			want: "<p>:::bogus\nx\n:::</p>\n<blockquote>\n<p>[!BOGUS]\ny</p>\n</blockquote>\n",
+		},
+		{
+			name: "nested",
+			md:   ":::note\n:::caution\ninner\n:::\nouter\n:::\n",
+			want: `<aside class="admonition admonition-note" role="note">
+<p class="admonition-title">Note</p>
+<aside class="admonition admonition-caution" role="note">
+<p class="admonition-title">Caution</p>
+<p>inner</p>
+</aside>
+<p>outer</p>
+</aside>
+`,
+		},
+		{
+			name: "unterminated",
+			md:   ":::important\nhello\n\nstill\n",
+			want: `<aside class="admonition admonition-important" role="note">
+<p class="admonition-title">Important</p>
+<p>hello</p>
+<p>still</p>
+</aside>
+`,
+		},
+		{
+			name: "in a code block",
+			md:   "```\n:::note\nx\n:::\n```\n",
+			want: codeBlock(":::note", "x", ":::"),
+		},
+		{
+			name: "code block in an admonition",
+			md:   ":::note\n```\n:::\n```\nafter\n:::\n",
+			want: `<aside class="admonition admonition-note" role="note">
+<p class="admonition-title">Note</p>
+` + codeBlock(":::") + `<p>after</p>
+</aside>
+`,
+		},
+	})
+}
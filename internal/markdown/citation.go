@@ -0,0 +1,135 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Cite is a work cited by a citation, with an optional locator such as
+// "p. 33".
+type Cite struct {
+	Key     string
+	Locator string
+}
+
+// Citation is an inline `[@key]` citation of the works of the bibliography
+// of a post, written like in Pandoc: `[@doe99, p. 33; @roe04]`. Citations are
+// rendered as placeholder comments, numbered and linked to the references of
+// the page when it is generated.
+type Citation struct {
+	ast.BaseInline
+	Cites []Cite
+}
+
+var KindCitation = ast.NewNodeKind("Citation")
+
+func (n *Citation) Kind() ast.NodeKind {
+	return KindCitation
+}
+
+func (n *Citation) Dump(source []byte, level int) {
+	keys := make([]string, len(n.Cites))
+	for i, c := range n.Cites {
+		keys[i] = c.Key
+	}
+	ast.DumpHelper(n, source, level, map[string]string{"Keys": strings.Join(keys, ";")}, nil)
+}
+
+// citeRe matches a cite of a citation. Trailing punctuation is not part of the
+// key.
+var citeRe = regexp.MustCompile(`^\s*@(\w(?:[\w:.#$%&+?<>~/-]*\w)?)\s*(?:,\s*(.*?))?\s*$`)
+
+const citationPrefix = "cite:"
+
+// CitationComment returns the placeholder comment of a citation.
+func CitationComment(cites []Cite) string {
+	items := make([]string, len(cites))
+	for i, c := range cites {
+		items[i] = c.Key
+		if c.Locator != "" {
+			items[i] += "," + c.Locator
+		}
+	}
+	return "<!--" + citationPrefix + strings.Join(items, ";") + "-->"
+}
+
+// ParseCitationComment parses the data of a placeholder comment.
+func ParseCitationComment(data string) ([]Cite, bool) {
+	rest, ok := strings.CutPrefix(data, citationPrefix)
+	if !ok {
+		return nil, false
+	}
+	var cites []Cite
+	for _, item := range strings.Split(rest, ";") {
+		key, locator, _ := strings.Cut(item, ",")
+		cites = append(cites, Cite{Key: key, Locator: locator})
+	}
+	return cites, true
+}
+
+// parseCites parses the text between the brackets of a citation.
+func parseCites(s string) ([]Cite, bool) {
+	var cites []Cite
+	for _, item := range strings.Split(s, ";") {
+		m := citeRe.FindStringSubmatch(item)
+		if m == nil {
+			return nil, false
+		}
+		// "--" would end the placeholder comment
+		cites = append(cites, Cite{Key: m[1], Locator: strings.ReplaceAll(m[2], "--", "–")})
+	}
+	return cites, true
+}
+
+type citationParser struct{}
+
+func (p *citationParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (p *citationParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if len(line) < 3 || line[1] != '@' {
+		return nil
+	}
+	end := strings.IndexAny(string(line), "]\n")
+	if end < 0 || line[end] != ']' {
+		return nil
+	}
+	// links and link references such as [@user](https://...) stay links
+	if end+1 < len(line) && (line[end+1] == '(' || line[end+1] == '[') {
+		return nil
+	}
+	cites, ok := parseCites(string(line[1:end]))
+	if !ok {
+		return nil
+	}
+	block.Advance(end + 1)
+	return &Citation{Cites: cites}
+}
+
+type citationRenderer struct{}
+
+func (r *citationRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindCitation, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			w.WriteString(CitationComment(node.(*Citation).Cites))
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+type citationExtension struct{}
+
+func (e *citationExtension) Extend(m goldmark.Markdown) {
+	// before the link parser, which also starts at '['
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(&citationParser{}, 150)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&citationRenderer{}, 50)))
+}
@@ -0,0 +1,102 @@
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// DetailsSummary is the default summary of details blocks, which pages
+// localize when they are generated.
+const DetailsSummary = "Details"
+
+// Details is a collapsed block, such as a long log, written as a `:::details`
+// container with its summary, closed by a `:::` line:
+//
+//	:::details Full build log
+//	...
+//	:::
+type Details struct {
+	ast.BaseBlock
+	Summary string
+}
+
+var KindDetails = ast.NewNodeKind("Details")
+
+func (n *Details) Kind() ast.NodeKind {
+	return KindDetails
+}
+
+func (n *Details) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Summary": n.Summary}, nil)
+}
+
+var detailsRe = regexp.MustCompile(`^:::\s*details(?:\s+(.*?))?\s*$`)
+
+type detailsParser struct{}
+
+func (p *detailsParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+func (p *detailsParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	m := detailsRe.FindSubmatch(bytes.TrimRight(line, "\r\n"))
+	if m == nil {
+		return nil, parser.NoChildren
+	}
+	reader.Advance(segment.Len() - newline(line))
+	return &Details{Summary: string(m[1])}, parser.HasChildren
+}
+
+func (p *detailsParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	if closesContainer(node, line, pc) {
+		reader.Advance(segment.Len() - newline(line))
+		return parser.Close
+	}
+	return parser.Continue | parser.HasChildren
+}
+
+func (p *detailsParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *detailsParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *detailsParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+type detailsRenderer struct{}
+
+func (r *detailsRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindDetails, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		n := node.(*Details)
+		if !entering {
+			w.WriteString("</details>\n")
+			return ast.WalkContinue, nil
+		}
+		summary := n.Summary
+		if summary == "" {
+			summary = DetailsSummary
+		}
+		w.WriteString(`<details class="details">` + "\n")
+		w.WriteString(`<summary class="details-summary">` + html.EscapeString(summary) + "</summary>\n")
+		return ast.WalkContinue, nil
+	})
+}
+
+type detailsExtension struct{}
+
+func (e *detailsExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithBlockParsers(util.Prioritized(&detailsParser{}, 50)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&detailsRenderer{}, 50)))
+}
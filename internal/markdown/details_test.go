@@ -0,0 +1,64 @@
+package markdown
+
+import "testing"
+
+func TestDetails(t *testing.T) {
+	testMarkdown(t, []struct{ name, md, want string }{
+		{
+			name: "details",
+			md:   ":::details Full <build> log\nok\n:::\nafter\n",
+			want: `<details class="details">
+<summary class="details-summary">Full &lt;build&gt; log</summary>
+<p>ok</p>
+</details>
+<p>after</p>
+`,
+		},
+		{
+			name: "default summary",
+			md:   ":::details\nok\n:::\n",
+			want: `<details class="details">
+<summary class="details-summary">Details</summary>
+<p>ok</p>
+</details>
+`,
+		},
+		{
+			name: "nested",
+			md:   ":::details Outer\n:::details Inner\ninner\n:::\nouter\n:::\n",
+			want: `<details class="details">
+<summary class="details-summary">Outer</summary>
+<details class="details">
+<summary class="details-summary">Inner</summary>
+<p>inner</p>
+</details>
+<p>outer</p>
+</details>
+`,
+		},
+		{
+			name: "unterminated",
+			md:   ":::details\nhello\n\nstill\n",
+			want: `<details class="details">
+<summary class="details-summary">Details</summary>
+<p>hello</p>
+<p>still</p>
+</details>
+`,
+		},
+		{
+			name: "in a code block",
+			md:   "```\n:::details\nx\n:::\n```\n",
+			want: codeBlock(":::details", "x", ":::"),
+		},
+		{
+			name: "code block in details",
+			md:   ":::details\n```\n:::\n```\nafter\n:::\n",
+			want: `<details class="details">
+<summary class="details-summary">Details</summary>
+` + codeBlock(":::") + `<p>after</p>
+</details>
+`,
+		},
+	})
+}
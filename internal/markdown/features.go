@@ -0,0 +1,119 @@
+package markdown
+
+import (
+	"html"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"gosuda.org/website/internal/types"
+)
+
+// ScriptBlock is a fenced code block whose content is rendered in the browser
+// by a script instead of being highlighted: a ```math or ```mermaid block.
+type ScriptBlock struct {
+	ast.BaseBlock
+	Lang   string
+	Source string
+}
+
+var KindScriptBlock = ast.NewNodeKind("ScriptBlock")
+
+func (n *ScriptBlock) Kind() ast.NodeKind {
+	return KindScriptBlock
+}
+
+func (n *ScriptBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Lang": n.Lang}, nil)
+}
+
+// scriptBlockClasses are the classes of the element each script block is
+// rendered as, which the scripts of the language look for.
+var scriptBlockClasses = map[string]string{
+	"math":    "math",
+	"mermaid": "mermaid",
+}
+
+// scriptBlockTransformer replaces the fenced code blocks of script block
+// languages before they reach the highlighter.
+type scriptBlockTransformer struct{}
+
+func (t *scriptBlockTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var blocks []*ast.FencedCodeBlock
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if b, ok := n.(*ast.FencedCodeBlock); ok && entering {
+			if _, ok := scriptBlockClasses[string(b.Language(reader.Source()))]; ok {
+				blocks = append(blocks, b)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, b := range blocks {
+		var src []byte
+		lines := b.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			src = append(src, line.Value(reader.Source())...)
+		}
+		sb := &ScriptBlock{Lang: string(b.Language(reader.Source())), Source: string(src)}
+		b.Parent().ReplaceChild(b.Parent(), b, sb)
+	}
+}
+
+type scriptBlockRenderer struct{}
+
+func (r *scriptBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindScriptBlock, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			n := node.(*ScriptBlock)
+			tag := "div"
+			if n.Lang == "mermaid" {
+				tag = "pre"
+			}
+			w.WriteString("<" + tag + ` class="` + scriptBlockClasses[n.Lang] + `">`)
+			w.WriteString(html.EscapeString(n.Source))
+			w.WriteString("</" + tag + ">\n")
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+type scriptBlockExtension struct{}
+
+func (e *scriptBlockExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(&scriptBlockTransformer{}, 100)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&scriptBlockRenderer{}, 50)))
+}
+
+// documentFeatures returns the features of the content of a parsed document.
+func documentFeatures(doc ast.Node) types.Features {
+	var f types.Features
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			f.Code = true
+		case *ScriptBlock:
+			switch n.Lang {
+			case "math":
+				f.Math = true
+			case "mermaid":
+				f.Mermaid = true
+			}
+		case *Tabs:
+			f.Tabs = true
+		case *Shortcode:
+			if n.Name == "gallery" {
+				f.Gallery = true
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return f
+}
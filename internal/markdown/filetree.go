@@ -0,0 +1,162 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// FileTree is a ```filetree block, a project layout written as an indented
+// list of paths, one per line, with optional ` # comments`. Entries ending
+// with a slash, or with entries indented under them, are folders. The lines
+// may be drawn with the box drawing characters of the output of tree(1).
+//
+//	```filetree
+//	cmd/
+//	  server/
+//	    main.go  # the entry point
+//	go.mod
+//	```
+type FileTree struct {
+	ast.BaseBlock
+	Entries []*FileTreeEntry
+}
+
+// FileTreeEntry is a file or a folder of a FileTree.
+type FileTreeEntry struct {
+	Name    string
+	Comment string
+	Entries []*FileTreeEntry
+}
+
+// Folder reports whether the entry is a folder.
+func (e *FileTreeEntry) Folder() bool {
+	return strings.HasSuffix(e.Name, "/") || len(e.Entries) > 0
+}
+
+var KindFileTree = ast.NewNodeKind("FileTree")
+
+func (n *FileTree) Kind() ast.NodeKind {
+	return KindFileTree
+}
+
+func (n *FileTree) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// parseFileTree returns the entries of the lines of a ```filetree block.
+func parseFileTree(src string) []*FileTreeEntry {
+	var root FileTreeEntry
+	type open struct {
+		indent int
+		entry  *FileTreeEntry
+	}
+	stack := []open{{-1, &root}}
+	for _, line := range strings.Split(src, "\n") {
+		// the indentation, including the lines drawn by tree(1)
+		var indent int
+		rest := strings.TrimLeftFunc(line, func(r rune) bool {
+			switch {
+			case r == '\t':
+				indent += 4
+			case r == ' ' || r == '\u00a0' || '\u2500' <= r && r <= '\u257f': // box drawing
+				indent++
+			default:
+				return false
+			}
+			return true
+		})
+		name, comment, _ := strings.Cut(rest, " #")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		entry := &FileTreeEntry{Name: name, Comment: strings.TrimSpace(comment)}
+		for stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].entry
+		parent.Entries = append(parent.Entries, entry)
+		stack = append(stack, open{indent, entry})
+	}
+	return root.Entries
+}
+
+// fileTreeTransformer replaces the ```filetree blocks before they reach the
+// highlighter.
+type fileTreeTransformer struct{}
+
+func (t *fileTreeTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var blocks []*ast.FencedCodeBlock
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if b, ok := n.(*ast.FencedCodeBlock); ok && entering && string(b.Language(reader.Source())) == "filetree" {
+			blocks = append(blocks, b)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, b := range blocks {
+		var src []byte
+		lines := b.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			src = append(src, line.Value(reader.Source())...)
+		}
+		ft := &FileTree{Entries: parseFileTree(string(src))}
+		b.Parent().ReplaceChild(b.Parent(), b, ft)
+	}
+}
+
+const (
+	folderIcon = `<svg class="filetree-icon" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round" aria-hidden="true"><path d="M22 19a2 2 0 0 1-2 2H4a2 2 0 0 1-2-2V5a2 2 0 0 1 2-2h5l2 3h9a2 2 0 0 1 2 2z"></path></svg>`
+	fileIcon   = `<svg class="filetree-icon" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round" aria-hidden="true"><path d="M13 2H6a2 2 0 0 0-2 2v16a2 2 0 0 0 2 2h12a2 2 0 0 0 2-2V9z"></path><polyline points="13 2 13 9 20 9"></polyline></svg>`
+)
+
+// writeFileTreeEntries writes entries as a list of files and folders, the
+// folders with the lists of their entries.
+func writeFileTreeEntries(w util.BufWriter, entries []*FileTreeEntry) {
+	w.WriteString("<ul>\n")
+	for _, e := range entries {
+		if e.Folder() {
+			w.WriteString(`<li class="filetree-folder">` + folderIcon)
+		} else {
+			w.WriteString(`<li class="filetree-file">` + fileIcon)
+		}
+		w.WriteString(`<code class="filetree-name">` + html.EscapeString(e.Name) + `</code>`)
+		if e.Comment != "" {
+			w.WriteString(` <span class="filetree-comment">` + html.EscapeString(e.Comment) + `</span>`)
+		}
+		if len(e.Entries) > 0 {
+			w.WriteString("\n")
+			writeFileTreeEntries(w, e.Entries)
+		}
+		w.WriteString("</li>\n")
+	}
+	w.WriteString("</ul>\n")
+}
+
+type fileTreeRenderer struct{}
+
+func (r *fileTreeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindFileTree, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			w.WriteString(`<div class="filetree not-prose">` + "\n")
+			writeFileTreeEntries(w, node.(*FileTree).Entries)
+			w.WriteString("</div>\n")
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+type fileTreeExtension struct{}
+
+func (e *fileTreeExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(&fileTreeTransformer{}, 100)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&fileTreeRenderer{}, 50)))
+}
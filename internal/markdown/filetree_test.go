@@ -0,0 +1,84 @@
+package markdown
+
+import "testing"
+
+func TestFileTree(t *testing.T) {
+	folder := func(name string) string {
+		return `<li class="filetree-folder">` + folderIcon + `<code class="filetree-name">` + name + `</code>`
+	}
+	file := func(name string) string {
+		return `<li class="filetree-file">` + fileIcon + `<code class="filetree-name">` + name + `</code>`
+	}
+	testMarkdown(t, []struct{ name, md, want string }{
+		{
+			name: "indented",
+			md:   "```filetree\ncmd/\n  server/\n    main.go  # the <entry> point\n\ngo.mod\n```\n",
+			want: `<div class="filetree not-prose">
+<ul>
+` + folder("cmd/") + `
+<ul>
+` + folder("server/") + `
+<ul>
+` + file("main.go") + ` <span class="filetree-comment">the &lt;entry&gt; point</span></li>
+</ul>
+</li>
+</ul>
+</li>
+` + file("go.mod") + `</li>
+</ul>
+</div>
+`,
+		},
+		{
+			name: "tree output",
+			md:   "```filetree\n.\n├── a\n│   └── b.go\n└── c\n```\n",
+			want: `<div class="filetree not-prose">
+<ul>
+` + folder(".") + `
+<ul>
+` + folder("a") + `
+<ul>
+` + file("b.go") + `</li>
+</ul>
+</li>
+` + file("c") + `</li>
+</ul>
+</li>
+</ul>
+</div>
+`,
+		},
+		{
+			name: "in an admonition",
+			md:   ":::note\n```filetree\na/\n\tb\n```\n:::\n",
+			want: `<aside class="admonition admonition-note" role="note">
+<p class="admonition-title">Note</p>
+<div class="filetree not-prose">
+<ul>
+` + folder("a/") + `
+<ul>
+` + file("b") + `</li>
+</ul>
+</li>
+</ul>
+</div>
+</aside>
+`,
+		},
+		{
+			name: "unterminated",
+			md:   "```filetree\na/\n",
+			want: `<div class="filetree not-prose">
+<ul>
+` + folder("a/") + `</li>
+</ul>
+</div>
+`,
+		},
+		{
+			name: "in a code block",
+			md:   "````\n```filetree\na\n```\n````\n",
+			want: codeBlock("```filetree", "a", "```"),
+		},
+	})
+}
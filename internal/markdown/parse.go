@@ -10,6 +10,7 @@ import (
 	meta "github.com/yuin/goldmark-meta"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
+	gtext "github.com/yuin/goldmark/text"
 	"gopkg.in/yaml.v3"
 	"gosuda.org/website/internal/types"
 	"mvdan.cc/xurls/v2"
@@ -32,7 +33,15 @@ var gMark = goldmark.New(
 			highlighting.WithGuessLanguage(true),
 		),
 		extension.GFM,
+		extension.Footnote,
 		extension.CJK,
+		&shortcodeExtension{},
+		&citationExtension{},
+		&admonitionExtension{},
+		&tabsExtension{},
+		&detailsExtension{},
+		&fileTreeExtension{},
+		&scriptBlockExtension{},
 	),
 )
 
@@ -64,10 +73,13 @@ func ParseMarkdown(text string) (*types.Document, error) {
 	context := parser.NewContext()
 	var buf bytes.Buffer
 
-	err := gMark.Convert([]byte(text), &buf, parser.WithContext(context))
+	source := []byte(text)
+	root := gMark.Parser().Parse(gtext.NewReader(source), parser.WithContext(context))
+	err := gMark.Renderer().Render(&buf, source, root)
 	if err != nil {
 		return nil, err
 	}
+	doc.Features = documentFeatures(root)
 
 	metadata := meta.Get(context)
 	err = parseMetadata(doc, metadata)
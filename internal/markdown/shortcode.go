@@ -0,0 +1,100 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Shortcode is a block level `{{< name args >}}` call. Shortcodes are rendered as
+// placeholder comments and expanded when pages are generated, so their output
+// stays current without re-rendering (or re-translating) the document.
+type Shortcode struct {
+	ast.BaseBlock
+	Name string
+	Args string
+}
+
+var KindShortcode = ast.NewNodeKind("Shortcode")
+
+func (n *Shortcode) Kind() ast.NodeKind {
+	return KindShortcode
+}
+
+func (n *Shortcode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Name": n.Name, "Args": n.Args}, nil)
+}
+
+var shortcodeRe = regexp.MustCompile(`^\{\{<\s*([A-Za-z][\w-]*)\s*(.*?)\s*>\}\}\s*$`)
+
+const shortcodePrefix = "shortcode:"
+
+// ShortcodeComment returns the placeholder comment of a shortcode.
+func ShortcodeComment(name, args string) string {
+	return "<!--" + shortcodePrefix + name + " " + args + "-->"
+}
+
+// ParseShortcodeComment parses the data of a placeholder comment.
+func ParseShortcodeComment(data string) (name, args string, ok bool) {
+	rest, ok := strings.CutPrefix(data, shortcodePrefix)
+	if !ok {
+		return "", "", false
+	}
+	name, args, _ = strings.Cut(rest, " ")
+	return name, args, true
+}
+
+type shortcodeParser struct{}
+
+func (p *shortcodeParser) Trigger() []byte {
+	return []byte{'{'}
+}
+
+func (p *shortcodeParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	m := shortcodeRe.FindSubmatch(line)
+	if m == nil || strings.Contains(string(m[2]), "--") {
+		return nil, parser.NoChildren
+	}
+	reader.Advance(segment.Len())
+	return &Shortcode{Name: string(m[1]), Args: string(m[2])}, parser.NoChildren
+}
+
+func (p *shortcodeParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	return parser.Close
+}
+
+func (p *shortcodeParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *shortcodeParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *shortcodeParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+type shortcodeRenderer struct{}
+
+func (r *shortcodeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindShortcode, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			n := node.(*Shortcode)
+			w.WriteString(ShortcodeComment(n.Name, n.Args) + "\n")
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+type shortcodeExtension struct{}
+
+func (e *shortcodeExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithBlockParsers(util.Prioritized(&shortcodeParser{}, 50)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&shortcodeRenderer{}, 50)))
+}
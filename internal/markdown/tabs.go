@@ -0,0 +1,240 @@
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strconv"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Tabs is a group of tabs showing one of their panels at a time, such as the
+// same instructions for several operating systems, written as a `:::tabs`
+// container of `@tab Label` sections closed by a `:::` line:
+//
+//	:::tabs
+//	@tab go get
+//	...
+//	@tab Docker
+//	...
+//	:::
+//
+// The panels of the group are shown one after another until tabs.js turns it
+// into a tab list. Groups of tabs with the same labels switch together.
+type Tabs struct {
+	ast.BaseBlock
+	// Index numbers the groups of the document from 1, for the ids of their
+	// tabs and panels.
+	Index int
+}
+
+var KindTabs = ast.NewNodeKind("Tabs")
+
+func (n *Tabs) Kind() ast.NodeKind {
+	return KindTabs
+}
+
+func (n *Tabs) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Index": strconv.Itoa(n.Index)}, nil)
+}
+
+// Tab is a panel of a Tabs group.
+type Tab struct {
+	ast.BaseBlock
+	Label string
+}
+
+var KindTab = ast.NewNodeKind("Tab")
+
+func (n *Tab) Kind() ast.NodeKind {
+	return KindTab
+}
+
+func (n *Tab) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Label": n.Label}, nil)
+}
+
+var (
+	tabsRe = regexp.MustCompile(`^:::\s*tabs\s*$`)
+	tabRe  = regexp.MustCompile(`^@tab\s+(.+?)\s*$`)
+)
+
+// innermostContainer returns the innermost of the open `:::` containers, the
+// one a `:::` line closes, or nil when the line is in an open code block.
+func innermostContainer(pc parser.Context) ast.Node {
+	blocks := pc.OpenedBlocks()
+	for i := len(blocks) - 1; i >= 0; i-- {
+		switch blocks[i].Node.(type) {
+		case *Admonition, *Details, *Tabs:
+			return blocks[i].Node
+		case *ast.FencedCodeBlock:
+			return nil
+		}
+	}
+	return nil
+}
+
+// closesContainer reports whether line is the `:::` line closing node.
+func closesContainer(node ast.Node, line []byte, pc parser.Context) bool {
+	return string(bytes.TrimSpace(line)) == ":::" && innermostContainer(pc) == node
+}
+
+type tabsParser struct{}
+
+func (p *tabsParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+func (p *tabsParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	if !tabsRe.Match(bytes.TrimRight(line, "\r\n")) {
+		return nil, parser.NoChildren
+	}
+	reader.Advance(segment.Len() - newline(line))
+	return &Tabs{}, parser.HasChildren
+}
+
+func (p *tabsParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	if closesContainer(node, line, pc) {
+		reader.Advance(segment.Len() - newline(line))
+		return parser.Close
+	}
+	return parser.Continue | parser.HasChildren
+}
+
+func (p *tabsParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	// the content before the first tab has no panel to be shown in
+	for c := node.FirstChild(); c != nil; {
+		next := c.NextSibling()
+		if _, ok := c.(*Tab); !ok {
+			node.RemoveChild(node, c)
+		}
+		c = next
+	}
+}
+
+func (p *tabsParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *tabsParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+type tabParser struct{}
+
+func (p *tabParser) Trigger() []byte {
+	return []byte{'@'}
+}
+
+func (p *tabParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	if _, ok := parent.(*Tabs); !ok {
+		return nil, parser.NoChildren
+	}
+	line, segment := reader.PeekLine()
+	m := tabRe.FindSubmatch(bytes.TrimRight(line, "\r\n"))
+	if m == nil {
+		return nil, parser.NoChildren
+	}
+	reader.Advance(segment.Len() - newline(line))
+	return &Tab{Label: string(m[1])}, parser.HasChildren
+}
+
+func (p *tabParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	// the next tab, or the end of the group, is left to the group
+	line, _ := reader.PeekLine()
+	if tabRe.Match(bytes.TrimRight(line, "\r\n")) && innermostContainer(pc) == node.Parent() {
+		return parser.Close
+	}
+	return parser.Continue | parser.HasChildren
+}
+
+func (p *tabParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *tabParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *tabParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// tabsTransformer numbers the groups of tabs of a document.
+type tabsTransformer struct{}
+
+func (t *tabsTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var index int
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if tabs, ok := n.(*Tabs); ok && entering {
+			index++
+			tabs.Index = index
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+type tabsRenderer struct{}
+
+func (r *tabsRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindTabs, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		n := node.(*Tabs)
+		if !entering {
+			w.WriteString("</div>\n")
+			return ast.WalkContinue, nil
+		}
+		id := "tabs-" + strconv.Itoa(n.Index)
+		w.WriteString(`<div class="tabs" id="` + id + `">` + "\n")
+		// hidden until tabs.js hides the panels of the other tabs
+		w.WriteString(`<div role="tablist" hidden>`)
+		var i int
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			i++
+			tab := c.(*Tab)
+			selected, tabindex := "false", "-1"
+			if i == 1 {
+				selected, tabindex = "true", "0"
+			}
+			w.WriteString(`<button type="button" role="tab" id="` + id + "-tab-" + strconv.Itoa(i) +
+				`" aria-controls="` + id + "-panel-" + strconv.Itoa(i) + `" aria-selected="` + selected +
+				`" tabindex="` + tabindex + `">` + html.EscapeString(tab.Label) + `</button>`)
+		}
+		w.WriteString("</div>\n")
+		return ast.WalkContinue, nil
+	})
+	reg.Register(KindTab, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		n := node.(*Tab)
+		if !entering {
+			w.WriteString("</div>\n")
+			return ast.WalkContinue, nil
+		}
+		var i int
+		for c := node; c != nil; c = c.PreviousSibling() {
+			i++
+		}
+		id := "tabs-" + strconv.Itoa(node.Parent().(*Tabs).Index)
+		num := strconv.Itoa(i)
+		w.WriteString(`<div role="tabpanel" id="` + id + "-panel-" + num + `" aria-labelledby="` + id + "-tab-" + num + `" tabindex="0">` + "\n")
+		w.WriteString(`<p class="tab-label">` + html.EscapeString(n.Label) + "</p>\n")
+		return ast.WalkContinue, nil
+	})
+}
+
+type tabsExtension struct{}
+
+func (e *tabsExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(
+			util.Prioritized(&tabsParser{}, 50),
+			util.Prioritized(&tabParser{}, 50),
+		),
+		parser.WithASTTransformers(util.Prioritized(&tabsTransformer{}, 100)),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&tabsRenderer{}, 50)))
+}
@@ -0,0 +1,109 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// codeBlock returns the highlighted HTML of a code block of plain text lines.
+func codeBlock(lines ...string) string {
+	var b strings.Builder
+	b.WriteString(`<pre style="color:#f8f8f2;background-color:#282a36;"><code>`)
+	for i, line := range lines {
+		b.WriteString(`<span style="display:flex;"><span style="white-space:pre;-webkit-user-select:none;user-select:none;margin-right:0.4em;padding:0 0.4em 0 0.4em;color:#7f7f7f">` +
+			strconv.Itoa(i+1) + `</span><span>` + line + "\n</span></span>")
+	}
+	b.WriteString("</code></pre>")
+	return b.String()
+}
+
+// testMarkdown checks the HTML of the Markdown of each test.
+func testMarkdown(t *testing.T, tests []struct{ name, md, want string }) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseMarkdown(tt.md)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.HTML != tt.want {
+				t.Errorf("ParseMarkdown(%q) =\n%s\nwant\n%s", tt.md, doc.HTML, tt.want)
+			}
+		})
+	}
+}
+
+func TestTabs(t *testing.T) {
+	testMarkdown(t, []struct{ name, md, want string }{
+		{
+			name: "tabs",
+			md:   ":::tabs\nbefore\n@tab Linux\nl\n@tab <Mac>\nm\n:::\nafter\n",
+			want: `<div class="tabs" id="tabs-1">
+<div role="tablist" hidden><button type="button" role="tab" id="tabs-1-tab-1" aria-controls="tabs-1-panel-1" aria-selected="true" tabindex="0">Linux</button><button type="button" role="tab" id="tabs-1-tab-2" aria-controls="tabs-1-panel-2" aria-selected="false" tabindex="-1">&lt;Mac&gt;</button></div>
+<div role="tabpanel" id="tabs-1-panel-1" aria-labelledby="tabs-1-tab-1" tabindex="0">
+<p class="tab-label">Linux</p>
+<p>l</p>
+</div>
+<div role="tabpanel" id="tabs-1-panel-2" aria-labelledby="tabs-1-tab-2" tabindex="0">
+<p class="tab-label">&lt;Mac&gt;</p>
+<p>m</p>
+</div>
+</div>
+<p>after</p>
+`,
+		},
+		{
+			name: "nested",
+			md:   ":::tabs\n@tab A\n:::tabs\n@tab B\nb\n:::\na\n:::\n",
+			want: `<div class="tabs" id="tabs-1">
+<div role="tablist" hidden><button type="button" role="tab" id="tabs-1-tab-1" aria-controls="tabs-1-panel-1" aria-selected="true" tabindex="0">A</button></div>
+<div role="tabpanel" id="tabs-1-panel-1" aria-labelledby="tabs-1-tab-1" tabindex="0">
+<p class="tab-label">A</p>
+<div class="tabs" id="tabs-2">
+<div role="tablist" hidden><button type="button" role="tab" id="tabs-2-tab-1" aria-controls="tabs-2-panel-1" aria-selected="true" tabindex="0">B</button></div>
+<div role="tabpanel" id="tabs-2-panel-1" aria-labelledby="tabs-2-tab-1" tabindex="0">
+<p class="tab-label">B</p>
+<p>b</p>
+</div>
+</div>
+<p>a</p>
+</div>
+</div>
+`,
+		},
+		{
+			name: "unterminated",
+			md:   ":::tabs\n@tab A\na\n",
+			want: `<div class="tabs" id="tabs-1">
+<div role="tablist" hidden><button type="button" role="tab" id="tabs-1-tab-1" aria-controls="tabs-1-panel-1" aria-selected="true" tabindex="0">A</button></div>
+<div role="tabpanel" id="tabs-1-panel-1" aria-labelledby="tabs-1-tab-1" tabindex="0">
+<p class="tab-label">A</p>
+<p>a</p>
+</div>
+</div>
+`,
+		},
+		{
+			name: "tab outside tabs",
+			md:   "@tab A\na\n",
+			want: "<p>@tab A\na</p>\n",
+		},
+		{
+			name: "in a code block",
+			md:   "```\n:::tabs\n@tab A\n:::\n```\n",
+			want: codeBlock(":::tabs", "@tab A", ":::"),
+		},
+		{
+			name: "code block in a tab",
+			md:   ":::tabs\n@tab A\n```\n@tab B\n:::\n```\n:::\n",
+			want: `<div class="tabs" id="tabs-1">
+<div role="tablist" hidden><button type="button" role="tab" id="tabs-1-tab-1" aria-controls="tabs-1-panel-1" aria-selected="true" tabindex="0">A</button></div>
+<div role="tabpanel" id="tabs-1-panel-1" aria-labelledby="tabs-1-tab-1" tabindex="0">
+<p class="tab-label">A</p>
+` + codeBlock("@tab B", ":::") + `</div>
+</div>
+`,
+		},
+	})
+}
@@ -0,0 +1,118 @@
+// Package minhash estimates the similarity of texts with MinHash signatures of
+// their word shingles, and finds similar pairs with locality sensitive hashing.
+package minhash
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"gosuda.org/website/internal/wyhash"
+)
+
+const (
+	// Size is the number of hash functions of a signature.
+	Size = 128
+	// ShingleSize is the number of words of a shingle.
+	ShingleSize = 5
+
+	// bands of rows split the signature for locality sensitive hashing. Pairs
+	// above a similarity of about (1/bands)^(1/rows) ≈ 0.42 become candidates.
+	bands = 32
+	rows  = Size / bands
+)
+
+// Signature is the MinHash signature of a text.
+type Signature [Size]uint64
+
+// coefficients of the hash functions h_i(x) = a_i*x + b_i, with odd a_i so that
+// each is a permutation of the 64-bit integers.
+var coefA, coefB [Size]uint64
+
+func init() {
+	seed := uint64(0x6d696e68617368)
+	for i := range coefA {
+		coefA[i] = wyhash.WYRAND(&seed) | 1
+		coefB[i] = wyhash.WYRAND(&seed)
+	}
+}
+
+// Words splits text into lowercase words, dropping punctuation.
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// New returns the signature of text. Texts shorter than a shingle are
+// treated as a single shingle.
+func New(text string) Signature {
+	var sig Signature
+	for i := range sig {
+		sig[i] = ^uint64(0)
+	}
+	words := Words(text)
+	n := max(len(words)-ShingleSize+1, 1)
+	for i := 0; i < n; i++ {
+		shingle := strings.Join(words[i:min(i+ShingleSize, len(words))], " ")
+		x := wyhash.HashString(shingle, 0)
+		for j := range sig {
+			if h := coefA[j]*x + coefB[j]; h < sig[j] {
+				sig[j] = h
+			}
+		}
+	}
+	return sig
+}
+
+// Similarity estimates the Jaccard similarity of the shingles of the texts of
+// two signatures.
+func (s *Signature) Similarity(o *Signature) float64 {
+	var same int
+	for i := range s {
+		if s[i] == o[i] {
+			same++
+		}
+	}
+	return float64(same) / Size
+}
+
+// Pair is a pair of indexes into the signatures given to Similar, with I < J.
+type Pair struct {
+	I, J       int
+	Similarity float64
+}
+
+// Similar returns the pairs of signatures with an estimated similarity of at
+// least threshold, in the order of I and J. Only pairs that share a band of
+// their signatures are compared, so pairs below about 0.4 are never reported.
+func Similar(sigs []Signature, threshold float64) []Pair {
+	candidates := make(map[[2]int]bool)
+	for b := 0; b < bands; b++ {
+		buckets := make(map[uint64][]int)
+		for i := range sigs {
+			var key uint64
+			for _, v := range sigs[i][b*rows : (b+1)*rows] {
+				key = key*31 + v
+			}
+			for _, j := range buckets[key] {
+				candidates[[2]int{j, i}] = true
+			}
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	var pairs []Pair
+	for c := range candidates {
+		if sim := sigs[c[0]].Similarity(&sigs[c[1]]); sim >= threshold {
+			pairs = append(pairs, Pair{I: c[0], J: c[1], Similarity: sim})
+		}
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		if pairs[a].I != pairs[b].I {
+			return pairs[a].I < pairs[b].I
+		}
+		return pairs[a].J < pairs[b].J
+	})
+	return pairs
+}
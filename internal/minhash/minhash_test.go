@@ -0,0 +1,48 @@
+package minhash
+
+import (
+	"strings"
+	"testing"
+)
+
+const text = `Goroutines are lightweight threads managed by the Go runtime. Channels connect
+concurrent goroutines, so values can be sent from one goroutine and received in another.
+A mutex protects shared state when channels do not fit the problem, and the sync package
+provides wait groups to wait for a collection of goroutines to finish their work.`
+
+func TestSimilarity(t *testing.T) {
+	a := New(text)
+	b := New(strings.Replace(text, "lightweight", "cheap", 1))
+	c := New("The quick brown fox jumps over the lazy dog while the cat sleeps in the warm sun all afternoon long.")
+
+	if sim := a.Similarity(&a); sim != 1 {
+		t.Errorf("identical similarity = %v, want 1", sim)
+	}
+	if sim := a.Similarity(&b); sim < 0.7 {
+		t.Errorf("near-duplicate similarity = %v, want at least 0.7", sim)
+	}
+	if sim := a.Similarity(&c); sim > 0.1 {
+		t.Errorf("unrelated similarity = %v, want at most 0.1", sim)
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	sigs := []Signature{
+		New("Something else entirely, about databases and indexes and query planners."),
+		New(text),
+		New(strings.ToUpper(text)),
+		New(strings.Replace(text, "Go runtime", "scheduler", 1)),
+	}
+	pairs := Similar(sigs, 0.7)
+	if len(pairs) != 3 {
+		t.Fatalf("Similar() = %+v, want the 3 pairs of 1, 2 and 3", pairs)
+	}
+	for _, p := range pairs {
+		if p.I == 0 || p.I >= p.J {
+			t.Errorf("unexpected pair %+v", p)
+		}
+	}
+	if pairs[0].I != 1 || pairs[0].J != 2 || pairs[0].Similarity != 1 {
+		t.Errorf("pairs[0] = %+v, want {1 2 1}", pairs[0])
+	}
+}
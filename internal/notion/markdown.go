@@ -0,0 +1,263 @@
+package notion
+
+import (
+	"strconv"
+	"strings"
+
+	"gosuda.org/website/internal/htmltomd"
+)
+
+// Markdown converts content blocks to Markdown. image maps the URL of every image
+// (for example to a downloaded copy, since Notion file URLs expire), and may be nil.
+// The types of blocks without a Markdown equivalent are returned as unsupported.
+func Markdown(blocks []*Block, image func(url string) string) (md string, unsupported []string) {
+	w := &mdWriter{image: image}
+	return strings.TrimSpace(w.blocks(blocks)) + "\n", w.unsupported
+}
+
+type mdWriter struct {
+	image       func(string) string
+	unsupported []string
+}
+
+func isListItem(b *Block) bool {
+	return b.Type == "bulleted_list_item" || b.Type == "numbered_list_item" || b.Type == "to_do"
+}
+
+func (w *mdWriter) blocks(blocks []*Block) string {
+	var b strings.Builder
+	num := 0
+	for i, block := range blocks {
+		if block.Type == "numbered_list_item" {
+			num++
+		} else {
+			num = 0
+		}
+		s := w.block(block, num)
+		if s == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			// items of one list are separated by a single newline
+			if i > 0 && isListItem(blocks[i-1]) && blocks[i-1].Type == block.Type {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+func (w *mdWriter) block(b *Block, num int) string {
+	c := &b.Content
+	switch b.Type {
+	case "paragraph":
+		return join(w.text(c.RichText), w.blocks(b.Children))
+	case "heading_1", "heading_2", "heading_3":
+		level := int(b.Type[len(b.Type)-1] - '0')
+		return join(strings.Repeat("#", level)+" "+oneLine(w.text(c.RichText)), w.blocks(b.Children))
+	case "bulleted_list_item":
+		return w.listItem("- ", c.RichText, b.Children)
+	case "numbered_list_item":
+		return w.listItem(strconv.Itoa(num)+". ", c.RichText, b.Children)
+	case "to_do":
+		marker := "- [ ] "
+		if c.Checked {
+			marker = "- [x] "
+		}
+		return w.listItem(marker, c.RichText, b.Children)
+	case "toggle":
+		return join("**"+strings.TrimSpace(w.text(c.RichText))+"**", w.blocks(b.Children))
+	case "quote":
+		return prefixLines(join(w.text(c.RichText), w.blocks(b.Children)), "> ")
+	case "callout":
+		text := w.text(c.RichText)
+		if c.Icon != nil && c.Icon.Emoji != "" {
+			text = c.Icon.Emoji + " " + text
+		}
+		return prefixLines(join(text, w.blocks(b.Children)), "> ")
+	case "code":
+		code := PlainText(c.RichText)
+		fence := "```"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		return join(fence+codeLanguage(c.Language)+"\n"+code+"\n"+fence, w.caption(c.Caption))
+	case "equation":
+		return "```latex\n" + c.Expression + "\n```"
+	case "divider":
+		return "---"
+	case "image":
+		src := c.File.URL()
+		if w.image != nil {
+			src = w.image(src)
+		}
+		alt := oneLine(PlainText(c.Caption))
+		return join("!["+htmltomd.EscapeText(alt)+"]("+destination(src)+")", w.caption(c.Caption))
+	case "video", "audio", "file", "pdf":
+		return w.link(c.File.URL(), c.Caption)
+	case "bookmark", "embed", "link_preview":
+		return w.link(c.URL, c.Caption)
+	case "table":
+		return w.table(b)
+	case "column_list", "column", "synced_block":
+		return w.blocks(b.Children)
+	case "table_of_contents", "breadcrumb":
+		return ""
+	}
+	w.unsupported = append(w.unsupported, b.Type)
+	return ""
+}
+
+func join(parts ...string) string {
+	var nonEmpty []string
+	for _, p := range parts {
+		if strings.TrimSpace(p) != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, "\n\n")
+}
+
+func (w *mdWriter) listItem(marker string, text []RichText, children []*Block) string {
+	content := join(w.text(text), w.blocks(children))
+	indent := strings.Repeat(" ", len(marker))
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = marker + line
+		case line != "":
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (w *mdWriter) caption(caption []RichText) string {
+	if text := strings.TrimSpace(w.text(caption)); text != "" {
+		return "*" + text + "*"
+	}
+	return ""
+}
+
+func (w *mdWriter) link(url string, caption []RichText) string {
+	if url == "" {
+		return ""
+	}
+	if text := strings.TrimSpace(w.text(caption)); text != "" {
+		return "[" + text + "](" + destination(url) + ")"
+	}
+	return "<" + url + ">"
+}
+
+func (w *mdWriter) table(b *Block) string {
+	var rows []string
+	width := 0
+	for _, row := range b.Children {
+		cells := make([]string, len(row.Content.Cells))
+		for i, cell := range row.Content.Cells {
+			cells[i] = strings.ReplaceAll(oneLine(w.text(cell)), "|", `\|`)
+		}
+		width = max(width, len(cells))
+		rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+	separator := "|" + strings.Repeat(" --- |", width)
+	if !b.Content.HasColumnHeader {
+		// GitHub flavored tables require a header row
+		return "|" + strings.Repeat("  |", width) + "\n" + separator + "\n" + strings.Join(rows, "\n")
+	}
+	return rows[0] + "\n" + separator + "\n" + strings.Join(rows[1:], "\n")
+}
+
+// text converts rich text spans to inline Markdown.
+func (w *mdWriter) text(spans []RichText) string {
+	var b strings.Builder
+	for _, s := range spans {
+		a := s.Annotations
+		var text string
+		switch {
+		case s.Type == "equation" || a.Code:
+			fence := "`"
+			for strings.Contains(s.PlainText, fence) {
+				fence += "`"
+			}
+			text = fence + s.PlainText + fence
+		default:
+			text = htmltomd.EscapeText(s.PlainText)
+			if a.Strikethrough {
+				text = wrap(text, "~~")
+			}
+			if a.Italic {
+				text = wrap(text, "*")
+			}
+			if a.Bold {
+				text = wrap(text, "**")
+			}
+		}
+		if s.Href != "" {
+			text = "[" + text + "](" + destination(s.Href) + ")"
+		}
+		b.WriteString(text)
+	}
+	// line breaks within a block are hard breaks
+	return strings.ReplaceAll(b.String(), "\n", "\\\n")
+}
+
+// wrap surrounds s with marker, keeping surrounding whitespace outside of it.
+func wrap(s, marker string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	lead := s[:len(s)-len(strings.TrimLeft(s, " \t\n"))]
+	trail := s[len(strings.TrimRight(s, " \t\n")):]
+	return lead + marker + trimmed + marker + trail
+}
+
+func oneLine(s string) string {
+	return strings.ReplaceAll(s, "\\\n", " ")
+}
+
+func prefixLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = strings.TrimRight(prefix, " ")
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func destination(u string) string {
+	if strings.ContainsAny(u, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(u) + ">"
+	}
+	return u
+}
+
+// codeLanguage maps a Notion code block language to a fenced code info string.
+func codeLanguage(lang string) string {
+	switch lang {
+	case "plain text":
+		return ""
+	case "c++":
+		return "cpp"
+	case "c#":
+		return "csharp"
+	case "f#":
+		return "fsharp"
+	case "objective-c":
+		return "objectivec"
+	case "vb.net", "visual basic":
+		return "vbnet"
+	}
+	return strings.ReplaceAll(lang, " ", "")
+}
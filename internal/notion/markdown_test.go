@@ -0,0 +1,75 @@
+package notion
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	src := `[
+		{"id": "1", "type": "heading_2", "heading_2": {"rich_text": [{"type": "text", "plain_text": "Intro"}]}},
+		{"id": "2", "type": "paragraph", "paragraph": {"rich_text": [
+			{"type": "text", "plain_text": "Hello "},
+			{"type": "text", "plain_text": "bold ", "annotations": {"bold": true}},
+			{"type": "text", "plain_text": "a_b", "annotations": {"code": true}},
+			{"type": "text", "plain_text": " and ", "annotations": {}},
+			{"type": "text", "plain_text": "Go", "href": "https://go.dev", "annotations": {"italic": true}}
+		]}},
+		{"id": "3", "type": "bulleted_list_item", "has_children": true, "bulleted_list_item": {"rich_text": [{"type": "text", "plain_text": "one"}]}},
+		{"id": "4", "type": "bulleted_list_item", "bulleted_list_item": {"rich_text": [{"type": "text", "plain_text": "two"}]}},
+		{"id": "5", "type": "numbered_list_item", "numbered_list_item": {"rich_text": [{"type": "text", "plain_text": "first"}]}},
+		{"id": "6", "type": "numbered_list_item", "numbered_list_item": {"rich_text": [{"type": "text", "plain_text": "second"}]}},
+		{"id": "7", "type": "code", "code": {"language": "c++", "rich_text": [{"type": "text", "plain_text": "int *p;"}]}},
+		{"id": "8", "type": "image", "image": {"type": "file", "file": {"url": "https://s3/x.png?sig"}, "caption": [{"type": "text", "plain_text": "A chart"}]}},
+		{"id": "9", "type": "callout", "callout": {"icon": {"type": "emoji", "emoji": "💡"}, "rich_text": [{"type": "text", "plain_text": "Tip"}]}},
+		{"id": "10", "type": "table", "has_children": true, "table": {"table_width": 2, "has_column_header": true}},
+		{"id": "11", "type": "child_database", "child_database": {"title": "DB"}}
+	]`
+	var blocks []*Block
+	if err := json.Unmarshal([]byte(src), &blocks); err != nil {
+		t.Fatal(err)
+	}
+	blocks[2].Children = []*Block{{Type: "paragraph", Content: BlockContent{RichText: []RichText{{PlainText: "nested"}}}}}
+	blocks[9].Children = []*Block{
+		{Type: "table_row", Content: BlockContent{Cells: [][]RichText{{{PlainText: "k"}}, {{PlainText: "v"}}}}},
+		{Type: "table_row", Content: BlockContent{Cells: [][]RichText{{{PlainText: "a|b"}}, {{PlainText: "1"}}}}},
+	}
+
+	md, unsupported := Markdown(blocks, func(url string) string { return "/local.png" })
+	want := strings.Join([]string{
+		"## Intro",
+		"",
+		"Hello **bold** `a_b` and [*Go*](https://go.dev)",
+		"",
+		"- one",
+		"",
+		"  nested",
+		"- two",
+		"",
+		"1. first",
+		"2. second",
+		"",
+		"```cpp",
+		"int *p;",
+		"```",
+		"",
+		"![A chart](/local.png)",
+		"",
+		"*A chart*",
+		"",
+		"> 💡 Tip",
+		"",
+		"| k | v |",
+		"| --- | --- |",
+		"| a\\|b | 1 |",
+		"",
+	}, "\n")
+	if md != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", md, want)
+	}
+	if !slices.Equal(unsupported, []string{"child_database"}) {
+		t.Errorf("unsupported = %v", unsupported)
+	}
+}
@@ -0,0 +1,366 @@
+// Package notion is a minimal client for the Notion API, covering what is needed
+// to read the pages of a database and their content blocks.
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	apiURL = "https://api.notion.com/v1"
+	// Version is the Notion API version the types of this package follow.
+	Version = "2022-06-28"
+)
+
+// Client calls the Notion API with an integration token.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+	limiter *rate.Limiter
+}
+
+func NewClient(token string) *Client {
+	return &Client{
+		baseURL: apiURL,
+		token:   token,
+		http:    &http.Client{Timeout: time.Minute},
+		// Notion allows an average of three requests per second per integration
+		limiter: rate.NewLimiter(3, 3),
+	}
+}
+
+// Error is an error response of the Notion API.
+type Error struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("notion: %s (%d): %s", e.Code, e.Status, e.Message)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Notion-Version", Version)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return err
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			wait, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			select {
+			case <-time.After(time.Duration(max(wait, 1)) * time.Second):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if resp.StatusCode != http.StatusOK {
+			apiErr := &Error{Status: resp.StatusCode}
+			if json.Unmarshal(respBody, apiErr) != nil || apiErr.Message == "" {
+				apiErr.Message = strings.TrimSpace(string(respBody))
+			}
+			return apiErr
+		}
+		return json.Unmarshal(respBody, out)
+	}
+}
+
+type list[T any] struct {
+	Results    []T    `json:"results"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// QueryDatabase returns all pages of a database.
+func (c *Client) QueryDatabase(ctx context.Context, databaseID string) ([]*Page, error) {
+	var pages []*Page
+	cursor := ""
+	for {
+		body := map[string]any{"page_size": 100}
+		if cursor != "" {
+			body["start_cursor"] = cursor
+		}
+		var resp list[*Page]
+		err := c.do(ctx, http.MethodPost, "/databases/"+databaseID+"/query", body, &resp)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, resp.Results...)
+		if !resp.HasMore {
+			return pages, nil
+		}
+		cursor = resp.NextCursor
+	}
+}
+
+// Blocks returns the content blocks of a page or block, with the children of
+// nested blocks filled in. Child pages and databases are not descended into.
+func (c *Client) Blocks(ctx context.Context, blockID string) ([]*Block, error) {
+	var blocks []*Block
+	cursor := ""
+	for {
+		path := "/blocks/" + blockID + "/children?page_size=100"
+		if cursor != "" {
+			path += "&start_cursor=" + cursor
+		}
+		var resp list[*Block]
+		err := c.do(ctx, http.MethodGet, path, nil, &resp)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, resp.Results...)
+		if !resp.HasMore {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+
+	for _, b := range blocks {
+		if !b.HasChildren || b.Type == "child_page" || b.Type == "child_database" {
+			continue
+		}
+		children, err := c.Blocks(ctx, b.ID)
+		if err != nil {
+			return nil, err
+		}
+		b.Children = children
+	}
+	return blocks, nil
+}
+
+// Page is a database page.
+type Page struct {
+	ID             string              `json:"id"`
+	URL            string              `json:"url"`
+	CreatedTime    time.Time           `json:"created_time"`
+	LastEditedTime time.Time           `json:"last_edited_time"`
+	Archived       bool                `json:"archived"`
+	InTrash        bool                `json:"in_trash"`
+	Cover          *File               `json:"cover"`
+	Properties     map[string]Property `json:"properties"`
+}
+
+// Title returns the plain text of the title property.
+func (p *Page) Title() string {
+	for _, prop := range p.Properties {
+		if prop.Type == "title" {
+			return prop.Text()
+		}
+	}
+	return ""
+}
+
+// Property is a page property value.
+type Property struct {
+	Type        string     `json:"type"`
+	Title       []RichText `json:"title"`
+	RichText    []RichText `json:"rich_text"`
+	Select      *Option    `json:"select"`
+	Status      *Option    `json:"status"`
+	MultiSelect []Option   `json:"multi_select"`
+	People      []struct {
+		Name string `json:"name"`
+	} `json:"people"`
+	Date *struct {
+		Start string `json:"start"`
+		End   string `json:"end"`
+	} `json:"date"`
+	Checkbox bool   `json:"checkbox"`
+	URL      string `json:"url"`
+}
+
+type Option struct {
+	Name string `json:"name"`
+}
+
+// Text returns the value of a text-like property as plain text.
+func (p Property) Text() string {
+	switch p.Type {
+	case "title":
+		return PlainText(p.Title)
+	case "rich_text":
+		return PlainText(p.RichText)
+	case "select":
+		if p.Select != nil {
+			return p.Select.Name
+		}
+	case "status":
+		if p.Status != nil {
+			return p.Status.Name
+		}
+	case "url":
+		return p.URL
+	case "people":
+		names := make([]string, len(p.People))
+		for i, person := range p.People {
+			names[i] = person.Name
+		}
+		return strings.Join(names, ", ")
+	case "date":
+		if p.Date != nil {
+			return p.Date.Start
+		}
+	}
+	return ""
+}
+
+// Names returns the selected options of a select or multi-select property.
+func (p Property) Names() []string {
+	var names []string
+	for _, o := range p.MultiSelect {
+		names = append(names, o.Name)
+	}
+	if p.Select != nil {
+		names = append(names, p.Select.Name)
+	}
+	return names
+}
+
+// Time returns the start of a date property.
+func (p Property) Time() time.Time {
+	if p.Date == nil {
+		return time.Time{}
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if t, err := time.Parse(layout, p.Date.Start); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// RichText is a span of formatted text.
+type RichText struct {
+	Type        string `json:"type"`
+	PlainText   string `json:"plain_text"`
+	Href        string `json:"href"`
+	Annotations struct {
+		Bold          bool `json:"bold"`
+		Italic        bool `json:"italic"`
+		Strikethrough bool `json:"strikethrough"`
+		Underline     bool `json:"underline"`
+		Code          bool `json:"code"`
+	} `json:"annotations"`
+}
+
+// PlainText concatenates the plain text of spans.
+func PlainText(spans []RichText) string {
+	var b strings.Builder
+	for _, s := range spans {
+		b.WriteString(s.PlainText)
+	}
+	return b.String()
+}
+
+// File is a file hosted by Notion or an external URL.
+type File struct {
+	Type string `json:"type"`
+	File *struct {
+		URL string `json:"url"`
+	} `json:"file"`
+	External *struct {
+		URL string `json:"url"`
+	} `json:"external"`
+}
+
+// URL returns the file URL. URLs of files hosted by Notion expire after an hour.
+func (f *File) URL() string {
+	switch {
+	case f == nil:
+		return ""
+	case f.File != nil:
+		return f.File.URL
+	case f.External != nil:
+		return f.External.URL
+	}
+	return ""
+}
+
+// Hosted reports whether the file is hosted by Notion.
+func (f *File) Hosted() bool {
+	return f != nil && f.Type == "file"
+}
+
+// Block is a content block. The type specific fields of all block types are
+// merged into Content.
+type Block struct {
+	ID          string       `json:"id"`
+	Type        string       `json:"type"`
+	HasChildren bool         `json:"has_children"`
+	Content     BlockContent `json:"-"`
+	Children    []*Block     `json:"-"`
+}
+
+type BlockContent struct {
+	File
+	RichText        []RichText   `json:"rich_text"`
+	Caption         []RichText   `json:"caption"`
+	Language        string       `json:"language"`
+	Checked         bool         `json:"checked"`
+	Icon            *Icon        `json:"icon"`
+	URL             string       `json:"url"`
+	Expression      string       `json:"expression"`
+	Title           string       `json:"title"`
+	HasColumnHeader bool         `json:"has_column_header"`
+	Cells           [][]RichText `json:"cells"`
+}
+
+type Icon struct {
+	Type  string `json:"type"`
+	Emoji string `json:"emoji"`
+}
+
+func (b *Block) UnmarshalJSON(data []byte) error {
+	type plain Block
+	if err := json.Unmarshal(data, (*plain)(b)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if content, ok := fields[b.Type]; ok {
+		return json.Unmarshal(content, &b.Content)
+	}
+	return nil
+}
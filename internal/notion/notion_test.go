@@ -0,0 +1,66 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("Notion-Version") != Version {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"object": "error", "status": 401, "code": "unauthorized", "message": "bad token"}`))
+			return
+		}
+		cursor := r.URL.Query().Get("start_cursor")
+		if r.Method == http.MethodPost {
+			var body struct {
+				StartCursor string `json:"start_cursor"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			cursor = body.StartCursor
+		}
+		switch r.URL.Path + "?" + cursor {
+		case "/databases/db/query?":
+			w.Write([]byte(`{"results": [{"id": "p1", "properties": {"Name": {"type": "title", "title": [{"plain_text": "One"}]}}}], "has_more": true, "next_cursor": "c1"}`))
+		case "/blocks/p1/children?":
+			w.Write([]byte(`{"results": [{"id": "b1", "type": "toggle", "has_children": true, "toggle": {"rich_text": []}}], "has_more": false}`))
+		case "/blocks/b1/children?":
+			w.Write([]byte(`{"results": [{"id": "b2", "type": "paragraph", "paragraph": {"rich_text": [{"plain_text": "inner"}]}}], "has_more": false}`))
+		case "/databases/db/query?c1":
+			w.Write([]byte(`{"results": [{"id": "p2", "archived": true}], "has_more": false}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient("secret")
+	c.baseURL = srv.URL
+	ctx := context.Background()
+
+	pages, err := c.QueryDatabase(ctx, "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 || pages[0].Title() != "One" || !pages[1].Archived {
+		t.Errorf("QueryDatabase() = %+v", pages)
+	}
+
+	blocks, err := c.Blocks(ctx, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || len(blocks[0].Children) != 1 || PlainText(blocks[0].Children[0].Content.RichText) != "inner" {
+		t.Errorf("Blocks() = %+v", blocks)
+	}
+
+	c.token = "wrong"
+	_, err = c.Blocks(ctx, "p1")
+	if apiErr, ok := err.(*Error); !ok || apiErr.Code != "unauthorized" {
+		t.Errorf("Blocks() with a bad token: err = %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+// Package readability computes heuristic readability and style metrics of prose.
+// The heuristics are meant to rank posts for editing, not to grade them exactly.
+package readability
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Stats are the metrics of a text.
+type Stats struct {
+	Sentences int
+	Words     int
+	// AvgSentenceLength is the average number of words per sentence.
+	AvgSentenceLength float64
+	// LongSentences counts sentences longer than LongSentence words.
+	LongSentences int
+	// Passive counts sentences that look like they use the passive voice.
+	Passive int
+	// Score is the Flesch reading ease, 0 (hard) to 100 (easy). It is only
+	// computed for English text; HasScore reports whether it was.
+	Score    float64
+	HasScore bool
+}
+
+// LongSentence is the number of words above which a sentence counts as long.
+// Korean words (eojeol) carry particles and endings, so Korean sentences use a
+// lower limit.
+func LongSentence(lang string) int {
+	if lang == "ko" {
+		return 20
+	}
+	return 30
+}
+
+// PassiveRatio returns the fraction of sentences in the passive voice.
+func (s Stats) PassiveRatio() float64 {
+	if s.Sentences == 0 {
+		return 0
+	}
+	return float64(s.Passive) / float64(s.Sentences)
+}
+
+// Analyze computes the metrics of text in lang, a language code such as "en"
+// or "ko". Paragraphs are separated by newlines.
+func Analyze(text, lang string) Stats {
+	var st Stats
+	long := LongSentence(lang)
+	var syllables int
+	for _, s := range Sentences(text) {
+		words := strings.Fields(s)
+		if len(words) == 0 {
+			continue
+		}
+		st.Sentences++
+		st.Words += len(words)
+		if len(words) > long {
+			st.LongSentences++
+		}
+		if IsPassive(s, lang) {
+			st.Passive++
+		}
+		for _, w := range words {
+			syllables += Syllables(w)
+		}
+	}
+	if st.Sentences == 0 {
+		return st
+	}
+	st.AvgSentenceLength = float64(st.Words) / float64(st.Sentences)
+	if lang == "en" {
+		st.HasScore = true
+		st.Score = 206.835 - 1.015*st.AvgSentenceLength - 84.6*float64(syllables)/float64(st.Words)
+		st.Score = min(max(st.Score, 0), 100)
+	}
+	return st
+}
+
+// Sentences splits text into sentences at terminal punctuation followed by a
+// space, and at newlines.
+func Sentences(text string) []string {
+	var sentences []string
+	for _, para := range strings.Split(text, "\n") {
+		start := 0
+		for i, r := range para {
+			if !isTerminal(r) {
+				continue
+			}
+			next := i + utf8.RuneLen(r)
+			// keep runs of punctuation such as "?!" and "..." together
+			if next < len(para) {
+				nr, _ := utf8.DecodeRuneInString(para[next:])
+				if !unicode.IsSpace(nr) && !isFullWidthTerminal(r) {
+					continue
+				}
+			}
+			if s := strings.TrimSpace(para[start:next]); s != "" && !isAbbreviation(s) {
+				sentences = append(sentences, s)
+				start = next
+			}
+		}
+		if s := strings.TrimSpace(para[start:]); s != "" {
+			sentences = append(sentences, s)
+		}
+	}
+	return sentences
+}
+
+func isTerminal(r rune) bool {
+	return r == '.' || r == '!' || r == '?' || isFullWidthTerminal(r)
+}
+
+func isFullWidthTerminal(r rune) bool {
+	return r == '。' || r == '！' || r == '？'
+}
+
+var abbreviations = []string{"e.g.", "i.e.", "etc.", "vs.", "mr.", "mrs.", "dr.", "cf."}
+
+// isAbbreviation reports whether s ends with an abbreviation rather than a sentence.
+func isAbbreviation(s string) bool {
+	fields := strings.Fields(s)
+	last := strings.ToLower(fields[len(fields)-1])
+	for _, a := range abbreviations {
+		if last == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Syllables estimates the syllables of an English word by counting vowel
+// groups. Hangul and other non-Latin words count one syllable per letter.
+func Syllables(word string) int {
+	word = strings.ToLower(strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }))
+	if word == "" {
+		return 0
+	}
+	var n int
+	prevVowel := false
+	latin := true
+	for _, r := range word {
+		if r > unicode.MaxASCII && unicode.IsLetter(r) {
+			latin = false
+		}
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !prevVowel {
+			n++
+		}
+		prevVowel = vowel
+	}
+	if !latin {
+		return utf8.RuneCountInString(word)
+	}
+	// a final silent e, except in "-le" endings such as "table"
+	if strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") && n > 1 {
+		n--
+	}
+	return max(n, 1)
+}
+
+// a form of "to be" followed by a past participle, allowing one adverb in between
+var englishPassive = regexp.MustCompile(`(?i)\b(am|is|are|was|were|be|been|being)\s+(\w+ly\s+)?(\w+ed|` + irregularParticiples + `)\b`)
+
+const irregularParticiples = `built|written|taken|given|known|made|done|seen|shown|found|held|kept|left|lost|sent|set|run|put|read|chosen|driven|drawn|broken|spoken|forgotten|hidden|thrown|understood|bound|caught|taught|brought|bought|thought|meant|begun|won|split|shut|hit|cut|spent|led|fed|paid|laid|sold|told`
+
+// koreanPassiveEndings are word endings of -되다, -받다 and -당하다 verbs and of
+// -아/어지다 constructions.
+var koreanPassiveEndings = []string{
+	"된다", "되었다", "됐다", "됩니다", "되었습니다", "됐습니다", "되어", "되며", "되고", "되는", "된", "돼요", "되어요",
+	"받았다", "받는다", "받습니다", "당했다", "당한다", "당합니다",
+	"어진다", "어졌다", "아진다", "아졌다", "여진다", "여졌다", "어집니다", "어졌습니다", "어지는", "어진",
+}
+
+// IsPassive reports whether sentence looks like it uses the passive voice.
+// Korean ("ko") uses markers of Korean passives; other languages use the
+// English "to be" + past participle pattern.
+func IsPassive(sentence, lang string) bool {
+	if lang != "ko" {
+		return englishPassive.MatchString(sentence)
+	}
+	if strings.Contains(sentence, "에 의해") || strings.Contains(sentence, "에 의하여") {
+		return true
+	}
+	for _, w := range strings.Fields(sentence) {
+		w = strings.TrimRightFunc(w, func(r rune) bool { return !unicode.Is(unicode.Hangul, r) })
+		for _, ending := range koreanPassiveEndings {
+			if strings.HasSuffix(w, ending) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Heading is a heading of a document.
+type Heading struct {
+	Level int
+	Text  string
+}
+
+// HeadingWarnings checks the structure of the headings of a document whose
+// title is rendered as the only h1.
+func HeadingWarnings(headings []Heading) []string {
+	var warnings []string
+	seen := make(map[string]bool)
+	prev := 1
+	for _, h := range headings {
+		text := strings.TrimSpace(h.Text)
+		switch {
+		case h.Level == 1:
+			warnings = append(warnings, fmt.Sprintf("h1 %q in the body, the title is the h1", text))
+		case h.Level > prev+1:
+			warnings = append(warnings, fmt.Sprintf("h%d %q skips h%d", h.Level, text, prev+1))
+		}
+		if text == "" {
+			warnings = append(warnings, fmt.Sprintf("empty h%d", h.Level))
+		} else if key := strings.ToLower(text); seen[key] {
+			warnings = append(warnings, fmt.Sprintf("duplicate heading %q", text))
+		} else {
+			seen[key] = true
+		}
+		prev = max(h.Level, 1)
+	}
+	return warnings
+}
@@ -0,0 +1,68 @@
+package readability
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSentences(t *testing.T) {
+	got := Sentences("Hello world. This is Go, e.g. a language! Really?! Yes\n빠르다。느리다")
+	want := []string{"Hello world.", "This is Go, e.g. a language!", "Really?!", "Yes", "빠르다。", "느리다"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Sentences() = %q, want %q", got, want)
+	}
+}
+
+func TestSyllables(t *testing.T) {
+	for word, want := range map[string]int{"go": 1, "table": 2, "generator": 4, "make": 1, "readability,": 5, "고루틴": 3} {
+		if got := Syllables(word); got != want {
+			t.Errorf("Syllables(%q) = %d, want %d", word, got, want)
+		}
+	}
+}
+
+func TestIsPassive(t *testing.T) {
+	tests := []struct {
+		sentence, lang string
+		want           bool
+	}{
+		{"The file was written by the generator.", "en", true},
+		{"The post is quickly rendered.", "en", true},
+		{"The generator writes the file.", "en", false},
+		{"파일은 생성기에 의해 작성된다.", "ko", true},
+		{"이 기능은 v2에서 추가되었습니다.", "ko", true},
+		{"새로 만들어진 채널을 닫는다.", "ko", true},
+		{"생성기가 파일을 작성한다.", "ko", false},
+	}
+	for _, tt := range tests {
+		if got := IsPassive(tt.sentence, tt.lang); got != tt.want {
+			t.Errorf("IsPassive(%q, %q) = %v, want %v", tt.sentence, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	st := Analyze("The cat sat on the mat. It was fed by the dog.", "en")
+	if st.Sentences != 2 || st.Words != 12 || st.Passive != 1 || !st.HasScore {
+		t.Errorf("Analyze() = %+v", st)
+	}
+	if st.Score < 90 {
+		t.Errorf("Score = %.1f, want an easy text", st.Score)
+	}
+	if ko := Analyze("고루틴은 가볍다. 채널로 통신한다.", "ko"); ko.HasScore || ko.Sentences != 2 {
+		t.Errorf("Analyze(ko) = %+v", ko)
+	}
+}
+
+func TestHeadingWarnings(t *testing.T) {
+	got := HeadingWarnings([]Heading{{2, "Intro"}, {4, "Detail"}, {2, "intro"}, {1, "Title"}, {2, ""}})
+	want := []string{
+		`h4 "Detail" skips h3`,
+		`duplicate heading "intro"`,
+		`h1 "Title" in the body, the title is the h1`,
+		`empty h2`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("HeadingWarnings() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,488 @@
+// Package spell checks words against hunspell dictionaries. It reads the .dic
+// and .aff formats and supports prefixes, suffixes, cross products, forbidden
+// words and simple compounding, which covers the common en_US and ko_KR
+// dictionaries. Suggestions and the other hunspell options are not supported.
+package spell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Dictionary is a loaded hunspell dictionary.
+type Dictionary struct {
+	words    map[string][]flags
+	prefixes []*affix
+	suffixes []*affix
+	// cross holds the affix flags that combine with affixes of the other kind.
+	cross map[string]bool
+
+	flagType      string
+	forbidden     string
+	needAffix     string
+	compound      string
+	onlyCompound  string
+	compoundBegin string
+	compoundEnd   string
+	compoundMin   int
+}
+
+// flags are the flags of a dictionary word, each flag as a string.
+type flags []string
+
+func (f flags) has(flag string) bool {
+	if flag == "" {
+		return false
+	}
+	for _, x := range f {
+		if x == flag {
+			return true
+		}
+	}
+	return false
+}
+
+type affix struct {
+	flag  string
+	strip string
+	add   string
+	cond  []condition
+	// contFlags are the flags of the affixed word, for twofold affixes and compounding.
+	contFlags flags
+}
+
+// condition matches one character of a condition pattern.
+type condition struct {
+	any    bool
+	negate bool
+	chars  string
+}
+
+func (c condition) match(r rune) bool {
+	if c.any {
+		return true
+	}
+	return strings.ContainsRune(c.chars, r) != c.negate
+}
+
+func parseCondition(s string) ([]condition, error) {
+	if s == "." {
+		return nil, nil
+	}
+	var conds []condition
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '.':
+			conds = append(conds, condition{any: true})
+			i++
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated condition %q", s)
+			}
+			class := s[i+1 : i+end]
+			c := condition{chars: class}
+			if strings.HasPrefix(class, "^") {
+				c = condition{negate: true, chars: class[1:]}
+			}
+			conds = append(conds, c)
+			i += end + 1
+		default:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			conds = append(conds, condition{chars: string(r)})
+			i += size
+		}
+	}
+	return conds, nil
+}
+
+// matchEnd reports whether the conditions match the end of word, for suffixes.
+func matchEnd(conds []condition, word string) bool {
+	runes := []rune(word)
+	if len(conds) > len(runes) {
+		return false
+	}
+	runes = runes[len(runes)-len(conds):]
+	for i, c := range conds {
+		if !c.match(runes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchStart reports whether the conditions match the start of word, for prefixes.
+func matchStart(conds []condition, word string) bool {
+	runes := []rune(word)
+	if len(conds) > len(runes) {
+		return false
+	}
+	for i, c := range conds {
+		if !c.match(runes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Open loads the dictionary at path, without the .aff or .dic extension.
+func Open(path string) (*Dictionary, error) {
+	aff, err := os.Open(path + ".aff")
+	if err != nil {
+		return nil, err
+	}
+	defer aff.Close()
+	dic, err := os.Open(path + ".dic")
+	if err != nil {
+		return nil, err
+	}
+	defer dic.Close()
+	d, err := Load(aff, dic)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+// Load reads a dictionary from the contents of its .aff and .dic files.
+func Load(aff, dic io.Reader) (*Dictionary, error) {
+	d := &Dictionary{words: make(map[string][]flags), cross: make(map[string]bool), compoundMin: 3}
+	if err := d.readAff(aff); err != nil {
+		return nil, fmt.Errorf("aff: %w", err)
+	}
+	if err := d.readDic(dic); err != nil {
+		return nil, fmt.Errorf("dic: %w", err)
+	}
+	return d, nil
+}
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	return sc
+}
+
+func (d *Dictionary) readAff(r io.Reader) error {
+	sc := newScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch fields[0] {
+		case "SET":
+			if enc := strings.ToUpper(fields[1]); enc != "UTF-8" && enc != "UTF8" {
+				return fmt.Errorf("line %d: unsupported encoding %s, convert the dictionary to UTF-8", line, fields[1])
+			}
+		case "FLAG":
+			d.flagType = fields[1]
+		case "FORBIDDENWORD":
+			d.forbidden = fields[1]
+		case "NEEDAFFIX":
+			d.needAffix = fields[1]
+		case "COMPOUNDFLAG":
+			d.compound = fields[1]
+		case "COMPOUNDBEGIN":
+			d.compoundBegin = fields[1]
+		case "COMPOUNDEND":
+			d.compoundEnd = fields[1]
+		case "ONLYINCOMPOUND":
+			d.onlyCompound = fields[1]
+		case "COMPOUNDMIN":
+			n, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			d.compoundMin = max(n, 1)
+		case "PFX", "SFX":
+			// the header "SFX flag cross count" is followed by count rules
+			// "SFX flag strip add condition"
+			if len(fields) == 4 {
+				if _, err := strconv.Atoi(fields[3]); err == nil {
+					d.cross[fields[1]] = fields[2] == "Y"
+					continue
+				}
+			}
+			if len(fields) < 5 {
+				return fmt.Errorf("line %d: invalid affix rule", line)
+			}
+			a := &affix{flag: fields[1], strip: fields[2]}
+			if a.strip == "0" {
+				a.strip = ""
+			}
+			add, cont, _ := strings.Cut(fields[3], "/")
+			if add != "0" {
+				a.add = add
+			}
+			a.contFlags = d.parseFlags(cont)
+			cond, err := parseCondition(fields[4])
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			a.cond = cond
+			if fields[0] == "PFX" {
+				d.prefixes = append(d.prefixes, a)
+			} else {
+				d.suffixes = append(d.suffixes, a)
+			}
+		}
+	}
+	return sc.Err()
+}
+
+func (d *Dictionary) readDic(r io.Reader) error {
+	sc := newScanner(r)
+	first := true
+	for sc.Scan() {
+		text := strings.TrimSpace(sc.Text())
+		if first {
+			first = false
+			// the first line is the approximate word count
+			if _, err := strconv.Atoi(text); err == nil {
+				continue
+			}
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		// morphological fields follow a tab or space
+		if i := strings.IndexAny(text, "\t "); i >= 0 {
+			text = text[:i]
+		}
+		word, fl := text, ""
+		if i := unescapedSlash(text); i >= 0 {
+			word, fl = text[:i], text[i+1:]
+		}
+		word = strings.ReplaceAll(word, `\/`, "/")
+		d.words[word] = append(d.words[word], d.parseFlags(fl))
+	}
+	return sc.Err()
+}
+
+func unescapedSlash(s string) int {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '/' && s[i-1] != '\\' {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d *Dictionary) parseFlags(s string) flags {
+	if s == "" {
+		return nil
+	}
+	var f flags
+	switch d.flagType {
+	case "long":
+		for i := 0; i+1 < len(s); i += 2 {
+			f = append(f, s[i:i+2])
+		}
+	case "num":
+		for _, n := range strings.Split(s, ",") {
+			f = append(f, strings.TrimSpace(n))
+		}
+	default:
+		for _, r := range s {
+			f = append(f, string(r))
+		}
+	}
+	return f
+}
+
+// Add adds words to the dictionary, such as the words of an allowlist.
+func (d *Dictionary) Add(words ...string) {
+	for _, w := range words {
+		d.words[w] = append(d.words[w], nil)
+	}
+}
+
+// Check reports whether word is spelled correctly. Capitalized and
+// all-uppercase forms of dictionary words are accepted.
+func (d *Dictionary) Check(word string) bool {
+	for _, w := range caseVariants(word) {
+		if ok, found := d.check(w); found {
+			return ok
+		}
+	}
+	return false
+}
+
+func caseVariants(word string) []string {
+	variants := []string{word}
+	lower := strings.ToLower(word)
+	if lower != word {
+		r, size := utf8.DecodeRuneInString(word)
+		if strings.ToUpper(word) == word || (unicode.IsUpper(r) && word[size:] == lower[size:]) {
+			variants = append(variants, lower)
+			// all-uppercase words may be capitalized dictionary words, such as proper nouns
+			if strings.ToUpper(word) == word && len(word) > size {
+				variants = append(variants, string(r)+lower[size:])
+			}
+		}
+	}
+	return variants
+}
+
+// check returns whether word is correct, and whether it was found at all;
+// forbidden words are found but not correct.
+func (d *Dictionary) check(word string) (ok, found bool) {
+	if d.forbiddenWord(word) {
+		return false, true
+	}
+	if d.root(word, false) || d.affixed(word, false) {
+		return true, true
+	}
+	if d.compound != "" || d.compoundBegin != "" {
+		if d.compoundWord(word, 0) {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+func (d *Dictionary) forbiddenWord(word string) bool {
+	for _, f := range d.words[word] {
+		if f.has(d.forbidden) {
+			return true
+		}
+	}
+	return false
+}
+
+// root reports whether word is a dictionary word usable on its own, or as a
+// compound part if inCompound.
+func (d *Dictionary) root(word string, inCompound bool) bool {
+	for _, f := range d.words[word] {
+		if f.has(d.needAffix) || f.has(d.forbidden) {
+			continue
+		}
+		if !inCompound && f.has(d.onlyCompound) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// stemHas reports whether stem is a dictionary word with flag.
+func (d *Dictionary) stemHas(stem, flag string, inCompound bool) bool {
+	for _, f := range d.words[stem] {
+		if f.has(flag) && !f.has(d.forbidden) && (inCompound || !f.has(d.onlyCompound)) {
+			return true
+		}
+	}
+	return false
+}
+
+// affixed reports whether word is a dictionary word with a prefix, a suffix or both.
+func (d *Dictionary) affixed(word string, inCompound bool) bool {
+	for _, s := range d.suffixes {
+		stem, ok := d.stripSuffix(word, s)
+		if !ok {
+			continue
+		}
+		if d.stemHas(stem, s.flag, inCompound) {
+			return true
+		}
+		// a prefix combined with the suffix
+		for _, p := range d.prefixes {
+			if !d.cross[s.flag] || !d.cross[p.flag] {
+				continue
+			}
+			if inner, ok := d.stripPrefix(stem, p); ok && d.stemHasBoth(inner, p.flag, s.flag, inCompound) {
+				return true
+			}
+		}
+		// a twofold suffix, where the outer suffix is a continuation flag of the inner one
+		for _, s2 := range d.suffixes {
+			if !s2.contFlags.has(s.flag) {
+				continue
+			}
+			if inner, ok := d.stripSuffix(stem, s2); ok && d.stemHas(inner, s2.flag, inCompound) {
+				return true
+			}
+		}
+	}
+	for _, p := range d.prefixes {
+		if stem, ok := d.stripPrefix(word, p); ok && d.stemHas(stem, p.flag, inCompound) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dictionary) stemHasBoth(stem, a, b string, inCompound bool) bool {
+	for _, f := range d.words[stem] {
+		if f.has(a) && f.has(b) && !f.has(d.forbidden) && (inCompound || !f.has(d.onlyCompound)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dictionary) stripSuffix(word string, s *affix) (string, bool) {
+	if !strings.HasSuffix(word, s.add) || len(word) == len(s.add) && s.strip == "" {
+		return "", false
+	}
+	stem := word[:len(word)-len(s.add)] + s.strip
+	return stem, matchEnd(s.cond, stem)
+}
+
+func (d *Dictionary) stripPrefix(word string, p *affix) (string, bool) {
+	if !strings.HasPrefix(word, p.add) || len(word) == len(p.add) && p.strip == "" {
+		return "", false
+	}
+	stem := p.strip + word[len(p.add):]
+	return stem, matchStart(p.cond, stem)
+}
+
+// compoundWord reports whether word is a sequence of compound parts, each a
+// root or affixed word with the compound flag.
+func (d *Dictionary) compoundWord(word string, depth int) bool {
+	if depth > 4 {
+		return false
+	}
+	runes := []rune(word)
+	for i := d.compoundMin; i <= len(runes)-d.compoundMin; i++ {
+		head, tail := string(runes[:i]), string(runes[i:])
+		begin := d.compoundBegin
+		if depth > 0 || begin == "" {
+			begin = d.compound
+		}
+		if !d.compoundPart(head, begin) {
+			continue
+		}
+		end := d.compoundEnd
+		if end == "" {
+			end = d.compound
+		}
+		if d.compoundPart(tail, end) || d.compoundPart(tail, d.compound) || d.compoundWord(tail, depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dictionary) compoundPart(part, flag string) bool {
+	if flag == "" {
+		return false
+	}
+	if d.stemHas(part, flag, true) {
+		return true
+	}
+	for _, s := range d.suffixes {
+		if stem, ok := d.stripSuffix(part, s); ok && d.stemHas(stem, s.flag, true) && (s.contFlags.has(flag) || d.stemHas(stem, flag, true)) {
+			return true
+		}
+	}
+	return false
+}
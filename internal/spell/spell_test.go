@@ -0,0 +1,90 @@
+package spell
+
+import (
+	"strings"
+	"testing"
+)
+
+const testAff = `SET UTF-8
+FLAG long
+FORBIDDENWORD !!
+COMPOUNDFLAG Cc
+COMPOUNDMIN 1
+
+PFX Un Y 1
+PFX Un 0 un .
+
+SFX Sx Y 3
+SFX Sx y ies [^aeiou]y
+SFX Sx 0 s [aeiou]y
+SFX Sx 0 s [^y]
+
+SFX Ed Y 2
+SFX Ed 0 ed [^e]
+SFX Ed 0 d e
+
+SFX Ly N 1
+SFX Ly 0 ly/Sx .
+
+SFX Ko Y 2
+SFX Ko 하다 한다 하다
+SFX Ko 하다 했다 하다
+`
+
+const testDic = `8
+lock/UnEdSx
+city/Sx
+day/Sx
+bake/Ed
+quick/Ly
+colour/!!
+고루틴/Cc
+채널/Cc
+공부하다/Ko
+`
+
+func TestCheck(t *testing.T) {
+	d, err := Load(strings.NewReader(testAff), strings.NewReader(testDic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Add("GoSuda")
+
+	for word, want := range map[string]bool{
+		"lock":     true,
+		"Lock":     true,
+		"LOCK":     true,
+		"unlocked": true,
+		"unlocks":  true,
+		"cities":   true,
+		"citys":    false,
+		"days":     true,
+		"baked":    true,
+		"bakeed":   false,
+		"quicklys": false, // Ly is not cross and has no twofold rule for Sx as outer suffix
+		"colour":   false,
+		"GoSuda":   true,
+		"gosuda":   false,
+		"lokc":     false,
+		"고루틴":      true,
+		"고루틴채널":    true,
+		"공부한다":     true,
+		"공부했다":     true,
+		"공부했어":     false,
+	} {
+		if got := d.Check(word); got != want {
+			t.Errorf("Check(%q) = %v, want %v", word, got, want)
+		}
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	d := &Dictionary{flagType: "num"}
+	if f := d.parseFlags("101,2"); len(f) != 2 || f[0] != "101" || f[1] != "2" {
+		t.Errorf("num flags = %q", f)
+	}
+	d.flagType = ""
+	if f := d.parseFlags("AB"); len(f) != 2 || f[1] != "B" {
+		t.Errorf("char flags = %q", f)
+	}
+}
@@ -0,0 +1,99 @@
+package tagging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lemon-mint/coord/llm"
+	"github.com/lemon-mint/coord/llmtools"
+)
+
+const prompt = `You are tasked with choosing tags for a blog post based on its Markdown source. Tags group related posts on the blog, so a consistent vocabulary matters more than precise wording.
+
+Here is the document you need to analyze:
+<document>
+<INPUT_DOCUMENT>
+</document>
+
+These are the tags already used on the blog, with the number of posts using each one:
+<vocabulary>
+<VOCABULARY>
+</vocabulary>
+
+To complete this task:
+1. Carefully read the document and identify its main topics, technologies, and concepts.
+2. Prefer tags from the vocabulary. Only propose a new tag when no existing tag covers an important topic.
+3. Write new tags in the style of the vocabulary: lowercase, short, and in English.
+
+Choose at most <MAX_TAGS> tags, the most relevant first.
+
+Present the tags one per line in the following format:
+[START_TOKEN]
+tag
+tag
+[END_TOKEN]`
+
+var (
+	ErrFailedToSuggestTags = errors.New("failed to suggest tags")
+)
+
+// Tag is a tag of the existing vocabulary.
+type Tag struct {
+	Name  string
+	Count int
+}
+
+// SuggestTags proposes at most n tags for the markdown document input,
+// preferring the tags of vocabulary.
+func SuggestTags(ctx context.Context, l llm.Model, input string, vocabulary []Tag, n int) ([]string, error) {
+	var b [8]byte
+	rand.Read(b[:])
+	startToken := "[" + hex.EncodeToString(b[:]) + "]"
+	rand.Read(b[:])
+	endToken := "[" + hex.EncodeToString(b[:]) + "]"
+
+	var vocab strings.Builder
+	for _, t := range vocabulary {
+		vocab.WriteString(t.Name + " (" + strconv.Itoa(t.Count) + ")\n")
+	}
+	if len(vocabulary) == 0 {
+		vocab.WriteString("(none yet)\n")
+	}
+
+	prompt := strings.Replace(prompt, "[START_TOKEN]", startToken, 1)
+	prompt = strings.Replace(prompt, "[END_TOKEN]", endToken, 1)
+	prompt = strings.Replace(prompt, "<MAX_TAGS>", strconv.Itoa(n), 1)
+	prompt = strings.Replace(prompt, "<VOCABULARY>", strings.TrimSpace(vocab.String()), 1)
+	prompt = strings.Replace(prompt, "<INPUT_DOCUMENT>", input, 1)
+
+	resp := l.GenerateStream(ctx, &llm.ChatContext{}, llm.TextContent(llm.RoleUser, prompt))
+	err := resp.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	text := llmtools.TextFromContents(resp.Content)
+	sidx := strings.Index(text, startToken)
+	eidx := strings.Index(text, endToken)
+	if sidx == -1 || eidx == -1 || eidx < sidx {
+		return nil, ErrFailedToSuggestTags
+	}
+
+	var tags []string
+	for _, line := range strings.Split(text[sidx+len(startToken):eidx], "\n") {
+		tag := strings.ToLower(strings.TrimSpace(strings.TrimLeft(line, "-*# \t")))
+		if tag == "" || len(tags) == n || slices.Contains(tags, tag) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) == 0 {
+		return nil, ErrFailedToSuggestTags
+	}
+	return tags, nil
+}
@@ -0,0 +1,124 @@
+package translate
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	"github.com/lemon-mint/coord/llm"
+	"github.com/rs/zerolog/log"
+	"github.com/zeebo/blake3"
+)
+
+// Memory is a translation memory, mapping the keys of source segments to their
+// translations.
+type Memory map[string]string
+
+// Segments splits markdown text into paragraphs, keeping fenced code blocks
+// whole. Each segment keeps the blank lines that follow it, so that the
+// segments concatenate back into text.
+func Segments(text string) []string {
+	var segments []string
+	var current strings.Builder
+	var fence string
+	flush := func() {
+		s := current.String()
+		current.Reset()
+		if strings.TrimSpace(s) == "" && len(segments) > 0 {
+			segments[len(segments)-1] += s
+			return
+		}
+		segments = append(segments, s)
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			fence = trimmed[:3]
+		case fence != "" && strings.HasPrefix(trimmed, fence):
+			fence = ""
+		}
+		current.WriteString(line)
+		if fence == "" && trimmed == "" {
+			flush()
+		}
+	}
+	if current.Len() > 0 {
+		flush()
+	}
+	return segments
+}
+
+// SegmentKey returns the memory key of a segment, ignoring its trailing blank lines.
+func SegmentKey(segment string) string {
+	h := blake3.New()
+	h.WriteString(strings.TrimRight(segment, " \t\n"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// splitTrailingSpace splits the trailing blank lines off a segment.
+func splitTrailingSpace(segment string) (body, space string) {
+	body = strings.TrimRight(segment, " \t\n")
+	return body, segment[len(body):]
+}
+
+// TranslateWithMemory translates input like Translate, reusing the
+// translations of the segments found in mem and translating only the others.
+// It returns the translation and the memory of its segments, which replaces
+// mem once the translation is accepted.
+//
+// If no segment is found in mem, input is translated as a whole, and the
+// memory is filled in if the translation has as many segments as input.
+func TranslateWithMemory(ctx context.Context, l llm.Model, input, targetLanguage string, mem Memory, terms ...Term) (string, Memory, error) {
+	segments := Segments(input)
+	next := make(Memory, len(segments))
+
+	var hits int
+	for _, seg := range segments {
+		if _, ok := mem[SegmentKey(seg)]; ok {
+			hits++
+		}
+	}
+
+	if hits == 0 {
+		translated, err := Translate(ctx, l, input, targetLanguage, terms...)
+		if err != nil {
+			return "", nil, err
+		}
+		if out := Segments(translated); len(out) == len(segments) {
+			for i, seg := range segments {
+				body, _ := splitTrailingSpace(out[i])
+				next[SegmentKey(seg)] = body
+			}
+		} else {
+			log.Debug().Int("segments", len(segments)).Int("translated_segments", len(out)).Msg("translated segments are not aligned, not filling translation memory")
+		}
+		return translated, next, nil
+	}
+
+	log.Debug().Int("segments", len(segments)).Int("reused", hits).Msg("reusing translation memory")
+	var b strings.Builder
+	for _, seg := range segments {
+		body, space := splitTrailingSpace(seg)
+		if body == "" {
+			b.WriteString(seg)
+			continue
+		}
+		key := SegmentKey(seg)
+		translated, ok := mem[key]
+		if !ok {
+			t, err := Translate(ctx, l, body, targetLanguage, terms...)
+			if err != nil {
+				return "", nil, err
+			}
+			translated, _ = splitTrailingSpace(t)
+		}
+		next[key] = translated
+		b.WriteString(translated)
+		b.WriteString(space)
+	}
+	return b.String(), next, nil
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/hex"
 	"errors"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"cloud.google.com/go/vertexai/genai"
 	"cloud.google.com/go/vertexai/genai/tokenizer"
@@ -172,8 +174,94 @@ var (
 	ErrFailedToTranslate = errors.New("failed to translate")
 )
 
-func translateChunk(ctx context.Context, l llm.Model, chunk string, targetLanguage string) (string, error) {
+// Term is a glossary entry. Source is translated as Translation, or kept
+// unchanged if Translation is empty.
+type Term struct {
+	Source      string
+	Translation string
+}
+
+// Want returns the text expected in the translation in place of t.Source.
+func (t Term) Want() string {
+	if t.Translation == "" {
+		return t.Source
+	}
+	return t.Translation
+}
+
+// containsTerm reports whether s contains term with the same case, so that
+// "Go" is not found in "let's go". Terms starting or ending with a letter or
+// digit must not be part of a longer Latin word, so that "Go" is not found in
+// "Good", while a Korean particle may follow a term.
+func containsTerm(s, term string) bool {
+	if term == "" {
+		return false
+	}
+	for i := 0; i <= len(s)-len(term); {
+		j := strings.Index(s[i:], term)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(term)
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if !isLatinWordRune(before) && !isLatinWordRune(after) {
+			return true
+		}
+		i = start + 1
+	}
+	return false
+}
+
+func isLatinWordRune(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// relevantTerms returns the terms found in text.
+func relevantTerms(text string, terms []Term) []Term {
+	var found []Term
+	for _, t := range terms {
+		if containsTerm(text, t.Source) {
+			found = append(found, t)
+		}
+	}
+	return found
+}
+
+// glossaryPrompt instructs the translator to follow the glossary terms found in chunk.
+func glossaryPrompt(chunk string, terms []Term) string {
+	terms = relevantTerms(chunk, terms)
+	if len(terms) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("GLOSSARY:\nUse exactly these translations for the following terms, and keep the terms marked as unchanged in their original form.\n")
+	for _, t := range terms {
+		if t.Translation == "" {
+			b.WriteString("- " + t.Source + " → (unchanged)\n")
+		} else {
+			b.WriteString("- " + t.Source + " → " + t.Translation + "\n")
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// MissingTerms returns the terms found in source whose expected translation
+// is missing from translated.
+func MissingTerms(source, translated string, terms []Term) []Term {
+	var missing []Term
+	for _, t := range relevantTerms(source, terms) {
+		if !containsTerm(translated, t.Want()) {
+			missing = append(missing, t)
+		}
+	}
+	return missing
+}
+
+func translateChunk(ctx context.Context, l llm.Model, chunk string, targetLanguage string, terms []Term) (string, error) {
 	prompt := strings.Replace(prompt, "<TARGET_LANGUAGE>", targetLanguage, -1)
+	prompt = strings.Replace(prompt, "INPUT_TEXT:\n\n", glossaryPrompt(chunk, terms)+"INPUT_TEXT:\n\n", 1)
 
 	var b [8]byte
 	rand.Read(b[:])
@@ -200,12 +288,13 @@ func translateChunk(ctx context.Context, l llm.Model, chunk string, targetLangua
 	return "", ErrFailedToTranslate
 }
 
-func Translate(ctx context.Context, l llm.Model, input, targetLanguage string) (string, error) {
+// Translate translates input into targetLanguage, following the glossary terms.
+func Translate(ctx context.Context, l llm.Model, input, targetLanguage string, terms ...Term) (string, error) {
 	chunks := chunkMarkdown(input)
 	translatedChunks := make([]string, len(chunks))
 
 	for i, chunk := range chunks {
-		translatedChunk, err := translateChunk(ctx, l, chunk, targetLanguage)
+		translatedChunk, err := translateChunk(ctx, l, chunk, targetLanguage, terms)
 		if err != nil {
 			return "", err
 		}
@@ -194,3 +194,79 @@ Final paragraph to conclude the headers and tables test.`,
 		})
 	}
 }
+
+func TestMissingTerms(t *testing.T) {
+	terms := []Term{
+		{Source: "goroutine"},
+		{Source: "Go"},
+		{Source: "channel", Translation: "채널"},
+	}
+
+	testCases := []struct {
+		name       string
+		source     string
+		translated string
+		want       []string
+	}{
+		{
+			name:       "kept and translated terms",
+			source:     "A goroutine sends values to a channel in Go.",
+			translated: "goroutine은 Go에서 채널로 값을 보냅니다.",
+		},
+		{
+			name:       "mangled terms",
+			source:     "A goroutine sends values to a channel.",
+			translated: "고루틴은 통로로 값을 보냅니다.",
+			want:       []string{"goroutine", "channel"},
+		},
+		{
+			name:       "terms inside longer words are ignored",
+			source:     "A good gopher.",
+			translated: "좋은 고퍼.",
+		},
+		{
+			name:       "terms are case-sensitive",
+			source:     "Let's go.",
+			translated: "가자.",
+		},
+		{
+			name:       "terms inside longer words do not count",
+			source:     "Written in Go.",
+			translated: "Written in Golang.",
+			want:       []string{"Go"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, term := range MissingTerms(tc.source, tc.translated, terms) {
+				got = append(got, term.Source)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("MissingTerms() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSegments(t *testing.T) {
+	input := "# Title\n\nFirst paragraph\nstill first.\n\n\n" + "```go\nfunc main() {\n\n}\n```\n\nLast."
+	want := []string{
+		"# Title\n\n",
+		"First paragraph\nstill first.\n\n\n",
+		"```go\nfunc main() {\n\n}\n```\n\n",
+		"Last.",
+	}
+
+	got := Segments(input)
+	if strings.Join(got, "") != input {
+		t.Errorf("Segments() do not concatenate back into the input: %q", got)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Segments() = %q, want %q", got, want)
+	}
+	if SegmentKey("Last.") != SegmentKey("Last.\n\n") {
+		t.Errorf("SegmentKey() depends on trailing blank lines")
+	}
+}
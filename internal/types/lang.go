@@ -1,5 +1,11 @@
 package types
 
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
 type Lang = string
 
 const (
@@ -25,6 +31,57 @@ const (
 	LangTurkish    Lang = "tr"
 )
 
+// rtlLanguages are the languages written right-to-left.
+var rtlLanguages = map[Lang]bool{
+	"ar": true, // Arabic
+	"fa": true, // Persian
+	"he": true, // Hebrew
+	"ps": true, // Pashto
+	"sd": true, // Sindhi
+	"ug": true, // Uyghur
+	"ur": true, // Urdu
+	"yi": true, // Yiddish
+}
+
+// IsRTL reports whether lang, a language code with an optional region or
+// script subtag, is written right-to-left.
+func IsRTL(lang Lang) bool {
+	base, _, _ := strings.Cut(lang, "-")
+	return rtlLanguages[strings.ToLower(base)]
+}
+
+// ParseLang validates a BCP-47 language tag, returning it in canonical form
+// (e.g. "ko-kr" becomes "ko-KR") and its base language (e.g. "ko"), which
+// keys translations and URLs.
+func ParseLang(s string) (tag string, base Lang, err error) {
+	t, err := language.Parse(s)
+	if err != nil {
+		return "", "", err
+	}
+	b, _ := t.Base()
+	return t.String(), b.String(), nil
+}
+
+// OGLocale returns the Open Graph locale of a BCP-47 tag, a language and
+// territory like "ko_KR". Tags without a region get the most likely one.
+func OGLocale(tag string) string {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return ""
+	}
+	b, _ := t.Base()
+	r, _ := t.Region()
+	return b.String() + "_" + r.String()
+}
+
+// Direction returns the text direction of lang, "rtl" or "ltr".
+func Direction(lang Lang) string {
+	if IsRTL(lang) {
+		return "rtl"
+	}
+	return "ltr"
+}
+
 var SupportedLanguages = []Lang{
 	LangEnglish,
 	LangSpanish,
@@ -94,3 +151,51 @@ func FullLangName(lang Lang) string {
 		return "Unknown"
 	}
 }
+
+// NativeLangName returns the name of lang in lang itself, as shown in language switchers.
+func NativeLangName(lang Lang) string {
+	switch lang {
+	case LangEnglish:
+		return "English"
+	case LangSpanish:
+		return "Español"
+	case LangChinese:
+		return "中文"
+	case LangKorean:
+		return "한국어"
+	case LangJapanese:
+		return "日本語"
+	case LangGerman:
+		return "Deutsch"
+	case LangRussian:
+		return "Русский"
+	case LangFrench:
+		return "Français"
+	case LangDutch:
+		return "Nederlands"
+	case LangItalian:
+		return "Italiano"
+	case LangIndonesian:
+		return "Bahasa Indonesia"
+	case LangPortuguese:
+		return "Português"
+	case LangSwedish:
+		return "Svenska"
+	case LangCzech:
+		return "Čeština"
+	case LangSlovak:
+		return "Slovenčina"
+	case LangPolish:
+		return "Polski"
+	case LangRomanian:
+		return "Română"
+	case LangHungarian:
+		return "Magyar"
+	case LangFinnish:
+		return "Suomi"
+	case LangTurkish:
+		return "Türkçe"
+	default:
+		return FullLangName(lang)
+	}
+}
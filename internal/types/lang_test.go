@@ -0,0 +1,42 @@
+package types
+
+import "testing"
+
+func TestParseLang(t *testing.T) {
+	testCases := []struct {
+		in, tag, base, locale string
+	}{
+		{"ko", "ko", "ko", "ko_KR"},
+		{"ko-kr", "ko-KR", "ko", "ko_KR"},
+		{"pt-BR", "pt-BR", "pt", "pt_BR"},
+		{"zh-Hant-TW", "zh-Hant-TW", "zh", "zh_TW"},
+		{"en", "en", "en", "en_US"},
+	}
+	for _, tc := range testCases {
+		tag, base, err := ParseLang(tc.in)
+		if err != nil {
+			t.Errorf("ParseLang(%q) error: %v", tc.in, err)
+			continue
+		}
+		if tag != tc.tag || base != tc.base {
+			t.Errorf("ParseLang(%q) = %q, %q, want %q, %q", tc.in, tag, base, tc.tag, tc.base)
+		}
+		if locale := OGLocale(tag); locale != tc.locale {
+			t.Errorf("OGLocale(%q) = %q, want %q", tag, locale, tc.locale)
+		}
+	}
+
+	for _, in := range []string{"korean", "ko_KR!", ""} {
+		if _, _, err := ParseLang(in); err == nil {
+			t.Errorf("ParseLang(%q) accepted an invalid tag", in)
+		}
+	}
+}
+
+func TestDirection(t *testing.T) {
+	for lang, want := range map[Lang]string{"ar": "rtl", "he-IL": "rtl", "fa": "rtl", "ko": "ltr", "en-US": "ltr"} {
+		if got := Direction(lang); got != want {
+			t.Errorf("Direction(%q) = %q, want %q", lang, got, want)
+		}
+	}
+}
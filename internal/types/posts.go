@@ -38,6 +38,9 @@ type Post struct {
 	Main *Document `json:"main,omitempty" yaml:"main,omitempty"`
 	// Translated contains translated versions of the post content, keyed by language code.
 	Translated map[string]*Document `json:"translated,omitempty" yaml:"translated,omitempty"`
+	// TranslationMemory contains the translations of the paragraphs of the main
+	// document, keyed by language code and source paragraph hash.
+	TranslationMemory map[string]map[string]string `json:"translation_memory,omitempty" yaml:"translation_memory,omitempty"`
 }
 
 // DocumentType represents the type of a document (e.g., Markdown, HTML).
@@ -51,6 +54,29 @@ const (
 	DocumentTypeHTML                         // html
 )
 
+// StatusPublished is the editorial state of the posts on the site.
+const StatusPublished = "published"
+
+// Kind is the kind of content of a document.
+type Kind string
+
+const (
+	// KindPost is a dated blog post, listed on the index and in feeds. (default)
+	KindPost Kind = "post"
+	// KindPage is a standalone page at a custom path, such as /about, which is
+	// left out of the index, feeds and archives.
+	KindPage Kind = "page"
+	// KindDoc is a page of the documentation tree under root/docs, listed in
+	// the docs sidebar instead of the index. (set from the file location)
+	KindDoc Kind = "doc"
+	// KindTalk is a conference talk, listed on the talks page with its event,
+	// slides and video instead of the index.
+	KindTalk Kind = "talk"
+	// KindLink is a short link post about an external page, shown inline on
+	// the index and published in the links feed instead of the main feeds.
+	KindLink Kind = "link"
+)
+
 // Document represents the content and metadata of a post in a specific language.
 type Document struct {
 	// Type indicates the format of the document content.
@@ -61,8 +87,44 @@ type Document struct {
 	HTML string `json:"html,omitempty" yaml:"html,omitempty"`
 	// Metadata contains any additional metadata parsed from the Markdown document.
 	Metadata Metadata `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	// FilePath is the source file of the document, if it is tracked in the repository. (optional)
+	FilePath string `json:"file_path,omitempty" yaml:"file_path,omitempty"`
+	// SourceHash is the Post.Hash of the main document this document was translated from. (translations only)
+	SourceHash string `json:"source_hash,omitempty" yaml:"source_hash,omitempty"`
+	// Lang is the BCP-47 language tag of the document (e.g. "ko-KR"), whose base
+	// language is Metadata.Language.
+	Lang string `json:"lang,omitempty" yaml:"lang,omitempty"`
+	// Source is who translated the document. (translations only, default: TranslationMachine)
+	Source TranslationSource `json:"source,omitempty" yaml:"source,omitempty"`
+	// Features are the kinds of content of the document that need scripts or stylesheets.
+	Features Features `json:"features,omitempty" yaml:"features,omitempty"`
+}
+
+// Features flags the kinds of content of a document, so that pages load only
+// the scripts and stylesheets their content needs.
+type Features struct {
+	// Code is set for code blocks.
+	Code bool `json:"code,omitempty" yaml:"code,omitempty"`
+	// Math is set for math blocks.
+	Math bool `json:"math,omitempty" yaml:"math,omitempty"`
+	// Mermaid is set for Mermaid diagrams.
+	Mermaid bool `json:"mermaid,omitempty" yaml:"mermaid,omitempty"`
+	// Gallery is set for gallery shortcodes.
+	Gallery bool `json:"gallery,omitempty" yaml:"gallery,omitempty"`
+	// Tabs is set for groups of tabs.
+	Tabs bool `json:"tabs,omitempty" yaml:"tabs,omitempty"`
 }
 
+// TranslationSource tells machine translations from human translations.
+type TranslationSource string
+
+const (
+	// TranslationMachine is a translation made by the translation pipeline, which replaces it when the main document changes.
+	TranslationMachine TranslationSource = "machine"
+	// TranslationHuman is a translation maintained in a source file, which the translation pipeline never replaces.
+	TranslationHuman TranslationSource = "human"
+)
+
 // Metadata is a struct that holds various types of meta data parsed from a Markdown document
 type Metadata struct {
 	// ID is the unique identifier for the post. (propagated to Post.ID)
@@ -77,22 +139,102 @@ type Metadata struct {
 	Language string `json:"language,omitempty" yaml:"language,omitempty"`
 	// Date is the publication date of the document.
 	Date time.Time `json:"date,omitempty" yaml:"date,omitempty"`
+	// Kind is the kind of content, a post or a standalone page. (default: "post")
+	Kind Kind `json:"kind,omitempty" yaml:"kind,omitempty"`
 	// Path is the URL path for the post. (propagated to Post.Path)
 	Path string `json:"path,omitempty" yaml:"path,omitempty"`
 	// GoPackage is the Go package associated with the post (optional). Only effective if the post is Main Document.
 	GoPackage string `json:"go_package,omitempty" yaml:"go_package,omitempty"`
 	// GoRepoURL is the URL of the Go package repository (optional). Only effective if the post is Main Document.
 	GoRepoURL string `json:"go_repourl,omitempty" yaml:"go_repourl,omitempty"`
-	// Canonical is the canonical URL for the post.
+	// Canonical is the canonical URL for the post, absolute or relative to the site. (default: the URL of the post)
 	Canonical string `json:"canonical,omitempty" yaml:"canonical,omitempty"`
 	// Hidden indicates whether the post should be listed on the front page.
 	Hidden bool `json:"hidden,omitempty" yaml:"hidden,omitempty"`
+	// Draft keeps the post out of the site until it is unset; drafts can be
+	// shared with preview links.
+	Draft bool `json:"draft,omitempty" yaml:"draft,omitempty"`
+	// Status is the editorial state of the post, one of workflow.states. Only
+	// published posts reach the site. (default: "published", or "draft" for drafts)
+	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+	// Image is the site path of the cover image, relative to the public directory. (e.g. "/assets/images/post/cover.png")
+	Image string `json:"image,omitempty" yaml:"image,omitempty"`
+	// ImageAlt is the alternative text of the cover image.
+	ImageAlt string `json:"image_alt,omitempty" yaml:"image_alt,omitempty"`
+	// Thumbnail is the site path of the image shown on index cards. (default: generated from Image)
+	Thumbnail string `json:"thumbnail,omitempty" yaml:"thumbnail,omitempty"`
+	// AudioFile is the site path of an audio version of the post, relative to the public directory. (optional)
+	AudioFile string `json:"audio_file,omitempty" yaml:"audio_file,omitempty"`
+	// Duration is the length of the audio file. (e.g. "12:34" or "1:02:03")
+	Duration string `json:"duration,omitempty" yaml:"duration,omitempty"`
+	// Tags is a list of topics of the post.
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// Series is the name of the series the post belongs to. (optional, see the series section of config.jsonnet)
+	Series string `json:"series,omitempty" yaml:"series,omitempty"`
+	// SeriesOrder is the position of the post in its series; ties are ordered by date.
+	SeriesOrder int `json:"series_order,omitempty" yaml:"series_order,omitempty"`
+	// Pinned keeps the post at the top of the index regardless of its date.
+	Pinned bool `json:"pinned,omitempty" yaml:"pinned,omitempty"`
+	// Featured shows the post in the homepage hero section and the featured sidebar.
+	Featured bool `json:"featured,omitempty" yaml:"featured,omitempty"`
+	// Weight orders pinned and featured posts, ties by date, and the pages of
+	// the docs sidebar, ties by title; lower weights come first.
+	Weight int `json:"weight,omitempty" yaml:"weight,omitempty"`
+	// LastReviewed is the date the post was last checked for accuracy. (optional)
+	LastReviewed time.Time `json:"last_reviewed,omitempty" yaml:"last_reviewed,omitempty"`
+	// ExpiryDate is the date after which the post is considered outdated. (optional)
+	ExpiryDate time.Time `json:"expiry_date,omitempty" yaml:"expiry_date,omitempty"`
+	// Comments overrides whether the comment section is shown. (default: shown when comments are configured)
+	Comments *bool `json:"comments,omitempty" yaml:"comments,omitempty"`
+	// NoIndex asks search engines not to index the post. (hidden posts are never indexed)
+	NoIndex bool `json:"noindex,omitempty" yaml:"noindex,omitempty"`
+	// NoGlossary keeps the terms of the post from being linked to the glossary page.
+	NoGlossary bool `json:"no_glossary,omitempty" yaml:"no_glossary,omitempty"`
 	// NoTranslate indicates whether the post should be translated.
 	NoTranslate bool `json:"no_translate,omitempty" yaml:"no_translate,omitempty"`
 	// IgnoreLangs is a list of languages to ignore when translating the post.
 	IgnoreLangs []string `json:"ignore_langs,omitempty" yaml:"ignore_langs,omitempty"`
 	// LangCanonical is the canonical URL for the post in a specific language.
 	LangCanonical map[string]string `json:"lang_canonical,omitempty" yaml:"lang_canonical,omitempty"`
+	// Event is the name of the event a talk was given at. (talks only)
+	Event string `json:"event,omitempty" yaml:"event,omitempty"`
+	// EventURL is the website of the event. (talks only, optional)
+	EventURL string `json:"event_url,omitempty" yaml:"event_url,omitempty"`
+	// SlidesURL is the URL of the slides of a talk, embedded if it is a PDF or Google Slides. (talks only, optional)
+	SlidesURL string `json:"slides_url,omitempty" yaml:"slides_url,omitempty"`
+	// VideoURL is the URL of the recording of a talk, embedded if it is on YouTube or Vimeo. (talks only, optional)
+	VideoURL string `json:"video_url,omitempty" yaml:"video_url,omitempty"`
+	// LinkURL is the page a link post points to, which its title links to in
+	// the index and feeds. (links only, required)
+	LinkURL string `json:"link_url,omitempty" yaml:"link_url,omitempty"`
+	// Sidenotes shows the footnotes in the margin beside the text on wide screens, keeping them at the end of the post on narrow screens.
+	Sidenotes bool `json:"sidenotes,omitempty" yaml:"sidenotes,omitempty"`
+	// Bibliography is the BibTeX (.bib) or CSL-JSON (.json) file of the works cited with `[@key]`,
+	// relative to the post file or a site path in the public directory. Only effective if the post is Main Document.
+	Bibliography string `json:"bibliography,omitempty" yaml:"bibliography,omitempty"`
+	// ExtraCSS are stylesheets linked by the page of the post, site paths or URLs
+	// of the origins allowed by the extras section of config.jsonnet. Only effective if the post is Main Document.
+	ExtraCSS []string `json:"extra_css,omitempty" yaml:"extra_css,omitempty"`
+	// ExtraJS are scripts loaded by the page of the post, likewise. Only effective if the post is Main Document.
+	ExtraJS []string `json:"extra_js,omitempty" yaml:"extra_js,omitempty"`
+	// HeadHTML is markup added to the head of the page of the post, of the elements
+	// allowed by the extras section of config.jsonnet. Only effective if the post is Main Document.
+	HeadHTML string `json:"head_html,omitempty" yaml:"head_html,omitempty"`
+	// Aliases is a list of old URL paths that redirect to the post.
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	// TranslationOf is the ID of the post this file translates, marking it as a human translation. (optional, requires Language)
+	TranslationOf string `json:"translation_of,omitempty" yaml:"translation_of,omitempty"`
+}
+
+// IsPost reports whether the document is a blog post, rather than a page or
+// a doc.
+func (g *Metadata) IsPost() bool {
+	return g.Kind == "" || g.Kind == KindPost
+}
+
+// IsPage reports whether the document is a standalone page rather than a post.
+func (g *Metadata) IsPage() bool {
+	return g.Kind == KindPage
 }
 
 func (g *Metadata) Hash() string {
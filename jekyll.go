@@ -0,0 +1,380 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"gosuda.org/website/internal/frontmatter"
+	"gosuda.org/website/internal/htmltomd"
+	"gosuda.org/website/internal/types"
+)
+
+// jekyllPage is a post, draft or page of a Jekyll site.
+type jekyllPage struct {
+	// file is the path of the source file relative to the site, slash separated.
+	file       string
+	front      map[string]any
+	body       string
+	slug       string
+	post       bool
+	categories []string
+	meta       *types.Metadata
+}
+
+var jekyllPermalinkStyles = map[string]string{
+	"date":     "/:categories/:year/:month/:day/:title:output_ext",
+	"pretty":   "/:categories/:year/:month/:day/:title/",
+	"ordinal":  "/:categories/:year/:y_day/:title:output_ext",
+	"weekdate": "/:categories/:year/W:week/:short_day/:title:output_ext",
+	"none":     "/:categories/:title:output_ext",
+}
+
+var (
+	jekyllPostName    = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-(.+)$`)
+	jekyllPlaceholder = regexp.MustCompile(`:(\w+)`)
+)
+
+// originalURL returns the URL path the page had on the Jekyll site.
+func (p *jekyllPage) originalURL(permalink string) string {
+	if style, ok := jekyllPermalinkStyles[permalink]; ok {
+		permalink = style
+	}
+	if u := frontString(p.front, "permalink"); u != "" {
+		permalink = u
+	} else if !p.post {
+		name := strings.TrimSuffix(p.file, path.Ext(p.file))
+		if strings.HasSuffix(permalink, "/") {
+			return "/" + name + "/"
+		}
+		return "/" + name + ".html"
+	}
+
+	date := p.meta.Date
+	u := jekyllPlaceholder.ReplaceAllStringFunc(permalink, func(m string) string {
+		switch m[1:] {
+		case "year":
+			return date.Format("2006")
+		case "short_year":
+			return date.Format("06")
+		case "month":
+			return date.Format("01")
+		case "i_month":
+			return date.Format("1")
+		case "short_month":
+			return date.Format("Jan")
+		case "long_month":
+			return date.Format("January")
+		case "day":
+			return date.Format("02")
+		case "i_day":
+			return date.Format("2")
+		case "y_day":
+			return date.Format("002")
+		case "hour":
+			return date.Format("15")
+		case "minute":
+			return date.Format("04")
+		case "second":
+			return date.Format("05")
+		case "title", "slug":
+			return p.slug
+		case "categories":
+			var parts []string
+			for _, c := range p.categories {
+				parts = append(parts, importSlug(c))
+			}
+			return strings.Join(parts, "/")
+		case "output_ext":
+			return ".html"
+		}
+		return m
+	})
+	for strings.Contains(u, "//") {
+		u = strings.ReplaceAll(u, "//", "/")
+	}
+	return u
+}
+
+var (
+	liquidRaw       = regexp.MustCompile(`(?s)\{%-?\s*raw\s*-?%\}(.*?)\{%-?\s*endraw\s*-?%\}`)
+	liquidHighlight = regexp.MustCompile(`(?s)\{%-?\s*highlight\s+([\w+#-]*)[^%]*-?%\}\n?(.*?)\n?\{%-?\s*endhighlight\s*-?%\}`)
+	liquidTag       = regexp.MustCompile(`(?s)\{%-?\s*(\w+)(.*?)-?%\}`)
+	liquidOutput    = regexp.MustCompile(`(?s)\{\{-?\s*(.*?)\s*-?\}\}`)
+	liquidURLFilter = regexp.MustCompile(`^["']([^"']*)["']\s*\|\s*(?:relative_url|absolute_url|prepend:\s*site\.baseurl)$`)
+	kramdownIAL     = regexp.MustCompile(`\{:[^}\n]*\}`)
+)
+
+// jekyllImport holds the state of a Jekyll site import.
+type jekyllImport struct {
+	site  string
+	pages []*jekyllPage
+}
+
+// ref resolves a post_url or link tag argument to the site path of an imported page.
+func (j *jekyllImport) ref(target string) (string, bool) {
+	target = strings.Trim(strings.TrimSpace(target), `"'`)
+	for _, p := range j.pages {
+		name := strings.TrimSuffix(p.file, path.Ext(p.file))
+		if target == p.file || target == name || strings.HasSuffix(name, "/"+target) {
+			return p.meta.Path, true
+		}
+	}
+	return "", false
+}
+
+// convert rewrites the Liquid markup and kramdown attribute lists of a page body to plain Markdown.
+func (j *jekyllImport) convert(p *jekyllPage) string {
+	file := filepath.Join(j.site, filepath.FromSlash(p.file))
+
+	// raw blocks are kept verbatim, so they are swapped out before converting the rest
+	var raws []string
+	body := liquidRaw.ReplaceAllStringFunc(p.body, func(m string) string {
+		raws = append(raws, liquidRaw.FindStringSubmatch(m)[1])
+		return "\x00raw\x00"
+	})
+
+	body = liquidHighlight.ReplaceAllStringFunc(body, func(m string) string {
+		sub := liquidHighlight.FindStringSubmatch(m)
+		fence := "```"
+		for strings.Contains(sub[2], fence) {
+			fence += "`"
+		}
+		return fence + sub[1] + "\n" + sub[2] + "\n" + fence
+	})
+	body = liquidTag.ReplaceAllStringFunc(body, func(m string) string {
+		sub := liquidTag.FindStringSubmatch(m)
+		switch sub[1] {
+		case "post_url", "link":
+			if target, ok := j.ref(sub[2]); ok {
+				return target
+			}
+		case "comment", "endcomment":
+			return ""
+		}
+		return unconverted(file, m)
+	})
+	body = liquidOutput.ReplaceAllStringFunc(body, func(m string) string {
+		expr := liquidOutput.FindStringSubmatch(m)[1]
+		switch expr {
+		case "site.baseurl", "site.url":
+			return ""
+		case "page.title":
+			return p.meta.Title
+		}
+		if sub := liquidURLFilter.FindStringSubmatch(expr); sub != nil {
+			return sub[1]
+		}
+		return unconverted(file, m)
+	})
+	body = kramdownIAL.ReplaceAllString(body, "")
+
+	for _, raw := range raws {
+		body = strings.Replace(body, "\x00raw\x00", raw, 1)
+	}
+	return body
+}
+
+func import_jekyll_main(args []string) {
+	fs := flag.NewFlagSet("import jekyll", flag.ExitOnError)
+	out := fs.String("out", rootDir, "directory to write posts (blog/) and pages (pages/) to")
+	drafts := fs.Bool("drafts", false, "import _drafts and unpublished posts as hidden posts")
+	static := fs.Bool("static", false, "copy the assets and images directories of the site into "+publicDir)
+	overwrite := fs.Bool("overwrite", false, "overwrite previously imported files")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal().Msg("usage: import jekyll [flags] <site directory>")
+	}
+
+	site := fs.Arg(0)
+	config := make(map[string]any)
+	if data, err := os.ReadFile(filepath.Join(site, "_config.yml")); err == nil {
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			log.Fatal().Err(err).Msg("failed to parse _config.yml")
+		}
+	}
+	permalink := frontString(config, "permalink")
+	if permalink == "" {
+		permalink = "date"
+	}
+
+	j := &jekyllImport{site: site}
+	var skipped int
+	err := filepath.WalkDir(site, func(fp string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(site, fp)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		name := path.Base(rel)
+		if d.IsDir() {
+			switch {
+			case rel == ".":
+				return nil
+			case name == "_posts" || name == "_drafts":
+				return nil
+			case strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ext := path.Ext(name)
+		if ext != ".md" && ext != ".markdown" && ext != ".html" {
+			return nil
+		}
+		segments := strings.Split(path.Dir(rel), "/")
+		var post, draft bool
+		var categories []string
+		for i, s := range segments {
+			if s == "_posts" || s == "_drafts" {
+				post, draft = true, s == "_drafts"
+				categories = segments[:i]
+			}
+		}
+		if !post && (rel != name || strings.HasPrefix(name, "index.") || strings.HasPrefix(name, "404.") || strings.EqualFold(name, "README.md")) {
+			return nil // only top level pages are imported, layouts and includes are skipped above
+		}
+
+		data, err := os.ReadFile(fp)
+		if err != nil {
+			return err
+		}
+		if !post && !strings.HasPrefix(string(data), "---") {
+			return nil // files without front matter are copied verbatim by Jekyll
+		}
+		front, body, err := frontmatter.Parse(data)
+		if err != nil {
+			log.Error().Err(err).Str("path", fp).Msgf("skipping %s, failed to parse front matter", fp)
+			skipped++
+			return nil
+		}
+		if published, ok := frontBool(front, "published"); ok && !published {
+			draft = true
+		}
+		if draft && !*drafts {
+			skipped++
+			return nil
+		}
+		if ext == ".html" {
+			md, err := htmltomd.Convert(string(body))
+			if err != nil {
+				return err
+			}
+			body = []byte(md)
+		}
+
+		stem := strings.TrimSuffix(name, ext)
+		p := &jekyllPage{file: rel, front: front, body: string(body), post: post}
+		meta := &types.Metadata{
+			Title:       frontString(front, "title"),
+			Author:      frontString(front, "author"),
+			Description: frontString(front, "description", "excerpt"),
+			Date:        frontTime(front, "date"),
+			Hidden:      draft,
+		}
+		if m := jekyllPostName.FindStringSubmatch(stem); m != nil {
+			stem = m[2]
+			if meta.Date.IsZero() {
+				meta.Date, _ = time.Parse(time.DateOnly, m[1])
+			}
+		}
+		if meta.Date.IsZero() && post {
+			if info, err := d.Info(); err == nil {
+				meta.Date = info.ModTime().UTC()
+			}
+		}
+		p.slug = importSlug(frontString(front, "slug"))
+		if p.slug == "" {
+			p.slug = importSlug(stem)
+		}
+		if meta.Title == "" {
+			meta.Title = strings.ReplaceAll(stem, "-", " ")
+		}
+
+		// categories and tags may be lists or space separated strings
+		p.categories = append(categories, frontStrings(front, "categories")...)
+		p.categories = append(p.categories, frontStrings(front, "category")...)
+		var tags []string
+		for _, t := range frontStrings(front, "tags") {
+			tags = append(tags, strings.Fields(t)...)
+		}
+		var cats []string
+		for _, c := range p.categories {
+			cats = append(cats, strings.Fields(c)...)
+		}
+		p.categories = cats
+		meta.Tags = appendUnique(tags, cats...)
+
+		if image, ok := front["image"].(map[string]any); ok {
+			meta.Image = frontString(image, "path")
+			meta.ImageAlt = frontString(image, "alt")
+		} else {
+			meta.Image = frontString(front, "image")
+		}
+		if !strings.HasPrefix(meta.Image, "/") {
+			meta.Image = ""
+		}
+
+		p.meta = meta
+		j.pages = append(j.pages, p)
+		return nil
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to read Jekyll site %s", site)
+	}
+
+	for _, p := range j.pages {
+		original := p.originalURL(permalink)
+		if p.post {
+			p.meta.Path = "/blog/posts/" + p.slug
+		} else {
+			p.meta.Path = strings.TrimSuffix(strings.TrimSuffix(original, ".html"), "/")
+		}
+		if !p.meta.Hidden && strings.TrimSuffix(original, "/") != p.meta.Path {
+			p.meta.Aliases = append(p.meta.Aliases, original)
+		}
+		// jekyll-redirect-from
+		p.meta.Aliases = appendUnique(p.meta.Aliases, frontStrings(p.front, "redirect_from")...)
+	}
+
+	var imported int
+	for _, p := range j.pages {
+		dir := filepath.Join(*out, "blog")
+		if !p.post {
+			dir = filepath.Join(*out, "pages")
+		}
+		ok, err := writeImportedPost(dir, p.slug, p.meta, j.convert(p), *overwrite)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to write %q", p.meta.Title)
+		}
+		if ok {
+			imported++
+		} else {
+			skipped++
+		}
+	}
+
+	if *static {
+		var copied int
+		for _, dir := range []string{"assets", "images", "img"} {
+			n, err := copyTree(filepath.Join(site, dir), filepath.Join(publicDir, dir))
+			if err != nil && !os.IsNotExist(err) {
+				log.Fatal().Err(err).Msgf("failed to copy the %s directory", dir)
+			}
+			copied += n
+		}
+		log.Info().Int("files", copied).Msgf("copied %d static files", copied)
+	}
+
+	log.Info().Int("imported", imported).Int("skipped", skipped).Msgf("imported %d Jekyll posts and pages", imported)
+}
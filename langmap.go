@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// langPath returns the path of the variant of an English page path in lang.
+// English pages are served without a language prefix.
+func langPath(path string, lang types.Lang) string {
+	if lang == types.LangEnglish {
+		return path
+	}
+	return "/" + lang + path
+}
+
+// postAlternate returns the hreflang alternates of a post available in languages.
+func postAlternate(post *types.Post, languages []types.Lang) *view.Alternate {
+	alt := &view.Alternate{}
+	for _, lang := range languages {
+		alt.Versions = append(alt.Versions, view.KV{
+			Key:   lang,
+			Value: baseURL + pageURL(langPath(post.Path, lang)),
+		})
+	}
+	return alt
+}
+
+// indexAlternate returns the hreflang alternates of the home page.
+func indexAlternate() *view.Alternate {
+	alt := &view.Alternate{}
+	for _, lang := range types.SupportedLanguages {
+		alt.Versions = append(alt.Versions, view.KV{
+			Key:   lang,
+			Value: baseURL + langPath("/", lang),
+		})
+	}
+	return alt
+}
+
+// pageLanguages returns the language switcher links of a page with the
+// hreflang alternates alt.
+func pageLanguages(alt *view.Alternate) []view.LanguageLink {
+	if alt == nil {
+		return nil
+	}
+	links := make([]view.LanguageLink, 0, len(alt.Versions))
+	for _, v := range alt.Versions {
+		links = append(links, view.LanguageLink{
+			Lang: v.Key,
+			Name: types.NativeLangName(v.Key),
+			URL:  strings.TrimPrefix(v.Value, baseURL),
+		})
+	}
+	return links
+}
+
+// LangMap maps the paths of pages to their language variants, for language
+// switchers and edge functions redirecting by Accept-Language.
+type LangMap struct {
+	// Default is the language of pages without a language prefix.
+	Default types.Lang `json:"default"`
+	// Languages are the supported languages.
+	Languages []types.Lang `json:"languages"`
+	// Pages maps the default path of each page to its path in each available language.
+	Pages map[string]map[types.Lang]string `json:"pages"`
+}
+
+// generateLangMap writes /langmap.json.
+func generateLangMap(gc *GenerationContext) error {
+	log.Debug().Msg("start generating language map")
+	m := &LangMap{
+		Default:   types.LangEnglish,
+		Languages: types.SupportedLanguages,
+		Pages:     make(map[string]map[types.Lang]string),
+	}
+
+	add := func(path string, alt *view.Alternate) {
+		variants := make(map[types.Lang]string, len(alt.Versions))
+		for _, l := range pageLanguages(alt) {
+			variants[l.Lang] = l.URL
+		}
+		m.Pages[path] = variants
+	}
+
+	add("/", indexAlternate())
+	for _, post := range publishedPosts(gc) {
+		languages := make([]types.Lang, 0, len(post.Translated))
+		for lang := range post.Translated {
+			languages = append(languages, lang)
+		}
+		sort.Strings(languages)
+		add(post.Path, postAlternate(post, languages))
+	}
+
+	err := writeJSON("/langmap.json", m)
+	if err != nil {
+		return err
+	}
+	log.Debug().Int("pages", len(m.Pages)).Msg("done generating language map")
+	return nil
+}
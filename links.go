@@ -0,0 +1,106 @@
+package main
+
+import (
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+)
+
+const (
+	// linksPath is the site path under which link posts without a path live.
+	linksPath = "/links"
+	// linksFeedPath is the site path of the feed of link posts.
+	linksFeedPath = linksPath + "/feed.rss"
+)
+
+// linkTitle returns the title of a link post without one: the host and path
+// of the linked page.
+func linkTitle(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+	return strings.TrimPrefix(u.Host, "www.") + strings.TrimSuffix(u.Path, "/")
+}
+
+// linkPosts returns the published link posts, newest first.
+func linkPosts(gc *GenerationContext) []*types.Post {
+	var links []*types.Post
+	for _, post := range publishedPosts(gc) {
+		if post.Main.Metadata.Kind == types.KindLink {
+			links = append(links, post)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Main.Metadata.Date.After(links[j].Main.Metadata.Date)
+	})
+	return links
+}
+
+// generateLinksFeed writes the feed of link posts, in English where translated
+// and in their main language otherwise. As is the convention for link blogs,
+// item titles point to the linked page and the commentary, ending with a
+// permalink, is the description.
+func generateLinksFeed(gc *GenerationContext) error {
+	links := linkPosts(gc)
+	if len(links) == 0 {
+		return nil
+	}
+	log.Debug().Msg("start generating links RSS feed")
+	feed := &feeds.Feed{
+		Title:       "GoSuda Links",
+		Link:        &feeds.Link{Href: baseURL + "/"},
+		Description: "Links shared by GoSuda members.",
+		Author:      &feeds.Author{Name: "Gosuda", Email: "webmaster@gosuda.org"},
+		Created:     time.Now().UTC(),
+	}
+	for _, post := range links {
+		lang := types.LangEnglish
+		doc, ok := post.Translated[lang]
+		if !ok {
+			doc, lang = post.Main, post.Main.Metadata.Language
+		}
+		doc, err := gc.DataStore.document(post, doc)
+		if err != nil {
+			return err
+		}
+		permalink := postURL(post, lang)
+		content, err := absoluteHTML(doc.HTML, permalink)
+		if err != nil {
+			return err
+		}
+		feed.Items = append(feed.Items, &feeds.Item{
+			Id:          langFeedID(post.ID, lang),
+			Title:       doc.Metadata.Title,
+			Link:        &feeds.Link{Href: post.Main.Metadata.LinkURL},
+			Author:      &feeds.Author{Name: doc.Metadata.Author},
+			Description: content + `<p><a href="` + html.EscapeString(permalink) + `">#</a></p>`,
+			Created:     post.CreatedAt.UTC(),
+			Updated:     post.UpdatedAt.UTC(),
+		})
+	}
+
+	rss, err := feed.ToRss()
+	if err != nil {
+		return err
+	}
+	fp := filepath.Join(distDir, filepath.FromSlash(linksFeedPath))
+	err = os.MkdirAll(filepath.Dir(fp), 0755)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(fp, []byte(styleFeed(rss)), 0644)
+	if err != nil {
+		return err
+	}
+	log.Debug().Int("links", len(links)).Msg("done generating links RSS feed")
+	return nil
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -19,30 +21,84 @@ var _ = func() struct{} {
 }()
 
 //go:generate templ generate
-//go:generate bun run build
 
 func generate_main() {
-	ds, err := initializeDatabase(dbFile)
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	suggestMetadata := fs.Bool("suggest-metadata", false, "generate missing descriptions with the LLM and write them to the front matter")
+	strictA11y := fs.Bool("strict-a11y", false, "fail the build on accessibility issues in the generated pages")
+	fs.Parse(os.Args[1:])
+	if *suggestMetadata && llmModel == nil {
+		log.Fatal().Msg("--suggest-metadata needs the LLM client, which is disabled by LLM_INIT")
+	}
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+
+	err = runHooks("prebuild", cfg.Hooks.Prebuild, nil)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("prebuild hook failed")
+	}
+
+	ds, err := initializeDatabaseLazy(dbFile)
 	if err != nil {
 		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
 	}
 
 	gc := GenerationContext{
-		DataStore: ds,
-		UsedPosts: make(map[string]struct{}),
-		PathMap:   make(map[string]string),
+		Config:          cfg,
+		DataStore:       ds,
+		UsedPosts:       make(map[string]struct{}),
+		PathMap:         make(map[string]string),
+		SuggestMetadata: *suggestMetadata,
+		StrictA11y:      *strictA11y,
 	}
 
+	// posts missing from the previous build are announced
+	before := visiblePostIDs(&gc)
+
 	err = generate(&gc)
 	if err != nil {
 		log.Fatal().Err(err).Msgf("failed to generate website")
 	}
+	logAssetChanges(gc.Assets)
+	logWalkErrors()
+
+	if cfg.Announce.Enabled {
+		detectNewPosts(&gc, before)
+		if cfg.Announce.OnBuild {
+			if failed := announcePending(cfg, ds, false); failed > 0 {
+				log.Error().Int("failed", failed).Msg("failed to post some announcements, run announce to retry")
+			}
+		}
+	}
+
+	if cfg.Archive.Enabled && cfg.Archive.OnBuild {
+		if _, failed := archivePending(cfg, ds, false); failed > 0 {
+			log.Error().Int("failed", failed).Msg("failed to archive some links, run archive to retry")
+		}
+	}
+
+	if cfg.CrossPost.OnBuild && len(crossPostPlatforms(&cfg.CrossPost)) > 0 {
+		_, failed, err := crossPostPending(cfg, ds, false)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to cross-post")
+		} else if failed > 0 {
+			log.Error().Int("failed", failed).Msg("failed to cross-post some articles, run crosspost to retry")
+		}
+	}
 
 	err = updateDatabase(dbFile, ds)
 	if err != nil {
 		log.Fatal().Err(err).Msgf("failed to update database file %s", dbFile)
 	}
 
+	err = runHooks("postbuild", cfg.Hooks.Postbuild, changedFiles(&gc))
+	if err != nil {
+		log.Fatal().Err(err).Msgf("postbuild hook failed")
+	}
+
 	log.Info().Msgf("website generated")
 }
 
@@ -62,7 +118,7 @@ func remove_lang_main() {
 }
 
 func get_translation_main() {
-	ds, err := initializeDatabase(dbFile)
+	ds, err := initializeDatabaseContent(dbFile)
 	if err != nil {
 		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
 	}
@@ -75,7 +131,7 @@ func get_translation_main() {
 	}
 }
 func eval_translation_main() {
-	ds, err := initializeDatabase(dbFile)
+	ds, err := initializeDatabaseContent(dbFile)
 	if err != nil {
 		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
 	}
@@ -96,7 +152,7 @@ func eval_translation_main() {
 }
 
 func eval_all_main() {
-	ds, err := initializeDatabase(dbFile)
+	ds, err := initializeDatabaseContent(dbFile)
 	if err != nil {
 		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
 	}
@@ -136,7 +192,7 @@ func main() {
 		defer llmModel.Close()
 	}
 
-	if len(os.Args) == 1 {
+	if len(os.Args) == 1 || strings.HasPrefix(os.Args[1], "-") {
 		generate_main()
 		return
 	}
@@ -153,5 +209,29 @@ func main() {
 		return
 	case "eval_all":
 		eval_all_main() // eval all translations and remove if it is low quality.
+	case "deploy":
+		deploy_main() // publish dist to the configured deploy target.
+	case "verify":
+		verify_main() // check a deployed site against dist/.manifest.json.
+	case "report":
+		report_main() // print content reports.
+	case "export":
+		export_main() // export posts to other formats.
+	case "import":
+		import_main() // import posts from other blogging platforms.
+	case "sync":
+		sync_main() // sync posts from external content sources.
+	case "crosspost":
+		crosspost_main() // republish new or updated posts on other platforms.
+	case "announce":
+		announce_main() // announce newly published posts on Mastodon and Bluesky.
+	case "archive":
+		archive_main() // request archive.org snapshots of the outbound links of posts.
+	case "check":
+		check_main() // check the content, e.g. its spelling.
+	case "suggest":
+		suggest_main() // suggest metadata for posts with the LLM.
+	case "serve":
+		serve_main() // serve dist and a GraphQL endpoint over the DataStore.
 	}
 }
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zeebo/blake3"
+)
+
+const manifestFile = ".manifest.json"
+
+// Manifest lists every generated file so a deployment can be verified.
+type Manifest struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Files       []ManifestEntry `json:"files"`
+	// Assets lists the public/ files changed since the previous build,
+	// so a deploy step can upload only those.
+	Assets *AssetChanges `json:"assets,omitempty"`
+	// Bundles maps the bundle entry points to their fingerprinted files.
+	Bundles map[string]string `json:"bundles,omitempty"`
+	// Changed lists the source files changed since the previous build,
+	// passed to the predeploy hooks.
+	Changed []string `json:"changed,omitempty"`
+	// Skipped lists the paths the build skipped as unreadable, whose files
+	// are missing from Files.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// ManifestEntry describes a single generated file.
+type ManifestEntry struct {
+	// Path is the URL path of the file, relative to the site root.
+	Path string `json:"path"`
+	// Hash is the hex encoded blake3 hash of the file content.
+	Hash string `json:"hash"`
+	// Size is the file size in bytes.
+	Size int64 `json:"size"`
+}
+
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := blake3.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+func generateManifest(dir string, assets *AssetChanges, bundles map[string]string, changed []string) error {
+	log.Debug().Msg("start generating build manifest")
+	list, err := generateFileList(dir)
+	if err != nil {
+		return err
+	}
+
+	m := Manifest{GeneratedAt: time.Now().UTC(), Assets: assets, Bundles: bundles, Changed: changed, Skipped: skippedPaths()}
+	for _, path := range list {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = "/" + filepath.ToSlash(rel)
+		if rel == "/"+manifestFile {
+			continue
+		}
+
+		hash, size, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		m.Files = append(m.Files, ManifestEntry{Path: rel, Hash: hash, Size: size})
+	}
+
+	data, err := json.MarshalIndent(&m, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(dir, manifestFile), data, 0644)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Int("files", len(m.Files)).Msg("done generating build manifest")
+	return nil
+}
+
+func readManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// isServedFile reports whether a manifest entry is expected to be reachable over HTTP.
+// Hosting control files (_headers, CNAME, ...) are consumed by the host, not served.
+func isServedFile(path string) bool {
+	name := strings.TrimPrefix(path, "/")
+	if strings.Contains(name, "/") {
+		return true
+	}
+	return !strings.HasPrefix(name, "_") && !strings.HasPrefix(name, ".") && name != "CNAME"
+}
+
+func verifyEntry(client *http.Client, siteURL string, e ManifestEntry) error {
+	url := siteURL + e.Path
+	url = strings.TrimSuffix(url, "index.html")
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	h := blake3.New()
+	n, err := io.Copy(h, resp.Body)
+	if err != nil {
+		return err
+	}
+	if hash := hex.EncodeToString(h.Sum(nil)); hash != e.Hash {
+		return fmt.Errorf("hash mismatch: expected %s (%d bytes), got %s (%d bytes)", e.Hash, e.Size, hash, n)
+	}
+	return nil
+}
+
+func verify_main() {
+	siteURL := baseURL
+	if len(os.Args) > 2 {
+		siteURL = strings.TrimSuffix(os.Args[2], "/")
+	}
+
+	m, err := readManifest(distDir)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to read build manifest")
+	}
+
+	for _, path := range m.Skipped {
+		log.Warn().Str("path", path).Msgf("%s was skipped by the build and is not verified", path)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	entries := make(chan ManifestEntry)
+	var mu sync.Mutex
+	var failed int
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range entries {
+				err := verifyEntry(client, siteURL, e)
+				if err != nil {
+					log.Error().Err(err).Str("path", e.Path).Msgf("verification failed for %s", e.Path)
+					mu.Lock()
+					failed++
+					mu.Unlock()
+					continue
+				}
+				log.Debug().Str("path", e.Path).Msgf("verified %s", e.Path)
+			}
+		}()
+	}
+	for _, e := range m.Files {
+		if isServedFile(e.Path) {
+			entries <- e
+		}
+	}
+	close(entries)
+	wg.Wait()
+
+	if failed > 0 {
+		log.Fatal().Int("failed", failed).Msgf("deployment of %s does not match the build manifest", siteURL)
+	}
+	log.Info().Int("files", len(m.Files)).Int("skipped", len(m.Skipped)).Msgf("deployment of %s matches the build manifest", siteURL)
+}
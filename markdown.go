@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -18,16 +22,59 @@ import (
 	"gosuda.org/website/internal/types"
 )
 
-func parseMarkdown(path string, data []byte) (*types.Document, error) {
+func parseMarkdown(gc *GenerationContext, path string, data []byte) (*types.Document, error) {
+	if doc, ok := gc.RenderCache.get(data); ok {
+		log.Debug().Str("path", path).Msgf("using cached rendering of markdown file %s", path)
+		return doc, nil
+	}
 	log.Debug().Str("path", path).Msgf("rendering markdown file %s", path)
 	doc, err := markdown.ParseMarkdown(string(data))
 	if err != nil {
 		return nil, err
 	}
 	log.Debug().Str("path", path).Int("rendered_size", len(doc.HTML)).Msgf("rendered markdown file %s", path)
+	gc.RenderCache.put(data, doc)
 	return doc, nil
 }
 
+// suggestDescription returns an LLM generated summary of doc in its language,
+// cached by the content of the document.
+func suggestDescription(gc *GenerationContext, path string, doc *types.Document) string {
+	key := suggestionKey(doc)
+	if desc, ok := gc.DataStore.Suggestions[key]; ok {
+		log.Debug().Str("path", path).Str("description", desc).Msgf("using cached description for document %s", path)
+		return desc
+	}
+
+	var lang string
+	if doc.Metadata.Language != "" {
+		lang = types.FullLangName(doc.Metadata.Language)
+	}
+	log.Debug().Str("path", path).Msgf("generating description for document %s", path)
+	desc, err := description.GenerateDescription(context.Background(), llmModel, doc.Markdown, lang)
+	if err != nil {
+		log.Error().Str("path", path).Err(err).Msgf("failed to generate description for document %s", path)
+		return ""
+	}
+	log.Info().Str("path", path).Str("description", desc).Msgf("generated description for document %s", path)
+	if gc.DataStore.Suggestions == nil {
+		gc.DataStore.Suggestions = make(map[string]string)
+	}
+	gc.DataStore.Suggestions[key] = desc
+	return desc
+}
+
+// suggestionKey hashes the body and language of a document, which the front
+// matter written back by the generator does not change.
+func suggestionKey(doc *types.Document) string {
+	body := strings.TrimPrefix(doc.Markdown, "---\n")
+	if _, rest, ok := strings.Cut(body, "---\n"); ok {
+		body = rest
+	}
+	h := sha256.Sum256([]byte(doc.Metadata.Language + "\x00" + body))
+	return hex.EncodeToString(h[:])
+}
+
 func processMarkdownFile(gc *GenerationContext, path string) (*types.Document, error) {
 	log.Debug().Str("path", path).Msgf("start processing markdown file %s", path)
 
@@ -39,10 +86,12 @@ func processMarkdownFile(gc *GenerationContext, path string) (*types.Document, e
 	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")) // normalize line endings
 	log.Debug().Str("path", path).Int("size", len(data)).Msgf("read markdown file %s", path)
 
-	doc, err := parseMarkdown(path, data)
+	doc, err := parseMarkdown(gc, path, data)
 	if err != nil {
 		return nil, err
 	}
+	sec := sectionOf(gc, path)
+	filled := sec.apply(&doc.Metadata)
 
 	if doc.Metadata.ID == "" {
 		doc.Metadata.ID = types.RandID()
@@ -54,18 +103,54 @@ func processMarkdownFile(gc *GenerationContext, path string) (*types.Document, e
 		log.Debug().Str("path", path).Msgf("assigned new date to document %s", path)
 	}
 
+	if doc.Metadata.Kind == "" && isDocsFile(path) {
+		doc.Metadata.Kind = types.KindDoc
+	}
+	switch doc.Metadata.Kind {
+	case "", types.KindPost, types.KindPage, types.KindDoc, types.KindTalk:
+	case types.KindLink:
+		if doc.Metadata.LinkURL == "" {
+			log.Warn().Str("path", path).Msgf("link post %s has no link_url, treating it as a post", path)
+			doc.Metadata.Kind = ""
+		}
+	default:
+		log.Warn().Str("path", path).Str("kind", string(doc.Metadata.Kind)).Msgf("document %s has an unknown kind, treating it as a post", path)
+		doc.Metadata.Kind = ""
+	}
+
+	if doc.Metadata.Kind == types.KindLink {
+		if doc.Metadata.Title == "" {
+			doc.Metadata.Title = linkTitle(doc.Metadata.LinkURL)
+		}
+		if doc.Metadata.Path == "" {
+			// link posts are too short to name, so they are numbered by ID
+			id := doc.Metadata.ID
+			doc.Metadata.Path = linksPath + "/" + id[:min(12, len(id))]
+		}
+	}
+
+	if doc.Metadata.Path == "" && doc.Metadata.IsPage() {
+		// pages live at the top level, named after their file (root/about.md is /about)
+		doc.Metadata.Path = "/" + strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	if doc.Metadata.Path == "" && doc.Metadata.Kind == types.KindDoc {
+		doc.Metadata.Path = docsPath(path)
+	}
+
+	if doc.Metadata.Path == "" && sec.Permalink != "" {
+		doc.Metadata.Path = expandPermalink(sec.Permalink, path, &doc.Metadata)
+	}
+
 	if doc.Metadata.Path == "" {
 		doc.Metadata.Path = generatePath(doc.Metadata.Title)
 	}
 
-	if doc.Metadata.Description == "" {
-		log.Debug().Str("path", path).Msgf("generating description for document %s", path)
-		desc, err := description.GenerateDescription(context.Background(), llmModel, doc.Markdown)
-		if err != nil {
-			log.Error().Str("path", path).Err(err).Msgf("failed to generate description for document %s", path)
+	if doc.Metadata.Language != "" {
+		if _, _, err := types.ParseLang(doc.Metadata.Language); err != nil {
+			log.Error().Str("path", path).Str("lang", doc.Metadata.Language).Err(err).Msgf("document %s has an invalid BCP-47 language tag, detecting its language instead", path)
+			doc.Metadata.Language = ""
 		}
-		doc.Metadata.Description = desc
-		log.Debug().Str("path", path).Str("description", doc.Metadata.Description).Msgf("generated description for document %s", path)
 	}
 
 	if doc.Metadata.Language == "" {
@@ -76,14 +161,27 @@ func processMarkdownFile(gc *GenerationContext, path string) (*types.Document, e
 			lang = mapDetectedLanguage(detectedLang)
 			confidence := languageDetector.ComputeLanguageConfidence(doc.Markdown, detectedLang)
 			log.Debug().Str("path", path).Str("lang", lang).Float64("confidence", confidence).Msgf("detected language of document %s", path)
-			doc.Metadata.Language = lang
+		} else {
+			log.Warn().Str("path", path).Msgf("could not detect the language of document %s, assuming English", path)
+		}
+		doc.Metadata.Language = lang
+	}
+
+	// link posts are their own description
+	if doc.Metadata.Description == "" && doc.Metadata.Kind != types.KindLink {
+		if !gc.SuggestMetadata {
+			log.Warn().Str("path", path).Msgf("document %s has no description, run with --suggest-metadata to generate one", path)
+		} else if desc := suggestDescription(gc, path, doc); desc != "" {
+			doc.Metadata.Description = desc
 		}
 	}
 
 	log.Debug().Str("path", path).Msgf("saving updated document %s", path)
 
 	if doc.Type == types.DocumentTypeMarkdown {
-		newMeta, err := yaml.Marshal(&doc.Metadata)
+		// section defaults stay in the section file
+		saved := filled.strip(doc.Metadata)
+		newMeta, err := yaml.Marshal(&saved)
 		if err != nil {
 			return nil, err
 		}
@@ -111,6 +209,12 @@ func processMarkdownFile(gc *GenerationContext, path string) (*types.Document, e
 		log.Debug().Str("path", path).Msgf("skipping non-markdown document %s", path)
 	}
 
+	// the front matter keeps the full tag, translations and URLs use its base language
+	doc.Lang, doc.Metadata.Language, _ = types.ParseLang(doc.Metadata.Language)
+	if !slices.Contains(types.SupportedLanguages, doc.Metadata.Language) {
+		log.Warn().Str("path", path).Str("lang", doc.Lang).Msgf("document %s is written in an unsupported language", path)
+	}
+
 	now := time.Now()
 
 	// Update Post Object
@@ -136,17 +240,16 @@ func processMarkdownFile(gc *GenerationContext, path string) (*types.Document, e
 	}
 	post.Translated[doc.Metadata.Language] = doc
 
-	if post.Hash != hash {
+	changed := post.Hash != hash
+	if changed {
 		post.Hash = hash
 		post.UpdatedAt = now
-		err = translatePost(gc, post, true, doc.Metadata.Language)
-		if err != nil {
-			log.Error().Str("path", path).Err(err).Msg("failed to translate")
-		}
-	} else {
-		err = translatePost(gc, post, false, doc.Metadata.Language)
+		gc.ChangedPosts = append(gc.ChangedPosts, path)
+	}
+	for _, p := range gc.Plugins.Processors {
+		err = p.ProcessPost(gc, post, changed)
 		if err != nil {
-			log.Error().Str("path", path).Err(err).Msg("failed to translate")
+			log.Error().Str("path", path).Str("plugin", p.Name()).Err(err).Msgf("failed to process post with %s", p.Name())
 		}
 	}
 
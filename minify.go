@@ -59,6 +59,11 @@ func minifyDir(dir string) error {
 	}
 
 	for _, path := range list {
+		// files with a source map are already minified, minifying them
+		// again would break the mappings
+		if _, err := os.Stat(path + ".map"); err == nil {
+			continue
+		}
 		ext := strings.ToLower(filepath.Ext(path))
 		switch ext {
 		case ".html", ".htm":
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+)
+
+type opmlDocument struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Head    opmlHead      `xml:"head"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated"`
+	OwnerName   string `xml:"ownerName"`
+	OwnerEmail  string `xml:"ownerEmail"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Language string        `xml:"language,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// rssOutline returns the outline of an RSS feed.
+func rssOutline(title, feedURL, htmlURL string, lang types.Lang) opmlOutline {
+	return opmlOutline{Text: title, Title: title, Type: "rss", XMLURL: feedURL, HTMLURL: htmlURL, Language: lang}
+}
+
+// generateOPML writes dist/feeds.opml, which lists the feed of every language
+// and tag so that all of them can be subscribed to in one import.
+func generateOPML(gc *GenerationContext) error {
+	log.Debug().Msg("start generating OPML feed list")
+	langs := opmlOutline{Text: "Languages"}
+	for _, lang := range types.SupportedLanguages {
+		langs.Outlines = append(langs.Outlines, rssOutline(langFeedTitle(lang), langFeedURL(lang), baseURL+langPath("/", lang), lang))
+	}
+
+	tags := opmlOutline{Text: "Tags"}
+	for tag := range tagPosts(gc) {
+		tags.Outlines = append(tags.Outlines, rssOutline("GoSuda Blog - "+tag, baseURL+tagFeedPath(tag), baseURL+"/", ""))
+	}
+	sort.Slice(tags.Outlines, func(i, j int) bool {
+		return tags.Outlines[i].Text < tags.Outlines[j].Text
+	})
+
+	doc := opmlDocument{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:       "GoSuda Blog Feeds",
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+			OwnerName:   "Gosuda",
+			OwnerEmail:  "webmaster@gosuda.org",
+		},
+		Body: []opmlOutline{langs},
+	}
+	if len(tags.Outlines) > 0 {
+		doc.Body = append(doc.Body, tags)
+	}
+	if len(linkPosts(gc)) > 0 {
+		doc.Body = append(doc.Body, rssOutline("GoSuda Links", baseURL+linksFeedPath, baseURL+"/", ""))
+	}
+	if gc.Config.Releases.Enabled {
+		doc.Body = append(doc.Body, rssOutline("GoSuda Releases", releasesFeedURL(), baseURL+pageURL(releasesPath), types.LangEnglish))
+	}
+	if pc := &gc.Config.Podcast; pc.Enabled {
+		doc.Body = append(doc.Body, rssOutline(pc.Title, baseURL+"/podcast.rss", baseURL+"/", ""))
+	}
+
+	data, err := xml.MarshalIndent(&doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(distDir, "feeds.opml"), append([]byte(xmlHeader), data...), 0644)
+	if err != nil {
+		return err
+	}
+	log.Debug().Int("tags", len(tags.Outlines)).Msg("done generating OPML feed list")
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/view"
+)
+
+// chromiumNames are the executables tried for rendering PDFs, after $CHROME_PATH.
+var chromiumNames = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"}
+
+func findChromium() (string, error) {
+	if p := os.Getenv("CHROME_PATH"); p != "" {
+		return p, nil
+	}
+	for _, name := range chromiumNames {
+		if p, err := exec.LookPath(name); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("no headless chromium found, set CHROME_PATH")
+}
+
+// fileURL returns the file URL of a site path in the public directory.
+func fileURL(sitePath string) (string, error) {
+	abs, err := filepath.Abs(publicPath(sitePath))
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(), nil
+}
+
+// printHTML prepares rendered post HTML for printing: scripts and comments are removed,
+// local images are loaded from the public directory and links point to the live site.
+func printHTML(doc, pageURL string) (string, error) {
+	var rerr error
+	inScript := false
+	out, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		switch {
+		case t.IsTag("script"):
+			inScript = t.Type == htmlrewrite.StartTagToken
+			return []byte{}
+		case t.Type == htmlrewrite.EndTagToken && t.Data == "script":
+			inScript = false
+			return []byte{}
+		case inScript || t.Type == htmlrewrite.CommentToken:
+			return []byte{}
+		case t.Type != htmlrewrite.StartTagToken && t.Type != htmlrewrite.SelfClosingTagToken:
+			return nil
+		}
+
+		changed := false
+		if href, ok := t.Attr("href"); ok && (strings.HasPrefix(href, "/") || strings.HasPrefix(href, "#")) {
+			t.SetAttr("href", absURL(pageURL, href))
+			changed = true
+		}
+		if src, ok := t.Attr("src"); ok && strings.HasPrefix(src, "/") && !strings.HasPrefix(src, "//") {
+			u, err := fileURL(src)
+			if err != nil {
+				rerr = err
+				return nil
+			}
+			t.SetAttr("src", u)
+			changed = true
+		}
+		if !changed {
+			return nil
+		}
+		return t.Render()
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(out), rerr
+}
+
+// renderPDF prints the HTML document doc to the PDF file out with headless chromium.
+func renderPDF(doc []byte, out string) error {
+	chromium, err := findChromium()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", "export-*.html")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(doc)
+	tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	absOut, err := filepath.Abs(out)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(chromium, "--headless", "--disable-gpu", "--no-pdf-header-footer",
+		"--allow-file-access-from-files", "--print-to-pdf="+absOut, "file://"+filepath.ToSlash(tmp.Name()))
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func export_pdf_main(args []string) {
+	fs := flag.NewFlagSet("export pdf", flag.ExitOnError)
+	lang := fs.String("lang", "", "language of the exported posts (default: the original language of the first post)")
+	output := fs.String("o", "", "output file (default: <post-id>.pdf)")
+	title := fs.String("title", "", "cover title when exporting several posts (default: the title of the first post)")
+	htmlOnly := fs.Bool("html", false, "write the print HTML instead of rendering a PDF")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: export pdf [flags] <post-id>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ds, err := initializeDatabaseContent(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+
+	book := &view.PrintBook{}
+	for i, id := range fs.Args() {
+		post, ok := ds.Posts[id]
+		if !ok {
+			log.Fatal().Msgf("post %s not found", id)
+		}
+		if *lang == "" {
+			*lang = post.Main.Metadata.Language
+		}
+		doc, ok := post.Translated[*lang]
+		if !ok {
+			log.Fatal().Msgf("post %s has no %s translation", id, *lang)
+		}
+
+		pageURL := postURL(post, *lang)
+		body, err := printHTML(doc.HTML, pageURL)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to prepare post %s for printing", id)
+		}
+		book.Chapters = append(book.Chapters, view.PrintChapter{
+			Title:  doc.Metadata.Title,
+			Author: doc.Metadata.Author,
+			Date:   doc.Metadata.Date,
+			URL:    pageURL,
+			Body:   body,
+		})
+
+		if i == 0 {
+			book.Language = *lang
+			book.Title = doc.Metadata.Title
+			book.Description = doc.Metadata.Description
+			book.Author = doc.Metadata.Author
+			book.Date = doc.Metadata.Date
+			if cover := post.Main.Metadata.Image; cover != "" {
+				book.Cover, err = fileURL(cover)
+				if err != nil {
+					log.Fatal().Err(err).Msgf("failed to resolve cover image %s", cover)
+				}
+			}
+		}
+	}
+	if len(book.Chapters) > 1 {
+		book.Description = ""
+		if *title != "" {
+			book.Title = *title
+		}
+	}
+
+	var b bytes.Buffer
+	err = view.PrintDocument(book).Render(localizedContext(book.Language), &b)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to render print document")
+	}
+
+	out := *output
+	if out == "" {
+		out = fs.Arg(0) + ".pdf"
+		if *htmlOnly {
+			out = fs.Arg(0) + ".html"
+		}
+	}
+	if *htmlOnly {
+		err = os.WriteFile(out, b.Bytes(), 0644)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to write %s", out)
+		}
+		log.Info().Int("posts", len(book.Chapters)).Msgf("exported print HTML to %s", out)
+		return
+	}
+
+	err = renderPDF(b.Bytes(), out)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to render %s (-html writes the print HTML without chromium)", out)
+	}
+	log.Info().Int("posts", len(book.Chapters)).Msgf("exported PDF to %s", out)
+}
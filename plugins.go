@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// Plugin is an extension of the generator. A plugin implements one or more of
+// the extension point interfaces, PostProcessor, ContentTransformer,
+// MetadataEnricher, OutputGenerator and DeployTarget, and is added to every
+// point it implements.
+type Plugin interface {
+	// Name identifies the plugin in logs, errors and plugins.disabled.
+	Name() string
+}
+
+// PostProcessor updates a post after its source file is read, before any page
+// is written, such as adding its translations. changed reports whether the
+// source changed since the previous build.
+type PostProcessor interface {
+	Plugin
+	ProcessPost(gc *GenerationContext, post *types.Post, changed bool) error
+}
+
+// ContentTransformer rewrites the rendered HTML of a post in a language before
+// its page is written.
+type ContentTransformer interface {
+	Plugin
+	TransformContent(gc *GenerationContext, post *types.Post, lang types.Lang, doc string) (string, error)
+}
+
+// MetadataEnricher adds to the page metadata of a post in a language.
+type MetadataEnricher interface {
+	Plugin
+	EnrichMetadata(gc *GenerationContext, post *types.Post, lang types.Lang, meta *view.Metadata) error
+}
+
+// OutputGenerator writes files to dist after the pages of the posts are
+// written, and before the pages are bundled and minified.
+type OutputGenerator interface {
+	Plugin
+	GenerateOutput(gc *GenerationContext) error
+}
+
+// DeployTarget publishes dist, selected by deploy.target.
+type DeployTarget interface {
+	Plugin
+	Deploy(cfg *SiteConfig, dir string) error
+}
+
+// SelectivePlugin is a plugin that has the methods of every extension point
+// but serves only some of them, as exec plugins do. Register adds it only to
+// the points it implements: "process", "transform", "enrich", "output" and
+// "deploy".
+type SelectivePlugin interface {
+	Plugin
+	Implements(point string) bool
+}
+
+// PluginRegistry holds the enabled plugins of each extension point, in the
+// order they were registered.
+type PluginRegistry struct {
+	Processors    []PostProcessor
+	Transformers  []ContentTransformer
+	Enrichers     []MetadataEnricher
+	Generators    []OutputGenerator
+	DeployTargets map[string]DeployTarget
+
+	names map[string]bool
+	// closers are the running exec plugins.
+	closers []io.Closer
+}
+
+// Register adds p to the extension points it implements.
+func (r *PluginRegistry) Register(p Plugin) error {
+	name := p.Name()
+	if r.names[name] {
+		return fmt.Errorf("plugin %s is registered twice", name)
+	}
+
+	serves := func(point string) bool { return true }
+	if sp, is := p.(SelectivePlugin); is {
+		serves = sp.Implements
+	}
+
+	var ok bool
+	if pp, is := p.(PostProcessor); is && serves("process") {
+		r.Processors = append(r.Processors, pp)
+		ok = true
+	}
+	if t, is := p.(ContentTransformer); is && serves("transform") {
+		r.Transformers = append(r.Transformers, t)
+		ok = true
+	}
+	if e, is := p.(MetadataEnricher); is && serves("enrich") {
+		r.Enrichers = append(r.Enrichers, e)
+		ok = true
+	}
+	if g, is := p.(OutputGenerator); is && serves("output") {
+		r.Generators = append(r.Generators, g)
+		ok = true
+	}
+	if d, is := p.(DeployTarget); is && serves("deploy") {
+		if r.DeployTargets == nil {
+			r.DeployTargets = make(map[string]DeployTarget)
+		}
+		r.DeployTargets[name] = d
+		ok = true
+	}
+	if !ok {
+		return fmt.Errorf("plugin %s implements no extension point", name)
+	}
+
+	if r.names == nil {
+		r.names = make(map[string]bool)
+	}
+	r.names[name] = true
+	return nil
+}
+
+// plugins are the plugins of the generator, in order. Plugins outside of the
+// built-in ones add themselves from an init function of their file.
+var plugins = builtinPlugins()
+
+// newPluginRegistry registers the plugins not in plugins.disabled, and starts
+// and registers the exec plugins of plugins.exec after them.
+func newPluginRegistry(cfg *SiteConfig) (*PluginRegistry, error) {
+	known := func(name string) bool {
+		return slices.ContainsFunc(plugins, func(p Plugin) bool { return p.Name() == name }) ||
+			slices.ContainsFunc(cfg.Plugins.Exec, func(pc ExecPluginConfig) bool { return pc.Name == name })
+	}
+	for _, name := range cfg.Plugins.Disabled {
+		if !known(name) {
+			return nil, fmt.Errorf("plugins.disabled: unknown plugin %s", name)
+		}
+	}
+
+	r := &PluginRegistry{}
+	for _, p := range plugins {
+		if slices.Contains(cfg.Plugins.Disabled, p.Name()) {
+			continue
+		}
+		err := r.Register(p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range cfg.Plugins.Exec {
+		pc := &cfg.Plugins.Exec[i]
+		if slices.Contains(cfg.Plugins.Disabled, pc.Name) {
+			continue
+		}
+		p, err := startExecPlugin(pc)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.closers = append(r.closers, p)
+		err = r.Register(p)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Close stops the exec plugins.
+func (r *PluginRegistry) Close() {
+	for _, c := range r.closers {
+		err := c.Close()
+		if err != nil {
+			log.Warn().Err(err).Msg("plugin exited with an error")
+		}
+	}
+	r.closers = nil
+}
+
+// transformFunc adapts a post-processing function to a ContentTransformer.
+type transformFunc struct {
+	name string
+	fn   func(gc *GenerationContext, post *types.Post, doc string) (string, error)
+}
+
+func (f transformFunc) Name() string { return f.name }
+
+func (f transformFunc) TransformContent(gc *GenerationContext, post *types.Post, lang types.Lang, doc string) (string, error) {
+	return f.fn(gc, post, doc)
+}
+
+// enrichFunc adapts a function to a MetadataEnricher.
+type enrichFunc struct {
+	name string
+	fn   func(gc *GenerationContext, post *types.Post, lang types.Lang, meta *view.Metadata) error
+}
+
+func (f enrichFunc) Name() string { return f.name }
+
+func (f enrichFunc) EnrichMetadata(gc *GenerationContext, post *types.Post, lang types.Lang, meta *view.Metadata) error {
+	return f.fn(gc, post, lang, meta)
+}
+
+// outputFunc adapts a generate function to an OutputGenerator.
+type outputFunc struct {
+	name string
+	fn   func(gc *GenerationContext) error
+}
+
+func (f outputFunc) Name() string { return f.name }
+
+func (f outputFunc) GenerateOutput(gc *GenerationContext) error { return f.fn(gc) }
+
+// deployFunc adapts a deploy function to a DeployTarget.
+type deployFunc struct {
+	name string
+	fn   func(cfg *SiteConfig, dir string) error
+}
+
+func (f deployFunc) Name() string { return f.name }
+
+func (f deployFunc) Deploy(cfg *SiteConfig, dir string) error { return f.fn(cfg, dir) }
+
+// builtinPlugins returns the plugins of the features of the generator.
+func builtinPlugins() []Plugin {
+	return []Plugin{
+		machineTranslations{},
+
+		enrichFunc{"features", enrichFeatures},
+		enrichFunc{"extras", enrichExtras},
+
+		transformFunc{"shortcodes", expandShortcodes},
+		citations{},
+		admonitions{},
+		transformFunc{"video-posters", addVideoPosters},
+		transformFunc{"dark-images", addDarkImages},
+		transformFunc{"glossary-links", linkGlossaryTerms},
+		transformFunc{"acronyms", expandAcronyms},
+		archiveLinks{},
+		// after the transformers of the footnotes they copy
+		transformFunc{"sidenotes", addSidenotes},
+
+		// the sitemaps are collected from the feeds
+		outputFunc{"feeds", generateFeeds},
+		outputFunc{"docs-versions", generateDocsVersions},
+		outputFunc{"short-urls", generateShortURLs},
+		outputFunc{"tag-feeds", generateTagFeeds},
+		outputFunc{"links-feed", generateLinksFeed},
+		outputFunc{"releases", generateReleases},
+		outputFunc{"projects", generateProjects},
+		outputFunc{"cv", generateCV},
+		outputFunc{"glossary", generateGlossaryPage},
+		outputFunc{"opml", generateOPML},
+		outputFunc{"sitemaps", generateSitemaps},
+		outputFunc{"podcast", generatePodcastFeed},
+		outputFunc{"api", generateAPI},
+		outputFunc{"langmap", generateLangMap},
+		// last, to index the pages written by the other generators
+		outputFunc{"search", generateSearchIndexes},
+
+		deployFunc{"github-pages", func(cfg *SiteConfig, dir string) error {
+			return deployGitHubPages(&cfg.Deploy, dir)
+		}},
+	}
+}
@@ -0,0 +1,73 @@
+// Command banner is an example exec plugin, see execplugins.go. It adds the
+// HTML of its "html" option before the content of every post:
+//
+//	plugins: {
+//	  exec: [
+//	    { name: "banner", command: ["go", "run", "./plugins/banner"], options: { html: "<p>...</p>" } },
+//	  ],
+//	},
+package main
+
+import (
+	"io"
+	"log"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+)
+
+type InitArgs struct {
+	Name    string         `json:"name"`
+	BaseURL string         `json:"base_url"`
+	Options map[string]any `json:"options"`
+}
+
+type InitReply struct {
+	Points []string `json:"points"`
+}
+
+type TransformArgs struct {
+	Lang string `json:"lang"`
+	HTML string `json:"html"`
+}
+
+type TransformReply struct {
+	HTML string `json:"html"`
+}
+
+// Plugin is the receiver of the Plugin.* methods the generator calls.
+type Plugin struct {
+	html string
+}
+
+func (p *Plugin) Init(args InitArgs, reply *InitReply) error {
+	p.html, _ = args.Options["html"].(string)
+	reply.Points = []string{"transform"}
+	return nil
+}
+
+func (p *Plugin) TransformContent(args TransformArgs, reply *TransformReply) error {
+	reply.HTML = p.html + args.HTML
+	return nil
+}
+
+// stdio joins the standard input and output into a connection.
+type stdio struct {
+	io.Reader
+	io.Writer
+}
+
+func (stdio) Close() error { return nil }
+
+func main() {
+	// standard output carries the responses, so logs go to standard error
+	log.SetOutput(os.Stderr)
+
+	server := rpc.NewServer()
+	err := server.RegisterName("Plugin", &Plugin{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// returns when the generator closes the standard input
+	server.ServeCodec(jsonrpc.NewServerCodec(stdio{os.Stdin, os.Stdout}))
+}
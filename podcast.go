@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+)
+
+const itunesNS = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+type podcastRSS struct {
+	XMLName xml.Name       `xml:"rss"`
+	Version string         `xml:"version,attr"`
+	Itunes  string         `xml:"xmlns:itunes,attr"`
+	Channel podcastChannel `xml:"channel"`
+}
+
+type podcastChannel struct {
+	Title       string          `xml:"title"`
+	Link        string          `xml:"link"`
+	Description string          `xml:"description"`
+	Language    string          `xml:"language"`
+	Author      string          `xml:"itunes:author"`
+	Owner       podcastOwner    `xml:"itunes:owner"`
+	Image       podcastImage    `xml:"itunes:image"`
+	Category    podcastCategory `xml:"itunes:category"`
+	Explicit    string          `xml:"itunes:explicit"`
+	Items       []podcastItem   `xml:"item"`
+}
+
+type podcastOwner struct {
+	Name  string `xml:"itunes:name"`
+	Email string `xml:"itunes:email"`
+}
+
+type podcastImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type podcastCategory struct {
+	Text string `xml:"text,attr"`
+}
+
+type podcastItem struct {
+	Title       string           `xml:"title"`
+	Link        string           `xml:"link"`
+	Description string           `xml:"description"`
+	GUID        podcastGUID      `xml:"guid"`
+	PubDate     string           `xml:"pubDate"`
+	Enclosure   podcastEnclosure `xml:"enclosure"`
+	Duration    string           `xml:"itunes:duration,omitempty"`
+	Author      string           `xml:"itunes:author,omitempty"`
+}
+
+type podcastGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type podcastEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+var durationRe = regexp.MustCompile(`^(\d+:)?\d{1,2}:\d{2}$|^\d+$`)
+
+func audioType(p string) string {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".mp3":
+		return "audio/mpeg"
+	case ".m4a":
+		return "audio/x-m4a"
+	case ".ogg", ".oga":
+		return "audio/ogg"
+	case ".opus":
+		return "audio/opus"
+	case ".wav":
+		return "audio/wav"
+	}
+	return "application/octet-stream"
+}
+
+// audioPosts returns the published posts with an audio version, newest first,
+// after checking that their audio files exist.
+func audioPosts(gc *GenerationContext) ([]*types.Post, error) {
+	var posts []*types.Post
+	for _, post := range listedPosts(gc) {
+		pm := &post.Main.Metadata
+		if pm.AudioFile == "" {
+			continue
+		}
+		if _, err := os.Stat(publicPath(pm.AudioFile)); err != nil {
+			return nil, fmt.Errorf("%s: audio file %s does not exist in %s: %w", post.FilePath, pm.AudioFile, publicDir, err)
+		}
+		if pm.Duration != "" && !durationRe.MatchString(pm.Duration) {
+			return nil, fmt.Errorf("%s: invalid duration %q, use HH:MM:SS, MM:SS or seconds", post.FilePath, pm.Duration)
+		}
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].Main.Metadata.Date.After(posts[j].Main.Metadata.Date)
+	})
+	return posts, nil
+}
+
+func generatePodcastFeed(gc *GenerationContext) error {
+	pc := &gc.Config.Podcast
+	if !pc.Enabled {
+		return nil
+	}
+	log.Debug().Msg("start generating podcast feed")
+
+	posts, err := audioPosts(gc)
+	if err != nil {
+		return err
+	}
+
+	explicit := "false"
+	if pc.Explicit {
+		explicit = "true"
+	}
+	rss := podcastRSS{
+		Version: "2.0",
+		Itunes:  itunesNS,
+		Channel: podcastChannel{
+			Title:       pc.Title,
+			Link:        baseURL + "/",
+			Description: pc.Description,
+			Language:    pc.Language,
+			Author:      pc.Author,
+			Owner:       podcastOwner{Name: pc.Author, Email: pc.Email},
+			Image:       podcastImage{Href: baseURL + pc.Image},
+			Category:    podcastCategory{Text: pc.Category},
+			Explicit:    explicit,
+		},
+	}
+
+	for _, post := range posts {
+		pm := post.Main.Metadata
+		info, err := os.Stat(filepath.Join(distDir, filepath.FromSlash(path.Clean("/"+pm.AudioFile))))
+		if err != nil {
+			return err
+		}
+
+		rss.Channel.Items = append(rss.Channel.Items, podcastItem{
+			Title:       pm.Title,
+			Link:        postURL(post, pm.Language),
+			Description: pm.Description,
+			GUID:        podcastGUID{Value: post.ID},
+			PubDate:     pm.Date.UTC().Format(time.RFC1123Z),
+			Enclosure:   podcastEnclosure{URL: baseURL + pm.AudioFile, Length: info.Size(), Type: audioType(pm.AudioFile)},
+			Duration:    pm.Duration,
+			Author:      pm.Author,
+		})
+	}
+
+	data, err := xml.MarshalIndent(&rss, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(distDir, "podcast.rss"), append([]byte(xmlHeader+feedStylesheet()), data...), 0644)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Int("episodes", len(rss.Channel.Items)).Msg("done generating podcast feed")
+	return nil
+}
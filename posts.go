@@ -0,0 +1,103 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gosuda.org/website/internal/types"
+)
+
+// isNoIndex reports whether search engines should be kept from indexing the post.
+func isNoIndex(post *types.Post) bool {
+	return post.Main.Metadata.NoIndex || post.Main.Metadata.Hidden
+}
+
+// isExpired reports whether the post is past its expiry date.
+func isExpired(post *types.Post) bool {
+	expiry := post.Main.Metadata.ExpiryDate
+	return !expiry.IsZero() && time.Now().After(expiry)
+}
+
+// metadataStatus returns the editorial state of a document.
+func metadataStatus(meta *types.Metadata) string {
+	switch {
+	case meta.Status != "":
+		return meta.Status
+	case meta.Draft:
+		return "draft"
+	}
+	return types.StatusPublished
+}
+
+// postStatus returns the editorial state of the post.
+func postStatus(post *types.Post) string {
+	return metadataStatus(&post.Main.Metadata)
+}
+
+// isUnpublished reports whether the post is not published yet: in another
+// state than published, or dated in the future.
+func isUnpublished(post *types.Post) bool {
+	return postStatus(post) != types.StatusPublished || post.Main.Metadata.Date.After(time.Now())
+}
+
+// publishedPosts returns the posts that are rendered, ordered by ID.
+// Drafts and future posts are left out, and expired posts when the site
+// unpublishes them.
+func publishedPosts(gc *GenerationContext) []*types.Post {
+	posts := make([]*types.Post, 0, len(gc.DataStore.Posts))
+	for _, post := range gc.DataStore.Posts {
+		if isUnpublished(post) {
+			continue
+		}
+		if gc.Config.Expiry.Action == "unpublish" && isExpired(post) {
+			continue
+		}
+		posts = append(posts, post)
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].ID < posts[j].ID
+	})
+	return posts
+}
+
+// listedPosts returns the published posts that are listed on the index, in
+// feeds and in archives, which leaves out standalone pages and docs.
+func listedPosts(gc *GenerationContext) []*types.Post {
+	posts := publishedPosts(gc)
+	listed := posts[:0]
+	for _, post := range posts {
+		if post.Main == nil || post.Main.Metadata.IsPost() {
+			listed = append(listed, post)
+		}
+	}
+	return listed
+}
+
+// editURL returns the URL for editing the source of the post in lang, or "" if the
+// document has no tracked source file (e.g. machine translations).
+func editURL(gc *GenerationContext, post *types.Post, lang types.Lang) string {
+	rc := &gc.Config.Repository
+	if rc.URL == "" {
+		return ""
+	}
+
+	var path string
+	if doc, ok := post.Translated[lang]; ok && doc.FilePath != "" {
+		path = doc.FilePath
+	} else if lang == post.Main.Metadata.Language {
+		path = post.FilePath
+	}
+	if path == "" {
+		return ""
+	}
+
+	return strings.TrimSuffix(rc.URL, "/") + "/edit/" + rc.Branch + "/" + filepath.ToSlash(path)
+}
+
+// postURL returns the absolute URL of the post page in lang.
+func postURL(post *types.Post, lang types.Lang) string {
+	return baseURL + pageURL(langPath(post.Path, lang))
+}
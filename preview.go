@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+)
+
+// previewPrefix is the site path under which the pages of unpublished posts
+// are written during the build, before they are moved out of dist to
+// previewDir.
+const previewPrefix = "/preview/"
+
+// previewToken returns the token of the preview of a post in lang, signed
+// with secret so that it cannot be derived from the post.
+func previewToken(secret, id string, lang types.Lang) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(id + "/" + lang))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// unpublishedPosts returns the drafts and future posts, ordered by ID.
+func unpublishedPosts(ds *DataStore) []*types.Post {
+	var posts []*types.Post
+	for _, post := range ds.Posts {
+		if isUnpublished(post) {
+			posts = append(posts, post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].ID < posts[j].ID
+	})
+	return posts
+}
+
+// extractPreviews moves the preview pages out of dist, after they went through
+// the same processing as the other pages, so that they are served by `serve`
+// and never deployed.
+func extractPreviews(gc *GenerationContext) error {
+	err := os.RemoveAll(previewDir)
+	if err != nil {
+		return err
+	}
+
+	var pages int
+	for p := range gc.Pages {
+		token, ok := strings.CutPrefix(p, previewPrefix)
+		if !ok {
+			continue
+		}
+		if pages == 0 {
+			err = os.MkdirAll(previewDir, 0755)
+			if err != nil {
+				return err
+			}
+		}
+		err = os.Rename(pageFile(p), filepath.Join(previewDir, token+".html"))
+		if err != nil {
+			return err
+		}
+		delete(gc.Pages, p)
+		pages++
+	}
+	if pages == 0 {
+		return nil
+	}
+
+	err = os.RemoveAll(filepath.Join(distDir, filepath.FromSlash(previewPrefix)))
+	if err != nil {
+		return err
+	}
+	log.Debug().Int("pages", pages).Str("dir", previewDir).Msg("moved preview pages out of dist")
+	return nil
+}
+
+// logPreviewLinks prints the preview links of the unpublished posts served at
+// base.
+func logPreviewLinks(cfg *SiteConfig, ds *DataStore, base string) {
+	for _, post := range unpublishedPosts(ds) {
+		langs := make([]string, 0, len(post.Translated))
+		for lang := range post.Translated {
+			langs = append(langs, lang)
+		}
+		sort.Strings(langs)
+		for _, lang := range langs {
+			token := previewToken(cfg.Preview.Secret, post.ID, lang)
+			if _, err := os.Stat(filepath.Join(previewDir, token+".html")); err != nil {
+				continue
+			}
+			log.Info().Str("post", post.FilePath).Str("lang", lang).Msgf("preview: %s%s%s", base, previewPrefix, token)
+		}
+	}
+}
+
+// previewHandler serves the preview pages by their token.
+func previewHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := path.Base(r.URL.Path)
+		if strings.ContainsAny(token, "./") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Robots-Tag", "noindex")
+		http.ServeFile(w, r, filepath.Join(previewDir, token+".html"))
+	})
+}
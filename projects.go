@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// projectsPath is the site path of the projects page.
+const projectsPath = "/projects"
+
+// ProjectEntry caches the metadata of a GitHub repository.
+type ProjectEntry struct {
+	// FetchedAt is when the entry was fetched, for projects.ttl.
+	FetchedAt   time.Time `json:"fetched_at"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	URL         string    `json:"html_url"`
+	Homepage    string    `json:"homepage"`
+	Language    string    `json:"language"`
+	Stars       int       `json:"stargazers_count"`
+	Topics      []string  `json:"topics"`
+	PushedAt    time.Time `json:"pushed_at"`
+	Archived    bool      `json:"archived"`
+}
+
+// githubRepository fetches the metadata of the GitHub repository repo. ("owner/name")
+func githubRepository(pc *ProjectsConfig, repo string) (*ProjectEntry, error) {
+	header := http.Header{}
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if pc.Token != "" {
+		header.Set("Authorization", "Bearer "+pc.Token)
+	}
+	var entry ProjectEntry
+	err := jsonRequest(http.MethodGet, "https://api.github.com/repos/"+repo, header, nil, &entry)
+	if err != nil {
+		return nil, err
+	}
+	entry.FetchedAt = time.Now().UTC()
+	return &entry, nil
+}
+
+// collectProjects returns the metadata of the configured repositories, in
+// order. Cached entries younger than projects.ttl are reused, and entries
+// that cannot be refreshed are kept, however old.
+func collectProjects(gc *GenerationContext) []*ProjectEntry {
+	pc := &gc.Config.Projects
+	ttl, _ := time.ParseDuration(pc.TTL)
+	if gc.DataStore.Projects == nil {
+		gc.DataStore.Projects = make(map[string]*ProjectEntry)
+	}
+
+	var projects []*ProjectEntry
+	for _, repo := range pc.Repositories {
+		entry, ok := gc.DataStore.Projects[repo]
+		if !ok || time.Since(entry.FetchedAt) > ttl {
+			fetched, err := githubRepository(pc, repo)
+			switch {
+			case err == nil:
+				entry, ok = fetched, true
+				gc.DataStore.Projects[repo] = entry
+			case ok:
+				log.Warn().Err(err).Str("repository", repo).Msgf("failed to refresh %s, using the cached metadata", repo)
+			default:
+				log.Warn().Err(err).Str("repository", repo).Msgf("failed to fetch %s, leaving it out", repo)
+			}
+		}
+		if ok {
+			projects = append(projects, entry)
+		}
+	}
+	return projects
+}
+
+// generateProjects writes the projects page, in English.
+func generateProjects(gc *GenerationContext) error {
+	if !gc.Config.Projects.Enabled {
+		return nil
+	}
+	log.Debug().Msg("start generating projects page")
+	entries := collectProjects(gc)
+
+	projects := make([]view.Project, 0, len(entries))
+	for _, e := range entries {
+		if e.Archived {
+			continue
+		}
+		projects = append(projects, view.Project{
+			Name:        e.Name,
+			Description: e.Description,
+			URL:         e.URL,
+			Tags:        e.Topics,
+			Stars:       e.Stars,
+			Language:    e.Language,
+			PushedAt:    e.PushedAt,
+		})
+	}
+
+	l := gc.I18n.Localizer(types.LangEnglish)
+	url := baseURL + pageURL(projectsPath)
+	meta := &view.Metadata{
+		Language:    types.LangEnglish,
+		Title:       l.T("GoSuda | Projects"),
+		Description: l.T("Open source projects of GoSuda."),
+		Author:      "GoSuda",
+		Image:       baseURL + "/assets/images/ogp_placeholder.png",
+		URL:         url,
+		Canonical:   url,
+		BaseURL:     baseURL,
+		UpdatedAt:   time.Now().UTC(),
+		Analytics:   viewAnalytics(gc),
+		Feeds:       viewFeeds(gc, types.LangEnglish),
+	}
+
+	var b bytes.Buffer
+	err := view.ProjectsPage(meta, projects).Render(renderContext(gc, types.LangEnglish), &b)
+	if err != nil {
+		return err
+	}
+	for _, p := range []string{projectsPath, "/" + types.LangEnglish + projectsPath} {
+		err = writePage(gc, p, "the projects page", b.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	gc.Sitemaps[types.LangEnglish] = append(gc.Sitemaps[types.LangEnglish], view.SitemapURL{Loc: url, LastMod: meta.UpdatedAt})
+	log.Debug().Int("projects", len(projects)).Msg("done generating projects page")
+	return nil
+}
@@ -0,0 +1,38 @@
+<?xml version="1.0" encoding="UTF-8"?>
+<!-- Renders the RSS feeds as a web page when they are opened in a browser. -->
+<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
+	<xsl:output method="html" version="1.0" encoding="UTF-8" indent="yes"/>
+	<xsl:template match="/">
+		<html>
+			<head>
+				<meta charset="UTF-8"/>
+				<meta name="viewport" content="width=device-width, initial-scale=1.0"/>
+				<meta name="robots" content="noindex"/>
+				<title><xsl:value-of select="/rss/channel/title"/> (RSS)</title>
+				<link rel="stylesheet" href="/main.css"/>
+			</head>
+			<body>
+				<div class="max-w-3xl mx-auto p-4">
+					<section class="border-2 border-black rounded-lg p-4 mb-6 bg-yellow-100">
+						<strong class="font-bold">This is a web feed.</strong>
+						Copy the URL from the address bar into your feed reader to follow new posts.
+					</section>
+					<header class="mb-6">
+						<h1 class="text-3xl font-bold mb-2"><xsl:value-of select="/rss/channel/title"/></h1>
+						<p class="mb-2"><xsl:value-of select="/rss/channel/description"/></p>
+						<a class="text-blue-500 hover:underline" href="{/rss/channel/link}">Visit the website →</a>
+					</header>
+					<xsl:for-each select="/rss/channel/item">
+						<article class="border-2 border-black rounded-lg p-4 mb-4">
+							<h2 class="text-xl font-bold mb-1">
+								<a class="hover:underline" href="{link}"><xsl:value-of select="title"/></a>
+							</h2>
+							<div class="text-sm text-gray-500 mb-2"><xsl:value-of select="pubDate"/></div>
+							<p><xsl:value-of select="description"/></p>
+						</article>
+					</xsl:for-each>
+				</div>
+			</body>
+		</html>
+	</xsl:template>
+</xsl:stylesheet>
@@ -0,0 +1,70 @@
+// Loaded by the pages with gallery shortcodes, see features.gallery of config.jsonnet.
+
+// Galleries rendered by the gallery shortcode open their images in a lightbox.
+function initGalleries() {
+  document.querySelectorAll("[data-gallery]").forEach((gallery) => {
+    const items = JSON.parse(gallery.dataset.items || "[]");
+    if (items.length === 0) return;
+
+    let overlay, img, index = 0;
+    // arrows follow the reading direction of the page
+    const rtl = document.documentElement.dir === "rtl";
+    const show = (i) => {
+      index = (i + items.length) % items.length;
+      // the links of the grid carry the image URLs with the site path prefix
+      const link = gallery.querySelector(`a[data-index="${index}"]`);
+      img.src = link ? link.href : items[index].src;
+      img.alt = items[index].alt;
+    };
+    const onKey = (e) => {
+      if (e.key === "Escape") close();
+      else if (e.key === "ArrowLeft") show(rtl ? index + 1 : index - 1);
+      else if (e.key === "ArrowRight") show(rtl ? index - 1 : index + 1);
+    };
+    const close = () => {
+      overlay.remove();
+      document.removeEventListener("keydown", onKey);
+    };
+    const open = (i) => {
+      overlay = document.createElement("div");
+      overlay.className = "fixed inset-0 z-50 flex items-center justify-center bg-black/90";
+      overlay.setAttribute("role", "dialog");
+      overlay.setAttribute("aria-modal", "true");
+
+      img = document.createElement("img");
+      img.className = "max-w-[90vw] max-h-[90vh] object-contain";
+      overlay.appendChild(img);
+
+      const button = (label, text, cls, onClick) => {
+        const b = document.createElement("button");
+        b.type = "button";
+        b.className = "absolute text-white text-4xl p-4 " + cls;
+        b.setAttribute("aria-label", label);
+        b.textContent = text;
+        b.addEventListener("click", (e) => { e.stopPropagation(); onClick(); });
+        overlay.appendChild(b);
+        return b;
+      };
+      const closeButton = button("Close", "×", "top-0 end-0", close);
+      if (items.length > 1) {
+        button("Previous image", rtl ? "›" : "‹", "start-0", () => show(index - 1));
+        button("Next image", rtl ? "‹" : "›", "end-0", () => show(index + 1));
+      }
+      overlay.addEventListener("click", (e) => { if (e.target === overlay) close(); });
+      document.addEventListener("keydown", onKey);
+
+      document.body.appendChild(overlay);
+      show(i);
+      closeButton.focus();
+    };
+
+    gallery.querySelectorAll("a[data-index]").forEach((a) => {
+      a.addEventListener("click", (e) => {
+        e.preventDefault();
+        open(Number(a.dataset.index));
+      });
+    });
+  });
+}
+
+initGalleries();
@@ -1,3 +1,7 @@
+// the root of the site, which is below the root of the host when the site is
+// served under a path prefix (main.js is at the root of the site)
+const siteRoot = new URL(".", document.currentScript.src);
+
 function isCrawler() {
   const userAgent = navigator.userAgent.toLowerCase();
   const crawlerPattern = "(bot|crawler|spider|crawl|agent|fetcher|facebookexternalhit|facebookexternalhit|facebookcatalog|googlebot|baidu|msn|ecosia|instagram|ia_archiver|slack|bing|yeti|yahoo|duckduckgo|linkedin|mediapartners|adsbot)"
@@ -103,8 +107,39 @@ async function displayAlt() {
   }
 }
 
+function registerServiceWorker() {
+  if (!("serviceWorker" in navigator)) return;
+  navigator.serviceWorker.register(new URL("sw.js", siteRoot)).catch(() => {});
+}
+
+// Analytics scripts are rendered inert (type="text/plain") and activated here,
+// so visitors opting out through Do Not Track or Global Privacy Control are never tracked.
+function loadAnalytics() {
+  const optOut = navigator.doNotTrack === "1" || navigator.globalPrivacyControl === true;
+
+  document.querySelectorAll('script[type="text/plain"][data-analytics]').forEach((inert) => {
+    if (inert.dataset.consent === "dnt" && optOut) return;
+
+    if (inert.dataset.analytics === "ga4") {
+      window.dataLayer = window.dataLayer || [];
+      window.gtag = function () { window.dataLayer.push(arguments); };
+      window.gtag("js", new Date());
+      window.gtag("config", inert.dataset.id);
+    }
+
+    const script = document.createElement("script");
+    for (const attr of inert.attributes) {
+      if (attr.name !== "type") script.setAttribute(attr.name, attr.value);
+    }
+    script.async = true;
+    inert.replaceWith(script);
+  });
+}
+
 async function main() {
   displayAlt();
+  registerServiceWorker();
+  loadAnalytics();
 }
 
 main();
@@ -0,0 +1,9 @@
+// Loaded by the pages with math blocks after KaTeX, see features.math of config.jsonnet.
+
+function renderMath() {
+  document.querySelectorAll("div.math").forEach((el) => {
+    katex.render(el.textContent, el, { displayMode: true, throwOnError: false });
+  });
+}
+
+renderMath();
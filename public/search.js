@@ -0,0 +1,132 @@
+// Loaded by the search page after lunr.js and the lunr-languages stemmer of its
+// language, see search of config.jsonnet.
+
+// The search page queries the prebuilt lunr index of its language. The tags of
+// the results are facets, and the links of the language filter keep the query.
+async function initSearch() {
+  const results = document.querySelector("[data-search-results]");
+  if (!results) return;
+  const input = document.querySelector("[data-search-form]").elements.q;
+  const status = document.querySelector("[data-search-status]");
+  const facets = document.querySelector("[data-search-tags]");
+  const params = new URLSearchParams(location.search);
+  input.value = params.get("q") || "";
+  const selected = new Set(params.getAll("tag"));
+
+  let index, documents;
+  status.textContent = status.dataset.loading;
+  try {
+    const [data, docs] = await Promise.all(
+      [results.dataset.index, results.dataset.documents].map(async (url) => {
+        const res = await fetch(url);
+        if (!res.ok) throw new Error(`${url}: ${res.status}`);
+        return res.json();
+      }),
+    );
+    // fails if the stemmer of the index language is not registered
+    index = lunr.Index.load(data);
+    documents = docs;
+  } catch (err) {
+    console.error(err);
+    status.textContent = status.dataset.failed;
+    return;
+  }
+
+  const search = (q) => {
+    try {
+      return index.search(q);
+    } catch {
+      // not a valid lunr query, such as "go:", so search its words
+      return index.search(q.replace(/[:~^*+-]/g, " "));
+    }
+  };
+
+  const tagsOf = (doc) => doc.tags || [];
+
+  const render = () => {
+    const q = input.value.trim();
+    const url = new URL(location.href);
+    url.search = "";
+    if (q) url.searchParams.set("q", q);
+    selected.forEach((tag) => url.searchParams.append("tag", tag));
+    history.replaceState(null, "", url);
+    document.querySelectorAll("a[data-search-lang]").forEach((a) => {
+      const link = new URL(a.href);
+      link.search = q ? "?" + new URLSearchParams({ q }) : "";
+      a.href = link;
+    });
+
+    results.replaceChildren();
+    facets.replaceChildren();
+    if (!q) {
+      status.textContent = "";
+      return;
+    }
+    const hits = search(q).filter((hit) => documents[hit.ref]);
+
+    // the facets count the tags of all hits, the results have every selected tag
+    const counts = new Map();
+    selected.forEach((tag) => counts.set(tag, 0));
+    hits.forEach((hit) => tagsOf(documents[hit.ref]).forEach((tag) => counts.set(tag, (counts.get(tag) || 0) + 1)));
+    [...counts]
+      .sort((a, b) => b[1] - a[1] || a[0].localeCompare(b[0]))
+      .forEach(([tag, count]) => {
+        const button = document.createElement("button");
+        button.type = "button";
+        button.className = "px-2 py-1 border-2 border-black rounded";
+        if (selected.has(tag)) button.classList.add("bg-black", "text-white");
+        button.setAttribute("aria-pressed", selected.has(tag));
+        button.textContent = `${tag} (${count})`;
+        button.addEventListener("click", () => {
+          if (!selected.delete(tag)) selected.add(tag);
+          render();
+        });
+        facets.appendChild(button);
+      });
+
+    const shown = hits.filter((hit) => [...selected].every((tag) => tagsOf(documents[hit.ref]).includes(tag)));
+    const lang = document.documentElement.lang;
+    status.textContent = shown.length
+      ? status.dataset.count.replace("{count}", shown.length.toLocaleString(lang))
+      : status.dataset.empty;
+    shown.forEach((hit) => {
+      const doc = documents[hit.ref];
+      const item = document.createElement("li");
+      item.className = "border-2 border-black rounded-lg p-4";
+      const title = document.createElement("h2");
+      title.className = "text-2xl font-bold mb-2";
+      const link = document.createElement("a");
+      link.href = hit.ref;
+      link.className = "hover:underline";
+      link.textContent = doc.title;
+      title.appendChild(link);
+      item.appendChild(title);
+      if (doc.description) {
+        const description = document.createElement("p");
+        description.textContent = doc.description;
+        item.appendChild(description);
+      }
+      if (tagsOf(doc).length > 0) {
+        const tags = document.createElement("p");
+        tags.className = "text-sm text-gray-600 mt-2";
+        tags.textContent = tagsOf(doc).map((tag) => "#" + tag).join(" ");
+        item.appendChild(tags);
+      }
+      results.appendChild(item);
+    });
+  };
+
+  let timer;
+  input.addEventListener("input", () => {
+    clearTimeout(timer);
+    timer = setTimeout(render, 150);
+  });
+  input.form.addEventListener("submit", (e) => {
+    e.preventDefault();
+    clearTimeout(timer);
+    render();
+  });
+  render();
+}
+
+initSearch();
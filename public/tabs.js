@@ -0,0 +1,63 @@
+// Loaded by the pages with groups of tabs, see features.tabs of config.jsonnet.
+
+// The groups of tabs are rendered with all their panels shown one after
+// another; they become tab lists showing one panel at a time. Choosing a tab
+// chooses the tabs of the same label in the other groups, and on the next
+// pages, such as the same operating system everywhere.
+function initTabs() {
+  const groups = document.querySelectorAll(".tabs");
+  if (groups.length === 0) return;
+
+  const tabs = (group) => [...group.querySelectorAll(':scope > [role="tablist"] > [role="tab"]')];
+  const select = (group, tab) => {
+    tabs(group).forEach((t) => {
+      const selected = t === tab;
+      t.setAttribute("aria-selected", String(selected));
+      t.tabIndex = selected ? 0 : -1;
+      document.getElementById(t.getAttribute("aria-controls")).hidden = !selected;
+    });
+  };
+  const selectLabel = (label) => {
+    groups.forEach((group) => {
+      const tab = tabs(group).find((t) => t.textContent === label);
+      if (tab) select(group, tab);
+    });
+  };
+
+  let saved = null;
+  try {
+    saved = localStorage.getItem("tab");
+  } catch {}
+  // arrows follow the reading direction of the page
+  const rtl = document.documentElement.dir === "rtl";
+
+  groups.forEach((group) => {
+    const list = tabs(group);
+    group.querySelector(':scope > [role="tablist"]').hidden = false;
+    group.querySelectorAll(':scope > [role="tabpanel"] > .tab-label').forEach((p) => (p.hidden = true));
+    select(group, list.find((t) => t.textContent === saved) || list[0]);
+
+    list.forEach((tab, i) => {
+      tab.addEventListener("click", () => {
+        selectLabel(tab.textContent);
+        try {
+          localStorage.setItem("tab", tab.textContent);
+        } catch {}
+      });
+      tab.addEventListener("keydown", (e) => {
+        let next;
+        if (e.key === "ArrowLeft") next = rtl ? i + 1 : i - 1;
+        else if (e.key === "ArrowRight") next = rtl ? i - 1 : i + 1;
+        else if (e.key === "Home") next = 0;
+        else if (e.key === "End") next = list.length - 1;
+        else return;
+        e.preventDefault();
+        const t = list[(next + list.length) % list.length];
+        select(group, t);
+        t.focus();
+      });
+    });
+  });
+}
+
+initTabs();
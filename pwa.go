@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zeebo/blake3"
+	"golang.org/x/image/draw"
+	"gosuda.org/website/internal/types"
+)
+
+// pwaIcons is the icon set referenced by the page head and the web manifest.
+var pwaIcons = []struct {
+	Name string
+	Size int
+}{
+	{"favicon-16x16.png", 16},
+	{"favicon-32x32.png", 32},
+	{"apple-touch-icon.png", 180},
+	{"mstile-150x150.png", 150},
+	{"android-chrome-192x192.png", 192},
+	{"android-chrome-512x512.png", 512},
+}
+
+func resizeImage(src image.Image, size int) image.Image {
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+	return dst
+}
+
+// encodeICO encodes PNG images into an ICO container.
+func encodeICO(images [][]byte, sizes []int) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, [3]uint16{0, 1, uint16(len(images))})
+
+	offset := 6 + 16*len(images)
+	for i, data := range images {
+		dim := uint8(sizes[i])
+		if sizes[i] >= 256 {
+			dim = 0
+		}
+		b.Write([]byte{dim, dim, 0, 0})
+		binary.Write(&b, binary.LittleEndian, [2]uint16{1, 32})
+		binary.Write(&b, binary.LittleEndian, [2]uint32{uint32(len(data)), uint32(offset)})
+		offset += len(data)
+	}
+	for _, data := range images {
+		b.Write(data)
+	}
+	return b.Bytes()
+}
+
+func generatePWAIcons(pc *PWAConfig) error {
+	f, err := os.Open(pc.Icon)
+	if err != nil {
+		return err
+	}
+	src, _, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", pc.Icon, err)
+	}
+
+	assetsDir := filepath.Join(distDir, "assets")
+	err = os.MkdirAll(assetsDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	for _, icon := range pwaIcons {
+		b.Reset()
+		err = png.Encode(&b, resizeImage(src, icon.Size))
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filepath.Join(assetsDir, icon.Name), b.Bytes(), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	icoSizes := []int{16, 32, 48}
+	var icoImages [][]byte
+	for _, size := range icoSizes {
+		b.Reset()
+		err = png.Encode(&b, resizeImage(src, size))
+		if err != nil {
+			return err
+		}
+		icoImages = append(icoImages, append([]byte(nil), b.Bytes()...))
+	}
+	ico := encodeICO(icoImages, icoSizes)
+	for _, path := range []string{filepath.Join(distDir, "favicon.ico"), filepath.Join(assetsDir, "favicon.ico")} {
+		err = os.WriteFile(path, ico, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type webManifestIcon struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type"`
+}
+
+type webManifest struct {
+	Name            string            `json:"name"`
+	ShortName       string            `json:"short_name"`
+	Description     string            `json:"description,omitempty"`
+	StartURL        string            `json:"start_url"`
+	Scope           string            `json:"scope"`
+	Icons           []webManifestIcon `json:"icons"`
+	ThemeColor      string            `json:"theme_color"`
+	BackgroundColor string            `json:"background_color"`
+	Display         string            `json:"display"`
+}
+
+const serviceWorkerTemplate = `const CACHE = "site-%s";
+const PRECACHE = %s;
+
+self.addEventListener("install", (event) => {
+  event.waitUntil(caches.open(CACHE).then((cache) => cache.addAll(PRECACHE)).then(() => self.skipWaiting()));
+});
+
+self.addEventListener("activate", (event) => {
+  event.waitUntil(
+    caches.keys()
+      .then((keys) => Promise.all(keys.filter((key) => key !== CACHE).map((key) => caches.delete(key))))
+      .then(() => self.clients.claim())
+  );
+});
+
+self.addEventListener("fetch", (event) => {
+  const request = event.request;
+  if (request.method !== "GET" || new URL(request.url).origin !== self.location.origin) return;
+
+  if (request.mode === "navigate") {
+    // network first for pages, so new posts show up while online
+    event.respondWith(
+      fetch(request)
+        .then((response) => {
+          const copy = response.clone();
+          caches.open(CACHE).then((cache) => cache.put(request, copy));
+          return response;
+        })
+        .catch(() => caches.match(request).then((cached) => cached || caches.match(PRECACHE[0])))
+    );
+    return;
+  }
+
+  // cache first for static assets
+  event.respondWith(
+    caches.match(request).then((cached) => cached || fetch(request).then((response) => {
+      const copy = response.clone();
+      caches.open(CACHE).then((cache) => cache.put(request, copy));
+      return response;
+    }))
+  );
+});
+`
+
+// precacheList returns the static assets and recent post pages cached by the
+// service worker, the site root first.
+func precacheList(gc *GenerationContext, recent int) ([]string, error) {
+	list := []string{"/"}
+
+	files, err := generateFileList(distDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range files {
+		rel, err := filepath.Rel(distDir, path)
+		if err != nil {
+			return nil, err
+		}
+		rel = "/" + filepath.ToSlash(rel)
+		switch strings.ToLower(filepath.Ext(rel)) {
+		case ".css", ".js", ".woff2":
+			if rel == "/sw.js" || rel == "/tailwind-main.css" {
+				continue
+			}
+			list = append(list, rel)
+		}
+	}
+
+	var posts []*types.Post
+	for _, post := range listedPosts(gc) {
+		if post.Main.Metadata.Hidden {
+			continue
+		}
+		if _, ok := post.Translated[types.LangEnglish]; !ok {
+			continue
+		}
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].Main.Metadata.Date.After(posts[j].Main.Metadata.Date)
+	})
+	if len(posts) > recent {
+		posts = posts[:recent]
+	}
+	for _, post := range posts {
+		list = append(list, pageURL(post.Path))
+	}
+
+	return list, nil
+}
+
+// generatePWA writes the web manifest, the icon set and the offline service worker.
+func generatePWA(gc *GenerationContext) error {
+	pc := &gc.Config.PWA
+	if !pc.Enabled {
+		return nil
+	}
+	log.Debug().Msg("start generating PWA files")
+
+	err := generatePWAIcons(pc)
+	if err != nil {
+		return err
+	}
+
+	m := webManifest{
+		Name:            pc.Name,
+		ShortName:       pc.ShortName,
+		Description:     pc.Description,
+		StartURL:        "/",
+		Scope:           "/",
+		ThemeColor:      pc.ThemeColor,
+		BackgroundColor: pc.BackgroundColor,
+		Display:         "standalone",
+	}
+	for _, icon := range pwaIcons {
+		if strings.HasPrefix(icon.Name, "android-chrome-") {
+			size := fmt.Sprintf("%dx%d", icon.Size, icon.Size)
+			m.Icons = append(m.Icons, webManifestIcon{Src: "/assets/" + icon.Name, Sizes: size, Type: "image/png"})
+		}
+	}
+	data, err := json.Marshal(&m)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(distDir, "assets", "site.webmanifest"), data, 0644)
+	if err != nil {
+		return err
+	}
+
+	precache, err := precacheList(gc, pc.PrecachePosts)
+	if err != nil {
+		return err
+	}
+	// the service worker is not rewritten by applyPathPrefix
+	urls := make([]string, len(precache))
+	for i, p := range precache {
+		urls[i] = sitePath(p)
+	}
+	precacheJSON, err := json.Marshal(urls)
+	if err != nil {
+		return err
+	}
+
+	// The cache version changes whenever a precached file changes.
+	h := blake3.New()
+	for _, p := range precache {
+		h.WriteString(p)
+		if data, err := os.ReadFile(filepath.Join(distDir, filepath.FromSlash(p))); err == nil {
+			h.Write(data)
+		}
+	}
+	version := hex.EncodeToString(h.Sum(nil))[:16]
+
+	sw := fmt.Sprintf(serviceWorkerTemplate, version, precacheJSON)
+	err = os.WriteFile(filepath.Join(distDir, "sw.js"), []byte(sw), 0644)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Int("precache", len(precache)).Msg("done generating PWA files")
+	return nil
+}
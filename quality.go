@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/readability"
+	"gosuda.org/website/internal/types"
+)
+
+// proseBlocks are the elements whose end separates paragraphs of prose.
+var proseBlocks = map[string]bool{
+	"p": true, "li": true, "blockquote": true, "td": true, "th": true, "div": true, "br": true, "figcaption": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// proseSkipped are the elements whose text is not prose.
+var proseSkipped = map[string]bool{"pre": true, "script": true, "style": true, "math": true, "svg": true}
+
+// documentProse returns the prose of a rendered document, one paragraph per
+// line, and its headings.
+func documentProse(doc *types.Document) (string, []readability.Heading) {
+	var text, heading strings.Builder
+	var headings []readability.Heading
+	skip, level := 0, 0
+	htmlrewrite.Walk([]byte(doc.HTML), func(t *htmlrewrite.Token) {
+		switch t.Type {
+		case htmlrewrite.StartTagToken:
+			if proseSkipped[t.Data] {
+				skip++
+			}
+			if len(t.Data) == 2 && t.Data[0] == 'h' && t.Data[1] >= '1' && t.Data[1] <= '6' {
+				level = int(t.Data[1] - '0')
+				heading.Reset()
+			}
+		case htmlrewrite.EndTagToken:
+			if proseSkipped[t.Data] && skip > 0 {
+				skip--
+			}
+			if level > 0 && len(t.Data) == 2 && t.Data[0] == 'h' {
+				headings = append(headings, readability.Heading{Level: level, Text: heading.String()})
+				level = 0
+			}
+			if proseBlocks[t.Data] {
+				text.WriteByte('\n')
+			}
+		case htmlrewrite.SelfClosingTagToken:
+			if proseBlocks[t.Data] {
+				text.WriteByte('\n')
+			}
+		case htmlrewrite.TextToken:
+			if skip > 0 {
+				return
+			}
+			if level > 0 {
+				heading.WriteString(t.Data)
+				return
+			}
+			text.WriteString(strings.ReplaceAll(t.Data, "\n", " "))
+		}
+	})
+	return text.String(), headings
+}
+
+// postQuality is the quality report of a document.
+type postQuality struct {
+	post     *types.Post
+	doc      *types.Document
+	stats    readability.Stats
+	warnings []string
+}
+
+func report_quality_main(args []string) {
+	fs := flag.NewFlagSet("report quality", flag.ExitOnError)
+	sortBy := fs.String("sort", "length", "order posts by average sentence length (length), passive voice (passive) or reading ease (score)")
+	translations := fs.Bool("translations", false, "include translations")
+	fs.Parse(args)
+
+	ds, err := initializeDatabaseContent(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+
+	var reports []*postQuality
+	for _, post := range ds.Posts {
+		if post.Main == nil || post.Main.Metadata.Hidden {
+			continue
+		}
+		docs := []*types.Document{post.Main}
+		if *translations {
+			for lang, doc := range post.Translated {
+				if lang != post.Main.Metadata.Language {
+					docs = append(docs, doc)
+				}
+			}
+		}
+		for _, doc := range docs {
+			prose, headings := documentProse(doc)
+			reports = append(reports, &postQuality{
+				post:     post,
+				doc:      doc,
+				stats:    readability.Analyze(prose, doc.Metadata.Language),
+				warnings: readability.HeadingWarnings(headings),
+			})
+		}
+	}
+
+	less := map[string]func(a, b *postQuality) bool{
+		"length":  func(a, b *postQuality) bool { return a.stats.AvgSentenceLength > b.stats.AvgSentenceLength },
+		"passive": func(a, b *postQuality) bool { return a.stats.PassiveRatio() > b.stats.PassiveRatio() },
+		"score": func(a, b *postQuality) bool {
+			// posts without a score go last
+			if a.stats.HasScore != b.stats.HasScore {
+				return a.stats.HasScore
+			}
+			return a.stats.Score < b.stats.Score
+		},
+	}[*sortBy]
+	if less == nil {
+		log.Fatal().Msgf("unknown sort order %q", *sortBy)
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		return less(reports[i], reports[j])
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tLANG\tEASE\tWORDS/SENTENCE\tLONG\tPASSIVE\tHEADINGS\tTITLE")
+	var warned int
+	for _, r := range reports {
+		ease := "-"
+		if r.stats.HasScore {
+			ease = fmt.Sprintf("%.0f", r.stats.Score)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.1f\t%d/%d\t%.0f%%\t%d\t%s\n", r.post.FilePath, r.doc.Metadata.Language, ease,
+			r.stats.AvgSentenceLength, r.stats.LongSentences, r.stats.Sentences, r.stats.PassiveRatio()*100, len(r.warnings), r.doc.Metadata.Title)
+		if len(r.warnings) > 0 {
+			warned++
+		}
+	}
+	w.Flush()
+
+	for _, r := range reports {
+		for _, warning := range r.warnings {
+			fmt.Printf("%s (%s): %s\n", r.post.FilePath, r.doc.Metadata.Language, warning)
+		}
+	}
+	log.Info().Int("documents", len(reports)).Int("heading_warnings", warned).Msgf("analyzed %d documents, %d with heading warnings", len(reports), warned)
+}
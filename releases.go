@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/markdown"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// releasesPath is the site path of the release notes page.
+const releasesPath = "/releases"
+
+// Release is a release of a project, cached in the DataStore so that the
+// releases page can be built when the sources are unreachable.
+type Release struct {
+	Project    string    `json:"project"`
+	Tag        string    `json:"tag"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url,omitempty"`
+	Date       time.Time `json:"date"`
+	Prerelease bool      `json:"prerelease,omitempty"`
+	// Notes are the release notes in Markdown.
+	Notes string `json:"notes"`
+}
+
+// githubRelease is a release of the GitHub Releases API.
+type githubRelease struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	HTMLURL     string    `json:"html_url"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// githubReleases returns the published releases of the GitHub repository src.GitHub, newest first.
+func githubReleases(rc *ReleasesConfig, src *ReleaseSource) ([]*Release, error) {
+	header := http.Header{}
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if rc.Token != "" {
+		header.Set("Authorization", "Bearer "+rc.Token)
+	}
+	var list []githubRelease
+	url := fmt.Sprintf("https://api.github.com/repos/%s/releases?per_page=%d", src.GitHub, rc.Limit)
+	err := jsonRequest(http.MethodGet, url, header, nil, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []*Release
+	for _, r := range list {
+		if r.Draft {
+			continue
+		}
+		releases = append(releases, &Release{
+			Project:    src.Name,
+			Tag:        r.TagName,
+			Name:       cmp.Or(r.Name, r.TagName),
+			URL:        r.HTMLURL,
+			Date:       r.PublishedAt,
+			Prerelease: r.Prerelease,
+			Notes:      r.Body,
+		})
+	}
+	return releases, nil
+}
+
+// gitTagReleases returns the annotated tags of the git repository src.Git as
+// releases, newest first. The tag message is the release notes, and its
+// subject the release name. Lightweight tags are skipped.
+func gitTagReleases(rc *ReleasesConfig, src *ReleaseSource) ([]*Release, error) {
+	out, err := runGit(src.Git, nil, "for-each-ref", "--sort=-creatordate",
+		"--format=%(refname:short)%1f%(objecttype)%1f%(creatordate:iso-strict)%1f%(contents:subject)%1f%(contents:body)%1e", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []*Release
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.Split(strings.TrimPrefix(record, "\n"), "\x1f")
+		if len(fields) != 5 || fields[1] != "tag" {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("tag %s: %w", fields[0], err)
+		}
+		r := &Release{
+			Project: src.Name,
+			Tag:     fields[0],
+			Name:    cmp.Or(fields[3], fields[0]),
+			Date:    date,
+			Notes:   strings.TrimSpace(fields[4]),
+		}
+		if src.URL != "" && strings.HasPrefix(src.URL, "https://github.com/") {
+			r.URL = src.URL + "/releases/tag/" + r.Tag
+		}
+		releases = append(releases, r)
+		if len(releases) == rc.Limit {
+			break
+		}
+	}
+	return releases, nil
+}
+
+// collectReleases returns the releases of every configured repository, newest
+// first. A repository that cannot be read keeps its releases of the last
+// successful build.
+func collectReleases(gc *GenerationContext) []*Release {
+	rc := &gc.Config.Releases
+	if gc.DataStore.Releases == nil {
+		gc.DataStore.Releases = make(map[string][]*Release)
+	}
+
+	var releases []*Release
+	for i := range rc.Repositories {
+		src := &rc.Repositories[i]
+		var (
+			key  string
+			list []*Release
+			err  error
+		)
+		switch {
+		case src.GitHub != "":
+			key = "github:" + src.GitHub
+			list, err = githubReleases(rc, src)
+		case src.Git != "":
+			key = "git:" + src.Git
+			list, err = gitTagReleases(rc, src)
+		default:
+			log.Warn().Str("name", src.Name).Msg("release repository has neither github nor git set, skipping it")
+			continue
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("repository", key).Msgf("failed to read the releases of %s, using the cached releases", src.Name)
+			list = gc.DataStore.Releases[key]
+		} else {
+			gc.DataStore.Releases[key] = list
+		}
+		releases = append(releases, list...)
+	}
+
+	sort.SliceStable(releases, func(i, j int) bool {
+		return releases[i].Date.After(releases[j].Date)
+	})
+	return releases
+}
+
+// releasesFeedURL returns the URL of the releases feed.
+func releasesFeedURL() string {
+	return baseURL + releasesPath + "/feed.rss"
+}
+
+// generateReleases writes the release notes page and its feed, in English.
+func generateReleases(gc *GenerationContext) error {
+	rc := &gc.Config.Releases
+	if !rc.Enabled {
+		return nil
+	}
+	log.Debug().Msg("start generating releases")
+	releases := collectReleases(gc)
+
+	projectURLs := make(map[string]string)
+	for _, src := range rc.Repositories {
+		projectURLs[src.Name] = src.URL
+	}
+
+	feed := &feeds.Feed{
+		Title:       "GoSuda Releases",
+		Link:        &feeds.Link{Href: baseURL + pageURL(releasesPath)},
+		Description: "Release notes of GoSuda projects.",
+		Author:      &feeds.Author{Name: "Gosuda", Email: "webmaster@gosuda.org"},
+		Created:     time.Now().UTC(),
+	}
+	items := make([]view.Release, 0, len(releases))
+	for _, r := range releases {
+		notes, err := markdown.ParseMarkdown(r.Notes)
+		if err != nil {
+			return fmt.Errorf("release notes of %s %s: %w", r.Project, r.Tag, err)
+		}
+		items = append(items, view.Release{
+			Project:    r.Project,
+			ProjectURL: projectURLs[r.Project],
+			Tag:        r.Tag,
+			Name:       r.Name,
+			URL:        r.URL,
+			Date:       r.Date,
+			Prerelease: r.Prerelease,
+			HTML:       notes.HTML,
+		})
+
+		link := cmp.Or(r.URL, baseURL+pageURL(releasesPath))
+		content, err := absoluteHTML(notes.HTML, link)
+		if err != nil {
+			return err
+		}
+		feed.Items = append(feed.Items, &feeds.Item{
+			Id:          langFeedID("release:"+r.Project+"@"+r.Tag, types.LangEnglish),
+			Title:       r.Project + " " + r.Name,
+			Link:        &feeds.Link{Href: link},
+			Description: content,
+			Created:     r.Date.UTC(),
+		})
+	}
+
+	rss, err := feed.ToRss()
+	if err != nil {
+		return err
+	}
+	fp := filepath.Join(distDir, filepath.FromSlash(releasesPath), "feed.rss")
+	err = os.MkdirAll(filepath.Dir(fp), 0755)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(fp, []byte(styleFeed(rss)), 0644)
+	if err != nil {
+		return err
+	}
+
+	l := gc.I18n.Localizer(types.LangEnglish)
+	url := baseURL + pageURL(releasesPath)
+	meta := &view.Metadata{
+		Language:    types.LangEnglish,
+		Title:       l.T("GoSuda | Releases"),
+		Description: l.T("Release notes of GoSuda projects."),
+		Author:      "GoSuda",
+		Image:       baseURL + "/assets/images/ogp_placeholder.png",
+		URL:         url,
+		Canonical:   url,
+		BaseURL:     baseURL,
+		Analytics:   viewAnalytics(gc),
+		Feeds:       append([]view.FeedLink{{Title: feed.Title, URL: releasesFeedURL(), Lang: types.LangEnglish}}, viewFeeds(gc, types.LangEnglish)...),
+	}
+	if len(releases) > 0 {
+		meta.CreatedAt = releases[len(releases)-1].Date
+		meta.UpdatedAt = releases[0].Date
+	}
+
+	var b bytes.Buffer
+	err = view.ReleasesPage(meta, items).Render(renderContext(gc, types.LangEnglish), &b)
+	if err != nil {
+		return err
+	}
+	for _, p := range []string{releasesPath, "/" + types.LangEnglish + releasesPath} {
+		err = writePage(gc, p, "the releases page", b.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	gc.Sitemaps[types.LangEnglish] = append(gc.Sitemaps[types.LangEnglish], view.SitemapURL{Loc: url, LastMod: meta.UpdatedAt})
+	log.Debug().Int("releases", len(releases)).Msg("done generating releases")
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// renderContext returns the context the templates of pages in lang are
+// rendered with.
+func renderContext(gc *GenerationContext, lang types.Lang) context.Context {
+	return view.NewContext(context.Background(), &view.RenderContext{
+		Localizer: gc.I18n.Localizer(lang),
+		Funcs:     templateFuncs(gc),
+	})
+}
+
+// templateFuncs returns the helpers of the templates.
+func templateFuncs(gc *GenerationContext) view.Funcs {
+	return view.Funcs{
+		URL: func(p string, lang string) string {
+			return pageURL(langPath(p, lang))
+		},
+		Asset: func(p string) string {
+			p = path.Clean("/" + p)
+			if _, err := os.Stat(filepath.Join(publicDir, filepath.FromSlash(p))); err != nil {
+				log.Warn().Str("asset", p).Msgf("template asset %s does not exist in %s", p, publicDir)
+			}
+			return p
+		},
+		Related: func(id string, lang string, n int) []*view.BlogPostPreview {
+			return relatedPosts(gc, id, lang, n)
+		},
+		Tags: func() []view.TagCount {
+			return tagCounts(gc)
+		},
+		Search: func(lang string) string {
+			if !gc.Config.Search.Enabled {
+				return ""
+			}
+			return pageURL(langPath(searchPath, lang))
+		},
+	}
+}
+
+// relatedPosts returns previews of up to n listed posts available in lang that
+// share tags with the post of id, most shared tags first, then newest first.
+func relatedPosts(gc *GenerationContext, id string, lang types.Lang, n int) []*view.BlogPostPreview {
+	post, ok := gc.DataStore.Posts[id]
+	if !ok || post.Main == nil {
+		return nil
+	}
+	tags := make(map[string]bool)
+	for _, tag := range post.Main.Metadata.Tags {
+		tags[tag] = true
+	}
+
+	type scored struct {
+		post   *types.Post
+		shared int
+	}
+	var candidates []scored
+	for _, p := range listedPosts(gc) {
+		if p.ID == id || p.Main.Metadata.Hidden {
+			continue
+		}
+		var shared int
+		for _, tag := range p.Main.Metadata.Tags {
+			if tags[tag] {
+				shared++
+			}
+		}
+		if shared > 0 {
+			candidates = append(candidates, scored{p, shared})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].shared != candidates[j].shared {
+			return candidates[i].shared > candidates[j].shared
+		}
+		return candidates[i].post.Main.Metadata.Date.After(candidates[j].post.Main.Metadata.Date)
+	})
+
+	var previews []*view.BlogPostPreview
+	for _, c := range candidates {
+		if len(previews) == n {
+			break
+		}
+		if preview := postPreview(gc, c.post, lang); preview != nil {
+			previews = append(previews, preview)
+		}
+	}
+	return previews
+}
+
+// tagCounts returns the tags of the listed posts, most used first, then by
+// name.
+func tagCounts(gc *GenerationContext) []view.TagCount {
+	var counts []view.TagCount
+	for tag, posts := range tagPosts(gc) {
+		counts = append(counts, view.TagCount{Name: tag, Count: len(posts), FeedURL: baseURL + tagFeedPath(tag)})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	return counts
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/rs/zerolog/log"
+	"github.com/zeebo/blake3"
+	"gosuda.org/website/internal/types"
+)
+
+// renderCacheDir holds the rendered Markdown documents of the previous builds,
+// which CI restores between runs.
+const renderCacheDir = "zdata/cache"
+
+// renderCache stores the documents rendered from Markdown files, keyed by the
+// hash of their content and of the renderer, so that the files unchanged since
+// the previous build are not rendered again. A nil renderCache renders every
+// file.
+type renderCache struct {
+	dir string
+	// prefix hashes the renderer into the keys.
+	prefix string
+	// used are the keys of the documents of this build, see prune.
+	used map[string]bool
+}
+
+// newRenderCache returns the render cache of the renderer of gc, or nil if the
+// renderer is unknown, as its changes would go unnoticed. The documents only
+// depend on the renderer and their Markdown, not on the config.
+func newRenderCache(gc *GenerationContext) *renderCache {
+	if gc.Renderer == "" {
+		return nil
+	}
+	return &renderCache{
+		dir:    renderCacheDir,
+		prefix: gc.Renderer + "\x00",
+		used:   make(map[string]bool),
+	}
+}
+
+func (c *renderCache) path(data []byte) (key, path string) {
+	h := blake3.New()
+	h.Write([]byte(c.prefix))
+	h.Write(data)
+	key = hex.EncodeToString(h.Sum(nil))
+	return key, filepath.Join(c.dir, key+".json.zst")
+}
+
+// get returns the cached document of the Markdown data.
+func (c *renderCache) get(data []byte) (*types.Document, bool) {
+	if c == nil {
+		return nil, false
+	}
+	key, path := c.path(data)
+	compressed, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	d, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, false
+	}
+	defer d.Close()
+	raw, err := d.DecodeAll(compressed, nil)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msgf("ignoring corrupt render cache entry %s", path)
+		return nil, false
+	}
+	var doc types.Document
+	err = json.Unmarshal(raw, &doc)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msgf("ignoring corrupt render cache entry %s", path)
+		return nil, false
+	}
+	c.used[key] = true
+	return &doc, true
+}
+
+// put caches the document rendered from the Markdown data. Failing to write
+// the cache only costs rendering the file again.
+func (c *renderCache) put(data []byte, doc *types.Document) {
+	if c == nil {
+		return
+	}
+	key, path := c.path(data)
+	c.used[key] = true
+	err := c.write(path, doc)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msgf("failed to write render cache entry %s", path)
+	}
+}
+
+func (c *renderCache) write(path string, doc *types.Document) error {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	e, err := zstd.NewWriter(nil)
+	if err != nil {
+		return err
+	}
+	defer e.Close()
+
+	err = os.MkdirAll(c.dir, 0755)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path+".tmp", e.EncodeAll(raw, nil), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// prune removes the cached documents not used by this build, those of changed
+// or removed files and of previous renderers.
+func (c *renderCache) prune() error {
+	if c == nil {
+		return nil
+	}
+	entries, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var removed int
+	for _, e := range entries {
+		key, ok := strings.CutSuffix(e.Name(), ".json.zst")
+		if ok && c.used[key] {
+			continue
+		}
+		err = os.Remove(filepath.Join(c.dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		removed++
+	}
+	log.Debug().Int("used", len(c.used)).Int("removed", removed).Msg("pruned render cache")
+	return nil
+}
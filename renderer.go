@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zeebo/blake3"
+	"gosuda.org/website/internal/markdown"
+	"gosuda.org/website/internal/types"
+)
+
+// markdownDir holds the Markdown renderer, whose extensions render the HTML of
+// the documents.
+const markdownDir = "internal/markdown"
+
+// rendererFiles returns the files the HTML of the documents depends on: the
+// Markdown renderer, the document types it fills in and go.sum, which pins the
+// versions of goldmark and chroma. The templates of view/ only render pages
+// around the documents and are not included.
+func rendererFiles() ([]string, error) {
+	var files []string
+	for _, pattern := range []string{
+		filepath.Join(markdownDir, "*.go"),
+		filepath.Join("internal", "types", "*.go"),
+		"go.sum",
+	} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if !strings.HasSuffix(m, "_test.go") {
+				files = append(files, filepath.ToSlash(m))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// rendererHash returns the hash of the renderer, or "" if its files are not
+// found, such as when the generator runs outside of the repository.
+func rendererHash() (string, error) {
+	files, err := rendererFiles()
+	if err != nil || len(files) == 0 {
+		return "", err
+	}
+	h := blake3.New()
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte(path + "\x00"))
+		h.Write(data)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// rerenderDocuments renders the stored documents of the posts again from their
+// Markdown if the renderer changed since they were rendered. The main
+// documents and human translations are rendered on every build, through the
+// render cache; the machine translations are rendered when they are
+// translated, which is when the content of their post changes, and would
+// otherwise keep the HTML of the renderer of their translation.
+func rerenderDocuments(gc *GenerationContext) {
+	if gc.Renderer == "" {
+		log.Warn().Msgf("renderer not found in %s, stored documents are not rendered again", markdownDir)
+		return
+	}
+	if gc.DataStore.Renderer == gc.Renderer {
+		log.Debug().Msg("renderer unchanged, keeping stored documents")
+		return
+	}
+
+	log.Debug().Str("renderer", gc.Renderer).Msg("start rendering stored documents with the changed renderer")
+	var count int
+	for _, post := range gc.DataStore.Posts {
+		for lang, doc := range post.Translated {
+			if doc == post.Main || isHumanTranslation(doc) || doc.Type != types.DocumentTypeMarkdown {
+				continue
+			}
+			stored, err := gc.DataStore.document(post, doc)
+			if err != nil {
+				log.Error().Err(err).Str("path", post.FilePath).Str("lang", lang).Msgf("failed to read the %s translation of %s", lang, post.FilePath)
+				continue
+			}
+			if stored.Markdown == "" {
+				continue
+			}
+			rendered, err := markdown.ParseMarkdown(stored.Markdown)
+			if err != nil {
+				log.Error().Err(err).Str("path", post.FilePath).Str("lang", lang).Msgf("failed to render the %s translation of %s", lang, post.FilePath)
+				continue
+			}
+			stored.HTML = rendered.HTML
+			err = gc.DataStore.setDocument(post, doc, stored)
+			if err != nil {
+				log.Error().Err(err).Str("path", post.FilePath).Str("lang", lang).Msgf("failed to store the %s translation of %s", lang, post.FilePath)
+				continue
+			}
+			doc.Features = rendered.Features
+			count++
+		}
+	}
+	gc.DataStore.Renderer = gc.Renderer
+	log.Info().Int("documents", count).Msg("renderer changed, rendered stored documents again")
+}
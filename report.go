@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+)
+
+func report_main() {
+	if len(os.Args) < 3 {
+		log.Fatal().Msg("usage: report <stale|alt|quality|duplicates|translations|weight|review|calendar>")
+	}
+
+	switch os.Args[2] {
+	case "stale":
+		report_stale_main(os.Args[3:]) // list posts not reviewed or updated recently.
+	case "alt":
+		report_alt_main(os.Args[3:]) // list images without alt text and suggest some.
+	case "quality":
+		report_quality_main(os.Args[3:]) // readability and style metrics of posts.
+	case "duplicates":
+		report_duplicates_main(os.Args[3:]) // list near-duplicate posts.
+	case "translations":
+		report_translations_main(os.Args[3:]) // translation coverage and staleness per language.
+	case "weight":
+		report_weight_main(os.Args[3:]) // page weight of the generated pages.
+	case "review":
+		report_review_main(os.Args[3:]) // list posts awaiting review.
+	case "calendar":
+		report_calendar_main(os.Args[3:]) // scheduled, recently published and review-due posts.
+	default:
+		log.Fatal().Msgf("unknown report %q", os.Args[2])
+	}
+}
+
+// lastTouched returns the most recent of the post's review and update dates.
+func lastTouched(post *types.Post) time.Time {
+	t := post.UpdatedAt
+	if reviewed := post.Main.Metadata.LastReviewed; reviewed.After(t) {
+		t = reviewed
+	}
+	return t
+}
+
+func report_stale_main(args []string) {
+	fs := flag.NewFlagSet("report stale", flag.ExitOnError)
+	months := fs.Int("months", 12, "report posts not reviewed or updated in this many months")
+	fail := fs.Bool("fail", false, "exit with a non-zero status if any post is stale")
+	fs.Parse(args)
+
+	ds, err := initializeDatabase(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+
+	cutoff := time.Now().AddDate(0, -*months, 0)
+	var stale []*types.Post
+	for _, post := range ds.Posts {
+		if post.Main.Metadata.Hidden {
+			continue
+		}
+		if lastTouched(post).Before(cutoff) {
+			stale = append(stale, post)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		return lastTouched(stale[i]).Before(lastTouched(stale[j]))
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LAST TOUCHED\tREVIEWED\tFILE\tTITLE")
+	for _, post := range stale {
+		reviewed := "never"
+		if t := post.Main.Metadata.LastReviewed; !t.IsZero() {
+			reviewed = t.Format(time.DateOnly)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", lastTouched(post).Format(time.DateOnly), reviewed, post.FilePath, post.Main.Metadata.Title)
+	}
+	w.Flush()
+
+	log.Info().Int("stale", len(stale)).Int("months", *months).Msgf("found %d posts not reviewed in %d months", len(stale), *months)
+	if *fail && len(stale) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+)
+
+func generateRobotsTxt(gc *GenerationContext) error {
+	log.Debug().Msg("start generating robots.txt")
+
+	var b bytes.Buffer
+	for i, rule := range gc.Config.Robots.Rules {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for _, ua := range rule.UserAgents {
+			b.WriteString("User-agent: " + ua + "\n")
+		}
+		for _, path := range rule.Allow {
+			b.WriteString("Allow: " + sitePath(path) + "\n")
+		}
+		for _, path := range rule.Disallow {
+			b.WriteString("Disallow: " + sitePath(path) + "\n")
+		}
+	}
+
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	// the sitemap index lists the sitemaps of every language
+	b.WriteString("Sitemap: " + baseURL + "/sitemap_index.xml\n")
+	b.WriteString("Sitemap: " + baseURL + "/feed.rss\n")
+	for _, lang := range types.SupportedLanguages {
+		if lang == types.LangEnglish {
+			continue
+		}
+		b.WriteString("Sitemap: " + baseURL + "/" + lang + "/feed.rss\n")
+	}
+
+	err := os.WriteFile(filepath.Join(distDir, "robots.txt"), b.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msg("done generating robots.txt")
+	return nil
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/html"
+	"gosuda.org/website/internal/htmlcheck"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/lunr"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// searchPath is the site path of the search page.
+const searchPath = "/search/"
+
+// searchFields are the fields of the search indexes, matches in titles
+// scoring above matches in the text.
+var searchFields = []lunr.Field{{Name: "title", Boost: 10}, {Name: "body"}}
+
+// searchDropped are the elements left out of the indexed text of pages, along
+// with those of text-only pages.
+var searchDropped = map[string]bool{"nav": true, "aside": true, "footer": true}
+
+// SearchDocument is an entry of the documents of a search index, which lunr
+// search results refer to by the URL path of the page.
+type SearchDocument struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// searchPage is the indexed content of a rendered page.
+type searchPage struct {
+	Lang types.Lang
+	SearchDocument
+	Body string
+}
+
+// readSearchPage extracts the language, title, description and text of the
+// rendered page at the site path p. The text is that of its article, or of
+// its main element. ok is false for pages kept out of search engines and
+// pages with another canonical URL, such as the text-only variants.
+func readSearchPage(p string, data []byte) (page searchPage, ok bool, err error) {
+	var title, article, main, body strings.Builder
+	var inTitle bool
+	var inArticle, inMain, skip int
+	ok = true
+	_, err = htmlrewrite.Rewrite(data, func(t *htmlrewrite.Token) []byte {
+		switch t.Type {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch {
+			case skip > 0:
+				if !htmlcheck.IsVoid(t.Data) {
+					skip++
+				}
+				return nil
+			case (textDropped[t.Data] || searchDropped[t.Data]) && t.Type == html.StartTagToken:
+				skip++
+				return nil
+			}
+			switch t.Data {
+			case "html":
+				if lang, found := t.Attr("lang"); found {
+					if _, base, err := types.ParseLang(lang); err == nil {
+						page.Lang = base
+					}
+				}
+			case "title":
+				inTitle = true
+			case "meta":
+				name, _ := t.Attr("name")
+				content, _ := t.Attr("content")
+				switch name {
+				case "description":
+					page.Description = content
+				case "robots":
+					ok = ok && !strings.Contains(content, "noindex")
+				}
+			case "link":
+				if rel, _ := t.Attr("rel"); rel == "canonical" {
+					href, _ := t.Attr("href")
+					ok = ok && href == baseURL+pageURL(p)
+				}
+			case "article":
+				inArticle++
+			case "main":
+				inMain++
+			}
+		case html.EndTagToken:
+			switch {
+			case skip > 0:
+				skip--
+				return nil
+			case t.Data == "title":
+				inTitle = false
+			case t.Data == "article":
+				inArticle--
+			case t.Data == "main":
+				inMain--
+			}
+		case html.TextToken:
+			switch {
+			case skip > 0:
+			case inTitle:
+				title.WriteString(t.Data)
+			case inArticle > 0:
+				article.WriteString(t.Data)
+			case inMain > 0:
+				main.WriteString(t.Data)
+			default:
+				body.WriteString(t.Data)
+			}
+			return nil
+		}
+		// words of adjacent blocks are separated
+		for _, b := range []*strings.Builder{&article, &main, &body} {
+			b.WriteByte(' ')
+		}
+		return nil
+	})
+	if err != nil {
+		return page, false, err
+	}
+
+	page.Title = strings.Join(strings.Fields(title.String()), " ")
+	for _, text := range []string{article.String(), main.String(), body.String()} {
+		if page.Body = strings.Join(strings.Fields(text), " "); page.Body != "" {
+			break
+		}
+	}
+	return page, ok, nil
+}
+
+// generateSearchIndexes writes a prebuilt Lunr index of the pages of each
+// language to /search/<lang>/index.json, and the titles, descriptions and tags
+// of its documents, keyed by their URL paths, to /search/<lang>/documents.json.
+func generateSearchIndexes(gc *GenerationContext) error {
+	if !gc.Config.Search.Enabled {
+		return nil
+	}
+	log.Debug().Msg("start generating search indexes")
+
+	paths := make([]string, 0, len(gc.Pages))
+	for p := range gc.Pages {
+		// moved out of dist once written, see extractPreviews
+		if !strings.HasPrefix(p, previewPrefix) {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	// the tags of the pages of posts
+	sources := make(map[string]*types.Post)
+	for _, post := range gc.DataStore.Posts {
+		sources[post.FilePath] = post
+	}
+
+	builders := make(map[types.Lang]*lunr.Builder)
+	documents := make(map[types.Lang]map[string]SearchDocument)
+	for _, p := range paths {
+		data, err := os.ReadFile(pageFile(p))
+		if err != nil {
+			return err
+		}
+		page, ok, err := readSearchPage(p, data)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", p, err)
+		}
+		if !ok || page.Lang == "" {
+			continue
+		}
+		b, ok := builders[page.Lang]
+		if !ok {
+			b = lunr.NewBuilder(page.Lang, searchFields...)
+			builders[page.Lang] = b
+			documents[page.Lang] = make(map[string]SearchDocument)
+		}
+		if post, ok := sources[gc.Pages[p]]; ok && post.Main != nil {
+			page.Tags = post.Main.Metadata.Tags
+		}
+		ref := sitePath(pageURL(p))
+		b.Add(ref, page.Title, page.Body)
+		documents[page.Lang][ref] = page.SearchDocument
+	}
+
+	for lang, b := range builders {
+		err := writeJSON("/search/"+lang+"/index.json", b.Build())
+		if err != nil {
+			return err
+		}
+		err = writeJSON("/search/"+lang+"/documents.json", documents[lang])
+		if err != nil {
+			return err
+		}
+		if !lunr.Stemmed(lang) {
+			log.Debug().Str("lang", lang).Msgf("search index of %s is not stemmed", types.FullLangName(lang))
+		}
+	}
+
+	log.Debug().Int("languages", len(builders)).Msg("done generating search indexes")
+	return nil
+}
+
+// searchScripts returns the scripts of the search page in lang: lunr.js, the
+// lunr-languages stemmer of lang, and search.js.
+func searchScripts(sc *SearchConfig, lang types.Lang) []string {
+	scripts := slices.Clone(sc.Scripts)
+	if lang != types.LangEnglish && lunr.Stemmed(lang) {
+		for _, src := range sc.LanguageScripts {
+			scripts = append(scripts, strings.ReplaceAll(src, "{lang}", lang))
+		}
+	}
+	return append(scripts, "/search.js")
+}
+
+// generateSearchPage writes the search page of lang, which queries the search
+// index of lang written by generateSearchIndexes.
+func generateSearchPage(gc *GenerationContext, lang types.Lang) error {
+	sc := &gc.Config.Search
+	if !sc.Enabled {
+		return nil
+	}
+	log.Debug().Str("lang", lang).Msg("start generating search page")
+
+	languages := make([]view.LanguageLink, 0, len(types.SupportedLanguages))
+	for _, l := range types.SupportedLanguages {
+		languages = append(languages, view.LanguageLink{
+			Lang: l,
+			Name: types.NativeLangName(l),
+			URL:  pageURL(langPath(searchPath, l)),
+		})
+	}
+
+	l := gc.I18n.Localizer(lang)
+	url := baseURL + pageURL(langPath(searchPath, lang))
+	meta := &view.Metadata{
+		Language:    lang,
+		Title:       l.T("GoSuda | Search"),
+		Description: l.T("Search the posts of GoSuda."),
+		Author:      "GoSuda",
+		Image:       baseURL + "/assets/images/ogp_placeholder.png",
+		URL:         url,
+		Canonical:   url,
+		BaseURL:     baseURL,
+		// results pages are kept out of search engines and search indexes
+		Robots:    "noindex",
+		Analytics: viewAnalytics(gc),
+		Feeds:     viewFeeds(gc, lang),
+		Languages: languages,
+		Scripts:   searchScripts(sc, lang),
+	}
+	search := &view.Search{
+		Index:     sitePath("/search/" + lang + "/index.json"),
+		Documents: sitePath("/search/" + lang + "/documents.json"),
+	}
+
+	var b bytes.Buffer
+	err := view.SearchPage(meta, search).Render(renderContext(gc, lang), &b)
+	if err != nil {
+		return err
+	}
+	paths := []string{"/" + lang + searchPath}
+	if lang == types.LangEnglish {
+		paths = append(paths, searchPath)
+	}
+	for _, p := range paths {
+		err = writePage(gc, p, "the search page", b.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	log.Debug().Str("lang", lang).Msg("done generating search page")
+	return nil
+}
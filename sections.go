@@ -0,0 +1,192 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"gosuda.org/website/internal/frontmatter"
+	"gosuda.org/website/internal/types"
+)
+
+// sectionFile is the file whose front matter sets the defaults of the
+// documents in its directory and the directories below it.
+const sectionFile = "_index.md"
+
+// section holds the front matter defaults of a directory. Defaults of nested
+// sections override those of their parents, except tags, which add up; a
+// nested section can set hidden: false to show what a parent hid.
+type section struct {
+	Kind types.Kind `yaml:"kind"`
+	// Layout is the layout of the documents, which is given by their kind
+	// (post, page, doc, talk or link); kind wins if both are set.
+	Layout      types.Kind `yaml:"layout"`
+	Author      string     `yaml:"author"`
+	Language    string     `yaml:"language"`
+	Tags        []string   `yaml:"tags"`
+	Series      string     `yaml:"series"`
+	Hidden      *bool      `yaml:"hidden"`
+	NoTranslate *bool      `yaml:"no_translate"`
+	// Permalink is the path pattern of documents without a path, with the
+	// placeholders :year, :month, :day, :section, :slug (the file name) and
+	// :title. (e.g. "/blog/:year/:slug")
+	Permalink string `yaml:"permalink"`
+}
+
+// isSectionFile reports whether path is a section defaults file, which is
+// not a document itself.
+func isSectionFile(path string) bool {
+	return filepath.Base(path) == sectionFile
+}
+
+// loadSections reads the section files of list, keyed by their directory.
+func loadSections(list []string) (map[string]*section, error) {
+	sections := make(map[string]*section)
+	for _, path := range list {
+		if !isSectionFile(path) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		front, _, err := frontmatter.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		yamlData, err := yaml.Marshal(front)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		var s section
+		err = yaml.Unmarshal(yamlData, &s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		sections[filepath.Dir(path)] = &s
+		log.Debug().Str("path", path).Msgf("loaded section defaults %s", path)
+	}
+	return sections, nil
+}
+
+// sectionOf returns the defaults of the document at path, merged from the
+// sections of its directory and all directories above it.
+func sectionOf(gc *GenerationContext, path string) section {
+	var dirs []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == rootDir || dir == "." || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	var merged section
+	for _, dir := range slices.Backward(dirs) {
+		s, ok := gc.Sections[dir]
+		if !ok {
+			continue
+		}
+		merged.Kind = cmp.Or(s.Kind, s.Layout, merged.Kind)
+		merged.Author = cmp.Or(s.Author, merged.Author)
+		merged.Language = cmp.Or(s.Language, merged.Language)
+		merged.Series = cmp.Or(s.Series, merged.Series)
+		merged.Permalink = cmp.Or(s.Permalink, merged.Permalink)
+		if s.Hidden != nil {
+			merged.Hidden = s.Hidden
+		}
+		if s.NoTranslate != nil {
+			merged.NoTranslate = s.NoTranslate
+		}
+		for _, tag := range s.Tags {
+			if !slices.Contains(merged.Tags, tag) {
+				merged.Tags = append(merged.Tags, tag)
+			}
+		}
+	}
+	return merged
+}
+
+// sectionFields records the metadata fields a section filled in, so that they
+// are not written back to the front matter of the document.
+type sectionFields struct {
+	kind, author, language, series, hidden, noTranslate bool
+	// ownTags are the tags of the document itself.
+	ownTags []string
+}
+
+// apply fills the fields of meta that the document leaves empty with the
+// defaults of s, and adds the section tags.
+func (s *section) apply(meta *types.Metadata) sectionFields {
+	f := sectionFields{ownTags: meta.Tags}
+	if meta.Kind == "" && s.Kind != "" {
+		meta.Kind, f.kind = s.Kind, true
+	}
+	if meta.Author == "" && s.Author != "" {
+		meta.Author, f.author = s.Author, true
+	}
+	if meta.Language == "" && s.Language != "" {
+		meta.Language, f.language = s.Language, true
+	}
+	if meta.Series == "" && s.Series != "" {
+		meta.Series, f.series = s.Series, true
+	}
+	if !meta.Hidden && s.Hidden != nil && *s.Hidden {
+		meta.Hidden, f.hidden = true, true
+	}
+	if !meta.NoTranslate && s.NoTranslate != nil && *s.NoTranslate {
+		meta.NoTranslate, f.noTranslate = true, true
+	}
+	for _, tag := range s.Tags {
+		if !slices.Contains(meta.Tags, tag) {
+			meta.Tags = append(slices.Clip(meta.Tags), tag)
+		}
+	}
+	return f
+}
+
+// strip returns meta without the fields filled in by the section.
+func (f *sectionFields) strip(meta types.Metadata) types.Metadata {
+	if f.kind {
+		meta.Kind = ""
+	}
+	if f.author {
+		meta.Author = ""
+	}
+	if f.language {
+		meta.Language = ""
+	}
+	if f.series {
+		meta.Series = ""
+	}
+	if f.hidden {
+		meta.Hidden = false
+	}
+	if f.noTranslate {
+		meta.NoTranslate = false
+	}
+	meta.Tags = f.ownTags
+	return meta
+}
+
+// expandPermalink returns the path of the document at path from the
+// permalink pattern of its section.
+func expandPermalink(pattern, path string, meta *types.Metadata) string {
+	sec, _ := filepath.Rel(rootDir, filepath.Dir(path))
+	if sec == "." {
+		sec = ""
+	}
+	r := strings.NewReplacer(
+		":year", meta.Date.Format("2006"),
+		":month", meta.Date.Format("01"),
+		":day", meta.Date.Format("02"),
+		":section", filepath.ToSlash(sec),
+		":slug", importSlug(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))),
+		":title", importSlug(meta.Title),
+	)
+	p := "/" + strings.Trim(r.Replace(pattern), "/")
+	return strings.ReplaceAll(p, "//", "/")
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// distHandler serves the generated site like the static hosts do: post pages are
+// written as <path>.html and served without the extension.
+func distHandler() http.Handler {
+	files := http.FileServer(http.Dir(distDir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := path.Clean("/" + r.URL.Path)
+		if !strings.HasSuffix(r.URL.Path, "/") && path.Ext(p) == "" {
+			fp := filepath.Join(distDir, filepath.FromSlash(p))
+			if _, err := os.Stat(fp); os.IsNotExist(err) {
+				if _, err := os.Stat(fp + ".html"); err == nil {
+					r.URL.Path = p + ".html"
+				}
+			}
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+func serve_main() {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	fs.Parse(os.Args[2:])
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+
+	ds, err := initializeDatabaseLazy(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", newContentGraph(ds))
+	mux.Handle(pathPrefix+"/", http.StripPrefix(pathPrefix, distHandler()))
+	if cfg.Preview.Secret != "" {
+		mux.Handle(pathPrefix+previewPrefix, previewHandler())
+		logPreviewLinks(cfg, ds, "http://"+*addr+pathPrefix)
+	}
+
+	log.Info().Str("addr", *addr).Msgf("serving %s on http://%s%s/ (GraphQL at /graphql)", distDir, *addr, pathPrefix)
+	err = http.ListenAndServe(*addr, mux)
+	if err != nil {
+		log.Fatal().Err(err).Msg("server stopped")
+	}
+}
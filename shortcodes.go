@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/markdown"
+	"gosuda.org/website/internal/types"
+)
+
+// shortcodeFunc renders a shortcode of a post to HTML.
+// index is the position of the shortcode in the page, for generating unique IDs.
+type shortcodeFunc func(gc *GenerationContext, post *types.Post, args string, index int) (string, error)
+
+var shortcodes = map[string]shortcodeFunc{
+	"gallery": galleryShortcode,
+}
+
+// expandShortcodes replaces the shortcode placeholders of a rendered document.
+func expandShortcodes(gc *GenerationContext, post *types.Post, doc string) (string, error) {
+	var index int
+	var expandErr error
+	out, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		if t.Type != htmlrewrite.CommentToken || expandErr != nil {
+			return nil
+		}
+		name, args, ok := markdown.ParseShortcodeComment(t.Data)
+		if !ok {
+			return nil
+		}
+
+		fn, ok := shortcodes[name]
+		if !ok {
+			expandErr = fmt.Errorf("%s: unknown shortcode %q", post.FilePath, name)
+			return nil
+		}
+		index++
+		html, err := fn(gc, post, args, index)
+		if err != nil {
+			expandErr = fmt.Errorf("%s: shortcode %s: %w", post.FilePath, name, err)
+			return nil
+		}
+		return []byte(html)
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(out), expandErr
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/zeebo/blake3"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// shortURLPrefix is the site path under which short URLs redirect to posts.
+const shortURLPrefix = "/s/"
+
+// shortCode returns the base36 code of post id, from the first n bytes of its
+// hash.
+func shortCode(id string, n int) string {
+	sum := blake3.Sum256([]byte(id))
+	var buf [8]byte
+	copy(buf[8-n:], sum[:n])
+	return strconv.FormatUint(binary.BigEndian.Uint64(buf[:]), 36)
+}
+
+// shortCodes returns the short codes of posts, keyed by post ID.
+func shortCodes(ds *DataStore) map[string]string {
+	codes := make(map[string]string, len(ds.ShortURLs))
+	for code, id := range ds.ShortURLs {
+		codes[id] = code
+	}
+	return codes
+}
+
+// assignShortURLs gives every published post without a short code a new one,
+// lengthening codes that collide. Codes are kept in the DataStore so that they
+// never change once shared, and the posts that got one are returned.
+func assignShortURLs(gc *GenerationContext) []*types.Post {
+	ds := gc.DataStore
+	if ds.ShortURLs == nil {
+		ds.ShortURLs = make(map[string]string)
+	}
+	codes := shortCodes(ds)
+
+	var assigned []*types.Post
+	for _, post := range publishedPosts(gc) {
+		if _, ok := codes[post.ID]; ok {
+			continue
+		}
+		for n := 4; n <= 8; n++ {
+			code := shortCode(post.ID, n)
+			if _, taken := ds.ShortURLs[code]; !taken {
+				ds.ShortURLs[code] = post.ID
+				codes[post.ID] = code
+				assigned = append(assigned, post)
+				break
+			}
+		}
+	}
+	return assigned
+}
+
+// shortURLRedirects returns the redirects of the short URLs of published posts.
+func shortURLRedirects(gc *GenerationContext) []Redirect {
+	var redirects []Redirect
+	codes := shortCodes(gc.DataStore)
+	for _, post := range publishedPosts(gc) {
+		if code, ok := codes[post.ID]; ok {
+			redirects = append(redirects, Redirect{From: shortURLPrefix + code, To: pageURL(post.Path)})
+		}
+	}
+	sort.Slice(redirects, func(i, j int) bool {
+		return redirects[i].From < redirects[j].From
+	})
+	return redirects
+}
+
+// generateShortURLs assigns short URLs to new posts and writes a redirect
+// stub for each, next to the entries added to _redirects.
+func generateShortURLs(gc *GenerationContext) error {
+	log.Debug().Msg("start generating short URLs")
+	assigned := assignShortURLs(gc)
+	codes := shortCodes(gc.DataStore)
+	for _, post := range assigned {
+		code := codes[post.ID]
+		log.Info().Str("path", post.Path).Str("url", baseURL+shortURLPrefix+code).Msgf("assigned short URL %s%s%s", baseURL, shortURLPrefix, code)
+	}
+
+	redirects := shortURLRedirects(gc)
+	var b bytes.Buffer
+	for _, r := range redirects {
+		b.Reset()
+		err := view.RedirectPage(baseURL+r.To).Render(context.Background(), &b)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(filepath.Dir(pageFile(r.From)), 0755)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(pageFile(r.From), b.Bytes(), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	log.Debug().Int("short_urls", len(redirects)).Msg("done generating short URLs")
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/types"
+)
+
+// sidenoteBlocks are the elements that keep a footnote out of the margin, as
+// a sidenote is a line of phrasing content in the paragraph citing it.
+var sidenoteBlocks = map[string]bool{
+	"pre": true, "ul": true, "ol": true, "table": true, "blockquote": true,
+	"div": true, "figure": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "hr": true,
+}
+
+// footnoteNotes returns the content of the footnotes of doc that fit in a
+// sidenote, keyed by footnote number, without their back links, and the
+// number of footnotes. Paragraphs are separated by line breaks.
+func footnoteNotes(doc []byte) (notes map[string][]byte, footnotes int, err error) {
+	notes = make(map[string][]byte)
+	var id string
+	var note bytes.Buffer
+	var depth, backref, paragraphs int
+	var fits bool
+	err = htmlrewrite.Walk(doc, func(t *htmlrewrite.Token) {
+		if id == "" {
+			if t.IsTag("li") {
+				if v, _ := t.Attr("id"); strings.HasPrefix(v, "fn:") {
+					id, depth, paragraphs, fits = strings.TrimPrefix(v, "fn:"), 1, 0, true
+					note.Reset()
+					footnotes++
+				}
+			}
+			return
+		}
+
+		switch t.Type {
+		case htmlrewrite.StartTagToken:
+			switch {
+			case t.Data == "li":
+				depth++
+			case t.Data == "p":
+				if paragraphs++; paragraphs > 1 {
+					note.WriteString("<br>")
+				}
+				return
+			case t.Data == "a" && backref > 0:
+				backref++
+			case t.Data == "a":
+				if class, _ := t.Attr("class"); class == "footnote-backref" {
+					backref = 1
+				}
+			case sidenoteBlocks[t.Data]:
+				fits = false
+			}
+		case htmlrewrite.EndTagToken:
+			switch t.Data {
+			case "li":
+				if depth--; depth == 0 {
+					if fits {
+						notes[id] = bytes.Clone(trimNote(note.Bytes()))
+					}
+					id = ""
+					return
+				}
+			case "p":
+				return
+			case "a":
+				if backref > 0 {
+					backref--
+					return
+				}
+			}
+		case htmlrewrite.SelfClosingTagToken:
+			if sidenoteBlocks[t.Data] {
+				fits = false
+			}
+		}
+		if backref == 0 {
+			note.Write(t.Raw)
+		}
+	})
+	return notes, footnotes, err
+}
+
+// trimNote trims the spaces around a note and the no-break spaces that were
+// before its back links.
+func trimNote(note []byte) []byte {
+	for {
+		trimmed := bytes.TrimSuffix(bytes.TrimSpace(note), []byte("&#160;"))
+		if len(trimmed) == len(note) {
+			return note
+		}
+		note = trimmed
+	}
+}
+
+// addSidenotes repeats the footnotes of posts with sidenotes set beside their
+// first references, as sidenotes the stylesheet shows in the margin of wide
+// screens instead of the footnotes at the end of the post. The footnotes
+// repeated are marked sidenoted, and so is the list of footnotes if all are;
+// the others, such as footnotes with code blocks, stay at the end.
+func addSidenotes(gc *GenerationContext, post *types.Post, doc string) (string, error) {
+	if post.Main == nil || !post.Main.Metadata.Sidenotes || !strings.Contains(doc, `class="footnotes"`) {
+		return doc, nil
+	}
+	notes, footnotes, err := footnoteNotes([]byte(doc))
+	if err != nil {
+		return "", err
+	}
+
+	// the footnote of the <sup> being read, if it is the first reference
+	var ref string
+	out, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		switch {
+		case t.IsTag("div"):
+			if class, _ := t.Attr("class"); class == "footnotes" && len(notes) == footnotes {
+				t.SetAttr("class", "footnotes sidenoted")
+				return t.Render()
+			}
+		case t.IsTag("li"):
+			id, _ := t.Attr("id")
+			if _, ok := notes[strings.TrimPrefix(id, "fn:")]; ok && strings.HasPrefix(id, "fn:") {
+				t.SetAttr("class", "sidenoted")
+				return t.Render()
+			}
+		case t.IsTag("sup"):
+			id, _ := t.Attr("id")
+			ref, _ = strings.CutPrefix(id, "fnref:")
+		case t.Type == htmlrewrite.EndTagToken && t.Data == "sup" && ref != "":
+			note, ok := notes[ref]
+			n := ref
+			ref = ""
+			if ok {
+				var b bytes.Buffer
+				b.Write(t.Raw)
+				b.WriteString(`<span class="sidenote" role="note"><span class="sidenote-number">` + n + `</span> `)
+				b.Write(note)
+				b.WriteString(`</span>`)
+				return b.Bytes()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
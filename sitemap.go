@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+
+// Limits of a single sitemap file of the sitemaps protocol.
+const (
+	sitemapMaxURLs  = 50000
+	sitemapMaxBytes = 50 << 20
+)
+
+func encodeSiteMapXML(urls []view.SitemapURL) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	err := view.Sitemap(urls).Render(context.Background(), &b)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func encodeSitemapIndexXML(sitemaps []view.SitemapURL) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	err := view.SitemapIndex(sitemaps).Render(context.Background(), &b)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// sitemapChunk is an encoded sitemap of a part of the URLs of a language.
+type sitemapChunk struct {
+	urls []view.SitemapURL
+	data []byte
+}
+
+// chunkSitemap encodes urls into sitemaps within the protocol limits. No urls,
+// e.g. of a new language without posts, are encoded into one empty sitemap.
+func chunkSitemap(urls []view.SitemapURL) ([]sitemapChunk, error) {
+	if len(urls) == 0 {
+		data, err := encodeSiteMapXML(nil)
+		if err != nil {
+			return nil, err
+		}
+		return []sitemapChunk{{data: data}}, nil
+	}
+
+	var chunks []sitemapChunk
+	for len(urls) > 0 {
+		n := min(len(urls), sitemapMaxURLs)
+		for {
+			data, err := encodeSiteMapXML(urls[:n])
+			if err != nil {
+				return nil, err
+			}
+			if len(data) <= sitemapMaxBytes || n == 1 {
+				chunks = append(chunks, sitemapChunk{urls: urls[:n], data: data})
+				break
+			}
+			n /= 2
+		}
+		urls = urls[n:]
+	}
+	return chunks, nil
+}
+
+// lastModified returns the latest modification time of urls.
+func lastModified(urls []view.SitemapURL) time.Time {
+	var t time.Time
+	for _, u := range urls {
+		if u.LastMod.After(t) {
+			t = u.LastMod
+		}
+	}
+	return t
+}
+
+// gzipFile writes data gzip-compressed to fp.
+func gzipFile(fp string, data []byte) error {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	_, err := zw.Write(data)
+	if err != nil {
+		return err
+	}
+	err = zw.Close()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fp, b.Bytes(), 0644)
+}
+
+// generateSitemaps writes the sitemaps of the languages collected from the
+// feeds. Every language is split into gzip-compressed sitemaps within the
+// protocol limits under /sitemaps/, all listed by /sitemap_index.xml. The
+// per-language /<lang>/sitemap.xml is kept as a plain sitemap while it fits
+// in one file, and becomes an index of the chunks of its language otherwise.
+func generateSitemaps(gc *GenerationContext) error {
+	log.Debug().Msg("start generating sitemaps")
+	err := os.MkdirAll(filepath.Join(distDir, "sitemaps"), 0755)
+	if err != nil {
+		return err
+	}
+
+	var index []view.SitemapURL
+	for _, lang := range types.SupportedLanguages {
+		urls, ok := gc.Sitemaps[lang]
+		if !ok {
+			continue
+		}
+		chunks, err := chunkSitemap(urls)
+		if err != nil {
+			return err
+		}
+
+		var langIndex []view.SitemapURL
+		for i, chunk := range chunks {
+			if len(chunk.urls) == 0 {
+				// an empty sitemap has no modification time to list
+				continue
+			}
+			name := fmt.Sprintf("/sitemaps/%s-%d.xml.gz", lang, i+1)
+			err = gzipFile(filepath.Join(distDir, filepath.FromSlash(name)), chunk.data)
+			if err != nil {
+				return err
+			}
+			langIndex = append(langIndex, view.SitemapURL{Loc: baseURL + name, LastMod: lastModified(chunk.urls)})
+		}
+		index = append(index, langIndex...)
+
+		sitemap := chunks[0].data
+		if len(chunks) > 1 {
+			log.Info().Str("lang", lang).Int("urls", len(urls)).Int("sitemaps", len(chunks)).Msgf("split the %s sitemap into %d sitemaps", lang, len(chunks))
+			sitemap, err = encodeSitemapIndexXML(langIndex)
+			if err != nil {
+				return err
+			}
+		}
+		fp := filepath.Join(distDir, lang, "sitemap.xml")
+		if lang == types.LangEnglish {
+			fp = filepath.Join(distDir, "sitemap.xml")
+		}
+		err = os.WriteFile(fp, sitemap, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := encodeSitemapIndexXML(index)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(distDir, "sitemap_index.xml"), data, 0644)
+	if err != nil {
+		return err
+	}
+	log.Debug().Int("sitemaps", len(index)).Msg("done generating sitemaps")
+	return nil
+}
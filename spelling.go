@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+	"gosuda.org/website/internal/frontmatter"
+	"gosuda.org/website/internal/spell"
+)
+
+func check_main() {
+	if len(os.Args) < 3 {
+		log.Fatal().Msg("usage: check <spelling> ...")
+	}
+
+	switch os.Args[2] {
+	case "spelling":
+		check_spelling_main(os.Args[3:]) // spellcheck markdown sources against hunspell dictionaries.
+	default:
+		log.Fatal().Msgf("unknown check %q", os.Args[2])
+	}
+}
+
+// misspelling is a word not found in the dictionary of its script.
+type misspelling struct {
+	line, col int
+	word      string
+}
+
+// spellScripts maps dictionary languages to the script of the words they check.
+// Words are checked by script rather than by the language of the post, so that
+// English terms in Korean posts are checked against the English dictionary.
+var spellScripts = map[string]*unicode.RangeTable{
+	"en": unicode.Latin,
+	"ko": unicode.Hangul,
+}
+
+var spellMarkdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// proseSegments returns the byte ranges of the prose of a markdown document,
+// excluding front matter, code, raw HTML, URLs and shortcodes.
+func proseSegments(src []byte) [][2]int {
+	_, body, err := frontmatter.Parse(src)
+	if err != nil {
+		body = src
+	}
+	offset := len(src) - len(body)
+	// shortcodes are blanked so that their arguments are not read as text
+	body = shortcodeLine.ReplaceAllFunc(body, func(b []byte) []byte {
+		return bytes.Repeat([]byte(" "), len(b))
+	})
+
+	var segments [][2]int
+	doc := spellMarkdown.Parser().Parse(text.NewReader(body))
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.CodeSpan, *ast.CodeBlock, *ast.FencedCodeBlock, *ast.HTMLBlock, *ast.RawHTML, *ast.AutoLink:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			segments = append(segments, [2]int{offset + n.Segment.Start, offset + n.Segment.Stop})
+		}
+		return ast.WalkContinue, nil
+	})
+	return segments
+}
+
+// spellWords calls fn with the words of s to check and their byte offsets.
+// Tokens that look like code, paths or URLs, acronyms and camelCase names are
+// skipped. In tokens mixing scripts, such as a Latin word followed by a Korean
+// particle, only the Latin part is checked.
+func spellWords(s string, fn func(word string, offset int)) {
+	i := 0
+	for _, token := range strings.Fields(s) {
+		start := strings.Index(s[i:], token) + i
+		i = start + len(token)
+
+		trimmed := strings.TrimFunc(token, func(r rune) bool { return !unicode.IsLetter(r) })
+		if trimmed == "" || strings.ContainsAny(trimmed, "_/\\@.:=<>{}()[]#$%&*+|~`0123456789") {
+			continue
+		}
+		start += strings.Index(token, trimmed)
+
+		mixed := strings.IndexFunc(trimmed, func(r rune) bool { return unicode.Is(unicode.Latin, r) }) >= 0 &&
+			strings.IndexFunc(trimmed, func(r rune) bool { return unicode.Is(unicode.Hangul, r) }) >= 0
+		for _, part := range strings.FieldsFunc(trimmed, func(r rune) bool { return r == '-' || r == '–' || r == '—' }) {
+			off := start + strings.Index(trimmed, part)
+			if mixed {
+				end := strings.IndexFunc(part, func(r rune) bool { return !unicode.Is(unicode.Latin, r) && r != '\'' && r != '’' })
+				if end == 0 {
+					continue
+				}
+				if end > 0 {
+					part = part[:end]
+				}
+			}
+			part = strings.Trim(strings.ReplaceAll(part, "’", "'"), "'")
+			if utf8.RuneCountInString(part) < 2 || isIdentifierCase(part) {
+				continue
+			}
+			fn(part, off)
+		}
+	}
+}
+
+// isIdentifierCase reports whether word is an acronym or a camelCase name.
+func isIdentifierCase(word string) bool {
+	upper := 0
+	for i, r := range word {
+		if unicode.IsUpper(r) {
+			upper++
+			if i > 0 && upper == 1 {
+				return true
+			}
+		}
+	}
+	return upper > 1
+}
+
+// checkSpelling returns the misspelled words of the markdown file src.
+func checkSpelling(src []byte, dicts map[string]*spell.Dictionary) []misspelling {
+	var found []misspelling
+	for _, seg := range proseSegments(src) {
+		spellWords(string(src[seg[0]:seg[1]]), func(word string, offset int) {
+			r, _ := utf8.DecodeRuneInString(word)
+			for lang, script := range spellScripts {
+				if !unicode.Is(script, r) {
+					continue
+				}
+				if d := dicts[lang]; d != nil && !d.Check(word) {
+					pos := seg[0] + offset
+					lineStart := bytes.LastIndexByte(src[:pos], '\n') + 1
+					found = append(found, misspelling{
+						line: bytes.Count(src[:pos], []byte("\n")) + 1,
+						col:  utf8.RuneCount(src[lineStart:pos]) + 1,
+						word: word,
+					})
+				}
+			}
+		})
+	}
+	return found
+}
+
+// readAllowlist returns the words of an allowlist file, one per line, with #
+// comments. A missing file is an empty allowlist.
+func readAllowlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var words []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		if w := strings.TrimSpace(line); w != "" {
+			words = append(words, w)
+		}
+	}
+	return words, sc.Err()
+}
+
+func check_spelling_main(args []string) {
+	fs := flag.NewFlagSet("check spelling", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: check spelling [flags] [file ...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+	sc := &cfg.Spelling
+	allow, err := readAllowlist(sc.Allowlist)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to read allowlist %s", sc.Allowlist)
+	}
+
+	dicts := make(map[string]*spell.Dictionary)
+	for lang, path := range sc.Dictionaries {
+		if spellScripts[lang] == nil {
+			log.Warn().Msgf("no script is known for the %s dictionary, skipping it", lang)
+			continue
+		}
+		d, err := spell.Open(path)
+		if os.IsNotExist(err) {
+			log.Warn().Str("path", path).Msgf("%s dictionary not found, skipping %s words (install %s.aff and %s.dic)", lang, lang, path, path)
+			continue
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to load %s dictionary", lang)
+		}
+		d.Add(allow...)
+		dicts[lang] = d
+	}
+	if len(dicts) == 0 {
+		log.Fatal().Msg("no spelling dictionary could be loaded (spelling.dictionaries)")
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		list, err := generateFileList(rootDir)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to list %s", rootDir)
+		}
+		for _, path := range list {
+			if filepath.Ext(path) == ".md" {
+				files = append(files, path)
+			}
+		}
+	}
+
+	var total int
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to read %s", path)
+		}
+		src = bytes.ReplaceAll(src, []byte("\r\n"), []byte("\n"))
+		for _, m := range checkSpelling(src, dicts) {
+			fmt.Printf("%s:%d:%d: %s\n", path, m.line, m.col, m.word)
+			total++
+		}
+	}
+
+	log.Info().Int("files", len(files)).Int("misspellings", total).Msgf("found %d misspelled words in %d files", total, len(files))
+	if total > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/htmlrewrite"
+)
+
+func sriHash(data []byte) string {
+	sum := sha512.Sum384(data)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+type sriResolver struct {
+	gc     *GenerationContext
+	local  map[string]string
+	client *http.Client
+}
+
+// integrity returns the SRI hash for ref, or "" if ref is neither a local asset nor a pinned CDN asset.
+func (r *sriResolver) integrity(ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", nil
+	}
+
+	if u.Host == "" || u.Scheme+"://"+u.Host+pathPrefix == baseURL {
+		// local references carry the path prefix, see applyPathPrefix
+		if !strings.HasPrefix(u.Path, pathPrefix+"/") {
+			return "", nil
+		}
+		p := strings.TrimPrefix(u.Path, pathPrefix)
+		if h, ok := r.local[p]; ok {
+			return h, nil
+		}
+		data, err := os.ReadFile(filepath.Join(distDir, filepath.FromSlash(p)))
+		if err != nil {
+			return "", err
+		}
+		h := sriHash(data)
+		r.local[p] = h
+		return h, nil
+	}
+
+	var pinned bool
+	for _, prefix := range r.gc.Config.SRI.Pinned {
+		if strings.HasPrefix(ref, prefix) {
+			pinned = true
+			break
+		}
+	}
+	if !pinned {
+		return "", nil
+	}
+
+	if h, ok := r.gc.DataStore.Integrity[ref]; ok {
+		return h, nil
+	}
+
+	log.Debug().Str("url", ref).Msgf("fetching pinned asset %s", ref)
+	resp, err := r.client.Get(ref)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching %s: unexpected status %s", ref, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	h := sriHash(data)
+	if r.gc.DataStore.Integrity == nil {
+		r.gc.DataStore.Integrity = make(map[string]string)
+	}
+	r.gc.DataStore.Integrity[ref] = h
+	return h, nil
+}
+
+// applySRI adds integrity and crossorigin attributes to scripts and stylesheets
+// that reference local files or pinned CDN assets.
+func applySRI(gc *GenerationContext) error {
+	if !gc.Config.SRI.Enabled {
+		return nil
+	}
+	log.Debug().Msg("start adding subresource integrity attributes")
+
+	list, err := generateFileList(distDir)
+	if err != nil {
+		return err
+	}
+
+	r := &sriResolver{
+		gc:     gc,
+		local:  make(map[string]string),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	for _, path := range list {
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".html" && ext != ".htm" {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var rerr error
+		data, err = htmlrewrite.Rewrite(data, func(t *htmlrewrite.Token) []byte {
+			var ref string
+			switch {
+			case t.IsTag("script"):
+				ref, _ = t.Attr("src")
+			case t.IsTag("link"):
+				rel, _ := t.Attr("rel")
+				if rel != "stylesheet" && rel != "preload" && rel != "modulepreload" {
+					return nil
+				}
+				if as, _ := t.Attr("as"); rel == "preload" && as != "script" && as != "style" {
+					return nil
+				}
+				ref, _ = t.Attr("href")
+			default:
+				return nil
+			}
+			if ref == "" {
+				return nil
+			}
+			if _, ok := t.Attr("integrity"); ok {
+				return nil
+			}
+
+			h, err := r.integrity(ref)
+			if err != nil {
+				log.Error().Err(err).Str("path", path).Str("ref", ref).Msgf("failed to compute integrity of %s", ref)
+				rerr = err
+				return nil
+			}
+			if h == "" {
+				return nil
+			}
+
+			t.SetAttr("integrity", h)
+			if _, ok := t.Attr("crossorigin"); !ok {
+				t.SetAttr("crossorigin", "anonymous")
+			}
+			return t.Render()
+		})
+		if err != nil {
+			return err
+		}
+		if rerr != nil {
+			return rerr
+		}
+
+		err = os.WriteFile(path, data, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Debug().Msg("done adding subresource integrity attributes")
+	return nil
+}
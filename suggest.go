@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"gosuda.org/website/internal/tagging"
+)
+
+func suggest_main() {
+	if len(os.Args) < 3 {
+		log.Fatal().Msg("usage: suggest <tags> ...")
+	}
+
+	switch os.Args[2] {
+	case "tags":
+		suggest_tags_main(os.Args[3:]) // propose tags for untagged posts as a patch.
+	default:
+		log.Fatal().Msgf("unknown suggestion %q", os.Args[2])
+	}
+}
+
+// tagVocabulary returns the tags used by posts, the most used first.
+func tagVocabulary(gc *GenerationContext) []tagging.Tag {
+	counts := make(map[string]int)
+	for _, post := range publishedPosts(gc) {
+		if post.Main == nil {
+			continue
+		}
+		for _, tag := range post.Main.Metadata.Tags {
+			counts[tag]++
+		}
+	}
+	vocab := make([]tagging.Tag, 0, len(counts))
+	for name, count := range counts {
+		vocab = append(vocab, tagging.Tag{Name: name, Count: count})
+	}
+	sort.Slice(vocab, func(i, j int) bool {
+		if vocab[i].Count != vocab[j].Count {
+			return vocab[i].Count > vocab[j].Count
+		}
+		return vocab[i].Name < vocab[j].Name
+	})
+	return vocab
+}
+
+// unifiedDiff returns a single hunk unified diff between the lines a and b of
+// the file name, or "" if they are equal.
+func unifiedDiff(name string, a, b []string) string {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	if prefix == len(a) && prefix == len(b) {
+		return ""
+	}
+
+	const contextLines = 3
+	start := max(prefix-contextLines, 0)
+	endA := min(len(a)-suffix+contextLines, len(a))
+	endB := min(len(b)-suffix+contextLines, len(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", name, name)
+	fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", start+1, endA-start, start+1, endB-start)
+	for _, line := range a[start:prefix] {
+		sb.WriteString(" " + line + "\n")
+	}
+	for _, line := range a[prefix : len(a)-suffix] {
+		sb.WriteString("-" + line + "\n")
+	}
+	for _, line := range b[prefix : len(b)-suffix] {
+		sb.WriteString("+" + line + "\n")
+	}
+	for _, line := range a[len(a)-suffix : endA] {
+		sb.WriteString(" " + line + "\n")
+	}
+	return sb.String()
+}
+
+// fileLines splits data into lines without their line endings.
+func fileLines(data string) []string {
+	var lines []string
+	sc := bufio.NewScanner(strings.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	return lines
+}
+
+// tagsPatch returns a patch adding tags to the front matter of the markdown file fp.
+func tagsPatch(fp string, tags []string) (string, error) {
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		return "", err
+	}
+	src := string(data)
+	if !strings.HasPrefix(src, "---\n") {
+		return "", ErrInvalidMarkdown
+	}
+	_, body, ok := strings.Cut(strings.TrimPrefix(src, "---\n"), "---\n")
+	if !ok {
+		return "", ErrInvalidMarkdown
+	}
+	meta := readMetadata(fp)
+	if meta == nil {
+		return "", ErrInvalidMarkdown
+	}
+	meta.Tags = tags
+	newFront, err := yaml.Marshal(meta)
+	if err != nil {
+		return "", err
+	}
+
+	name := filepath.ToSlash(filepath.Clean(fp))
+	return unifiedDiff(name, fileLines(src), fileLines("---\n"+string(newFront)+"---\n"+body)), nil
+}
+
+func suggest_tags_main(args []string) {
+	fs := flag.NewFlagSet("suggest tags", flag.ExitOnError)
+	n := fs.Int("n", 4, "the maximum number of tags per post")
+	out := fs.String("o", "", "write the patch to this file instead of stdout")
+	fs.Parse(args)
+	if llmModel == nil {
+		log.Fatal().Msg("suggest tags needs the LLM client, which is disabled by LLM_INIT")
+	}
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+	ds, err := initializeDatabaseContent(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+	gc := &GenerationContext{Config: cfg, DataStore: ds}
+	vocab := tagVocabulary(gc)
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to create %s", *out)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var suggested int
+	for _, post := range publishedPosts(gc) {
+		if post.Main == nil || post.FilePath == "" || len(post.Main.Metadata.Tags) > 0 {
+			continue
+		}
+		log.Debug().Str("path", post.FilePath).Msgf("start suggesting tags for %s", post.FilePath)
+		tags, err := tagging.SuggestTags(context.Background(), llmModel, post.Main.Markdown, vocab, *n)
+		if err != nil {
+			log.Error().Err(err).Str("path", post.FilePath).Msgf("failed to suggest tags for %s", post.FilePath)
+			continue
+		}
+		patch, err := tagsPatch(post.FilePath, tags)
+		if err != nil {
+			log.Error().Err(err).Str("path", post.FilePath).Msgf("failed to read %s", post.FilePath)
+			continue
+		}
+		log.Info().Str("path", post.FilePath).Strs("tags", tags).Msgf("suggested tags for %q", post.Main.Metadata.Title)
+		io.WriteString(w, patch)
+		suggested++
+	}
+	log.Info().Int("posts", suggested).Msgf("suggested tags for %d posts, review and apply the patch with git apply", suggested)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"gosuda.org/website/internal/frontmatter"
+	"gosuda.org/website/internal/notion"
+	"gosuda.org/website/internal/types"
+)
+
+func sync_main() {
+	if len(os.Args) < 3 {
+		log.Fatal().Msg("usage: sync <notion|labels> ...")
+	}
+
+	switch os.Args[2] {
+	case "notion":
+		sync_notion_main(os.Args[3:]) // pull the pages of the configured Notion database.
+	case "labels":
+		sync_labels_main(os.Args[3:]) // label a pull request with the states of the posts it changes.
+	default:
+		log.Fatal().Msgf("unknown sync source %q", os.Args[2])
+	}
+}
+
+// NotionEntry records a Notion page synced to a post.
+type NotionEntry struct {
+	// PostID is the ID of the post the page is written as.
+	PostID string `json:"post_id"`
+	// File is the markdown file the page is written to.
+	File string `json:"file"`
+	// LastEdited is the last edit time of the page when it was synced.
+	LastEdited time.Time `json:"last_edited"`
+}
+
+// readMetadata returns the front matter of a markdown file, or nil if it cannot be read.
+func readMetadata(fp string) *types.Metadata {
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		return nil
+	}
+	front, _, err := frontmatter.Parse(data)
+	if err != nil {
+		return nil
+	}
+	yamlData, err := yaml.Marshal(front)
+	if err != nil {
+		return nil
+	}
+	var meta types.Metadata
+	if yaml.Unmarshal(yamlData, &meta) != nil {
+		return nil
+	}
+	return &meta
+}
+
+// notionMetadata maps the properties of a page onto meta.
+func notionMetadata(cfg *NotionConfig, page *notion.Page, meta *types.Metadata) {
+	props := page.Properties
+	meta.Title = page.Title()
+	if desc := props[cfg.Properties.Description].Text(); desc != "" {
+		meta.Description = desc
+	}
+	meta.Tags = props[cfg.Properties.Tags].Names()
+	meta.Date = props[cfg.Properties.Date].Time().UTC()
+	if meta.Date.IsZero() {
+		meta.Date = page.CreatedTime.UTC()
+	}
+	if author := props[cfg.Properties.Author].Text(); author != "" {
+		meta.Author = author
+	}
+	meta.Series = props[cfg.Properties.Series].Text()
+	if p, ok := props[cfg.Properties.Published]; ok && p.Type == "checkbox" {
+		meta.Hidden = !p.Checkbox
+	}
+}
+
+func sync_notion_main(args []string) {
+	fs := flag.NewFlagSet("sync notion", flag.ExitOnError)
+	force := fs.Bool("force", false, "convert all pages, including pages not edited since the last sync")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+	nc := &cfg.Notion
+	if nc.Token == "" || nc.DatabaseID == "" {
+		log.Fatal().Msg("notion.token and notion.database_id must be configured (NOTION_TOKEN, NOTION_DATABASE_ID)")
+	}
+
+	ds, err := initializeDatabase(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+	if ds.Notion == nil {
+		ds.Notion = make(map[string]*NotionEntry)
+	}
+
+	ctx := context.Background()
+	client := notion.NewClient(nc.Token)
+	log.Debug().Str("database", nc.DatabaseID).Msg("start querying notion database")
+	pages, err := client.QueryDatabase(ctx, nc.DatabaseID)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to query notion database %s", nc.DatabaseID)
+	}
+	log.Debug().Int("pages", len(pages)).Msg("done querying notion database")
+
+	dir := filepath.Join(rootDir, nc.Dir)
+	// files hosted by Notion are served from URLs that expire, so images are always downloaded
+	ii := newImageImporter("/assets/images/notion")
+	seen := make(map[string]bool)
+	var updated, unchanged, removed int
+	for _, page := range pages {
+		if page.Archived || page.InTrash {
+			continue
+		}
+		seen[page.ID] = true
+
+		entry := ds.Notion[page.ID]
+		if entry != nil && !*force && entry.LastEdited.Equal(page.LastEditedTime) {
+			if _, err := os.Stat(entry.File); err == nil {
+				unchanged++
+				continue
+			}
+		}
+
+		blocks, err := client.Blocks(ctx, page.ID)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to read the content of notion page %s", page.URL)
+		}
+		body, unsupported := notion.Markdown(blocks, ii.localize)
+		for _, kind := range unsupported {
+			log.Warn().Str("page", page.URL).Msgf("skipped unsupported %s block in %s", kind, page.Title())
+		}
+
+		// keep the metadata the generator adds to the file, such as the path and language
+		meta := &types.Metadata{}
+		if entry != nil {
+			if m := readMetadata(entry.File); m != nil {
+				meta = m
+			}
+			meta.ID = entry.PostID
+		}
+		notionMetadata(nc, page, meta)
+		if page.Cover != nil {
+			if p := ii.localize(page.Cover.URL()); strings.HasPrefix(p, "/") {
+				meta.Image = p
+			}
+		}
+
+		slug := importSlug(page.Properties[nc.Properties.Slug].Text())
+		if slug == "" {
+			slug = importSlug(meta.Title)
+		}
+		if slug == "" {
+			slug = strings.ReplaceAll(page.ID, "-", "")
+		}
+		fp := filepath.Join(dir, slug+".md")
+		if _, err := os.Stat(fp); err == nil && (entry == nil || entry.File != fp) {
+			// another page, or a file not created by the sync, already uses the slug
+			slug += "-" + strings.ReplaceAll(page.ID, "-", "")[:8]
+			fp = filepath.Join(dir, slug+".md")
+		}
+
+		_, err = writeImportedPost(dir, slug, meta, body, true)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to write %q", meta.Title)
+		}
+		if entry != nil && entry.File != fp {
+			log.Info().Str("from", entry.File).Str("to", fp).Msgf("renamed %s", meta.Title)
+			os.Remove(entry.File)
+		}
+		ds.Notion[page.ID] = &NotionEntry{PostID: meta.ID, File: fp, LastEdited: page.LastEditedTime}
+		updated++
+	}
+
+	// pages removed from the database (or archived) are removed from the site
+	for id, entry := range ds.Notion {
+		if seen[id] {
+			continue
+		}
+		log.Info().Str("path", entry.File).Msgf("removing %s, the notion page was deleted", entry.File)
+		if err := os.Remove(entry.File); err != nil && !os.IsNotExist(err) {
+			log.Error().Err(err).Msgf("failed to remove %s", entry.File)
+			continue
+		}
+		delete(ds.Notion, id)
+		removed++
+	}
+
+	err = updateDatabase(dbFile, ds)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to update database file %s", dbFile)
+	}
+	log.Info().Int("updated", updated).Int("unchanged", unchanged).Int("removed", removed).Msgf("synced %d notion pages", updated)
+}
@@ -2,7 +2,7 @@
 
 import typography from '@tailwindcss/typography';
 export default {
-  content: ["./{view,public,templates}/**/*.{html,js,templ}"],
+  content: ["./{view,public,templates}/**/*.{html,js,templ,xsl}"],
   theme: {
     extend: {},
     fontFamily: {
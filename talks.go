@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// talksPath is the site path of the talks listing.
+const talksPath = "/talks"
+
+// videoEmbedURL returns the player URL of a YouTube or Vimeo video, or "" if
+// the video cannot be embedded.
+func videoEmbedURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimPrefix(u.Hostname(), "www.")
+	switch host {
+	case "youtube.com", "m.youtube.com":
+		if id := u.Query().Get("v"); id != "" {
+			return "https://www.youtube-nocookie.com/embed/" + url.PathEscape(id)
+		}
+	case "youtu.be":
+		if id := strings.Trim(u.Path, "/"); id != "" {
+			return "https://www.youtube-nocookie.com/embed/" + url.PathEscape(id)
+		}
+	case "vimeo.com":
+		if id := path.Base(u.Path); id != "" && strings.Trim(id, "0123456789") == "" {
+			return "https://player.vimeo.com/video/" + id
+		}
+	}
+	return ""
+}
+
+// slidesEmbedURL returns the URL to embed the slides at raw with, for PDF
+// files and Google Slides, or "" if the slides cannot be embedded.
+func slidesEmbedURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	if strings.EqualFold(path.Ext(u.Path), ".pdf") {
+		return raw
+	}
+	if u.Hostname() == "docs.google.com" {
+		// https://docs.google.com/presentation/d/<id>/edit -> /presentation/d/<id>/embed
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) >= 3 && parts[0] == "presentation" && parts[1] == "d" {
+			return "https://docs.google.com/presentation/d/" + parts[2] + "/embed"
+		}
+	}
+	return ""
+}
+
+// viewTalk returns the event, slides and video of a talk post.
+func viewTalk(post *types.Post) *view.Talk {
+	pm := &post.Main.Metadata
+	return &view.Talk{
+		Event:       pm.Event,
+		EventURL:    pm.EventURL,
+		SlidesURL:   pm.SlidesURL,
+		SlidesEmbed: slidesEmbedURL(pm.SlidesURL),
+		VideoURL:    pm.VideoURL,
+		VideoEmbed:  videoEmbedURL(pm.VideoURL),
+	}
+}
+
+// talkPosts returns the published talks translated into lang, newest first.
+func talkPosts(gc *GenerationContext, lang types.Lang) []*types.Post {
+	var talks []*types.Post
+	for _, post := range publishedPosts(gc) {
+		if post.Main.Metadata.Kind != types.KindTalk || post.Main.Metadata.Hidden {
+			continue
+		}
+		if _, ok := post.Translated[lang]; ok {
+			talks = append(talks, post)
+		}
+	}
+	sort.Slice(talks, func(i, j int) bool {
+		return talks[i].Main.Metadata.Date.After(talks[j].Main.Metadata.Date)
+	})
+	return talks
+}
+
+// generateTalks writes the talks listing of lang, if there are talks in lang.
+func generateTalks(gc *GenerationContext, lang types.Lang) error {
+	talks := talkPosts(gc, lang)
+	if len(talks) == 0 {
+		return nil
+	}
+	log.Debug().Str("lang", lang).Msg("start generating talks page")
+
+	previews := make([]view.TalkPreview, 0, len(talks))
+	for _, post := range talks {
+		doc := post.Translated[lang]
+		t := viewTalk(post)
+		previews = append(previews, view.TalkPreview{
+			Title:       doc.Metadata.Title,
+			Description: doc.Metadata.Description,
+			URL:         postURL(post, lang),
+			Date:        post.Main.Metadata.Date,
+			Event:       t.Event,
+			Slides:      t.SlidesURL != "",
+			Video:       t.VideoURL != "",
+		})
+	}
+
+	l := gc.I18n.Localizer(lang)
+	url := baseURL + pageURL(langPath(talksPath, lang))
+	meta := &view.Metadata{
+		Language:    lang,
+		Title:       l.T("GoSuda | Talks"),
+		Description: l.T("Talks and presentations by GoSuda members."),
+		Author:      "GoSuda",
+		Image:       baseURL + "/assets/images/ogp_placeholder.png",
+		URL:         url,
+		Canonical:   url,
+		BaseURL:     baseURL,
+		CreatedAt:   talks[len(talks)-1].Main.Metadata.Date,
+		UpdatedAt:   talks[0].Main.Metadata.Date,
+		Analytics:   viewAnalytics(gc),
+		Feeds:       viewFeeds(gc, lang),
+	}
+
+	var b bytes.Buffer
+	err := view.TalksPage(meta, previews).Render(renderContext(gc, lang), &b)
+	if err != nil {
+		return err
+	}
+	paths := []string{"/" + lang + talksPath}
+	if lang == types.LangEnglish {
+		paths = append(paths, talksPath)
+	}
+	for _, p := range paths {
+		err = writePage(gc, p, "the talks page", b.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	log.Debug().Str("lang", lang).Int("talks", len(talks)).Msg("done generating talks page")
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+
+	"golang.org/x/net/html"
+	"gosuda.org/website/internal/htmlcheck"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
+)
+
+// textPath returns the site path of the text-only variant of a post.
+func textPath(post *types.Post, lang types.Lang) string {
+	return "/txt" + langPath(post.Path, lang)
+}
+
+// textDropped are the elements removed with their content from text-only pages.
+var textDropped = map[string]bool{
+	"script": true, "style": true, "noscript": true, "iframe": true, "video": true, "audio": true,
+	"svg": true, "canvas": true, "object": true, "form": true, "button": true,
+}
+
+// textHTML strips a rendered post down to its text: embeds, scripts and
+// sidenotes are dropped, images are replaced by their alternative text and the class and
+// style attributes are removed.
+func textHTML(doc string) (string, error) {
+	skip := 0
+	out, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		switch t.Type {
+		case html.StartTagToken:
+			// sidenotes repeat the footnotes, which text-only pages keep
+			class, _ := t.Attr("class")
+			if (skip > 0 && !htmlcheck.IsVoid(t.Data)) || textDropped[t.Data] || class == "sidenote" {
+				skip++
+				return []byte{}
+			}
+		case html.EndTagToken:
+			if skip > 0 {
+				skip--
+				return []byte{}
+			}
+			if t.Data == "picture" {
+				return []byte{}
+			}
+			return nil
+		}
+		if skip > 0 {
+			return []byte{}
+		}
+
+		switch {
+		case t.IsTag("img"):
+			if alt, _ := t.Attr("alt"); alt != "" {
+				return []byte("[" + html.EscapeString(alt) + "]")
+			}
+			return []byte{}
+		case t.Type == html.StartTagToken || t.Type == html.SelfClosingTagToken:
+			if t.Data == "source" || t.Data == "picture" {
+				return []byte{}
+			}
+			attrs := t.Token.Attr[:0]
+			for _, a := range t.Token.Attr {
+				if a.Key != "class" && a.Key != "style" {
+					attrs = append(attrs, a)
+				}
+			}
+			t.Token.Attr = attrs
+			return t.Render()
+		}
+		return nil
+	})
+	return string(out), err
+}
+
+// writeTextPage writes the text-only variant of a post page. meta is the
+// metadata of the full page and doc its rendered document.
+func writeTextPage(gc *GenerationContext, ctx context.Context, post *types.Post, lang types.Lang, meta *view.Metadata, doc types.Document) error {
+	var err error
+	doc.HTML, err = textHTML(doc.HTML)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	err = view.TextPage(meta, &doc).Render(ctx, &b)
+	if err != nil {
+		return err
+	}
+	return writePage(gc, textPath(post, lang), post.FilePath, b.Bytes())
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 	"time"
@@ -15,7 +18,18 @@ import (
 	"gosuda.org/website/internal/types"
 )
 
-func translatePost(_ *GenerationContext, post *types.Post, retranslate bool, ignoreLangs ...types.Lang) error {
+// machineTranslations is the post processor translating posts into the
+// supported languages: all of them when the post changed, and otherwise the
+// missing ones.
+type machineTranslations struct{}
+
+func (machineTranslations) Name() string { return "translations" }
+
+func (machineTranslations) ProcessPost(gc *GenerationContext, post *types.Post, changed bool) error {
+	return translatePost(gc, post, changed, post.Main.Metadata.Language)
+}
+
+func translatePost(gc *GenerationContext, post *types.Post, retranslate bool, ignoreLangs ...types.Lang) error {
 	if post.Translated == nil {
 		post.Translated = make(map[string]*types.Document)
 	}
@@ -25,21 +39,42 @@ func translatePost(_ *GenerationContext, post *types.Post, retranslate bool, ign
 	}
 
 	if len(post.Main.Metadata.IgnoreLangs) > 0 {
-		ignoreLangs = append([]string(nil), ignoreLangs...)
-		ignoreLangs = append(ignoreLangs, post.Main.Metadata.IgnoreLangs...)
+		ignoreLangs = append(slices.Clone(ignoreLangs), post.Main.Metadata.IgnoreLangs...)
 	}
 
 	for _, lang := range post.Main.Metadata.IgnoreLangs {
 		if lang == post.Main.Metadata.Language {
 			continue
 		}
+		if doc, ok := post.Translated[lang]; ok && isHumanTranslation(doc) {
+			continue
+		}
 		delete(post.Translated, lang)
 	}
 
+	// human translations are never replaced, they only become stale
+	ignoreLangs = slices.Clone(ignoreLangs)
+	for lang, doc := range post.Translated {
+		if isHumanTranslation(doc) {
+			ignoreLangs = append(ignoreLangs, lang)
+		}
+	}
+	for lang := range gc.HumanTranslations[post.ID] {
+		ignoreLangs = append(ignoreLangs, lang)
+	}
+
 	ctx := context.Background()
 
 	var langs []types.Lang
 	if !retranslate {
+		// translations of an unchanged post are current, including those made
+		// before source hashes were recorded
+		for lang, doc := range post.Translated {
+			if doc.SourceHash == "" && lang != post.Main.Metadata.Language {
+				doc.SourceHash = post.Hash
+			}
+		}
+
 		// only retranslate the missing languages
 		for _, lang := range types.SupportedLanguages {
 			if _, ok := post.Translated[string(lang)]; !ok && !slices.Contains(ignoreLangs, lang) {
@@ -59,6 +94,12 @@ func translatePost(_ *GenerationContext, post *types.Post, retranslate bool, ign
 		}
 	}
 
+	// the translation memory is only read for the posts being translated
+	err := gc.DataStore.loadTranslationMemory(post)
+	if err != nil {
+		return err
+	}
+
 	for _, lang := range langs {
 		var retry int
 		for retry < 3 {
@@ -68,7 +109,7 @@ func translatePost(_ *GenerationContext, post *types.Post, retranslate bool, ign
 				time.Sleep(time.Second * 3)
 			}
 
-			err := translateLang(ctx, post, lang)
+			err := translateLang(ctx, gc, post, lang)
 			if err != nil {
 				log.Error().Err(err).Str("path", post.FilePath).Str("lang", string(lang)).Msg("failed to translate, retrying")
 				continue
@@ -82,7 +123,16 @@ func translatePost(_ *GenerationContext, post *types.Post, retranslate bool, ign
 
 var ErrLowQualityTranslation = errors.New("low quality translation")
 
-func translateLang(ctx context.Context, post *types.Post, lang types.Lang) error {
+// checkGlossary warns about the glossary terms used in source whose approved
+// translation is missing from translated. The translation is kept: a term can
+// be rephrased correctly, e.g. with a particle or in another word form.
+func checkGlossary(post *types.Post, lang types.Lang, source, translated string, terms []translate.Term) {
+	for _, t := range translate.MissingTerms(source, translated, terms) {
+		log.Warn().Str("path", post.FilePath).Str("lang", string(lang)).Str("term", t.Source).Str("want", t.Want()).Msg("translation does not follow the glossary")
+	}
+}
+
+func translateLang(ctx context.Context, gc *GenerationContext, post *types.Post, lang types.Lang) error {
 	log.Debug().Str("path", post.FilePath).Str("lang", string(lang)).Msg("translating post")
 	original := post.Main.Markdown
 	original = strings.TrimPrefix(original, "---\n")
@@ -92,15 +142,17 @@ func translateLang(ctx context.Context, post *types.Post, lang types.Lang) error
 	}
 
 	fullLangName := types.FullLangName(lang)
+	terms := gc.Glossary.TermsFor(lang)
 
 	meta := post.Main.Metadata
 	meta.Language = lang
 
 	log.Debug().Str("path", post.FilePath).Str("lang", string(lang)).Msg("translating post title")
-	newTitle, err := translate.Translate(ctx, llmModel, post.Main.Metadata.Title, fullLangName)
+	newTitle, err := translate.Translate(ctx, llmModel, post.Main.Metadata.Title, fullLangName, terms...)
 	if err != nil {
 		return err
 	}
+	checkGlossary(post, lang, post.Main.Metadata.Title, newTitle, terms)
 	meta.Title = newTitle
 	log.Debug().Str("path", post.FilePath).Str("lang", string(lang)).Str("title", newTitle).Msg("translated post title")
 	log.Debug().Str("path", post.FilePath).Str("lang", string(lang)).Msg("evaluating translated title")
@@ -114,10 +166,11 @@ func translateLang(ctx context.Context, post *types.Post, lang types.Lang) error
 	}
 
 	log.Debug().Str("path", post.FilePath).Str("lang", string(lang)).Msg("translating post description")
-	newDescription, err := translate.Translate(ctx, llmModel, post.Main.Metadata.Description, fullLangName)
+	newDescription, err := translate.Translate(ctx, llmModel, post.Main.Metadata.Description, fullLangName, terms...)
 	if err != nil {
 		return err
 	}
+	checkGlossary(post, lang, post.Main.Metadata.Description, newDescription, terms)
 	log.Debug().Str("path", post.FilePath).Str("lang", string(lang)).Msg("evaluating translated description")
 	score, err = evaluate.EvaluateTranslation(ctx, llmModel, post.Main.Metadata.Language, lang, post.Main.Metadata.Description, newDescription)
 	if err != nil {
@@ -132,11 +185,13 @@ func translateLang(ctx context.Context, post *types.Post, lang types.Lang) error
 	log.Debug().Str("path", post.FilePath).Str("lang", string(lang)).Str("description", newDescription).Msg("translated post description")
 
 	log.Debug().Str("path", post.FilePath).Str("lang", string(lang)).Msg("translating post content")
-	tranDocument, err := translate.Translate(ctx, llmModel, origDocument, fullLangName)
+	// unchanged paragraphs keep their previous translation
+	tranDocument, memory, err := translate.TranslateWithMemory(ctx, llmModel, origDocument, fullLangName, post.TranslationMemory[lang], terms...)
 	if err != nil {
 		return err
 	}
 	log.Debug().Str("path", post.FilePath).Str("lang", string(lang)).Msg("translated post content")
+	checkGlossary(post, lang, origDocument, tranDocument, terms)
 
 	log.Debug().Str("path", post.FilePath).Str("lang", string(lang)).Msg("evaluating translated post content")
 	score, err = evaluate.EvaluateTranslation(ctx, llmModel, post.Main.Metadata.Language, lang, origDocument, tranDocument)
@@ -158,7 +213,129 @@ func translateLang(ctx context.Context, post *types.Post, lang types.Lang) error
 	if err != nil {
 		return err
 	}
+	doc.SourceHash = post.Hash
+	doc.Source = types.TranslationMachine
+	doc.Lang = lang
 	post.Translated[string(lang)] = doc
+	if post.TranslationMemory == nil {
+		post.TranslationMemory = make(map[string]map[string]string)
+	}
+	post.TranslationMemory[lang] = memory
+
+	return nil
+}
 
+// findHumanTranslations records the markdown files of list that are human
+// translations, so that they are not processed as posts and the languages
+// they cover are not machine translated.
+func findHumanTranslations(gc *GenerationContext, list []string) {
+	gc.HumanTranslations = make(map[string]map[types.Lang]string)
+	for _, path := range list {
+		if ext := strings.ToLower(filepath.Ext(path)); ext != ".md" && ext != ".markdown" {
+			continue
+		}
+		meta := readMetadata(path)
+		if meta == nil || meta.TranslationOf == "" {
+			continue
+		}
+		_, lang, err := types.ParseLang(meta.Language)
+		if err != nil {
+			log.Error().Str("path", path).Err(err).Msgf("human translation %s has no valid language, skipping it", path)
+			continue
+		}
+		if gc.HumanTranslations[meta.TranslationOf] == nil {
+			gc.HumanTranslations[meta.TranslationOf] = make(map[types.Lang]string)
+		}
+		gc.HumanTranslations[meta.TranslationOf][lang] = path
+	}
+}
+
+// isHumanTranslationFile reports whether path was found by findHumanTranslations.
+func isHumanTranslationFile(gc *GenerationContext, path string) bool {
+	for _, files := range gc.HumanTranslations {
+		for _, fp := range files {
+			if fp == path {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// processHumanTranslations adds the human translations to their posts, and
+// drops human translations whose files were removed. A translation records the
+// main document it was made from when its file changes, so that it becomes
+// stale when the main document changes afterwards.
+func processHumanTranslations(gc *GenerationContext) error {
+	for id, post := range gc.DataStore.Posts {
+		for lang, doc := range post.Translated {
+			if isHumanTranslation(doc) && gc.HumanTranslations[id][lang] == "" {
+				log.Info().Str("id", id).Str("lang", lang).Msgf("removing human translation of %s, its file was deleted", post.FilePath)
+				delete(post.Translated, lang)
+			}
+		}
+	}
+
+	for id, files := range gc.HumanTranslations {
+		post, ok := gc.DataStore.Posts[id]
+		if !ok || post.Main == nil {
+			for _, path := range files {
+				log.Error().Str("path", path).Msgf("%s translates unknown post %s", path, id)
+			}
+			continue
+		}
+		for lang, path := range files {
+			if lang == post.Main.Metadata.Language {
+				log.Error().Str("path", path).Msgf("%s translates %s into its own language", path, post.FilePath)
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+			doc, err := parseMarkdown(gc, path, data)
+			if err != nil {
+				return err
+			}
+
+			doc.Lang, _, _ = types.ParseLang(doc.Metadata.Language)
+
+			// the translation shares the metadata of the main document, except for its texts
+			meta := post.Main.Metadata
+			meta.Language = lang
+			meta.TranslationOf = id
+			if doc.Metadata.Title != "" {
+				meta.Title = doc.Metadata.Title
+			}
+			if doc.Metadata.Description != "" {
+				meta.Description = doc.Metadata.Description
+			}
+			if doc.Metadata.Author != "" {
+				meta.Author = doc.Metadata.Author
+			}
+			if doc.Metadata.ImageAlt != "" {
+				meta.ImageAlt = doc.Metadata.ImageAlt
+			}
+			doc.Metadata = meta
+			doc.FilePath = path
+			doc.Source = types.TranslationHuman
+
+			doc.SourceHash = post.Hash
+			if prev, ok := post.Translated[lang]; ok && isHumanTranslation(prev) {
+				prev, err = gc.DataStore.document(post, prev)
+				if err != nil {
+					return err
+				}
+				if prev.Markdown == doc.Markdown {
+					doc.SourceHash = prev.SourceHash
+				}
+			}
+			if doc.SourceHash != post.Hash {
+				log.Warn().Str("path", path).Str("main", post.FilePath).Msgf("human translation %s is stale, %s changed since it was translated", path, post.FilePath)
+			}
+			post.Translated[lang] = doc
+		}
+	}
 	return nil
 }
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+)
+
+// translationStatus is the state of a post in one language.
+type translationStatus string
+
+const (
+	translationSource  translationStatus = "source"
+	translationCurrent translationStatus = "ok"
+	translationStale   translationStatus = "stale"
+	translationMissing translationStatus = "missing"
+	translationIgnored translationStatus = "ignored"
+	// translationLocked is a human maintained translation, which the machine
+	// translation pipeline does not replace.
+	translationLocked      translationStatus = "human"
+	translationLockedStale translationStatus = "human, stale"
+)
+
+// isHumanTranslation reports whether doc is a human translation, which the
+// translation pipeline never replaces.
+func isHumanTranslation(doc *types.Document) bool {
+	return doc.Source == types.TranslationHuman
+}
+
+// translationStatusOf returns the state of the translation of post into lang.
+func translationStatusOf(post *types.Post, lang types.Lang) translationStatus {
+	meta := &post.Main.Metadata
+	if meta.Language == lang {
+		return translationSource
+	}
+	doc, ok := post.Translated[lang]
+	switch {
+	case !ok && (meta.NoTranslate || slices.Contains(meta.IgnoreLangs, lang)):
+		return translationIgnored
+	case !ok:
+		return translationMissing
+	case isHumanTranslation(doc) && doc.SourceHash != "" && doc.SourceHash != post.Hash:
+		return translationLockedStale
+	case isHumanTranslation(doc):
+		return translationLocked
+	case doc.SourceHash != "" && doc.SourceHash != post.Hash:
+		return translationStale
+	}
+	return translationCurrent
+}
+
+// translationSymbols are the compact forms of statuses in the report table.
+var translationSymbols = map[translationStatus]string{
+	translationSource:      "src",
+	translationCurrent:     "ok",
+	translationStale:       "STALE",
+	translationMissing:     "--",
+	translationIgnored:     "skip",
+	translationLocked:      "human",
+	translationLockedStale: "HUMAN!",
+}
+
+func report_translations_main(args []string) {
+	fs := flag.NewFlagSet("report translations", flag.ExitOnError)
+	incomplete := fs.Bool("incomplete", false, "only list posts with missing or stale translations")
+	fs.Parse(args)
+
+	ds, err := initializeDatabase(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+
+	posts := make([]*types.Post, 0, len(ds.Posts))
+	for _, post := range ds.Posts {
+		if post.Main != nil {
+			posts = append(posts, post)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].FilePath < posts[j].FilePath
+	})
+
+	langs := types.SupportedLanguages
+	counts := make(map[types.Lang]map[translationStatus]int)
+	for _, lang := range langs {
+		counts[lang] = make(map[translationStatus]int)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 1, ' ', 0)
+	header := []string{"FILE"}
+	for _, lang := range langs {
+		header = append(header, strings.ToUpper(lang))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, post := range posts {
+		row := []string{post.FilePath}
+		needsWork := false
+		for _, lang := range langs {
+			status := translationStatusOf(post, lang)
+			counts[lang][status]++
+			if status == translationMissing || status == translationStale || status == translationLockedStale {
+				needsWork = true
+			}
+			row = append(row, translationSymbols[status])
+		}
+		if *incomplete && !needsWork {
+			continue
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LANG\tCOVERAGE\tOK\tHUMAN\tSTALE\tMISSING\tSKIPPED")
+	for _, lang := range langs {
+		c := counts[lang]
+		translated := c[translationSource] + c[translationCurrent] + c[translationLocked]
+		wanted := len(posts) - c[translationIgnored]
+		coverage := 100.0
+		if wanted > 0 {
+			coverage = float64(translated) * 100 / float64(wanted)
+		}
+		fmt.Fprintf(w, "%s\t%.0f%%\t%d\t%d\t%d\t%d\t%d\n", lang, coverage, c[translationCurrent], c[translationLocked]+c[translationLockedStale],
+			c[translationStale]+c[translationLockedStale], c[translationMissing], c[translationIgnored])
+	}
+	w.Flush()
+	log.Info().Int("posts", len(posts)).Int("languages", len(langs)).Msgf("reported translations of %d posts in %d languages", len(posts), len(langs))
+}
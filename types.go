@@ -2,28 +2,120 @@ package main
 
 import (
 	"fmt"
+	"regexp"
 
+	"gosuda.org/website/internal/bibliography"
+	"gosuda.org/website/internal/i18n"
 	"gosuda.org/website/internal/types"
+	"gosuda.org/website/view"
 )
 
 const (
-	rootDir   = "root"
-	publicDir = "public"
-	distDir   = "dist"
-	dbFile    = "zdata/data.json.zstd"
-	baseURL   = "https://gosuda.org"
+	rootDir    = "root"
+	publicDir  = "public"
+	distDir    = "dist"
+	i18nDir    = "i18n"
+	viewDir    = "view"
+	dbFile     = "zdata/data.json.zstd"
+	configFile = "config.jsonnet"
+	// previewDir holds the pages of unpublished posts, served by `serve`.
+	previewDir = ".preview"
+	// siteIgnoreFile lists the files of the source trees left out of the build, in gitignore syntax.
+	siteIgnoreFile = ".siteignore"
 )
 
+// baseURL is the absolute URL of the site without a trailing slash, set from
+// base_url when the config is loaded.
+var baseURL = "https://gosuda.org"
+
 var (
 	ErrInvalidMarkdown = fmt.Errorf("invalid markdown file")
 )
 
 type GenerationContext struct {
+	Config    *SiteConfig
 	DataStore *DataStore
 	UsedPosts map[string]struct{}
 	PathMap   map[string]string
+	// Images holds the processed cover images, keyed by post ID.
+	Images map[string]*PostImage
+	// Galleries caches the images of gallery shortcodes, keyed by post ID and directory.
+	Galleries map[string][]view.GalleryImage
+	// Bibliographies caches the bibliographies of posts, keyed by file path.
+	Bibliographies map[string]bibliography.Bibliography
+	// Posters caches the generated poster frames of videos, keyed by video site path.
+	Posters map[string]string
+	// DarkImages caches the generated dark variants of images, keyed by image site path.
+	DarkImages map[string]string
+	// SuggestMetadata generates missing descriptions with the LLM. (--suggest-metadata)
+	SuggestMetadata bool
+	// StrictA11y fails the build on accessibility issues instead of only warning. (--strict-a11y)
+	StrictA11y bool
+	// HumanTranslations maps post IDs to the files of their human translations, keyed by language.
+	HumanTranslations map[string]map[types.Lang]string
+	// Glossary is the translation glossary. (translation.glossary)
+	Glossary *Glossary
+	// GlossaryTerms are the terms of the glossary page. (glossary.data)
+	GlossaryTerms []*glossaryTerm
+	// Acronyms matches the acronyms of acronyms.terms, see expandAcronyms.
+	Acronyms *regexp.Regexp
+	// I18n holds the UI message translations of the templates.
+	I18n *i18n.Bundle
+	// Pages maps the site paths of the written pages to their sources, see writePage.
+	Pages map[string]string
+	// Docs are the published docs of each version, see collectDocs.
+	Docs map[string]map[string]*types.Post
+	// Sections are the defaults of the _index.md files, keyed by directory.
+	Sections map[string]*section
+	// Sitemaps are the sitemap entries of each language, collected from the feeds.
+	Sitemaps map[types.Lang][]view.SitemapURL
+	// Assets are the public/ files changed since the previous build, see trackAssets.
+	Assets *AssetChanges
+	// ChangedPosts are the source files of the posts whose content changed since the previous build.
+	ChangedPosts []string
+	// Bundles maps the site paths of the bundle entry points to their bundled files, see bundleAssets.
+	Bundles map[string]string
+	// Plugins are the enabled plugins of each extension point, see newPluginRegistry.
+	Plugins *PluginRegistry
+	// Fonts is the site path of the stylesheet of the subset webfonts, see generateFonts.
+	Fonts string
+	// Renderer is the hash of the Markdown renderer of this build, see rendererHash.
+	Renderer string
+	// RenderCache holds the documents rendered by the previous builds, see parseMarkdown.
+	RenderCache *renderCache
 }
 
 type DataStore struct {
-	Posts map[string]*types.Post `json:"posts"`
+	Posts map[string]*types.Post `json:"posts,omitempty"`
+	// Integrity caches SRI hashes of pinned CDN assets, keyed by URL.
+	Integrity map[string]string `json:"integrity,omitempty"`
+	// Contributors caches the commit authors of post source files, keyed by file path.
+	Contributors map[string]*ContributorsEntry `json:"contributors,omitempty"`
+	// Notion maps synced Notion page IDs to their posts.
+	Notion map[string]*NotionEntry `json:"notion,omitempty"`
+	// CrossPosts maps post IDs to their copies on other platforms, keyed by platform.
+	CrossPosts map[string]map[string]*RemotePost `json:"cross_posts,omitempty"`
+	// Announcements maps post IDs to their social media announcements.
+	Announcements map[string]*Announcement `json:"announcements,omitempty"`
+	// Suggestions caches LLM generated descriptions, keyed by the hash of the document body and language.
+	Suggestions map[string]string `json:"suggestions,omitempty"`
+	// Releases caches the releases of the release repositories, keyed by source. (e.g. "github:owner/name")
+	Releases map[string][]*Release `json:"releases,omitempty"`
+	// Projects caches the metadata of the repositories of the projects page, keyed by "owner/name".
+	Projects map[string]*ProjectEntry `json:"projects,omitempty"`
+	// ShortURLs maps the codes of short URLs to their post IDs.
+	ShortURLs map[string]string `json:"short_urls,omitempty"`
+	// Assets caches the blake3 hashes of the public/ files of the previous build, keyed by site path.
+	Assets map[string]string `json:"assets,omitempty"`
+	// Archives maps the outbound links of posts to their archive.org snapshots.
+	Archives map[string]*ArchiveEntry `json:"archives,omitempty"`
+	// Renderer is the hash of the renderer the stored documents were rendered with, see rerenderDocuments.
+	Renderer string `json:"renderer,omitempty"`
+
+	// path is the database file the DataStore was read from.
+	path string
+	// metadataOnly is set when the posts were read without the content of
+	// their documents, which is then read from content, see document.
+	metadataOnly bool
+	content      *contentStore
 }
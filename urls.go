@@ -0,0 +1,347 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/htmlrewrite"
+)
+
+// pathPrefix is the path of baseURL, such as "/blog" for a site served at
+// https://example.com/blog, or "" for a site at the root of its host.
+var pathPrefix string
+
+// sitePath returns the path on the host of the site path p. References that
+// are not root-relative are returned unchanged.
+func sitePath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") {
+		return p
+	}
+	return pathPrefix + p
+}
+
+// URL styles of pages. (url_style)
+const (
+	// urlStyleClean serves /post from post.html, relying on the host to omit the extension.
+	urlStyleClean = "clean"
+	// urlStyleHTML links to /post.html.
+	urlStyleHTML = "html"
+	// urlStyleSlash serves /post/ from post/index.html.
+	urlStyleSlash = "slash"
+)
+
+// urlStyle is the form of page URLs, set from url_style when the config is loaded.
+var urlStyle = urlStyleClean
+
+// pageURL returns the URL path of the page at the site path p in the
+// configured URL style. Directory paths, ending with a slash, are unchanged.
+func pageURL(p string) string {
+	if strings.HasSuffix(p, "/") {
+		return p
+	}
+	switch urlStyle {
+	case urlStyleHTML:
+		return p + ".html"
+	case urlStyleSlash:
+		return p + "/"
+	}
+	return p
+}
+
+// pageFile returns the file in the dist directory of the page at the site path p.
+func pageFile(p string) string {
+	switch {
+	case strings.HasSuffix(p, "/"):
+		p += "index.html"
+	case urlStyle == urlStyleSlash:
+		p += "/index.html"
+	default:
+		p += ".html"
+	}
+	return filepath.Join(distDir, filepath.FromSlash(p))
+}
+
+// writePage writes the page at the site path p, generated from source, and
+// records it in gc.Pages. Writing over a page from another source is an error.
+func writePage(gc *GenerationContext, p, source string, data []byte) error {
+	fp := pageFile(p)
+	// "/post" and "/post/" are the same file in the slash style
+	for _, other := range []string{p, strings.TrimSuffix(p, "/"), p + "/"} {
+		if src, ok := gc.Pages[other]; ok && src != source && pageFile(other) == fp {
+			return fmt.Errorf("%s and %s are both written to %s", src, source, fp)
+		}
+	}
+	err := os.MkdirAll(filepath.Dir(fp), 0755)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(fp, data, 0644)
+	if err != nil {
+		return err
+	}
+	if gc.Pages == nil {
+		gc.Pages = make(map[string]string)
+	}
+	gc.Pages[p] = source
+	return nil
+}
+
+// pageRedirects returns the redirects between the forms of the URLs of the
+// written pages. Hosts already serve the clean style from the .html files, so
+// it needs none. Hosts also redirect /post.html to /post on their own, so the
+// html style follows them rather than redirecting back, which would loop. The
+// slash style redirects both /post and the old /post.html to /post/.
+func pageRedirects(gc *GenerationContext) []Redirect {
+	if urlStyle == urlStyleClean {
+		return nil
+	}
+	redirects := make([]Redirect, 0, len(gc.Pages))
+	for p := range gc.Pages {
+		if strings.HasSuffix(p, "/") {
+			continue
+		}
+		switch urlStyle {
+		case urlStyleHTML:
+			redirects = append(redirects, Redirect{From: pageURL(p), To: p})
+		case urlStyleSlash:
+			redirects = append(redirects,
+				Redirect{From: p, To: pageURL(p)},
+				Redirect{From: p + ".html", To: pageURL(p)},
+			)
+		}
+	}
+	sort.Slice(redirects, func(i, j int) bool {
+		return redirects[i].From < redirects[j].From
+	})
+	return redirects
+}
+
+// applyURLStyle rewrites the links of the generated pages to other pages
+// into the configured URL style, including links written in posts.
+func applyURLStyle(gc *GenerationContext) error {
+	if urlStyle == urlStyleClean {
+		return nil
+	}
+	log.Debug().Str("style", urlStyle).Msg("start rewriting page links")
+
+	list, err := generateFileList(distDir)
+	if err != nil {
+		return err
+	}
+	for _, path := range list {
+		if ext := strings.ToLower(filepath.Ext(path)); ext != ".html" && ext != ".htm" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		data, err = htmlrewrite.Rewrite(data, func(t *htmlrewrite.Token) []byte {
+			if t.Type != htmlrewrite.StartTagToken && t.Type != htmlrewrite.SelfClosingTagToken {
+				return nil
+			}
+			href, ok := t.Attr("href")
+			if !ok {
+				return nil
+			}
+			origin := ""
+			if strings.HasPrefix(href, baseURL+"/") {
+				origin, href = baseURL, strings.TrimPrefix(href, baseURL)
+			}
+			if !strings.HasPrefix(href, "/") || strings.HasPrefix(href, "//") {
+				return nil
+			}
+			p, rest := href, ""
+			if i := strings.IndexAny(href, "?#"); i >= 0 {
+				p, rest = href[:i], href[i:]
+			}
+			if _, ok := gc.Pages[p]; !ok {
+				return nil
+			}
+			t.SetAttr("href", origin+pageURL(p)+rest)
+			return t.Render()
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		err = os.WriteFile(path, data, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	log.Debug().Int("pages", len(gc.Pages)).Msg("done rewriting page links")
+	return nil
+}
+
+// absURL resolves ref, a URL, site path or relative reference, against the
+// page at pageURL, or against the site root if pageURL is empty.
+func absURL(pageURL, ref string) string {
+	r, err := url.Parse(sitePath(ref))
+	if err != nil || r.IsAbs() {
+		return ref
+	}
+	base, err := url.Parse(cmp.Or(pageURL, baseURL+"/"))
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(r).String()
+}
+
+// urlAttrs are the attributes holding URLs that absoluteHTML resolves.
+var urlAttrs = map[string]bool{"href": true, "src": true, "poster": true}
+
+// absoluteHTML resolves the relative links and sources of doc against the
+// page at pageURL, for content read away from the site, such as in feeds.
+func absoluteHTML(doc, pageURL string) (string, error) {
+	out, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		if t.Type != htmlrewrite.StartTagToken && t.Type != htmlrewrite.SelfClosingTagToken {
+			return nil
+		}
+		changed := false
+		for i := range t.Token.Attr {
+			a := &t.Token.Attr[i]
+			if urlAttrs[a.Key] {
+				if abs := absURL(pageURL, a.Val); abs != a.Val {
+					a.Val = abs
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			return nil
+		}
+		return t.Render()
+	})
+	return string(out), err
+}
+
+// prefixAttrs are the attributes holding URLs that applyPathPrefix rewrites.
+var prefixAttrs = map[string]bool{"href": true, "src": true, "poster": true, "action": true, "formaction": true}
+
+// cssRootURL matches the root-relative url() references of stylesheets.
+var cssRootURL = regexp.MustCompile(`url\(\s*(['"]?)/([^/'"])`)
+
+// prefixSrcset returns the srcset attribute value v with the path prefix
+// added to its root-relative candidates.
+func prefixSrcset(v string) string {
+	candidates := strings.Split(v, ",")
+	for i, c := range candidates {
+		c = strings.TrimSpace(c)
+		u, descriptor, _ := strings.Cut(c, " ")
+		candidates[i] = strings.TrimSpace(sitePath(u) + " " + descriptor)
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// applyPathPrefix adds the path prefix of the base URL to the root-relative
+// links and asset references of the generated pages, stylesheets and web
+// manifest, so that the site works when served below the root of its host.
+// The _headers and _redirects files are left as they are, since hosts only
+// read them at the root.
+func applyPathPrefix() error {
+	if pathPrefix == "" {
+		return nil
+	}
+	log.Debug().Str("prefix", pathPrefix).Msg("start adding path prefix")
+
+	list, err := generateFileList(distDir)
+	if err != nil {
+		return err
+	}
+	var files int
+	for _, path := range list {
+		var rewrite func([]byte) ([]byte, error)
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".html", ".htm", ".xsl":
+			rewrite = prefixHTML
+		case ".css":
+			rewrite = func(data []byte) ([]byte, error) {
+				return cssRootURL.ReplaceAll(data, []byte("url(${1}"+pathPrefix+"/${2}")), nil
+			}
+		case ".webmanifest":
+			rewrite = prefixManifest
+		default:
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		data, err = rewrite(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		err = os.WriteFile(path, data, 0644)
+		if err != nil {
+			return err
+		}
+		files++
+	}
+	log.Debug().Int("files", files).Msg("done adding path prefix")
+	return nil
+}
+
+// prefixHTML adds the path prefix to the root-relative URL attributes of an
+// HTML (or XSLT) document.
+func prefixHTML(data []byte) ([]byte, error) {
+	return htmlrewrite.Rewrite(data, func(t *htmlrewrite.Token) []byte {
+		if t.Type != htmlrewrite.StartTagToken && t.Type != htmlrewrite.SelfClosingTagToken {
+			return nil
+		}
+		changed := false
+		for i := range t.Token.Attr {
+			a := &t.Token.Attr[i]
+			var v string
+			switch {
+			case prefixAttrs[a.Key]:
+				v = sitePath(a.Val)
+			case a.Key == "srcset" || a.Key == "imagesrcset":
+				v = prefixSrcset(a.Val)
+			default:
+				continue
+			}
+			if v != a.Val {
+				a.Val = v
+				changed = true
+			}
+		}
+		if !changed {
+			return nil
+		}
+		return t.Render()
+	})
+}
+
+// prefixManifest adds the path prefix to the start URL, scope and icons of a
+// web manifest.
+func prefixManifest(data []byte) ([]byte, error) {
+	var m map[string]any
+	err := json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"start_url", "scope"} {
+		if s, ok := m[key].(string); ok {
+			m[key] = sitePath(s)
+		}
+	}
+	if icons, ok := m["icons"].([]any); ok {
+		for _, icon := range icons {
+			if icon, ok := icon.(map[string]any); ok {
+				if s, ok := icon["src"].(string); ok {
+					icon["src"] = sitePath(s)
+				}
+			}
+		}
+	}
+	return json.Marshal(m)
+}
@@ -1,18 +1,113 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"io/fs"
+	"maps"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/pemistahl/lingua-go"
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/ignore"
 )
 
+// siteIgnore holds the rules of .siteignore, loaded with the config.
+var siteIgnore *ignore.Matcher
+
+// siteIgnored reports whether path is excluded from the build by .siteignore.
+// Only the source trees are filtered, the generated dist/ tree is left alone.
+func siteIgnored(path string, isDir bool) bool {
+	path = filepath.ToSlash(filepath.Clean(path))
+	if path == distDir || strings.HasPrefix(path, distDir+"/") {
+		return false
+	}
+	return siteIgnore.Match(path, isDir)
+}
+
+// walkErrors collects the paths skipped because they could not be read,
+// reported in the build summary by logWalkErrors.
+var walkErrors struct {
+	sync.Mutex
+	paths map[string]error
+}
+
+func recordWalkError(path string, err error) {
+	walkErrors.Lock()
+	defer walkErrors.Unlock()
+	if _, ok := walkErrors.paths[path]; ok {
+		return
+	}
+	if walkErrors.paths == nil {
+		walkErrors.paths = make(map[string]error)
+	}
+	walkErrors.paths[path] = err
+	log.Warn().Err(err).Str("path", path).Msgf("skipping unreadable %s", path)
+}
+
+// logWalkErrors prints the paths skipped while walking the site directories.
+func logWalkErrors() {
+	walkErrors.Lock()
+	defer walkErrors.Unlock()
+	if len(walkErrors.paths) == 0 {
+		return
+	}
+	paths := slices.Sorted(maps.Keys(walkErrors.paths))
+	for _, path := range paths {
+		log.Warn().Err(walkErrors.paths[path]).Str("path", path).Msgf("skipped %s", path)
+	}
+	log.Warn().Int("paths", len(paths)).Msgf("skipped %d unreadable paths", len(paths))
+}
+
+// skippedPaths returns the paths skipped while walking the site directories.
+func skippedPaths() []string {
+	walkErrors.Lock()
+	defer walkErrors.Unlock()
+	return slices.Sorted(maps.Keys(walkErrors.paths))
+}
+
+// walkable reports whether a filepath.Walk callback of the tree at root can
+// use the entry. Entries within the tree that failed to stat or list, and
+// dangling symlinks, are recorded and skipped; the error of root itself is
+// returned, as there is no tree to walk.
+func walkable(root, path string, info fs.FileInfo, err error) (bool, error) {
+	if err != nil {
+		if path == root {
+			return false, err
+		}
+		recordWalkError(path, err)
+		return false, nil
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if _, err := os.Stat(path); err != nil {
+			recordWalkError(path, err)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 func generateFileList(dir string) ([]string, error) {
 	var fileList []string
 	err := filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if ok, err := walkable(dir, path, info, err); !ok {
+			return err
+		}
+		if siteIgnored(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		if !info.IsDir() {
 			fileList = append(fileList, path)
 		}
@@ -27,6 +122,33 @@ func generateFileList(dir string) ([]string, error) {
 	return fileList, nil
 }
 
+// forEachPage calls fn with the site path and content of every HTML page of the
+// dist directory, in path order.
+func forEachPage(fn func(page string, data []byte) error) error {
+	list, err := generateFileList(distDir)
+	if err != nil {
+		return err
+	}
+	for _, path := range list {
+		if strings.ToLower(filepath.Ext(path)) != ".html" {
+			continue
+		}
+		rel, err := filepath.Rel(distDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		err = fn("/"+filepath.ToSlash(rel), data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func copyFile(src, dst string) error {
 	srcFile, err := os.Open(src)
 	if err != nil {
@@ -38,36 +160,73 @@ func copyFile(src, dst string) error {
 	if err != nil {
 		return err
 	}
-	defer dstFile.Close()
 
-	_, err = srcFile.WriteTo(dstFile)
+	// io.Copy lets the kernel copy the data (copy_file_range, sendfile)
+	// where possible and falls back to a buffered copy otherwise.
+	_, err = io.Copy(dstFile, srcFile)
 	if err != nil {
+		dstFile.Close()
 		return err
 	}
-	return nil
+	return dstFile.Close()
 }
 
+// copyDir copies the tree of src into dst. Directories are created while walking,
+// the files are copied by a pool of workers.
 func copyDir(src, dst string) error {
-	filepath.Walk(src, func(path string, info fs.FileInfo, err error) error {
-		if err != nil {
+	type job struct{ src, dst string }
+	jobs := make(chan job)
+
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				err := copyFile(j.src, j.dst)
+				var pe *fs.PathError
+				if errors.As(err, &pe) && pe.Path == j.src {
+					recordWalkError(j.src, err)
+					continue
+				}
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("failed to copy %s: %w", j.src, err)
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	err := filepath.Walk(src, func(path string, info fs.FileInfo, err error) error {
+		if ok, err := walkable(src, path, info, err); !ok {
 			return err
 		}
+		if siteIgnored(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		relPath := strings.TrimPrefix(path, src)
 		dstPath := filepath.Join(dst, relPath)
 		if info.IsDir() {
-			err := os.MkdirAll(dstPath, os.ModePerm)
-			if err != nil {
-				return err
-			}
-		} else {
-			err := copyFile(path, dstPath)
-			if err != nil {
-				return err
-			}
+			return os.MkdirAll(dstPath, os.ModePerm)
 		}
+		jobs <- job{path, dstPath}
 		return nil
 	})
-	return nil
+	close(jobs)
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	return firstErr
 }
 
 func mapDetectedLanguage(detectedLang lingua.Language) string {
@@ -102,3 +261,19 @@ func mapDetectedLanguage(detectedLang lingua.Language) string {
 		return "en"
 	}
 }
+
+// runGit runs git in dir with extra environment variables and returns its trimmed stdout.
+func runGit(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/types"
+)
+
+// videoPoster extracts the first second of a local video to a JPEG poster frame
+// and returns its site path. It returns "" if ffmpeg is not available.
+func videoPoster(gc *GenerationContext, src string) (string, error) {
+	if gc.Posters == nil {
+		gc.Posters = make(map[string]string)
+	}
+	if poster, ok := gc.Posters[src]; ok {
+		return poster, nil
+	}
+
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		log.Warn().Str("video", src).Msg("ffmpeg not found, skipping video poster generation")
+		gc.Posters[src] = ""
+		return "", nil
+	}
+
+	input := filepath.Join(distDir, filepath.FromSlash(src))
+	if _, err := os.Stat(input); err != nil {
+		return "", fmt.Errorf("video %s does not exist: %w", src, err)
+	}
+
+	sum := sha256.Sum256([]byte(src))
+	poster := "/assets/posters/" + hex.EncodeToString(sum[:8]) + ".jpg"
+	output := filepath.Join(distDir, filepath.FromSlash(poster))
+	err = os.MkdirAll(filepath.Dir(output), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := exec.Command(ffmpeg, "-loglevel", "error", "-y", "-ss", "1", "-i", input, "-frames:v", "1", "-q:v", "3", output).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("generating poster of %s: %w: %s", src, err, strings.TrimSpace(string(out)))
+	}
+
+	gc.Posters[src] = poster
+	log.Debug().Str("video", src).Str("poster", poster).Msgf("generated poster for %s", src)
+	return poster, nil
+}
+
+// addVideoPosters sets the poster attribute of local videos that have none.
+func addVideoPosters(gc *GenerationContext, post *types.Post, doc string) (string, error) {
+	var posterErr error
+	out, err := htmlrewrite.Rewrite([]byte(doc), func(t *htmlrewrite.Token) []byte {
+		if !t.IsTag("video") || posterErr != nil {
+			return nil
+		}
+		if _, ok := t.Attr("poster"); ok {
+			return nil
+		}
+		src, _ := t.Attr("src")
+		if !strings.HasPrefix(src, "/") || strings.HasPrefix(src, "//") {
+			return nil
+		}
+
+		poster, err := videoPoster(gc, src)
+		if err != nil {
+			posterErr = fmt.Errorf("%s: %w", post.FilePath, err)
+			return nil
+		}
+		if poster == "" {
+			return nil
+		}
+		t.SetAttr("poster", poster)
+		return t.Render()
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(out), posterErr
+}
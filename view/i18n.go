@@ -0,0 +1,33 @@
+package view
+
+import (
+	"context"
+	"time"
+
+	"gosuda.org/website/internal/i18n"
+	"gosuda.org/website/internal/types"
+)
+
+// T returns the UI message key, written in English, translated into the
+// language of the page being rendered.
+func T(ctx context.Context, key string, args ...any) string {
+	return i18n.FromContext(ctx).T(key, args...)
+}
+
+// Lang returns the BCP-47 tag of the page content.
+func (m *Metadata) Lang() string {
+	if m.LangTag != "" {
+		return m.LangTag
+	}
+	return m.Language
+}
+
+// Dir returns the text direction of lang, "rtl" or "ltr", for dir attributes.
+func Dir(lang string) string {
+	return types.Direction(lang)
+}
+
+// Date formats the date of t for the language of the page being rendered.
+func Date(ctx context.Context, t time.Time) string {
+	return i18n.FromContext(ctx).Date(t)
+}
@@ -11,12 +11,15 @@ import templruntime "github.com/a-h/templ/runtime"
 import "time"
 
 type Metadata struct {
-	Language    string
+	Language string
+	// LangTag is the BCP-47 tag of the page content, if more specific than Language.
+	LangTag     string
 	Title       string
 	Description string
 	Author      string
 	Keywords    []string
 	Image       string
+	ImageAlt    string
 	URL         string
 	BaseURL     string
 	Canonical   string
@@ -24,8 +27,32 @@ type Metadata struct {
 	UpdatedAt   time.Time
 	GoImport    string
 	CustomHead  string
+	Robots      string
+	Outdated    bool
+	EditURL     string
+	AudioURL    string
+	AudioType   string
+
+	Contributors []Contributor
+	Comments     *Comments
+	Analytics    []Analytics
 
 	Alternate *Alternate
+	// Feeds are the feeds advertised for autodiscovery, the feed of the page language first.
+	Feeds []FeedLink
+	// Languages are the language variants of the page, for the language switcher.
+	Languages []LanguageLink
+	// Docs is the navigation of documentation pages.
+	Docs *DocsNav
+	// Talk is the event, slides and video of talk pages.
+	Talk *Talk
+	// TextURL is the text-only variant of the page, if there is one.
+	TextURL string
+	// Prefetch are the pages likely visited next, such as the adjacent posts.
+	Prefetch []string
+	// Scripts and Styles are loaded for the content of the page, such as math.
+	Scripts []string
+	Styles  []string
 }
 
 type Alternate struct {
@@ -33,12 +60,25 @@ type Alternate struct {
 	Versions []KV
 }
 
+type LanguageLink struct {
+	Lang string
+	Name string
+	URL  string
+}
+
+type FeedLink struct {
+	Title string
+	URL   string
+	// Lang is the language of the feed, if it is not multilingual.
+	Lang string
+}
+
 type KV struct {
 	Key   string
 	Value string
 }
 
-func IndexPage(m *Metadata, blogPosts []*BlogPostPreview, featuredPosts []FeaturedPost) templ.Component {
+func IndexPage(m *Metadata, sections []*HomeSection) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -64,14 +104,27 @@ func IndexPage(m *Metadata, blogPosts []*BlogPostPreview, featuredPosts []Featur
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var2 string
-		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(m.Language)
+		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(m.Lang())
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `view/index.templ`, Line: 35, Col: 24}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `view/index.templ`, Line: 75, Col: 22}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("\" dir=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var3 string
+		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(Dir(m.Language))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `view/index.templ`, Line: 75, Col: 46}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString("\">")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
@@ -80,7 +133,7 @@ func IndexPage(m *Metadata, blogPosts []*BlogPostPreview, featuredPosts []Featur
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = IndexPageBody(m, blogPosts, featuredPosts).Render(ctx, templ_7745c5c3_Buffer)
+		templ_7745c5c3_Err = IndexPageBody(m, sections).Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -0,0 +1,102 @@
+package view
+
+import (
+	"context"
+
+	"gosuda.org/website/internal/i18n"
+)
+
+// Funcs are the helpers the generator registers for templates, so that
+// layouts get URLs, assets and related content without reaching into the
+// posts of the site. Any of them may be nil.
+type Funcs struct {
+	// URL returns the URL of the page of a site path in a language, in the
+	// URL style of the site.
+	URL func(path, lang string) string
+	// Asset returns the site path of a file of public/. Bundles, fingerprints
+	// and the path prefix are applied to the written pages afterwards.
+	Asset func(path string) string
+	// Related returns up to n posts sharing tags with the post of id, in a
+	// language, most shared tags first.
+	Related func(id, lang string, n int) []*BlogPostPreview
+	// Tags returns the tags of the listed posts, most used first.
+	Tags func() []TagCount
+	// Search returns the URL of the search page in a language, or "" if the
+	// site has none.
+	Search func(lang string) string
+}
+
+// TagCount is a tag and the number of listed posts with it.
+type TagCount struct {
+	Name  string
+	Count int
+	// FeedURL is the URL of the feed of the posts with the tag.
+	FeedURL string
+}
+
+// RenderContext is what templates are rendered with: the localizer of the
+// page language and the registered helpers.
+type RenderContext struct {
+	Localizer *i18n.Localizer
+	Funcs     Funcs
+}
+
+type renderContextKey struct{}
+
+// NewContext returns a copy of ctx carrying rc, and its localizer for T and
+// Date.
+func NewContext(ctx context.Context, rc *RenderContext) context.Context {
+	ctx = i18n.NewContext(ctx, rc.Localizer)
+	return context.WithValue(ctx, renderContextKey{}, rc)
+}
+
+// funcs returns the helpers of the render context of ctx.
+func funcs(ctx context.Context) Funcs {
+	if rc, ok := ctx.Value(renderContextKey{}).(*RenderContext); ok {
+		return rc.Funcs
+	}
+	return Funcs{}
+}
+
+// URL returns the URL of the page of a site path in the language of the page
+// being rendered.
+func URL(ctx context.Context, path string) string {
+	if f := funcs(ctx).URL; f != nil {
+		return f(path, i18n.FromContext(ctx).Lang())
+	}
+	return path
+}
+
+// Asset returns the site path of a file of public/.
+func Asset(ctx context.Context, path string) string {
+	if f := funcs(ctx).Asset; f != nil {
+		return f(path)
+	}
+	return path
+}
+
+// Related returns up to n posts related to the post of id, in the language of
+// the page being rendered.
+func Related(ctx context.Context, id string, n int) []*BlogPostPreview {
+	if f := funcs(ctx).Related; f != nil {
+		return f(id, i18n.FromContext(ctx).Lang(), n)
+	}
+	return nil
+}
+
+// Tags returns the tags of the listed posts.
+func Tags(ctx context.Context) []TagCount {
+	if f := funcs(ctx).Tags; f != nil {
+		return f()
+	}
+	return nil
+}
+
+// SearchURL returns the URL of the search page in the language of the page
+// being rendered, or "" if the site has none.
+func SearchURL(ctx context.Context) string {
+	if f := funcs(ctx).Search; f != nil {
+		return f(i18n.FromContext(ctx).Lang())
+	}
+	return ""
+}
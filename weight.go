@@ -0,0 +1,194 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/htmlrewrite"
+)
+
+// pageWeight is the size of a generated page and of the local assets it references.
+type pageWeight struct {
+	// path is the site path of the page without the path prefix.
+	path    string
+	html    int64
+	images  int64
+	scripts int64
+	styles  int64
+}
+
+func (w *pageWeight) total() int64 {
+	return w.html + w.images + w.scripts + w.styles
+}
+
+// localAsset returns the site path, without the path prefix, of a reference
+// from the page at page to a file of the site, or false for external references.
+func localAsset(page, ref string) (string, bool) {
+	u, err := url.Parse(ref)
+	if err != nil || (u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	if u.Host != "" && u.Scheme+"://"+u.Host+pathPrefix != baseURL {
+		return "", false
+	}
+	base := &url.URL{Path: pathPrefix + page}
+	p := base.ResolveReference(u).Path
+	if !strings.HasPrefix(p, pathPrefix+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(p, pathPrefix), true
+}
+
+// measurePageWeights weighs every HTML page of the dist directory.
+// Each asset is counted once per page.
+func measurePageWeights() ([]*pageWeight, error) {
+	sizes := make(map[string]int64)
+	assetSize := func(p string) int64 {
+		if size, ok := sizes[p]; ok {
+			return size
+		}
+		var size int64
+		if info, err := os.Stat(filepath.Join(distDir, filepath.FromSlash(p))); err == nil && !info.IsDir() {
+			size = info.Size()
+		}
+		sizes[p] = size
+		return size
+	}
+
+	var weights []*pageWeight
+	err := forEachPage(func(page string, data []byte) error {
+		w := &pageWeight{path: page, html: int64(len(data))}
+		seen := make(map[string]struct{})
+		add := func(total *int64, ref string) {
+			p, ok := localAsset(w.path, ref)
+			if !ok {
+				return
+			}
+			if _, ok := seen[p]; ok {
+				return
+			}
+			seen[p] = struct{}{}
+			*total += assetSize(p)
+		}
+		_, err := htmlrewrite.Rewrite(data, func(t *htmlrewrite.Token) []byte {
+			switch {
+			case t.IsTag("img"):
+				if src, ok := t.Attr("src"); ok {
+					add(&w.images, src)
+				}
+			case t.IsTag("video"):
+				if poster, ok := t.Attr("poster"); ok {
+					add(&w.images, poster)
+				}
+			case t.IsTag("script"):
+				if src, ok := t.Attr("src"); ok {
+					add(&w.scripts, src)
+				}
+			case t.IsTag("link"):
+				if rel, _ := t.Attr("rel"); rel == "stylesheet" {
+					href, _ := t.Attr("href")
+					add(&w.styles, href)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", page, err)
+		}
+		weights = append(weights, w)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(weights, func(i, j int) bool {
+		return weights[i].total() > weights[j].total()
+	})
+	return weights, nil
+}
+
+// overBudget returns the pages heavier than budget KiB, heaviest first.
+func overBudget(weights []*pageWeight, budget int64) []*pageWeight {
+	var over []*pageWeight
+	for _, w := range weights {
+		if w.total() > budget*1024 {
+			over = append(over, w)
+		}
+	}
+	return over
+}
+
+func formatKiB(n int64) string {
+	return fmt.Sprintf("%.1f", float64(n)/1024)
+}
+
+func checkPageWeights(gc *GenerationContext) error {
+	wc := &gc.Config.Weight
+	log.Debug().Int64("budget", wc.Budget).Msg("start checking page weights")
+	weights, err := measurePageWeights()
+	if err != nil {
+		return err
+	}
+
+	over := overBudget(weights, wc.Budget)
+	for _, w := range over {
+		log.Warn().Str("page", w.path).Str("kib", formatKiB(w.total())).Int64("budget", wc.Budget).
+			Msgf("page %s weighs %s KiB, over the budget of %d KiB", w.path, formatKiB(w.total()), wc.Budget)
+	}
+	log.Debug().Int("pages", len(weights)).Int("over_budget", len(over)).Msg("done checking page weights")
+	if wc.Fail && len(over) > 0 {
+		return fmt.Errorf("%d pages are over the weight budget of %d KiB", len(over), wc.Budget)
+	}
+	return nil
+}
+
+func report_weight_main(args []string) {
+	fs := flag.NewFlagSet("report weight", flag.ExitOnError)
+	budget := fs.Int64("budget", 0, "flag pages heavier than this many KiB (default: weight.budget)")
+	all := fs.Bool("all", false, "list every page instead of only those over budget")
+	fail := fs.Bool("fail", false, "exit with a non-zero status if any page is over budget")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+	if *budget <= 0 {
+		*budget = cfg.Weight.Budget
+	}
+
+	weights, err := measurePageWeights()
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to measure the pages of %s, run generate first", distDir)
+	}
+	over := overBudget(weights, *budget)
+	list := over
+	if *all {
+		list = weights
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TOTAL KIB\tHTML\tIMAGES\tSCRIPTS\tSTYLES\tOVER\tPAGE")
+	for _, pw := range list {
+		mark := ""
+		if pw.total() > *budget*1024 {
+			mark = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", formatKiB(pw.total()), formatKiB(pw.html), formatKiB(pw.images),
+			formatKiB(pw.scripts), formatKiB(pw.styles), mark, pw.path)
+	}
+	w.Flush()
+
+	log.Info().Int("pages", len(weights)).Int("over_budget", len(over)).Msgf("%d of %d pages are over the budget of %d KiB", len(over), len(weights), *budget)
+	if *fail && len(over) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+)
+
+// generateSecurityTxt writes security.txt to /.well-known/ and, for older clients, to the site root.
+func generateSecurityTxt(gc *GenerationContext) error {
+	sc := &gc.Config.Security
+	if len(sc.Contact) == 0 {
+		return nil
+	}
+	log.Debug().Msg("start generating security.txt")
+
+	expires := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, sc.ExpiresInDays)
+
+	var b bytes.Buffer
+	for _, contact := range sc.Contact {
+		b.WriteString("Contact: " + contact + "\n")
+	}
+	b.WriteString("Expires: " + expires.Format(time.RFC3339) + "\n")
+	if sc.Encryption != "" {
+		b.WriteString("Encryption: " + sc.Encryption + "\n")
+	}
+	if sc.Policy != "" {
+		b.WriteString("Policy: " + sc.Policy + "\n")
+	}
+	if len(sc.PreferredLanguages) > 0 {
+		b.WriteString("Preferred-Languages: " + strings.Join(sc.PreferredLanguages, ", ") + "\n")
+	}
+	b.WriteString("Canonical: " + baseURL + "/.well-known/security.txt\n")
+	b.WriteString("Canonical: " + baseURL + "/security.txt\n")
+
+	err := os.MkdirAll(filepath.Join(distDir, ".well-known"), 0755)
+	if err != nil {
+		return err
+	}
+	for _, path := range []string{filepath.Join(distDir, ".well-known", "security.txt"), filepath.Join(distDir, "security.txt")} {
+		err = os.WriteFile(path, b.Bytes(), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Debug().Time("expires", expires).Msg("done generating security.txt")
+	return nil
+}
+
+// generateHumansTxt writes humans.txt. (https://humanstxt.org)
+func generateHumansTxt(gc *GenerationContext) error {
+	hc := &gc.Config.Humans
+	log.Debug().Msg("start generating humans.txt")
+
+	var b bytes.Buffer
+	b.WriteString("/* TEAM */\n")
+	for _, m := range hc.Team {
+		b.WriteString("\tName: " + m.Name + "\n")
+		if m.Role != "" {
+			b.WriteString("\tRole: " + m.Role + "\n")
+		}
+		if m.Contact != "" {
+			b.WriteString("\tContact: " + m.Contact + "\n")
+		}
+		if m.Location != "" {
+			b.WriteString("\tLocation: " + m.Location + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	authors := make(map[string]struct{})
+	for _, post := range publishedPosts(gc) {
+		if author := post.Main.Metadata.Author; author != "" {
+			authors[author] = struct{}{}
+		}
+	}
+	if len(authors) > 0 {
+		names := make([]string, 0, len(authors))
+		for name := range authors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		b.WriteString("/* AUTHORS */\n")
+		for _, name := range names {
+			b.WriteString("\tName: " + name + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if len(hc.Thanks) > 0 {
+		b.WriteString("/* THANKS */\n")
+		for _, name := range hc.Thanks {
+			b.WriteString("\tName: " + name + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	languages := make([]string, 0, len(types.SupportedLanguages))
+	for _, lang := range types.SupportedLanguages {
+		languages = append(languages, types.FullLangName(lang))
+	}
+
+	b.WriteString("/* SITE */\n")
+	b.WriteString("\tLast update: " + time.Now().UTC().Format("2006/01/02") + "\n")
+	b.WriteString("\tLanguage: " + strings.Join(languages, " / ") + "\n")
+	if len(hc.Standards) > 0 {
+		b.WriteString("\tStandards: " + strings.Join(hc.Standards, ", ") + "\n")
+	}
+	if len(hc.Components) > 0 {
+		b.WriteString("\tComponents: " + strings.Join(hc.Components, ", ") + "\n")
+	}
+	if len(hc.Software) > 0 {
+		b.WriteString("\tSoftware: " + strings.Join(hc.Software, ", ") + "\n")
+	}
+
+	err := os.WriteFile(filepath.Join(distDir, "humans.txt"), b.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msg("done generating humans.txt")
+	return nil
+}
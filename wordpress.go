@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/htmlrewrite"
+	"gosuda.org/website/internal/htmltomd"
+	"gosuda.org/website/internal/types"
+)
+
+// wxrChannel is the channel of a WordPress eXtended RSS export. Elements of the wp
+// namespace are matched by local name, since its URL changes with the export version.
+type wxrChannel struct {
+	BaseSiteURL string      `xml:"base_site_url"`
+	Authors     []wxrAuthor `xml:"author"`
+	Items       []wxrItem   `xml:"item"`
+}
+
+type wxrAuthor struct {
+	Login       string `xml:"author_login"`
+	DisplayName string `xml:"author_display_name"`
+}
+
+type wxrItem struct {
+	Title         string        `xml:"title"`
+	Link          string        `xml:"link"`
+	Creator       string        `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Encoded       []wxrEncoded  `xml:"encoded"`
+	PostID        string        `xml:"post_id"`
+	PostDate      string        `xml:"post_date"`
+	PostDateGMT   string        `xml:"post_date_gmt"`
+	PostName      string        `xml:"post_name"`
+	Status        string        `xml:"status"`
+	PostType      string        `xml:"post_type"`
+	AttachmentURL string        `xml:"attachment_url"`
+	Categories    []wxrCategory `xml:"category"`
+	PostMeta      []wxrPostMeta `xml:"postmeta"`
+}
+
+// wxrEncoded is a content:encoded or excerpt:encoded element.
+type wxrEncoded struct {
+	XMLName xml.Name
+	Text    string `xml:",chardata"`
+}
+
+type wxrCategory struct {
+	Domain   string `xml:"domain,attr"`
+	Nicename string `xml:"nicename,attr"`
+	Name     string `xml:",chardata"`
+}
+
+type wxrPostMeta struct {
+	Key   string `xml:"meta_key"`
+	Value string `xml:"meta_value"`
+}
+
+func (it *wxrItem) encoded(kind string) string {
+	for _, e := range it.Encoded {
+		if strings.Contains(e.XMLName.Space, kind) {
+			return e.Text
+		}
+	}
+	return ""
+}
+
+func (it *wxrItem) meta(key string) string {
+	for _, m := range it.PostMeta {
+		if m.Key == key {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+func (it *wxrItem) date() time.Time {
+	for _, s := range []string{it.PostDateGMT, it.PostDate} {
+		if t, err := time.Parse(time.DateTime, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+var (
+	wpCaption   = regexp.MustCompile(`(?s)\[caption[^\]]*\]\s*((?:<a[^>]*>\s*)?<img[^>]*>(?:\s*</a>)?)\s*(.*?)\[/caption\]`)
+	wpEmbed     = regexp.MustCompile(`(?s)\[embed[^\]]*\](.*?)\[/embed\]`)
+	wpCode      = regexp.MustCompile(`(?s)\[(?:source)?code(?:\s+lang(?:uage)?="?([\w+#-]*)"?)?[^\]]*\](.*?)\[/(?:source)?code\]`)
+	wpBlockTags = regexp.MustCompile(`(?i)<(?:p|div|h[1-6]|ul|ol|pre|blockquote|table|figure)[\s>]`)
+	wpParagraph = regexp.MustCompile(`(?i)<p[\s>]`)
+)
+
+// wpContent expands the common WordPress shortcodes and, for classic editor posts,
+// the automatic paragraphs that WordPress adds when rendering (wpautop).
+func wpContent(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = wpCaption.ReplaceAllString(content, "<figure>$1<figcaption>$2</figcaption></figure>")
+	content = wpEmbed.ReplaceAllString(content, "\n\n<p><a href=\"$1\">$1</a></p>\n\n")
+	content = wpCode.ReplaceAllStringFunc(content, func(m string) string {
+		sub := wpCode.FindStringSubmatch(m)
+		return "\n\n<pre><code class=\"language-" + sub[1] + "\">" + html.EscapeString(strings.Trim(sub[2], "\n")) + "</code></pre>\n\n"
+	})
+	if wpParagraph.MatchString(content) {
+		return content
+	}
+
+	var b strings.Builder
+	inPre := 0
+	for _, chunk := range strings.Split(content, "\n\n") {
+		inPre += strings.Count(chunk, "<pre") - strings.Count(chunk, "</pre")
+		trimmed := strings.TrimSpace(chunk)
+		switch {
+		case trimmed == "":
+		case inPre > 0 || wpBlockTags.MatchString(trimmed[:min(len(trimmed), 12)]):
+			b.WriteString(chunk + "\n\n")
+		default:
+			b.WriteString("<p>" + strings.ReplaceAll(trimmed, "\n", "<br>\n") + "</p>\n\n")
+		}
+	}
+	return b.String()
+}
+
+func import_wordpress_main(args []string) {
+	fs := flag.NewFlagSet("import wordpress", flag.ExitOnError)
+	out := fs.String("out", rootDir, "directory to write posts (blog/) and pages (pages/) to")
+	drafts := fs.Bool("drafts", false, "import drafts and private posts as hidden posts")
+	images := fs.Bool("images", false, "download images from the WordPress uploads into "+publicDir+"/assets/images/wordpress")
+	overwrite := fs.Bool("overwrite", false, "overwrite previously imported files")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal().Msg("usage: import wordpress [flags] <export.xml>")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to open %s", fs.Arg(0))
+	}
+	var rss struct {
+		Channel wxrChannel `xml:"channel"`
+	}
+	err = xml.NewDecoder(f).Decode(&rss)
+	f.Close()
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to parse WordPress export %s", fs.Arg(0))
+	}
+	ch := &rss.Channel
+
+	authors := make(map[string]string)
+	for _, a := range ch.Authors {
+		authors[a.Login] = a.DisplayName
+	}
+	attachments := make(map[string]string)
+	for _, it := range ch.Items {
+		if it.PostType == "attachment" {
+			attachments[it.PostID] = it.AttachmentURL
+		}
+	}
+
+	var ii *imageImporter
+	if *images {
+		ii = newImageImporter("/assets/images/wordpress")
+	}
+	uploads := strings.TrimSuffix(ch.BaseSiteURL, "/") + "/wp-content/uploads/"
+	isUpload := func(src string) bool {
+		return ch.BaseSiteURL != "" && strings.HasPrefix(src, uploads) || strings.Contains(src, "/wp-content/uploads/")
+	}
+
+	var imported, skipped int
+	for _, it := range ch.Items {
+		if it.PostType != "post" && it.PostType != "page" {
+			continue
+		}
+		switch it.Status {
+		case "publish":
+		case "draft", "pending", "private", "future":
+			if !*drafts {
+				skipped++
+				continue
+			}
+		default: // trash, auto-draft, inherit
+			skipped++
+			continue
+		}
+
+		content := wpContent(it.encoded("content"))
+		if ii != nil {
+			rewritten, err := htmlrewrite.Rewrite([]byte(content), func(t *htmlrewrite.Token) []byte {
+				src, ok := t.Attr("src")
+				if !t.IsTag("img") || !ok || !isUpload(src) {
+					return nil
+				}
+				t.SetAttr("src", ii.localize(src))
+				return t.Render()
+			})
+			if err != nil {
+				log.Fatal().Err(err).Msgf("failed to process images of %q", it.Title)
+			}
+			content = string(rewritten)
+		}
+		body, err := htmltomd.Convert(content)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to convert %q to markdown", it.Title)
+		}
+
+		meta := &types.Metadata{
+			Title:       html.UnescapeString(it.Title),
+			Author:      authors[it.Creator],
+			Description: strings.TrimSpace(html.UnescapeString(stripTags(it.encoded("excerpt")))),
+			Date:        it.date(),
+			Hidden:      it.Status != "publish",
+		}
+		if meta.Author == "" {
+			meta.Author = it.Creator
+		}
+		for _, c := range it.Categories {
+			if (c.Domain == "post_tag" || c.Domain == "category") && c.Nicename != "uncategorized" {
+				meta.Tags = append(meta.Tags, html.UnescapeString(c.Name))
+			}
+		}
+		if cover := attachments[it.meta("_thumbnail_id")]; cover != "" && ii != nil {
+			if p := ii.localize(cover); strings.HasPrefix(p, "/") {
+				meta.Image = p
+			}
+		}
+
+		slug := it.PostName
+		if slug == "" {
+			slug = importSlug(meta.Title)
+		}
+		if slug == "" {
+			slug = "wp-" + it.PostID
+		}
+		slug, _ = url.PathUnescape(slug)
+
+		var original string
+		if u, err := url.Parse(it.Link); err == nil && u.Path != "" && u.RawQuery == "" {
+			original = u.Path
+		}
+		dir := filepath.Join(*out, "blog")
+		if it.PostType == "page" {
+			dir = filepath.Join(*out, "pages")
+			meta.Path = "/" + slug
+		}
+		if original != "" && strings.TrimSuffix(original, "/") != meta.Path {
+			meta.Aliases = append(meta.Aliases, original)
+		}
+
+		ok, err := writeImportedPost(dir, importSlug(slug), meta, body, *overwrite)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to write %q", meta.Title)
+		}
+		if ok {
+			imported++
+		} else {
+			skipped++
+		}
+	}
+
+	log.Info().Int("imported", imported).Int("skipped", skipped).Msgf("imported %d WordPress posts and pages", imported)
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// stripTags removes HTML tags from s.
+func stripTags(s string) string {
+	return tagPattern.ReplaceAllString(s, "")
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gosuda.org/website/internal/types"
+)
+
+// checkStatuses returns an error listing the posts in a state that is not in
+// workflow.states, which would otherwise silently keep them off the site.
+func checkStatuses(gc *GenerationContext) error {
+	var errs []error
+	for _, post := range gc.DataStore.Posts {
+		if status := postStatus(post); !slices.Contains(gc.Config.Workflow.States, status) {
+			errs = append(errs, fmt.Errorf("%s: status %q is not in workflow.states", post.FilePath, status))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func report_review_main(args []string) {
+	fs := flag.NewFlagSet("report review", flag.ExitOnError)
+	fs.Parse(args)
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+	ds, err := initializeDatabase(dbFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to initialize database file %s", dbFile)
+	}
+
+	var pending []*types.Post
+	for _, post := range ds.Posts {
+		if slices.Contains(cfg.Workflow.Review, postStatus(post)) {
+			pending = append(pending, post)
+		}
+	}
+	// waiting the longest first
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].UpdatedAt.Before(pending[j].UpdatedAt)
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tUPDATED\tAUTHOR\tFILE\tTITLE\tPREVIEW")
+	for _, post := range pending {
+		preview := "-"
+		if cfg.Preview.Secret != "" {
+			preview = previewPrefix + previewToken(cfg.Preview.Secret, post.ID, post.Main.Metadata.Language)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", postStatus(post), post.UpdatedAt.Format(time.DateOnly),
+			post.Main.Metadata.Author, post.FilePath, post.Main.Metadata.Title, preview)
+	}
+	w.Flush()
+
+	log.Info().Int("posts", len(pending)).Msgf("%d posts awaiting review", len(pending))
+}
+
+// githubHeader returns the headers of GitHub API requests authorized by token.
+func githubHeader(token string) http.Header {
+	header := http.Header{}
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return header
+}
+
+// nextPageURL returns the URL of the next page of a paginated GitHub API
+// response from its Link header, or "" on the last page.
+func nextPageURL(header http.Header) string {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// sync_labels_main sets the state labels of a pull request of the content
+// repository from the status of the posts it changes, as checked out.
+func sync_labels_main(args []string) {
+	fs := flag.NewFlagSet("sync labels", flag.ExitOnError)
+	pr := fs.Int("pr", 0, "number of the pull request to label")
+	dryRun := fs.Bool("dry-run", false, "print the label changes without making them")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to load config file %s", configFile)
+	}
+	wc := &cfg.Workflow
+	if *pr <= 0 || wc.Repo == "" {
+		log.Fatal().Msg("sync labels needs --pr and workflow.repo")
+	}
+	header := githubHeader(wc.Token)
+	api := fmt.Sprintf("https://api.github.com/repos/%s", wc.Repo)
+
+	type prFile struct {
+		Filename string `json:"filename"`
+		Status   string `json:"status"`
+	}
+	var files []prFile
+	// the files are paginated, pull requests can change more than one page
+	next := fmt.Sprintf("%s/pulls/%d/files?per_page=100", api, *pr)
+	for next != "" {
+		var page []prFile
+		respHeader, err := jsonRequestHeader(http.MethodGet, next, header, nil, &page)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to list the files of pull request %d", *pr)
+		}
+		files = append(files, page...)
+		next = nextPageURL(respHeader)
+	}
+
+	want := make(map[string]bool)
+	for _, f := range files {
+		if f.Status == "removed" || !strings.HasPrefix(f.Filename, rootDir+"/") || !strings.HasSuffix(f.Filename, ".md") {
+			continue
+		}
+		meta := readMetadata(f.Filename)
+		if meta == nil {
+			continue
+		}
+		status := metadataStatus(meta)
+		if label, ok := wc.Labels[status]; ok {
+			want[label] = true
+			log.Debug().Str("file", f.Filename).Str("status", status).Msgf("%s is %s", f.Filename, status)
+		}
+	}
+
+	var labels []struct {
+		Name string `json:"name"`
+	}
+	err = jsonRequest(http.MethodGet, fmt.Sprintf("%s/issues/%d/labels", api, *pr), header, nil, &labels)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("failed to list the labels of pull request %d", *pr)
+	}
+	have := make(map[string]bool)
+	for _, l := range labels {
+		have[l.Name] = true
+	}
+
+	var add []string
+	for label := range want {
+		if !have[label] {
+			add = append(add, label)
+		}
+	}
+	sort.Strings(add)
+	var remove []string
+	for _, label := range wc.Labels {
+		if have[label] && !want[label] {
+			remove = append(remove, label)
+		}
+	}
+	sort.Strings(remove)
+
+	for _, label := range add {
+		log.Info().Int("pr", *pr).Str("label", label).Msgf("adding label %q", label)
+	}
+	for _, label := range remove {
+		log.Info().Int("pr", *pr).Str("label", label).Msgf("removing label %q", label)
+	}
+	if *dryRun {
+		return
+	}
+
+	if len(add) > 0 {
+		err = jsonRequest(http.MethodPost, fmt.Sprintf("%s/issues/%d/labels", api, *pr), header, map[string]any{"labels": add}, &labels)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to label pull request %d", *pr)
+		}
+	}
+	for _, label := range remove {
+		err = jsonRequest(http.MethodDelete, fmt.Sprintf("%s/issues/%d/labels/%s", api, *pr, url.PathEscape(label)), header, nil, &labels)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("failed to remove label %q from pull request %d", label, *pr)
+		}
+	}
+}